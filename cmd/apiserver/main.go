@@ -24,6 +24,7 @@ import (
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/server"
 	"github.com/llm-d-incubation/batch-gateway/internal/util/interrupt"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/tracing"
 	"k8s.io/klog/v2"
 )
 
@@ -47,6 +48,17 @@ func main() {
 
 	logger.Info("starting api server")
 
+	shutdownTracing, err := tracing.Setup(ctx, "batch-gateway-apiserver", config.Tracing)
+	if err != nil {
+		logger.Error(err, "failed to set up tracing")
+		return
+	}
+	defer func() {
+		if err := shutdownTracing(); err != nil {
+			logger.Error(err, "failed to flush traces")
+		}
+	}()
+
 	server, err := server.New(config)
 	if err != nil {
 		logger.Error(err, "failed to create api server")
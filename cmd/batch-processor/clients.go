@@ -0,0 +1,87 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file selects the storage clients of the processor from its configuration.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/memory"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/postgresql"
+	files "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	filesmemory "github.com/llm-d-incubation/batch-gateway/internal/files_store/memory"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+)
+
+// storageClients are the clients of the processor to the databases and the files storage.
+type storageClients struct {
+	db     db.BatchDBClient
+	pq     db.BatchPriorityQueueClient
+	status db.BatchStatusClient
+	event  db.BatchEventChannelClient
+	fileDB db.BatchFileDBClient
+	files  files.BatchFilesClient
+
+	pool *sql.DB // the connection pool of the postgresql clients, if any
+}
+
+// newStorageClients returns the storage clients selected by the database URL of the configuration.
+// A postgresql URL selects the postgresql jobs and status clients, on a pool whose schema is migrated first.
+// The clients of a database URL that isn't supported are left unset, failing the pre-flight check of the processor.
+func newStorageClients(ctx context.Context, cfg *config.ProcessorConfig) (storageClients, error) {
+	switch {
+	case cfg.DatabaseURL == config.MemoryDatabaseURL:
+		return storageClients{
+			db:     memory.NewBatchDBClient(),
+			pq:     memory.NewBatchPriorityQueueClient(),
+			status: memory.NewBatchStatusClient(),
+			event:  memory.NewBatchEventChannelClient(),
+			fileDB: memory.NewBatchFileDBClient(),
+			files:  filesmemory.NewBatchFilesClient(),
+		}, nil
+	case strings.HasPrefix(cfg.DatabaseURL, "postgres://"), strings.HasPrefix(cfg.DatabaseURL, "postgresql://"):
+		pool, err := sql.Open("pgx", cfg.DatabaseURL)
+		if err != nil {
+			return storageClients{}, fmt.Errorf("failed to open the postgresql database: %w", err)
+		}
+		if err := postgresql.Migrate(ctx, pool); err != nil {
+			pool.Close()
+			return storageClients{}, fmt.Errorf("failed to migrate the postgresql database: %w", err)
+		}
+		return storageClients{
+			db:     postgresql.NewBatchDBClient(pool, postgresql.Config{}),
+			status: postgresql.NewBatchStatusClient(pool, postgresql.Config{}),
+			pool:   pool,
+		}, nil
+	}
+	return storageClients{}, nil
+}
+
+// Close closes the connection pool of the clients, if any.
+func (s storageClients) Close() error {
+	if s.pool == nil {
+		return nil
+	}
+	return s.pool.Close()
+}
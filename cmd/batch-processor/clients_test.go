@@ -0,0 +1,72 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the selection of the storage clients of the processor.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/worker"
+	mockbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch/mock"
+)
+
+func TestNewStorageClients(t *testing.T) {
+	tests := []struct {
+		name        string
+		databaseURL string
+		wantValid   bool
+	}{
+		{name: "in-memory", databaseURL: config.MemoryDatabaseURL, wantValid: true},
+		{name: "not configured", databaseURL: "", wantValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig()
+			cfg.DatabaseURL = tt.databaseURL
+
+			storage, err := newStorageClients(context.Background(), cfg)
+			if err != nil {
+				t.Fatalf("Failed to create the storage clients: %v", err)
+			}
+			defer storage.Close()
+			clients := worker.NewProcessorClients(
+				storage.db, storage.pq, storage.status, storage.event, storage.fileDB, storage.files,
+				mockbatch.NewMockInferenceClient(),
+			)
+			err = clients.Validate()
+			if tt.wantValid && err != nil {
+				t.Errorf("Expected the clients to be valid, got %v", err)
+			}
+			if !tt.wantValid && err == nil {
+				t.Errorf("Expected the clients to be invalid")
+			}
+		})
+	}
+}
+
+func TestNewStorageClientsUnreachablePostgres(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.DatabaseURL = "postgres://batch@127.0.0.1:1/batch?connect_timeout=1"
+
+	if _, err := newStorageClients(context.Background(), cfg); err == nil {
+		t.Errorf("Expected an error for an unreachable postgresql database")
+	}
+}
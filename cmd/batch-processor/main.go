@@ -25,16 +25,18 @@ import (
 	"os"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/klog/v2"
 
-	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
 	"github.com/llm-d-incubation/batch-gateway/internal/inference"
 	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/health"
 	"github.com/llm-d-incubation/batch-gateway/internal/processor/metrics"
 	"github.com/llm-d-incubation/batch-gateway/internal/processor/worker"
 	"github.com/llm-d-incubation/batch-gateway/internal/util/interrupt"
 	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
 	"github.com/llm-d-incubation/batch-gateway/internal/util/tls"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/tracing"
 )
 
 func main() {
@@ -72,17 +74,118 @@ func run() error {
 		return err
 	}
 
+	if err := cfg.ApplyEnvOverrides(); err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to apply environment overrides. Processor cannot start")
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		logger.V(logging.ERROR).Error(err, "Invalid configuration. Processor cannot start", "path", *cfgFilePath)
+		return err
+	}
+
 	// metrics setup
-	if err := metrics.InitMetrics(*cfg); err != nil {
+	if err := metrics.InitMetrics(*cfg, prometheus.NewRegistry()); err != nil {
 		logger.V(logging.ERROR).Error(err, "Failed to initialize metrics")
 		return err
 	}
 	logger.V(logging.INFO).Info("Metrics initialized", "numWorkers", cfg.NumWorkers)
 
+	// tracing setup
+	shutdownTracing, err := tracing.Setup(ctx, "batch-gateway-processor", cfg.Tracing)
+	if err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to set up tracing")
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(); err != nil {
+			logger.V(logging.ERROR).Error(err, "Failed to flush traces")
+		}
+	}()
+
 	// setup context with graceful shutdown
-	ctx, cancel := interrupt.ContextWithSignal(ctx)
+	ctx, cancel := interrupt.ContextWithSignalTimeout(ctx, cfg.ShutdownGracePeriod)
 	defer cancel()
 
+	// Todo:: db/llmd client setup
+	storage, err := newStorageClients(ctx, cfg)
+	if err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to create the storage clients")
+		return err
+	}
+	defer storage.Close()
+	dbClient, pqClient := storage.db, storage.pq
+	if cfg.DatabaseURL == config.MemoryDatabaseURL {
+		logger.V(logging.INFO).Info("Using the in-memory database and files storage, their data is lost when the processor exits")
+	}
+
+	// the retry budget is shared by the inference requests of all the workers
+	var retryBudget *inference.RetryBudget
+	if cfg.InferenceRetryBudgetRate > 0 {
+		retryBudget = inference.NewRetryBudget(cfg.InferenceRetryBudgetRate, cfg.InferenceRetryBudgetBurst)
+	}
+
+	// Initialize inference client with configuration
+	inferenceClient, err := inference.NewHTTPClient(inference.HTTPClientConfig{
+		BaseURL:                   cfg.InferenceGatewayURL,
+		Timeout:                   cfg.InferenceRequestTimeout + cfg.LateResponseGracePeriod,
+		APIKey:                    cfg.InferenceAPIKey,
+		Protocol:                  inference.HTTPProtocol(cfg.InferenceHTTPProtocol),
+		MaxIdleConns:              cfg.InferenceMaxIdleConns,
+		MaxIdleConnsPerHost:       cfg.InferenceMaxIdleConnsPerHost,
+		IdleConnTimeout:           cfg.InferenceIdleConnTimeout,
+		DialTimeout:               cfg.InferenceDialTimeout,
+		KeepAlive:                 cfg.InferenceKeepAlive,
+		ResponseHeaderTimeout:     cfg.InferenceResponseHeaderTimeout,
+		OnConnection:              metrics.RecordInferenceConnection,
+		MaxRetries:                cfg.InferenceMaxRetries,
+		InitialBackoff:            cfg.InferenceInitialBackoff,
+		MaxBackoff:                cfg.InferenceMaxBackoff,
+		OnRetry: func(model string, category inference.ErrorCategory) {
+			metrics.RecordInferenceRetry(model, string(category))
+		},
+		RetryBudget:               retryBudget,
+		OnRetryBudgetExhausted:    metrics.RecordRetryBudgetExhausted,
+		ReuseResponseBuffers:      cfg.InferenceReuseResponseBuffers,
+		TLSInsecureSkipVerify:     cfg.InferenceTLSInsecureSkipVerify,
+		TLSCACertFile:             cfg.InferenceTLSCACertFile,
+		TLSClientCertFile:         cfg.InferenceTLSClientCertFile,
+		TLSClientKeyFile:          cfg.InferenceTLSClientKeyFile,
+	})
+	if err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to initialize inference client")
+		return err
+	}
+	logger.V(logging.INFO).Info("Initialized inference client",
+		"baseURL", cfg.InferenceGatewayURL,
+		"timeout", cfg.InferenceRequestTimeout,
+		"protocol", cfg.InferenceHTTPProtocol,
+		"streaming", cfg.InferenceStreaming,
+		"maxRetries", cfg.InferenceMaxRetries)
+
+	// the circuit breaker wraps the inference requests of the workers, not the readiness checks
+	var workerInferenceClient inference.Client = inferenceClient
+	if cfg.InferenceBreakerThreshold > 0 {
+		workerInferenceClient = inference.NewCircuitBreaker(inferenceClient, inference.BreakerConfig{
+			FailureThreshold: cfg.InferenceBreakerThreshold,
+			Cooldown:         cfg.InferenceBreakerCooldown,
+			OnStateChange: func(state inference.BreakerState) {
+				logger.V(logging.WARNING).Info("Inference circuit breaker changed state", "state", state.String())
+				metrics.SetInferenceBreakerState(int(state))
+			},
+		})
+	}
+
+	// readiness reflects whether the configured clients are reachable
+	readinessDeps := []health.Dependency{health.InferenceDependency(inferenceClient)}
+	if dbClient != nil {
+		readinessDeps = append(readinessDeps, health.DBDependency(dbClient))
+	}
+	if pqClient != nil {
+		readinessDeps = append(readinessDeps, health.QueueDependency(pqClient))
+	}
+	readiness := health.NewReadinessHandler(health.DefaultReadinessTimeout, readinessDeps...)
+
 	go func() {
 		m := http.NewServeMux()
 		m.Handle("/metrics", metrics.NewMetricsHandler())
@@ -90,6 +193,7 @@ func run() error {
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte("ok"))
 		})
+		m.Handle(health.ReadinessPath, readiness)
 
 		server := &http.Server{
 			Addr:    cfg.Addr,
@@ -98,11 +202,19 @@ func run() error {
 
 		// tls setup
 		if cfg.SSLEnabled() {
-			tlsConfig, err := tls.GetTlsConfig(tls.LOAD_TYPE_SERVER, false, cfg.SSLCertFile, cfg.SSLKeyFile, "")
+			tlsConfig, err := tls.GetTlsConfig(tls.LOAD_TYPE_SERVER, false, "", "", "")
 			if err != nil {
 				logger.V(logging.ERROR).Error(err, "Failed to configure TLS for observability server")
 				return
 			}
+			// reload the certificate when it is rotated on disk
+			reloader, err := tls.NewCertReloader(cfg.SSLCertFile, cfg.SSLKeyFile)
+			if err != nil {
+				logger.V(logging.ERROR).Error(err, "Failed to configure TLS for observability server")
+				return
+			}
+			go reloader.Watch(ctx, tls.CertReloadInterval)
+			tlsConfig.GetCertificate = reloader.GetCertificate
 			server.TLSConfig = tlsConfig
 			logger.V(logging.INFO).Info("Observability server TLS configured")
 		}
@@ -133,36 +245,8 @@ func run() error {
 
 	}()
 
-	// Todo:: db/llmd client setup
-	var dbClient db.BatchDBClient
-	var pqClient db.BatchPriorityQueueClient
-	var statusClient db.BatchStatusClient
-	var eventClient db.BatchEventChannelClient
-
-	// Initialize inference client with configuration
-	inferenceClient, err := inference.NewHTTPClient(inference.HTTPClientConfig{
-		BaseURL:                   cfg.InferenceGatewayURL,
-		Timeout:                   cfg.InferenceRequestTimeout,
-		APIKey:                    cfg.InferenceAPIKey,
-		MaxRetries:                cfg.InferenceMaxRetries,
-		InitialBackoff:            cfg.InferenceInitialBackoff,
-		MaxBackoff:                cfg.InferenceMaxBackoff,
-		TLSInsecureSkipVerify:     cfg.InferenceTLSInsecureSkipVerify,
-		TLSCACertFile:             cfg.InferenceTLSCACertFile,
-		TLSClientCertFile:         cfg.InferenceTLSClientCertFile,
-		TLSClientKeyFile:          cfg.InferenceTLSClientKeyFile,
-	})
-	if err != nil {
-		logger.V(logging.ERROR).Error(err, "Failed to initialize inference client")
-		return err
-	}
-	logger.V(logging.INFO).Info("Initialized inference client",
-		"baseURL", cfg.InferenceGatewayURL,
-		"timeout", cfg.InferenceRequestTimeout,
-		"maxRetries", cfg.InferenceMaxRetries)
-
 	processorClients := worker.NewProcessorClients(
-		dbClient, pqClient, statusClient, eventClient, inferenceClient,
+		dbClient, pqClient, storage.status, storage.event, storage.fileDB, storage.files, workerInferenceClient,
 	)
 
 	// initialize processor (worker pool manager)
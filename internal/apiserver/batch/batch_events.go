@@ -0,0 +1,156 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file implements the Server-Sent Events stream of the status of a batch.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+const (
+	// batchEventStatus is the name of the events carrying the Batch object
+	batchEventStatus = "status"
+
+	// batchEventsRefreshInterval is how often a stream re-reads the batch between status update events,
+	// so updates whose event was missed are streamed too
+	batchEventsRefreshInterval = 5 * time.Second
+)
+
+// StreamBatchEvents streams the status of a batch as Server-Sent Events.
+// A status event carrying the Batch object is sent when the client connects, and each time the status or the request
+// counts of the batch change. The stream ends when the batch reaches a final status.
+func (c *BatchApiHandler) StreamBatchEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	batchID := r.PathValue(pathParamBatchID)
+	if batchID == "" {
+		apiErr := openai.NewAPIError(http.StatusBadRequest, "", pathParamBatchID+" is required", nil)
+		common.WriteAPIError(ctx, w, apiErr)
+		return
+	}
+
+	// subscribe before reading the batch, so no update is missed in between
+	events, err := c.eventClient.ConsumerGetChannel(ctx, batchID)
+	if err != nil {
+		logger.Error(err, "failed to subscribe to batch events", "batch_id", batchID)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	defer events.CloseFn()
+
+	batch, err := c.getBatch(ctx, common.GetTenantIDFromContext(ctx), batchID)
+	if err != nil {
+		logger.Error(err, "failed to get batch", "batch_id", batchID)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	if batch == nil {
+		apiErr := openai.NewAPIError(http.StatusNotFound, "", fmt.Sprintf("Batch with ID %s not found", batchID), nil)
+		common.WriteAPIError(ctx, w, apiErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	send := func(batch *openai.Batch) bool {
+		c.truncateErrors(batch)
+		data, err := json.Marshal(batch)
+		if err != nil {
+			logger.Error(err, "failed to marshal batch", "batch_id", batchID)
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", batchEventStatus, data); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if !send(batch) || batch.Status.IsFinal() {
+		return
+	}
+
+	ticker := time.NewTicker(batchEventsRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// the client disconnected
+			return
+		case <-c.streamsDone:
+			return
+		case _, ok := <-events.Events:
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+		}
+
+		updated, err := c.getBatch(ctx, common.GetTenantIDFromContext(ctx), batchID)
+		if err != nil {
+			logger.Error(err, "failed to refresh batch", "batch_id", batchID)
+			continue
+		}
+		if updated == nil {
+			// the batch was deleted
+			return
+		}
+		if updated.Status == batch.Status && updated.RequestCounts == batch.RequestCounts {
+			continue
+		}
+		batch = updated
+		if !send(batch) || batch.Status.IsFinal() {
+			return
+		}
+	}
+}
+
+// CloseStreamsWhenDone ends the event streams of the handler when ctx is done,
+// so that they don't hold the graceful shutdown of the server.
+func (c *BatchApiHandler) CloseStreamsWhenDone(ctx context.Context) {
+	c.streamsDone = ctx.Done()
+}
+
+// getBatch returns the batch of the tenant, or nil if it doesn't exist or belongs to another tenant.
+func (c *BatchApiHandler) getBatch(ctx context.Context, tenantID, batchID string) (*openai.Batch, error) {
+	job, err := c.getTenantJob(ctx, tenantID, batchID)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	return jobToBatch(job)
+}
+
+// notifyStatusUpdate tells the event streams of the batch that its status was updated.
+func (c *BatchApiHandler) notifyStatusUpdate(r *http.Request, batchID string) {
+	event := []api.BatchEvent{{ID: batchID, Type: api.BatchEventStatusUpdate, TTL: c.config.BatchTTLSeconds}}
+	if _, err := c.eventClient.ProducerSendEvents(r.Context(), event); err != nil {
+		logging.GetRequestLogger(r).Error(err, "failed to send status update event", "batch_id", batchID)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the batch event streams.
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/middleware"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	sharedbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// storeBatchStatusForTest stores a batch of the default tenant with the given status, or updates its status.
+func storeBatchStatusForTest(t *testing.T, handler *BatchApiHandler, batchID string, statusInfo openai.BatchStatusInfo) {
+	t.Helper()
+
+	specData, _ := json.Marshal(openai.BatchSpec{
+		InputFileID:      "file-abc123",
+		Endpoint:         openai.EndpointChatCompletions,
+		CompletionWindow: "24h",
+	})
+	statusData, _ := json.Marshal(statusInfo)
+	job := &api.BatchJob{
+		ID: batchID, SLO: time.Now().Add(24 * time.Hour), TTL: 86400,
+		Tags: []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)}, Spec: specData, Status: statusData,
+	}
+
+	jobs, _, _ := handler.dbClient.Get(context.Background(), []string{batchID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	if len(jobs) == 0 {
+		if _, err := handler.dbClient.Store(context.Background(), job); err != nil {
+			t.Fatalf("Failed to store batch: %v", err)
+		}
+		return
+	}
+	if err := handler.dbClient.Update(context.Background(), job); err != nil {
+		t.Fatalf("Failed to update batch: %v", err)
+	}
+}
+
+// newEventsServerForTest serves the batch routes through the middlewares affecting streamed responses.
+func newEventsServerForTest(handler *BatchApiHandler) *httptest.Server {
+	mux := http.NewServeMux()
+	common.RegisterHandler(mux, handler)
+	return httptest.NewServer(middleware.CompressionMiddleware(1024)(middleware.RequestMiddleware(mux)))
+}
+
+// readBatchEvent reads the next event of the stream, and returns its name and batch.
+func readBatchEvent(t *testing.T, reader *bufio.Reader) (string, *openai.Batch) {
+	t.Helper()
+
+	var name string
+	var batch *openai.Batch
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return name, batch
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			batch = &openai.Batch{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), batch); err != nil {
+				t.Fatalf("Failed to parse event data %q: %v", line, err)
+			}
+		}
+	}
+}
+
+func TestStreamBatchEvents(t *testing.T) {
+	t.Run("StreamsUntilFinalStatus", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		storeBatchStatusForTest(t, handler, "batch-events", openai.BatchStatusInfo{Status: openai.BatchStatusInProgress})
+		server := newEventsServerForTest(handler)
+		defer server.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/batches/batch-events/events", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Expected content type text/event-stream, got %q", ct)
+		}
+		reader := bufio.NewReader(resp.Body)
+
+		// the current status is sent on connection
+		name, batch := readBatchEvent(t, reader)
+		if name != batchEventStatus || batch == nil || batch.ID != "batch-events" || batch.Status != openai.BatchStatusInProgress {
+			t.Fatalf("Unexpected first event %q: %+v", name, batch)
+		}
+
+		// progress
+		storeBatchStatusForTest(t, handler, "batch-events", openai.BatchStatusInfo{
+			Status:        openai.BatchStatusInProgress,
+			RequestCounts: openai.BatchRequestCounts{Total: 2, Completed: 1},
+		})
+		if _, err := handler.eventClient.ProducerSendEvents(context.Background(), []api.BatchEvent{
+			{ID: "batch-events", Type: api.BatchEventStatusUpdate, TTL: 60},
+		}); err != nil {
+			t.Fatalf("Failed to send event: %v", err)
+		}
+		if _, batch := readBatchEvent(t, reader); batch.RequestCounts.Completed != 1 {
+			t.Fatalf("Expected progress event, got %+v", batch)
+		}
+
+		// final status
+		storeBatchStatusForTest(t, handler, "batch-events", openai.BatchStatusInfo{
+			Status:        openai.BatchStatusCompleted,
+			RequestCounts: openai.BatchRequestCounts{Total: 2, Completed: 2},
+		})
+		handler.eventClient.ProducerSendEvents(context.Background(), []api.BatchEvent{
+			{ID: "batch-events", Type: api.BatchEventStatusUpdate, TTL: 60},
+		})
+		if _, batch := readBatchEvent(t, reader); batch.Status != openai.BatchStatusCompleted {
+			t.Fatalf("Expected completed event, got %+v", batch)
+		}
+
+		// the stream ends with the final status
+		if rest, err := io.ReadAll(reader); err != nil || len(rest) != 0 {
+			t.Errorf("Expected the stream to end, got %q, err %v", rest, err)
+		}
+	})
+
+	t.Run("ClientDisconnectUnsubscribes", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		storeBatchStatusForTest(t, handler, "batch-events", openai.BatchStatusInfo{Status: openai.BatchStatusInProgress})
+		server := newEventsServerForTest(handler)
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/batches/batch-events/events", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		readBatchEvent(t, bufio.NewReader(resp.Body))
+		cancel()
+		resp.Body.Close()
+
+		// no listener is left for the batch
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			sent, err := handler.eventClient.ProducerSendEvents(context.Background(), []api.BatchEvent{
+				{ID: "batch-events", Type: api.BatchEventStatusUpdate, TTL: 60},
+			})
+			if err != nil {
+				t.Fatalf("Failed to send event: %v", err)
+			}
+			if len(sent) == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected the stream to unsubscribe after the client disconnected")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		server := newEventsServerForTest(handler)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/v1/batches/batch-missing/events")
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	})
+}
@@ -19,24 +19,108 @@ limitations under the License.
 package batch
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
 	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	filesapi "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	sharedbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/ids"
 	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
 	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/tracing"
 )
 
 const (
 	pathParamBatchID = "batch_id"
-	pathParamLimit   = "limit"
-	pathParamAfter   = "after"
+
+	// defaultListLimit and maxListLimit bound the page size of ListBatches
+	defaultListLimit = 20
+	maxListLimit     = 100
+
+	// queryParamValidateOnly validates the input file of a batch without creating the batch
+	queryParamValidateOnly = "validate_only"
+
+	// queryParamMetadataPrefix starts the metadata[key]=value filters of ListBatches
+	queryParamMetadataPrefix = "metadata["
+
+	// lineErrorCodeModelNotAllowed is the validation error of a line targeting a model the tenant may not use
+	lineErrorCodeModelNotAllowed = "model_not_allowed"
+
+	// maxReportedDuplicateCustomIDs bounds the duplicated custom_ids listed in the error of a batch request
+	maxReportedDuplicateCustomIDs = 10
+
+	headerIdempotencyKey     = "Idempotency-Key"
+	headerIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	idempotencyKeyPrefix     = "batch-idempotency:"
+
+	// idempotencyReservationTimeout is how long an Idempotency-Key is reserved for the batch being created,
+	// after which the reservation of a request that didn't complete is taken over by a retried request
+	idempotencyReservationTimeout = time.Minute
+
+	// maxIdempotencyKeyAttempts bounds the attempts to reserve an Idempotency-Key whose record is replaced concurrently
+	maxIdempotencyKeyAttempts = 3
 )
 
+// validateBatchRequest validates a batch request, whose completion window must also be one of the configured windows.
+func (c *BatchApiHandler) validateBatchRequest(batchReq *openai.CreateBatchRequest) error {
+	err := batchReq.Validate()
+	var verr *openai.ValidationError
+	if err != nil && !errors.As(err, &verr) {
+		return err
+	}
+	if verr == nil {
+		verr = &openai.ValidationError{}
+	}
+
+	validWindow := !slices.ContainsFunc(verr.Errors, func(e openai.FieldError) bool { return e.Param == "completion_window" })
+	if validWindow && !slices.ContainsFunc(c.config.CompletionWindows, func(window string) bool {
+		return openai.NormalizeCompletionWindow(window) == batchReq.CompletionWindow
+	}) {
+		verr.Add("completion_window", fmt.Sprintf("completion_window must be one of: %s", strings.Join(c.config.CompletionWindows, ", ")))
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// idempotencyRecord records the batch created for an Idempotency-Key, or the reservation of the key for the batch
+// being created when BatchID is empty.
+type idempotencyRecord struct {
+	BatchID     string `json:"batch_id"`
+	RequestHash string `json:"request_hash"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// idempotencyKey returns the key under which the batch created for a tenant's Idempotency-Key is recorded.
+func idempotencyKey(tenantID, key string) string {
+	return idempotencyKeyPrefix + tenantID + ":" + key
+}
+
+// hashBatchRequest returns a hash identifying the payload of a batch request.
+func hashBatchRequest(batchReq *openai.CreateBatchRequest) string {
+	data, _ := json.Marshal(batchReq)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
 func jobToBatch(job *api.BatchJob) (*openai.Batch, error) {
 	batch := &openai.Batch{
 		ID: job.ID,
@@ -53,21 +137,35 @@ func jobToBatch(job *api.BatchJob) (*openai.Batch, error) {
 	return batch, nil
 }
 
+// truncateErrors caps the errors returned with the batch at MaxBatchErrors.
+func (c *BatchApiHandler) truncateErrors(batch *openai.Batch) {
+	if batch.Errors != nil {
+		batch.Errors.Truncate(c.config.MaxBatchErrors)
+	}
+}
+
 type BatchApiHandler struct {
 	config       *common.ServerConfig
 	dbClient     api.BatchDBClient
 	queueClient  api.BatchPriorityQueueClient
 	eventClient  api.BatchEventChannelClient
 	statusClient api.BatchStatusClient
+	fileDBClient api.BatchFileDBClient
+	filesClient  filesapi.BatchFilesClient
+	now          func() time.Time // replaced in tests
+	streamsDone  <-chan struct{}  // ends the event streams, see CloseStreamsWhenDone
 }
 
-func NewBatchApiHandler(config *common.ServerConfig, dbClient api.BatchDBClient, queueClient api.BatchPriorityQueueClient, eventClient api.BatchEventChannelClient, statusClient api.BatchStatusClient) *BatchApiHandler {
+func NewBatchApiHandler(config *common.ServerConfig, dbClient api.BatchDBClient, queueClient api.BatchPriorityQueueClient, eventClient api.BatchEventChannelClient, statusClient api.BatchStatusClient, fileDBClient api.BatchFileDBClient, filesClient filesapi.BatchFilesClient) *BatchApiHandler {
 	return &BatchApiHandler{
 		config:       config,
 		dbClient:     dbClient,
 		queueClient:  queueClient,
 		eventClient:  eventClient,
 		statusClient: statusClient,
+		fileDBClient: fileDBClient,
+		filesClient:  filesClient,
+		now:          time.Now,
 	}
 }
 
@@ -83,6 +181,11 @@ func (c *BatchApiHandler) GetRoutes() []common.Route {
 			Pattern:     "/v1/batches",
 			HandlerFunc: c.ListBatches,
 		},
+		{
+			Method:      http.MethodPost,
+			Pattern:     "/v1/batches/status",
+			HandlerFunc: c.GetBatchesStatus,
+		},
 		{
 			Method:      http.MethodGet,
 			Pattern:     "/v1/batches/{batch_id}",
@@ -93,6 +196,16 @@ func (c *BatchApiHandler) GetRoutes() []common.Route {
 			Pattern:     "/v1/batches/{batch_id}/cancel",
 			HandlerFunc: c.CancelBatch,
 		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/v1/batches/{batch_id}/events",
+			HandlerFunc: c.StreamBatchEvents,
+		},
+		{
+			Method:      http.MethodGet,
+			Pattern:     "/v1/batches/{batch_id}/output",
+			HandlerFunc: c.ListBatchOutput,
+		},
 	}
 }
 
@@ -110,23 +223,86 @@ func (c *BatchApiHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate request
-	if err := batchReq.Validate(); err != nil {
+	batchReq.CompletionWindow = openai.NormalizeCompletionWindow(batchReq.CompletionWindow)
+	if err := c.validateBatchRequest(batchReq); err != nil {
 		logger.Error(err, "failed to validate request")
 		apiErr := openai.NewAPIError(http.StatusBadRequest, "", err.Error(), nil)
+		var verr *openai.ValidationError
+		if errors.As(err, &verr) {
+			apiErr = verr.APIError(http.StatusBadRequest)
+		}
 		common.WriteAPIError(ctx, w, apiErr)
 		return
 	}
 
-	batchID := fmt.Sprintf("batch_%s", uuid.NewString())
+	if batchReq.MixedEndpoints && !c.config.MixedEndpointBatchesEnabled {
+		apiErr := openai.NewAPIError(http.StatusBadRequest, "", "mixed_endpoints batches are not enabled", nil)
+		common.WriteAPIError(ctx, w, apiErr)
+		return
+	}
+
+	if validateOnly := r.URL.Query().Get(queryParamValidateOnly); validateOnly != "" {
+		ok, err := strconv.ParseBool(validateOnly)
+		if err != nil {
+			apiErr := openai.NewAPIError(http.StatusBadRequest, "", "invalid validate_only parameter: must be a boolean", nil)
+			common.WriteAPIError(ctx, w, apiErr)
+			return
+		}
+		if ok {
+			c.validateBatch(w, r, batchReq)
+			return
+		}
+	}
+
+	// a retried request with the same Idempotency-Key returns the batch created by the first request
+	key := r.Header.Get(headerIdempotencyKey)
+	if len(key) > maxIdempotencyKeyLength {
+		apiErr := openai.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("%s must be at most %d characters", headerIdempotencyKey, maxIdempotencyKeyLength), nil)
+		common.WriteAPIError(ctx, w, apiErr)
+		return
+	}
+	var reservation []byte
+	created := false
+	if key != "" {
+		if reservation = c.reserveIdempotencyKey(w, r, key, hashBatchRequest(batchReq)); reservation == nil {
+			return
+		}
+		// the key is released if the batch isn't created, so that the request can be retried
+		defer func() {
+			if !created {
+				c.releaseIdempotencyKey(r, key, reservation)
+			}
+		}()
+	}
+
+	if !c.checkActiveBatchQuota(w, r) {
+		return
+	}
+
+	limitErrors, ok := c.checkInputFile(w, r, batchReq)
+	if !ok {
+		return
+	}
+
+	batchID := ids.New(c.config.BatchIDPrefix)
 
 	// construct batch spec
 	batchSpec := openai.BatchSpec{
-		Object:           "batch",
-		Endpoint:         batchReq.Endpoint,
-		InputFileID:      batchReq.InputFileID,
-		CompletionWindow: batchReq.CompletionWindow,
-		Metadata:         batchReq.Metadata,
-		CreatedAt:        time.Now().UTC().Unix(),
+		Object:             "batch",
+		Endpoint:           batchReq.Endpoint,
+		InputFileID:        batchReq.InputFileID,
+		CompletionWindow:   batchReq.CompletionWindow,
+		Metadata:           batchReq.Metadata,
+		CreatedAt:          time.Now().UTC().Unix(),
+		MixedEndpoints:     batchReq.MixedEndpoints,
+		Priority:           batchReq.Priority,
+		CallbackURL:        batchReq.CallbackURL,
+		OutputExpiresAfter: batchReq.OutputExpiresAfter,
+
+		ValidationStrictness: c.config.ValidationStrictness,
+	}
+	if batchSpec.Priority == "" {
+		batchSpec.Priority = openai.BatchPriorityNormal
 	}
 	batchSpecData, err := json.Marshal(batchSpec)
 	if err != nil {
@@ -135,41 +311,59 @@ func (c *BatchApiHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// construct batch status
-	batchStatus := openai.BatchStatusInfo{
-		Status: openai.BatchStatusValidating,
-	}
-	batchStatusData, err := json.Marshal(batchStatus)
+	// store batch job
+	completionDuration, err := time.ParseDuration(batchReq.CompletionWindow)
 	if err != nil {
-		logger.Error(err, "failed to marshal batch status")
+		logger.Error(err, "failed to parse completion window duration")
 		common.WriteInternalServerError(ctx, w)
 		return
 	}
+	slo := time.Now().UTC().Add(completionDuration)
 
-	// store batch job
-	completionDuration, err := time.ParseDuration(batchReq.CompletionWindow)
+	// construct batch status
+	expiresAt := slo.Unix()
+	batchStatus := openai.BatchStatusInfo{
+		Status:    openai.BatchStatusValidating,
+		ExpiresAt: &expiresAt,
+	}
+	if limitErrors != nil {
+		// the batch would exceed the limits of the inference gateway, so it fails before being processed
+		failedAt := time.Now().UTC().Unix()
+		limitErrors.Truncate(c.config.MaxBatchErrors)
+		batchStatus.Status = openai.BatchStatusFailed
+		batchStatus.FailedAt = &failedAt
+		batchStatus.Errors = limitErrors
+	}
+	batchStatusData, err := json.Marshal(batchStatus)
 	if err != nil {
-		logger.Error(err, "failed to parse completion window duration")
+		logger.Error(err, "failed to marshal batch status")
 		common.WriteInternalServerError(ctx, w)
 		return
 	}
-	slo := time.Now().UTC().Add(completionDuration)
 
 	ttl := c.config.BatchTTLSeconds
 	if batchReq.OutputExpiresAfter != nil {
-		if batchReq.OutputExpiresAfter.Anchor == "" || batchReq.OutputExpiresAfter.Anchor == "created_at" {
-			// TODO: get the batch file create time, and set TTL to batch file create time + OutputExpiresAfter.Seconds
-			ttl = int(completionDuration.Seconds()) + int(batchReq.OutputExpiresAfter.Seconds)
-		}
+		// the output files are created before the batch expires, and expire OutputExpiresAfter.Seconds later
+		ttl = int(completionDuration.Seconds()) + int(batchReq.OutputExpiresAfter.Seconds)
 	}
 
+	// the input file is not deleted while the batch tagged with it is processed
+	tags := []string{sharedbatch.TenantTag(common.GetTenantIDFromContext(ctx)), sharedbatch.InputFileTag(batchReq.InputFileID)}
+	if !batchStatus.Status.IsFinal() {
+		tags = append(tags, sharedbatch.ActiveJobTag)
+	}
 	job := &api.BatchJob{
 		ID:     batchID,
 		SLO:    slo,
 		TTL:    ttl,
-		Tags:   nil,
+		Tags:   tags,
 		Spec:   batchSpecData,
 		Status: batchStatusData,
+
+		Metadata: batchReq.Metadata,
+
+		// the processing of the batch continues the trace of its creation
+		TraceContext: tracing.Inject(ctx),
 	}
 
 	_, err = c.dbClient.Store(ctx, job)
@@ -178,19 +372,30 @@ func (c *BatchApiHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
 		common.WriteInternalServerError(ctx, w)
 		return
 	}
-
-	// enqueue job
-	bjp := &api.BatchJobPriority{
-		ID:  batchID,
-		SLO: slo,
+	if !c.checkInputFileKept(w, r, batchReq.InputFileID, batchID) {
+		return
 	}
-	if err := c.queueClient.Enqueue(ctx, bjp); err != nil {
-		logger.Error(err, "failed to enqueue batch job priority")
-		if _, delErr := c.dbClient.Delete(ctx, []string{batchID}); delErr != nil {
-			logger.Error(delErr, "failed to cleanup batch job after enqueue failure", "batch_id", batchID)
+
+	// enqueue job, unless it failed at creation
+	if !batchStatus.Status.IsFinal() {
+		bjp := &api.BatchJobPriority{
+			ID:       batchID,
+			SLO:      slo,
+			Priority: sharedbatch.QueuePriority(batchSpec.Priority),
 		}
-		common.WriteInternalServerError(ctx, w)
-		return
+		if err := c.queueClient.Enqueue(ctx, bjp); err != nil {
+			logger.Error(err, "failed to enqueue batch job priority")
+			if _, delErr := c.dbClient.Delete(ctx, []string{batchID}); delErr != nil {
+				logger.Error(delErr, "failed to cleanup batch job after enqueue failure", "batch_id", batchID)
+			}
+			common.WriteInternalServerError(ctx, w)
+			return
+		}
+	}
+
+	created = true
+	if key != "" {
+		c.recordIdempotencyKey(r, key, reservation, batchID)
 	}
 
 	// construct create response
@@ -203,60 +408,469 @@ func (c *BatchApiHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
 	common.WriteJSONResponse(ctx, w, http.StatusOK, batch)
 }
 
-func (c *BatchApiHandler) ListBatches(w http.ResponseWriter, r *http.Request) {
+// reserveIdempotencyKey reserves the Idempotency-Key of a batch request for the batch it creates, and returns the
+// record of the reservation. The key is reserved atomically in the database, so of concurrent requests with the
+// same key only one creates a batch. If the key is already used, it responds like replayIdempotentRequest
+// and returns nil.
+func (c *BatchApiHandler) reserveIdempotencyKey(w http.ResponseWriter, r *http.Request, key, requestHash string) []byte {
 	ctx := r.Context()
 	logger := logging.GetRequestLogger(r)
+	recordKey := idempotencyKey(common.GetTenantIDFromContext(ctx), key)
+	reservation, _ := json.Marshal(idempotencyRecord{RequestHash: requestHash, CreatedAt: c.now().Unix()})
 
-	// Parse query parameters
-	query := r.URL.Query()
-	limit := 20
-	if limitStr := query.Get(pathParamLimit); limitStr != "" {
-		var parsedLimit int
-		if _, err := fmt.Sscanf(limitStr, "%d", &parsedLimit); err != nil {
-			apiErr := openai.NewAPIError(http.StatusBadRequest, "", "invalid limit parameter: must be an integer", nil)
-			common.WriteAPIError(ctx, w, apiErr)
-			return
+	// a stale record is replaced by the reservation, unless it was replaced concurrently
+	var current []byte
+	for range maxIdempotencyKeyAttempts {
+		reserved, err := c.dbClient.CompareAndSetIdempotencyKey(ctx, recordKey, c.config.IdempotencyKeyTTLSeconds, current, reservation)
+		if err != nil {
+			logger.Error(err, "failed to reserve idempotency key")
+			common.WriteInternalServerError(ctx, w)
+			return nil
 		}
+		if reserved {
+			return reservation
+		}
+		if current, err = c.dbClient.GetIdempotencyKey(ctx, recordKey); err != nil {
+			logger.Error(err, "failed to look up idempotency key")
+			common.WriteInternalServerError(ctx, w)
+			return nil
+		}
+		if current != nil && c.replayIdempotentRequest(w, r, current, requestHash) {
+			return nil
+		}
+	}
+	writeIdempotencyKeyInUse(ctx, w)
+	return nil
+}
 
-		if parsedLimit < 1 || parsedLimit > 100 {
-			apiErr := openai.NewAPIError(http.StatusBadRequest, "", "invalid limit parameter: must be between 1 and 100", nil)
-			common.WriteAPIError(ctx, w, apiErr)
-			return
+// replayIdempotentRequest responds with the batch created for the record of an Idempotency-Key within the TTL
+// window, or with a 409 if the key was used for a different request or its batch is still being created.
+// It returns false if the record is stale, as its batch was deleted or its reservation timed out,
+// and the batch should be created.
+func (c *BatchApiHandler) replayIdempotentRequest(w http.ResponseWriter, r *http.Request, data []byte, requestHash string) bool {
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	record := idempotencyRecord{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		logger.Error(err, "failed to unmarshal idempotency record")
+		return false
+	}
+	age := c.now().Unix() - record.CreatedAt
+	if age >= int64(c.config.IdempotencyKeyTTLSeconds) {
+		return false
+	}
+	if record.RequestHash != requestHash {
+		apiErr := openai.NewAPIError(http.StatusConflict, "", fmt.Sprintf("%s was already used with a different request", headerIdempotencyKey), nil)
+		common.WriteAPIError(ctx, w, apiErr)
+		return true
+	}
+	if record.BatchID == "" {
+		if age >= int64(idempotencyReservationTimeout.Seconds()) {
+			return false
 		}
-		limit = parsedLimit
+		writeIdempotencyKeyInUse(ctx, w)
+		return true
 	}
 
-	after := 0
-	if afterStr := query.Get(pathParamAfter); afterStr != "" {
-		var parsedAfter int
-		if _, err := fmt.Sscanf(afterStr, "%d", &parsedAfter); err != nil {
-			apiErr := openai.NewAPIError(http.StatusBadRequest, "", "invalid after parameter: must be an integer", nil)
-			common.WriteAPIError(ctx, w, apiErr)
-			return
+	// the batch may have been deleted since
+	job, err := c.getTenantJob(ctx, common.GetTenantIDFromContext(ctx), record.BatchID)
+	if err != nil {
+		logger.Error(err, "failed to get batch from database", "batch_id", record.BatchID)
+		common.WriteInternalServerError(ctx, w)
+		return true
+	}
+	if job == nil {
+		return false
+	}
+	batch, err := jobToBatch(job)
+	if err != nil {
+		logger.Error(err, "failed to convert job to batch", "batch_id", record.BatchID)
+		common.WriteInternalServerError(ctx, w)
+		return true
+	}
+	c.truncateErrors(batch)
+
+	w.Header().Set(headerIdempotentReplayed, "true")
+	common.WriteJSONResponse(ctx, w, http.StatusOK, batch)
+	return true
+}
+
+// writeIdempotencyKeyInUse rejects a batch request whose Idempotency-Key is reserved by a request in progress.
+func writeIdempotencyKeyInUse(ctx context.Context, w http.ResponseWriter) {
+	apiErr := openai.NewAPIError(http.StatusConflict, "", fmt.Sprintf("a request with the same %s is in progress", headerIdempotencyKey), nil)
+	common.WriteAPIError(ctx, w, apiErr)
+}
+
+// recordIdempotencyKey replaces the reservation of the Idempotency-Key with the batch created for it. A failure
+// is logged, as the batch was created: once the reservation times out, a retried request creates another batch.
+func (c *BatchApiHandler) recordIdempotencyKey(r *http.Request, key string, reservation []byte, batchID string) {
+	ctx := r.Context()
+	record := idempotencyRecord{}
+	_ = json.Unmarshal(reservation, &record)
+	record.BatchID = batchID
+	data, _ := json.Marshal(record)
+
+	recordKey := idempotencyKey(common.GetTenantIDFromContext(ctx), key)
+	set, err := c.dbClient.CompareAndSetIdempotencyKey(ctx, recordKey, c.config.IdempotencyKeyTTLSeconds, reservation, data)
+	if err == nil && !set {
+		err = errors.New("the reservation of the key was taken over")
+	}
+	if err != nil {
+		logging.GetRequestLogger(r).Error(err, "failed to record idempotency key", "batch_id", batchID)
+	}
+}
+
+// releaseIdempotencyKey releases the reservation of the Idempotency-Key of a request that didn't create a batch.
+// A failure is logged, the reservation then times out.
+func (c *BatchApiHandler) releaseIdempotencyKey(r *http.Request, key string, reservation []byte) {
+	// the key is released even if the request was cancelled
+	ctx := context.WithoutCancel(r.Context())
+	recordKey := idempotencyKey(common.GetTenantIDFromContext(ctx), key)
+	if _, err := c.dbClient.CompareAndDeleteIdempotencyKey(ctx, recordKey, reservation); err != nil {
+		logging.GetRequestLogger(r).Error(err, "failed to release idempotency key")
+	}
+}
+
+// validateBatch validates the input file of a batch request like the processor would, without creating the batch,
+// and responds with the request counts and the errors of the invalid lines.
+func (c *BatchApiHandler) validateBatch(w http.ResponseWriter, r *http.Request, batchReq *openai.CreateBatchRequest) {
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	scan, err := c.scanInputFile(ctx, batchReq, true)
+	if err != nil {
+		logger.Error(err, "failed to read input file", "input_file_id", batchReq.InputFileID)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	if !scan.found {
+		writeInputFileNotFound(ctx, w, batchReq.InputFileID)
+		return
+	}
+	if scan.tooManyRequests {
+		c.writeTooManyRequests(ctx, w)
+		return
+	}
+
+	validation := openai.BatchValidation{Object: "batch.validation"}
+	validation.RequestCounts.Total = scan.total
+	validation.RequestCounts.Failed = int64(len(scan.lineErrors))
+	batchErrors := &openai.BatchErrors{Object: "list", Data: scan.lineErrors}
+	if validation.RequestCounts.Total == 0 {
+		batchErrors.Data = append(batchErrors.Data, openai.BatchError{Code: "empty_file", Message: "the input file has no requests"})
+	}
+	validation.Valid = len(batchErrors.Data) == 0
+	if !validation.Valid {
+		batchErrors.Truncate(c.config.MaxBatchErrors)
+		validation.Errors = batchErrors
+	}
+
+	common.WriteJSONResponse(ctx, w, http.StatusOK, validation)
+}
+
+// checkInputFile checks the input file of a batch request before the batch is created. The batch request is
+// rejected if the input file has more requests than a batch may have, targets a model the tenant may not use,
+// or uses a custom_id on more than one line, as the output lines could not be matched to the requests.
+// It returns the errors of the lines exceeding the limits of their endpoints, or nil if none does,
+// and false if the request was rejected and a response was written.
+func (c *BatchApiHandler) checkInputFile(w http.ResponseWriter, r *http.Request, batchReq *openai.CreateBatchRequest) (*openai.BatchErrors, bool) {
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	scan, err := c.scanInputFile(ctx, batchReq, false)
+	if err != nil {
+		logger.Error(err, "failed to read input file", "input_file_id", batchReq.InputFileID)
+		common.WriteInternalServerError(ctx, w)
+		return nil, false
+	}
+	// a missing input file is failed by the processor, unless the lines must be checked against the models
+	// and the limits of the tenant
+	if !scan.found {
+		if scan.checksLines {
+			writeInputFileNotFound(ctx, w, batchReq.InputFileID)
+			return nil, false
 		}
+		return nil, true
+	}
+	if scan.tooManyRequests {
+		c.writeTooManyRequests(ctx, w)
+		return nil, false
+	}
+	if scan.disallowedLine > 0 {
+		msg := fmt.Sprintf("model %q is not allowed (line %d of the input file)", scan.disallowedModel, scan.disallowedLine)
+		common.WriteAPIError(ctx, w, openai.NewAPIError(http.StatusBadRequest, "", msg, nil))
+		return nil, false
+	}
+	if duplicates := scan.customIDs.Duplicates(); len(duplicates) > 0 {
+		common.WriteBadRequest(ctx, w, duplicateCustomIDsMessage(duplicates), "input_file_id")
+		return nil, false
+	}
+	return scan.limitErrors, true
+}
 
-		if parsedAfter < 0 {
-			apiErr := openai.NewAPIError(http.StatusBadRequest, "", "invalid after parameter: must be equal to or greater than 0", nil)
-			common.WriteAPIError(ctx, w, apiErr)
+// inputFileScan is what a single pass over the input file of a batch request found.
+type inputFileScan struct {
+	found           bool  // the tenant has the input file
+	checksLines     bool  // the lines are checked against the allowed models or the input limits
+	total           int64 // the requests read
+	tooManyRequests bool  // the file has more requests than a batch may have, the following lines were not read
+
+	customIDs       *sharedbatch.CustomIDs
+	disallowedModel interface{} // the first model the tenant may not use, and its line
+	disallowedLine  int64
+	lineErrors      []openai.BatchError // the errors of the invalid lines, as the processor would report them
+	limitErrors     *openai.BatchErrors // the errors of the lines exceeding the limits of their endpoints
+}
+
+// scanInputFile reads the input file of a batch request once, and checks its lines against the allowed models
+// of the tenant, the uniqueness of their custom_id and the input limits. The lines are only parsed like the processor
+// would if reportLines is set or if they must be checked against the models or the limits, the other lines are
+// counted and their custom_id is read without decoding the rest of the request.
+// The reading stops at the first line after MaxRequestsPerBatch, so that oversized files are not read through.
+func (c *BatchApiHandler) scanInputFile(ctx context.Context, batchReq *openai.CreateBatchRequest, reportLines bool) (*inputFileScan, error) {
+	allowedModels := c.config.AllowedModels.ForTenant(common.GetTenantIDFromContext(ctx))
+	limits := c.inputLimits().NewChecker()
+	scan := &inputFileScan{
+		checksLines: allowedModels != nil || c.inputLimits() != (sharedbatch.InputLimits{}),
+		customIDs:   sharedbatch.NewCustomIDs(),
+	}
+
+	content, closeFile, err := c.openFile(ctx, batchReq.InputFileID)
+	if err != nil || content == nil {
+		return scan, err
+	}
+	defer closeFile()
+	scan.found = true
+
+	parseLines := reportLines || scan.checksLines
+	count, err := sharedbatch.ScanLines(content, int64(max(c.config.MaxRequestsPerBatch, 0)), func(lineNum int64, data []byte) {
+		if !parseLines {
+			// lines that are not valid JSON have no custom_id, and are failed by the processor
+			line := struct {
+				CustomID string `json:"custom_id"`
+			}{}
+			if json.Unmarshal(data, &line) == nil && line.CustomID != "" {
+				scan.customIDs.Add(line.CustomID, lineNum)
+			}
 			return
 		}
-		after = parsedAfter
+
+		line, parseErr := sharedbatch.ParseRequestLine(data, batchReq.Endpoint, batchReq.MixedEndpoints, c.config.ValidationStrictness)
+		lineErr := parseErr
+		if line != nil && line.CustomID != "" {
+			if firstLine := scan.customIDs.Add(line.CustomID, lineNum); firstLine > 0 && lineErr == nil {
+				lineErr = &sharedbatch.LineError{
+					Code:    sharedbatch.LineErrorCodeDuplicateCustomID,
+					Message: fmt.Sprintf("custom_id %q is already used by line %d", line.CustomID, firstLine),
+				}
+			}
+		}
+		// the models and the limits are checked on the valid requests, the invalid ones are failed by the processor
+		if parseErr == nil {
+			if !modelAllowed(line, allowedModels) {
+				if scan.disallowedLine == 0 {
+					scan.disallowedModel, scan.disallowedLine = line.Body["model"], lineNum
+				}
+				if lineErr == nil {
+					lineErr = &sharedbatch.LineError{Code: lineErrorCodeModelNotAllowed, Message: fmt.Sprintf("model %q is not allowed", line.Body["model"])}
+				}
+			}
+			if limitErr := limits.Check(line); limitErr != nil {
+				if scan.limitErrors == nil {
+					scan.limitErrors = &openai.BatchErrors{Object: "list"}
+				}
+				scan.limitErrors.Data = append(scan.limitErrors.Data, openai.BatchError{Code: limitErr.Code, Message: limitErr.Message, Line: lineNum})
+				if lineErr == nil {
+					lineErr = limitErr
+				}
+			}
+		}
+		if lineErr != nil {
+			scan.lineErrors = append(scan.lineErrors, openai.BatchError{Code: lineErr.Code, Message: lineErr.Message, Line: lineNum})
+		}
+	})
+	scan.total, scan.tooManyRequests = count.Lines, count.ExceedsLimit
+	return scan, err
+}
+
+// writeTooManyRequests rejects a batch request whose input file has more requests than a batch may have.
+func (c *BatchApiHandler) writeTooManyRequests(ctx context.Context, w http.ResponseWriter) {
+	msg := fmt.Sprintf("The input file has more than %d requests, the maximum of a batch", c.config.MaxRequestsPerBatch)
+	common.WriteBadRequest(ctx, w, msg, "input_file_id")
+}
+
+// duplicateCustomIDsMessage lists the custom_ids used by more than one line, and the lines using them.
+func duplicateCustomIDsMessage(duplicates []sharedbatch.DuplicateCustomID) string {
+	listed := make([]string, 0, min(len(duplicates), maxReportedDuplicateCustomIDs))
+	for _, duplicate := range duplicates[:cap(listed)] {
+		lines := make([]string, len(duplicate.Lines))
+		for i, lineNum := range duplicate.Lines {
+			lines[i] = strconv.FormatInt(lineNum, 10)
+		}
+		listed = append(listed, fmt.Sprintf("%q (lines %s)", duplicate.CustomID, strings.Join(lines, ", ")))
+	}
+	msg := "custom_id must be unique within the input file, duplicated: " + strings.Join(listed, ", ")
+	if more := len(duplicates) - len(listed); more > 0 {
+		msg += fmt.Sprintf(" and %d more", more)
+	}
+	return msg
+}
+
+// inputLimits returns the limits of the lines of the batches.
+func (c *BatchApiHandler) inputLimits() sharedbatch.InputLimits {
+	return sharedbatch.InputLimits{
+		MaxBodyBytes:        c.config.MaxLineBodyBytes,
+		MaxEmbeddingsInputs: c.config.MaxEmbeddingsInputsPerBatch,
+	}
+}
+
+// modelAllowed checks the model of a request line against the allowed models. A nil list allows all models.
+func modelAllowed(line *sharedbatch.RequestLine, allowedModels []string) bool {
+	if allowedModels == nil {
+		return true
+	}
+	model, _ := line.Body["model"].(string)
+	return slices.Contains(allowedModels, model)
+}
+
+// scanFile calls onLine with the 1-based line number and the content of each non-blank line of
+// a JSONL file of the tenant, such as the input file of a batch, until onLine returns false.
+// It returns false if the tenant has no such file.
+func (c *BatchApiHandler) scanFile(ctx context.Context, fileID string, onLine func(lineNum int64, data []byte) bool) (bool, error) {
+	content, closeFile, err := c.openFile(ctx, fileID)
+	if err != nil || content == nil {
+		return content != nil, err
 	}
+	defer closeFile()
 
-	// TODO: We need a way to associate jobs to a tenant / user
-	// Request limit+1 to check if there are more results
-	jobs, _, err := c.dbClient.Get(ctx, nil, nil, api.TagsLogicalCondNa, true, after, limit+1)
+	input := bufio.NewReader(content)
+	for lineNum := int64(1); ; lineNum++ {
+		data, readErr := input.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return true, readErr
+		}
+		if data = bytes.TrimSpace(data); len(data) > 0 && !onLine(lineNum, data) {
+			return true, nil
+		}
+		if readErr != nil {
+			return true, nil
+		}
+	}
+}
+
+// openFile opens the content of a JSONL file of the tenant, decompressed if it was uploaded compressed,
+// and returns the function closing it. The content is nil if the tenant has no such file.
+func (c *BatchApiHandler) openFile(ctx context.Context, fileID string) (io.Reader, func(), error) {
+	file, err := c.getTenantFile(ctx, common.GetTenantIDFromContext(ctx), fileID)
+	if err != nil || file == nil {
+		return nil, nil, err
+	}
+
+	reader, _, err := c.filesClient.Retrieve(ctx, file.ContentLocation())
+	if errors.Is(err, filesapi.ErrFileNotFound) {
+		return nil, nil, nil
+	}
 	if err != nil {
-		logger.Error(err, "failed to list batches from database")
+		return nil, nil, err
+	}
+	closeFile := func() {}
+	if closer, ok := reader.(io.Closer); ok {
+		closeFile = func() { closer.Close() }
+	}
+
+	content, err := sharedbatch.NewInputReader(reader)
+	if err != nil {
+		closeFile()
+		return nil, nil, err
+	}
+	return content, closeFile, nil
+}
+
+// getTenantJob gets the job of the batch if it exists and belongs to the tenant, or nil otherwise.
+// The batches of other tenants are reported as not found, so that their IDs are not disclosed.
+func (c *BatchApiHandler) getTenantJob(ctx context.Context, tenantID, batchID string) (*api.BatchJob, error) {
+	jobs, _, err := c.dbClient.Get(ctx, []string{batchID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 || !slices.Contains(jobs[0].Tags, sharedbatch.TenantTag(tenantID)) {
+		return nil, nil
+	}
+	return jobs[0], nil
+}
+
+// getTenantFile gets the record of the file if it exists and belongs to the tenant, or nil otherwise.
+func (c *BatchApiHandler) getTenantFile(ctx context.Context, tenantID, fileID string) (*api.BatchFile, error) {
+	files, _, err := c.fileDBClient.Get(ctx, []string{fileID}, nil, api.TagsLogicalCondNa, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 || !slices.Contains(files[0].Tags, sharedbatch.TenantTag(tenantID)) {
+		return nil, nil
+	}
+	return files[0], nil
+}
+
+// checkInputFileKept checks that the input file of the stored batch isn't being deleted. A file deletion marks the
+// file before it looks for the batches using it, so either it finds the batch or the batch finds the mark.
+// The batch of a file being deleted is deleted, and the error is written.
+func (c *BatchApiHandler) checkInputFileKept(w http.ResponseWriter, r *http.Request, fileID, batchID string) bool {
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	deleting, err := c.statusClient.Get(ctx, sharedbatch.FileDeletionKey(fileID))
+	if err == nil && deleting == nil {
+		return true
+	}
+	if _, delErr := c.dbClient.Delete(ctx, []string{batchID}); delErr != nil {
+		logger.Error(delErr, "failed to cleanup batch job of a deleted input file", "batch_id", batchID)
+	}
+	if err != nil {
+		logger.Error(err, "failed to check the deletion of the input file", "file_id", fileID)
 		common.WriteInternalServerError(ctx, w)
+		return false
+	}
+	writeInputFileNotFound(ctx, w, fileID)
+	return false
+}
+
+func writeInputFileNotFound(ctx context.Context, w http.ResponseWriter, fileID string) {
+	apiErr := openai.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("input file %s not found", fileID), nil)
+	common.WriteAPIError(ctx, w, apiErr)
+}
+
+func (c *BatchApiHandler) ListBatches(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	page, apiErr := common.ParsePagination(r.URL.Query(), defaultListLimit, maxListLimit)
+	if apiErr != nil {
+		common.WriteAPIError(ctx, w, *apiErr)
+		return
+	}
+	metadata, apiErr := parseMetadataFilter(r.URL.Query())
+	if apiErr != nil {
+		common.WriteAPIError(ctx, w, *apiErr)
 		return
 	}
 
-	// Check if there are more results
-	hasMore := len(jobs) > limit
-	if hasMore {
-		jobs = jobs[:limit] // Trim to requested limit
+	// Request limit+1 to check if there are more results.
+	// only the batches of the tenant are listed, metadata filters select the jobs with the metadata index of the database
+	tags := []string{sharedbatch.TenantTag(common.GetTenantIDFromContext(ctx))}
+	var jobs []*api.BatchJob
+	var err error
+	if len(metadata) > 0 {
+		jobs, _, err = c.dbClient.GetByMetadata(ctx, metadata, tags, true, page.After, page.Limit+1)
+	} else {
+		jobs, _, err = c.dbClient.Get(ctx, nil, tags, api.TagsLogicalCondAnd, true, page.After, page.Limit+1)
 	}
+	if err != nil {
+		logger.Error(err, "failed to list batches from database")
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	jobs, hasMore := common.TrimPage(jobs, page.Limit)
 
 	// Convert jobs to batch responses
 	batches := make([]openai.Batch, 0, len(jobs))
@@ -266,29 +880,42 @@ func (c *BatchApiHandler) ListBatches(w http.ResponseWriter, r *http.Request) {
 			logger.Error(err, "failed to convert job to batch", "batch_id", job.ID)
 			continue
 		}
+		c.truncateErrors(batch)
 
 		batches = append(batches, *batch)
 	}
 
-	resp := openai.ListBatchResponse{
-		Object:  "list",
-		Data:    batches,
-		HasMore: hasMore,
-	}
-	if len(batches) > 0 {
-		resp.FirstID = batches[0].ID
-		resp.LastID = batches[len(batches)-1].ID
-	}
-
+	resp := common.NewListResponse(batches, hasMore, func(b openai.Batch) string { return b.ID })
 	common.WriteJSONResponse(ctx, w, http.StatusOK, resp)
 }
 
+// parseMetadataFilter parses the metadata[key]=value query parameters of a list request
+// into the metadata pairs the batches must have.
+func parseMetadataFilter(query url.Values) (map[string]string, *openai.APIError) {
+	var metadata map[string]string
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, queryParamMetadataPrefix)
+		if !ok {
+			continue
+		}
+		key, ok = strings.CutSuffix(key, "]")
+		if !ok || key == "" || len(values) != 1 {
+			apiErr := openai.NewAPIError(http.StatusBadRequest, "",
+				fmt.Sprintf("invalid %s parameter: metadata filters must be given once as metadata[key]=value", param), &param)
+			return nil, &apiErr
+		}
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadata[key] = values[0]
+	}
+	return metadata, nil
+}
+
 func (c *BatchApiHandler) RetrieveBatch(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	logger := logging.GetRequestLogger(r)
 
-	// TODO: permssion check
-
 	// extract batch_id from path
 	batchID := r.PathValue(pathParamBatchID)
 	if batchID == "" {
@@ -297,28 +924,27 @@ func (c *BatchApiHandler) RetrieveBatch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get batch from database
-	jobs, _, err := c.dbClient.Get(ctx, []string{batchID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	// Get batch of the tenant from database
+	job, err := c.getTenantJob(ctx, common.GetTenantIDFromContext(ctx), batchID)
 	if err != nil {
 		logger.Error(err, "failed to get batch from database", "batch_id", batchID)
 		common.WriteInternalServerError(ctx, w)
 		return
 	}
 
-	if len(jobs) == 0 {
+	if job == nil {
 		apiErr := openai.NewAPIError(http.StatusNotFound, "", fmt.Sprintf("Batch with ID %s not found", batchID), nil)
 		common.WriteAPIError(ctx, w, apiErr)
 		return
 	}
 
-	job := jobs[0]
-
 	batch, err := jobToBatch(job)
 	if err != nil {
 		logger.Error(err, "failed to convert job to batch", "batch_id", batchID)
 		common.WriteInternalServerError(ctx, w)
 		return
 	}
+	c.truncateErrors(batch)
 
 	common.WriteJSONResponse(ctx, w, http.StatusOK, batch)
 }
@@ -327,7 +953,6 @@ func (c *BatchApiHandler) CancelBatch(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	logger := logging.GetRequestLogger(r)
 
-	// TODO: permssion check
 	batchID := r.PathValue(pathParamBatchID)
 	if batchID == "" {
 		apiErr := openai.NewAPIError(http.StatusBadRequest, "", pathParamBatchID+" is required", nil)
@@ -335,22 +960,20 @@ func (c *BatchApiHandler) CancelBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get batch from database
-	jobs, _, err := c.dbClient.Get(ctx, []string{batchID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	// Get batch of the tenant from database
+	job, err := c.getTenantJob(ctx, common.GetTenantIDFromContext(ctx), batchID)
 	if err != nil {
 		logger.Error(err, "failed to get batch from database", "batch_id", batchID)
 		common.WriteInternalServerError(ctx, w)
 		return
 	}
 
-	if len(jobs) == 0 {
+	if job == nil {
 		apiErr := openai.NewAPIError(http.StatusNotFound, "", fmt.Sprintf("Batch with ID %s not found", batchID), nil)
 		common.WriteAPIError(ctx, w, apiErr)
 		return
 	}
 
-	job := jobs[0]
-
 	batch, err := jobToBatch(job)
 	if err != nil {
 		logger.Error(err, "failed to convert job to batch", "batch_id", batchID)
@@ -410,11 +1033,20 @@ func (c *BatchApiHandler) CancelBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	job.Status = updatedStatusData
-	if err := c.dbClient.Update(ctx, job); err != nil {
+	err = c.dbClient.Update(ctx, job)
+	if errors.Is(err, api.ErrFinalStatus) {
+		// the batch completed since it was read
+		apiErr := openai.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("Batch %s is complete and cannot be cancelled", batchID), nil)
+		common.WriteAPIError(ctx, w, apiErr)
+		return
+	}
+	if err != nil {
 		logger.Error(err, "failed to update batch in database", "batch_id", batchID)
 		common.WriteInternalServerError(ctx, w)
 		return
 	}
+	c.notifyStatusUpdate(r, batchID)
+	c.truncateErrors(batch)
 
 	common.WriteJSONResponse(ctx, w, http.StatusOK, batch)
 }
@@ -19,30 +19,81 @@ package batch
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
 	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
 	mockapi "github.com/llm-d-incubation/batch-gateway/internal/database/mock"
+	filesapi "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	mockfiles "github.com/llm-d-incubation/batch-gateway/internal/files_store/mock"
+	sharedbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
 	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
 )
 
 func setupBatchApiHandlerForTest() *BatchApiHandler {
-	config := &common.ServerConfig{}
+	config := &common.ServerConfig{CompletionWindows: []string{"24h"}}
 	dbClient := mockapi.NewMockBatchDBClient()
 	eventClient := mockapi.NewMockBatchEventChannelClient()
 	queueClient := mockapi.NewMockBatchPriorityQueueClient()
 	statusClient := mockapi.NewMockBatchStatusClient()
-	handler := NewBatchApiHandler(config, dbClient, queueClient, eventClient, statusClient)
+	fileDBClient := mockapi.NewMockBatchFileDBClient()
+	filesClient := mockfiles.NewMockBatchFilesClient()
+	handler := NewBatchApiHandler(config, dbClient, queueClient, eventClient, statusClient, fileDBClient, filesClient)
 	return handler
 }
 
+// storeInputFileForTest stores an input file of the default tenant.
+func storeInputFileForTest(t *testing.T, handler *BatchApiHandler, fileID, content string) {
+	t.Helper()
+	if _, err := handler.filesClient.Store(context.Background(), fileID, 0, bytes.NewBufferString(content)); err != nil {
+		t.Fatalf("Failed to store input file: %v", err)
+	}
+	file := &api.BatchFile{ID: fileID, TTL: 3600, Tags: []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)}}
+	if _, err := handler.fileDBClient.Store(context.Background(), file); err != nil {
+		t.Fatalf("Failed to store input file record: %v", err)
+	}
+}
+
+// retrieveCountingFilesClient counts the files retrieved from the files client.
+type retrieveCountingFilesClient struct {
+	filesapi.BatchFilesClient
+	retrieved int
+}
+
+func (c *retrieveCountingFilesClient) Retrieve(ctx context.Context, location string) (io.Reader, *filesapi.BatchFileMetadata, error) {
+	c.retrieved++
+	return c.BatchFilesClient.Retrieve(ctx, location)
+}
+
+// finalStatusDBClient rejects the updates of the jobs as final.
+type finalStatusDBClient struct {
+	api.BatchDBClient
+}
+
+func (c *finalStatusDBClient) Update(ctx context.Context, job *api.BatchJob) error {
+	return fmt.Errorf("cannot update job with ID '%s': %w", job.ID, api.ErrFinalStatus)
+}
+
+// gzipForTest returns the gzip compressed content.
+func gzipForTest(content string) string {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(content))
+	zw.Close()
+	return buf.String()
+}
+
 func TestBatchHandler(t *testing.T) {
 
 	t.Run("CreateBatch", func(t *testing.T) {
@@ -98,6 +149,1015 @@ func TestBatchHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("CreateMixedEndpointsBatch", func(t *testing.T) {
+		for _, enabled := range []bool{false, true} {
+			handler := setupBatchApiHandlerForTest()
+			handler.config.MixedEndpointBatchesEnabled = enabled
+
+			body, _ := json.Marshal(openai.CreateBatchRequest{
+				InputFileID:      "file-abc123",
+				Endpoint:         openai.EndpointChatCompletions,
+				CompletionWindow: "24h",
+				MixedEndpoints:   true,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body))
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, req)
+
+			if !enabled {
+				if rr.Code != http.StatusBadRequest {
+					t.Errorf("Expected status %d when mixed endpoints are disabled, got %d", http.StatusBadRequest, rr.Code)
+				}
+				continue
+			}
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+			var batch openai.Batch
+			if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if !batch.MixedEndpoints {
+				t.Error("Expected mixed_endpoints to be set on the batch")
+			}
+		}
+	})
+
+	t.Run("CreatePriorityBatch", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+
+		createBatch := func(priority openai.BatchPriority) (int, openai.Batch) {
+			body, _ := json.Marshal(openai.CreateBatchRequest{
+				InputFileID:      "file-abc123",
+				Endpoint:         openai.EndpointChatCompletions,
+				CompletionWindow: "24h",
+				Priority:         priority,
+			})
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body)))
+			var batch openai.Batch
+			if rr.Code == http.StatusOK {
+				if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+			}
+			return rr.Code, batch
+		}
+
+		if code, _ := createBatch("urgent"); code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for an unknown priority, got %d", http.StatusBadRequest, code)
+		}
+
+		_, normal := createBatch("")
+		if normal.Priority != openai.BatchPriorityNormal {
+			t.Errorf("Expected priority %q by default, got %q", openai.BatchPriorityNormal, normal.Priority)
+		}
+		_, low := createBatch(openai.BatchPriorityLow)
+		_, high := createBatch(openai.BatchPriorityHigh)
+
+		// the high priority batch is dequeued before the earlier batches
+		tasks, err := handler.queueClient.Dequeue(context.Background(), 0, 3)
+		if err != nil {
+			t.Fatalf("Failed to dequeue: %v", err)
+		}
+		wantIDs := []string{high.ID, normal.ID, low.ID}
+		if len(tasks) != len(wantIDs) {
+			t.Fatalf("Expected %d queued batches, got %d", len(wantIDs), len(tasks))
+		}
+		for i, id := range wantIDs {
+			if tasks[i].ID != id {
+				t.Errorf("Expected batch %s at position %d, got %s", id, i, tasks[i].ID)
+			}
+		}
+	})
+
+	t.Run("CreateBatchMetadataLimits", func(t *testing.T) {
+		tooMany := map[string]string{}
+		for i := 0; i <= openai.MaxMetadataPairs; i++ {
+			tooMany[fmt.Sprintf("key-%d", i)] = "value"
+		}
+		longKey := strings.Repeat("k", openai.MaxMetadataKeyLength+1)
+
+		tests := []struct {
+			name        string
+			metadata    map[string]string
+			wantStatus  int
+			wantMessage string
+		}{
+			{name: "within limits", metadata: map[string]string{
+				strings.Repeat("k", openai.MaxMetadataKeyLength): strings.Repeat("v", openai.MaxMetadataValueLength),
+			}, wantStatus: http.StatusOK},
+			{name: "too many keys", metadata: tooMany, wantStatus: http.StatusBadRequest, wantMessage: "at most 16 key-value pairs"},
+			{name: "overlong key", metadata: map[string]string{longKey: "value"}, wantStatus: http.StatusBadRequest, wantMessage: longKey},
+			{name: "overlong value", metadata: map[string]string{
+				"description": strings.Repeat("v", openai.MaxMetadataValueLength+1),
+			}, wantStatus: http.StatusBadRequest, wantMessage: `"description"`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupBatchApiHandlerForTest()
+
+				body, _ := json.Marshal(openai.CreateBatchRequest{
+					InputFileID:      "file-abc123",
+					Endpoint:         openai.EndpointChatCompletions,
+					CompletionWindow: "24h",
+					Metadata:         tt.metadata,
+				})
+				rr := httptest.NewRecorder()
+				handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body)))
+
+				if rr.Code != tt.wantStatus {
+					t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+				}
+				if tt.wantStatus == http.StatusOK {
+					return
+				}
+				var errResp openai.ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if errResp.Error.Param == nil || *errResp.Error.Param != "metadata" {
+					t.Errorf("Expected param metadata, got %v", errResp.Error.Param)
+				}
+				if !strings.Contains(errResp.Error.Message, tt.wantMessage) {
+					t.Errorf("Expected message to contain %q, got %q", tt.wantMessage, errResp.Error.Message)
+				}
+			})
+		}
+	})
+
+	t.Run("CreateCallbackBatch", func(t *testing.T) {
+		tests := []struct {
+			callbackURL string
+			wantStatus  int
+		}{
+			{callbackURL: "https://example.com/hooks/batch", wantStatus: http.StatusOK},
+			{callbackURL: "ftp://example.com/hooks/batch", wantStatus: http.StatusBadRequest},
+			{callbackURL: "/hooks/batch", wantStatus: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			handler := setupBatchApiHandlerForTest()
+
+			body, _ := json.Marshal(openai.CreateBatchRequest{
+				InputFileID:      "file-abc123",
+				Endpoint:         openai.EndpointChatCompletions,
+				CompletionWindow: "24h",
+				CallbackURL:      tt.callbackURL,
+			})
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body)))
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d for %q, got %d", tt.wantStatus, tt.callbackURL, rr.Code)
+				continue
+			}
+			if rr.Code == http.StatusOK {
+				var batch openai.Batch
+				if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if batch.CallbackURL != tt.callbackURL {
+					t.Errorf("Expected callback_url %q, got %q", tt.callbackURL, batch.CallbackURL)
+				}
+			}
+		}
+	})
+
+	t.Run("CreateBatchOutputExpiresAfterAnchor", func(t *testing.T) {
+		for anchor, wantStatus := range map[string]int{"created_at": http.StatusOK, "completed_at": http.StatusBadRequest} {
+			handler := setupBatchApiHandlerForTest()
+
+			body, _ := json.Marshal(openai.CreateBatchRequest{
+				InputFileID:        "file-abc123",
+				Endpoint:           openai.EndpointChatCompletions,
+				CompletionWindow:   "24h",
+				OutputExpiresAfter: &openai.OutputExpiresAfter{Anchor: anchor, Seconds: 3600},
+			})
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body)))
+
+			if rr.Code != wantStatus {
+				t.Errorf("Expected status %d for anchor %q, got %d", wantStatus, anchor, rr.Code)
+			}
+		}
+	})
+
+	t.Run("CreateBatchValidationErrors", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+
+		body, _ := json.Marshal(openai.CreateBatchRequest{
+			Endpoint:           "/v1/unknown",
+			CompletionWindow:   "one day",
+			OutputExpiresAfter: &openai.OutputExpiresAfter{Anchor: "created_at", Seconds: 60},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.CreateBatch(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+		var errResp openai.ErrorResponse
+		if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+
+		// every invalid field is reported
+		wantParams := []string{"completion_window", "endpoint", "input_file_id", "output_expires_after.seconds"}
+		if len(errResp.Error.Errors) != len(wantParams) {
+			t.Fatalf("Expected %d field errors, got %+v", len(wantParams), errResp.Error.Errors)
+		}
+		for i, param := range wantParams {
+			if errResp.Error.Errors[i].Param != param || errResp.Error.Errors[i].Message == "" {
+				t.Errorf("Expected an error for %s, got %+v", param, errResp.Error.Errors[i])
+			}
+		}
+		if errResp.Error.Param == nil || *errResp.Error.Param != wantParams[0] {
+			t.Errorf("Expected param %q, got %v", wantParams[0], errResp.Error.Param)
+		}
+	})
+
+	t.Run("CompletionWindow", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			window     string
+			wantStatus int
+		}{
+			{name: "documented form", window: "24h", wantStatus: http.StatusOK},
+			{name: "whitespace and uppercase", window: " 24H ", wantStatus: http.StatusOK},
+			{name: "nonsense", window: "forever", wantStatus: http.StatusBadRequest},
+			{name: "blank", window: "  ", wantStatus: http.StatusBadRequest},
+			{name: "negative duration", window: "-24h", wantStatus: http.StatusBadRequest},
+			{name: "duration not offered", window: "48h", wantStatus: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupBatchApiHandlerForTest()
+
+				body, _ := json.Marshal(openai.CreateBatchRequest{
+					InputFileID:      "file-abc123",
+					Endpoint:         openai.EndpointChatCompletions,
+					CompletionWindow: tt.window,
+				})
+				rr := httptest.NewRecorder()
+				handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body)))
+
+				if rr.Code != tt.wantStatus {
+					t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+				}
+				if tt.wantStatus != http.StatusOK {
+					var errResp openai.ErrorResponse
+					if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+						t.Fatalf("Failed to decode response body: %v", err)
+					}
+					if errResp.Error.Param == nil || *errResp.Error.Param != "completion_window" {
+						t.Errorf("Expected param completion_window, got %v", errResp.Error.Param)
+					}
+					return
+				}
+
+				// the batch reports the normalized window
+				var batch openai.Batch
+				if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if batch.CompletionWindow != "24h" {
+					t.Errorf("Expected completion_window 24h, got %q", batch.CompletionWindow)
+				}
+			})
+		}
+	})
+
+	t.Run("ValidateOnly", func(t *testing.T) {
+		validLine := `{"custom_id":"req-%d","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[]}}` + "\n"
+		valid := fmt.Sprintf(validLine, 1) + fmt.Sprintf(validLine, 2) + "\n" + fmt.Sprintf(validLine, 3)
+		malformed := fmt.Sprintf(validLine, 1) +
+			"not json\n" +
+			`{"custom_id":"req-3","method":"POST","url":"/v1/embeddings","body":{"model":"m","input":"x"}}` + "\n" +
+			`{"custom_id":"req-4","method":"POST","url":"/v1/chat/completions","body":{"messages":[]}}` + "\n"
+
+		tests := []struct {
+			name       string
+			content    string
+			wantValid  bool
+			wantTotal  int64
+			wantErrors []openai.BatchError
+		}{
+			{name: "valid file", content: valid, wantValid: true, wantTotal: 3},
+			{name: "gzip compressed file", content: gzipForTest(valid), wantValid: true, wantTotal: 3},
+			{
+				name:      "malformed lines",
+				content:   malformed,
+				wantTotal: 4,
+				wantErrors: []openai.BatchError{
+					{Code: sharedbatch.LineErrorCodeInvalidJSON, Line: 2},
+					{Code: sharedbatch.LineErrorCodeInvalidRequest, Line: 3},
+					{Code: sharedbatch.LineErrorCodeInvalidRequest, Line: 4},
+				},
+			},
+			{
+				name:      "duplicate custom_id",
+				content:   fmt.Sprintf(validLine, 1) + fmt.Sprintf(validLine, 2) + fmt.Sprintf(validLine, 1),
+				wantTotal: 3,
+				wantErrors: []openai.BatchError{
+					{Code: sharedbatch.LineErrorCodeDuplicateCustomID, Line: 3},
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupBatchApiHandlerForTest()
+				storeInputFileForTest(t, handler, "file-input", tt.content)
+
+				body, _ := json.Marshal(openai.CreateBatchRequest{
+					InputFileID:      "file-input",
+					Endpoint:         openai.EndpointChatCompletions,
+					CompletionWindow: "24h",
+				})
+				req := httptest.NewRequest(http.MethodPost, "/v1/batches?validate_only=true", bytes.NewReader(body))
+				rr := httptest.NewRecorder()
+				handler.CreateBatch(rr, req)
+
+				if rr.Code != http.StatusOK {
+					t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+				}
+				var validation openai.BatchValidation
+				if err := json.NewDecoder(rr.Body).Decode(&validation); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if validation.Valid != tt.wantValid || validation.RequestCounts.Total != tt.wantTotal ||
+					validation.RequestCounts.Failed != int64(len(tt.wantErrors)) {
+					t.Errorf("Unexpected validation result: %+v", validation)
+				}
+				var gotErrors []openai.BatchError
+				if validation.Errors != nil {
+					gotErrors = validation.Errors.Data
+				}
+				if len(gotErrors) != len(tt.wantErrors) {
+					t.Fatalf("Expected %d errors, got %+v", len(tt.wantErrors), gotErrors)
+				}
+				for i, want := range tt.wantErrors {
+					if gotErrors[i].Code != want.Code || gotErrors[i].Line != want.Line || gotErrors[i].Message == "" {
+						t.Errorf("Expected error %+v, got %+v", want, gotErrors[i])
+					}
+				}
+
+				// nothing was persisted or enqueued
+				jobs, _, err := handler.dbClient.Get(context.Background(), nil, nil, api.TagsLogicalCondNa, true, 0, 10)
+				if err != nil || len(jobs) != 0 {
+					t.Errorf("Expected no batch to be stored, got %d (err %v)", len(jobs), err)
+				}
+			})
+		}
+
+		t.Run("unknown input file", func(t *testing.T) {
+			handler := setupBatchApiHandlerForTest()
+			body, _ := json.Marshal(openai.CreateBatchRequest{
+				InputFileID:      "file-missing",
+				Endpoint:         openai.EndpointChatCompletions,
+				CompletionWindow: "24h",
+			})
+			req := httptest.NewRequest(http.MethodPost, "/v1/batches?validate_only=true", bytes.NewReader(body))
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+		})
+	})
+
+	t.Run("AllowedModels", func(t *testing.T) {
+		line := `{"custom_id":"req-%d","method":"POST","url":"/v1/chat/completions","body":{"model":"%s","messages":[]}}` + "\n"
+
+		tests := []struct {
+			name       string
+			models     []string
+			wantStatus int
+			wantMsg    string
+		}{
+			{name: "allowed", models: []string{"m1", "m1"}, wantStatus: http.StatusOK},
+			{name: "disallowed", models: []string{"m2"}, wantStatus: http.StatusBadRequest, wantMsg: `model "m2" is not allowed (line 1 of the input file)`},
+			{name: "mixed models", models: []string{"m1", "m1", "m3", "m2"}, wantStatus: http.StatusBadRequest, wantMsg: `model "m3" is not allowed (line 3 of the input file)`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupBatchApiHandlerForTest()
+				handler.config.AllowedModels = common.ModelAllowlist{Models: []string{"m1"}}
+
+				content := ""
+				for i, model := range tt.models {
+					content += fmt.Sprintf(line, i, model)
+				}
+				storeInputFileForTest(t, handler, "file-input", content)
+
+				body, _ := json.Marshal(openai.CreateBatchRequest{
+					InputFileID:      "file-input",
+					Endpoint:         openai.EndpointChatCompletions,
+					CompletionWindow: "24h",
+				})
+				req := httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body))
+				rr := httptest.NewRecorder()
+				handler.CreateBatch(rr, req)
+
+				if rr.Code != tt.wantStatus {
+					t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+				}
+				if tt.wantMsg == "" {
+					return
+				}
+				var errResp openai.ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if errResp.Error.Message != tt.wantMsg {
+					t.Errorf("Expected error message %q, got %q", tt.wantMsg, errResp.Error.Message)
+				}
+				jobs, _, _ := handler.dbClient.Get(context.Background(), nil, nil, api.TagsLogicalCondNa, true, 0, 10)
+				if len(jobs) != 0 {
+					t.Errorf("Expected no batch to be stored, got %d", len(jobs))
+				}
+			})
+		}
+	})
+
+	t.Run("DuplicateCustomIDs", func(t *testing.T) {
+		line := `{"custom_id":"%s","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[]}}` + "\n"
+
+		tests := []struct {
+			name       string
+			customIDs  []string
+			wantStatus int
+			wantMsg    string
+		}{
+			{name: "unique", customIDs: []string{"a", "b", "c"}, wantStatus: http.StatusOK},
+			{
+				name:       "duplicate",
+				customIDs:  []string{"a", "b", "a", "c", "a"},
+				wantStatus: http.StatusBadRequest,
+				wantMsg:    `custom_id must be unique within the input file, duplicated: "a" (lines 1, 3, 5)`,
+			},
+			{
+				name:       "several duplicates",
+				customIDs:  []string{"a", "b", "b", "a"},
+				wantStatus: http.StatusBadRequest,
+				wantMsg:    `custom_id must be unique within the input file, duplicated: "b" (lines 2, 3), "a" (lines 1, 4)`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupBatchApiHandlerForTest()
+				content := ""
+				for _, customID := range tt.customIDs {
+					content += fmt.Sprintf(line, customID)
+				}
+				storeInputFileForTest(t, handler, "file-input", content)
+
+				body, _ := json.Marshal(openai.CreateBatchRequest{
+					InputFileID:      "file-input",
+					Endpoint:         openai.EndpointChatCompletions,
+					CompletionWindow: "24h",
+				})
+				req := httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body))
+				rr := httptest.NewRecorder()
+				handler.CreateBatch(rr, req)
+
+				if rr.Code != tt.wantStatus {
+					t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+				}
+				if tt.wantMsg == "" {
+					return
+				}
+				var errResp openai.ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if errResp.Error.Message != tt.wantMsg {
+					t.Errorf("Expected error message %q, got %q", tt.wantMsg, errResp.Error.Message)
+				}
+				if errResp.Error.Param == nil || *errResp.Error.Param != "input_file_id" {
+					t.Errorf("Expected param input_file_id, got %v", errResp.Error.Param)
+				}
+				jobs, _, _ := handler.dbClient.Get(context.Background(), nil, nil, api.TagsLogicalCondNa, true, 0, 10)
+				if len(jobs) != 0 {
+					t.Errorf("Expected no batch to be stored, got %d", len(jobs))
+				}
+			})
+		}
+	})
+
+	t.Run("MaxRequestsPerBatch", func(t *testing.T) {
+		line := `{"custom_id":"req-%d","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[]}}` + "\n"
+
+		tests := []struct {
+			name         string
+			lines        int
+			validateOnly bool
+			wantStatus   int
+		}{
+			{name: "at the limit", lines: 3, wantStatus: http.StatusOK},
+			{name: "over the limit", lines: 4, wantStatus: http.StatusBadRequest},
+			{name: "validate only over the limit", lines: 4, validateOnly: true, wantStatus: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupBatchApiHandlerForTest()
+				handler.config.MaxRequestsPerBatch = 3
+				content := ""
+				for i := range tt.lines {
+					content += fmt.Sprintf(line, i)
+				}
+				storeInputFileForTest(t, handler, "file-input", strings.TrimSuffix(content, "\n"))
+
+				body, _ := json.Marshal(openai.CreateBatchRequest{
+					InputFileID:      "file-input",
+					Endpoint:         openai.EndpointChatCompletions,
+					CompletionWindow: "24h",
+				})
+				target := "/v1/batches"
+				if tt.validateOnly {
+					target += "?validate_only=true"
+				}
+				req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+				rr := httptest.NewRecorder()
+				handler.CreateBatch(rr, req)
+
+				if rr.Code != tt.wantStatus {
+					t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+				}
+				if tt.wantStatus != http.StatusBadRequest {
+					return
+				}
+				var errResp openai.ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if want := "The input file has more than 3 requests, the maximum of a batch"; errResp.Error.Message != want {
+					t.Errorf("Expected error message %q, got %q", want, errResp.Error.Message)
+				}
+				jobs, _, _ := handler.dbClient.Get(context.Background(), nil, nil, api.TagsLogicalCondNa, true, 0, 10)
+				if len(jobs) != 0 {
+					t.Errorf("Expected no batch to be stored, got %d", len(jobs))
+				}
+			})
+		}
+	})
+
+	t.Run("InputFileReadOnce", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		handler.config.MaxRequestsPerBatch = 10
+		handler.config.AllowedModels = common.ModelAllowlist{Models: []string{"m"}}
+		handler.config.MaxLineBodyBytes = 1000
+		storeInputFileForTest(t, handler, "file-input",
+			`{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[]}}`)
+		files := &retrieveCountingFilesClient{BatchFilesClient: handler.filesClient}
+		handler.filesClient = files
+
+		body, _ := json.Marshal(openai.CreateBatchRequest{
+			InputFileID:      "file-input",
+			Endpoint:         openai.EndpointChatCompletions,
+			CompletionWindow: "24h",
+		})
+		for _, target := range []string{"/v1/batches?validate_only=true", "/v1/batches"} {
+			files.retrieved = 0
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body)))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+			// the request count, the models, the custom_ids and the limits are checked in a single pass
+			if files.retrieved != 1 {
+				t.Errorf("Expected the input file to be read once by %s, got %d", target, files.retrieved)
+			}
+		}
+	})
+
+	t.Run("InputFileBeingDeleted", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		storeInputFileForTest(t, handler, "file-input",
+			`{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[]}}`)
+		// the file was marked by a concurrent DeleteFile
+		key := sharedbatch.FileDeletionKey("file-input")
+		if err := handler.statusClient.Set(context.Background(), key, sharedbatch.FileDeletionTTLSeconds, []byte("file-input")); err != nil {
+			t.Fatalf("Failed to mark the input file: %v", err)
+		}
+
+		body, _ := json.Marshal(openai.CreateBatchRequest{
+			InputFileID:      "file-input",
+			Endpoint:         openai.EndpointChatCompletions,
+			CompletionWindow: "24h",
+		})
+		rr := httptest.NewRecorder()
+		handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+		}
+		jobs, _, _ := handler.dbClient.Get(context.Background(), nil, nil, api.TagsLogicalCondNa, true, 0, 10)
+		if len(jobs) != 0 {
+			t.Errorf("Expected no batch on a file being deleted, got %d", len(jobs))
+		}
+	})
+
+	t.Run("InputLimits", func(t *testing.T) {
+		embeddingsLine := `{"custom_id":"req-%d","method":"POST","url":"/v1/embeddings","body":{"model":"m","input":%s}}` + "\n"
+		chatLine := `{"custom_id":"req-%d","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[{"role":"user","content":%q}]}}` + "\n"
+
+		tests := []struct {
+			name       string
+			endpoint   openai.Endpoint
+			content    string
+			wantErrors []openai.BatchError
+		}{
+			{
+				name:     "within limits",
+				endpoint: openai.EndpointEmbeddings,
+				content:  fmt.Sprintf(embeddingsLine, 1, `["a","b"]`) + fmt.Sprintf(embeddingsLine, 2, `[1,2,3,4,5]`) + fmt.Sprintf(embeddingsLine, 3, `"c"`),
+			},
+			{
+				// the token array of line 1 is a single input
+				name:     "oversized embeddings batch",
+				endpoint: openai.EndpointEmbeddings,
+				content: fmt.Sprintf(embeddingsLine, 1, `[1,2,3]`) + fmt.Sprintf(embeddingsLine, 2, `["a","b"]`) +
+					fmt.Sprintf(embeddingsLine, 3, `["c","d"]`) + fmt.Sprintf(embeddingsLine, 4, `"e"`),
+				wantErrors: []openai.BatchError{{Code: sharedbatch.LineErrorCodeTooManyInputs, Line: 3}},
+			},
+			{
+				name:       "oversized line body",
+				endpoint:   openai.EndpointChatCompletions,
+				content:    fmt.Sprintf(chatLine, 1, "hi") + fmt.Sprintf(chatLine, 2, strings.Repeat("x", 200)) + fmt.Sprintf(chatLine, 3, "hi"),
+				wantErrors: []openai.BatchError{{Code: sharedbatch.LineErrorCodeBodyTooLarge, Line: 2}},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupBatchApiHandlerForTest()
+				handler.config.MaxLineBodyBytes = 150
+				handler.config.MaxEmbeddingsInputsPerBatch = 4
+				handler.config.MaxBatchErrors = 10
+				storeInputFileForTest(t, handler, "file-input", tt.content)
+				body, _ := json.Marshal(openai.CreateBatchRequest{
+					InputFileID:      "file-input",
+					Endpoint:         tt.endpoint,
+					CompletionWindow: "24h",
+				})
+
+				checkErrors := func(batchErrors *openai.BatchErrors) {
+					t.Helper()
+					var gotErrors []openai.BatchError
+					if batchErrors != nil {
+						gotErrors = batchErrors.Data
+					}
+					if len(gotErrors) != len(tt.wantErrors) {
+						t.Fatalf("Expected %d errors, got %+v", len(tt.wantErrors), gotErrors)
+					}
+					for i, want := range tt.wantErrors {
+						if gotErrors[i].Code != want.Code || gotErrors[i].Line != want.Line || gotErrors[i].Message == "" {
+							t.Errorf("Expected error %+v, got %+v", want, gotErrors[i])
+						}
+					}
+				}
+
+				// the validation reports the lines exceeding the limits
+				rr := httptest.NewRecorder()
+				handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, "/v1/batches?validate_only=true", bytes.NewReader(body)))
+				var validation openai.BatchValidation
+				if err := json.NewDecoder(rr.Body).Decode(&validation); err != nil {
+					t.Fatalf("Failed to decode validation: %v", err)
+				}
+				if validation.Valid != (len(tt.wantErrors) == 0) {
+					t.Errorf("Unexpected validation result: %+v", validation)
+				}
+				checkErrors(validation.Errors)
+
+				// a batch exceeding them is created failed, and isn't processed
+				rr = httptest.NewRecorder()
+				handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body)))
+				if rr.Code != http.StatusOK {
+					t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+				}
+				var batch openai.Batch
+				if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				checkErrors(batch.Errors)
+				wantStatus, wantQueued := openai.BatchStatusValidating, 1
+				if len(tt.wantErrors) > 0 {
+					wantStatus, wantQueued = openai.BatchStatusFailed, 0
+					if batch.FailedAt == nil {
+						t.Errorf("Expected failed_at to be set")
+					}
+				}
+				if batch.Status != wantStatus {
+					t.Errorf("Expected status %s, got %s", wantStatus, batch.Status)
+				}
+				if n, _ := handler.queueClient.Len(context.Background()); n != wantQueued {
+					t.Errorf("Expected %d queued batches, got %d", wantQueued, n)
+				}
+				// only the batches to process are tagged active, for the processors to recover them
+				jobs, _, _ := handler.dbClient.Get(context.Background(), []string{batch.ID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+				if len(jobs) != 1 || slices.Contains(jobs[0].Tags, sharedbatch.ActiveJobTag) != (wantQueued == 1) {
+					t.Errorf("Expected the batch to be tagged active: %v, got %+v", wantQueued == 1, jobs)
+				}
+
+				// the errors are kept with the batch
+				rr = httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "/v1/batches/"+batch.ID, nil)
+				req.SetPathValue(pathParamBatchID, batch.ID)
+				handler.RetrieveBatch(rr, req)
+				var retrieved openai.Batch
+				if err := json.NewDecoder(rr.Body).Decode(&retrieved); err != nil {
+					t.Fatalf("Failed to decode retrieved batch: %v", err)
+				}
+				checkErrors(retrieved.Errors)
+			})
+		}
+	})
+
+	t.Run("IdempotencyKey", func(t *testing.T) {
+		createBatch := func(handler *BatchApiHandler, key, inputFileID string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(openai.CreateBatchRequest{
+				InputFileID:      inputFileID,
+				Endpoint:         openai.EndpointChatCompletions,
+				CompletionWindow: "24h",
+			})
+			req := httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body))
+			req.Header.Set(headerIdempotencyKey, key)
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, req)
+			return rr
+		}
+		decodeBatchID := func(t *testing.T, rr *httptest.ResponseRecorder) string {
+			t.Helper()
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+			var batch openai.Batch
+			if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			return batch.ID
+		}
+		setup := func() *BatchApiHandler {
+			handler := setupBatchApiHandlerForTest()
+			handler.config.IdempotencyKeyTTLSeconds = 3600
+			return handler
+		}
+
+		t.Run("replay", func(t *testing.T) {
+			handler := setup()
+			first := createBatch(handler, "key-1", "file-abc123")
+			if first.Header().Get(headerIdempotentReplayed) != "" {
+				t.Errorf("Expected first request not to be marked as replayed")
+			}
+			firstID := decodeBatchID(t, first)
+
+			second := createBatch(handler, "key-1", "file-abc123")
+			if got := second.Header().Get(headerIdempotentReplayed); got != "true" {
+				t.Errorf("Expected %s header to be 'true', got %q", headerIdempotentReplayed, got)
+			}
+			if secondID := decodeBatchID(t, second); secondID != firstID {
+				t.Errorf("Expected replayed batch %s, got %s", firstID, secondID)
+			}
+
+			jobs, _, err := handler.dbClient.Get(context.Background(), nil, nil, api.TagsLogicalCondNa, true, 0, 10)
+			if err != nil {
+				t.Fatalf("Failed to list batches: %v", err)
+			}
+			if len(jobs) != 1 {
+				t.Errorf("Expected 1 stored batch, got %d", len(jobs))
+			}
+
+			// a different key creates a new batch
+			if otherID := decodeBatchID(t, createBatch(handler, "key-2", "file-abc123")); otherID == firstID {
+				t.Errorf("Expected a new batch for a different key")
+			}
+		})
+
+		t.Run("conflict", func(t *testing.T) {
+			handler := setup()
+			decodeBatchID(t, createBatch(handler, "key-1", "file-abc123"))
+
+			rr := createBatch(handler, "key-1", "file-other")
+			if rr.Code != http.StatusConflict {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusConflict, rr.Code, rr.Body.String())
+			}
+		})
+
+		t.Run("expiry", func(t *testing.T) {
+			handler := setup()
+			firstID := decodeBatchID(t, createBatch(handler, "key-1", "file-abc123"))
+
+			handler.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+			rr := createBatch(handler, "key-1", "file-other")
+			if secondID := decodeBatchID(t, rr); secondID == firstID {
+				t.Errorf("Expected a new batch after the key expired")
+			}
+		})
+
+		t.Run("concurrent retries", func(t *testing.T) {
+			handler := setup()
+			var wg sync.WaitGroup
+			responses := make([]*httptest.ResponseRecorder, 10)
+			for i := range responses {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					responses[i] = createBatch(handler, "key-1", "file-abc123")
+				}()
+			}
+			wg.Wait()
+
+			// the retries waiting for the first request are rejected, the others replay its batch
+			for _, rr := range responses {
+				if rr.Code != http.StatusOK && rr.Code != http.StatusConflict {
+					t.Errorf("Expected status %d or %d, got %d: %s", http.StatusOK, http.StatusConflict, rr.Code, rr.Body.String())
+				}
+			}
+			jobs, _, err := handler.dbClient.Get(context.Background(), nil, nil, api.TagsLogicalCondNa, true, 0, 10)
+			if err != nil {
+				t.Fatalf("Failed to list batches: %v", err)
+			}
+			if len(jobs) != 1 {
+				t.Errorf("Expected 1 stored batch, got %d", len(jobs))
+			}
+		})
+
+		t.Run("in progress", func(t *testing.T) {
+			handler := setup()
+			batchReq := &openai.CreateBatchRequest{
+				InputFileID:      "file-abc123",
+				Endpoint:         openai.EndpointChatCompletions,
+				CompletionWindow: "24h",
+			}
+			reservation, _ := json.Marshal(idempotencyRecord{RequestHash: hashBatchRequest(batchReq), CreatedAt: time.Now().Unix()})
+			recordKey := idempotencyKey(sharedbatch.DefaultTenantID, "key-1")
+			if _, err := handler.dbClient.CompareAndSetIdempotencyKey(context.Background(), recordKey, 3600, nil, reservation); err != nil {
+				t.Fatalf("Failed to reserve idempotency key: %v", err)
+			}
+
+			if rr := createBatch(handler, "key-1", "file-abc123"); rr.Code != http.StatusConflict {
+				t.Errorf("Expected status %d while the key is reserved, got %d: %s", http.StatusConflict, rr.Code, rr.Body.String())
+			}
+
+			// the reservation of a request that didn't complete is taken over
+			handler.now = func() time.Time { return time.Now().Add(2 * idempotencyReservationTimeout) }
+			decodeBatchID(t, createBatch(handler, "key-1", "file-abc123"))
+		})
+
+		t.Run("released on failure", func(t *testing.T) {
+			handler := setup()
+			handler.config.AllowedModels = common.ModelAllowlist{Models: []string{"m"}}
+			if rr := createBatch(handler, "key-1", "file-input"); rr.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+			}
+
+			// the retry with the input file creates the batch
+			storeInputFileForTest(t, handler, "file-input",
+				`{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[]}}`)
+			decodeBatchID(t, createBatch(handler, "key-1", "file-input"))
+		})
+
+		t.Run("key too long", func(t *testing.T) {
+			handler := setup()
+			rr := createBatch(handler, strings.Repeat("k", maxIdempotencyKeyLength+1), "file-abc123")
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+		})
+	})
+
+	t.Run("ActiveBatchQuota", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		handler.config.MaxActiveBatchesPerTenant = 2
+
+		createBatch := func(tenantID string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(openai.CreateBatchRequest{
+				InputFileID:      "file-abc123",
+				Endpoint:         openai.EndpointChatCompletions,
+				CompletionWindow: "24h",
+			})
+			req := httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body))
+			req = req.WithContext(common.WithTenantID(req.Context(), tenantID))
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, req)
+			return rr
+		}
+
+		var batchIDs []string
+		for i := 0; i < 2; i++ {
+			rr := createBatch("tenant-a")
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+			var batch openai.Batch
+			if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			batchIDs = append(batchIDs, batch.ID)
+		}
+
+		// the batch above the quota is rejected
+		if rr := createBatch("tenant-a"); rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("Expected status %d above the quota, got %d: %s", http.StatusTooManyRequests, rr.Code, rr.Body.String())
+		}
+
+		// the quota is per tenant
+		if rr := createBatch("tenant-b"); rr.Code != http.StatusOK {
+			t.Errorf("Expected status %d for another tenant, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		// a completed batch frees its place in the quota
+		status, _ := json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusCompleted})
+		if err := handler.dbClient.Update(context.Background(), &api.BatchJob{ID: batchIDs[0], Status: status}); err != nil {
+			t.Fatalf("Failed to update batch: %v", err)
+		}
+		if rr := createBatch("tenant-a"); rr.Code != http.StatusOK {
+			t.Errorf("Expected status %d once a batch completed, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		if rr := createBatch("tenant-a"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected status %d above the quota, got %d: %s", http.StatusTooManyRequests, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("TenantScoping", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		withTenant := func(req *http.Request, tenantID string) *http.Request {
+			return req.WithContext(common.WithTenantID(req.Context(), tenantID))
+		}
+		createBatch := func(tenantID string) string {
+			body, _ := json.Marshal(openai.CreateBatchRequest{
+				InputFileID:      "file-abc123",
+				Endpoint:         openai.EndpointChatCompletions,
+				CompletionWindow: "24h",
+				Metadata:         map[string]string{"team": "search"},
+			})
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, withTenant(httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body)), tenantID))
+			var batch openai.Batch
+			if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil || rr.Code != http.StatusOK {
+				t.Fatalf("Failed to create batch: %d %v", rr.Code, err)
+			}
+			return batch.ID
+		}
+		batchA := createBatch("tenant-a")
+		batchB := createBatch("tenant-b")
+
+		t.Run("retrieve", func(t *testing.T) {
+			for tenantID, wantCode := range map[string]int{"tenant-a": http.StatusOK, "tenant-b": http.StatusNotFound} {
+				req := withTenant(httptest.NewRequest(http.MethodGet, "/v1/batches/"+batchA, nil), tenantID)
+				req.SetPathValue(pathParamBatchID, batchA)
+				rr := httptest.NewRecorder()
+				handler.RetrieveBatch(rr, req)
+				if rr.Code != wantCode {
+					t.Errorf("Expected status %d for %s, got %d", wantCode, tenantID, rr.Code)
+				}
+			}
+		})
+
+		t.Run("cancel", func(t *testing.T) {
+			req := withTenant(httptest.NewRequest(http.MethodPost, "/v1/batches/"+batchA+"/cancel", nil), "tenant-b")
+			req.SetPathValue(pathParamBatchID, batchA)
+			rr := httptest.NewRecorder()
+			handler.CancelBatch(rr, req)
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+			}
+
+			jobs, _, _ := handler.dbClient.Get(context.Background(), []string{batchA}, nil, api.TagsLogicalCondNa, true, 0, 1)
+			batch, err := jobToBatch(jobs[0])
+			if err != nil {
+				t.Fatalf("Failed to convert job: %v", err)
+			}
+			if batch.Status != openai.BatchStatusValidating {
+				t.Errorf("Expected the batch of the other tenant to stay %s, got %s", openai.BatchStatusValidating, batch.Status)
+			}
+		})
+
+		t.Run("list", func(t *testing.T) {
+			for _, target := range []string{"/v1/batches", "/v1/batches?metadata[team]=search"} {
+				for tenantID, wantID := range map[string]string{"tenant-a": batchA, "tenant-b": batchB} {
+					rr := httptest.NewRecorder()
+					handler.ListBatches(rr, withTenant(httptest.NewRequest(http.MethodGet, target, nil), tenantID))
+					var resp openai.ListResponse[openai.Batch]
+					if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+						t.Fatalf("Failed to decode response body: %v", err)
+					}
+					if len(resp.Data) != 1 || resp.Data[0].ID != wantID {
+						t.Errorf("Expected %s to list only batch %s, got %+v", tenantID, wantID, resp.Data)
+					}
+				}
+			}
+		})
+	})
+
 	t.Run("RetrieveBatch", func(t *testing.T) {
 		handler := setupBatchApiHandlerForTest()
 		dbClient := handler.dbClient
@@ -122,7 +1182,7 @@ func TestBatchHandler(t *testing.T) {
 			ID:     batchID,
 			SLO:    time.Now().UTC().Add(24 * time.Hour),
 			TTL:    86400,
-			Tags:   []string{},
+			Tags:   []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)},
 			Spec:   specData,
 			Status: statusData,
 		})
@@ -152,6 +1212,72 @@ func TestBatchHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("RetrieveBatchTruncatesErrors", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		handler.config.MaxBatchErrors = 10
+		dbClient := handler.dbClient
+
+		// create a failed batch with more errors than the cap
+		batchID := "batch-test-errors"
+		numErrors := 25
+		batchErrors := &openai.BatchErrors{Object: "list"}
+		for i := 1; i <= numErrors; i++ {
+			batchErrors.Data = append(batchErrors.Data, openai.BatchError{
+				Code:    "invalid_request",
+				Message: fmt.Sprintf("invalid request on line %d", i),
+				Line:    int64(i),
+			})
+		}
+		specData, _ := json.Marshal(openai.BatchSpec{
+			InputFileID:      "file-abc123",
+			Endpoint:         openai.EndpointChatCompletions,
+			CompletionWindow: "24h",
+		})
+		statusData, _ := json.Marshal(openai.BatchStatusInfo{
+			Status: openai.BatchStatusFailed,
+			Errors: batchErrors,
+		})
+		dbClient.Store(context.Background(), &api.BatchJob{
+			ID:     batchID,
+			SLO:    time.Now().UTC().Add(24 * time.Hour),
+			TTL:    86400,
+			Tags:   []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)},
+			Spec:   specData,
+			Status: statusData,
+		})
+
+		// get batch
+		req := httptest.NewRequest(http.MethodGet, "/v1/batches/"+batchID, nil)
+		req.SetPathValue("batch_id", batchID)
+		rr := httptest.NewRecorder()
+		handler.RetrieveBatch(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+
+		var batch openai.Batch
+		if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+
+		if batch.Errors == nil {
+			t.Fatal("Expected errors in the response")
+		}
+		if len(batch.Errors.Data) != 10 {
+			t.Errorf("Expected 10 errors, got %d", len(batch.Errors.Data))
+		}
+		if !batch.Errors.Truncated {
+			t.Error("Expected errors to be flagged as truncated")
+		}
+		if batch.Errors.Total != numErrors {
+			t.Errorf("Expected total errors to be %d, got %d", numErrors, batch.Errors.Total)
+		}
+		if batch.Errors.Data[0].Line != 1 {
+			t.Errorf("Expected the first errors to be kept, got line %d", batch.Errors.Data[0].Line)
+		}
+	})
+
 	t.Run("ListBatches", func(t *testing.T) {
 		handler := setupBatchApiHandlerForTest()
 		dbClient := handler.dbClient
@@ -177,7 +1303,7 @@ func TestBatchHandler(t *testing.T) {
 				ID:     batchID,
 				SLO:    time.Now().UTC().Add(24 * time.Hour),
 				TTL:    86400,
-				Tags:   []string{},
+				Tags:   []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)},
 				Spec:   specData,
 				Status: statusData,
 			})
@@ -221,6 +1347,96 @@ func TestBatchHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("ListBatchesByMetadata", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+
+		batchIDs := map[string]string{}
+		for name, metadata := range map[string]map[string]string{
+			"a-prod": {"team": "a", "env": "prod"},
+			"a-dev":  {"team": "a", "env": "dev"},
+			"b-prod": {"team": "b", "env": "prod"},
+			"none":   nil,
+		} {
+			body, _ := json.Marshal(openai.CreateBatchRequest{
+				InputFileID:      "file-abc123",
+				Endpoint:         openai.EndpointChatCompletions,
+				CompletionWindow: "24h",
+				Metadata:         metadata,
+			})
+			rr := httptest.NewRecorder()
+			handler.CreateBatch(rr, httptest.NewRequest(http.MethodPost, "/v1/batches", bytes.NewReader(body)))
+			var batch openai.Batch
+			if err := json.NewDecoder(rr.Body).Decode(&batch); err != nil || rr.Code != http.StatusOK {
+				t.Fatalf("Failed to create batch: %d %v", rr.Code, err)
+			}
+			batchIDs[batch.ID] = name
+		}
+
+		tests := []struct {
+			name      string
+			query     string
+			wantNames []string
+		}{
+			{name: "one pair", query: "metadata[team]=a", wantNames: []string{"a-dev", "a-prod"}},
+			{name: "all pairs must match", query: "metadata[team]=a&metadata[env]=prod", wantNames: []string{"a-prod"}},
+			{name: "no match", query: "metadata[team]=c", wantNames: []string{}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				handler.ListBatches(rr, httptest.NewRequest(http.MethodGet, "/v1/batches?"+tt.query, nil))
+				if rr.Code != http.StatusOK {
+					t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+				}
+				var resp openai.ListBatchResponse
+				if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+
+				names := []string{}
+				for _, batch := range resp.Data {
+					names = append(names, batchIDs[batch.ID])
+				}
+				slices.Sort(names)
+				if !slices.Equal(names, tt.wantNames) {
+					t.Errorf("Expected batches %v, got %v", tt.wantNames, names)
+				}
+			})
+		}
+
+		t.Run("limit", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ListBatches(rr, httptest.NewRequest(http.MethodGet, "/v1/batches?metadata[env]=prod&limit=1", nil))
+			var resp openai.ListBatchResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if len(resp.Data) != 1 || !resp.HasMore {
+				t.Errorf("Expected one of the two prod batches and more, got %d (has_more %v)", len(resp.Data), resp.HasMore)
+			}
+
+			// the next page starts at the cursor of the database
+			rr = httptest.NewRecorder()
+			handler.ListBatches(rr, httptest.NewRequest(http.MethodGet, "/v1/batches?metadata[env]=prod&limit=1&after=1", nil))
+			var next openai.ListBatchResponse
+			if err := json.NewDecoder(rr.Body).Decode(&next); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if len(next.Data) != 1 || next.HasMore || next.Data[0].ID == resp.Data[0].ID {
+				t.Errorf("Expected the other prod batch and no more, got %+v (has_more %v)", next.Data, next.HasMore)
+			}
+		})
+
+		t.Run("invalid filter", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ListBatches(rr, httptest.NewRequest(http.MethodGet, "/v1/batches?metadata[]=a", nil))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+		})
+	})
+
 	t.Run("CancelBatch", func(t *testing.T) {
 		handler := setupBatchApiHandlerForTest()
 		dbClient := handler.dbClient
@@ -245,7 +1461,7 @@ func TestBatchHandler(t *testing.T) {
 			ID:     batchID,
 			SLO:    time.Now().UTC().Add(24 * time.Hour),
 			TTL:    86400,
-			Tags:   []string{},
+			Tags:   []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)},
 			Spec:   specData,
 			Status: statusData,
 		})
@@ -276,6 +1492,31 @@ func TestBatchHandler(t *testing.T) {
 			t.Error("Expected cancelling_at to be set")
 		}
 	})
+
+	t.Run("CancelCompletedBatch", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		batchID := "batch-test-cancel-completed"
+		specData, _ := json.Marshal(openai.BatchSpec{InputFileID: "file-abc123", Endpoint: openai.EndpointChatCompletions})
+		statusData, _ := json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusInProgress})
+		handler.dbClient.Store(context.Background(), &api.BatchJob{
+			ID:     batchID,
+			SLO:    time.Now().UTC().Add(24 * time.Hour),
+			TTL:    86400,
+			Tags:   []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)},
+			Spec:   specData,
+			Status: statusData,
+		})
+		// the batch completes after it is read by the cancel
+		handler.dbClient = &finalStatusDBClient{BatchDBClient: handler.dbClient}
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/batches/"+batchID+"/cancel", nil)
+		req.SetPathValue("batch_id", batchID)
+		rr := httptest.NewRecorder()
+		handler.CancelBatch(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, rr.Code, rr.Body.String())
+		}
+	})
 }
 
 // Benchmark tests for batch handler
@@ -322,7 +1563,7 @@ func BenchmarkBatchHandler(b *testing.B) {
 			ID:     batchID,
 			SLO:    time.Now().UTC().Add(24 * time.Hour),
 			TTL:    86400,
-			Tags:   []string{},
+			Tags:   []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)},
 			Spec:   specData,
 			Status: statusData,
 		})
@@ -359,7 +1600,7 @@ func BenchmarkBatchHandler(b *testing.B) {
 				ID:     batchID,
 				SLO:    time.Now().UTC().Add(24 * time.Hour),
 				TTL:    86400,
-				Tags:   []string{},
+				Tags:   []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)},
 				Spec:   specData,
 				Status: statusData,
 			})
@@ -399,7 +1640,7 @@ func BenchmarkBatchHandler(b *testing.B) {
 				ID:     batchID,
 				SLO:    time.Now().UTC().Add(24 * time.Hour),
 				TTL:    86400,
-				Tags:   []string{},
+				Tags:   []string{sharedbatch.TenantTag(sharedbatch.DefaultTenantID)},
 				Spec:   specData,
 				Status: statusData,
 			})
@@ -0,0 +1,112 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file implements the paginated listing of the output lines of a batch, so that large outputs
+// can be browsed without downloading the whole output file.
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	sharedbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+// ListBatchOutput lists a page of the response lines of the output file of a batch.
+// The after parameter is the number of the last line of the previous page, returned as next_after.
+// A batch that isn't finished yet has no output to list, and is answered with a 409 error.
+func (c *BatchApiHandler) ListBatchOutput(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	batchID := r.PathValue(pathParamBatchID)
+	if batchID == "" {
+		apiErr := openai.NewAPIError(http.StatusBadRequest, "", pathParamBatchID+" is required", nil)
+		common.WriteAPIError(ctx, w, apiErr)
+		return
+	}
+	page, apiErr := common.ParsePagination(r.URL.Query(), defaultListLimit, maxListLimit)
+	if apiErr != nil {
+		common.WriteAPIError(ctx, w, *apiErr)
+		return
+	}
+
+	batch, err := c.getBatch(ctx, common.GetTenantIDFromContext(ctx), batchID)
+	if err != nil {
+		logger.Error(err, "failed to get batch", "batch_id", batchID)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	if batch == nil {
+		common.WriteNotFound(ctx, w, fmt.Sprintf("Batch with ID %s not found", batchID))
+		return
+	}
+	if !batch.Status.IsFinal() {
+		common.WriteConflict(ctx, w, fmt.Sprintf("Batch with ID %s is %s, its output is available once it is finished", batchID, batch.Status))
+		return
+	}
+	if batch.OutputFileID == "" {
+		common.WriteNotFound(ctx, w, fmt.Sprintf("Batch with ID %s has no output file", batchID))
+		return
+	}
+
+	// one more line than the limit is read to check if there are more lines
+	lines := []sharedbatch.ResponseLine{}
+	var lastLine int64
+	var hasMore bool
+	var lineErr error
+	found, err := c.scanFile(ctx, batch.OutputFileID, func(lineNum int64, data []byte) bool {
+		if lineNum <= int64(page.After) {
+			return true
+		}
+		if len(lines) == page.Limit {
+			hasMore = true
+			return false
+		}
+		var line sharedbatch.ResponseLine
+		if err := json.Unmarshal(data, &line); err != nil {
+			lineErr = fmt.Errorf("invalid output line %d: %w", lineNum, err)
+			return false
+		}
+		lines = append(lines, line)
+		lastLine = lineNum
+		return true
+	})
+	if err == nil {
+		err = lineErr
+	}
+	if err != nil {
+		logger.Error(err, "failed to read output file", "batch_id", batchID, "output_file_id", batch.OutputFileID)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	if !found {
+		common.WriteNotFound(ctx, w, fmt.Sprintf("Output file %s of batch %s not found", batch.OutputFileID, batchID))
+		return
+	}
+
+	resp := sharedbatch.OutputPage{
+		ListResponse: common.NewListResponse(lines, hasMore, func(line sharedbatch.ResponseLine) string { return line.ID }),
+	}
+	if hasMore {
+		resp.NextAfter = lastLine
+	}
+	common.WriteJSONResponse(ctx, w, http.StatusOK, resp)
+}
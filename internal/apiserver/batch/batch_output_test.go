@@ -0,0 +1,112 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the listing of the output lines of a batch.
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sharedbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// listBatchOutputForTest lists the output lines of the batch with the query, and returns the response.
+func listBatchOutputForTest(handler *BatchApiHandler, batchID, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/v1/batches/"+batchID+"/output?"+query, nil)
+	req.SetPathValue(pathParamBatchID, batchID)
+	rr := httptest.NewRecorder()
+	handler.ListBatchOutput(rr, req)
+	return rr
+}
+
+func TestListBatchOutput(t *testing.T) {
+	var output strings.Builder
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(&output, `{"id":"batch_req_%d","custom_id":"req-%d","response":{"status_code":200,"request_id":"r%d","body":{}},"error":null}`+"\n", i, i, i)
+	}
+
+	t.Run("Paginates", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		storeInputFileForTest(t, handler, "file-output", output.String())
+		storeBatchStatusForTest(t, handler, "batch-output", openai.BatchStatusInfo{Status: openai.BatchStatusCompleted, OutputFileID: "file-output"})
+
+		var customIDs []string
+		query := "limit=2"
+		for pages := 1; ; pages++ {
+			rr := listBatchOutputForTest(handler, "batch-output", query)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+			}
+			var page sharedbatch.OutputPage
+			if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if page.Object != "list" || len(page.Data) == 0 || page.FirstID != page.Data[0].ID || page.LastID != page.Data[len(page.Data)-1].ID {
+				t.Fatalf("Unexpected page %d: %+v", pages, page)
+			}
+			for _, line := range page.Data {
+				if line.Response == nil || line.Response.StatusCode != http.StatusOK {
+					t.Errorf("Expected the response of line %s to be parsed, got %+v", line.CustomID, line.Response)
+				}
+				customIDs = append(customIDs, line.CustomID)
+			}
+			if !page.HasMore {
+				if page.NextAfter != 0 || pages != 3 {
+					t.Errorf("Expected the last page to be page 3 without cursor, got page %d with cursor %d", pages, page.NextAfter)
+				}
+				break
+			}
+			query = fmt.Sprintf("limit=2&after=%d", page.NextAfter)
+		}
+
+		if got := strings.Join(customIDs, ","); got != "req-1,req-2,req-3,req-4,req-5" {
+			t.Errorf("Expected every output line once in order, got %s", got)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		storeBatchStatusForTest(t, handler, "batch-running", openai.BatchStatusInfo{Status: openai.BatchStatusInProgress})
+		storeBatchStatusForTest(t, handler, "batch-no-output", openai.BatchStatusInfo{Status: openai.BatchStatusFailed})
+		storeBatchStatusForTest(t, handler, "batch-missing-file", openai.BatchStatusInfo{Status: openai.BatchStatusCompleted, OutputFileID: "file-missing"})
+
+		tests := []struct {
+			name     string
+			batchID  string
+			query    string
+			wantCode int
+		}{
+			{name: "not finished", batchID: "batch-running", wantCode: http.StatusConflict},
+			{name: "unknown batch", batchID: "batch-unknown", wantCode: http.StatusNotFound},
+			{name: "no output file", batchID: "batch-no-output", wantCode: http.StatusNotFound},
+			{name: "missing output file", batchID: "batch-missing-file", wantCode: http.StatusNotFound},
+			{name: "invalid limit", batchID: "batch-running", query: "limit=0", wantCode: http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if rr := listBatchOutputForTest(handler, tt.batchID, tt.query); rr.Code != tt.wantCode {
+					t.Errorf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, tt.wantCode, rr.Body.String())
+				}
+			})
+		}
+	})
+}
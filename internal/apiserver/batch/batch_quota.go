@@ -0,0 +1,85 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file implements the quota of active batches of a tenant.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	sharedbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+// quotaPageSize is the number of batches fetched at a time when counting the active batches of a tenant
+const quotaPageSize = 100
+
+// checkActiveBatchQuota responds with a 429 error if the tenant already has max_active_batches_per_tenant
+// batches that are not final. It returns false if the batch must not be created.
+// Concurrent creations may exceed the quota by the number of requests checked at the same time.
+func (c *BatchApiHandler) checkActiveBatchQuota(w http.ResponseWriter, r *http.Request) bool {
+	maxActive := c.config.MaxActiveBatchesPerTenant
+	if maxActive <= 0 {
+		return true
+	}
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	active, err := c.countActiveBatches(ctx, common.GetTenantIDFromContext(ctx))
+	if err != nil {
+		logger.Error(err, "failed to count active batches")
+		common.WriteInternalServerError(ctx, w)
+		return false
+	}
+	if active >= maxActive {
+		message := fmt.Sprintf("Active batch limit reached: %d batches are in progress, the maximum is %d. "+
+			"Wait for a batch to complete before creating another one", active, maxActive)
+		common.WriteAPIError(ctx, w, openai.NewAPIError(http.StatusTooManyRequests, "", message, nil))
+		return false
+	}
+	return true
+}
+
+// countActiveBatches returns the number of batches of the tenant that are not final.
+func (c *BatchApiHandler) countActiveBatches(ctx context.Context, tenantID string) (int, error) {
+	tag := sharedbatch.TenantTag(tenantID)
+	active := 0
+	for start := 0; ; {
+		jobs, cursor, err := c.dbClient.Get(ctx, nil, []string{tag}, api.TagsLogicalCondAnd, false, start, quotaPageSize)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list batches: %w", err)
+		}
+		for _, job := range jobs {
+			statusInfo := openai.BatchStatusInfo{}
+			if err := json.Unmarshal(job.Status, &statusInfo); err != nil {
+				return 0, fmt.Errorf("failed to parse status of batch %s: %w", job.ID, err)
+			}
+			if !statusInfo.Status.IsFinal() {
+				active++
+			}
+		}
+		if cursor == 0 || len(jobs) < quotaPageSize {
+			return active, nil
+		}
+		start = cursor
+	}
+}
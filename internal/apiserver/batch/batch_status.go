@@ -0,0 +1,97 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file implements the bulk status endpoint, returning many batches of the tenant in one response.
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	sharedbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+// maxBatchStatusIDs is the maximum number of batches requested at once from the bulk status endpoint
+const maxBatchStatusIDs = maxListLimit
+
+// batchStatusRequest is the body of a bulk status request.
+type batchStatusRequest struct {
+	BatchIDs []string `json:"batch_ids"`
+}
+
+// GetBatchesStatus returns the batches of the requested IDs, in the order of the request.
+// The IDs of batches that don't exist or belong to another tenant are skipped.
+func (c *BatchApiHandler) GetBatchesStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	req := batchStatusRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		common.WriteBadRequest(ctx, w, "invalid request body", "")
+		return
+	}
+	if len(req.BatchIDs) == 0 {
+		common.WriteBadRequest(ctx, w, "batch_ids is required", "batch_ids")
+		return
+	}
+	if len(req.BatchIDs) > maxBatchStatusIDs {
+		common.WriteBadRequest(ctx, w, fmt.Sprintf("batch_ids must have at most %d IDs", maxBatchStatusIDs), "batch_ids")
+		return
+	}
+
+	// a batch requested more than once is returned once
+	var batchIDs []string
+	for _, batchID := range req.BatchIDs {
+		if !slices.Contains(batchIDs, batchID) {
+			batchIDs = append(batchIDs, batchID)
+		}
+	}
+	jobs, _, err := c.dbClient.Get(ctx, batchIDs, nil, api.TagsLogicalCondNa, true, 0, len(batchIDs))
+	if err != nil {
+		logger.Error(err, "failed to get batches from database")
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	jobsByID := make(map[string]*api.BatchJob, len(jobs))
+	for _, job := range jobs {
+		jobsByID[job.ID] = job
+	}
+
+	tag := sharedbatch.TenantTag(common.GetTenantIDFromContext(ctx))
+	batches := make([]openai.Batch, 0, len(jobs))
+	for _, batchID := range batchIDs {
+		job, ok := jobsByID[batchID]
+		if !ok || !slices.Contains(job.Tags, tag) {
+			continue
+		}
+		batch, err := jobToBatch(job)
+		if err != nil {
+			logger.Error(err, "failed to convert job to batch", "batch_id", job.ID)
+			continue
+		}
+		c.truncateErrors(batch)
+		batches = append(batches, *batch)
+	}
+
+	resp := common.NewListResponse(batches, false, func(b openai.Batch) string { return b.ID })
+	common.WriteJSONResponse(ctx, w, http.StatusOK, resp)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the bulk status endpoint.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	sharedbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// getBatchesStatusForTest requests the status of batches as the tenant, and returns the response.
+func getBatchesStatusForTest(handler *BatchApiHandler, tenantID, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/batches/status", strings.NewReader(body))
+	req = req.WithContext(common.WithTenantID(req.Context(), tenantID))
+	rr := httptest.NewRecorder()
+	handler.GetBatchesStatus(rr, req)
+	return rr
+}
+
+func TestGetBatchesStatus(t *testing.T) {
+	t.Run("OwnedBatches", func(t *testing.T) {
+		handler := setupBatchApiHandlerForTest()
+		for _, b := range []struct {
+			id       string
+			tenantID string
+			status   openai.BatchStatus
+		}{
+			{id: "batch-a1", tenantID: "tenant-a", status: openai.BatchStatusInProgress},
+			{id: "batch-a2", tenantID: "tenant-a", status: openai.BatchStatusCompleted},
+			{id: "batch-b1", tenantID: "tenant-b", status: openai.BatchStatusInProgress},
+		} {
+			status, _ := json.Marshal(openai.BatchStatusInfo{Status: b.status})
+			job := &api.BatchJob{
+				ID: b.id, SLO: time.Now().Add(time.Hour), TTL: 3600,
+				Tags: []string{sharedbatch.TenantTag(b.tenantID)}, Spec: []byte(`{}`), Status: status,
+			}
+			if _, err := handler.dbClient.Store(context.Background(), job); err != nil {
+				t.Fatalf("Failed to store batch: %v", err)
+			}
+		}
+
+		// the batches of another tenant and the unknown batches are skipped
+		rr := getBatchesStatusForTest(handler, "tenant-a", `{"batch_ids":["batch-a2","batch-b1","batch-missing","batch-a1","batch-a2"]}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var resp openai.ListResponse[openai.Batch]
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		var ids []string
+		statuses := map[string]openai.BatchStatus{}
+		for _, batch := range resp.Data {
+			ids = append(ids, batch.ID)
+			statuses[batch.ID] = batch.Status
+		}
+		if want := []string{"batch-a2", "batch-a1"}; !slices.Equal(ids, want) {
+			t.Errorf("Expected batches %v, got %v", want, ids)
+		}
+		if statuses["batch-a1"] != openai.BatchStatusInProgress || statuses["batch-a2"] != openai.BatchStatusCompleted {
+			t.Errorf("Unexpected statuses %v", statuses)
+		}
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		tooMany, _ := json.Marshal(map[string][]string{"batch_ids": make([]string, maxBatchStatusIDs+1)})
+		tests := []struct {
+			name string
+			body string
+		}{
+			{name: "invalid body", body: `{"batch_ids":`},
+			{name: "no IDs", body: `{"batch_ids":[]}`},
+			{name: "too many IDs", body: string(tooMany)},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := getBatchesStatusForTest(setupBatchApiHandlerForTest(), "tenant-a", tt.body)
+				if rr.Code != http.StatusBadRequest {
+					t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+				}
+			})
+		}
+	})
+}
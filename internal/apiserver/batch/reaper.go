@@ -0,0 +1,158 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the reaper enforcing the TTL of batches.
+// Final batches older than batch_ttl_seconds are deleted with their input, output and error files.
+// Batches that are still being processed are never deleted.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/metrics"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	filesapi "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+	"k8s.io/klog/v2"
+)
+
+// reaperPageSize is the number of batches fetched at a time by a reaper pass
+const reaperPageSize = 100
+
+// BatchReaper deletes final batches past their TTL, with their files.
+type BatchReaper struct {
+	config       *common.ServerConfig
+	dbClient     api.BatchDBClient
+	fileDBClient api.BatchFileDBClient
+	filesClient  filesapi.BatchFilesClient
+	now          func() time.Time // replaced in tests
+}
+
+func NewBatchReaper(config *common.ServerConfig, dbClient api.BatchDBClient, fileDBClient api.BatchFileDBClient, filesClient filesapi.BatchFilesClient) *BatchReaper {
+	return &BatchReaper{
+		config:       config,
+		dbClient:     dbClient,
+		fileDBClient: fileDBClient,
+		filesClient:  filesClient,
+		now:          time.Now,
+	}
+}
+
+// Run reaps expired batches every interval until ctx is done.
+func (r *BatchReaper) Run(ctx context.Context, interval time.Duration) {
+	logger := klog.FromContext(ctx).WithName("batch_reaper")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reaped, err := r.Reap(ctx)
+			if err != nil {
+				logger.Error(err, "failed to reap expired batches")
+			}
+			if reaped > 0 {
+				logger.V(logging.INFO).Info("reaped expired batches", "count", reaped)
+			}
+		}
+	}
+}
+
+// Reap deletes the final batches created more than batch_ttl_seconds ago, and returns the number of deleted batches.
+// A batch whose files can't be deleted is kept, so that it is retried by the next pass.
+func (r *BatchReaper) Reap(ctx context.Context) (int, error) {
+	logger := klog.FromContext(ctx)
+	deadline := r.now().Add(-time.Duration(r.config.BatchTTLSeconds) * time.Second).Unix()
+
+	var expired []*openai.Batch
+	for start := 0; ; {
+		jobs, cursor, err := r.dbClient.Get(ctx, nil, nil, api.TagsLogicalCondNa, true, start, reaperPageSize)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list batches: %w", err)
+		}
+		for _, job := range jobs {
+			batch, err := jobToBatch(job)
+			if err != nil {
+				logger.Error(err, "failed to convert job to batch", "batch_id", job.ID)
+				continue
+			}
+			if batch.Status.IsFinal() && batch.CreatedAt <= deadline {
+				expired = append(expired, batch)
+			}
+		}
+		if cursor == 0 || len(jobs) < reaperPageSize {
+			break
+		}
+		start = cursor
+	}
+
+	reaped := 0
+	for _, batch := range expired {
+		files, err := r.deleteFiles(ctx, batch.InputFileID, batch.OutputFileID, batch.ErrorFileID)
+		if err != nil {
+			logger.Error(err, "failed to delete files of expired batch", "batch_id", batch.ID)
+			continue
+		}
+		if _, err := r.dbClient.Delete(ctx, []string{batch.ID}); err != nil {
+			logger.Error(err, "failed to delete expired batch", "batch_id", batch.ID)
+			continue
+		}
+		metrics.RecordBatchReaped(files)
+		reaped++
+	}
+	return reaped, nil
+}
+
+// deleteFiles deletes the content and metadata of files, and returns the number of files that existed.
+func (r *BatchReaper) deleteFiles(ctx context.Context, fileIDs ...string) (int, error) {
+	deleted := 0
+	for _, fileID := range fileIDs {
+		if fileID == "" {
+			continue
+		}
+		records, _, err := r.fileDBClient.Get(ctx, []string{fileID}, nil, api.TagsLogicalCondNa, 0, 1)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to get metadata of file %s: %w", fileID, err)
+		}
+		location := fileID
+		if len(records) > 0 {
+			location = records[0].ContentLocation()
+		}
+
+		found := true
+		if err := r.filesClient.Delete(ctx, location); err != nil {
+			if !errors.Is(err, filesapi.ErrFileNotFound) {
+				return deleted, fmt.Errorf("failed to delete file %s: %w", fileID, err)
+			}
+			found = false
+		}
+		deletedIDs, err := r.fileDBClient.Delete(ctx, []string{fileID})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete metadata of file %s: %w", fileID, err)
+		}
+		if found || len(deletedIDs) > 0 {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the batch reaper.
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	mockapi "github.com/llm-d-incubation/batch-gateway/internal/database/mock"
+	mockfiles "github.com/llm-d-incubation/batch-gateway/internal/files_store/mock"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+func TestBatchReaper(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	config := &common.ServerConfig{BatchTTLSeconds: 3600}
+
+	dbClient := mockapi.NewMockBatchDBClient()
+	fileDBClient := mockapi.NewMockBatchFileDBClient()
+	filesClient := mockfiles.NewMockBatchFilesClient()
+	reaper := NewBatchReaper(config, dbClient, fileDBClient, filesClient)
+	reaper.now = func() time.Time { return now }
+
+	// output files are stored at a recorded location, input files at their ID
+	locations := map[string]string{}
+	storeFile := func(fileID, location string) {
+		locations[fileID] = location
+		if location == "" {
+			locations[fileID] = fileID
+		}
+		if _, err := filesClient.Store(ctx, locations[fileID], 0, bytes.NewBufferString("content")); err != nil {
+			t.Fatalf("Failed to store file: %v", err)
+		}
+		if _, err := fileDBClient.Store(ctx, &api.BatchFile{ID: fileID, TTL: 3600, Location: location}); err != nil {
+			t.Fatalf("Failed to store file metadata: %v", err)
+		}
+	}
+	storeBatch := func(id string, status openai.BatchStatus, age time.Duration) {
+		spec, _ := json.Marshal(openai.BatchSpec{InputFileID: "file-in-" + id, CreatedAt: now.Add(-age).Unix()})
+		statusData, _ := json.Marshal(openai.BatchStatusInfo{Status: status, OutputFileID: "file-out-" + id})
+		job := &api.BatchJob{ID: id, SLO: now, TTL: 3600, Spec: spec, Status: statusData}
+		if _, err := dbClient.Store(ctx, job); err != nil {
+			t.Fatalf("Failed to store batch: %v", err)
+		}
+		storeFile("file-in-"+id, "")
+		storeFile("file-out-"+id, "default/file-out-"+id)
+	}
+
+	storeBatch("expired-completed", openai.BatchStatusCompleted, 2*time.Hour)
+	storeBatch("expired-cancelled", openai.BatchStatusCancelled, 2*time.Hour)
+	storeBatch("expired-in-progress", openai.BatchStatusInProgress, 2*time.Hour)
+	storeBatch("recent-completed", openai.BatchStatusCompleted, 30*time.Minute)
+
+	reaped, err := reaper.Reap(ctx)
+	if err != nil {
+		t.Fatalf("Reap failed: %v", err)
+	}
+	if reaped != 2 {
+		t.Errorf("Expected 2 reaped batches, got %d", reaped)
+	}
+
+	jobs, _, _ := dbClient.Get(ctx, nil, nil, api.TagsLogicalCondNa, true, 0, 0)
+	var remaining []string
+	for _, job := range jobs {
+		remaining = append(remaining, job.ID)
+	}
+	slices.Sort(remaining)
+	if want := []string{"expired-in-progress", "recent-completed"}; !slices.Equal(remaining, want) {
+		t.Errorf("Expected remaining batches %v, got %v", want, remaining)
+	}
+
+	for _, tt := range []struct {
+		fileID     string
+		wantExists bool
+	}{
+		{fileID: "file-in-expired-completed"},
+		{fileID: "file-out-expired-completed"},
+		{fileID: "file-out-expired-cancelled"},
+		{fileID: "file-out-expired-in-progress", wantExists: true},
+		{fileID: "file-out-recent-completed", wantExists: true},
+	} {
+		_, _, err := filesClient.Retrieve(ctx, locations[tt.fileID])
+		if exists := err == nil; exists != tt.wantExists {
+			t.Errorf("Expected file %s to exist: %v, got %v", tt.fileID, tt.wantExists, exists)
+		}
+		files, _, _ := fileDBClient.Get(ctx, []string{tt.fileID}, nil, api.TagsLogicalCondNa, 0, 1)
+		if exists := len(files) > 0; exists != tt.wantExists {
+			t.Errorf("Expected metadata of file %s to exist: %v, got %v", tt.fileID, tt.wantExists, exists)
+		}
+	}
+
+	// once the clock passes the TTL of the recent batch, it is reaped too
+	reaper.now = func() time.Time { return now.Add(time.Hour) }
+	if reaped, err := reaper.Reap(ctx); err != nil || reaped != 1 {
+		t.Errorf("Expected the recent batch to be reaped after its TTL, got %d reaped, err %v", reaped, err)
+	}
+}
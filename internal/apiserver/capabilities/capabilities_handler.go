@@ -0,0 +1,88 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the HTTP handler for the capabilities endpoint.
+// It lets clients discover the supported endpoints and limits of the gateway.
+package capabilities
+
+import (
+	"net/http"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+const (
+	CapabilitiesPath = "/v1/capabilities"
+)
+
+// Capabilities describes what the gateway supports.
+type Capabilities struct {
+	// The object type, which is always `capabilities`.
+	Object string `json:"object"`
+
+	// The endpoints that can be used by a batch.
+	Endpoints []openai.Endpoint `json:"endpoints"`
+
+	// The completion windows that can be requested for a batch.
+	CompletionWindows []string `json:"completion_windows"`
+
+	// The maximum number of requests in a batch input file.
+	MaxRequestsPerBatch int `json:"max_requests_per_batch"`
+
+	// The maximum size of an uploaded file, in bytes.
+	MaxFileSizeBytes int64 `json:"max_file_size_bytes"`
+
+	// The maximum size of the request body of a line of a batch input file, in bytes. Zero means no limit.
+	MaxLineBodyBytes int `json:"max_line_body_bytes"`
+
+	// The purposes that can be used when uploading a file.
+	FilePurposes []openai.FileObjectPurpose `json:"file_purposes"`
+}
+
+type CapabilitiesApiHandler struct {
+	config *common.ServerConfig
+}
+
+func NewCapabilitiesApiHandler(config *common.ServerConfig) *CapabilitiesApiHandler {
+	return &CapabilitiesApiHandler{
+		config: config,
+	}
+}
+
+func (c *CapabilitiesApiHandler) GetRoutes() []common.Route {
+	return []common.Route{
+		{
+			Method:      http.MethodGet,
+			Pattern:     CapabilitiesPath,
+			HandlerFunc: c.GetCapabilities,
+		},
+	}
+}
+
+func (c *CapabilitiesApiHandler) GetCapabilities(w http.ResponseWriter, r *http.Request) {
+	resp := Capabilities{
+		Object:              "capabilities",
+		Endpoints:           openai.SupportedEndpoints,
+		CompletionWindows:   c.config.CompletionWindows,
+		MaxRequestsPerBatch: c.config.MaxRequestsPerBatch,
+		MaxFileSizeBytes:    c.config.MaxFileSizeBytes,
+		MaxLineBodyBytes:    c.config.MaxLineBodyBytes,
+		FilePurposes:        openai.FileObjectPurposes,
+	}
+
+	common.WriteJSONResponse(r.Context(), w, http.StatusOK, resp)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the capabilities handler.
+package capabilities
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+func TestCapabilitiesHandler(t *testing.T) {
+	config := common.NewConfig()
+	config.MaxFileSizeBytes = 1024
+	config.MaxRequestsPerBatch = 10
+	config.CompletionWindows = []string{"1h", "24h"}
+
+	mux := http.NewServeMux()
+	common.RegisterHandler(mux, NewCapabilitiesApiHandler(config))
+
+	req := httptest.NewRequest(http.MethodGet, CapabilitiesPath, nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var caps Capabilities
+	if err := json.NewDecoder(rr.Body).Decode(&caps); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	if caps.Object != "capabilities" {
+		t.Errorf("Expected object to be 'capabilities', got %v", caps.Object)
+	}
+	if caps.MaxFileSizeBytes != config.MaxFileSizeBytes {
+		t.Errorf("Expected max_file_size_bytes to be %d, got %d", config.MaxFileSizeBytes, caps.MaxFileSizeBytes)
+	}
+	if caps.MaxRequestsPerBatch != config.MaxRequestsPerBatch {
+		t.Errorf("Expected max_requests_per_batch to be %d, got %d", config.MaxRequestsPerBatch, caps.MaxRequestsPerBatch)
+	}
+	if caps.MaxLineBodyBytes != config.MaxLineBodyBytes {
+		t.Errorf("Expected max_line_body_bytes to be %d, got %d", config.MaxLineBodyBytes, caps.MaxLineBodyBytes)
+	}
+	if !slices.Equal(caps.CompletionWindows, config.CompletionWindows) {
+		t.Errorf("Expected completion_windows to be %v, got %v", config.CompletionWindows, caps.CompletionWindows)
+	}
+	if !slices.Equal(caps.Endpoints, openai.SupportedEndpoints) {
+		t.Errorf("Expected endpoints to be %v, got %v", openai.SupportedEndpoints, caps.Endpoints)
+	}
+	if !slices.Contains(caps.FilePurposes, openai.FileObjectPurposeBatch) {
+		t.Errorf("Expected file_purposes to contain %q, got %v", openai.FileObjectPurposeBatch, caps.FilePurposes)
+	}
+}
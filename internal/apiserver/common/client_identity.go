@@ -0,0 +1,60 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides helpers for carrying the verified TLS client identity of a request.
+package common
+
+import (
+	"context"
+	"crypto/tls"
+)
+
+// ClientIdentity identifies a client by its verified TLS client certificate.
+type ClientIdentity struct {
+	CommonName string
+	DNSNames   []string
+	URIs       []string
+}
+
+type clientIdentityKey struct{}
+
+// ClientIdentityFromTLS returns the identity of the verified client certificate of a TLS connection.
+// It returns false if the client did not present a verified certificate.
+func ClientIdentityFromTLS(state *tls.ConnectionState) (ClientIdentity, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ClientIdentity{}, false
+	}
+	cert := state.VerifiedChains[0][0]
+	identity := ClientIdentity{
+		CommonName: cert.Subject.CommonName,
+		DNSNames:   cert.DNSNames,
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	return identity, true
+}
+
+// WithClientIdentity returns a copy of ctx carrying the client identity.
+func WithClientIdentity(ctx context.Context, identity ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, identity)
+}
+
+// GetClientIdentityFromContext returns the client identity carried by ctx, if any.
+func GetClientIdentityFromContext(ctx context.Context) (ClientIdentity, bool) {
+	identity, ok := ctx.Value(clientIdentityKey{}).(ClientIdentity)
+	return identity, ok
+}
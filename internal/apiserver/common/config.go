@@ -21,21 +21,240 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/ids"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/tracing"
 	"gopkg.in/yaml.v3"
 	"k8s.io/klog/v2"
 )
 
+const (
+	DefaultMaxFileSizeBytes        int64 = 200 * 1024 * 1024 // 200 MB, matching the OpenAI batch input file limit
+	DefaultMaxRequestsPerBatch     int   = 50000             // matching the OpenAI batch input file limit
+	DefaultMaxLineBodyBytes        int   = 1024 * 1024       // 1 MB
+	DefaultMaxEmbeddingsInputs     int   = 50000             // matching the OpenAI embeddings batch limit
+	DefaultCompletionWindow              = "24h"
+	DefaultFileTTLSeconds          int   = 30 * 24 * 60 * 60 // 30 days
+	DefaultFileDedupWindowSecs     int   = 5 * 60            // 5 minutes
+	DefaultMaxBatchErrors          int   = 100
+	DefaultCompressionMinSize      int   = 1024         // 1 KB
+	DefaultIdempotencyKeyTTLSecs   int   = 24 * 60 * 60 // 24 hours
+	DefaultBatchReaperIntervalSecs int   = 60 * 60      // 1 hour
+	DefaultFileReaperIntervalSecs  int   = 60 * 60      // 1 hour
+)
+
 type ServerConfig struct {
 	Host            string `yaml:"host"`
 	Port            string `yaml:"port"`
 	SSLCertFile     string `yaml:"ssl_cert_file"`
 	SSLKeyFile      string `yaml:"ssl_key_file"`
 	BatchTTLSeconds int    `yaml:"batch_ttl_seconds"`
+
+	// SSLRequireClientCert enables mutual TLS: clients must present a certificate signed by a CA in SSLClientCAFile.
+	SSLRequireClientCert bool   `yaml:"ssl_require_client_cert"`
+	SSLClientCAFile      string `yaml:"ssl_client_ca_file"`
+
+	// ObservabilityPort is the port of a separate server exposing the metrics and health endpoints,
+	// on the same host as the API. The observability server is disabled when empty.
+	ObservabilityPort string `yaml:"observability_port"`
+
+	// APIKeys maps the API keys accepted by the server to the ID of their tenant.
+	// Requests are authenticated with an "Authorization: Bearer <key>" header when API keys are set.
+	APIKeys map[string]string `yaml:"api_keys"`
+
+	// BatchIDPrefix and FileIDPrefix are the prefixes of the IDs of created batches and uploaded files.
+	// They may only contain letters, digits, '-' and '_'.
+	BatchIDPrefix string `yaml:"batch_id_prefix"`
+	FileIDPrefix  string `yaml:"file_id_prefix"`
+
+	// MaxFileSizeBytes is the maximum size of an uploaded file in bytes
+	MaxFileSizeBytes int64 `yaml:"max_file_size_bytes"`
+
+	// MaxConcurrentTransfers is the maximum number of file uploads and downloads in flight.
+	// The transfers above it are rejected with a 503 error. The transfers are not limited when it is 0.
+	MaxConcurrentTransfers int `yaml:"max_concurrent_transfers"`
+
+	// AllowedPurposes restricts the purposes of the uploaded files. All known purposes are allowed by default.
+	AllowedPurposes []openai.FileObjectPurpose `yaml:"allowed_purposes"`
+
+	// MaxRequestsPerBatch is the maximum number of requests (lines) in a batch input file.
+	// Batches with larger input files are rejected at creation.
+	MaxRequestsPerBatch int `yaml:"max_requests_per_batch"`
+
+	// MaxLineBodyBytes is the maximum size of the request body of a line of a batch input file in bytes.
+	// Batches with larger lines fail at creation, with an error per line. Zero disables the check.
+	MaxLineBodyBytes int `yaml:"max_line_body_bytes"`
+
+	// MaxEmbeddingsInputsPerBatch is the maximum number of inputs of the embeddings requests of a batch.
+	// Batches with more inputs fail at creation. Zero disables the check.
+	MaxEmbeddingsInputsPerBatch int `yaml:"max_embeddings_inputs_per_batch"`
+
+	// ValidationStrictness is how strictly the request lines of the batches are validated, strict or lenient.
+	// Strict rejects the lines with unknown keys and malformed bodies up front, lenient only checks the keys
+	// required to route the lines and lets the inference backend reject their bodies. Defaults to lenient.
+	ValidationStrictness openai.ValidationStrictness `yaml:"validation_strictness"`
+
+	// CompletionWindows lists the completion windows offered to clients, as durations such as "24h" or "1h".
+	// Windows shorter than 24h serve time-sensitive batches; the processor counts the batches meeting them.
+	CompletionWindows []string `yaml:"completion_windows"`
+
+	// CompressionMinSizeBytes is the response size from which responses are gzipped for clients accepting it.
+	// Zero disables the compression.
+	CompressionMinSizeBytes int `yaml:"compression_min_size_bytes"`
+
+	// LogPanicStack includes the stack trace of recovered handler panics in the error log.
+	// Stack traces are never returned to clients.
+	LogPanicStack bool `yaml:"log_panic_stack"`
+
+	// RateLimit limits the rate of requests of each tenant. Rate limiting is disabled by default.
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+
+	// AllowedModels restricts the models tenants may target with their batches. All models are allowed by default.
+	AllowedModels ModelAllowlist `yaml:"allowed_models"`
+
+	// MixedEndpointBatchesEnabled allows clients to create mixed-endpoint batches,
+	// in which each request line selects its own endpoint
+	MixedEndpointBatchesEnabled bool `yaml:"mixed_endpoint_batches_enabled"`
+
+	// MaxActiveBatchesPerTenant is the maximum number of batches of a tenant that are not final.
+	// The batches created above it are rejected with a 429 error. The batches are not limited when it is 0.
+	MaxActiveBatchesPerTenant int `yaml:"max_active_batches_per_tenant"`
+
+	// MaxBatchErrors is the maximum number of errors returned with a batch.
+	// Batches with more errors report the total number of errors and are flagged as truncated.
+	MaxBatchErrors int `yaml:"max_batch_errors"`
+
+	// IdempotencyKeyTTLSeconds is the number of seconds an Idempotency-Key of a batch creation is remembered.
+	// Retried requests with the same key within the window return the originally created batch.
+	IdempotencyKeyTTLSeconds int `yaml:"idempotency_key_ttl_seconds"`
+
+	// BatchReaperIntervalSeconds is the interval at which final batches older than BatchTTLSeconds are deleted,
+	// with their files. Zero disables the reaper, as does a zero BatchTTLSeconds.
+	BatchReaperIntervalSeconds int `yaml:"batch_reaper_interval_seconds"`
+
+	// FileTTLSeconds is the number of seconds an uploaded file is kept before it expires
+	FileTTLSeconds int `yaml:"file_ttl_seconds"`
+
+	// FileReaperIntervalSeconds is the interval at which files past their expiration time are deleted.
+	// Zero disables the reaper.
+	FileReaperIntervalSeconds int `yaml:"file_reaper_interval_seconds"`
+
+	// FileDedupEnabled enables returning the existing file when a tenant re-uploads an identical file
+	// within FileDedupWindowSeconds, instead of storing a duplicate
+	FileDedupEnabled bool `yaml:"file_dedup_enabled"`
+
+	// FileDedupWindowSeconds is the time window in seconds in which identical uploads are de-duplicated
+	FileDedupWindowSeconds int `yaml:"file_dedup_window_seconds"`
+
+	// TempDir is the directory of the temporary files, such as the uploaded files before they are stored.
+	// It must exist and be writable.
+	TempDir string `yaml:"temp_dir"`
+
+	// Tracing configures the export of the spans of the requests. The trace context of a request creating
+	// a batch is stored with the batch, so its processing continues the trace.
+	Tracing tracing.Config `yaml:"tracing"`
+}
+
+// ModelAllowlist holds the models every tenant may use, and the overrides of specific tenants.
+type ModelAllowlist struct {
+	// Models lists the models every tenant may use. Empty allows all models.
+	Models []string `yaml:"models"`
+
+	// Tenants overrides the allowed models of the listed tenants
+	Tenants map[string][]string `yaml:"tenants"`
+}
+
+// ForTenant returns the models the tenant may use. A nil list means all models are allowed.
+func (a ModelAllowlist) ForTenant(tenantID string) []string {
+	if models, ok := a.Tenants[tenantID]; ok {
+		return models
+	}
+	if len(a.Models) == 0 {
+		return nil
+	}
+	return a.Models
+}
+
+// RateLimit is a token bucket rate limit.
+type RateLimit struct {
+	// RequestsPerSecond is the steady-state rate of requests
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+
+	// Burst is the number of requests that can be made at once
+	Burst int `yaml:"burst"`
+}
+
+// RateLimitConfig holds the rate limit applied to every tenant, and the overrides of specific tenants.
+type RateLimitConfig struct {
+	RateLimit `yaml:",inline"`
+
+	// Tenants overrides the rate limit of the listed tenants
+	Tenants map[string]RateLimit `yaml:"tenants"`
+}
+
+// Enabled returns true if rate limiting is configured.
+func (c RateLimitConfig) Enabled() bool {
+	return c.RequestsPerSecond > 0 || len(c.Tenants) > 0
+}
+
+// ForTenant returns the rate limit of the tenant. A zero rate limit means the tenant is not limited.
+func (c RateLimitConfig) ForTenant(tenantID string) RateLimit {
+	if limit, ok := c.Tenants[tenantID]; ok {
+		return limit
+	}
+	return c.RateLimit
+}
+
+// Validate checks the rate limits.
+func (c RateLimitConfig) Validate() error {
+	if err := c.RateLimit.validate(); err != nil {
+		return err
+	}
+	for tenantID, limit := range c.Tenants {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("tenant %q: %w", tenantID, err)
+		}
+	}
+	return nil
+}
+
+func (l RateLimit) validate() error {
+	if l.RequestsPerSecond < 0 {
+		return fmt.Errorf("requests_per_second must not be negative")
+	}
+	if l.RequestsPerSecond > 0 && l.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1 when requests_per_second is set")
+	}
+	return nil
+}
+
+// IsPurposeAllowed reports whether files of the purpose may be uploaded.
+func (c *ServerConfig) IsPurposeAllowed(purpose openai.FileObjectPurpose) bool {
+	return len(c.AllowedPurposes) == 0 || slices.Contains(c.AllowedPurposes, purpose)
 }
 
 func NewConfig() *ServerConfig {
-	return &ServerConfig{}
+	return &ServerConfig{
+		BatchIDPrefix:               ids.DefaultBatchIDPrefix,
+		FileIDPrefix:                ids.DefaultFileIDPrefix,
+		MaxFileSizeBytes:            DefaultMaxFileSizeBytes,
+		MaxRequestsPerBatch:         DefaultMaxRequestsPerBatch,
+		MaxLineBodyBytes:            DefaultMaxLineBodyBytes,
+		MaxEmbeddingsInputsPerBatch: DefaultMaxEmbeddingsInputs,
+		ValidationStrictness:        openai.ValidationLenient,
+		CompletionWindows:           []string{DefaultCompletionWindow},
+		MaxBatchErrors:              DefaultMaxBatchErrors,
+		CompressionMinSizeBytes:     DefaultCompressionMinSize,
+		FileTTLSeconds:              DefaultFileTTLSeconds,
+		FileDedupWindowSeconds:      DefaultFileDedupWindowSecs,
+		IdempotencyKeyTTLSeconds:    DefaultIdempotencyKeyTTLSecs,
+		BatchReaperIntervalSeconds:  DefaultBatchReaperIntervalSecs,
+		FileReaperIntervalSeconds:   DefaultFileReaperIntervalSecs,
+		TempDir:                     os.TempDir(),
+	}
 }
 
 func (c *ServerConfig) Load() error {
@@ -63,6 +282,105 @@ func (c *ServerConfig) Validate() error {
 		return fmt.Errorf("port cannot be empty")
 	}
 
+	if c.ObservabilityPort != "" && c.ObservabilityPort == c.Port {
+		return fmt.Errorf("observability_port must differ from port")
+	}
+
+	if err := ids.ValidatePrefix(c.BatchIDPrefix); err != nil {
+		return fmt.Errorf("invalid batch_id_prefix: %w", err)
+	}
+	if err := ids.ValidatePrefix(c.FileIDPrefix); err != nil {
+		return fmt.Errorf("invalid file_id_prefix: %w", err)
+	}
+
+	if c.MaxFileSizeBytes <= 0 {
+		return fmt.Errorf("max_file_size_bytes must be positive")
+	}
+
+	if c.MaxConcurrentTransfers < 0 {
+		return fmt.Errorf("max_concurrent_transfers must not be negative")
+	}
+
+	for _, purpose := range c.AllowedPurposes {
+		if !purpose.IsValid() {
+			return fmt.Errorf("allowed_purposes contains unknown purpose %q", purpose)
+		}
+	}
+
+	if c.MaxRequestsPerBatch <= 0 {
+		return fmt.Errorf("max_requests_per_batch must be positive")
+	}
+
+	if c.MaxLineBodyBytes < 0 {
+		return fmt.Errorf("max_line_body_bytes must not be negative")
+	}
+
+	if c.MaxEmbeddingsInputsPerBatch < 0 {
+		return fmt.Errorf("max_embeddings_inputs_per_batch must not be negative")
+	}
+
+	if !c.ValidationStrictness.IsValid() {
+		return fmt.Errorf("validation_strictness must be %q or %q, got %q", openai.ValidationStrict, openai.ValidationLenient, c.ValidationStrictness)
+	}
+
+	if len(c.CompletionWindows) == 0 {
+		return fmt.Errorf("completion_windows cannot be empty")
+	}
+	for _, window := range c.CompletionWindows {
+		if d, err := time.ParseDuration(openai.NormalizeCompletionWindow(window)); err != nil || d <= 0 {
+			return fmt.Errorf("completion_windows must be positive durations (e.g., 24h), got %q", window)
+		}
+	}
+
+	for key, tenantID := range c.APIKeys {
+		if key == "" || tenantID == "" {
+			return fmt.Errorf("api_keys entries must have a non-empty key and tenant ID")
+		}
+	}
+
+	if c.CompressionMinSizeBytes < 0 {
+		return fmt.Errorf("compression_min_size_bytes must not be negative")
+	}
+
+	if err := c.RateLimit.Validate(); err != nil {
+		return fmt.Errorf("invalid rate_limit: %w", err)
+	}
+
+	if c.MaxActiveBatchesPerTenant < 0 {
+		return fmt.Errorf("max_active_batches_per_tenant must not be negative")
+	}
+	if c.MaxBatchErrors <= 0 {
+		return fmt.Errorf("max_batch_errors must be positive")
+	}
+
+	if c.IdempotencyKeyTTLSeconds <= 0 {
+		return fmt.Errorf("idempotency_key_ttl_seconds must be positive")
+	}
+
+	if c.BatchReaperIntervalSeconds < 0 {
+		return fmt.Errorf("batch_reaper_interval_seconds must not be negative")
+	}
+
+	if c.FileReaperIntervalSeconds < 0 {
+		return fmt.Errorf("file_reaper_interval_seconds must not be negative")
+	}
+
+	if c.FileTTLSeconds <= 0 {
+		return fmt.Errorf("file_ttl_seconds must be positive")
+	}
+
+	if c.FileDedupEnabled && c.FileDedupWindowSeconds <= 0 {
+		return fmt.Errorf("file_dedup_window_seconds must be positive when file_dedup_enabled is set")
+	}
+
+	if err := c.Tracing.Validate(); err != nil {
+		return fmt.Errorf("invalid tracing: %w", err)
+	}
+
+	if err := validateWritableDir(c.TempDir); err != nil {
+		return fmt.Errorf("invalid temp_dir: %w", err)
+	}
+
 	// If one SSL file is provided, both must be provided
 	if (c.SSLCertFile != "" && c.SSLKeyFile == "") || (c.SSLCertFile == "" && c.SSLKeyFile != "") {
 		return fmt.Errorf("both ssl-cert-file and ssl-private-key-file must be provided together")
@@ -78,9 +396,41 @@ func (c *ServerConfig) Validate() error {
 		}
 	}
 
+	if c.SSLRequireClientCert {
+		if !c.SSLEnabled() {
+			return fmt.Errorf("ssl_require_client_cert requires ssl-cert-file and ssl-private-key-file")
+		}
+		if c.SSLClientCAFile == "" {
+			return fmt.Errorf("ssl_client_ca_file must be provided when ssl_require_client_cert is set")
+		}
+		if _, err := os.Stat(c.SSLClientCAFile); err != nil {
+			return fmt.Errorf("ssl client ca file not found: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// validateWritableDir checks that dir is an existing directory in which files can be created.
+func validateWritableDir(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("directory cannot be empty")
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	file, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
 func (c *ServerConfig) loadFromFile(path string) error {
 	if path == "" {
 		return fmt.Errorf("config file path cannot be empty")
@@ -75,6 +75,22 @@ port: "3000"
 				},
 				wantErr: false,
 			},
+			{
+				name:       "invalid validation strictness",
+				yamlConfig: `validation_strictness: pedantic`,
+				fileName:   "config.yaml",
+				wantErr:    true,
+			},
+			{
+				name: "invalid completion window",
+				yamlConfig: `
+completion_windows:
+  - "1h"
+  - "soon"
+`,
+				fileName: "config.yaml",
+				wantErr:  true,
+			},
 			{
 				name:       "invalid yaml",
 				yamlConfig: `invalid: yaml: syntax: error`,
@@ -131,6 +147,41 @@ port: "3000"
 		}
 	})
 
+	t.Run("TempDir", func(t *testing.T) {
+		dir := t.TempDir()
+		file := filepath.Join(dir, "file")
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+
+		tests := []struct {
+			name    string
+			tempDir string
+			wantErr bool
+		}{
+			{name: "writable directory", tempDir: dir},
+			{name: "empty", tempDir: "", wantErr: true},
+			{name: "missing directory", tempDir: filepath.Join(dir, "missing"), wantErr: true},
+			{name: "not a directory", tempDir: file, wantErr: true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				config := NewConfig()
+				config.Port = "8000"
+				config.TempDir = tt.tempDir
+				if err := config.Validate(); (err != nil) != tt.wantErr {
+					t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			})
+		}
+
+		// no file is left behind by the validation
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("Expected the validation to leave the directory unchanged, got %d entries", len(entries))
+		}
+	})
+
 	t.Run("LoadNegative", func(t *testing.T) {
 		t.Run("MissingConfigFile", func(t *testing.T) {
 			// Save original os.Args and restore after test
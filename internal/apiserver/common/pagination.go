@@ -0,0 +1,100 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides helpers for paginated list endpoints.
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+const (
+	QueryParamLimit = "limit"
+	QueryParamAfter = "after"
+)
+
+// Pagination is the page requested by a list request.
+type Pagination struct {
+	// Limit is the maximum number of items to return
+	Limit int
+	// After is the cursor returned by the database for the previous page, 0 for the first page
+	After int
+}
+
+// ParsePagination parses the limit and after query parameters of a list request.
+// limit defaults to defaultLimit and is clamped to maxLimit. An invalid parameter returns a bad request error.
+func ParsePagination(query url.Values, defaultLimit, maxLimit int) (Pagination, *openai.APIError) {
+	page := Pagination{Limit: defaultLimit}
+
+	if limitStr := query.Get(QueryParamLimit); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			apiErr := openai.NewAPIError(http.StatusBadRequest, "", "invalid limit parameter: must be an integer", nil)
+			return page, &apiErr
+		}
+		if limit < 1 {
+			apiErr := openai.NewAPIError(http.StatusBadRequest, "", fmt.Sprintf("invalid limit parameter: must be between 1 and %d", maxLimit), nil)
+			return page, &apiErr
+		}
+		page.Limit = min(limit, maxLimit)
+	}
+
+	if afterStr := query.Get(QueryParamAfter); afterStr != "" {
+		after, err := strconv.Atoi(afterStr)
+		if err != nil {
+			apiErr := openai.NewAPIError(http.StatusBadRequest, "", "invalid after parameter: must be an integer", nil)
+			return page, &apiErr
+		}
+		if after < 0 {
+			apiErr := openai.NewAPIError(http.StatusBadRequest, "", "invalid after parameter: must be equal to or greater than 0", nil)
+			return page, &apiErr
+		}
+		page.After = after
+	}
+
+	return page, nil
+}
+
+// TrimPage trims items fetched with a limit of limit+1 to the requested limit.
+// hasMore reports whether there were more items than the limit.
+func TrimPage[T any](items []T, limit int) (page []T, hasMore bool) {
+	if len(items) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
+
+// NewListResponse returns the list envelope of a page of items, identified by the id function.
+func NewListResponse[T any](data []T, hasMore bool, id func(T) string) openai.ListResponse[T] {
+	resp := openai.ListResponse[T]{
+		Object:  "list",
+		Data:    data,
+		HasMore: hasMore,
+	}
+	if resp.Data == nil {
+		resp.Data = []T{}
+	}
+	if len(data) > 0 {
+		resp.FirstID = id(data[0])
+		resp.LastID = id(data[len(data)-1])
+	}
+	return resp
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the pagination helpers.
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	t.Run("ParsePagination", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			query   string
+			want    Pagination
+			wantErr bool
+		}{
+			{name: "defaults", query: "", want: Pagination{Limit: 20}},
+			{name: "limit and after", query: "limit=5&after=42", want: Pagination{Limit: 5, After: 42}},
+			{name: "limit at max", query: "limit=100", want: Pagination{Limit: 100}},
+			{name: "limit above max is clamped", query: "limit=1000", want: Pagination{Limit: 100}},
+			{name: "zero limit", query: "limit=0", wantErr: true},
+			{name: "negative limit", query: "limit=-1", wantErr: true},
+			{name: "non-integer limit", query: "limit=ten", wantErr: true},
+			{name: "negative after", query: "after=-1", wantErr: true},
+			{name: "non-integer after", query: "after=batch_1", wantErr: true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				query, _ := url.ParseQuery(tt.query)
+				got, apiErr := ParsePagination(query, 20, 100)
+				if tt.wantErr {
+					if apiErr == nil {
+						t.Fatalf("expected an error, got %+v", got)
+					}
+					if apiErr.Code != http.StatusBadRequest {
+						t.Errorf("expected status %d, got %d", http.StatusBadRequest, apiErr.Code)
+					}
+					return
+				}
+				if apiErr != nil {
+					t.Fatalf("unexpected error: %v", apiErr.Message)
+				}
+				if got != tt.want {
+					t.Errorf("expected %+v, got %+v", tt.want, got)
+				}
+			})
+		}
+	})
+
+	t.Run("TrimPage", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			items       []string
+			limit       int
+			wantLen     int
+			wantHasMore bool
+		}{
+			{name: "empty", items: nil, limit: 2, wantLen: 0},
+			{name: "fewer than limit", items: []string{"a"}, limit: 2, wantLen: 1},
+			{name: "exactly limit", items: []string{"a", "b"}, limit: 2, wantLen: 2},
+			{name: "more than limit", items: []string{"a", "b", "c"}, limit: 2, wantLen: 2, wantHasMore: true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				page, hasMore := TrimPage(tt.items, tt.limit)
+				if len(page) != tt.wantLen || hasMore != tt.wantHasMore {
+					t.Errorf("expected %d items and has_more %v, got %d items and has_more %v", tt.wantLen, tt.wantHasMore, len(page), hasMore)
+				}
+			})
+		}
+	})
+
+	t.Run("NewListResponse", func(t *testing.T) {
+		id := func(s string) string { return "id-" + s }
+
+		resp := NewListResponse([]string{"a", "b", "c"}, true, id)
+		if resp.Object != "list" || resp.FirstID != "id-a" || resp.LastID != "id-c" || !resp.HasMore {
+			t.Errorf("unexpected envelope: %+v", resp)
+		}
+
+		// an empty page is encoded with an empty data array and no IDs
+		data, err := json.Marshal(NewListResponse[string](nil, false, id))
+		if err != nil {
+			t.Fatalf("failed to marshal response: %v", err)
+		}
+		want := `{"object":"list","data":[],"first_id":"","last_id":"","has_more":false}`
+		if string(data) != want {
+			t.Errorf("expected %s, got %s", want, data)
+		}
+	})
+}
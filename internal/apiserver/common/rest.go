@@ -41,7 +41,10 @@ func RegisterHandler(mux *http.ServeMux, h ApiHandler) {
 	routes := h.GetRoutes()
 	for _, route := range routes {
 		pattern := route.Method + " " + route.Pattern
-		mux.HandleFunc(pattern, route.HandlerFunc)
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			setRoutePattern(r.Context(), route.Pattern)
+			route.HandlerFunc(w, r)
+		})
 	}
 }
 
@@ -82,3 +85,36 @@ func WriteInternalServerError(ctx context.Context, w http.ResponseWriter) {
 	apiErr := openai.NewAPIError(http.StatusInternalServerError, "", "Internal Server Error", nil)
 	WriteAPIError(ctx, w, apiErr)
 }
+
+// WriteBadRequest writes a 400 error. param names the invalid parameter of the request, if any.
+func WriteBadRequest(ctx context.Context, w http.ResponseWriter, message string, param string) {
+	WriteAPIError(ctx, w, openai.NewAPIError(http.StatusBadRequest, "", message, errorParam(param)))
+}
+
+// WriteForbidden writes a 403 error. param names the parameter of the request that is not permitted, if any.
+func WriteForbidden(ctx context.Context, w http.ResponseWriter, message string, param string) {
+	WriteAPIError(ctx, w, openai.NewAPIError(http.StatusForbidden, "", message, errorParam(param)))
+}
+
+// WriteNotFound writes a 404 error.
+func WriteNotFound(ctx context.Context, w http.ResponseWriter, message string) {
+	WriteAPIError(ctx, w, openai.NewAPIError(http.StatusNotFound, "", message, nil))
+}
+
+// WriteConflict writes a 409 error.
+func WriteConflict(ctx context.Context, w http.ResponseWriter, message string) {
+	WriteAPIError(ctx, w, openai.NewAPIError(http.StatusConflict, "", message, nil))
+}
+
+// WriteUnprocessable writes a 422 error. param names the invalid parameter of the request, if any.
+func WriteUnprocessable(ctx context.Context, w http.ResponseWriter, message string, param string) {
+	WriteAPIError(ctx, w, openai.NewAPIError(http.StatusUnprocessableEntity, "", message, errorParam(param)))
+}
+
+// errorParam returns the param field of an error, which is null when the error is not about a parameter.
+func errorParam(param string) *string {
+	if param == "" {
+		return nil
+	}
+	return &param
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the error responses of the REST API.
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		write    func(ctx context.Context, w http.ResponseWriter)
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "bad request with param",
+			write: func(ctx context.Context, w http.ResponseWriter) {
+				WriteBadRequest(ctx, w, "invalid purpose", "purpose")
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":{"code":400,"type":"BadRequestError","message":"invalid purpose","param":"purpose"}}`,
+		},
+		{
+			name: "bad request without param",
+			write: func(ctx context.Context, w http.ResponseWriter) {
+				WriteBadRequest(ctx, w, "invalid multipart form", "")
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":{"code":400,"type":"BadRequestError","message":"invalid multipart form","param":null}}`,
+		},
+		{
+			name: "forbidden",
+			write: func(ctx context.Context, w http.ResponseWriter) {
+				WriteForbidden(ctx, w, "purpose not allowed", "purpose")
+			},
+			wantCode: http.StatusForbidden,
+			wantBody: `{"error":{"code":403,"type":"PermissionDeniedError","message":"purpose not allowed","param":"purpose"}}`,
+		},
+		{
+			name:     "not found",
+			write:    func(ctx context.Context, w http.ResponseWriter) { WriteNotFound(ctx, w, "File with ID f not found") },
+			wantCode: http.StatusNotFound,
+			wantBody: `{"error":{"code":404,"type":"NotFoundError","message":"File with ID f not found","param":null}}`,
+		},
+		{
+			name:     "conflict",
+			write:    func(ctx context.Context, w http.ResponseWriter) { WriteConflict(ctx, w, "already used") },
+			wantCode: http.StatusConflict,
+			wantBody: `{"error":{"code":409,"type":"ConflictError","message":"already used","param":null}}`,
+		},
+		{
+			name: "unprocessable",
+			write: func(ctx context.Context, w http.ResponseWriter) {
+				WriteUnprocessable(ctx, w, "invalid endpoint", "endpoint")
+			},
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: `{"error":{"code":422,"type":"UnprocessableEntityError","message":"invalid endpoint","param":"endpoint"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.write(context.Background(), rr)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Expected content type application/json, got %q", contentType)
+			}
+
+			// the error object has every field of the OpenAI error schema, with param null when not set
+			var got, want map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to parse error response %s: %v", rr.Body.String(), err)
+			}
+			if err := json.Unmarshal([]byte(tt.wantBody), &want); err != nil {
+				t.Fatalf("Failed to parse expected response: %v", err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("Expected error response %s, got %s", wantJSON, gotJSON)
+			}
+		})
+	}
+}
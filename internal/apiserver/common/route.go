@@ -0,0 +1,46 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides helpers for reporting the route matched by a request to the middlewares wrapping the mux.
+package common
+
+import (
+	"context"
+)
+
+type routePatternKey struct{}
+
+// WithRoutePattern returns a copy of ctx in which the handler of a registered route records its pattern,
+// e.g. "/v1/batches/{batch_id}", so that the middleware that created ctx can read it after the request is served.
+func WithRoutePattern(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routePatternKey{}, new(string))
+}
+
+// GetRoutePatternFromContext returns the pattern of the route that served the request of ctx,
+// or an empty string if no registered route matched.
+func GetRoutePatternFromContext(ctx context.Context) string {
+	if pattern, ok := ctx.Value(routePatternKey{}).(*string); ok {
+		return *pattern
+	}
+	return ""
+}
+
+// setRoutePattern records the pattern of the route serving the request of ctx.
+func setRoutePattern(ctx context.Context, pattern string) {
+	if p, ok := ctx.Value(routePatternKey{}).(*string); ok {
+		*p = pattern
+	}
+}
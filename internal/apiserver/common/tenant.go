@@ -0,0 +1,39 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides helpers for carrying the tenant of a request.
+package common
+
+import (
+	"context"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+)
+
+type tenantIDKey struct{}
+
+// WithTenantID returns a copy of ctx carrying the tenant ID.
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey{}, tenantID)
+}
+
+// GetTenantIDFromContext returns the tenant ID carried by ctx, or the default tenant if there is none.
+func GetTenantIDFromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantIDKey{}).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return batch.DefaultTenantID
+}
@@ -19,16 +19,253 @@ limitations under the License.
 package files
 
 import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	filesapi "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/ids"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+	"k8s.io/klog/v2"
 )
 
+const (
+	pathParamFileID  = "file_id"
+	pathParamPurpose = "purpose"
+
+	// defaultListLimit and maxListLimit bound the page size of ListFiles
+	defaultListLimit = 10000
+	maxListLimit     = 10000
+
+	formFieldFile    = "file"
+	formFieldPurpose = "purpose"
+
+	maxFormValueBytes    = 1024    // the size limit of the non-file fields of an upload form
+	maxFormOverheadBytes = 1 << 20 // the size of an upload request allowed besides its file
+
+	dedupKeyPrefix = "file-dedup:"
+
+	// transferRetryAfterSeconds is the delay after which a transfer rejected by the transfer limit may be retried
+	transferRetryAfterSeconds = 1
+)
+
+// rejectedContentTypeCategories and rejectedContentTypes are content types that can't be JSONL batch input files
+var (
+	rejectedContentTypeCategories = []string{"image", "audio", "video", "font", "model", "multipart"}
+	rejectedContentTypes          = []string{
+		"application/zip", "application/x-tar", "application/x-bzip2",
+		"application/x-7z-compressed", "application/vnd.rar", "application/x-rar-compressed", "application/zstd",
+		"application/pdf", "application/msword", "application/vnd.ms-excel", "application/vnd.apache.parquet",
+	}
+)
+
+// gzipContentTypes are the content types declaring a gzip compressed file
+var gzipContentTypes = []string{"application/gzip", "application/x-gzip"}
+
+// errInvalidForm is returned when the multipart form of an upload can't be parsed
+var errInvalidForm = errors.New("invalid multipart form")
+
+// errUploadTooLarge is returned when the file or the request of an upload exceeds its size limit
+var errUploadTooLarge = errors.New("upload too large")
+
+// errInvalidCompressedFile is returned when an uploaded file declared or detected as gzip can't be decompressed
+var errInvalidCompressedFile = errors.New("invalid gzip compressed file")
+
+// isBatchInputContentType reports whether an uploaded batch input file may be JSONL according to its declared
+// content type. Like the OpenAI API, it is lenient: a missing, unknown, generic or text type is accepted, and only
+// types of other kinds of content, such as archives, documents or media, are rejected.
+func isBatchInputContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	if category, _, _ := strings.Cut(mediaType, "/"); slices.Contains(rejectedContentTypeCategories, category) {
+		return false
+	}
+	return !slices.Contains(rejectedContentTypes, mediaType) && !strings.HasPrefix(mediaType, "application/vnd.openxmlformats")
+}
+
+// uploadForm is the multipart form of a file upload. The uploaded file is received in a temporary file.
+type uploadForm struct {
+	purpose string
+	file    *os.File
+	header  *multipart.FileHeader
+}
+
+// Close closes and removes the temporary file of the upload, whether or not it was stored.
+func (f *uploadForm) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	f.file.Close()
+	return os.Remove(f.file.Name())
+}
+
+// parseUploadForm reads the multipart form of a file upload as it streams in, receiving the file in a temporary
+// file of the configured temp directory. The reading stops as soon as the file exceeds MaxFileSizeBytes, or the
+// request exceeds it by more than maxFormOverheadBytes, and errUploadTooLarge is returned.
+// An error wrapping errInvalidForm is returned if the request is not a valid multipart form.
+func (c *FilesApiHandler) parseUploadForm(w http.ResponseWriter, r *http.Request) (*uploadForm, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, c.config.MaxFileSizeBytes+maxFormOverheadBytes)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidForm, err)
+	}
+
+	form := &uploadForm{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			form.Close()
+			return nil, formError(err)
+		}
+
+		switch {
+		case part.FormName() == formFieldPurpose:
+			data, err := io.ReadAll(io.LimitReader(part, maxFormValueBytes))
+			if err != nil {
+				form.Close()
+				return nil, formError(err)
+			}
+			form.purpose = string(data)
+		case part.FormName() == formFieldFile && form.file == nil:
+			if err := c.receiveFile(form, part); err != nil {
+				form.Close()
+				return nil, err
+			}
+		}
+		part.Close()
+	}
+	return form, nil
+}
+
+// receiveFile copies the file part of an upload form to a temporary file.
+func (c *FilesApiHandler) receiveFile(form *uploadForm, part *multipart.Part) error {
+	file, err := os.CreateTemp(c.config.TempDir, "upload-*")
+	if err != nil {
+		return err
+	}
+	form.file = file
+
+	size, err := io.Copy(file, io.LimitReader(part, c.config.MaxFileSizeBytes+1))
+	if err != nil {
+		if _, ok := err.(*os.PathError); ok {
+			return err
+		}
+		// the request body could not be read
+		return formError(err)
+	}
+	// the rest of the file is not read
+	if size > c.config.MaxFileSizeBytes {
+		return errUploadTooLarge
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	form.header = &multipart.FileHeader{Filename: part.FileName(), Header: part.Header, Size: size}
+	return nil
+}
+
+// formError returns the error of reading an upload form: errUploadTooLarge if the request exceeded its size limit,
+// or an error wrapping errInvalidForm.
+func formError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return errUploadTooLarge
+	}
+	return fmt.Errorf("%w: %v", errInvalidForm, err)
+}
+
+// isGzipDeclared reports whether the part header of an uploaded file declares gzip compressed content.
+func isGzipDeclared(header textproto.MIMEHeader) bool {
+	if strings.EqualFold(header.Get("Content-Encoding"), "gzip") {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && slices.Contains(gzipContentTypes, mediaType)
+}
+
+// uncompressedSize returns the size of the decompressed content of a gzip compressed file, or 0 if the file is not
+// compressed. At most limit+1 bytes are decompressed. The file is rewound to its start.
+// errInvalidCompressedFile is returned if the file is declared or detected as gzip but can't be decompressed.
+func uncompressedSize(file io.ReadSeeker, declaredGzip bool, limit int64) (int64, error) {
+	magic := make([]byte, 2)
+	n, err := io.ReadFull(file, magic)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if !batch.IsGzip(magic[:n]) {
+		if declaredGzip {
+			return 0, errInvalidCompressedFile
+		}
+		return 0, nil
+	}
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errInvalidCompressedFile, err)
+	}
+	size, err := io.Copy(io.Discard, io.LimitReader(zr, limit+1))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errInvalidCompressedFile, err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// dedupKey returns the key under which the most recent upload of a tenant's file content is recorded.
+func dedupKey(tenantID string, purpose openai.FileObjectPurpose, checksum string) string {
+	return dedupKeyPrefix + tenantID + ":" + string(purpose) + ":" + checksum
+}
+
 type FilesApiHandler struct {
+	config        *common.ServerConfig
+	dbClient      api.BatchFileDBClient
+	filesClient   filesapi.BatchFilesClient
+	statusClient  api.BatchStatusClient
+	batchDBClient api.BatchDBClient // the batches, whose input files are not deleted while they are processed
+	usage         *fileUsage        // files being downloaded, which the reaper doesn't delete
+	transfers     transferLimit
+	locate        batch.FileLocator
 }
 
-func NewFilesApiHandler() *FilesApiHandler {
-	return &FilesApiHandler{}
+func NewFilesApiHandler(config *common.ServerConfig, dbClient api.BatchFileDBClient, filesClient filesapi.BatchFilesClient, statusClient api.BatchStatusClient, batchDBClient api.BatchDBClient) *FilesApiHandler {
+	return &FilesApiHandler{
+		config:        config,
+		dbClient:      dbClient,
+		filesClient:   filesClient,
+		statusClient:  statusClient,
+		batchDBClient: batchDBClient,
+		usage:         newFileUsage(),
+		transfers:     newTransferLimit(config.MaxConcurrentTransfers),
+		locate:        batch.TenantFileLocation,
+	}
 }
 
 func (c *FilesApiHandler) GetRoutes() []common.Route {
@@ -48,6 +285,11 @@ func (c *FilesApiHandler) GetRoutes() []common.Route {
 			Pattern:     "/v1/files/{file_id}/content",
 			HandlerFunc: c.DownloadFile,
 		},
+		{
+			Method:      http.MethodHead,
+			Pattern:     "/v1/files/{file_id}/content",
+			HandlerFunc: c.DownloadFile,
+		},
 		{
 			Method:      http.MethodGet,
 			Pattern:     "/v1/files",
@@ -62,21 +304,431 @@ func (c *FilesApiHandler) GetRoutes() []common.Route {
 }
 
 func (c *FilesApiHandler) CreateFile(w http.ResponseWriter, r *http.Request) {
-	common.WriteNotImplementedError(r.Context(), w)
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+	tenantID := common.GetTenantIDFromContext(ctx)
+
+	if !c.transfers.tryAcquire() {
+		// the upload is not read, so the connection can't be reused
+		w.Header().Set("Connection", "close")
+		writeTooManyTransfers(ctx, w)
+		return
+	}
+	defer c.transfers.release()
+
+	// parse request
+	form, err := c.parseUploadForm(w, r)
+	if errors.Is(err, errUploadTooLarge) {
+		// the rest of the request is not read, so the connection can't be reused
+		w.Header().Set("Connection", "close")
+		common.WriteBadRequest(ctx, w, fmt.Sprintf("file size exceeds the limit of %d bytes", c.config.MaxFileSizeBytes), formFieldFile)
+		return
+	}
+	if errors.Is(err, errInvalidForm) {
+		logger.Error(err, "failed to parse multipart form")
+		common.WriteBadRequest(ctx, w, "invalid multipart form", "")
+		return
+	}
+	if err != nil {
+		logger.Error(err, "failed to receive uploaded file")
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	defer func() {
+		if err := form.Close(); err != nil {
+			logger.Error(err, "failed to remove temporary upload file")
+		}
+	}()
+
+	// validate request
+	purpose := openai.FileObjectPurpose(form.purpose)
+	if !purpose.IsValid() {
+		common.WriteBadRequest(ctx, w, fmt.Sprintf("invalid purpose: %q", purpose), formFieldPurpose)
+		return
+	}
+	if !c.config.IsPurposeAllowed(purpose) {
+		common.WriteForbidden(ctx, w, fmt.Sprintf("uploading files with purpose %q is not allowed", purpose), formFieldPurpose)
+		return
+	}
+
+	file, header := form.file, form.header
+	if file == nil {
+		common.WriteBadRequest(ctx, w, formFieldFile+" is required", formFieldFile)
+		return
+	}
+
+	// reject batch input files declaring an obviously wrong type, e.g. an archive
+	contentType := header.Header.Get("Content-Type")
+	logger.V(logging.DEBUG).Info("uploaded file", "filename", header.Filename, "contentType", contentType, "purpose", purpose)
+	if purpose == openai.FileObjectPurposeBatch && !isBatchInputContentType(contentType) {
+		common.WriteBadRequest(ctx, w,
+			fmt.Sprintf("invalid file content type %q: batch input files must be JSONL", contentType), formFieldFile)
+		return
+	}
+
+	// gzip compressed batch input files are stored as uploaded, and decompressed when they are read.
+	// the size limit applies to their decompressed content too
+	var uncompressedBytes int64
+	if purpose == openai.FileObjectPurposeBatch {
+		uncompressedBytes, err = uncompressedSize(file, isGzipDeclared(header.Header), c.config.MaxFileSizeBytes)
+		if errors.Is(err, errInvalidCompressedFile) {
+			common.WriteBadRequest(ctx, w, err.Error(), formFieldFile)
+			return
+		}
+		if err != nil {
+			logger.Error(err, "failed to read uploaded file")
+			common.WriteInternalServerError(ctx, w)
+			return
+		}
+		if uncompressedBytes > c.config.MaxFileSizeBytes {
+			common.WriteBadRequest(ctx, w,
+				fmt.Sprintf("decompressed file size exceeds the limit of %d bytes", c.config.MaxFileSizeBytes), formFieldFile)
+			return
+		}
+	}
+
+	// return the existing file for an identical recent upload
+	var checksum string
+	if c.config.FileDedupEnabled {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			logger.Error(err, "failed to compute file checksum")
+			common.WriteInternalServerError(ctx, w)
+			return
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			logger.Error(err, "failed to rewind file")
+			common.WriteInternalServerError(ctx, w)
+			return
+		}
+		checksum = hex.EncodeToString(hash.Sum(nil))
+
+		if fileObj := c.findDuplicate(ctx, tenantID, purpose, checksum); fileObj != nil {
+			logger.V(logging.DEBUG).Info("duplicate upload, returning existing file", "file_id", fileObj.ID)
+			common.WriteJSONResponse(ctx, w, http.StatusOK, fileObj)
+			return
+		}
+	}
+
+	fileID := ids.New(c.config.FileIDPrefix)
+	location := c.locate(tenantID, fileID)
+
+	// store file content
+	fileMd, err := c.filesClient.Store(ctx, location, c.config.MaxFileSizeBytes, file)
+	if err != nil {
+		logger.Error(err, "failed to store file", "file_id", fileID, "location", location)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+
+	// store file metadata
+	now := time.Now().UTC().Unix()
+	fileObj := openai.FileObject{
+		ID:                fileID,
+		Bytes:             int32(fileMd.Size),
+		UncompressedBytes: uncompressedBytes,
+		CreatedAt:         int32(now),
+		ExpiresAt:         int32(now + int64(c.config.FileTTLSeconds)),
+		Filename:          header.Filename,
+		Object:            "file",
+		Purpose:           purpose,
+		Status:            openai.FileObjectStatusUploaded,
+	}
+	fileObjData, err := json.Marshal(fileObj)
+	if err != nil {
+		logger.Error(err, "failed to marshal file object")
+		c.cleanupFile(ctx, location)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+
+	batchFile := &api.BatchFile{
+		ID:       fileID,
+		TTL:      c.config.FileTTLSeconds,
+		Tags:     []string{batch.TenantTag(tenantID), batch.PurposeTag(purpose)},
+		Spec:     fileObjData,
+		Location: location,
+	}
+	if _, err := c.dbClient.Store(ctx, batchFile); err != nil {
+		logger.Error(err, "failed to store file metadata", "file_id", fileID)
+		c.cleanupFile(ctx, location)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+
+	// record the upload for de-duplication
+	if c.config.FileDedupEnabled {
+		if err := c.statusClient.Set(ctx, dedupKey(tenantID, purpose, checksum), c.config.FileDedupWindowSeconds, []byte(fileID)); err != nil {
+			logger.Error(err, "failed to record upload for de-duplication", "file_id", fileID)
+		}
+	}
+
+	common.WriteJSONResponse(ctx, w, http.StatusOK, fileObj)
 }
 
 func (c *FilesApiHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
-	common.WriteNotImplementedError(r.Context(), w)
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	fileObj, ok := c.getFileFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// the input file of a batch in progress is read until the batch completes. The file is marked before its
+	// references are checked, so that a batch created concurrently either is found or is rejected by the mark
+	if fileObj.Purpose == openai.FileObjectPurposeBatch {
+		if err := c.markFileDeletion(ctx, fileObj.ID); err != nil {
+			logger.Error(err, "failed to mark file deletion", "file_id", fileObj.ID)
+			common.WriteInternalServerError(ctx, w)
+			return
+		}
+		batchID, err := c.activeBatchUsingFile(ctx, common.GetTenantIDFromContext(ctx), fileObj.ID)
+		if err != nil {
+			logger.Error(err, "failed to find the batches using file", "file_id", fileObj.ID)
+			c.unmarkFileDeletion(ctx, fileObj.ID)
+			common.WriteInternalServerError(ctx, w)
+			return
+		}
+		if batchID != "" {
+			c.unmarkFileDeletion(ctx, fileObj.ID)
+			common.WriteConflict(ctx, w, fmt.Sprintf(
+				"File with ID %s is the input file of batch %s, which is in progress. "+
+					"The file can be deleted once the batch is complete", fileObj.ID, batchID))
+			return
+		}
+	}
+
+	// content that is already gone doesn't prevent deleting the metadata of the file.
+	// the mark of a deleted file is kept until it expires, the batches created meanwhile are rejected by it
+	if err := c.filesClient.Delete(ctx, fileObj.location); err != nil && !errors.Is(err, filesapi.ErrFileNotFound) {
+		logger.Error(err, "failed to delete file", "file_id", fileObj.ID)
+		if fileObj.Purpose == openai.FileObjectPurposeBatch {
+			c.unmarkFileDeletion(ctx, fileObj.ID)
+		}
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	if _, err := c.dbClient.Delete(ctx, []string{fileObj.ID}); err != nil {
+		logger.Error(err, "failed to delete file metadata", "file_id", fileObj.ID)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+
+	resp := openai.DeleteFileResponse{
+		ID:      fileObj.ID,
+		Object:  "file",
+		Deleted: true,
+	}
+	common.WriteJSONResponse(ctx, w, http.StatusOK, resp)
 }
 
+// DownloadFile serves the content of a file. A HEAD request gets the headers of the content, with its length,
+// without the content. Byte ranges of the content can be requested with a Range header.
 func (c *FilesApiHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
-	common.WriteNotImplementedError(r.Context(), w)
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	fileObj, ok := c.getFileFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// a HEAD request doesn't transfer the content
+	if r.Method != http.MethodHead {
+		if !c.transfers.tryAcquire() {
+			writeTooManyTransfers(ctx, w)
+			return
+		}
+		defer c.transfers.release()
+	}
+
+	// the file is being deleted by the reaper
+	if !c.usage.acquireRead(fileObj.ID) {
+		writeFileNotFound(ctx, w, fileObj.ID)
+		return
+	}
+	defer c.usage.releaseRead(fileObj.ID)
+
+	reader, fileMd, err := c.filesClient.Retrieve(ctx, fileObj.location)
+	if errors.Is(err, filesapi.ErrFileNotFound) {
+		writeFileNotFound(ctx, w, fileObj.ID)
+		return
+	}
+	if err != nil {
+		logger.Error(err, "failed to retrieve file", "file_id", fileObj.ID)
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	// the content of storage backends able to seek is served with Range request support, so interrupted
+	// downloads can be resumed. Unsatisfiable ranges are answered with 416 Range Not Satisfiable
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		var modTime time.Time
+		if fileMd != nil {
+			modTime = fileMd.ModTime
+		}
+		http.ServeContent(w, r, "", modTime, seeker)
+		return
+	}
+
+	if fileMd != nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(fileMd.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, reader); err != nil {
+		logger.Error(err, "failed to write file content", "file_id", fileObj.ID)
+	}
 }
 
 func (c *FilesApiHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
-	common.WriteNotImplementedError(r.Context(), w)
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+	tenantID := common.GetTenantIDFromContext(ctx)
+
+	query := r.URL.Query()
+	page, apiErr := common.ParsePagination(query, defaultListLimit, maxListLimit)
+	if apiErr != nil {
+		common.WriteAPIError(ctx, w, *apiErr)
+		return
+	}
+
+	tags := []string{batch.TenantTag(tenantID)}
+	if purposeStr := query.Get(pathParamPurpose); purposeStr != "" {
+		tags = append(tags, batch.PurposeTag(openai.FileObjectPurpose(purposeStr)))
+	}
+
+	// Request limit+1 to check if there are more results
+	batchFiles, _, err := c.dbClient.Get(ctx, nil, tags, api.TagsLogicalCondAnd, page.After, page.Limit+1)
+	if err != nil {
+		logger.Error(err, "failed to list files from database")
+		common.WriteInternalServerError(ctx, w)
+		return
+	}
+	batchFiles, hasMore := common.TrimPage(batchFiles, page.Limit)
+
+	files := make([]openai.FileObject, 0, len(batchFiles))
+	for _, batchFile := range batchFiles {
+		fileObj := openai.FileObject{}
+		if err := json.Unmarshal(batchFile.Spec, &fileObj); err != nil {
+			logger.Error(err, "failed to unmarshal file object", "file_id", batchFile.ID)
+			continue
+		}
+		files = append(files, fileObj)
+	}
+
+	resp := common.NewListResponse(files, hasMore, func(f openai.FileObject) string { return f.ID })
+	common.WriteJSONResponse(ctx, w, http.StatusOK, resp)
 }
 
 func (c *FilesApiHandler) RetrieveFile(w http.ResponseWriter, r *http.Request) {
-	common.WriteNotImplementedError(r.Context(), w)
+	fileObj, ok := c.getFileFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	common.WriteJSONResponse(r.Context(), w, http.StatusOK, fileObj.FileObject)
+}
+
+// getFileFromPath gets the file of the file_id path parameter, if it is owned by the tenant of the request.
+// The files of other tenants are reported as not found, so that their IDs are not disclosed.
+// If the file cannot be returned, an error response is written and ok is false.
+func (c *FilesApiHandler) getFileFromPath(w http.ResponseWriter, r *http.Request) (fileObj *storedFile, ok bool) {
+	ctx := r.Context()
+	logger := logging.GetRequestLogger(r)
+
+	fileID := r.PathValue(pathParamFileID)
+	if fileID == "" {
+		common.WriteBadRequest(ctx, w, pathParamFileID+" is required", pathParamFileID)
+		return nil, false
+	}
+
+	fileObj, err := c.getFile(ctx, common.GetTenantIDFromContext(ctx), fileID)
+	if err != nil {
+		logger.Error(err, "failed to get file from database", "file_id", fileID)
+		common.WriteInternalServerError(ctx, w)
+		return nil, false
+	}
+	if fileObj == nil {
+		writeFileNotFound(ctx, w, fileID)
+		return nil, false
+	}
+
+	return fileObj, true
+}
+
+// writeTooManyTransfers rejects a transfer above the limit of transfers in flight with a 503 error,
+// and a Retry-After header telling the client when to retry it.
+func writeTooManyTransfers(ctx context.Context, w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(transferRetryAfterSeconds))
+	apiErr := openai.NewAPIError(http.StatusServiceUnavailable, "", "Too many file transfers in progress, please retry later", nil)
+	common.WriteAPIError(ctx, w, apiErr)
+}
+
+// writeFileNotFound writes the not found error of a file.
+func writeFileNotFound(ctx context.Context, w http.ResponseWriter, fileID string) {
+	common.WriteNotFound(ctx, w, fmt.Sprintf("File with ID %s not found", fileID))
+}
+
+// storedFile is the file object of a file, with the location of its content in the files storage.
+type storedFile struct {
+	*openai.FileObject
+	location string
+}
+
+// getFile gets the file object of a tenant's file. If the file does not exist, (nil, nil) is returned.
+func (c *FilesApiHandler) getFile(ctx context.Context, tenantID, fileID string) (*storedFile, error) {
+	batchFiles, _, err := c.dbClient.Get(ctx, []string{fileID}, nil, api.TagsLogicalCondNa, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(batchFiles) == 0 || !slices.Contains(batchFiles[0].Tags, batch.TenantTag(tenantID)) {
+		return nil, nil
+	}
+
+	fileObj := &openai.FileObject{}
+	if err := json.Unmarshal(batchFiles[0].Spec, fileObj); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal file object: %w", err)
+	}
+	return &storedFile{FileObject: fileObj, location: batchFiles[0].ContentLocation()}, nil
+}
+
+// findDuplicate returns the file of an identical upload recorded within the de-duplication window, if any.
+func (c *FilesApiHandler) findDuplicate(ctx context.Context, tenantID string, purpose openai.FileObjectPurpose, checksum string) *openai.FileObject {
+	logger := klog.FromContext(ctx)
+
+	data, err := c.statusClient.Get(ctx, dedupKey(tenantID, purpose, checksum))
+	if err != nil {
+		logger.Error(err, "failed to look up recent uploads")
+		return nil
+	}
+	if data == nil {
+		return nil
+	}
+
+	// the recorded file may have been deleted since
+	fileObj, err := c.getFile(ctx, tenantID, string(data))
+	if err != nil {
+		logger.Error(err, "failed to get recently uploaded file", "file_id", string(data))
+		return nil
+	}
+	if fileObj == nil {
+		return nil
+	}
+	return fileObj.FileObject
+}
+
+// cleanupFile deletes the content of a file whose upload failed.
+func (c *FilesApiHandler) cleanupFile(ctx context.Context, location string) {
+	if err := c.filesClient.Delete(ctx, location); err != nil {
+		klog.FromContext(ctx).Error(err, "failed to cleanup file after upload failure", "location", location)
+	}
 }
@@ -0,0 +1,944 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for files handler.
+package files
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	mockapi "github.com/llm-d-incubation/batch-gateway/internal/database/mock"
+	filesapi "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	mockfiles "github.com/llm-d-incubation/batch-gateway/internal/files_store/mock"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+const testFileContent = `{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"model":"m"}}` + "\n"
+
+func setupFilesApiHandlerForTest(dedup bool) *FilesApiHandler {
+	config := common.NewConfig()
+	config.FileDedupEnabled = dedup
+	dbClient := mockapi.NewMockBatchFileDBClient()
+	filesClient := mockfiles.NewMockBatchFilesClient()
+	statusClient := mockapi.NewMockBatchStatusClient()
+	return NewFilesApiHandler(config, dbClient, filesClient, statusClient, mockapi.NewMockBatchDBClient())
+}
+
+func newUploadRequest(t *testing.T, tenantID, purpose, filename, content string) *http.Request {
+	t.Helper()
+	return newUploadRequestWithContentType(t, tenantID, purpose, filename, "application/octet-stream", content)
+}
+
+// newUploadRequestWithContentType returns an upload request declaring the content type of the file, if any.
+func newUploadRequestWithContentType(t *testing.T, tenantID, purpose, filename, contentType, content string) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField(formFieldPurpose, purpose); err != nil {
+		t.Fatalf("Failed to write purpose field: %v", err)
+	}
+	partHeader := textproto.MIMEHeader{}
+	partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, formFieldFile, filename))
+	if contentType != "" {
+		partHeader.Set("Content-Type", contentType)
+	}
+	part, err := writer.CreatePart(partHeader)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		t.Fatalf("Failed to write form file: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/files", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req.WithContext(common.WithTenantID(req.Context(), tenantID))
+}
+
+func uploadFile(t *testing.T, handler *FilesApiHandler, tenantID, content string) openai.FileObject {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	handler.CreateFile(rr, newUploadRequest(t, tenantID, string(openai.FileObjectPurposeBatch), "input.jsonl", content))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("CreateFile returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var fileObj openai.FileObject
+	if err := json.NewDecoder(rr.Body).Decode(&fileObj); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	return fileObj
+}
+
+// tempDirFilesClient records the entries of a directory when a file is stored, and fails with storeErr if set.
+type tempDirFilesClient struct {
+	*mockfiles.MockBatchFilesClient
+	dir            string
+	storeErr       error
+	entriesOnStore []string
+}
+
+func (c *tempDirFilesClient) Store(ctx context.Context, location string, fileSizeLimit int64, reader io.Reader) (*filesapi.BatchFileMetadata, error) {
+	entries, _ := os.ReadDir(c.dir)
+	for _, entry := range entries {
+		c.entriesOnStore = append(c.entriesOnStore, entry.Name())
+	}
+	if c.storeErr != nil {
+		return nil, c.storeErr
+	}
+	return c.MockBatchFilesClient.Store(ctx, location, fileSizeLimit, reader)
+}
+
+// discardFilesClient stores the files without keeping their content, so large uploads don't take memory.
+type discardFilesClient struct {
+	*mockfiles.MockBatchFilesClient
+}
+
+func (c *discardFilesClient) Store(ctx context.Context, location string, fileSizeLimit int64, reader io.Reader) (*filesapi.BatchFileMetadata, error) {
+	size, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		return nil, err
+	}
+	return &filesapi.BatchFileMetadata{Location: location, Size: size}, nil
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	reader io.Reader
+	read   atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read.Add(int64(n))
+	return n, err
+}
+
+// newStreamingUploadRequest returns an upload request of a batch input file of at least size bytes, whose body is
+// written as it is read, and the reader counting the bytes of the body read by the handler.
+func newStreamingUploadRequest(t *testing.T, tenantID string, size int64) (*http.Request, *countingReader) {
+	t.Helper()
+
+	pr, pw := io.Pipe()
+	t.Cleanup(func() { pr.Close() })
+	writer := multipart.NewWriter(pw)
+	go func() {
+		if err := writer.WriteField(formFieldPurpose, string(openai.FileObjectPurposeBatch)); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		partHeader := textproto.MIMEHeader{}
+		partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="input.jsonl"`, formFieldFile))
+		partHeader.Set("Content-Type", "application/jsonl")
+		part, err := writer.CreatePart(partHeader)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		chunk := []byte(strings.Repeat(testFileContent, 64*1024/len(testFileContent)))
+		for written := int64(0); written < size; written += int64(len(chunk)) {
+			if _, err := part.Write(chunk); err != nil {
+				// the handler stopped reading the request
+				return
+			}
+		}
+		pw.CloseWithError(writer.Close())
+	}()
+
+	body := &countingReader{reader: pr}
+	req := httptest.NewRequest(http.MethodPost, "/v1/files", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req.WithContext(common.WithTenantID(req.Context(), tenantID)), body
+}
+
+func newFileRequest(method, target, tenantID, fileID string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.SetPathValue(pathParamFileID, fileID)
+	return req.WithContext(common.WithTenantID(req.Context(), tenantID))
+}
+
+func TestFilesHandler(t *testing.T) {
+
+	t.Run("CreateFile", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+		if fileObj.Object != "file" {
+			t.Errorf("Expected object to be 'file', got %v", fileObj.Object)
+		}
+		if fileObj.Purpose != openai.FileObjectPurposeBatch {
+			t.Errorf("Expected purpose to be 'batch', got %v", fileObj.Purpose)
+		}
+		if fileObj.Filename != "input.jsonl" {
+			t.Errorf("Expected filename to be 'input.jsonl', got %v", fileObj.Filename)
+		}
+		if int(fileObj.Bytes) != len(testFileContent) {
+			t.Errorf("Expected bytes to be %d, got %d", len(testFileContent), fileObj.Bytes)
+		}
+	})
+
+	t.Run("CreateFileInvalidPurpose", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+
+		rr := httptest.NewRecorder()
+		handler.CreateFile(rr, newUploadRequest(t, "tenant-a", "unknown", "input.jsonl", testFileContent))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("CreateFileAllowedPurposes", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		handler.config.AllowedPurposes = []openai.FileObjectPurpose{openai.FileObjectPurposeBatch}
+
+		rr := httptest.NewRecorder()
+		handler.CreateFile(rr, newUploadRequest(t, "tenant-a", string(openai.FileObjectPurposeFineTune), "train.jsonl", testFileContent))
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusForbidden, rr.Body.String())
+		}
+		var errResp openai.ErrorResponse
+		if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+			t.Fatalf("Failed to decode error response: %v", err)
+		}
+		if errResp.Error.Type != "PermissionDeniedError" {
+			t.Errorf("Expected a PermissionDeniedError, got %q", errResp.Error.Type)
+		}
+		if errResp.Error.Param == nil || *errResp.Error.Param != formFieldPurpose {
+			t.Errorf("Expected the error param to be %q, got %v", formFieldPurpose, errResp.Error.Param)
+		}
+
+		// the allowed purposes are still accepted
+		uploadFile(t, handler, "tenant-a", testFileContent)
+	})
+
+	t.Run("CreateFileContentType", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			purpose     openai.FileObjectPurpose
+			contentType string
+			wantStatus  int
+		}{
+			{name: "no content type", purpose: openai.FileObjectPurposeBatch, wantStatus: http.StatusOK},
+			{name: "jsonl", purpose: openai.FileObjectPurposeBatch, contentType: "application/jsonl", wantStatus: http.StatusOK},
+			{name: "ndjson", purpose: openai.FileObjectPurposeBatch, contentType: "application/x-ndjson", wantStatus: http.StatusOK},
+			{name: "plain text with charset", purpose: openai.FileObjectPurposeBatch, contentType: "text/plain; charset=utf-8", wantStatus: http.StatusOK},
+			{name: "octet stream", purpose: openai.FileObjectPurposeBatch, contentType: "application/octet-stream", wantStatus: http.StatusOK},
+			{name: "zip", purpose: openai.FileObjectPurposeBatch, contentType: "application/zip", wantStatus: http.StatusBadRequest},
+			{name: "pdf", purpose: openai.FileObjectPurposeBatch, contentType: "application/pdf", wantStatus: http.StatusBadRequest},
+			{name: "image", purpose: openai.FileObjectPurposeBatch, contentType: "image/png", wantStatus: http.StatusBadRequest},
+			{name: "image for another purpose", purpose: openai.FileObjectPurposeVision, contentType: "image/png", wantStatus: http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupFilesApiHandlerForTest(false)
+
+				rr := httptest.NewRecorder()
+				handler.CreateFile(rr, newUploadRequestWithContentType(t, "tenant-a", string(tt.purpose), "input.jsonl", tt.contentType, testFileContent))
+				if rr.Code != tt.wantStatus {
+					t.Errorf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, tt.wantStatus, rr.Body.String())
+				}
+			})
+		}
+	})
+
+	t.Run("CreateFileGzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(testFileContent))
+		zw.Close()
+		compressed := buf.String()
+
+		tests := []struct {
+			name                  string
+			contentType           string
+			content               string
+			wantStatus            int
+			wantUncompressedBytes int64
+		}{
+			{name: "detected", contentType: "application/octet-stream", content: compressed, wantStatus: http.StatusOK, wantUncompressedBytes: int64(len(testFileContent))},
+			{name: "declared", contentType: "application/gzip", content: compressed, wantStatus: http.StatusOK, wantUncompressedBytes: int64(len(testFileContent))},
+			{name: "not compressed", contentType: "application/jsonl", content: testFileContent, wantStatus: http.StatusOK},
+			{name: "declared but not compressed", contentType: "application/gzip", content: testFileContent, wantStatus: http.StatusBadRequest},
+			{name: "corrupt", contentType: "application/octet-stream", content: compressed[:len(compressed)/2], wantStatus: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupFilesApiHandlerForTest(false)
+
+				rr := httptest.NewRecorder()
+				handler.CreateFile(rr, newUploadRequestWithContentType(t, "tenant-a", string(openai.FileObjectPurposeBatch), "input.jsonl.gz", tt.contentType, tt.content))
+				if rr.Code != tt.wantStatus {
+					t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, tt.wantStatus, rr.Body.String())
+				}
+				if tt.wantStatus != http.StatusOK {
+					return
+				}
+
+				var fileObj openai.FileObject
+				if err := json.NewDecoder(rr.Body).Decode(&fileObj); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if int(fileObj.Bytes) != len(tt.content) {
+					t.Errorf("Expected bytes to be %d, got %d", len(tt.content), fileObj.Bytes)
+				}
+				if fileObj.UncompressedBytes != tt.wantUncompressedBytes {
+					t.Errorf("Expected uncompressed bytes to be %d, got %d", tt.wantUncompressedBytes, fileObj.UncompressedBytes)
+				}
+
+				// the file is stored as uploaded
+				rr = httptest.NewRecorder()
+				handler.DownloadFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID+"/content", "tenant-a", fileObj.ID))
+				if rr.Body.String() != tt.content {
+					t.Errorf("Expected the stored content to be the uploaded one")
+				}
+			})
+		}
+	})
+
+	t.Run("CreateFileGzipTooLarge", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		handler.config.MaxFileSizeBytes = 1024
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(strings.Repeat(testFileContent, 100)))
+		zw.Close()
+
+		rr := httptest.NewRecorder()
+		handler.CreateFile(rr, newUploadRequest(t, "tenant-a", string(openai.FileObjectPurposeBatch), "input.jsonl.gz", buf.String()))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+		}
+	})
+
+	t.Run("CreateFileTempDir", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		handler.config.TempDir = t.TempDir()
+		filesClient := &tempDirFilesClient{MockBatchFilesClient: mockfiles.NewMockBatchFilesClient(), dir: handler.config.TempDir}
+		handler.filesClient = filesClient
+
+		uploadFile(t, handler, "tenant-a", testFileContent)
+
+		// the upload was received in the configured directory, and removed once stored
+		if len(filesClient.entriesOnStore) != 1 || !strings.HasPrefix(filesClient.entriesOnStore[0], "upload-") {
+			t.Errorf("Expected the upload to be received in the temp dir, got entries %v", filesClient.entriesOnStore)
+		}
+		if entries, _ := os.ReadDir(handler.config.TempDir); len(entries) != 0 {
+			t.Errorf("Expected the temp dir to be empty after the upload, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("CreateFileTempCleanup", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			purpose    string
+			storeErr   error
+			wantStatus int
+		}{
+			{name: "stored", purpose: string(openai.FileObjectPurposeBatch), wantStatus: http.StatusOK},
+			{name: "store failure", purpose: string(openai.FileObjectPurposeBatch), storeErr: fmt.Errorf("storage unavailable"), wantStatus: http.StatusInternalServerError},
+			{name: "rejected", purpose: "unknown", wantStatus: http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupFilesApiHandlerForTest(false)
+				handler.config.TempDir = t.TempDir()
+				handler.filesClient = &tempDirFilesClient{
+					MockBatchFilesClient: mockfiles.NewMockBatchFilesClient(),
+					dir:                  handler.config.TempDir,
+					storeErr:             tt.storeErr,
+				}
+
+				rr := httptest.NewRecorder()
+				handler.CreateFile(rr, newUploadRequest(t, "tenant-a", tt.purpose, "input.jsonl", testFileContent))
+				if rr.Code != tt.wantStatus {
+					t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+				}
+				if entries, _ := os.ReadDir(handler.config.TempDir); len(entries) != 0 {
+					t.Errorf("Expected no temp file to remain, got %d entries", len(entries))
+				}
+			})
+		}
+	})
+
+	t.Run("CreateFileTooLarge", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		handler.config.MaxFileSizeBytes = int64(len(testFileContent)) - 1
+
+		rr := httptest.NewRecorder()
+		handler.CreateFile(rr, newUploadRequest(t, "tenant-a", string(openai.FileObjectPurposeBatch), "input.jsonl", testFileContent))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("CreateFileLarge", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skipping large upload in short mode")
+		}
+		handler := setupFilesApiHandlerForTest(false)
+		handler.config.TempDir = t.TempDir()
+		handler.filesClient = &discardFilesClient{MockBatchFilesClient: mockfiles.NewMockBatchFilesClient()}
+
+		const size = 64 * 1024 * 1024
+		req, body := newStreamingUploadRequest(t, "tenant-a", size)
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		rr := httptest.NewRecorder()
+		handler.CreateFile(rr, req)
+		runtime.ReadMemStats(&after)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var fileObj openai.FileObject
+		if err := json.NewDecoder(rr.Body).Decode(&fileObj); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		if fileObj.Bytes < size {
+			t.Errorf("Expected a file of at least %d bytes, got %d", size, fileObj.Bytes)
+		}
+		if body.read.Load() < size {
+			t.Errorf("Expected the whole request to be read, got %d bytes", body.read.Load())
+		}
+
+		// the upload streams to the temporary file instead of being buffered in memory
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/8 {
+			t.Errorf("Expected the upload to allocate less than %d bytes, got %d", size/8, allocated)
+		}
+	})
+
+	t.Run("CreateFileTooLargeStreaming", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		handler.config.TempDir = t.TempDir()
+		handler.config.MaxFileSizeBytes = 1024 * 1024
+
+		req, body := newStreamingUploadRequest(t, "tenant-a", 64*1024*1024)
+		rr := httptest.NewRecorder()
+		handler.CreateFile(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+		if !strings.Contains(rr.Body.String(), "file size exceeds the limit") {
+			t.Errorf("Expected a file size error, got %s", rr.Body.String())
+		}
+		if rr.Header().Get("Connection") != "close" {
+			t.Errorf("Expected the connection to be closed, got Connection %q", rr.Header().Get("Connection"))
+		}
+
+		// the reading stopped at the limit instead of consuming the whole upload
+		if read := body.read.Load(); read > 2*handler.config.MaxFileSizeBytes {
+			t.Errorf("Expected the reading to stop at the limit of %d bytes, read %d bytes", handler.config.MaxFileSizeBytes, read)
+		}
+		if entries, _ := os.ReadDir(handler.config.TempDir); len(entries) != 0 {
+			t.Errorf("Expected no temp file to remain, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("CreateFileConcurrencyLimit", func(t *testing.T) {
+		const maxTransfers = 3
+		config := common.NewConfig()
+		config.TempDir = t.TempDir()
+		config.MaxConcurrentTransfers = maxTransfers
+		handler := NewFilesApiHandler(config, mockapi.NewMockBatchFileDBClient(), mockfiles.NewMockBatchFilesClient(), mockapi.NewMockBatchStatusClient(), mockapi.NewMockBatchDBClient())
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+
+		// the uploads in flight wait for their body, which is only written when the test ends
+		done := make(chan struct{}, maxTransfers)
+		var bodies []*io.PipeWriter
+		for range maxTransfers {
+			pr, pw := io.Pipe()
+			bodies = append(bodies, pw)
+			req := httptest.NewRequest(http.MethodPost, "/v1/files", pr)
+			req.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+			go func() {
+				handler.CreateFile(httptest.NewRecorder(), req)
+				done <- struct{}{}
+			}()
+		}
+		defer func() {
+			for _, pw := range bodies {
+				pw.CloseWithError(io.ErrUnexpectedEOF)
+			}
+			for range maxTransfers {
+				<-done
+			}
+		}()
+		for len(handler.transfers) < maxTransfers {
+			runtime.Gosched()
+		}
+
+		rr := httptest.NewRecorder()
+		handler.CreateFile(rr, newUploadRequest(t, "tenant-a", string(openai.FileObjectPurposeBatch), "input.jsonl", testFileContent))
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusServiceUnavailable, rr.Body.String())
+		}
+		if rr.Header().Get("Retry-After") != strconv.Itoa(transferRetryAfterSeconds) {
+			t.Errorf("Expected Retry-After %d, got %q", transferRetryAfterSeconds, rr.Header().Get("Retry-After"))
+		}
+
+		// downloads share the limit, but a HEAD request doesn't transfer the content
+		for _, tt := range []struct {
+			method   string
+			wantCode int
+		}{
+			{method: http.MethodGet, wantCode: http.StatusServiceUnavailable},
+			{method: http.MethodHead, wantCode: http.StatusOK},
+		} {
+			rr := httptest.NewRecorder()
+			handler.DownloadFile(rr, newFileRequest(tt.method, "/v1/files/"+fileObj.ID+"/content", "tenant-a", fileObj.ID))
+			if rr.Code != tt.wantCode {
+				t.Errorf("%s download returned wrong status code: got %v want %v", tt.method, rr.Code, tt.wantCode)
+			}
+		}
+	})
+
+	t.Run("CreateFileDedup", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			dedup      bool
+			tenantID   string
+			content    string
+			wantSameID bool
+		}{
+			{name: "disabled", dedup: false, tenantID: "tenant-a", content: testFileContent, wantSameID: false},
+			{name: "identical re-upload", dedup: true, tenantID: "tenant-a", content: testFileContent, wantSameID: true},
+			{name: "different content", dedup: true, tenantID: "tenant-a", content: testFileContent + testFileContent, wantSameID: false},
+			{name: "different tenant", dedup: true, tenantID: "tenant-b", content: testFileContent, wantSameID: false},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupFilesApiHandlerForTest(tt.dedup)
+
+				first := uploadFile(t, handler, "tenant-a", testFileContent)
+				second := uploadFile(t, handler, tt.tenantID, tt.content)
+
+				if (first.ID == second.ID) != tt.wantSameID {
+					t.Errorf("Got file IDs %s and %s, want same ID: %v", first.ID, second.ID, tt.wantSameID)
+				}
+			})
+		}
+	})
+
+	t.Run("CreateFileDedupAfterDelete", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(true)
+
+		first := uploadFile(t, handler, "tenant-a", testFileContent)
+		rr := httptest.NewRecorder()
+		handler.DeleteFile(rr, newFileRequest(http.MethodDelete, "/v1/files/"+first.ID, "tenant-a", first.ID))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("DeleteFile returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+
+		second := uploadFile(t, handler, "tenant-a", testFileContent)
+		if first.ID == second.ID {
+			t.Errorf("Expected a new file ID after the original file was deleted, got %s", second.ID)
+		}
+	})
+
+	t.Run("RetrieveFile", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+
+		rr := httptest.NewRecorder()
+		handler.RetrieveFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID, "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var got openai.FileObject
+		if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		if got.ID != fileObj.ID {
+			t.Errorf("Expected file ID %s, got %s", fileObj.ID, got.ID)
+		}
+
+		// files of other tenants are not visible
+		rr = httptest.NewRecorder()
+		handler.RetrieveFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID, "tenant-b", fileObj.ID))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("DownloadFile", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+
+		rr := httptest.NewRecorder()
+		handler.DownloadFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID+"/content", "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if rr.Body.String() != testFileContent {
+			t.Errorf("Expected content %q, got %q", testFileContent, rr.Body.String())
+		}
+	})
+
+	t.Run("FileOwnership", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			method   string
+			suffix   string
+			serve    func(h *FilesApiHandler) http.HandlerFunc
+			tenantID string
+			wantCode int
+		}{
+			{name: "owner retrieves", method: http.MethodGet, serve: func(h *FilesApiHandler) http.HandlerFunc { return h.RetrieveFile }, tenantID: "tenant-a", wantCode: http.StatusOK},
+			{name: "owner downloads", method: http.MethodGet, suffix: "/content", serve: func(h *FilesApiHandler) http.HandlerFunc { return h.DownloadFile }, tenantID: "tenant-a", wantCode: http.StatusOK},
+			{name: "owner deletes", method: http.MethodDelete, serve: func(h *FilesApiHandler) http.HandlerFunc { return h.DeleteFile }, tenantID: "tenant-a", wantCode: http.StatusOK},
+			{name: "other tenant retrieves", method: http.MethodGet, serve: func(h *FilesApiHandler) http.HandlerFunc { return h.RetrieveFile }, tenantID: "tenant-b", wantCode: http.StatusNotFound},
+			{name: "other tenant downloads", method: http.MethodGet, suffix: "/content", serve: func(h *FilesApiHandler) http.HandlerFunc { return h.DownloadFile }, tenantID: "tenant-b", wantCode: http.StatusNotFound},
+			{name: "other tenant deletes", method: http.MethodDelete, serve: func(h *FilesApiHandler) http.HandlerFunc { return h.DeleteFile }, tenantID: "tenant-b", wantCode: http.StatusNotFound},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupFilesApiHandlerForTest(false)
+				fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+
+				rr := httptest.NewRecorder()
+				tt.serve(handler)(rr, newFileRequest(tt.method, "/v1/files/"+fileObj.ID+tt.suffix, tt.tenantID, fileObj.ID))
+				if rr.Code != tt.wantCode {
+					t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, tt.wantCode)
+				}
+				if tt.wantCode == http.StatusNotFound && strings.Contains(rr.Body.String(), "req-1") {
+					t.Errorf("Expected no content of the file, got %s", rr.Body.String())
+				}
+
+				// the file of the owner is kept when another tenant deletes it
+				if tt.method == http.MethodDelete && tt.tenantID != "tenant-a" {
+					rr = httptest.NewRecorder()
+					handler.RetrieveFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID, "tenant-a", fileObj.ID))
+					if rr.Code != http.StatusOK {
+						t.Errorf("Expected the file to be kept, got status %v", rr.Code)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("HeadFileContent", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+		mux := http.NewServeMux()
+		common.RegisterHandler(mux, handler)
+
+		serve := func(method string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, "/v1/files/"+fileObj.ID+"/content", nil)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req.WithContext(common.WithTenantID(req.Context(), "tenant-a")))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("%s returned wrong status code: got %v want %v", method, rr.Code, http.StatusOK)
+			}
+			return rr
+		}
+
+		head := serve(http.MethodHead)
+		if head.Body.Len() != 0 {
+			t.Errorf("Expected no body, got %q", head.Body.String())
+		}
+		if want := strconv.Itoa(len(testFileContent)); head.Header().Get("Content-Length") != want {
+			t.Errorf("Expected Content-Length %s, got %q", want, head.Header().Get("Content-Length"))
+		}
+
+		get := serve(http.MethodGet)
+		for _, name := range []string{"Content-Length", "Content-Type"} {
+			if head.Header().Get(name) != get.Header().Get(name) {
+				t.Errorf("Expected %s %q to match the GET one %q", name, head.Header().Get(name), get.Header().Get(name))
+			}
+		}
+		if strconv.Itoa(get.Body.Len()) != get.Header().Get("Content-Length") {
+			t.Errorf("Expected a body of Content-Length bytes, got %d bytes", get.Body.Len())
+		}
+	})
+
+	t.Run("DownloadFileRange", func(t *testing.T) {
+		size := len(testFileContent)
+		tests := []struct {
+			name             string
+			rangeHeader      string
+			wantStatus       int
+			wantContentRange string
+			wantBody         string
+		}{
+			{name: "valid range", rangeHeader: "bytes=0-9", wantStatus: http.StatusPartialContent,
+				wantContentRange: fmt.Sprintf("bytes 0-9/%d", size), wantBody: testFileContent[:10]},
+			{name: "open-ended range", rangeHeader: "bytes=10-", wantStatus: http.StatusPartialContent,
+				wantContentRange: fmt.Sprintf("bytes 10-%d/%d", size-1, size), wantBody: testFileContent[10:]},
+			{name: "unsatisfiable range", rangeHeader: fmt.Sprintf("bytes=%d-", size+10), wantStatus: http.StatusRequestedRangeNotSatisfiable,
+				wantContentRange: fmt.Sprintf("bytes */%d", size)},
+			{name: "no range", wantStatus: http.StatusOK, wantBody: testFileContent},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupFilesApiHandlerForTest(false)
+				fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+
+				req := newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID+"/content", "tenant-a", fileObj.ID)
+				if tt.rangeHeader != "" {
+					req.Header.Set("Range", tt.rangeHeader)
+				}
+				rr := httptest.NewRecorder()
+				handler.DownloadFile(rr, req)
+
+				if rr.Code != tt.wantStatus {
+					t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+				}
+				if got := rr.Header().Get("Content-Range"); got != tt.wantContentRange {
+					t.Errorf("Expected Content-Range %q, got %q", tt.wantContentRange, got)
+				}
+				if tt.wantStatus != http.StatusRequestedRangeNotSatisfiable && rr.Body.String() != tt.wantBody {
+					t.Errorf("Expected content %q, got %q", tt.wantBody, rr.Body.String())
+				}
+			})
+		}
+	})
+
+	t.Run("ListFiles", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		uploadFile(t, handler, "tenant-a", testFileContent)
+		uploadFile(t, handler, "tenant-a", testFileContent)
+		uploadFile(t, handler, "tenant-b", testFileContent)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/files?limit=1", nil)
+		req = req.WithContext(common.WithTenantID(req.Context(), "tenant-a"))
+		rr := httptest.NewRecorder()
+		handler.ListFiles(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+
+		var resp openai.ListFilesResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		if len(resp.Data) != 1 || !resp.HasMore {
+			t.Errorf("Expected 1 file with more available, got %d files, has_more %v", len(resp.Data), resp.HasMore)
+		}
+	})
+
+	t.Run("DeleteFile", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+
+		rr := httptest.NewRecorder()
+		handler.DeleteFile(rr, newFileRequest(http.MethodDelete, "/v1/files/"+fileObj.ID, "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var resp openai.DeleteFileResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		if resp.ID != fileObj.ID || !resp.Deleted {
+			t.Errorf("Unexpected delete response: %+v", resp)
+		}
+
+		rr = httptest.NewRecorder()
+		handler.RetrieveFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID, "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("DeleteInputFileInUse", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+
+		// a batch in progress reads the file, another batch of the tenant reads another file
+		status, _ := json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusInProgress})
+		for id, fileID := range map[string]string{"batch-1": fileObj.ID, "batch-2": "file-other"} {
+			spec, _ := json.Marshal(openai.BatchSpec{InputFileID: fileID})
+			tags := []string{batch.TenantTag("tenant-a"), batch.InputFileTag(fileID)}
+			job := &api.BatchJob{ID: id, TTL: 3600, Tags: tags, Spec: spec, Status: status}
+			if _, err := handler.batchDBClient.Store(context.Background(), job); err != nil {
+				t.Fatalf("Failed to store batch: %v", err)
+			}
+		}
+
+		rr := httptest.NewRecorder()
+		handler.DeleteFile(rr, newFileRequest(http.MethodDelete, "/v1/files/"+fileObj.ID, "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+		}
+		if !strings.Contains(rr.Body.String(), "batch-1") {
+			t.Errorf("Expected the error to name the batch using the file, got %s", rr.Body.String())
+		}
+		rr = httptest.NewRecorder()
+		handler.RetrieveFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID, "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected the file to be kept, got status %v", rr.Code)
+		}
+		if mark, _ := handler.statusClient.Get(context.Background(), batch.FileDeletionKey(fileObj.ID)); mark != nil {
+			t.Errorf("Expected the deletion mark of the kept file to be removed, got %q", mark)
+		}
+
+		// the file can be deleted once the batch completed
+		status, _ = json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusCompleted})
+		if err := handler.batchDBClient.Update(context.Background(), &api.BatchJob{ID: "batch-1", Status: status}); err != nil {
+			t.Fatalf("Failed to update batch: %v", err)
+		}
+		rr = httptest.NewRecorder()
+		handler.DeleteFile(rr, newFileRequest(http.MethodDelete, "/v1/files/"+fileObj.ID, "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusOK {
+			t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		// the mark of the deleted file rejects the batches created on it meanwhile
+		if mark, _ := handler.statusClient.Get(context.Background(), batch.FileDeletionKey(fileObj.ID)); mark == nil {
+			t.Errorf("Expected the deleted file to be marked")
+		}
+	})
+
+	t.Run("FileLocation", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		handler.locate = func(tenantID, fileID string) string { return "custom/" + tenantID + "/" + fileID }
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+		location := "custom/tenant-a/" + fileObj.ID
+
+		// the location of the content is recorded with the file
+		records, _, err := handler.dbClient.Get(context.Background(), []string{fileObj.ID}, nil, api.TagsLogicalCondNa, 0, 1)
+		if err != nil || len(records) != 1 {
+			t.Fatalf("Failed to get file record: %v", err)
+		}
+		if records[0].Location != location {
+			t.Errorf("Expected location %q, got %q", location, records[0].Location)
+		}
+		if _, _, err := handler.filesClient.Retrieve(context.Background(), location); err != nil {
+			t.Errorf("Expected the content to be stored at %q: %v", location, err)
+		}
+
+		// the file ID is resolved to the location
+		rr := httptest.NewRecorder()
+		handler.DownloadFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID+"/content", "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusOK || rr.Body.String() != testFileContent {
+			t.Errorf("Expected the content of the file, got status %d and %q", rr.Code, rr.Body.String())
+		}
+		rr = httptest.NewRecorder()
+		handler.DeleteFile(rr, newFileRequest(http.MethodDelete, "/v1/files/"+fileObj.ID, "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if _, _, err := handler.filesClient.Retrieve(context.Background(), location); err == nil {
+			t.Errorf("Expected the content at %q to be deleted", location)
+		}
+	})
+
+	t.Run("FileWithoutLocation", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+
+		// files recorded without a location are stored at their ID
+		spec, _ := json.Marshal(openai.FileObject{ID: "file-legacy", Object: "file", Purpose: openai.FileObjectPurposeBatch})
+		if _, err := handler.filesClient.Store(context.Background(), "file-legacy", 0, strings.NewReader(testFileContent)); err != nil {
+			t.Fatalf("Failed to store file: %v", err)
+		}
+		batchFile := &api.BatchFile{ID: "file-legacy", TTL: 3600, Tags: []string{batch.TenantTag("tenant-a")}, Spec: spec}
+		if _, err := handler.dbClient.Store(context.Background(), batchFile); err != nil {
+			t.Fatalf("Failed to store file record: %v", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.DownloadFile(rr, newFileRequest(http.MethodGet, "/v1/files/file-legacy/content", "tenant-a", "file-legacy"))
+		if rr.Code != http.StatusOK || rr.Body.String() != testFileContent {
+			t.Errorf("Expected the content of the file, got status %d and %q", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			method string
+			target string
+			serve  func(h *FilesApiHandler) http.HandlerFunc
+		}{
+			{name: "retrieve", method: http.MethodGet, target: "/v1/files/file-missing", serve: func(h *FilesApiHandler) http.HandlerFunc { return h.RetrieveFile }},
+			{name: "download", method: http.MethodGet, target: "/v1/files/file-missing/content", serve: func(h *FilesApiHandler) http.HandlerFunc { return h.DownloadFile }},
+			{name: "delete", method: http.MethodDelete, target: "/v1/files/file-missing", serve: func(h *FilesApiHandler) http.HandlerFunc { return h.DeleteFile }},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler := setupFilesApiHandlerForTest(false)
+
+				rr := httptest.NewRecorder()
+				tt.serve(handler)(rr, newFileRequest(tt.method, tt.target, "tenant-a", "file-missing"))
+				if rr.Code != http.StatusNotFound {
+					t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+				}
+				var errResp openai.ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&errResp); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if !strings.Contains(errResp.Error.Message, "file-missing") {
+					t.Errorf("Expected the error message to contain the file ID, got %q", errResp.Error.Message)
+				}
+			})
+		}
+	})
+
+	t.Run("MissingFileContent", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+		if err := handler.filesClient.Delete(context.Background(), batch.TenantFileLocation("tenant-a", fileObj.ID)); err != nil {
+			t.Fatalf("Failed to delete file content: %v", err)
+		}
+
+		// the content is reported as not found rather than as an internal error
+		rr := httptest.NewRecorder()
+		handler.DownloadFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileObj.ID+"/content", "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+
+		// the metadata of the file can still be deleted
+		rr = httptest.NewRecorder()
+		handler.DeleteFile(rr, newFileRequest(http.MethodDelete, "/v1/files/"+fileObj.ID, "tenant-a", fileObj.ID))
+		if rr.Code != http.StatusOK {
+			t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+	})
+}
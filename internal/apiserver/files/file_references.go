@@ -0,0 +1,69 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file finds the batches referencing a file, which must not be deleted while they are processed.
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"k8s.io/klog/v2"
+)
+
+// referencesPageSize is the number of batches fetched at a time when looking for the batches referencing a file
+const referencesPageSize = 100
+
+// activeBatchUsingFile returns the ID of a batch of the tenant that is not final and has the file as input file,
+// or an empty string if there is none. Only the batches tagged with the input file are read.
+func (c *FilesApiHandler) activeBatchUsingFile(ctx context.Context, tenantID, fileID string) (string, error) {
+	tags := []string{batch.TenantTag(tenantID), batch.InputFileTag(fileID)}
+	for start := 0; ; {
+		jobs, cursor, err := c.batchDBClient.Get(ctx, nil, tags, api.TagsLogicalCondAnd, false, start, referencesPageSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to list batches: %w", err)
+		}
+		for _, job := range jobs {
+			statusInfo := openai.BatchStatusInfo{}
+			if err := json.Unmarshal(job.Status, &statusInfo); err != nil {
+				return "", fmt.Errorf("failed to parse status of batch %s: %w", job.ID, err)
+			}
+			if !statusInfo.Status.IsFinal() {
+				return job.ID, nil
+			}
+		}
+		if cursor == 0 || len(jobs) < referencesPageSize {
+			return "", nil
+		}
+		start = cursor
+	}
+}
+
+// markFileDeletion marks the file as being deleted, so that no batch is created on it once its references are checked.
+func (c *FilesApiHandler) markFileDeletion(ctx context.Context, fileID string) error {
+	return c.statusClient.Set(ctx, batch.FileDeletionKey(fileID), batch.FileDeletionTTLSeconds, []byte(fileID))
+}
+
+// unmarkFileDeletion removes the mark of a file that is kept.
+func (c *FilesApiHandler) unmarkFileDeletion(ctx context.Context, fileID string) {
+	if err := c.statusClient.Delete(ctx, batch.FileDeletionKey(fileID)); err != nil {
+		klog.FromContext(ctx).Error(err, "failed to remove the deletion mark of file", "file_id", fileID)
+	}
+}
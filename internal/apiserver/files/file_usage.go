@@ -0,0 +1,72 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file tracks the files being read and deleted, so that a file isn't deleted in the middle of a download.
+package files
+
+import "sync"
+
+// fileUsage tracks the files being downloaded and the files being deleted by the reaper.
+type fileUsage struct {
+	mu       sync.Mutex
+	readers  map[string]int
+	deleting map[string]struct{}
+}
+
+func newFileUsage() *fileUsage {
+	return &fileUsage{
+		readers:  make(map[string]int),
+		deleting: make(map[string]struct{}),
+	}
+}
+
+// acquireRead registers a reader of the file. It returns false if the file is being deleted.
+// A successful call must be followed by releaseRead.
+func (u *fileUsage) acquireRead(fileID string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, ok := u.deleting[fileID]; ok {
+		return false
+	}
+	u.readers[fileID]++
+	return true
+}
+
+func (u *fileUsage) releaseRead(fileID string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.readers[fileID]--; u.readers[fileID] <= 0 {
+		delete(u.readers, fileID)
+	}
+}
+
+// acquireDelete registers the deletion of the file. It returns false if the file is being read.
+// A successful call must be followed by releaseDelete.
+func (u *fileUsage) acquireDelete(fileID string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.readers[fileID] > 0 {
+		return false
+	}
+	u.deleting[fileID] = struct{}{}
+	return true
+}
+
+func (u *fileUsage) releaseDelete(fileID string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.deleting, fileID)
+}
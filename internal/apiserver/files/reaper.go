@@ -0,0 +1,137 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the reaper deleting uploaded files past their expiration time.
+// Files being downloaded are skipped, and deleted by a later pass.
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/metrics"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	filesapi "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+	"k8s.io/klog/v2"
+)
+
+// reaperPageSize is the number of files fetched at a time by a reaper pass
+const reaperPageSize = 100
+
+// FileReaper deletes the files whose expiration time has passed.
+type FileReaper struct {
+	dbClient    api.BatchFileDBClient
+	filesClient filesapi.BatchFilesClient
+	usage       *fileUsage
+	now         func() time.Time // replaced in tests
+}
+
+// NewReaper returns a reaper of the files of the handler, which doesn't delete the files it is serving.
+func (c *FilesApiHandler) NewReaper() *FileReaper {
+	return &FileReaper{
+		dbClient:    c.dbClient,
+		filesClient: c.filesClient,
+		usage:       c.usage,
+		now:         time.Now,
+	}
+}
+
+// Run reaps expired files every interval until ctx is done.
+func (r *FileReaper) Run(ctx context.Context, interval time.Duration) {
+	logger := klog.FromContext(ctx).WithName("file_reaper")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reaped, err := r.Reap(ctx)
+			if err != nil {
+				logger.Error(err, "failed to reap expired files")
+			}
+			if reaped > 0 {
+				logger.V(logging.INFO).Info("reaped expired files", "count", reaped)
+			}
+		}
+	}
+}
+
+// Reap deletes the content and metadata of expired files, and returns the number of deleted files.
+func (r *FileReaper) Reap(ctx context.Context) (int, error) {
+	logger := klog.FromContext(ctx)
+	now := r.now().Unix()
+
+	var expired []*api.BatchFile
+	for start := 0; ; {
+		batchFiles, cursor, err := r.dbClient.Get(ctx, nil, nil, api.TagsLogicalCondNa, start, reaperPageSize)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list files: %w", err)
+		}
+		for _, batchFile := range batchFiles {
+			fileObj := openai.FileObject{}
+			if err := json.Unmarshal(batchFile.Spec, &fileObj); err != nil {
+				logger.Error(err, "failed to unmarshal file object", "file_id", batchFile.ID)
+				continue
+			}
+			if fileObj.ExpiresAt > 0 && int64(fileObj.ExpiresAt) <= now {
+				expired = append(expired, batchFile)
+			}
+		}
+		if cursor <= start || len(batchFiles) < reaperPageSize {
+			break
+		}
+		start = cursor
+	}
+
+	reaped := 0
+	for _, batchFile := range expired {
+		deleted, err := r.deleteFile(ctx, batchFile)
+		if err != nil {
+			logger.Error(err, "failed to delete expired file", "file_id", batchFile.ID)
+			continue
+		}
+		if !deleted {
+			logger.V(logging.DEBUG).Info("expired file is in use, deferring its deletion", "file_id", batchFile.ID)
+			continue
+		}
+		reaped++
+	}
+	metrics.RecordExpiredFilesReaped(reaped)
+	return reaped, nil
+}
+
+// deleteFile deletes a file unless it is being downloaded, in which case false is returned.
+func (r *FileReaper) deleteFile(ctx context.Context, batchFile *api.BatchFile) (bool, error) {
+	if !r.usage.acquireDelete(batchFile.ID) {
+		return false, nil
+	}
+	defer r.usage.releaseDelete(batchFile.ID)
+
+	if err := r.filesClient.Delete(ctx, batchFile.ContentLocation()); err != nil && !errors.Is(err, filesapi.ErrFileNotFound) {
+		return false, err
+	}
+	if _, err := r.dbClient.Delete(ctx, []string{batchFile.ID}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
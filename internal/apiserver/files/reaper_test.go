@@ -0,0 +1,162 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the file reaper.
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+func TestFileReaper(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("expired files are deleted", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+		reaper := handler.NewReaper()
+
+		// the file is retained until it expires
+		if reaped, err := reaper.Reap(ctx); err != nil || reaped != 0 {
+			t.Fatalf("Expected no file to be reaped, got %d, err %v", reaped, err)
+		}
+		assertFileStatus(t, handler, fileObj.ID, http.StatusOK)
+
+		reaper.now = func() time.Time { return time.Unix(int64(fileObj.ExpiresAt), 0) }
+		if reaped, err := reaper.Reap(ctx); err != nil || reaped != 1 {
+			t.Fatalf("Expected 1 file to be reaped, got %d, err %v", reaped, err)
+		}
+		assertFileStatus(t, handler, fileObj.ID, http.StatusNotFound)
+		if _, _, err := handler.filesClient.Retrieve(ctx, batch.TenantFileLocation("tenant-a", fileObj.ID)); err == nil {
+			t.Error("Expected the content of the expired file to be deleted")
+		}
+	})
+
+	t.Run("non-expired files are retained", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		expired := uploadFile(t, handler, "tenant-a", testFileContent)
+		handler.config.FileTTLSeconds *= 2
+		retained := uploadFile(t, handler, "tenant-a", testFileContent)
+
+		reaper := handler.NewReaper()
+		reaper.now = func() time.Time { return time.Unix(int64(expired.ExpiresAt), 0) }
+		if reaped, err := reaper.Reap(ctx); err != nil || reaped != 1 {
+			t.Fatalf("Expected 1 file to be reaped, got %d, err %v", reaped, err)
+		}
+		assertFileStatus(t, handler, expired.ID, http.StatusNotFound)
+		assertFileStatus(t, handler, retained.ID, http.StatusOK)
+	})
+
+	t.Run("output files expire per their batch policy", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+
+		// an output file recorded by the processor under a 3600s output_expires_after policy
+		createdAt := time.Now().Unix()
+		fileObj := openai.FileObject{
+			ID:        "file-output",
+			CreatedAt: int32(createdAt),
+			ExpiresAt: int32(createdAt + 3600),
+			Filename:  "batch_output.jsonl",
+			Object:    "file",
+			Purpose:   openai.FileObjectPurposeBatchOutput,
+		}
+		spec, _ := json.Marshal(fileObj)
+		if _, err := handler.filesClient.Store(ctx, fileObj.ID, 0, strings.NewReader(testFileContent)); err != nil {
+			t.Fatalf("Failed to store file: %v", err)
+		}
+		batchFile := &api.BatchFile{ID: fileObj.ID, TTL: 3600, Tags: []string{batch.TenantTag("tenant-a")}, Spec: spec}
+		if _, err := handler.dbClient.Store(ctx, batchFile); err != nil {
+			t.Fatalf("Failed to store file record: %v", err)
+		}
+
+		reaper := handler.NewReaper()
+		reaper.now = func() time.Time { return time.Unix(createdAt, 0).Add(time.Hour - time.Second) }
+		if reaped, err := reaper.Reap(ctx); err != nil || reaped != 0 {
+			t.Fatalf("Expected no file to be reaped before an hour, got %d, err %v", reaped, err)
+		}
+		assertFileStatus(t, handler, fileObj.ID, http.StatusOK)
+
+		reaper.now = func() time.Time { return time.Unix(createdAt, 0).Add(time.Hour) }
+		if reaped, err := reaper.Reap(ctx); err != nil || reaped != 1 {
+			t.Fatalf("Expected the output file to be reaped after an hour, got %d, err %v", reaped, err)
+		}
+		assertFileStatus(t, handler, fileObj.ID, http.StatusNotFound)
+	})
+
+	t.Run("files being downloaded are deferred", func(t *testing.T) {
+		handler := setupFilesApiHandlerForTest(false)
+		fileObj := uploadFile(t, handler, "tenant-a", testFileContent)
+		reaper := handler.NewReaper()
+		reaper.now = func() time.Time { return time.Unix(int64(fileObj.ExpiresAt), 0) }
+
+		if !handler.usage.acquireRead(fileObj.ID) {
+			t.Fatal("Expected the file to be readable")
+		}
+		if reaped, err := reaper.Reap(ctx); err != nil || reaped != 0 {
+			t.Fatalf("Expected the file being downloaded not to be reaped, got %d, err %v", reaped, err)
+		}
+		assertFileStatus(t, handler, fileObj.ID, http.StatusOK)
+
+		handler.usage.releaseRead(fileObj.ID)
+		if reaped, err := reaper.Reap(ctx); err != nil || reaped != 1 {
+			t.Fatalf("Expected the file to be reaped after its download, got %d, err %v", reaped, err)
+		}
+	})
+}
+
+func TestFileUsage(t *testing.T) {
+	usage := newFileUsage()
+
+	if !usage.acquireDelete("file-1") {
+		t.Fatal("Expected an unused file to be deletable")
+	}
+	if usage.acquireRead("file-1") {
+		t.Error("Expected a file being deleted not to be readable")
+	}
+	usage.releaseDelete("file-1")
+
+	if !usage.acquireRead("file-1") || !usage.acquireRead("file-1") {
+		t.Fatal("Expected concurrent reads to be allowed")
+	}
+	usage.releaseRead("file-1")
+	if usage.acquireDelete("file-1") {
+		t.Error("Expected a file being read not to be deletable")
+	}
+	usage.releaseRead("file-1")
+	if !usage.acquireDelete("file-1") {
+		t.Error("Expected the file to be deletable after its reads")
+	}
+}
+
+// assertFileStatus checks the status of downloading the file of tenant-a.
+func assertFileStatus(t *testing.T, handler *FilesApiHandler, fileID string, wantStatus int) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	handler.DownloadFile(rr, newFileRequest(http.MethodGet, "/v1/files/"+fileID+"/content", "tenant-a", fileID))
+	if rr.Code != wantStatus {
+		t.Errorf("Expected status %d for file %s, got %d", wantStatus, fileID, rr.Code)
+	}
+}
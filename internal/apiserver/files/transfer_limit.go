@@ -0,0 +1,50 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file limits the number of file uploads and downloads in flight, so that a burst of large
+// transfers can't exhaust the disk and memory of the node.
+package files
+
+// transferLimit is a semaphore of the transfers in flight. A nil transferLimit doesn't limit the transfers.
+type transferLimit chan struct{}
+
+// newTransferLimit returns the limit of max transfers in flight, or nil if max is not positive.
+func newTransferLimit(max int) transferLimit {
+	if max <= 0 {
+		return nil
+	}
+	return make(transferLimit, max)
+}
+
+// tryAcquire registers a transfer without waiting. It returns false if the limit is reached.
+// A successful call must be followed by release.
+func (l transferLimit) tryAcquire() bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l transferLimit) release() {
+	if l != nil {
+		<-l
+	}
+}
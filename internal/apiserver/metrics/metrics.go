@@ -18,16 +18,21 @@ limitations under the License.
 package metrics
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// UnmatchedRoute is the route label of requests that didn't match a registered route.
+const UnmatchedRoute = "unmatched"
+
 var (
 	httpRequestsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
 			Help: "Total number of HTTP requests to the api server",
 		},
-		[]string{"method", "path", "status"},
+		[]string{"method", "route", "status_class"},
 	)
 	httpRequestDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -35,7 +40,25 @@ var (
 			Help:    "HTTP request duration in seconds for the api server",
 			Buckets: prometheus.DefBuckets,
 		},
-		[]string{"method", "path", "status"},
+		[]string{"method", "route", "status_class"},
+	)
+	batchesReapedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "batches_reaped_total",
+			Help: "Total number of final batches deleted after their TTL",
+		},
+	)
+	filesReapedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "batch_files_reaped_total",
+			Help: "Total number of files deleted with batches reaped after their TTL",
+		},
+	)
+	expiredFilesReapedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "expired_files_reaped_total",
+			Help: "Total number of uploaded files deleted after their expiration time",
+		},
 	)
 	httpRequestsInFlight = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -49,14 +72,42 @@ func init() {
 	prometheus.MustRegister(httpRequestsTotal)
 	prometheus.MustRegister(httpRequestDuration)
 	prometheus.MustRegister(httpRequestsInFlight)
+	prometheus.MustRegister(batchesReapedTotal)
+	prometheus.MustRegister(filesReapedTotal)
+	prometheus.MustRegister(expiredFilesReapedTotal)
 }
 
 func RecordRequestStart() {
 	httpRequestsInFlight.Inc()
 }
 
-func RecordRequestFinish(method, path, status string, durationSeconds float64) {
+// RecordRequestFinish records a served request. route is the pattern of the matched route rather than the
+// request path, and statusCode is reported by class (e.g. "2xx"), to keep the cardinality of the labels bounded.
+func RecordRequestFinish(method, route string, statusCode int, durationSeconds float64) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	statusClass := StatusClass(statusCode)
 	httpRequestsInFlight.Dec()
-	httpRequestsTotal.WithLabelValues(method, path, status).Inc()
-	httpRequestDuration.WithLabelValues(method, path, status).Observe(durationSeconds)
+	httpRequestsTotal.WithLabelValues(method, route, statusClass).Inc()
+	httpRequestDuration.WithLabelValues(method, route, statusClass).Observe(durationSeconds)
+}
+
+// StatusClass returns the class of an HTTP status code, e.g. "4xx" for 404.
+func StatusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
+
+// RecordBatchReaped records a batch deleted after its TTL, with the number of its files that were deleted.
+func RecordBatchReaped(files int) {
+	batchesReapedTotal.Inc()
+	filesReapedTotal.Add(float64(files))
+}
+
+// RecordExpiredFilesReaped records files deleted after their expiration time.
+func RecordExpiredFilesReaped(files int) {
+	expiredFilesReapedTotal.Add(float64(files))
 }
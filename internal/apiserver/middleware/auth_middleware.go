@@ -0,0 +1,93 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file implements authentication middleware that validates API keys and identifies the tenant of a request.
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/health"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/metrics"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+const bearerPrefix = "Bearer "
+
+// APIKeyValidator validates the API key of a request.
+type APIKeyValidator interface {
+	// Validate returns the ID of the tenant the API key belongs to, and false if the key is not valid.
+	Validate(ctx context.Context, apiKey string) (tenantID string, ok bool)
+}
+
+// StaticAPIKeyValidator validates API keys against a fixed set of keys, mapped to the ID of their tenant.
+type StaticAPIKeyValidator map[string]string
+
+func (v StaticAPIKeyValidator) Validate(ctx context.Context, apiKey string) (string, bool) {
+	for key, tenantID := range v {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+			return tenantID, true
+		}
+	}
+	return "", false
+}
+
+// AuthMiddleware rejects requests without a valid API key in the "Authorization: Bearer <key>" header,
+// and places the tenant of the key in the request context for the downstream handlers.
+// The /health and /metrics endpoints are not authenticated.
+func AuthMiddleware(validator APIKeyValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == health.HealthPath || r.URL.Path == metrics.MetricsPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			apiKey, ok := bearerToken(r)
+			if !ok {
+				apiErr := openai.NewAPIError(http.StatusUnauthorized, "", "Missing API key in the Authorization header", nil)
+				common.WriteAPIError(ctx, w, apiErr)
+				return
+			}
+
+			tenantID, ok := validator.Validate(ctx, apiKey)
+			if !ok {
+				logging.GetRequestLogger(r).V(logging.DEBUG).Info("invalid API key", "path", r.URL.Path)
+				apiErr := openai.NewAPIError(http.StatusUnauthorized, "", "Incorrect API key provided", nil)
+				common.WriteAPIError(ctx, w, apiErr)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(common.WithTenantID(ctx, tenantID)))
+		})
+	}
+}
+
+// bearerToken returns the token of the Authorization: Bearer header of the request.
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < len(bearerPrefix) || !strings.EqualFold(auth[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[len(bearerPrefix):])
+	return token, token != ""
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the authentication middleware.
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	validator := StaticAPIKeyValidator{
+		"key-a": "tenant-a",
+		"key-b": "tenant-b",
+	}
+
+	// the handler echoes the tenant of the request
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(common.GetTenantIDFromContext(r.Context())))
+	})
+	middleware := AuthMiddleware(validator)(handler)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+		wantTenant    string
+	}{
+		{name: "valid key", path: "/v1/batches", authorization: "Bearer key-a", wantStatus: http.StatusOK, wantTenant: "tenant-a"},
+		{name: "valid key of another tenant", path: "/v1/batches", authorization: "Bearer key-b", wantStatus: http.StatusOK, wantTenant: "tenant-b"},
+		{name: "case insensitive scheme", path: "/v1/files", authorization: "bearer key-a", wantStatus: http.StatusOK, wantTenant: "tenant-a"},
+		{name: "missing header", path: "/v1/batches", wantStatus: http.StatusUnauthorized},
+		{name: "empty key", path: "/v1/batches", authorization: "Bearer ", wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", path: "/v1/batches", authorization: "Basic key-a", wantStatus: http.StatusUnauthorized},
+		{name: "invalid key", path: "/v1/batches", authorization: "Bearer key-c", wantStatus: http.StatusUnauthorized},
+		{name: "health is not authenticated", path: "/health", wantStatus: http.StatusOK, wantTenant: "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			middleware.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if body := w.Body.String(); body != tt.wantTenant {
+					t.Errorf("expected tenant %q, got %q", tt.wantTenant, body)
+				}
+				return
+			}
+
+			var resp openai.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode JSON response: %v", err)
+			}
+			if resp.Error.Type != "AuthenticationError" {
+				t.Errorf("expected error type %q, got %q", "AuthenticationError", resp.Error.Type)
+			}
+		})
+	}
+}
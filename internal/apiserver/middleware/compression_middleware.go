@@ -0,0 +1,182 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file implements gzip compression middleware for large responses.
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+// compressedContentTypes lists the content types, or their prefixes, that are already compressed
+var compressedContentTypes = []string{
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/zstd",
+	"image/",
+	"video/",
+	"audio/",
+}
+
+// CompressionMiddleware gzips the responses of clients accepting gzip encoding,
+// when the response body reaches minSize bytes. Smaller responses are sent as is.
+// Responses with an already compressed content type or an existing content encoding are not compressed.
+func CompressionMiddleware(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, minSize: minSize, statusCode: http.StatusOK}
+			defer func() {
+				if err := cw.Close(); err != nil {
+					logging.GetRequestLogger(r).Error(err, "failed to write compressed response")
+				}
+			}()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter buffers the beginning of a response until it reaches minSize bytes,
+// then streams the rest of the response through a gzip writer if the response can be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	minSize     int
+	statusCode  int
+	buf         []byte
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.statusCode = code
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.wroteHeader {
+		if cw.gz != nil {
+			return cw.gz.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) < cw.minSize {
+		return len(p), nil
+	}
+	if err := cw.start(cw.compressible()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the buffered response of a response smaller than minSize, and completes a compressed response.
+func (cw *compressWriter) Close() error {
+	if !cw.wroteHeader {
+		return cw.start(false)
+	}
+	if cw.gz != nil {
+		return cw.gz.Close()
+	}
+	return nil
+}
+
+// FlushError sends the response written so far to the client, see http.ResponseController.
+// A response flushed before reaching minSize bytes, such as an event stream, is not compressed.
+func (cw *compressWriter) FlushError() error {
+	if !cw.wroteHeader {
+		if err := cw.start(false); err != nil {
+			return err
+		}
+	}
+	if cw.gz != nil {
+		if err := cw.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying response writer, see http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible checks if the content of the response can be compressed.
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	// the byte range of a partial response is a range of the uncompressed content
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	for _, compressed := range compressedContentTypes {
+		if strings.HasPrefix(contentType, compressed) {
+			return false
+		}
+	}
+	return true
+}
+
+// start writes the response header and the buffered beginning of the response, compressed or not.
+func (cw *compressWriter) start(compress bool) error {
+	cw.wroteHeader = true
+	if compress {
+		header := cw.Header()
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		cw.gz = gzip.NewWriter(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := cw.Write(buf)
+	return err
+}
+
+// acceptsGzip checks if the Accept-Encoding header of the request accepts gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		// a zero quality value means the encoding is not acceptable
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(value, 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
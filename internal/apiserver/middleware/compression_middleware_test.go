@@ -0,0 +1,106 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the compression middleware.
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompressionMiddleware(t *testing.T) {
+	const minSize = 100
+	small := strings.Repeat("s", minSize-1)
+	large := strings.Repeat(`{"id":"batch_123","object":"batch"}`+"\n", 100)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		streamed       bool // the body is written in small chunks with io.Copy, like file downloads
+		contentRange   string
+		wantCompressed bool
+	}{
+		{name: "below threshold", acceptEncoding: "gzip", contentType: "application/json", body: small},
+		{name: "above threshold", acceptEncoding: "gzip", contentType: "application/json", body: large, wantCompressed: true},
+		{name: "streamed download", acceptEncoding: "gzip, deflate", contentType: "application/octet-stream", body: large, streamed: true, wantCompressed: true},
+		{name: "streamed below threshold", acceptEncoding: "gzip", contentType: "application/octet-stream", body: small, streamed: true},
+		{name: "gzip not accepted", acceptEncoding: "", contentType: "application/json", body: large},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0, deflate", contentType: "application/json", body: large},
+		{name: "already compressed", acceptEncoding: "gzip", contentType: "application/gzip", body: large},
+		{name: "empty body", acceptEncoding: "gzip", contentType: "application/json", body: ""},
+		{name: "partial content", acceptEncoding: "gzip", contentType: "application/octet-stream", body: large, streamed: true, contentRange: "bytes 0-3499/7000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.contentRange != "" {
+					w.Header().Set("Content-Range", tt.contentRange)
+				}
+				w.WriteHeader(http.StatusCreated)
+				if tt.streamed {
+					// LimitReader hides strings.Reader's WriterTo so the 10-byte buffer is used
+					io.CopyBuffer(w, io.LimitReader(strings.NewReader(tt.body), int64(len(tt.body))), make([]byte, 10))
+					return
+				}
+				w.Write([]byte(tt.body))
+			})
+			req := httptest.NewRequest(http.MethodGet, "/v1/batches", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			CompressionMiddleware(minSize)(handler).ServeHTTP(w, req)
+
+			if w.Code != http.StatusCreated {
+				t.Errorf("expected status %d, got %d", http.StatusCreated, w.Code)
+			}
+
+			body := w.Body.Bytes()
+			compressed := w.Header().Get("Content-Encoding") == "gzip"
+			if compressed != tt.wantCompressed {
+				t.Fatalf("expected compressed=%v, got Content-Encoding %q", tt.wantCompressed, w.Header().Get("Content-Encoding"))
+			}
+			if compressed {
+				if w.Header().Get("Vary") != "Accept-Encoding" {
+					t.Errorf("expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+				}
+				if len(body) >= len(tt.body) {
+					t.Errorf("expected compressed body to be smaller than %d bytes, got %d", len(tt.body), len(body))
+				}
+				reader, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatalf("failed to create gzip reader: %v", err)
+				}
+				if body, err = io.ReadAll(reader); err != nil {
+					t.Fatalf("failed to decompress body: %v", err)
+				}
+			}
+			if string(body) != tt.body {
+				t.Errorf("expected body of %d bytes to round-trip, got %d bytes", len(tt.body), len(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file implements per-tenant rate limiting middleware based on token buckets.
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/health"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/metrics"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// tenantRateLimiter holds a token bucket per tenant.
+type tenantRateLimiter struct {
+	config   common.RateLimitConfig
+	now      func() time.Time
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newTenantRateLimiter(config common.RateLimitConfig) *tenantRateLimiter {
+	return &tenantRateLimiter{
+		config:   config,
+		now:      time.Now,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// limiter returns the token bucket of the tenant, created on the tenant's first request.
+func (l *tenantRateLimiter) limiter(tenantID string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.limiters[tenantID]
+	if !ok {
+		limit := l.config.ForTenant(tenantID)
+		if limit.RequestsPerSecond > 0 {
+			limiter = rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), limit.Burst)
+		} else {
+			limiter = rate.NewLimiter(rate.Inf, 0)
+		}
+		l.limiters[tenantID] = limiter
+	}
+	return limiter
+}
+
+// allow takes a token from the tenant's bucket. If the bucket is empty,
+// it returns false and how long to wait until a token is available.
+func (l *tenantRateLimiter) allow(tenantID string) (bool, time.Duration) {
+	now := l.now()
+	reservation := l.limiter(tenantID).ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, time.Second
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// RateLimitMiddleware limits the rate of requests of each tenant with a token bucket,
+// rejecting requests above the limit with a 429 error and a Retry-After header.
+// The tenant is taken from the request context, so the middleware must run after the authentication.
+func RateLimitMiddleware(config common.RateLimitConfig) func(http.Handler) http.Handler {
+	limiter := newTenantRateLimiter(config)
+	return limiter.middleware
+}
+
+func (l *tenantRateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == health.HealthPath || r.URL.Path == metrics.MetricsPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		if ok, retryAfter := l.allow(common.GetTenantIDFromContext(ctx)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			apiErr := openai.NewAPIError(http.StatusTooManyRequests, "", "Rate limit reached for requests", nil)
+			common.WriteAPIError(ctx, w, apiErr)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the rate limiting middleware.
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// rateLimitTestEnv sends requests through the rate limiting middleware with a fake clock.
+type rateLimitTestEnv struct {
+	limiter *tenantRateLimiter
+	handler http.Handler
+	now     time.Time
+}
+
+func newRateLimitTestEnv(config common.RateLimitConfig) *rateLimitTestEnv {
+	env := &rateLimitTestEnv{now: time.Now()}
+	env.limiter = newTenantRateLimiter(config)
+	env.limiter.now = func() time.Time { return env.now }
+	env.handler = env.limiter.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	return env
+}
+
+func (env *rateLimitTestEnv) send(tenantID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/v1/batches", nil)
+	req = req.WithContext(common.WithTenantID(req.Context(), tenantID))
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	return w
+}
+
+// sendN sends n requests of the tenant and returns the number of accepted requests.
+func (env *rateLimitTestEnv) sendN(tenantID string, n int) int {
+	accepted := 0
+	for i := 0; i < n; i++ {
+		if env.send(tenantID).Code == http.StatusOK {
+			accepted++
+		}
+	}
+	return accepted
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	config := common.RateLimitConfig{
+		RateLimit: common.RateLimit{RequestsPerSecond: 2, Burst: 5},
+		Tenants: map[string]common.RateLimit{
+			"premium":   {RequestsPerSecond: 10, Burst: 20},
+			"unlimited": {},
+		},
+	}
+
+	t.Run("Burst", func(t *testing.T) {
+		env := newRateLimitTestEnv(config)
+
+		if accepted := env.sendN("tenant-a", 10); accepted != 5 {
+			t.Errorf("expected the burst of 5 requests to be accepted, got %d", accepted)
+		}
+
+		w := env.send("tenant-a")
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != "1" {
+			t.Errorf("expected Retry-After 1, got %q", retryAfter)
+		}
+		var resp openai.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode JSON response: %v", err)
+		}
+		if resp.Error.Type != "RateLimitError" {
+			t.Errorf("expected error type %q, got %q", "RateLimitError", resp.Error.Type)
+		}
+	})
+
+	t.Run("SteadyState", func(t *testing.T) {
+		env := newRateLimitTestEnv(config)
+		env.sendN("tenant-a", 5) // drain the burst
+
+		// tokens are refilled at 2 requests per second
+		for i := 0; i < 10; i++ {
+			env.now = env.now.Add(500 * time.Millisecond)
+			if accepted := env.sendN("tenant-a", 2); accepted != 1 {
+				t.Fatalf("expected 1 request accepted every 500ms, got %d", accepted)
+			}
+		}
+	})
+
+	t.Run("TenantIsolation", func(t *testing.T) {
+		env := newRateLimitTestEnv(config)
+
+		env.sendN("tenant-a", 10)
+		if accepted := env.sendN("tenant-b", 5); accepted != 5 {
+			t.Errorf("expected tenant-b not to be limited by tenant-a, got %d accepted", accepted)
+		}
+	})
+
+	t.Run("TenantOverrides", func(t *testing.T) {
+		env := newRateLimitTestEnv(config)
+
+		if accepted := env.sendN("premium", 30); accepted != 20 {
+			t.Errorf("expected the premium burst of 20 requests to be accepted, got %d", accepted)
+		}
+		if accepted := env.sendN("unlimited", 100); accepted != 100 {
+			t.Errorf("expected unlimited tenant not to be limited, got %d accepted", accepted)
+		}
+	})
+}
@@ -20,15 +20,26 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"runtime/debug"
 	"testing"
 
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
 	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
 	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
 )
 
-// RecoveryMiddleware recovers from panics and returns a JSON error response
-func RecoveryMiddleware(next http.Handler) http.Handler {
+// RecoveryMiddleware recovers from panics and returns a JSON error response.
+// When logPanicStack is set, the stack trace of the panic is included in the error log; it is never sent to the client.
+func RecoveryMiddleware(logPanicStack bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return recoveryHandler(next, logPanicStack)
+	}
+}
+
+func recoveryHandler(next http.Handler, logPanicStack bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
@@ -42,13 +53,12 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 					panicErr = fmt.Errorf("%v", e)
 				}
 
-				if !testing.Testing() || testing.Verbose() {
-					logger := logging.GetRequestLogger(r)
-					logger.Error(panicErr, "handler panic",
-						"method", r.Method,
-						"path", r.URL.Path,
-						//"stack", string(debug.Stack()),
-					)
+				if logger, ok := panicLogger(r); ok {
+					keysAndValues := []interface{}{"method", r.Method, "path", r.URL.Path}
+					if logPanicStack {
+						keysAndValues = append(keysAndValues, "stack", string(debug.Stack()))
+					}
+					logger.Error(panicErr, "handler panic", keysAndValues...)
 				}
 
 				requestID := GetRequestIDFromContext(r.Context())
@@ -60,3 +70,12 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// panicLogger returns the logger for handler panics and whether panics should be logged.
+// Tests only log panics when running verbose or when they attach their own logger to the request.
+func panicLogger(r *http.Request) (klog.Logger, bool) {
+	if logger, err := logr.FromContext(r.Context()); err == nil {
+		return logger, true
+	}
+	return logging.GetRequestLogger(r), !testing.Testing() || testing.Verbose()
+}
@@ -23,14 +23,19 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/klog/v2"
+
 	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
 )
 
 func TestRecoveryMiddleware(t *testing.T) {
 	t.Run("NoPanic", doTestRecoveryMiddlewareNoPanic)
 	t.Run("WithPanic", doTestRecoveryMiddlewareWithPanic)
+	t.Run("LogPanicStack", doTestRecoveryMiddlewareLogPanicStack)
 }
 
 func doTestRecoveryMiddlewareNoPanic(t *testing.T) {
@@ -39,7 +44,7 @@ func doTestRecoveryMiddlewareNoPanic(t *testing.T) {
 		w.Write([]byte("success"))
 	})
 
-	middleware := RecoveryMiddleware(handler)
+	middleware := RecoveryMiddleware(false)(handler)
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	w := httptest.NewRecorder()
@@ -89,7 +94,7 @@ func doTestRecoveryMiddlewareWithPanic(t *testing.T) {
 				panic(tt.panicValue)
 			})
 
-			middleware := RecoveryMiddleware(handler)
+			middleware := RecoveryMiddleware(false)(handler)
 
 			req := httptest.NewRequest(http.MethodGet, "/test", nil)
 			ctx := context.WithValue(req.Context(), requestIDKey, "test-request-id-123")
@@ -142,12 +147,57 @@ func doTestRecoveryMiddlewareWithPanic(t *testing.T) {
 	}
 }
 
+func doTestRecoveryMiddlewareLogPanicStack(t *testing.T) {
+	tests := []struct {
+		name          string
+		logPanicStack bool
+	}{
+		{name: "disabled", logPanicStack: false},
+		{name: "enabled", logPanicStack: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("stack test panic")
+			})
+
+			var logs strings.Builder
+			logger := funcr.New(func(prefix, args string) {
+				logs.WriteString(args)
+			}, funcr.Options{})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req = req.WithContext(klog.NewContext(req.Context(), logger))
+			w := httptest.NewRecorder()
+
+			RecoveryMiddleware(tt.logPanicStack)(handler).ServeHTTP(w, req)
+
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+			}
+			if !strings.Contains(logs.String(), "stack test panic") {
+				t.Fatalf("expected panic to be logged, got %q", logs.String())
+			}
+
+			// the stack contains the frame of the middleware that recovered the panic
+			hasStack := strings.Contains(logs.String(), `"stack"`) && strings.Contains(logs.String(), "recovery_middleware.go")
+			if hasStack != tt.logPanicStack {
+				t.Errorf("expected stack in logs=%v, got logs %q", tt.logPanicStack, logs.String())
+			}
+			if strings.Contains(w.Body.String(), "goroutine") || strings.Contains(w.Body.String(), "recovery_middleware.go") {
+				t.Errorf("expected no stack in response body, got %q", w.Body.String())
+			}
+		})
+	}
+}
+
 func BenchmarkRecoveryMiddleware_NoPanic(b *testing.B) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	middleware := RecoveryMiddleware(handler)
+	middleware := RecoveryMiddleware(false)(handler)
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 
 	b.ResetTimer()
@@ -162,7 +212,7 @@ func BenchmarkRecoveryMiddleware_WithPanic(b *testing.B) {
 		panic("benchmark panic")
 	})
 
-	middleware := RecoveryMiddleware(handler)
+	middleware := RecoveryMiddleware(false)(handler)
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 
 	b.ResetTimer()
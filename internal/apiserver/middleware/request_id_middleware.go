@@ -0,0 +1,90 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file implements the middleware setting the ID of each request in its context and logger.
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"k8s.io/klog/v2"
+)
+
+type contextKey string
+
+const (
+	requestIDHeader            = "X-Request-ID"
+	requestIDKey    contextKey = "requestID"
+
+	// maxRequestIDLength is the maximum length of a request ID supplied by a client
+	maxRequestIDLength = 128
+)
+
+// RequestIDMiddleware sets the ID of every request, including the health and metrics requests,
+// so that the middlewares and handlers after it always log and report a valid request ID.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(withRequestID(w, r)))
+	})
+}
+
+// withRequestID returns the context of the request with its request ID and a logger including it.
+// A valid client-supplied request ID is reused, otherwise one is generated; the ID is echoed back
+// in the response headers, so clients can correlate their requests.
+// The context is returned unchanged if the request ID was already set.
+func withRequestID(w http.ResponseWriter, r *http.Request) context.Context {
+	ctx := r.Context()
+	if _, ok := ctx.Value(requestIDKey).(string); ok {
+		return ctx
+	}
+
+	requestID := r.Header.Get(requestIDHeader)
+	if !isValidRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+	w.Header().Set(requestIDHeader, requestID)
+
+	logger := klog.FromContext(ctx).WithValues("requestID", requestID)
+	ctx = klog.NewContext(ctx, logger)
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// isValidRequestID checks that a client-supplied request ID is not empty, not too long,
+// and only contains letters, digits and the characters '-', '_', '.' and ':'.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// GetRequestID retrieves the request ID from the context.
+func GetRequestIDFromContext(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+		return requestID
+	}
+	return "unknown"
+}
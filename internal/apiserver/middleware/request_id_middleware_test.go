@@ -0,0 +1,97 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the request ID middleware.
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/google/uuid"
+	"k8s.io/klog/v2"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/health"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		requestID  string
+		wantEchoed bool
+	}{
+		{name: "client-supplied", path: "/v1/batches", requestID: "client-req_42", wantEchoed: true},
+		{name: "generated", path: "/v1/batches"},
+		{name: "generated when invalid", path: "/v1/batches", requestID: "bad id"},
+		{name: "health request", path: health.HealthPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs []string
+			logger := funcr.New(func(prefix, args string) { logs = append(logs, args) }, funcr.Options{})
+
+			// the handler logs with the request logger and echoes the request ID of the context
+			handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logging.GetRequestLogger(r).Info("handled")
+				w.Write([]byte(GetRequestIDFromContext(r.Context())))
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req = req.WithContext(klog.NewContext(req.Context(), logger))
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-Id", tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			requestID := w.Header().Get("X-Request-Id")
+			if body := w.Body.String(); body != requestID {
+				t.Errorf("expected the context request ID %q to match the header %q", body, requestID)
+			}
+			if tt.wantEchoed {
+				if requestID != tt.requestID {
+					t.Errorf("expected request ID %q to be reused, got %q", tt.requestID, requestID)
+				}
+			} else if _, err := uuid.Parse(requestID); err != nil {
+				t.Errorf("expected a generated UUID request ID, got %q: %v", requestID, err)
+			}
+			if len(logs) != 1 || !strings.Contains(logs[0], `"requestID"="`+requestID+`"`) {
+				t.Errorf("expected the request logger to include request ID %q, got %v", requestID, logs)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddlewareKeepsID(t *testing.T) {
+	// the request middleware keeps the ID set before it instead of generating another one
+	var contextID string
+	handler := RequestIDMiddleware(RequestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextID = GetRequestIDFromContext(r.Context())
+	})))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/batches", nil))
+
+	if requestID := w.Header().Get("X-Request-Id"); contextID != requestID {
+		t.Errorf("expected the context request ID %q to match the header %q", contextID, requestID)
+	}
+}
@@ -14,29 +14,20 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// The file implements request middleware for generating request IDs, logging requests and recording metrics.
+// The file implements request middleware for logging requests and recording metrics.
 package middleware
 
 import (
-	"context"
 	"net/http"
-	"strconv"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/health"
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/metrics"
 	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
 	"k8s.io/klog/v2"
 )
 
-type contextKey string
-
-const (
-	requestIDHeader            = "X-Request-ID"
-	requestIDKey    contextKey = "requestID"
-)
-
 func RequestMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Skip /metrics and /health endpoints to avoid noise in logs and metrics
@@ -48,16 +39,16 @@ func RequestMiddleware(next http.Handler) http.Handler {
 		start := time.Now()
 		metrics.RecordRequestStart()
 
-		requestID := r.Header.Get(requestIDHeader)
-		if requestID == "" {
-			requestID = uuid.NewString()
-		}
-		w.Header().Set(requestIDHeader, requestID)
+		// the request ID is normally set by RequestIDMiddleware, and set here when it didn't run
+		ctx := withRequestID(w, r)
+		logger := klog.FromContext(ctx)
+		ctx = common.WithRoutePattern(ctx)
 
-		// Create request logger with request ID
-		logger := klog.FromContext(r.Context()).WithValues("requestID", requestID)
-		ctx := klog.NewContext(r.Context(), logger)
-		ctx = context.WithValue(ctx, requestIDKey, requestID)
+		// Attach the verified TLS client certificate identity for auditing
+		if identity, ok := common.ClientIdentityFromTLS(r.TLS); ok {
+			logger = logger.WithValues("clientCN", identity.CommonName)
+			ctx = klog.NewContext(common.WithClientIdentity(ctx, identity), logger)
+		}
 
 		// Wrap response writer to capture status code
 		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
@@ -71,8 +62,7 @@ func RequestMiddleware(next http.Handler) http.Handler {
 
 		defer func() {
 			duration := time.Since(start).Seconds()
-			status := strconv.Itoa(rw.statusCode)
-			metrics.RecordRequestFinish(r.Method, r.URL.Path, status, duration)
+			metrics.RecordRequestFinish(r.Method, common.GetRoutePatternFromContext(ctx), rw.statusCode, duration)
 		}()
 
 		next.ServeHTTP(rw, r.WithContext(ctx))
@@ -90,10 +80,7 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// GetRequestID retrieves the request ID from the context.
-func GetRequestIDFromContext(ctx context.Context) string {
-	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
-		return requestID
-	}
-	return "unknown"
+// Unwrap returns the underlying response writer, so that streamed responses can be flushed.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
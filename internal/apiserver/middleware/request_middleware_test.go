@@ -0,0 +1,117 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the request middleware.
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/metrics"
+)
+
+func TestRequestMiddleware(t *testing.T) {
+	// the handler echoes the request ID of the context
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GetRequestIDFromContext(r.Context())))
+	})
+	middleware := RequestMiddleware(handler)
+
+	tests := []struct {
+		name       string
+		requestID  string
+		wantEchoed bool
+	}{
+		{name: "client request ID is echoed", requestID: "client-req_42.a:b", wantEchoed: true},
+		{name: "generated when missing", requestID: ""},
+		{name: "generated when too long", requestID: strings.Repeat("a", maxRequestIDLength+1)},
+		{name: "generated when invalid characters", requestID: "bad id\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/batches", nil)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-Id", tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			middleware.ServeHTTP(w, req)
+
+			requestID := w.Header().Get("X-Request-Id")
+			if body := w.Body.String(); body != requestID {
+				t.Errorf("expected the context request ID %q to match the header %q", body, requestID)
+			}
+			if tt.wantEchoed {
+				if requestID != tt.requestID {
+					t.Errorf("expected request ID %q to be echoed, got %q", tt.requestID, requestID)
+				}
+				return
+			}
+			if _, err := uuid.Parse(requestID); err != nil {
+				t.Errorf("expected a generated UUID request ID, got %q: %v", requestID, err)
+			}
+		})
+	}
+}
+
+// routesForTest is an API handler serving a single route with the given status
+type routesForTest struct {
+	status int
+}
+
+func (h routesForTest) GetRoutes() []common.Route {
+	return []common.Route{{
+		Method:  http.MethodGet,
+		Pattern: "/v1/metrics-test/{id}",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(h.status)
+		},
+	}}
+}
+
+func TestRequestMiddlewareMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	common.RegisterHandler(mux, routesForTest{status: http.StatusNotFound})
+	handler := RequestMiddleware(mux)
+
+	// requests for different IDs are counted under the route pattern
+	for _, path := range []string{"/v1/metrics-test/a", "/v1/metrics-test/b", "/v1/metrics-test/c/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	metrics.NewMetricsApiHandler().MetricsHandler(rr, httptest.NewRequest(http.MethodGet, metrics.MetricsPath, nil))
+	body, _ := io.ReadAll(rr.Body)
+
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/v1/metrics-test/{id}",status_class="4xx"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status_class="4xx"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/v1/metrics-test/{id}",status_class="4xx"} 2`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+	if strings.Contains(string(body), "/v1/metrics-test/a") {
+		t.Error("expected request paths not to be used as labels")
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the tracing middleware.
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+)
+
+// recordSpans installs a tracer provider recording the ended spans for the duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		traceparent string
+		wantTraceID string
+		wantCode    codes.Code
+	}{
+		{name: "new trace", status: http.StatusOK, wantCode: codes.Unset},
+		{
+			name:        "continued trace",
+			status:      http.StatusOK,
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantCode:    codes.Unset,
+		},
+		{name: "server error", status: http.StatusInternalServerError, wantCode: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := recordSpans(t)
+			mux := http.NewServeMux()
+			common.RegisterHandler(mux, routesForTest{status: tt.status})
+			handler := RequestMiddleware(TracingMiddleware(mux))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/metrics-test/a", nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("Expected 1 span, got %d", len(spans))
+			}
+			span := spans[0]
+			if span.Name() != "GET /v1/metrics-test/{id}" {
+				t.Errorf("Expected span name %q, got %q", "GET /v1/metrics-test/{id}", span.Name())
+			}
+			if span.SpanKind() != trace.SpanKindServer {
+				t.Errorf("Expected a server span, got %s", span.SpanKind())
+			}
+			if got := spanAttribute(span, "http.response.status_code").AsInt64(); got != int64(tt.status) {
+				t.Errorf("Expected status code attribute %d, got %d", tt.status, got)
+			}
+			if span.Status().Code != tt.wantCode {
+				t.Errorf("Expected span status %s, got %s", tt.wantCode, span.Status().Code)
+			}
+			if tt.wantTraceID != "" {
+				if got := span.SpanContext().TraceID().String(); got != tt.wantTraceID {
+					t.Errorf("Expected the span to continue trace %s, got %s", tt.wantTraceID, got)
+				}
+				if !span.Parent().IsRemote() {
+					t.Error("Expected the span to have the remote parent of the request")
+				}
+			}
+		})
+	}
+}
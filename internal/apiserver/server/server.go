@@ -26,12 +26,15 @@ import (
 	"time"
 
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/capabilities"
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/files"
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/health"
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/metrics"
 	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/middleware"
 	mockapi "github.com/llm-d-incubation/batch-gateway/internal/database/mock"
+	mockfiles "github.com/llm-d-incubation/batch-gateway/internal/files_store/mock"
+	utls "github.com/llm-d-incubation/batch-gateway/internal/util/tls"
 	"k8s.io/klog/v2"
 )
 
@@ -58,36 +61,32 @@ func (s *Server) Start(ctx context.Context) error {
 		return err
 	}
 
-	handler := s.buildHandler()
+	handler := s.buildHandler(ctx)
 
 	httpserver := &http.Server{
 		Handler: handler,
 	}
 
 	// Enable TLS if cert and key are provided
+	var tlsConfig *tls.Config
 	if s.config.SSLEnabled() {
-		cert, err := tls.LoadX509KeyPair(s.config.SSLCertFile, s.config.SSLKeyFile)
+		tlsConfig, err = newTLSConfig(ctx, s.config)
 		if err != nil {
 			return err
 		}
-		httpserver.TLSConfig = &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12,
-			CipherSuites: []uint16{
-				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-			},
-		}
-		s.logger.Info("server TLS configured")
+		httpserver.TLSConfig = tlsConfig
+		s.logger.Info("server TLS configured", "requireClientCert", s.config.SSLRequireClientCert)
 	} else if s.config.SSLCertFile != "" || s.config.SSLKeyFile != "" {
 		err := fmt.Errorf("both tls-cert-file and tls-private-key-file must be provided to enable TLS")
 		return err
 	}
 
+	if s.config.ObservabilityPort != "" {
+		if err := s.startObservabilityServer(ctx, tlsConfig); err != nil {
+			return err
+		}
+	}
+
 	// graceful termination
 	go func() {
 		<-ctx.Done()
@@ -118,7 +117,85 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
-func (s *Server) buildHandler() http.Handler {
+// startObservabilityServer serves the metrics and health endpoints on the observability port until ctx is done.
+// It uses the server certificate when TLS is enabled, without requiring client certificates from scrapers.
+func (s *Server) startObservabilityServer(ctx context.Context, tlsConfig *tls.Config) error {
+	logger := s.logger.WithName("observability")
+
+	ln, err := net.Listen("tcp", s.config.Host+":"+s.config.ObservabilityPort)
+	if err != nil {
+		logger.Error(err, "failed to start")
+		return err
+	}
+	if tlsConfig != nil {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ClientAuth = tls.NoClientCert
+		tlsConfig.ClientCAs = nil
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+
+	httpserver := &http.Server{
+		Handler: buildObservabilityHandler(),
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancelFn := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFn()
+		if err := httpserver.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "failed to gracefully shutdown")
+		}
+	}()
+
+	go func() {
+		logger.Info("starting", "addr", ln.Addr().String(), "tls", tlsConfig != nil)
+		if err := httpserver.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Error(err, "failed to serve")
+		}
+	}()
+	return nil
+}
+
+// buildObservabilityHandler returns the handler of the observability server.
+// The metrics are the ones recorded by the request middleware of the API server.
+func buildObservabilityHandler() http.Handler {
+	mux := http.NewServeMux()
+	common.RegisterHandler(mux, health.NewHealthApiHandler())
+	common.RegisterHandler(mux, metrics.NewMetricsApiHandler())
+	return mux
+}
+
+// newTLSConfig returns the server TLS config. When client certificates are required,
+// only clients presenting a certificate signed by a CA in the client CA file can connect.
+// The server certificate is reloaded from disk when it is rotated, until ctx is done.
+func newTLSConfig(ctx context.Context, config *common.ServerConfig) (*tls.Config, error) {
+	clientCAFile := ""
+	if config.SSLRequireClientCert {
+		clientCAFile = config.SSLClientCAFile
+	}
+	tlsConfig, err := utls.GetTlsConfig(utls.LOAD_TYPE_SERVER, false, "", "", clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	reloader, err := utls.NewCertReloader(config.SSLCertFile, config.SSLKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	go reloader.Watch(ctx, utls.CertReloadInterval)
+	tlsConfig.GetCertificate = reloader.GetCertificate
+	tlsConfig.MinVersion = tls.VersionTLS12
+	tlsConfig.CipherSuites = []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	}
+	return tlsConfig, nil
+}
+
+func (s *Server) buildHandler(ctx context.Context) http.Handler {
 	mux := http.NewServeMux()
 
 	// TODO: change to actual implementation
@@ -126,18 +203,33 @@ func (s *Server) buildHandler() http.Handler {
 	eventClient := mockapi.NewMockBatchEventChannelClient()
 	queueClient := mockapi.NewMockBatchPriorityQueueClient()
 	statusClient := mockapi.NewMockBatchStatusClient()
+	fileDBClient := mockapi.NewMockBatchFileDBClient()
+	filesClient := mockfiles.NewMockBatchFilesClient()
 
 	// register handlers
 	healthHandler := health.NewHealthApiHandler()
 	metricsHandler := metrics.NewMetricsApiHandler()
-	filesHandler := files.NewFilesApiHandler()
-	batchHandler := batch.NewBatchApiHandler(s.config, dbClient, queueClient, eventClient, statusClient)
+	filesHandler := files.NewFilesApiHandler(s.config, fileDBClient, filesClient, statusClient, dbClient)
+	batchHandler := batch.NewBatchApiHandler(s.config, dbClient, queueClient, eventClient, statusClient, fileDBClient, filesClient)
+	batchHandler.CloseStreamsWhenDone(ctx)
+	capabilitiesHandler := capabilities.NewCapabilitiesApiHandler(s.config)
+
+	// delete final batches past their TTL, and expired files
+	reaperCtx := klog.NewContext(ctx, s.logger)
+	if s.config.BatchReaperIntervalSeconds > 0 && s.config.BatchTTLSeconds > 0 {
+		reaper := batch.NewBatchReaper(s.config, dbClient, fileDBClient, filesClient)
+		go reaper.Run(reaperCtx, time.Duration(s.config.BatchReaperIntervalSeconds)*time.Second)
+	}
+	if s.config.FileReaperIntervalSeconds > 0 {
+		go filesHandler.NewReaper().Run(reaperCtx, time.Duration(s.config.FileReaperIntervalSeconds)*time.Second)
+	}
 
 	handlers := []common.ApiHandler{
 		healthHandler,
 		metricsHandler,
 		filesHandler,
 		batchHandler,
+		capabilitiesHandler,
 	}
 	for _, c := range handlers {
 		common.RegisterHandler(mux, c)
@@ -145,12 +237,21 @@ func (s *Server) buildHandler() http.Handler {
 
 	// register middlewares
 	var h http.Handler
-	h = middleware.RecoveryMiddleware(mux) // Innermost, catches panics from business logic
+	h = middleware.RecoveryMiddleware(s.config.LogPanicStack)(mux) // Innermost, catches panics from business logic
 	//h = middleware.BodySizeLimitMiddleware(h) //  Limit request body size
 	//h = middleware.AuthorizationMiddleware(h) //  Check permissions
-	//h = middleware.AuthenticationMiddleware(h) // Verify API key/JWT
-	h = middleware.RequestMiddleware(h) // Request ID, logging, metrics
-	//h = middleware.RateLimitMiddleware(h)      // Early Rejection
+	if s.config.RateLimit.Enabled() {
+		h = middleware.RateLimitMiddleware(s.config.RateLimit)(h) // Reject tenants above their rate limit
+	}
+	if len(s.config.APIKeys) > 0 {
+		h = middleware.AuthMiddleware(middleware.StaticAPIKeyValidator(s.config.APIKeys))(h) // Verify API key
+	}
+	h = middleware.TracingMiddleware(h) // Span per request
+	h = middleware.RequestMiddleware(h) // Logging, metrics
+	if s.config.CompressionMinSizeBytes > 0 {
+		h = middleware.CompressionMiddleware(s.config.CompressionMinSizeBytes)(h) // Gzip large responses
+	}
+	h = middleware.RequestIDMiddleware(h)       // Request ID in the context and logger of every request
 	h = middleware.SecurityHeadersMiddleware(h) // Outermost, affects all responses
 
 	return h
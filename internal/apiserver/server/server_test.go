@@ -0,0 +1,220 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the HTTP server.
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/common"
+	"github.com/llm-d-incubation/batch-gateway/internal/apiserver/middleware"
+)
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil)
+	untrustedCA := newTestCert(t, "untrusted-ca", nil)
+	serverCert := newTestCert(t, "localhost", ca)
+	clientCert := newTestCert(t, "batch-client", ca)
+	untrustedClientCert := newTestCert(t, "batch-client", untrustedCA)
+
+	config := &common.ServerConfig{
+		SSLCertFile:          writePEM(t, dir, "server.pem", "CERTIFICATE", serverCert.Certificate[0]),
+		SSLKeyFile:           writeKey(t, dir, "server-key.pem", serverCert),
+		SSLRequireClientCert: true,
+		SSLClientCAFile:      writePEM(t, dir, "client-ca.pem", "CERTIFICATE", ca.Certificate[0]),
+	}
+	tlsConfig, err := newTLSConfig(t.Context(), config)
+	if err != nil {
+		t.Fatalf("failed to create TLS config: %v", err)
+	}
+
+	// the handler echoes the verified client identity
+	handler := middleware.RequestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := common.GetClientIdentityFromContext(r.Context())
+		if !ok {
+			http.Error(w, "no client identity", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(identity.CommonName))
+	}))
+	srv := httptest.NewUnstartedServer(handler)
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	defer srv.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.Leaf)
+
+	tests := []struct {
+		name       string
+		clientCert *tls.Certificate
+		wantErr    bool
+	}{
+		{name: "trusted client cert", clientCert: clientCert},
+		{name: "untrusted client cert", clientCert: untrustedClientCert, wantErr: true},
+		{name: "no client cert", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the server name is sent with SNI, so that the server selects the reloadable certificate
+			// instead of the default certificate of the test server
+			clientTLS := &tls.Config{RootCAs: rootCAs, ServerName: "localhost"}
+			if tt.clientCert != nil {
+				clientTLS.Certificates = []tls.Certificate{*tt.clientCert}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+			resp, err := client.Get(srv.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("expected the TLS handshake to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, resp.StatusCode, body)
+			}
+			if string(body) != "batch-client" {
+				t.Errorf("expected client CN %q, got %q", "batch-client", body)
+			}
+		})
+	}
+}
+
+func TestObservabilityHandler(t *testing.T) {
+	// a request served by the API records the metrics exposed by the observability server
+	api := middleware.RequestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/observability-test", nil))
+
+	srv := httptest.NewServer(buildObservabilityHandler())
+	defer srv.Close()
+
+	tests := []struct {
+		path         string
+		wantContains string
+	}{
+		{path: "/health", wantContains: "OK"},
+		{path: "/metrics", wantContains: `http_requests_total{method="GET",route="unmatched",status_class="4xx"}`},
+		{path: "/metrics", wantContains: "http_requests_in_flight"},
+		{path: "/metrics", wantContains: "http_request_duration_seconds_bucket"},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d for %s, got %d", http.StatusOK, tt.path, resp.StatusCode)
+		}
+		if !strings.Contains(string(body), tt.wantContains) {
+			t.Errorf("expected %s to contain %q", tt.path, tt.wantContains)
+		}
+	}
+
+	// the API routes aren't served on the observability server
+	resp, err := http.Get(srv.URL + "/v1/batches")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+// newTestCert returns a certificate for cn signed by parent, or a self-signed CA certificate if parent is nil.
+func newTestCert(t *testing.T, cn string, parent *tls.Certificate) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey.(*ecdsa.PrivateKey)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func writeKey(t *testing.T, dir, name string, cert *tls.Certificate) string {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return writePEM(t, dir, name, "EC PRIVATE KEY", der)
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
@@ -20,6 +20,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -35,6 +36,10 @@ type BatchJob struct {
 	Tags   []string  // [optional, updatable, returned by get, parsed by DB] A list of tags that enable to select jobs based on the tags' contents. The tags must not contain ';;', which is the separator.
 	Spec   []byte    // [optional, immutable, returned optionally by get, opaque to DB] The static part of the batch job (serialized), including the job's specification.
 	Status []byte    // [optional, updatable, returned by get, opaque to DB] The dynamic part of the batch job (serialized), including its status.
+
+	Metadata map[string]string // [optional, immutable, parsed by DB] The metadata key-value pairs of the job, indexed to select jobs by their metadata.
+
+	TraceContext map[string]string // [optional, immutable, returned by get, opaque to DB] The trace context of the request that created the job, continued by its processing.
 }
 
 func (bj *BatchJob) IsValid() error {
@@ -50,6 +55,9 @@ func (bj *BatchJob) IsValid() error {
 	return nil
 }
 
+// ErrFinalStatus is returned by the Update of a job whose status is final, by the clients that parse the status.
+var ErrFinalStatus = errors.New("job status is final")
+
 // BatchDBClient enables to manage batch job metadata objects in persistent storage.
 type BatchDBClient interface {
 	store.BatchClientAdmin
@@ -74,14 +82,43 @@ type BatchDBClient interface {
 		includeStatic bool, start, limit int) (
 		jobs []*BatchJob, cursor int, err error)
 
+	// GetByMetadata gets the batch jobs having all the specified metadata key-value pairs, and all the specified tags.
+	// The jobs are selected with the metadata index of the database, without reading the other jobs.
+	// If no metadata is specified, the function will return an empty list of jobs.
+	// includeStatic, start, limit and the returned cursor are as for Get.
+	GetByMetadata(ctx context.Context, metadata map[string]string, tags []string, includeStatic bool, start, limit int) (
+		jobs []*BatchJob, cursor int, err error)
+
 	// Update updates the dynamic parts of a batch job.
 	// The function will update in the job's record in the database - all the dynamic fields of the job which are not empty
 	// in the given job object.
 	// Any dynamic field that is empty in the given job object - will not be updated in the job's record in the database.
+	// A client that parses the status doesn't update the status of a job in a final state, and returns ErrFinalStatus.
 	Update(ctx context.Context, job *BatchJob) (err error)
 
 	// Delete deletes batch jobs.
 	Delete(ctx context.Context, IDs []string) (deletedIDs []string, err error)
+
+	// DeadLetter stores a copy of a permanently failed batch job in the dead-letter table,
+	// where it is kept for inspection independently of the job's record.
+	DeadLetter(ctx context.Context, job *BatchJob) (err error)
+
+	// GetDeadLetters gets the dead-lettered copies of batch jobs by their IDs.
+	GetDeadLetters(ctx context.Context, IDs []string) (jobs []*BatchJob, err error)
+
+	// GetIdempotencyKey gets the record of an idempotency key, such as the batch created for the Idempotency-Key
+	// of a request. Returns nil if the key has no record or its record expired.
+	GetIdempotencyKey(ctx context.Context, key string) (record []byte, err error)
+
+	// CompareAndSetIdempotencyKey atomically sets the record of an idempotency key for TTL seconds,
+	// if its current record is expected. A nil expected sets the record only if the key has no record,
+	// so of concurrent requests reserving a key only one succeeds.
+	// Returns true if the record was set.
+	CompareAndSetIdempotencyKey(ctx context.Context, key string, TTL int, expected, record []byte) (set bool, err error)
+
+	// CompareAndDeleteIdempotencyKey atomically deletes the record of an idempotency key, if its current record
+	// is expected. Returns true if the record was deleted.
+	CompareAndDeleteIdempotencyKey(ctx context.Context, key string, expected []byte) (deleted bool, err error)
 }
 
 type TagsLogicalCond int
@@ -100,9 +137,25 @@ var TagsLogicalCondNames = map[TagsLogicalCond]string{
 
 // -- Batch jobs priority queue --
 
+// Job priorities. Jobs with a higher priority are dequeued first.
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
 type BatchJobPriority struct {
-	ID  string    // ID of the batch job.
-	SLO time.Time // The SLO value determines the priority of the job.
+	ID       string    // ID of the batch job.
+	SLO      time.Time // The SLO value determines the priority of the job among jobs of the same Priority.
+	Priority int       // Jobs with a higher Priority are dequeued before jobs with an earlier SLO. Defaults to PriorityNormal.
+}
+
+// Before reports whether jp should be dequeued before other.
+func (jp *BatchJobPriority) Before(other *BatchJobPriority) bool {
+	if jp.Priority != other.Priority {
+		return jp.Priority > other.Priority
+	}
+	return jp.SLO.Before(other.SLO)
 }
 
 // BatchPriorityQueueClient enables to perform operations on a priority queue of jobs.
@@ -123,6 +176,9 @@ type BatchPriorityQueueClient interface {
 	// It returns the removed job numbers.
 	// An error is returned only if the removal operation fails.
 	Remove(ctx context.Context, jobPriority *BatchJobPriority) (int, error)
+
+	// Len returns the number of job priority objects waiting in the queue.
+	Len(ctx context.Context) (int, error)
 }
 
 // -- Batch jobs events and channels --
@@ -130,10 +186,11 @@ type BatchPriorityQueueClient interface {
 type BatchEventType int
 
 const (
-	BatchEventCancel BatchEventType = iota // Cancel a job.
-	BatchEventPause                        // Pause a job.
-	BatchEventResume                       // Resume a job.
-	BatchEventMaxVal                       // [Internal] Indicates the max value for the enum. Don't use this value.
+	BatchEventCancel       BatchEventType = iota // Cancel a job.
+	BatchEventPause                              // Pause a job.
+	BatchEventResume                             // Resume a job.
+	BatchEventStatusUpdate                       // The status of a job was updated. Notifies the listeners of the job's status.
+	BatchEventMaxVal                             // [Internal] Indicates the max value for the enum. Don't use this value.
 )
 
 type BatchEvent struct {
@@ -190,4 +247,60 @@ type BatchStatusClient interface {
 
 	// Delete removes the status data for a job.
 	Delete(ctx context.Context, ID string) error
+
+	// CompareAndSet atomically stores data with a new TTL if the stored data equals expected,
+	// or if no data is stored and expected is nil. It reports whether data was stored.
+	CompareAndSet(ctx context.Context, ID string, TTL int, expected, data []byte) (bool, error)
+
+	// CompareAndDelete atomically removes the data of ID if it equals expected, and reports whether it was removed.
+	CompareAndDelete(ctx context.Context, ID string, expected []byte) (bool, error)
+}
+
+// -- Batch files metadata store --
+
+type BatchFile struct {
+	ID   string   // [mandatory, immutable, returned by get, parsed by DB, must be unique] Unique ID of the file.
+	TTL  int      // [mandatory, immutable, not returned by get, parsed by DB] The number of seconds to set for the TTL of the DB record.
+	Tags []string // [optional, immutable, returned by get, parsed by DB] A list of tags that enable to select files based on the tags' contents. The tags must not contain ';;', which is the separator.
+	Spec []byte   // [optional, immutable, returned by get, opaque to DB] The file object (serialized).
+
+	Location string // [optional, immutable, returned by get, opaque to DB] The location of the file content in the files storage.
+}
+
+// ContentLocation returns the location of the file content in the files storage.
+// The content of files recorded without a location is stored at their ID.
+func (bf *BatchFile) ContentLocation() string {
+	if bf.Location != "" {
+		return bf.Location
+	}
+	return bf.ID
+}
+
+func (bf *BatchFile) IsValid() error {
+	if len(bf.ID) == 0 {
+		return fmt.Errorf("ID is empty")
+	}
+	if bf.TTL <= 0 {
+		return fmt.Errorf("TTL is invalid for ID %s", bf.ID)
+	}
+	return nil
+}
+
+// BatchFileDBClient enables to manage batch file metadata objects in persistent storage.
+type BatchFileDBClient interface {
+	store.BatchClientAdmin
+
+	// Store stores a batch file metadata object.
+	// Returns the ID of the file in the database.
+	Store(ctx context.Context, file *BatchFile) (ID string, err error)
+
+	// Get gets batch file metadata objects.
+	// If IDs are specified, this function will get files by the specified IDs.
+	// If tags are specified, this function will get files by the specified tags.
+	// tagsLogicalCond, start, limit and cursor have the same semantics as in BatchDBClient.Get.
+	Get(ctx context.Context, IDs []string, tags []string, tagsLogicalCond TagsLogicalCond, start, limit int) (
+		files []*BatchFile, cursor int, err error)
+
+	// Delete deletes batch file metadata objects.
+	Delete(ctx context.Context, IDs []string) (deletedIDs []string, err error)
 }
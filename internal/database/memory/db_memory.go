@@ -0,0 +1,323 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file implements the batch jobs database interface in memory.
+// The in-memory clients keep their data in the process, for local development and tests of a single process:
+// the data is lost when the process exits and is not shared with other processes.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
+)
+
+// BatchDBClient is an in-memory api.BatchDBClient.
+// The records expire after their TTL, and are copied in and out so callers don't share them.
+type BatchDBClient struct {
+	mu          sync.RWMutex
+	jobs        map[string]*api.BatchJob
+	expires     map[string]time.Time
+	deadLetters map[string]*api.BatchJob
+
+	// metadataIndex maps a metadata key and value to the IDs of the jobs having them
+	metadataIndex map[string]map[string]map[string]struct{}
+
+	idempotencyKeys map[string]idempotencyEntry
+
+	now func() time.Time
+}
+
+// idempotencyEntry is the record of an idempotency key, and the time it expires.
+type idempotencyEntry struct {
+	record  []byte
+	expires time.Time
+}
+
+func NewBatchDBClient() *BatchDBClient {
+	return &BatchDBClient{
+		jobs:          make(map[string]*api.BatchJob),
+		expires:       make(map[string]time.Time),
+		deadLetters:   make(map[string]*api.BatchJob),
+		metadataIndex: make(map[string]map[string]map[string]struct{}),
+
+		idempotencyKeys: make(map[string]idempotencyEntry),
+		now:             time.Now,
+	}
+}
+
+// copyJob returns a copy of the job, without its static part unless includeStatic is set.
+func copyJob(job *api.BatchJob, includeStatic bool) *api.BatchJob {
+	jobCopy := &api.BatchJob{
+		ID:       job.ID,
+		SLO:      job.SLO,
+		TTL:      job.TTL,
+		Tags:     slices.Clone(job.Tags),
+		Status:   slices.Clone(job.Status),
+		Metadata: maps.Clone(job.Metadata),
+
+		TraceContext: maps.Clone(job.TraceContext),
+	}
+	if includeStatic {
+		jobCopy.Spec = slices.Clone(job.Spec)
+	}
+	return jobCopy
+}
+
+func (c *BatchDBClient) Store(ctx context.Context, job *api.BatchJob) (string, error) {
+	if err := job.IsValid(); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.get(job.ID); ok {
+		return "", fmt.Errorf("job with ID '%s' already exists", job.ID)
+	}
+	c.remove(job.ID)
+	c.jobs[job.ID] = copyJob(job, true)
+	c.expires[job.ID] = c.now().Add(time.Duration(job.TTL) * time.Second)
+	for key, value := range job.Metadata {
+		if c.metadataIndex[key] == nil {
+			c.metadataIndex[key] = make(map[string]map[string]struct{})
+		}
+		if c.metadataIndex[key][value] == nil {
+			c.metadataIndex[key][value] = make(map[string]struct{})
+		}
+		c.metadataIndex[key][value][job.ID] = struct{}{}
+	}
+	return job.ID, nil
+}
+
+// get returns the job of ID, or false if there is none or it expired.
+func (c *BatchDBClient) get(ID string) (*api.BatchJob, bool) {
+	job, ok := c.jobs[ID]
+	if !ok || !c.now().Before(c.expires[ID]) {
+		return nil, false
+	}
+	return job, true
+}
+
+// remove removes the job of ID and its metadata index entries, if it exists.
+func (c *BatchDBClient) remove(ID string) bool {
+	job, ok := c.jobs[ID]
+	if !ok {
+		return false
+	}
+	for key, value := range job.Metadata {
+		delete(c.metadataIndex[key][value], ID)
+	}
+	delete(c.jobs, ID)
+	delete(c.expires, ID)
+	return true
+}
+
+func (c *BatchDBClient) Get(ctx context.Context, IDs []string, tags []string, tagsLogicalCond api.TagsLogicalCond,
+	includeStatic bool, start, limit int,
+) ([]*api.BatchJob, int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(IDs) > 0 {
+		var jobs []*api.BatchJob
+		for _, ID := range IDs {
+			if job, ok := c.get(ID); ok {
+				jobs = append(jobs, copyJob(job, includeStatic))
+			}
+		}
+		return jobs, 0, nil
+	}
+	if len(tags) == 0 {
+		return nil, 0, nil
+	}
+
+	var matching []string
+	for ID, job := range c.jobs {
+		if _, ok := c.get(ID); ok && matchTags(job.Tags, tags, tagsLogicalCond) {
+			matching = append(matching, ID)
+		}
+	}
+	return c.page(matching, includeStatic, start, limit)
+}
+
+func (c *BatchDBClient) GetByMetadata(ctx context.Context, metadata map[string]string, tags []string, includeStatic bool, start, limit int) ([]*api.BatchJob, int, error) {
+	if len(metadata) == 0 {
+		return nil, 0, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// the jobs having the least common pair are checked against the index of the other pairs
+	var candidates map[string]struct{}
+	for key, value := range metadata {
+		IDs := c.metadataIndex[key][value]
+		if candidates == nil || len(IDs) < len(candidates) {
+			candidates = IDs
+		}
+	}
+	var matching []string
+	for ID := range candidates {
+		matches := true
+		for key, value := range metadata {
+			if _, ok := c.metadataIndex[key][value][ID]; !ok {
+				matches = false
+				break
+			}
+		}
+		if job, ok := c.get(ID); ok && matches && matchTags(job.Tags, tags, api.TagsLogicalCondAnd) {
+			matching = append(matching, ID)
+		}
+	}
+	return c.page(matching, includeStatic, start, limit)
+}
+
+// page returns the page of the jobs of IDs ordered by ID, from the offset start, and the cursor of the next page.
+func (c *BatchDBClient) page(IDs []string, includeStatic bool, start, limit int) ([]*api.BatchJob, int, error) {
+	slices.Sort(IDs)
+	IDs = IDs[min(start, len(IDs)):]
+	if limit > 0 && len(IDs) > limit {
+		IDs = IDs[:limit]
+	}
+	jobs := make([]*api.BatchJob, len(IDs))
+	for i, ID := range IDs {
+		jobs[i] = copyJob(c.jobs[ID], includeStatic)
+	}
+	return jobs, start + len(jobs), nil
+}
+
+func (c *BatchDBClient) Update(ctx context.Context, job *api.BatchJob) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, ok := c.get(job.ID)
+	if !ok {
+		return fmt.Errorf("cannot update job with ID '%s': job doesn't exist", job.ID)
+	}
+	// only the dynamic fields set in the job are updated
+	if len(job.Tags) > 0 {
+		stored.Tags = slices.Clone(job.Tags)
+	}
+	if len(job.Status) > 0 {
+		stored.Status = slices.Clone(job.Status)
+	}
+	return nil
+}
+
+func (c *BatchDBClient) Delete(ctx context.Context, IDs []string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var deleted []string
+	for _, ID := range IDs {
+		_, live := c.get(ID)
+		if c.remove(ID) && live {
+			deleted = append(deleted, ID)
+		}
+	}
+	return deleted, nil
+}
+
+func (c *BatchDBClient) DeadLetter(ctx context.Context, job *api.BatchJob) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deadLetters[job.ID] = copyJob(job, true)
+	return nil
+}
+
+func (c *BatchDBClient) GetDeadLetters(ctx context.Context, IDs []string) ([]*api.BatchJob, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var jobs []*api.BatchJob
+	for _, ID := range IDs {
+		if job, ok := c.deadLetters[ID]; ok {
+			jobs = append(jobs, copyJob(job, true))
+		}
+	}
+	return jobs, nil
+}
+
+func (c *BatchDBClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	return store.CallContext(parentCtx, timeLimit)
+}
+
+func (c *BatchDBClient) GetIdempotencyKey(ctx context.Context, key string) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.idempotencyRecord(key)), nil
+}
+
+func (c *BatchDBClient) CompareAndSetIdempotencyKey(ctx context.Context, key string, TTL int, expected, record []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := c.idempotencyRecord(key)
+	if (expected == nil) != (current == nil) || !bytes.Equal(current, expected) {
+		return false, nil
+	}
+	c.idempotencyKeys[key] = idempotencyEntry{record: slices.Clone(record), expires: c.now().Add(time.Duration(TTL) * time.Second)}
+	return true, nil
+}
+
+func (c *BatchDBClient) CompareAndDeleteIdempotencyKey(ctx context.Context, key string, expected []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current := c.idempotencyRecord(key); current == nil || !bytes.Equal(current, expected) {
+		return false, nil
+	}
+	delete(c.idempotencyKeys, key)
+	return true, nil
+}
+
+// idempotencyRecord returns the record of the idempotency key, or nil if there is none or it expired.
+func (c *BatchDBClient) idempotencyRecord(key string) []byte {
+	entry, ok := c.idempotencyKeys[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil
+	}
+	return entry.record
+}
+
+func (c *BatchDBClient) Close() error {
+	return nil
+}
+
+// matchTags checks the tags of a record against the requested tags: with TagsLogicalCondOr the record
+// must have one of them, otherwise all of them.
+func matchTags(recordTags, tags []string, cond api.TagsLogicalCond) bool {
+	matched := 0
+	for _, tag := range tags {
+		if slices.Contains(recordTags, tag) {
+			matched++
+		}
+	}
+	if cond == api.TagsLogicalCondOr {
+		return matched > 0
+	}
+	return matched == len(tags)
+}
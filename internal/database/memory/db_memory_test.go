@@ -0,0 +1,286 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the in-memory BatchDBClient.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+)
+
+var _ api.BatchDBClient = (*BatchDBClient)(nil)
+
+func newTestJob(ID string, tags ...string) *api.BatchJob {
+	return &api.BatchJob{ID: ID, SLO: time.Now(), TTL: 60, Tags: tags, Spec: []byte("spec-" + ID), Status: []byte("validating")}
+}
+
+func TestBatchDBClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("store and get", func(t *testing.T) {
+		client := NewBatchDBClient()
+		job := newTestJob("batch-1")
+		ID, err := client.Store(ctx, job)
+		require.NoError(t, err)
+		assert.Equal(t, "batch-1", ID)
+
+		// the stored job is a copy
+		job.Spec[0] = 'X'
+
+		jobs, _, err := client.Get(ctx, []string{"batch-1", "missing"}, nil, api.TagsLogicalCondNa, true, 0, 0)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, []byte("spec-batch-1"), jobs[0].Spec)
+		assert.Equal(t, []byte("validating"), jobs[0].Status)
+
+		jobs, _, err = client.Get(ctx, []string{"batch-1"}, nil, api.TagsLogicalCondNa, false, 0, 0)
+		require.NoError(t, err)
+		assert.Nil(t, jobs[0].Spec)
+	})
+
+	t.Run("store rejects invalid and duplicate jobs", func(t *testing.T) {
+		client := NewBatchDBClient()
+		_, err := client.Store(ctx, &api.BatchJob{ID: "batch-1", TTL: 60})
+		assert.Error(t, err)
+
+		_, err = client.Store(ctx, newTestJob("batch-1"))
+		require.NoError(t, err)
+		_, err = client.Store(ctx, newTestJob("batch-1"))
+		assert.Error(t, err)
+	})
+
+	t.Run("get without IDs nor tags", func(t *testing.T) {
+		client := NewBatchDBClient()
+		_, err := client.Store(ctx, newTestJob("batch-1", "tenant:a"))
+		require.NoError(t, err)
+
+		jobs, _, err := client.Get(ctx, nil, nil, api.TagsLogicalCondNa, false, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+	})
+
+	t.Run("list by tags", func(t *testing.T) {
+		client := NewBatchDBClient()
+		for i := 0; i < 5; i++ {
+			tags := []string{"tenant:a"}
+			if i%2 == 0 {
+				tags = append(tags, "even")
+			}
+			_, err := client.Store(ctx, newTestJob(fmt.Sprintf("batch-%d", i), tags...))
+			require.NoError(t, err)
+		}
+		_, err := client.Store(ctx, newTestJob("batch-other", "tenant:b"))
+		require.NoError(t, err)
+
+		jobs, _, err := client.Get(ctx, nil, []string{"tenant:a", "even"}, api.TagsLogicalCondAnd, false, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-0", "batch-2", "batch-4"}, jobIDs(jobs))
+
+		jobs, _, err = client.Get(ctx, nil, []string{"even", "tenant:b"}, api.TagsLogicalCondOr, false, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-0", "batch-2", "batch-4", "batch-other"}, jobIDs(jobs))
+
+		// the pages follow each other with the cursor
+		var listed []string
+		cursor := 0
+		for {
+			jobs, next, err := client.Get(ctx, nil, []string{"tenant:a"}, api.TagsLogicalCondAnd, false, cursor, 2)
+			require.NoError(t, err)
+			if len(jobs) == 0 {
+				break
+			}
+			listed = append(listed, jobIDs(jobs)...)
+			cursor = next
+		}
+		assert.Equal(t, []string{"batch-0", "batch-1", "batch-2", "batch-3", "batch-4"}, listed)
+	})
+
+	t.Run("get by metadata", func(t *testing.T) {
+		client := NewBatchDBClient()
+		for i, metadata := range []map[string]string{
+			{"team": "a", "env": "prod"},
+			{"team": "a"},
+			{"team": "b", "env": "prod"},
+		} {
+			job := newTestJob(fmt.Sprintf("batch-%d", i), fmt.Sprintf("tenant:%d", i%2))
+			job.Metadata = metadata
+			_, err := client.Store(ctx, job)
+			require.NoError(t, err)
+		}
+
+		jobs, _, err := client.GetByMetadata(ctx, map[string]string{"team": "a"}, nil, false, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-0", "batch-1"}, jobIDs(jobs))
+
+		jobs, _, err = client.GetByMetadata(ctx, map[string]string{"team": "a", "env": "prod"}, nil, false, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-0"}, jobIDs(jobs))
+
+		jobs, _, err = client.GetByMetadata(ctx, map[string]string{"env": "prod"}, []string{"tenant:0"}, false, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-0", "batch-2"}, jobIDs(jobs))
+
+		jobs, _, err = client.GetByMetadata(ctx, map[string]string{"team": "a"}, []string{"tenant:1"}, false, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-1"}, jobIDs(jobs))
+
+		jobs, _, err = client.GetByMetadata(ctx, map[string]string{"team": "c"}, nil, false, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		_, err = client.Delete(ctx, []string{"batch-0"})
+		require.NoError(t, err)
+		jobs, _, err = client.GetByMetadata(ctx, map[string]string{"env": "prod"}, nil, false, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-2"}, jobIDs(jobs))
+	})
+
+	t.Run("update the status", func(t *testing.T) {
+		client := NewBatchDBClient()
+		_, err := client.Store(ctx, newTestJob("batch-1", "tenant:a"))
+		require.NoError(t, err)
+
+		require.NoError(t, client.Update(ctx, &api.BatchJob{ID: "batch-1", Status: []byte("in_progress")}))
+		jobs, _, err := client.Get(ctx, []string{"batch-1"}, nil, api.TagsLogicalCondNa, true, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("in_progress"), jobs[0].Status)
+		// the empty fields are not updated
+		assert.Equal(t, []string{"tenant:a"}, jobs[0].Tags)
+		assert.Equal(t, []byte("spec-batch-1"), jobs[0].Spec)
+
+		assert.Error(t, client.Update(ctx, &api.BatchJob{ID: "missing", Status: []byte("in_progress")}))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		client := NewBatchDBClient()
+		_, err := client.Store(ctx, newTestJob("batch-1"))
+		require.NoError(t, err)
+
+		deleted, err := client.Delete(ctx, []string{"batch-1", "missing"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-1"}, deleted)
+
+		jobs, _, err := client.Get(ctx, []string{"batch-1"}, nil, api.TagsLogicalCondNa, false, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+	})
+
+	t.Run("jobs expire after their TTL", func(t *testing.T) {
+		client := NewBatchDBClient()
+		now := time.Now()
+		client.now = func() time.Time { return now }
+		_, err := client.Store(ctx, newTestJob("batch-1", "tenant:a"))
+		require.NoError(t, err)
+
+		now = now.Add(time.Minute)
+		jobs, _, err := client.Get(ctx, []string{"batch-1"}, nil, api.TagsLogicalCondNa, false, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+		assert.Error(t, client.Update(ctx, &api.BatchJob{ID: "batch-1", Status: []byte("in_progress")}))
+
+		// the ID of an expired job can be reused
+		_, err = client.Store(ctx, newTestJob("batch-1"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("dead letters", func(t *testing.T) {
+		client := NewBatchDBClient()
+		require.NoError(t, client.DeadLetter(ctx, newTestJob("batch-1")))
+
+		jobs, err := client.GetDeadLetters(ctx, []string{"batch-1", "batch-2"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-1"}, jobIDs(jobs))
+	})
+
+	t.Run("idempotency keys", func(t *testing.T) {
+		client := NewBatchDBClient()
+		now := time.Now()
+		client.now = func() time.Time { return now }
+
+		// only the first reservation of a key succeeds
+		set, err := client.CompareAndSetIdempotencyKey(ctx, "key-1", 60, nil, []byte("pending"))
+		require.NoError(t, err)
+		assert.True(t, set)
+		set, err = client.CompareAndSetIdempotencyKey(ctx, "key-1", 60, nil, []byte("other"))
+		require.NoError(t, err)
+		assert.False(t, set)
+
+		set, err = client.CompareAndSetIdempotencyKey(ctx, "key-1", 60, []byte("pending"), []byte("batch-1"))
+		require.NoError(t, err)
+		assert.True(t, set)
+		record, err := client.GetIdempotencyKey(ctx, "key-1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("batch-1"), record)
+
+		deleted, err := client.CompareAndDeleteIdempotencyKey(ctx, "key-1", []byte("pending"))
+		require.NoError(t, err)
+		assert.False(t, deleted)
+		deleted, err = client.CompareAndDeleteIdempotencyKey(ctx, "key-1", []byte("batch-1"))
+		require.NoError(t, err)
+		assert.True(t, deleted)
+
+		// an expired key can be reserved again
+		_, err = client.CompareAndSetIdempotencyKey(ctx, "key-2", 60, nil, []byte("pending"))
+		require.NoError(t, err)
+		now = now.Add(time.Minute)
+		record, err = client.GetIdempotencyKey(ctx, "key-2")
+		require.NoError(t, err)
+		assert.Nil(t, record)
+		set, err = client.CompareAndSetIdempotencyKey(ctx, "key-2", 60, nil, []byte("pending"))
+		require.NoError(t, err)
+		assert.True(t, set)
+	})
+
+	t.Run("concurrent access", func(t *testing.T) {
+		client := NewBatchDBClient()
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ID := fmt.Sprintf("batch-%d", i)
+				_, err := client.Store(ctx, newTestJob(ID, "tenant:a"))
+				assert.NoError(t, err)
+				assert.NoError(t, client.Update(ctx, &api.BatchJob{ID: ID, Status: []byte("completed")}))
+				_, _, err = client.Get(ctx, nil, []string{"tenant:a"}, api.TagsLogicalCondAnd, false, 0, 0)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		jobs, _, err := client.Get(ctx, nil, []string{"tenant:a"}, api.TagsLogicalCondAnd, false, 0, 0)
+		require.NoError(t, err)
+		assert.Len(t, jobs, 10)
+	})
+}
+
+func jobIDs(jobs []*api.BatchJob) []string {
+	IDs := make([]string, len(jobs))
+	for i, job := range jobs {
+		IDs[i] = job.ID
+	}
+	return IDs
+}
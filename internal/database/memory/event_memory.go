@@ -0,0 +1,143 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file implements the batch jobs event channels interface in memory.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
+)
+
+// eventsChanSize is the number of events buffered by a consumer channel.
+const eventsChanSize = 100
+
+type pendingEvent struct {
+	event   api.BatchEvent
+	expires time.Time
+}
+
+// BatchEventChannelClient is an in-memory api.BatchEventChannelClient.
+// The events of a job are sent to all the consumers of the job. The events sent while the job has no consumer
+// are kept until their TTL expires, and are sent to the next consumer of the job, in FIFO order.
+type BatchEventChannelClient struct {
+	mu        sync.Mutex
+	consumers map[string][]chan api.BatchEvent
+	pending   map[string][]pendingEvent
+
+	now func() time.Time
+}
+
+func NewBatchEventChannelClient() *BatchEventChannelClient {
+	return &BatchEventChannelClient{
+		consumers: make(map[string][]chan api.BatchEvent),
+		pending:   make(map[string][]pendingEvent),
+		now:       time.Now,
+	}
+}
+
+func (c *BatchEventChannelClient) ConsumerGetChannel(ctx context.Context, ID string) (*api.BatchEventsChan, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan api.BatchEvent, eventsChanSize)
+	now := c.now()
+	for _, pending := range c.pending[ID] {
+		if now.Before(pending.expires) && len(ch) < cap(ch) {
+			ch <- pending.event
+		}
+	}
+	delete(c.pending, ID)
+	c.consumers[ID] = append(c.consumers[ID], ch)
+
+	closeFn := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		// the channel is closed once, by its consumer or by Close
+		for i, consumer := range c.consumers[ID] {
+			if consumer == ch {
+				c.consumers[ID] = append(c.consumers[ID][:i], c.consumers[ID][i+1:]...)
+				if len(c.consumers[ID]) == 0 {
+					delete(c.consumers, ID)
+				}
+				close(ch)
+				return
+			}
+		}
+	}
+
+	return &api.BatchEventsChan{
+		ID:      ID,
+		Events:  ch,
+		CloseFn: closeFn,
+	}, nil
+}
+
+func (c *BatchEventChannelClient) ProducerSendEvents(ctx context.Context, events []api.BatchEvent) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sentIDs := make([]string, 0, len(events))
+	for _, event := range events {
+		if err := event.IsValid(); err != nil {
+			return sentIDs, err
+		}
+		consumers := c.consumers[event.ID]
+		if len(consumers) == 0 {
+			c.pending[event.ID] = append(c.pending[event.ID], pendingEvent{
+				event:   api.BatchEvent{ID: event.ID, Type: event.Type},
+				expires: c.now().Add(time.Duration(event.TTL) * time.Second),
+			})
+			sentIDs = append(sentIDs, event.ID)
+			continue
+		}
+		for _, ch := range consumers {
+			select {
+			case ch <- api.BatchEvent{ID: event.ID, Type: event.Type}:
+			default:
+				return sentIDs, fmt.Errorf("events channel of ID %s is full", event.ID)
+			}
+		}
+		sentIDs = append(sentIDs, event.ID)
+	}
+	return sentIDs, nil
+}
+
+func (c *BatchEventChannelClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	return store.CallContext(parentCtx, timeLimit)
+}
+
+// Close closes the channels of all the consumers.
+func (c *BatchEventChannelClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, consumers := range c.consumers {
+		for _, ch := range consumers {
+			close(ch)
+		}
+	}
+	c.consumers = make(map[string][]chan api.BatchEvent)
+	c.pending = make(map[string][]pendingEvent)
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the in-memory BatchEventChannelClient.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+)
+
+var _ api.BatchEventChannelClient = (*BatchEventChannelClient)(nil)
+
+func receive(t *testing.T, ch chan api.BatchEvent) api.BatchEvent {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+		return api.BatchEvent{}
+	}
+}
+
+func TestBatchEventChannelClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publish and subscribe", func(t *testing.T) {
+		client := NewBatchEventChannelClient()
+		consumer1, err := client.ConsumerGetChannel(ctx, "batch-1")
+		require.NoError(t, err)
+		consumer2, err := client.ConsumerGetChannel(ctx, "batch-1")
+		require.NoError(t, err)
+		other, err := client.ConsumerGetChannel(ctx, "batch-2")
+		require.NoError(t, err)
+
+		sentIDs, err := client.ProducerSendEvents(ctx, []api.BatchEvent{
+			{ID: "batch-1", Type: api.BatchEventPause, TTL: 60},
+			{ID: "batch-1", Type: api.BatchEventCancel, TTL: 60},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"batch-1", "batch-1"}, sentIDs)
+
+		for _, consumer := range []*api.BatchEventsChan{consumer1, consumer2} {
+			assert.Equal(t, api.BatchEvent{ID: "batch-1", Type: api.BatchEventPause}, receive(t, consumer.Events))
+			assert.Equal(t, api.BatchEvent{ID: "batch-1", Type: api.BatchEventCancel}, receive(t, consumer.Events))
+		}
+		assert.Empty(t, other.Events)
+
+		consumer1.CloseFn()
+		_, open := <-consumer1.Events
+		assert.False(t, open)
+		consumer2.CloseFn()
+		other.CloseFn()
+		require.NoError(t, client.Close())
+	})
+
+	t.Run("events sent before the consumer subscribes", func(t *testing.T) {
+		client := NewBatchEventChannelClient()
+		now := time.Now()
+		client.now = func() time.Time { return now }
+
+		_, err := client.ProducerSendEvents(ctx, []api.BatchEvent{{ID: "batch-1", Type: api.BatchEventCancel, TTL: 60}})
+		require.NoError(t, err)
+		_, err = client.ProducerSendEvents(ctx, []api.BatchEvent{{ID: "batch-2", Type: api.BatchEventCancel, TTL: 60}})
+		require.NoError(t, err)
+
+		consumer, err := client.ConsumerGetChannel(ctx, "batch-1")
+		require.NoError(t, err)
+		assert.Equal(t, api.BatchEvent{ID: "batch-1", Type: api.BatchEventCancel}, receive(t, consumer.Events))
+
+		// the events expire after their TTL
+		now = now.Add(time.Minute)
+		expired, err := client.ConsumerGetChannel(ctx, "batch-2")
+		require.NoError(t, err)
+		assert.Empty(t, expired.Events)
+	})
+
+	t.Run("invalid events are rejected", func(t *testing.T) {
+		client := NewBatchEventChannelClient()
+		sentIDs, err := client.ProducerSendEvents(ctx, []api.BatchEvent{
+			{ID: "batch-1", Type: api.BatchEventCancel, TTL: 60},
+			{ID: "batch-1", Type: api.BatchEventMaxVal, TTL: 60},
+		})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"batch-1"}, sentIDs)
+	})
+
+	t.Run("close closes the consumer channels", func(t *testing.T) {
+		client := NewBatchEventChannelClient()
+		consumer, err := client.ConsumerGetChannel(ctx, "batch-1")
+		require.NoError(t, err)
+
+		require.NoError(t, client.Close())
+		_, open := <-consumer.Events
+		assert.False(t, open)
+
+		// closing the consumer afterwards is a no-op
+		consumer.CloseFn()
+	})
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file implements the batch files metadata store interface in memory.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
+)
+
+// BatchFileDBClient is an in-memory api.BatchFileDBClient.
+// The records expire after their TTL, and are copied in and out so callers don't share them.
+type BatchFileDBClient struct {
+	mu      sync.RWMutex
+	files   map[string]*api.BatchFile
+	expires map[string]time.Time
+
+	now func() time.Time
+}
+
+func NewBatchFileDBClient() *BatchFileDBClient {
+	return &BatchFileDBClient{
+		files:   make(map[string]*api.BatchFile),
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// copyFile returns a copy of the file record.
+func copyFile(file *api.BatchFile) *api.BatchFile {
+	fileCopy := *file
+	fileCopy.Tags = slices.Clone(file.Tags)
+	fileCopy.Spec = slices.Clone(file.Spec)
+	return &fileCopy
+}
+
+func (c *BatchFileDBClient) Store(ctx context.Context, file *api.BatchFile) (string, error) {
+	if err := file.IsValid(); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.get(file.ID); ok {
+		return "", fmt.Errorf("file with ID '%s' already exists", file.ID)
+	}
+	c.files[file.ID] = copyFile(file)
+	c.expires[file.ID] = c.now().Add(time.Duration(file.TTL) * time.Second)
+	return file.ID, nil
+}
+
+// get returns the file of ID, or false if there is none or it expired.
+func (c *BatchFileDBClient) get(ID string) (*api.BatchFile, bool) {
+	file, ok := c.files[ID]
+	if !ok || !c.now().Before(c.expires[ID]) {
+		return nil, false
+	}
+	return file, true
+}
+
+func (c *BatchFileDBClient) Get(ctx context.Context, IDs []string, tags []string, tagsLogicalCond api.TagsLogicalCond, start, limit int) ([]*api.BatchFile, int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(IDs) > 0 {
+		var files []*api.BatchFile
+		for _, ID := range IDs {
+			if file, ok := c.get(ID); ok {
+				files = append(files, copyFile(file))
+			}
+		}
+		return files, 0, nil
+	}
+	if len(tags) == 0 {
+		return nil, 0, nil
+	}
+
+	// the matching files are paged in the order of their IDs
+	var matching []string
+	for ID, file := range c.files {
+		if _, ok := c.get(ID); ok && matchTags(file.Tags, tags, tagsLogicalCond) {
+			matching = append(matching, ID)
+		}
+	}
+	slices.Sort(matching)
+	matching = matching[min(start, len(matching)):]
+	if limit > 0 && len(matching) > limit {
+		matching = matching[:limit]
+	}
+	files := make([]*api.BatchFile, len(matching))
+	for i, ID := range matching {
+		files[i] = copyFile(c.files[ID])
+	}
+	return files, start + len(files), nil
+}
+
+func (c *BatchFileDBClient) Delete(ctx context.Context, IDs []string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var deleted []string
+	for _, ID := range IDs {
+		_, live := c.get(ID)
+		if _, ok := c.files[ID]; ok {
+			delete(c.files, ID)
+			delete(c.expires, ID)
+			if live {
+				deleted = append(deleted, ID)
+			}
+		}
+	}
+	return deleted, nil
+}
+
+func (c *BatchFileDBClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	return store.CallContext(parentCtx, timeLimit)
+}
+
+func (c *BatchFileDBClient) Close() error {
+	return nil
+}
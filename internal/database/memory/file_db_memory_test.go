@@ -0,0 +1,126 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the in-memory BatchFileDBClient.
+
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+)
+
+var _ api.BatchFileDBClient = (*BatchFileDBClient)(nil)
+
+func newTestFile(ID string, tags ...string) *api.BatchFile {
+	return &api.BatchFile{ID: ID, TTL: 60, Tags: tags, Spec: []byte("spec-" + ID), Location: "tenant/" + ID}
+}
+
+func fileIDs(files []*api.BatchFile) []string {
+	IDs := make([]string, len(files))
+	for i, file := range files {
+		IDs[i] = file.ID
+	}
+	return IDs
+}
+
+func TestBatchFileDBClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("store and get", func(t *testing.T) {
+		client := NewBatchFileDBClient()
+		file := newTestFile("file-1")
+		ID, err := client.Store(ctx, file)
+		require.NoError(t, err)
+		assert.Equal(t, "file-1", ID)
+
+		// the stored file is a copy
+		file.Spec[0] = 'X'
+
+		files, _, err := client.Get(ctx, []string{"file-1", "missing"}, nil, api.TagsLogicalCondNa, 0, 0)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		assert.Equal(t, []byte("spec-file-1"), files[0].Spec)
+		assert.Equal(t, "tenant/file-1", files[0].ContentLocation())
+	})
+
+	t.Run("store rejects invalid and duplicate files", func(t *testing.T) {
+		client := NewBatchFileDBClient()
+		_, err := client.Store(ctx, &api.BatchFile{ID: "file-1"})
+		assert.Error(t, err)
+
+		_, err = client.Store(ctx, newTestFile("file-1"))
+		require.NoError(t, err)
+		_, err = client.Store(ctx, newTestFile("file-1"))
+		assert.Error(t, err)
+	})
+
+	t.Run("list by tags", func(t *testing.T) {
+		client := NewBatchFileDBClient()
+		for i := 0; i < 3; i++ {
+			_, err := client.Store(ctx, newTestFile(fmt.Sprintf("file-%d", i), "tenant:a"))
+			require.NoError(t, err)
+		}
+		_, err := client.Store(ctx, newTestFile("file-other", "tenant:b"))
+		require.NoError(t, err)
+
+		files, cursor, err := client.Get(ctx, nil, []string{"tenant:a"}, api.TagsLogicalCondAnd, 0, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"file-0", "file-1"}, fileIDs(files))
+
+		files, _, err = client.Get(ctx, nil, []string{"tenant:a"}, api.TagsLogicalCondAnd, cursor, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"file-2"}, fileIDs(files))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		client := NewBatchFileDBClient()
+		_, err := client.Store(ctx, newTestFile("file-1"))
+		require.NoError(t, err)
+
+		deleted, err := client.Delete(ctx, []string{"file-1", "missing"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"file-1"}, deleted)
+
+		files, _, err := client.Get(ctx, []string{"file-1"}, nil, api.TagsLogicalCondNa, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("files expire after their TTL", func(t *testing.T) {
+		client := NewBatchFileDBClient()
+		now := time.Now()
+		client.now = func() time.Time { return now }
+		_, err := client.Store(ctx, newTestFile("file-1"))
+		require.NoError(t, err)
+
+		now = now.Add(time.Minute)
+		files, _, err := client.Get(ctx, []string{"file-1"}, nil, api.TagsLogicalCondNa, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, files)
+
+		// the ID of an expired file can be reused
+		_, err = client.Store(ctx, newTestFile("file-1"))
+		assert.NoError(t, err)
+	})
+}
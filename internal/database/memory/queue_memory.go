@@ -0,0 +1,127 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file implements the batch jobs priority queue interface in memory.
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
+)
+
+// BatchPriorityQueueClient is an in-memory api.BatchPriorityQueueClient.
+// The queue is kept in dequeue order, and blocked dequeuers are woken up by the enqueues.
+type BatchPriorityQueueClient struct {
+	mu    sync.Mutex
+	queue []*api.BatchJobPriority
+
+	// enqueued is closed and replaced on every enqueue, to wake up the blocked dequeuers
+	enqueued chan struct{}
+}
+
+func NewBatchPriorityQueueClient() *BatchPriorityQueueClient {
+	return &BatchPriorityQueueClient{
+		enqueued: make(chan struct{}),
+	}
+}
+
+func (c *BatchPriorityQueueClient) Enqueue(ctx context.Context, jobPriority *api.BatchJobPriority) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// jobs of the same priority are dequeued in the order they were enqueued
+	i := len(c.queue)
+	for i > 0 && jobPriority.Before(c.queue[i-1]) {
+		i--
+	}
+	jobCopy := *jobPriority
+	c.queue = append(c.queue, nil)
+	copy(c.queue[i+1:], c.queue[i:])
+	c.queue[i] = &jobCopy
+
+	close(c.enqueued)
+	c.enqueued = make(chan struct{})
+	return nil
+}
+
+func (c *BatchPriorityQueueClient) Dequeue(ctx context.Context, timeout time.Duration, maxObjs int) ([]*api.BatchJobPriority, error) {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		c.mu.Lock()
+		if len(c.queue) > 0 || timeout <= 0 {
+			count := min(max(maxObjs, 1), len(c.queue))
+			jobPriorities := make([]*api.BatchJobPriority, count)
+			copy(jobPriorities, c.queue[:count])
+			c.queue = c.queue[count:]
+			c.mu.Unlock()
+			return jobPriorities, nil
+		}
+		enqueued := c.enqueued
+		c.mu.Unlock()
+
+		select {
+		case <-enqueued:
+		case <-deadline:
+			return []*api.BatchJobPriority{}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *BatchPriorityQueueClient) Remove(ctx context.Context, jobPriority *api.BatchJobPriority) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	queue := c.queue[:0]
+	for _, jp := range c.queue {
+		if jp.ID == jobPriority.ID {
+			removed++
+			continue
+		}
+		queue = append(queue, jp)
+	}
+	clear(c.queue[len(queue):])
+	c.queue = queue
+	return removed, nil
+}
+
+func (c *BatchPriorityQueueClient) Len(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.queue), nil
+}
+
+func (c *BatchPriorityQueueClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	return store.CallContext(parentCtx, timeLimit)
+}
+
+func (c *BatchPriorityQueueClient) Close() error {
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the in-memory BatchPriorityQueueClient.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+)
+
+var _ api.BatchPriorityQueueClient = (*BatchPriorityQueueClient)(nil)
+
+func TestBatchPriorityQueueClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("dequeues in priority order", func(t *testing.T) {
+		client := NewBatchPriorityQueueClient()
+		now := time.Now()
+		for _, jp := range []*api.BatchJobPriority{
+			{ID: "late", SLO: now.Add(time.Hour)},
+			{ID: "early", SLO: now},
+			{ID: "low", SLO: now, Priority: api.PriorityLow},
+			{ID: "high", SLO: now.Add(2 * time.Hour), Priority: api.PriorityHigh},
+			{ID: "early-2", SLO: now},
+		} {
+			require.NoError(t, client.Enqueue(ctx, jp))
+		}
+		n, err := client.Len(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 5, n)
+
+		var dequeued []string
+		for {
+			jobPriorities, err := client.Dequeue(ctx, 0, 2)
+			require.NoError(t, err)
+			if len(jobPriorities) == 0 {
+				break
+			}
+			for _, jp := range jobPriorities {
+				dequeued = append(dequeued, jp.ID)
+			}
+		}
+		assert.Equal(t, []string{"high", "early", "early-2", "late", "low"}, dequeued)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		client := NewBatchPriorityQueueClient()
+		require.NoError(t, client.Enqueue(ctx, &api.BatchJobPriority{ID: "batch-1", SLO: time.Now()}))
+		require.NoError(t, client.Enqueue(ctx, &api.BatchJobPriority{ID: "batch-2", SLO: time.Now()}))
+
+		removed, err := client.Remove(ctx, &api.BatchJobPriority{ID: "batch-1"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, removed)
+		removed, err = client.Remove(ctx, &api.BatchJobPriority{ID: "batch-1"})
+		require.NoError(t, err)
+		assert.Equal(t, 0, removed)
+
+		n, err := client.Len(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("dequeue waits for an enqueue", func(t *testing.T) {
+		client := NewBatchPriorityQueueClient()
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			assert.NoError(t, client.Enqueue(ctx, &api.BatchJobPriority{ID: "batch-1", SLO: time.Now()}))
+		}()
+
+		jobPriorities, err := client.Dequeue(ctx, 5*time.Second, 1)
+		require.NoError(t, err)
+		require.Len(t, jobPriorities, 1)
+		assert.Equal(t, "batch-1", jobPriorities[0].ID)
+	})
+
+	t.Run("dequeue times out", func(t *testing.T) {
+		client := NewBatchPriorityQueueClient()
+		jobPriorities, err := client.Dequeue(ctx, 20*time.Millisecond, 1)
+		require.NoError(t, err)
+		assert.Empty(t, jobPriorities)
+	})
+
+	t.Run("dequeue stops with the context", func(t *testing.T) {
+		client := NewBatchPriorityQueueClient()
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := client.Dequeue(cancelCtx, time.Minute, 1)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file implements the batch jobs temporary status store interface in memory.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
+)
+
+// BatchStatusClient is an in-memory api.BatchStatusClient.
+type BatchStatusClient struct {
+	mu      sync.Mutex
+	status  map[string][]byte
+	expires map[string]time.Time // the expiration time of the data having a TTL
+
+	now func() time.Time
+}
+
+func NewBatchStatusClient() *BatchStatusClient {
+	return &BatchStatusClient{
+		status:  make(map[string][]byte),
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (c *BatchStatusClient) Set(ctx context.Context, ID string, TTL int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(ID, TTL, data)
+	return nil
+}
+
+// set stores a copy of the data, which expires after TTL seconds if it is positive.
+func (c *BatchStatusClient) set(ID string, TTL int, data []byte) {
+	c.status[ID] = slices.Clone(data)
+	if TTL > 0 {
+		c.expires[ID] = c.now().Add(time.Duration(TTL) * time.Second)
+	} else {
+		delete(c.expires, ID)
+	}
+}
+
+// get returns the data of ID, removing it if it expired.
+func (c *BatchStatusClient) get(ID string) ([]byte, bool) {
+	if expires, ok := c.expires[ID]; ok && !c.now().Before(expires) {
+		c.delete(ID)
+	}
+	data, ok := c.status[ID]
+	return data, ok
+}
+
+func (c *BatchStatusClient) delete(ID string) {
+	delete(c.status, ID)
+	delete(c.expires, ID)
+}
+
+func (c *BatchStatusClient) Get(ctx context.Context, ID string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.get(ID)
+	if !ok {
+		return nil, nil
+	}
+	return slices.Clone(data), nil
+}
+
+func (c *BatchStatusClient) Delete(ctx context.Context, ID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.delete(ID)
+	return nil
+}
+
+func (c *BatchStatusClient) CompareAndSet(ctx context.Context, ID string, TTL int, expected, data []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, ok := c.get(ID)
+	if ok != (expected != nil) || !bytes.Equal(stored, expected) {
+		return false, nil
+	}
+	c.set(ID, TTL, data)
+	return true, nil
+}
+
+func (c *BatchStatusClient) CompareAndDelete(ctx context.Context, ID string, expected []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, ok := c.get(ID)
+	if !ok || !bytes.Equal(stored, expected) {
+		return false, nil
+	}
+	c.delete(ID)
+	return true, nil
+}
+
+func (c *BatchStatusClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	return store.CallContext(parentCtx, timeLimit)
+}
+
+func (c *BatchStatusClient) Close() error {
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the in-memory BatchStatusClient.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+)
+
+var _ api.BatchStatusClient = (*BatchStatusClient)(nil)
+
+func TestBatchStatusClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("set, get and delete", func(t *testing.T) {
+		client := NewBatchStatusClient()
+		data, err := client.Get(ctx, "batch-1")
+		require.NoError(t, err)
+		assert.Nil(t, data)
+
+		require.NoError(t, client.Set(ctx, "batch-1", 60, []byte("10")))
+		require.NoError(t, client.Set(ctx, "batch-1", 60, []byte("20")))
+		data, err = client.Get(ctx, "batch-1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("20"), data)
+
+		require.NoError(t, client.Delete(ctx, "batch-1"))
+		data, err = client.Get(ctx, "batch-1")
+		require.NoError(t, err)
+		assert.Nil(t, data)
+	})
+
+	t.Run("data expires after its TTL", func(t *testing.T) {
+		client := NewBatchStatusClient()
+		now := time.Now()
+		client.now = func() time.Time { return now }
+		require.NoError(t, client.Set(ctx, "batch-1", 60, []byte("10")))
+		require.NoError(t, client.Set(ctx, "batch-2", 0, []byte("10")))
+
+		now = now.Add(time.Minute)
+		data, err := client.Get(ctx, "batch-1")
+		require.NoError(t, err)
+		assert.Nil(t, data)
+		data, err = client.Get(ctx, "batch-2")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("10"), data)
+	})
+
+	t.Run("compare and set", func(t *testing.T) {
+		client := NewBatchStatusClient()
+		set, err := client.CompareAndSet(ctx, "batch-1", 60, nil, []byte("owner-a"))
+		require.NoError(t, err)
+		assert.True(t, set)
+
+		set, err = client.CompareAndSet(ctx, "batch-1", 60, nil, []byte("owner-b"))
+		require.NoError(t, err)
+		assert.False(t, set)
+
+		set, err = client.CompareAndSet(ctx, "batch-1", 60, []byte("owner-a"), []byte("owner-b"))
+		require.NoError(t, err)
+		assert.True(t, set)
+
+		deleted, err := client.CompareAndDelete(ctx, "batch-1", []byte("owner-a"))
+		require.NoError(t, err)
+		assert.False(t, deleted)
+		deleted, err = client.CompareAndDelete(ctx, "batch-1", []byte("owner-b"))
+		require.NoError(t, err)
+		assert.True(t, deleted)
+	})
+}
@@ -20,25 +20,79 @@ package mock
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
 )
 
 type MockBatchDBClient struct {
-	jobs sync.Map
+	jobs        sync.Map
+	deadLetters sync.Map
+
+	// metadataIndex maps a metadata key and value to the IDs of the jobs having them.
+	// The metadata of a job is kept as stored, as it is immutable and not given to updates
+	indexMu       sync.RWMutex
+	metadataIndex map[string]map[string]map[string]struct{}
+	jobMetadata   map[string]map[string]string
+
+	// jobsRead counts the job records read by the gets, to check the jobs selected by an index
+	jobsRead atomic.Int64
+
+	// idempotencyKeys keeps the records of the idempotency keys, which expire like the temporary status
+	idempotencyKeys *MockBatchStatusClient
 }
 
 func NewMockBatchDBClient() *MockBatchDBClient {
-	return &MockBatchDBClient{}
+	return &MockBatchDBClient{
+		metadataIndex: map[string]map[string]map[string]struct{}{},
+		jobMetadata:   map[string]map[string]string{},
+
+		idempotencyKeys: NewMockBatchStatusClient(),
+	}
 }
 
 func (m *MockBatchDBClient) Store(ctx context.Context, job *api.BatchJob) (string, error) {
 	m.jobs.Store(job.ID, job)
+	m.unindexMetadata(job.ID)
+	m.indexMetadata(job)
 	return job.ID, nil
 }
 
+// indexMetadata adds the job to the index of its metadata pairs.
+func (m *MockBatchDBClient) indexMetadata(job *api.BatchJob) {
+	if len(job.Metadata) == 0 {
+		return
+	}
+	m.indexMu.Lock()
+	defer m.indexMu.Unlock()
+	m.jobMetadata[job.ID] = job.Metadata
+	for key, value := range job.Metadata {
+		values, ok := m.metadataIndex[key]
+		if !ok {
+			values = map[string]map[string]struct{}{}
+			m.metadataIndex[key] = values
+		}
+		if values[value] == nil {
+			values[value] = map[string]struct{}{}
+		}
+		values[value][job.ID] = struct{}{}
+	}
+}
+
+// unindexMetadata removes the job from the index of its metadata pairs.
+func (m *MockBatchDBClient) unindexMetadata(ID string) {
+	m.indexMu.Lock()
+	defer m.indexMu.Unlock()
+	for key, value := range m.jobMetadata[ID] {
+		delete(m.metadataIndex[key][value], ID)
+	}
+	delete(m.jobMetadata, ID)
+}
+
 func (m *MockBatchDBClient) Get(ctx context.Context, IDs []string, tags []string, tagsLogicalCond api.TagsLogicalCond, includeStatic bool, start, limit int) ([]*api.BatchJob, int, error) {
 	var results []*api.BatchJob
 
@@ -47,13 +101,15 @@ func (m *MockBatchDBClient) Get(ctx context.Context, IDs []string, tags []string
 		for _, id := range IDs {
 			if value, ok := m.jobs.Load(id); ok {
 				if job, ok := value.(*api.BatchJob); ok {
+					m.jobsRead.Add(1)
 					results = append(results, job)
 				}
 			}
 		}
 	} else {
 		m.jobs.Range(func(key, value any) bool {
-			if job, ok := value.(*api.BatchJob); ok {
+			m.jobsRead.Add(1)
+			if job, ok := value.(*api.BatchJob); ok && matchTags(job.Tags, tags, tagsLogicalCond) {
 				results = append(results, job)
 				if len(results) >= limit && limit > 0 {
 					return false
@@ -66,11 +122,75 @@ func (m *MockBatchDBClient) Get(ctx context.Context, IDs []string, tags []string
 	return results, 0, nil
 }
 
+func (m *MockBatchDBClient) GetByMetadata(ctx context.Context, metadata map[string]string, tags []string, includeStatic bool, start, limit int) ([]*api.BatchJob, int, error) {
+	if len(metadata) == 0 {
+		return nil, 0, nil
+	}
+
+	// the jobs having the least common pair are checked against the index of the other pairs
+	m.indexMu.RLock()
+	var candidates map[string]struct{}
+	for key, value := range metadata {
+		ids := m.metadataIndex[key][value]
+		if candidates == nil || len(ids) < len(candidates) {
+			candidates = ids
+		}
+	}
+	var IDs []string
+	for id := range candidates {
+		matches := true
+		for key, value := range metadata {
+			if _, ok := m.metadataIndex[key][value][id]; !ok {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			IDs = append(IDs, id)
+		}
+	}
+	m.indexMu.RUnlock()
+
+	// the tags are not indexed, they are checked on the records of the jobs having the metadata
+	if len(tags) > 0 {
+		IDs = slices.DeleteFunc(IDs, func(id string) bool {
+			value, ok := m.jobs.Load(id)
+			if !ok {
+				return true
+			}
+			m.jobsRead.Add(1)
+			job, ok := value.(*api.BatchJob)
+			return !ok || !matchTags(job.Tags, tags, api.TagsLogicalCondAnd)
+		})
+	}
+
+	// start is the offset of the page in the matching jobs, ordered by ID
+	slices.Sort(IDs)
+	IDs = IDs[min(start, len(IDs)):]
+	if limit > 0 && len(IDs) > limit {
+		IDs = IDs[:limit]
+	}
+	if len(IDs) == 0 {
+		return nil, start, nil
+	}
+	results, _, err := m.Get(ctx, IDs, nil, api.TagsLogicalCondNa, includeStatic, 0, 0)
+	return results, start + len(IDs), err
+}
+
 func (m *MockBatchDBClient) Update(ctx context.Context, job *api.BatchJob) error {
-	if _, ok := m.jobs.Load(job.ID); !ok {
+	value, ok := m.jobs.Load(job.ID)
+	if !ok {
 		return fmt.Errorf("cannot update job with ID '%s': job doesn't exist", job.ID)
 	}
-	m.jobs.Store(job.ID, job)
+	// only the dynamic fields set in the job are updated
+	updated := *value.(*api.BatchJob)
+	if len(job.Tags) > 0 {
+		updated.Tags = job.Tags
+	}
+	if len(job.Status) > 0 {
+		updated.Status = job.Status
+	}
+	m.jobs.Store(job.ID, &updated)
 	return nil
 }
 
@@ -78,17 +198,53 @@ func (m *MockBatchDBClient) Delete(ctx context.Context, IDs []string) ([]string,
 	var deleted []string
 	for _, id := range IDs {
 		if _, ok := m.jobs.LoadAndDelete(id); ok {
+			m.unindexMetadata(id)
 			deleted = append(deleted, id)
 		}
 	}
 	return deleted, nil
 }
 
+func (m *MockBatchDBClient) DeadLetter(ctx context.Context, job *api.BatchJob) error {
+	// store a copy, so later updates of the job don't change its dead-lettered state
+	jobCopy := *job
+	m.deadLetters.Store(job.ID, &jobCopy)
+	return nil
+}
+
+func (m *MockBatchDBClient) GetDeadLetters(ctx context.Context, IDs []string) ([]*api.BatchJob, error) {
+	var results []*api.BatchJob
+	for _, id := range IDs {
+		if value, ok := m.deadLetters.Load(id); ok {
+			results = append(results, value.(*api.BatchJob))
+		}
+	}
+	return results, nil
+}
+
+func (m *MockBatchDBClient) GetIdempotencyKey(ctx context.Context, key string) ([]byte, error) {
+	return m.idempotencyKeys.Get(ctx, key)
+}
+
+func (m *MockBatchDBClient) CompareAndSetIdempotencyKey(ctx context.Context, key string, TTL int, expected, record []byte) (bool, error) {
+	return m.idempotencyKeys.CompareAndSet(ctx, key, TTL, expected, record)
+}
+
+func (m *MockBatchDBClient) CompareAndDeleteIdempotencyKey(ctx context.Context, key string, expected []byte) (bool, error) {
+	return m.idempotencyKeys.CompareAndDelete(ctx, key, expected)
+}
+
 func (m *MockBatchDBClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(parentCtx, timeLimit)
+	return store.CallContext(parentCtx, timeLimit)
 }
 
 func (m *MockBatchDBClient) Close() error {
 	m.jobs.Clear()
+	m.deadLetters.Clear()
+	m.indexMu.Lock()
+	clear(m.metadataIndex)
+	clear(m.jobMetadata)
+	m.indexMu.Unlock()
+	m.idempotencyKeys.Close()
 	return nil
 }
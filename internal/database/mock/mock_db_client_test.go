@@ -0,0 +1,100 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the in-memory mock of BatchDBClient.
+package mock
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+)
+
+func TestGetByMetadata(t *testing.T) {
+	ctx := context.Background()
+	client := NewMockBatchDBClient()
+	for i := 0; i < 1000; i++ {
+		metadata := map[string]string{"team": fmt.Sprintf("team-%d", i%10)}
+		if i%100 == 0 {
+			metadata["env"] = "prod"
+		}
+		job := &api.BatchJob{ID: fmt.Sprintf("batch-%04d", i), SLO: time.Now(), TTL: 60, Metadata: metadata}
+		if _, err := client.Store(ctx, job); err != nil {
+			t.Fatalf("Failed to store job: %v", err)
+		}
+	}
+
+	getIDs := func(metadata map[string]string, start, limit int) []string {
+		t.Helper()
+		jobs, _, err := client.GetByMetadata(ctx, metadata, nil, true, start, limit)
+		if err != nil {
+			t.Fatalf("Failed to get jobs by metadata: %v", err)
+		}
+		var IDs []string
+		for _, job := range jobs {
+			IDs = append(IDs, job.ID)
+		}
+		return IDs
+	}
+
+	t.Run("matching jobs only", func(t *testing.T) {
+		client.jobsRead.Store(0)
+		IDs := getIDs(map[string]string{"team": "team-0", "env": "prod"}, 0, 0)
+
+		want := []string{}
+		for i := 0; i < 1000; i += 100 {
+			want = append(want, fmt.Sprintf("batch-%04d", i))
+		}
+		if !slices.Equal(IDs, want) {
+			t.Errorf("Expected jobs %v, got %v", want, IDs)
+		}
+		// the jobs are selected by the index, only the matching records are read
+		if read := client.jobsRead.Load(); read != int64(len(want)) {
+			t.Errorf("Expected %d job records read, got %d", len(want), read)
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		first := getIDs(map[string]string{"env": "prod"}, 0, 4)
+		second := getIDs(map[string]string{"env": "prod"}, 4, 10)
+		if len(first) != 4 || len(second) != 6 || first[3] >= second[0] {
+			t.Errorf("Expected pages of 4 and 6 ordered jobs, got %v and %v", first, second)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		if IDs := getIDs(map[string]string{"team": "team-0", "env": "dev"}, 0, 0); len(IDs) != 0 {
+			t.Errorf("Expected no jobs, got %v", IDs)
+		}
+		if IDs := getIDs(nil, 0, 0); len(IDs) != 0 {
+			t.Errorf("Expected no jobs without metadata, got %v", IDs)
+		}
+	})
+
+	t.Run("deleted jobs", func(t *testing.T) {
+		if _, err := client.Delete(ctx, []string{"batch-0000"}); err != nil {
+			t.Fatalf("Failed to delete job: %v", err)
+		}
+		IDs := getIDs(map[string]string{"env": "prod"}, 0, 0)
+		if len(IDs) != 9 || slices.Contains(IDs, "batch-0000") {
+			t.Errorf("Expected the deleted job to be removed from the index, got %v", IDs)
+		}
+	})
+}
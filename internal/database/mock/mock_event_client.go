@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
 )
 
 type eventChannel struct {
@@ -120,7 +121,7 @@ func (m *MockBatchEventChannelClient) ProducerSendEvents(ctx context.Context, ev
 }
 
 func (m *MockBatchEventChannelClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(parentCtx, timeLimit)
+	return store.CallContext(parentCtx, timeLimit)
 }
 
 func (m *MockBatchEventChannelClient) Close() error {
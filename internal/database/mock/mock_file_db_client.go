@@ -0,0 +1,133 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides in-memory mock implementations for BatchFileDBClient.
+package mock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
+)
+
+type MockBatchFileDBClient struct {
+	mu    sync.RWMutex
+	files map[string]*api.BatchFile
+}
+
+func NewMockBatchFileDBClient() *MockBatchFileDBClient {
+	return &MockBatchFileDBClient{
+		files: make(map[string]*api.BatchFile),
+	}
+}
+
+func (m *MockBatchFileDBClient) Store(ctx context.Context, file *api.BatchFile) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[file.ID] = file
+	return file.ID, nil
+}
+
+func (m *MockBatchFileDBClient) Get(ctx context.Context, IDs []string, tags []string, tagsLogicalCond api.TagsLogicalCond, start, limit int) ([]*api.BatchFile, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []*api.BatchFile
+
+	// If IDs are specified, get by IDs
+	if len(IDs) > 0 {
+		for _, id := range IDs {
+			if file, ok := m.files[id]; ok {
+				results = append(results, file)
+			}
+		}
+		return results, 0, nil
+	}
+
+	// Otherwise, get by tags with a stable order for pagination
+	ids := make([]string, 0, len(m.files))
+	for id := range m.files {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		file := m.files[id]
+		if !matchTags(file.Tags, tags, tagsLogicalCond) {
+			continue
+		}
+		results = append(results, file)
+	}
+
+	if start > len(results) {
+		start = len(results)
+	}
+	results = results[start:]
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, start + len(results), nil
+}
+
+func (m *MockBatchFileDBClient) Delete(ctx context.Context, IDs []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted []string
+	for _, id := range IDs {
+		if _, ok := m.files[id]; ok {
+			delete(m.files, id)
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MockBatchFileDBClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	return store.CallContext(parentCtx, timeLimit)
+}
+
+func (m *MockBatchFileDBClient) Close() error {
+	return nil
+}
+
+// matchTags checks if the tags of an object match the requested tags.
+func matchTags(objTags, tags []string, cond api.TagsLogicalCond) bool {
+	if len(tags) == 0 {
+		return true
+	}
+
+	set := make(map[string]struct{}, len(objTags))
+	for _, tag := range objTags {
+		set[tag] = struct{}{}
+	}
+
+	matched := 0
+	for _, tag := range tags {
+		if _, ok := set[tag]; ok {
+			matched++
+		}
+	}
+	if cond == api.TagsLogicalCondOr {
+		return matched > 0
+	}
+	return matched == len(tags)
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
 )
 
 type MockBatchPriorityQueueClient struct {
@@ -40,10 +41,10 @@ func (m *MockBatchPriorityQueueClient) Enqueue(ctx context.Context, jobPriority
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Insert in sorted order by SLO (earlier SLO = higher priority)
+	// Insert in sorted order by priority, then by SLO (earlier SLO = higher priority)
 	insertIdx := len(m.queue)
 	for i, jp := range m.queue {
-		if jobPriority.SLO.Before(jp.SLO) {
+		if jobPriority.Before(jp) {
 			insertIdx = i
 			break
 		}
@@ -113,8 +114,15 @@ func (m *MockBatchPriorityQueueClient) Remove(ctx context.Context, jobPriority *
 	return 0, nil
 }
 
+func (m *MockBatchPriorityQueueClient) Len(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.queue), nil
+}
+
 func (m *MockBatchPriorityQueueClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(parentCtx, timeLimit)
+	return store.CallContext(parentCtx, timeLimit)
 }
 
 func (m *MockBatchPriorityQueueClient) Close() error {
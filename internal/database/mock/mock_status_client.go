@@ -18,43 +18,75 @@ limitations under the License.
 package mock
 
 import (
+	"bytes"
 	"context"
 	"sync"
 	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
 )
 
 type MockBatchStatusClient struct {
-	mu     sync.RWMutex
-	status map[string][]byte // Map of job ID to status data
+	mu      sync.RWMutex
+	status  map[string][]byte    // Map of job ID to status data
+	expires map[string]time.Time // Map of job ID to the expiration time of its data, if it has a TTL
+	now     func() time.Time
 }
 
 func NewMockBatchStatusClient() *MockBatchStatusClient {
 	return &MockBatchStatusClient{
-		status: make(map[string][]byte),
+		status:  make(map[string][]byte),
+		expires: make(map[string]time.Time),
+		now:     time.Now,
 	}
 }
 
+// SetClock replaces the clock against which the TTL of the data expires, so tests can expire data.
+func (m *MockBatchStatusClient) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = now
+}
+
 func (m *MockBatchStatusClient) Set(ctx context.Context, ID string, TTL int, data []byte) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Store a copy of the data to avoid external modifications
+	m.set(ID, TTL, data)
+	return nil
+}
+
+// set stores a copy of the data, to avoid external modifications, which expires after TTL seconds if it is positive.
+func (m *MockBatchStatusClient) set(ID string, TTL int, data []byte) {
 	dataCopy := make([]byte, len(data))
 	copy(dataCopy, data)
 
 	m.status[ID] = dataCopy
+	if TTL > 0 {
+		m.expires[ID] = m.now().Add(time.Duration(TTL) * time.Second)
+	} else {
+		delete(m.expires, ID)
+	}
+}
 
-	// Note: In a real implementation, TTL would be used to expire the data.
-	// For this mock, we'll just store the data without expiration.
-
-	return nil
+// get returns the data of ID, or false if there is none or it expired.
+func (m *MockBatchStatusClient) get(ID string) ([]byte, bool) {
+	data, exists := m.status[ID]
+	if !exists {
+		return nil, false
+	}
+	if expiresAt, ok := m.expires[ID]; ok && !m.now().Before(expiresAt) {
+		return nil, false
+	}
+	return data, true
 }
 
 func (m *MockBatchStatusClient) Get(ctx context.Context, ID string) ([]byte, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	data, exists := m.status[ID]
+	data, exists := m.get(ID)
 	if !exists {
 		// If no data exists, return (nil, nil) as per the interface contract
 		return nil, nil
@@ -72,12 +104,38 @@ func (m *MockBatchStatusClient) Delete(ctx context.Context, ID string) error {
 	defer m.mu.Unlock()
 
 	delete(m.status, ID)
+	delete(m.expires, ID)
 
 	return nil
 }
 
+func (m *MockBatchStatusClient) CompareAndSet(ctx context.Context, ID string, TTL int, expected, data []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, exists := m.get(ID)
+	if exists != (expected != nil) || !bytes.Equal(current, expected) {
+		return false, nil
+	}
+	m.set(ID, TTL, data)
+	return true, nil
+}
+
+func (m *MockBatchStatusClient) CompareAndDelete(ctx context.Context, ID string, expected []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, exists := m.get(ID)
+	if !exists || !bytes.Equal(current, expected) {
+		return false, nil
+	}
+	delete(m.status, ID)
+	delete(m.expires, ID)
+	return true, nil
+}
+
 func (m *MockBatchStatusClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(parentCtx, timeLimit)
+	return store.CallContext(parentCtx, timeLimit)
 }
 
 func (m *MockBatchStatusClient) Close() error {
@@ -86,6 +144,7 @@ func (m *MockBatchStatusClient) Close() error {
 
 	// Clear the status map
 	m.status = make(map[string][]byte)
+	m.expires = make(map[string]time.Time)
 
 	return nil
 }
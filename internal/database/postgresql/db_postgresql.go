@@ -15,5 +15,465 @@ limitations under the License.
 */
 
 // This file implements batch database interfaces using postgresql.
+// The clients run on a database/sql pool opened with a postgresql driver, on a schema created by Migrate.
+// The expiration of the records is checked against the clock of the database, shared by all the replicas.
 
 package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
+)
+
+// Config is the configuration of the postgresql clients.
+type Config struct {
+	// QueryTimeout limits the duration of the calls of the contexts returned by GetContext without a time limit.
+	// Defaults to store.DefaultTimeLimit.
+	QueryTimeout time.Duration
+}
+
+// client holds the connection pool shared by the postgresql clients.
+type client struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+func newClient(db *sql.DB, cfg Config) client {
+	return client{db: db, queryTimeout: cfg.QueryTimeout}
+}
+
+// GetContext returns a context for a call, limited to timeLimit or to the query timeout of the client.
+// The queries run with the context are cancelled by the database when it is done.
+func (c *client) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	if timeLimit <= 0 {
+		timeLimit = c.queryTimeout
+	}
+	return store.CallContext(parentCtx, timeLimit)
+}
+
+// Close closes the connection pool, which is shared by the clients created on it.
+func (c *client) Close() error {
+	return c.db.Close()
+}
+
+// BatchDBClient is a postgresql api.BatchDBClient.
+type BatchDBClient struct {
+	client
+}
+
+func NewBatchDBClient(db *sql.DB, cfg Config) *BatchDBClient {
+	return &BatchDBClient{client: newClient(db, cfg)}
+}
+
+// jsonParam returns the JSON of value as a query parameter, or NULL if value is empty.
+func jsonParam[T any](value []T) (interface{}, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (c *BatchDBClient) Store(ctx context.Context, job *api.BatchJob) (string, error) {
+	if err := job.IsValid(); err != nil {
+		return "", err
+	}
+	tags, metadata, traceContext, err := jobParams(job)
+	if err != nil {
+		return "", err
+	}
+
+	// an expired record of the same ID is replaced
+	args := append([]interface{}{job.ID, job.SLO, job.TTL, tags, job.Spec, job.Status, metadata, traceContext},
+		statusColumnValues(job.Status)...)
+	result, err := c.db.ExecContext(ctx, `
+		INSERT INTO batch_jobs (id, slo, expires_at, tags, spec, status, metadata, trace_context, `+statusColumnList+`)
+		VALUES ($1, $2, now() + make_interval(secs => $3::int), $4::jsonb, $5, $6, $7::jsonb, $8::jsonb,
+			$9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		ON CONFLICT (id) DO UPDATE SET
+			slo = EXCLUDED.slo, expires_at = EXCLUDED.expires_at, tags = EXCLUDED.tags, spec = EXCLUDED.spec,
+			status = EXCLUDED.status, metadata = EXCLUDED.metadata, trace_context = EXCLUDED.trace_context,
+			state = EXCLUDED.state, request_total = EXCLUDED.request_total,
+			request_completed = EXCLUDED.request_completed, request_failed = EXCLUDED.request_failed,
+			input_tokens = EXCLUDED.input_tokens, output_tokens = EXCLUDED.output_tokens,
+			total_tokens = EXCLUDED.total_tokens, in_progress_at = EXCLUDED.in_progress_at,
+			finalizing_at = EXCLUDED.finalizing_at, completed_at = EXCLUDED.completed_at,
+			failed_at = EXCLUDED.failed_at, expired_at = EXCLUDED.expired_at, cancelled_at = EXCLUDED.cancelled_at,
+			created_at = now(), updated_at = now()
+		WHERE batch_jobs.expires_at <= now()`,
+		args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to store job with ID '%s': %w", job.ID, err)
+	}
+	if stored, err := result.RowsAffected(); err != nil {
+		return "", err
+	} else if stored == 0 {
+		return "", fmt.Errorf("job with ID '%s' already exists", job.ID)
+	}
+	return job.ID, nil
+}
+
+// jobParams returns the JSON columns of a job as query parameters.
+func jobParams(job *api.BatchJob) (tags, metadata, traceContext string, err error) {
+	data, err := json.Marshal(append([]string{}, job.Tags...))
+	if err != nil {
+		return "", "", "", err
+	}
+	tags = string(data)
+	if metadata, err = jsonObject(job.Metadata); err != nil {
+		return "", "", "", err
+	}
+	if traceContext, err = jsonObject(job.TraceContext); err != nil {
+		return "", "", "", err
+	}
+	return tags, metadata, traceContext, nil
+}
+
+// jsonObject returns the JSON object of the map, empty if the map is nil.
+func jsonObject(m map[string]string) (string, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+// statusColumnList lists the columns of batch_jobs extracted from the status, in the order of statusColumnValues.
+const statusColumnList = `state, request_total, request_completed, request_failed, input_tokens, output_tokens, total_tokens,
+	in_progress_at, finalizing_at, completed_at, failed_at, expired_at, cancelled_at`
+
+// statusColumnValues returns the values of the status columns as query parameters, extracted from the serialized
+// status of a job. The values are all NULL if the status is empty or isn't a batch status.
+func statusColumnValues(status []byte) []interface{} {
+	values := make([]interface{}, 13)
+	var info openai.BatchStatusInfo
+	if len(status) == 0 || json.Unmarshal(status, &info) != nil {
+		return values
+	}
+	values[0] = string(info.Status)
+	values[1] = info.RequestCounts.Total
+	values[2] = info.RequestCounts.Completed
+	values[3] = info.RequestCounts.Failed
+	if info.Usage != nil {
+		values[4] = info.Usage.InputTokens
+		values[5] = info.Usage.OutputTokens
+		values[6] = info.Usage.TotalTokens
+	}
+	for i, at := range []*int64{info.InProgressAt, info.FinalizingAt, info.CompletedAt, info.FailedAt, info.ExpiredAt, info.CancelledAt} {
+		if at != nil {
+			values[7+i] = time.Unix(*at, 0).UTC()
+		}
+	}
+	return values
+}
+
+// jobColumns returns the columns selected for the jobs, the spec being selected only if includeStatic is set.
+func jobColumns(includeStatic bool) string {
+	if includeStatic {
+		return "id, slo, tags, spec, status, metadata, trace_context"
+	}
+	return "id, slo, tags, NULL::bytea, status, metadata, trace_context"
+}
+
+func scanJobs(rows *sql.Rows) ([]*api.BatchJob, error) {
+	defer rows.Close()
+
+	var jobs []*api.BatchJob
+	for rows.Next() {
+		job := &api.BatchJob{}
+		var tags, metadata, traceContext []byte
+		if err := rows.Scan(&job.ID, &job.SLO, &tags, &job.Spec, &job.Status, &metadata, &traceContext); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(tags, &job.Tags); err != nil {
+			return nil, fmt.Errorf("invalid tags of job with ID '%s': %w", job.ID, err)
+		}
+		if err := json.Unmarshal(metadata, &job.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata of job with ID '%s': %w", job.ID, err)
+		}
+		if err := json.Unmarshal(traceContext, &job.TraceContext); err != nil {
+			return nil, fmt.Errorf("invalid trace context of job with ID '%s': %w", job.ID, err)
+		}
+		if len(job.Tags) == 0 {
+			job.Tags = nil
+		}
+		if len(job.Metadata) == 0 {
+			job.Metadata = nil
+		}
+		if len(job.TraceContext) == 0 {
+			job.TraceContext = nil
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// limitParam returns the limit of a query, NULL selecting all the rows.
+func limitParam(limit int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(limit), Valid: limit > 0}
+}
+
+func (c *BatchDBClient) Get(ctx context.Context, IDs []string, tags []string, tagsLogicalCond api.TagsLogicalCond,
+	includeStatic bool, start, limit int,
+) ([]*api.BatchJob, int, error) {
+	if len(IDs) > 0 {
+		ids, err := jsonParam(IDs)
+		if err != nil {
+			return nil, 0, err
+		}
+		rows, err := c.db.QueryContext(ctx, `
+			SELECT `+jobColumns(includeStatic)+` FROM batch_jobs
+			WHERE id IN (SELECT jsonb_array_elements_text($1::jsonb)) AND expires_at > now()
+			ORDER BY id`, ids)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get jobs by IDs: %w", err)
+		}
+		jobs, err := scanJobs(rows)
+		return jobs, 0, err
+	}
+	if len(tags) == 0 {
+		return nil, 0, nil
+	}
+
+	tagsParam, err := jsonParam(tags)
+	if err != nil {
+		return nil, 0, err
+	}
+	condition := "tags @> $1::jsonb"
+	if tagsLogicalCond == api.TagsLogicalCondOr {
+		condition = "tags ?| ARRAY(SELECT jsonb_array_elements_text($1::jsonb))"
+	}
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT `+jobColumns(includeStatic)+` FROM batch_jobs
+		WHERE `+condition+` AND expires_at > now()
+		ORDER BY id OFFSET $2 LIMIT $3`, tagsParam, start, limitParam(limit))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get jobs by tags: %w", err)
+	}
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return jobs, start + len(jobs), nil
+}
+
+func (c *BatchDBClient) GetByMetadata(ctx context.Context, metadata map[string]string, tags []string, includeStatic bool, start, limit int) ([]*api.BatchJob, int, error) {
+	if len(metadata) == 0 {
+		return nil, 0, nil
+	}
+	metadataParam, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, 0, err
+	}
+	tagsParam, err := jsonParam(tags)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the containment operator is served by the GIN index of the metadata
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT `+jobColumns(includeStatic)+` FROM batch_jobs
+		WHERE metadata @> $1::jsonb AND ($4::jsonb IS NULL OR tags @> $4::jsonb) AND expires_at > now()
+		ORDER BY id OFFSET $2 LIMIT $3`, string(metadataParam), start, limitParam(limit), tagsParam)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get jobs by metadata: %w", err)
+	}
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return jobs, start + len(jobs), nil
+}
+
+// finalStates are the states of the jobs whose status isn't updated anymore.
+var finalStates = []openai.BatchStatus{
+	openai.BatchStatusCompleted, openai.BatchStatusFailed, openai.BatchStatusExpired, openai.BatchStatusCancelled,
+}
+
+// Update updates the tags and the status of the job that are set in a single statement,
+// so concurrent updates of a job are applied one after the other. The status columns that are not set in the status
+// keep their values.
+// The state of the job is checked by the statement: the status of a job in a final state isn't updated, and
+// api.ErrFinalStatus is returned, so a transition out of a final state can't overwrite a concurrent one into it.
+func (c *BatchDBClient) Update(ctx context.Context, job *api.BatchJob) error {
+	tags, err := jsonParam(job.Tags)
+	if err != nil {
+		return err
+	}
+	var status interface{}
+	if len(job.Status) > 0 {
+		status = job.Status
+	}
+	states, err := jsonParam(finalStates)
+	if err != nil {
+		return err
+	}
+
+	args := append([]interface{}{job.ID, tags, status}, statusColumnValues(job.Status)...)
+	args = append(args, states)
+	result, err := c.db.ExecContext(ctx, `
+		UPDATE batch_jobs SET tags = COALESCE($2::jsonb, tags), status = COALESCE($3, status),
+			state = COALESCE($4, state), request_total = COALESCE($5, request_total),
+			request_completed = COALESCE($6, request_completed), request_failed = COALESCE($7, request_failed),
+			input_tokens = COALESCE($8, input_tokens), output_tokens = COALESCE($9, output_tokens),
+			total_tokens = COALESCE($10, total_tokens), in_progress_at = COALESCE($11, in_progress_at),
+			finalizing_at = COALESCE($12, finalizing_at), completed_at = COALESCE($13, completed_at),
+			failed_at = COALESCE($14, failed_at), expired_at = COALESCE($15, expired_at),
+			cancelled_at = COALESCE($16, cancelled_at), updated_at = now()
+		WHERE id = $1 AND expires_at > now() AND ($3::bytea IS NULL OR state IS NULL
+			OR state NOT IN (SELECT jsonb_array_elements_text($17::jsonb)))`,
+		args...)
+	if err != nil {
+		return fmt.Errorf("failed to update job with ID '%s': %w", job.ID, err)
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated > 0 {
+		return nil
+	}
+
+	// the job is either missing or final
+	var exists bool
+	if err := c.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM batch_jobs WHERE id = $1 AND expires_at > now())`,
+		job.ID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to update job with ID '%s': %w", job.ID, err)
+	}
+	if exists {
+		return fmt.Errorf("cannot update job with ID '%s': %w", job.ID, api.ErrFinalStatus)
+	}
+	return fmt.Errorf("cannot update job with ID '%s': job doesn't exist", job.ID)
+}
+
+func (c *BatchDBClient) Delete(ctx context.Context, IDs []string) ([]string, error) {
+	if len(IDs) == 0 {
+		return nil, nil
+	}
+	ids, err := jsonParam(IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.db.QueryContext(ctx, `
+		DELETE FROM batch_jobs WHERE id IN (SELECT jsonb_array_elements_text($1::jsonb))
+		RETURNING id, expires_at > now()`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete jobs: %w", err)
+	}
+	defer rows.Close()
+
+	// the expired records are removed too, but only the live jobs are reported as deleted
+	var deleted []string
+	for rows.Next() {
+		var ID string
+		var live bool
+		if err := rows.Scan(&ID, &live); err != nil {
+			return nil, err
+		}
+		if live {
+			deleted = append(deleted, ID)
+		}
+	}
+	return deleted, rows.Err()
+}
+
+// PurgeExpired removes the records of the expired jobs, and returns the number of removed records.
+func (c *BatchDBClient) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := c.db.ExecContext(ctx, "DELETE FROM batch_jobs WHERE expires_at <= now()")
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (c *BatchDBClient) DeadLetter(ctx context.Context, job *api.BatchJob) error {
+	tags, metadata, traceContext, err := jobParams(job)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.db.ExecContext(ctx, `
+		INSERT INTO batch_dead_letters (id, slo, tags, spec, status, metadata, trace_context)
+		VALUES ($1, $2, $3::jsonb, $4, $5, $6::jsonb, $7::jsonb)
+		ON CONFLICT (id) DO UPDATE SET
+			slo = EXCLUDED.slo, tags = EXCLUDED.tags, spec = EXCLUDED.spec, status = EXCLUDED.status,
+			metadata = EXCLUDED.metadata, trace_context = EXCLUDED.trace_context, dead_lettered_at = now()`,
+		job.ID, job.SLO, tags, job.Spec, job.Status, metadata, traceContext); err != nil {
+		return fmt.Errorf("failed to dead-letter job with ID '%s': %w", job.ID, err)
+	}
+	return nil
+}
+
+func (c *BatchDBClient) GetDeadLetters(ctx context.Context, IDs []string) ([]*api.BatchJob, error) {
+	if len(IDs) == 0 {
+		return nil, nil
+	}
+	ids, err := jsonParam(IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT id, slo, tags, spec, status, metadata, trace_context FROM batch_dead_letters
+		WHERE id IN (SELECT jsonb_array_elements_text($1::jsonb))
+		ORDER BY id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead-lettered jobs: %w", err)
+	}
+	return scanJobs(rows)
+}
+
+func (c *BatchDBClient) GetIdempotencyKey(ctx context.Context, key string) ([]byte, error) {
+	var record []byte
+	err := c.db.QueryRowContext(ctx, `SELECT record FROM idempotency_keys WHERE key = $1 AND expires_at > now()`, key).Scan(&record)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key '%s': %w", key, err)
+	}
+	return record, nil
+}
+
+func (c *BatchDBClient) CompareAndSetIdempotencyKey(ctx context.Context, key string, TTL int, expected, record []byte) (bool, error) {
+	var result sql.Result
+	var err error
+	if expected == nil {
+		// the record is inserted, or replaces a record that expired
+		result, err = c.db.ExecContext(ctx, `
+			INSERT INTO idempotency_keys (key, record, expires_at) VALUES ($1, $3, now() + make_interval(secs => $2::int))
+			ON CONFLICT (key) DO UPDATE SET record = EXCLUDED.record, expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= now()`,
+			key, TTL, nonNil(record))
+	} else {
+		result, err = c.db.ExecContext(ctx, `
+			UPDATE idempotency_keys SET record = $3, expires_at = now() + make_interval(secs => $2::int)
+			WHERE key = $1 AND record = $4 AND expires_at > now()`,
+			key, TTL, nonNil(record), expected)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to compare and set idempotency key '%s': %w", key, err)
+	}
+	set, err := result.RowsAffected()
+	return set > 0, err
+}
+
+func (c *BatchDBClient) CompareAndDeleteIdempotencyKey(ctx context.Context, key string, expected []byte) (bool, error) {
+	result, err := c.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND record = $2 AND expires_at > now()`,
+		key, nonNil(expected))
+	if err != nil {
+		return false, fmt.Errorf("failed to compare and delete idempotency key '%s': %w", key, err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
@@ -0,0 +1,400 @@
+//go:build integration
+// +build integration
+
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// Integration tests using a postgres server in a test container, which requires Docker
+//
+// Run tests with:
+//   make test-integration
+//   Or manually: go test -v -tags=integration ./internal/database/postgresql/...
+
+const testPostgresImage = "postgres:16-alpine"
+
+// startPostgres starts a postgres container and returns a migrated connection pool to it.
+func startPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+	ctx := context.Background()
+
+	container, err := tcpostgres.Run(ctx, testPostgresImage,
+		tcpostgres.WithDatabase("batch"),
+		tcpostgres.WithUsername("batch"),
+		tcpostgres.WithPassword("batch"),
+		tcpostgres.BasicWaitStrategies())
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err, "Could not start postgres")
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.PingContext(ctx))
+
+	require.NoError(t, Migrate(ctx, db))
+	// migrating again is a no-op
+	require.NoError(t, Migrate(ctx, db))
+	return db
+}
+
+// TestPostgresIntegration aggregates all integration test cases
+// Run with: go test -tags=integration -run TestPostgresIntegration ./internal/database/postgresql
+func TestPostgresIntegration(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION_TESTS") == "true" {
+		t.Skip("Integration tests skipped")
+	}
+
+	db := startPostgres(t)
+	t.Run("Jobs", func(t *testing.T) { testJobs(t, NewBatchDBClient(db, Config{})) })
+	t.Run("Status", func(t *testing.T) { testStatus(t, NewBatchStatusClient(db, Config{})) })
+	t.Run("Claims", func(t *testing.T) { testClaims(t, NewBatchStatusClient(db, Config{})) })
+	t.Run("QueryTimeout", func(t *testing.T) {
+		testQueryTimeout(t, NewBatchDBClient(db, Config{QueryTimeout: 100 * time.Millisecond}))
+	})
+}
+
+func testJob(ID string, tags ...string) *api.BatchJob {
+	return &api.BatchJob{ID: ID, SLO: time.Now(), TTL: 60, Tags: tags, Spec: []byte("spec-" + ID), Status: []byte("validating")}
+}
+
+func jobIDs(jobs []*api.BatchJob) []string {
+	IDs := make([]string, len(jobs))
+	for i, job := range jobs {
+		IDs[i] = job.ID
+	}
+	return IDs
+}
+
+func testJobs(t *testing.T, client *BatchDBClient) {
+	ctx := context.Background()
+
+	t.Run("store and get", func(t *testing.T) {
+		job := testJob("get-1")
+		job.TraceContext = map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+		_, err := client.Store(ctx, job)
+		require.NoError(t, err)
+		_, err = client.Store(ctx, testJob("get-1"))
+		assert.Error(t, err, "the ID of a stored job can't be reused")
+
+		jobs, _, err := client.Get(ctx, []string{"get-1", "missing"}, nil, api.TagsLogicalCondNa, true, 0, 0)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, []byte("spec-get-1"), jobs[0].Spec)
+		assert.Equal(t, []byte("validating"), jobs[0].Status)
+		assert.Equal(t, job.TraceContext, jobs[0].TraceContext)
+
+		jobs, _, err = client.Get(ctx, []string{"get-1"}, nil, api.TagsLogicalCondNa, false, 0, 0)
+		require.NoError(t, err)
+		assert.Nil(t, jobs[0].Spec)
+	})
+
+	t.Run("list by tags", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			tags := []string{"tenant:list"}
+			if i%2 == 0 {
+				tags = append(tags, "even")
+			}
+			_, err := client.Store(ctx, testJob(fmt.Sprintf("list-%d", i), tags...))
+			require.NoError(t, err)
+		}
+
+		jobs, _, err := client.Get(ctx, nil, []string{"tenant:list", "even"}, api.TagsLogicalCondAnd, false, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"list-0", "list-2", "list-4"}, jobIDs(jobs))
+
+		var listed []string
+		cursor := 0
+		for {
+			jobs, next, err := client.Get(ctx, nil, []string{"tenant:list"}, api.TagsLogicalCondOr, false, cursor, 2)
+			require.NoError(t, err)
+			if len(jobs) == 0 {
+				break
+			}
+			listed = append(listed, jobIDs(jobs)...)
+			cursor = next
+		}
+		assert.Equal(t, []string{"list-0", "list-1", "list-2", "list-3", "list-4"}, listed)
+	})
+
+	t.Run("get by metadata", func(t *testing.T) {
+		for i, metadata := range []map[string]string{{"team": "a", "env": "prod"}, {"team": "a"}, {"team": "b"}} {
+			job := testJob(fmt.Sprintf("metadata-%d", i))
+			job.Metadata = metadata
+			_, err := client.Store(ctx, job)
+			require.NoError(t, err)
+		}
+
+		jobs, _, err := client.GetByMetadata(ctx, map[string]string{"team": "a"}, nil, false, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"metadata-0", "metadata-1"}, jobIDs(jobs))
+		assert.Equal(t, map[string]string{"team": "a", "env": "prod"}, jobs[0].Metadata)
+	})
+
+	t.Run("update the status", func(t *testing.T) {
+		_, err := client.Store(ctx, testJob("update-1", "tenant:a"))
+		require.NoError(t, err)
+
+		require.NoError(t, client.Update(ctx, &api.BatchJob{ID: "update-1", Status: []byte("in_progress")}))
+		jobs, _, err := client.Get(ctx, []string{"update-1"}, nil, api.TagsLogicalCondNa, true, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("in_progress"), jobs[0].Status)
+		assert.Equal(t, []string{"tenant:a"}, jobs[0].Tags)
+
+		assert.Error(t, client.Update(ctx, &api.BatchJob{ID: "missing", Status: []byte("in_progress")}))
+	})
+
+	t.Run("status columns", func(t *testing.T) {
+		_, err := client.Store(ctx, testJob("columns-1"))
+		require.NoError(t, err)
+
+		completedAt := int64(1700000000)
+		status, err := json.Marshal(openai.BatchStatusInfo{
+			Status:        openai.BatchStatusCompleted,
+			CompletedAt:   &completedAt,
+			RequestCounts: openai.BatchRequestCounts{Total: 3, Completed: 2, Failed: 1},
+			Usage:         &openai.BatchUsage{InputTokens: 10, OutputTokens: 20, TotalTokens: 30},
+		})
+		require.NoError(t, err)
+		require.NoError(t, client.Update(ctx, &api.BatchJob{ID: "columns-1", Status: status}))
+
+		var state string
+		var total, completed, failed, totalTokens int64
+		var completedTime time.Time
+		require.NoError(t, client.db.QueryRowContext(ctx, `
+			SELECT state, request_total, request_completed, request_failed, total_tokens, completed_at
+			FROM batch_jobs WHERE id = $1`, "columns-1").
+			Scan(&state, &total, &completed, &failed, &totalTokens, &completedTime))
+		assert.Equal(t, "completed", state)
+		assert.Equal(t, []int64{3, 2, 1, 30}, []int64{total, completed, failed, totalTokens})
+		assert.Equal(t, completedAt, completedTime.Unix())
+
+		// an update of the tags only keeps the columns
+		require.NoError(t, client.Update(ctx, &api.BatchJob{ID: "columns-1", Tags: []string{"tenant:b"}}))
+		require.NoError(t, client.db.QueryRowContext(ctx, `SELECT state FROM batch_jobs WHERE id = $1`, "columns-1").Scan(&state))
+		assert.Equal(t, "completed", state)
+	})
+
+	t.Run("final status", func(t *testing.T) {
+		_, err := client.Store(ctx, testJob("final-1"))
+		require.NoError(t, err)
+		inProgress, err := json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusInProgress})
+		require.NoError(t, err)
+		require.NoError(t, client.Update(ctx, &api.BatchJob{ID: "final-1", Status: inProgress}))
+
+		// of concurrent transitions to final states, only one is applied
+		var updated sync.WaitGroup
+		var mu sync.Mutex
+		var applied []openai.BatchStatus
+		for _, state := range finalStates {
+			updated.Add(1)
+			go func() {
+				defer updated.Done()
+				status, err := json.Marshal(openai.BatchStatusInfo{Status: state})
+				assert.NoError(t, err)
+				err = client.Update(ctx, &api.BatchJob{ID: "final-1", Status: status})
+				if err == nil {
+					mu.Lock()
+					applied = append(applied, state)
+					mu.Unlock()
+					return
+				}
+				assert.ErrorIs(t, err, api.ErrFinalStatus)
+			}()
+		}
+		updated.Wait()
+		require.Len(t, applied, 1)
+
+		var state string
+		require.NoError(t, client.db.QueryRowContext(ctx, `SELECT state FROM batch_jobs WHERE id = $1`, "final-1").Scan(&state))
+		assert.Equal(t, string(applied[0]), state)
+		assert.ErrorIs(t, client.Update(ctx, &api.BatchJob{ID: "final-1", Status: inProgress}), api.ErrFinalStatus)
+		// the tags of a final job are still updated
+		require.NoError(t, client.Update(ctx, &api.BatchJob{ID: "final-1", Tags: []string{"tenant:a"}}))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := client.Store(ctx, testJob("delete-1"))
+		require.NoError(t, err)
+
+		deleted, err := client.Delete(ctx, []string{"delete-1", "missing"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"delete-1"}, deleted)
+	})
+
+	t.Run("expired jobs", func(t *testing.T) {
+		job := testJob("expire-1")
+		job.TTL = 1
+		_, err := client.Store(ctx, job)
+		require.NoError(t, err)
+		time.Sleep(1100 * time.Millisecond)
+
+		jobs, _, err := client.Get(ctx, []string{"expire-1"}, nil, api.TagsLogicalCondNa, false, 0, 0)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		// the ID of an expired job can be reused
+		_, err = client.Store(ctx, testJob("expire-1"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("dead letters", func(t *testing.T) {
+		require.NoError(t, client.DeadLetter(ctx, testJob("dead-1")))
+		jobs, err := client.GetDeadLetters(ctx, []string{"dead-1", "missing"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dead-1"}, jobIDs(jobs))
+	})
+
+	t.Run("idempotency keys", func(t *testing.T) {
+		// of concurrent reservations of a key, only one succeeds
+		var reserved sync.WaitGroup
+		var mu sync.Mutex
+		succeeded := 0
+		for i := 0; i < 10; i++ {
+			reserved.Add(1)
+			go func() {
+				defer reserved.Done()
+				set, err := client.CompareAndSetIdempotencyKey(ctx, "key-1", 60, nil, []byte(fmt.Sprintf("pending-%d", i)))
+				assert.NoError(t, err)
+				if set {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}()
+		}
+		reserved.Wait()
+		assert.Equal(t, 1, succeeded)
+
+		record, err := client.GetIdempotencyKey(ctx, "key-1")
+		require.NoError(t, err)
+		set, err := client.CompareAndSetIdempotencyKey(ctx, "key-1", 60, record, []byte("batch-1"))
+		require.NoError(t, err)
+		assert.True(t, set)
+		deleted, err := client.CompareAndDeleteIdempotencyKey(ctx, "key-1", record)
+		require.NoError(t, err)
+		assert.False(t, deleted)
+
+		// an expired key can be reserved again
+		_, err = client.CompareAndSetIdempotencyKey(ctx, "key-2", 1, nil, []byte("pending"))
+		require.NoError(t, err)
+		time.Sleep(1100 * time.Millisecond)
+		set, err = client.CompareAndSetIdempotencyKey(ctx, "key-2", 60, nil, []byte("pending"))
+		require.NoError(t, err)
+		assert.True(t, set)
+	})
+}
+
+func testStatus(t *testing.T, client *BatchStatusClient) {
+	ctx := context.Background()
+
+	data, err := client.Get(ctx, "status-1")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	require.NoError(t, client.Set(ctx, "status-1", 60, []byte("10")))
+	data, err = client.Get(ctx, "status-1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("10"), data)
+
+	require.NoError(t, client.Set(ctx, "status-2", 1, []byte("10")))
+	time.Sleep(1100 * time.Millisecond)
+	data, err = client.Get(ctx, "status-2")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	require.NoError(t, client.Delete(ctx, "status-1"))
+	data, err = client.Get(ctx, "status-1")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+// testClaims checks that a single one of the replicas claiming a job at once gets it.
+func testClaims(t *testing.T, client *BatchStatusClient) {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var owners []string
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			owner := fmt.Sprintf("replica-%d", i)
+			claimed, err := client.CompareAndSet(ctx, "claim-1", 60, nil, []byte(owner))
+			assert.NoError(t, err)
+			if claimed {
+				mu.Lock()
+				owners = append(owners, owner)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Len(t, owners, 1)
+
+	// only the owner renews and releases its claim
+	renewed, err := client.CompareAndSet(ctx, "claim-1", 60, []byte("other"), []byte("other"))
+	require.NoError(t, err)
+	assert.False(t, renewed)
+	renewed, err = client.CompareAndSet(ctx, "claim-1", 60, []byte(owners[0]), []byte(owners[0]))
+	require.NoError(t, err)
+	assert.True(t, renewed)
+
+	released, err := client.CompareAndDelete(ctx, "claim-1", []byte(owners[0]))
+	require.NoError(t, err)
+	assert.True(t, released)
+
+	// an expired claim can be taken over
+	claimed, err := client.CompareAndSet(ctx, "claim-2", 1, nil, []byte("replica-a"))
+	require.NoError(t, err)
+	require.True(t, claimed)
+	time.Sleep(1100 * time.Millisecond)
+	claimed, err = client.CompareAndSet(ctx, "claim-2", 60, nil, []byte("replica-b"))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func testQueryTimeout(t *testing.T, client *BatchDBClient) {
+	ctx, cancel := client.GetContext(context.Background(), 0)
+	defer cancel()
+
+	_, err := client.db.ExecContext(ctx, "SELECT pg_sleep(5)")
+	assert.Error(t, err)
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,34 @@
+-- The batch jobs, selected by their tags and metadata. The spec and status are serialized by the callers.
+CREATE TABLE batch_jobs (
+    id         TEXT PRIMARY KEY,
+    slo        TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    tags       JSONB NOT NULL DEFAULT '[]',
+    spec       BYTEA,
+    status     BYTEA,
+    metadata   JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX batch_jobs_tags_idx ON batch_jobs USING GIN (tags);
+CREATE INDEX batch_jobs_metadata_idx ON batch_jobs USING GIN (metadata jsonb_path_ops);
+CREATE INDEX batch_jobs_expires_at_idx ON batch_jobs (expires_at);
+
+-- The copies of the jobs that failed permanently, kept for inspection.
+CREATE TABLE batch_dead_letters (
+    id               TEXT PRIMARY KEY,
+    slo              TIMESTAMPTZ NOT NULL,
+    tags             JSONB NOT NULL DEFAULT '[]',
+    spec             BYTEA,
+    status           BYTEA,
+    metadata         JSONB NOT NULL DEFAULT '{}',
+    dead_lettered_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- The temporary status of the jobs, including the claims of the processors on the jobs they process.
+CREATE TABLE batch_status (
+    id         TEXT PRIMARY KEY,
+    data       BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ
+);
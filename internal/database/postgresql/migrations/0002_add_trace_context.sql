@@ -0,0 +1,3 @@
+-- The trace context of the request that created a job, continued by its processing.
+ALTER TABLE batch_jobs ADD COLUMN trace_context JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE batch_dead_letters ADD COLUMN trace_context JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,6 @@
+-- The records of the idempotency keys, such as the batch created for the Idempotency-Key of a request.
+CREATE TABLE idempotency_keys (
+    key        TEXT PRIMARY KEY,
+    record     BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,18 @@
+-- The state, request counts, usage and timestamps of the jobs, extracted from their serialized status when it is
+-- stored, so the jobs can be queried and reported on without decoding the status.
+ALTER TABLE batch_jobs
+    ADD COLUMN state             TEXT,
+    ADD COLUMN request_total     BIGINT,
+    ADD COLUMN request_completed BIGINT,
+    ADD COLUMN request_failed    BIGINT,
+    ADD COLUMN input_tokens      BIGINT,
+    ADD COLUMN output_tokens     BIGINT,
+    ADD COLUMN total_tokens      BIGINT,
+    ADD COLUMN in_progress_at    TIMESTAMPTZ,
+    ADD COLUMN finalizing_at     TIMESTAMPTZ,
+    ADD COLUMN completed_at      TIMESTAMPTZ,
+    ADD COLUMN failed_at         TIMESTAMPTZ,
+    ADD COLUMN expired_at        TIMESTAMPTZ,
+    ADD COLUMN cancelled_at      TIMESTAMPTZ;
+
+CREATE INDEX batch_jobs_state_idx ON batch_jobs (state);
@@ -0,0 +1,41 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the schema migrations of the postgresql database.
+
+package postgresql
+
+import (
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected migrations, got none")
+	}
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("Expected migration %s to have version %d, got %d", m.name, i+1, m.version)
+		}
+		if m.sql == "" {
+			t.Errorf("Expected migration %s to have SQL", m.name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file implements the batch jobs temporary status store interface using postgresql.
+// The claims of the processors on the jobs are stored here, so the compare operations are single statements
+// that the database applies atomically.
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// BatchStatusClient is a postgresql api.BatchStatusClient.
+type BatchStatusClient struct {
+	client
+}
+
+func NewBatchStatusClient(db *sql.DB, cfg Config) *BatchStatusClient {
+	return &BatchStatusClient{client: newClient(db, cfg)}
+}
+
+// expiresAt is the expression of the expiration time of the data of TTL $2, NULL if the data doesn't expire.
+const expiresAt = "CASE WHEN $2::int > 0 THEN now() + make_interval(secs => $2::int) END"
+
+// live is the condition of the data that didn't expire.
+const live = "(batch_status.expires_at IS NULL OR batch_status.expires_at > now())"
+
+func (c *BatchStatusClient) Set(ctx context.Context, ID string, TTL int, data []byte) error {
+	if _, err := c.db.ExecContext(ctx, `
+		INSERT INTO batch_status (id, data, expires_at) VALUES ($1, $3, `+expiresAt+`)
+		ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at`,
+		ID, TTL, nonNil(data)); err != nil {
+		return fmt.Errorf("failed to set the status of ID '%s': %w", ID, err)
+	}
+	return nil
+}
+
+func (c *BatchStatusClient) Get(ctx context.Context, ID string) ([]byte, error) {
+	var data []byte
+	err := c.db.QueryRowContext(ctx, `SELECT data FROM batch_status WHERE id = $1 AND `+live, ID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the status of ID '%s': %w", ID, err)
+	}
+	return data, nil
+}
+
+func (c *BatchStatusClient) Delete(ctx context.Context, ID string) error {
+	if _, err := c.db.ExecContext(ctx, "DELETE FROM batch_status WHERE id = $1", ID); err != nil {
+		return fmt.Errorf("failed to delete the status of ID '%s': %w", ID, err)
+	}
+	return nil
+}
+
+func (c *BatchStatusClient) CompareAndSet(ctx context.Context, ID string, TTL int, expected, data []byte) (bool, error) {
+	var result sql.Result
+	var err error
+	if expected == nil {
+		// the data is inserted, or replaces data that expired
+		result, err = c.db.ExecContext(ctx, `
+			INSERT INTO batch_status (id, data, expires_at) VALUES ($1, $3, `+expiresAt+`)
+			ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at
+			WHERE NOT `+live,
+			ID, TTL, nonNil(data))
+	} else {
+		result, err = c.db.ExecContext(ctx, `
+			UPDATE batch_status SET data = $3, expires_at = `+expiresAt+`
+			WHERE id = $1 AND data = $4 AND `+live,
+			ID, TTL, nonNil(data), expected)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to compare and set the status of ID '%s': %w", ID, err)
+	}
+	set, err := result.RowsAffected()
+	return set > 0, err
+}
+
+func (c *BatchStatusClient) CompareAndDelete(ctx context.Context, ID string, expected []byte) (bool, error) {
+	result, err := c.db.ExecContext(ctx, `DELETE FROM batch_status WHERE id = $1 AND data = $2 AND `+live, ID, nonNil(expected))
+	if err != nil {
+		return false, fmt.Errorf("failed to compare and delete the status of ID '%s': %w", ID, err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// nonNil returns data, or an empty slice if it is nil, as the data column is not nullable.
+func nonNil(data []byte) []byte {
+	if data == nil {
+		return []byte{}
+	}
+	return data
+}
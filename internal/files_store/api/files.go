@@ -20,12 +20,16 @@ package api
 
 import (
 	"context"
+	"errors"
 	"io"
 	"time"
 
 	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
 )
 
+// ErrFileNotFound is returned, possibly wrapped, when the file at a location does not exist.
+var ErrFileNotFound = errors.New("file not found")
+
 type BatchFileMetadata struct {
 	Location string    // Absolute location of the file.
 	Size     int64     // The size of the file in bytes.
@@ -40,11 +44,13 @@ type BatchFilesClient interface {
 		fileMd *BatchFileMetadata, err error)
 
 	// Retrieve retrieves a file from the files storage.
+	// An error wrapping ErrFileNotFound is returned if the file does not exist.
 	Retrieve(ctx context.Context, location string) (reader io.Reader, fileMd *BatchFileMetadata, err error)
 
 	// List lists the files in the specified location. Location here is a pattern.
 	List(ctx context.Context, location string) (files []BatchFileMetadata, err error)
 
 	// Delete deletes the file in the specified location.
+	// An error wrapping ErrFileNotFound is returned if the file does not exist.
 	Delete(ctx context.Context, location string) (err error)
 }
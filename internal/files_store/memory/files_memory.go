@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file implements the batch files storage interface in memory.
+// The files are kept in the process, for local development and tests of a single process:
+// they are lost when the process exits and are not shared with other processes.
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
+)
+
+type file struct {
+	data    []byte
+	modTime time.Time
+}
+
+// BatchFilesClient is an in-memory api.BatchFilesClient.
+type BatchFilesClient struct {
+	mu    sync.RWMutex
+	files map[string]*file
+
+	now func() time.Time
+}
+
+func NewBatchFilesClient() *BatchFilesClient {
+	return &BatchFilesClient{
+		files: make(map[string]*file),
+		now:   time.Now,
+	}
+}
+
+func (c *BatchFilesClient) Store(ctx context.Context, location string, fileSizeLimit int64, reader io.Reader) (*api.BatchFileMetadata, error) {
+	if fileSizeLimit > 0 {
+		reader = io.LimitReader(reader, fileSizeLimit+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if fileSizeLimit > 0 && int64(len(data)) > fileSizeLimit {
+		return nil, fmt.Errorf("file size exceeds the limit of %d bytes", fileSizeLimit)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f := &file{data: data, modTime: c.now()}
+	c.files[location] = f
+	return metadata(location, f), nil
+}
+
+func (c *BatchFilesClient) Retrieve(ctx context.Context, location string) (io.Reader, *api.BatchFileMetadata, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	f, ok := c.files[location]
+	if !ok {
+		return nil, nil, fmt.Errorf("file %s: %w", location, api.ErrFileNotFound)
+	}
+	// the stored data is replaced, never modified, so it is read without a copy
+	return bytes.NewReader(f.data), metadata(location, f), nil
+}
+
+func (c *BatchFilesClient) List(ctx context.Context, location string) ([]api.BatchFileMetadata, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	files := []api.BatchFileMetadata{}
+	for loc, f := range c.files {
+		matched, err := path.Match(location, loc)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			files = append(files, *metadata(loc, f))
+		}
+	}
+	slices.SortFunc(files, func(a, b api.BatchFileMetadata) int { return strings.Compare(a.Location, b.Location) })
+	return files, nil
+}
+
+func (c *BatchFilesClient) Delete(ctx context.Context, location string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.files[location]; !ok {
+		return fmt.Errorf("file %s: %w", location, api.ErrFileNotFound)
+	}
+	delete(c.files, location)
+	return nil
+}
+
+func (c *BatchFilesClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	return store.CallContext(parentCtx, timeLimit)
+}
+
+func (c *BatchFilesClient) Close() error {
+	return nil
+}
+
+func metadata(location string, f *file) *api.BatchFileMetadata {
+	return &api.BatchFileMetadata{Location: location, Size: int64(len(f.data)), ModTime: f.modTime}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the in-memory BatchFilesClient.
+
+package memory
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+)
+
+var _ api.BatchFilesClient = (*BatchFilesClient)(nil)
+
+func TestBatchFilesClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("store and retrieve", func(t *testing.T) {
+		client := NewBatchFilesClient()
+		md, err := client.Store(ctx, "tenant/file-1", 0, strings.NewReader("content"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), md.Size)
+
+		reader, md, err := client.Retrieve(ctx, "tenant/file-1")
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+		assert.Equal(t, "tenant/file-1", md.Location)
+
+		_, _, err = client.Retrieve(ctx, "tenant/missing")
+		assert.ErrorIs(t, err, api.ErrFileNotFound)
+	})
+
+	t.Run("store rejects files over the limit", func(t *testing.T) {
+		client := NewBatchFilesClient()
+		_, err := client.Store(ctx, "tenant/file-1", 3, strings.NewReader("content"))
+		assert.Error(t, err)
+		_, _, err = client.Retrieve(ctx, "tenant/file-1")
+		assert.ErrorIs(t, err, api.ErrFileNotFound)
+	})
+
+	t.Run("list and delete", func(t *testing.T) {
+		client := NewBatchFilesClient()
+		for _, location := range []string{"a/file-2", "a/file-1", "b/file-3"} {
+			_, err := client.Store(ctx, location, 0, strings.NewReader(location))
+			require.NoError(t, err)
+		}
+
+		files, err := client.List(ctx, "a/*")
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		assert.Equal(t, "a/file-1", files[0].Location)
+		assert.Equal(t, "a/file-2", files[1].Location)
+
+		require.NoError(t, client.Delete(ctx, "a/file-1"))
+		assert.ErrorIs(t, client.Delete(ctx, "a/file-1"), api.ErrFileNotFound)
+		files, err = client.List(ctx, "a/*")
+		require.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+}
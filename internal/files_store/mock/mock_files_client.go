@@ -0,0 +1,136 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides in-memory mock implementations for BatchFilesClient.
+package mock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/store"
+)
+
+type mockFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+type MockBatchFilesClient struct {
+	mu    sync.RWMutex
+	files map[string]*mockFile // Map of location to file contents
+}
+
+func NewMockBatchFilesClient() *MockBatchFilesClient {
+	return &MockBatchFilesClient{
+		files: make(map[string]*mockFile),
+	}
+}
+
+func (m *MockBatchFilesClient) Store(ctx context.Context, location string, fileSizeLimit int64, reader io.Reader) (*api.BatchFileMetadata, error) {
+	if fileSizeLimit > 0 {
+		reader = io.LimitReader(reader, fileSizeLimit+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if fileSizeLimit > 0 && int64(len(data)) > fileSizeLimit {
+		return nil, fmt.Errorf("file size exceeds the limit of %d bytes", fileSizeLimit)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f := &mockFile{data: data, modTime: time.Now()}
+	m.files[location] = f
+
+	return &api.BatchFileMetadata{
+		Location: location,
+		Size:     int64(len(data)),
+		ModTime:  f.modTime,
+	}, nil
+}
+
+func (m *MockBatchFilesClient) Retrieve(ctx context.Context, location string) (io.Reader, *api.BatchFileMetadata, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.files[location]
+	if !ok {
+		return nil, nil, fmt.Errorf("file %s: %w", location, api.ErrFileNotFound)
+	}
+
+	return bytes.NewReader(f.data), &api.BatchFileMetadata{
+		Location: location,
+		Size:     int64(len(f.data)),
+		ModTime:  f.modTime,
+	}, nil
+}
+
+func (m *MockBatchFilesClient) List(ctx context.Context, location string) ([]api.BatchFileMetadata, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	files := []api.BatchFileMetadata{}
+	for loc, f := range m.files {
+		matched, err := path.Match(location, loc)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			files = append(files, api.BatchFileMetadata{
+				Location: loc,
+				Size:     int64(len(f.data)),
+				ModTime:  f.modTime,
+			})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Location < files[j].Location })
+
+	return files, nil
+}
+
+func (m *MockBatchFilesClient) Delete(ctx context.Context, location string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[location]; !ok {
+		return fmt.Errorf("file %s: %w", location, api.ErrFileNotFound)
+	}
+	delete(m.files, location)
+
+	return nil
+}
+
+func (m *MockBatchFilesClient) GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	return store.CallContext(parentCtx, timeLimit)
+}
+
+func (m *MockBatchFilesClient) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files = make(map[string]*mockFile)
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains a circuit breaker around an inference client.
+// When the inference gateway keeps failing, the breaker opens and fails the requests right away for a cooldown,
+// instead of retrying every line against a backend that is down. It then lets one request through to probe
+// whether the backend recovered, and closes again if it succeeds.
+
+package inference
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the RawError of the requests failed right away by an open circuit breaker, without being sent
+// to the inference gateway. They are server errors, like the errors of the gateway that opened the breaker.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // requests are sent
+	BreakerOpen                         // requests fail right away until the cooldown ends
+	BreakerHalfOpen                     // one request is sent to probe the backend
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerConfig holds the configuration of a circuit breaker
+type BreakerConfig struct {
+	FailureThreshold int           // consecutive server errors opening the breaker (default: 5)
+	Cooldown         time.Duration // time the breaker stays open before probing the backend (default: 30s)
+
+	// OnStateChange is called with the new state each time the breaker changes state.
+	// It is called with the breaker locked, so it must not call the breaker.
+	OnStateChange func(state BreakerState)
+}
+
+// CircuitBreaker is a Client failing requests with ErrCircuitOpen server errors while the inference gateway is down.
+// Only server errors, which include timeouts, count as failures: any other answer of the gateway shows it is up.
+type CircuitBreaker struct {
+	client Client
+	config BreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int       // consecutive failures while closed
+	openedAt time.Time // time the breaker last opened
+	probing  bool      // whether the probe request of the half-open breaker is in flight
+}
+
+// NewCircuitBreaker returns a circuit breaker around client. Streaming requests go through the breaker too
+// if client is a StreamingClient.
+func NewCircuitBreaker(client Client, config BreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	return &CircuitBreaker{client: client, config: config, now: time.Now}
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Generate sends the request unless the breaker is open
+func (b *CircuitBreaker) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, *ClientError) {
+	probe, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Generate(ctx, req)
+	b.record(probe, err)
+	return resp, err
+}
+
+// GenerateStream streams the request unless the breaker is open.
+// If the wrapped client can't stream, the request is answered without streaming.
+func (b *CircuitBreaker) GenerateStream(ctx context.Context, req *GenerateRequest, onChunk func(chunk []byte) error) (*GenerateResponse, *ClientError) {
+	streamer, ok := b.client.(StreamingClient)
+	if !ok {
+		return b.Generate(ctx, req)
+	}
+	probe, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := streamer.GenerateStream(ctx, req, onChunk)
+	b.record(probe, err)
+	return resp, err
+}
+
+// allow returns the error of a request short-circuited by the breaker, or nil if the request can be sent.
+// Once the cooldown ends, the first request is the probe of the half-open breaker.
+func (b *CircuitBreaker) allow() (probe bool, err *ClientError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.Cooldown {
+		b.setState(BreakerHalfOpen)
+	}
+	switch {
+	case b.state == BreakerClosed:
+		return false, nil
+	case b.state == BreakerHalfOpen && !b.probing:
+		b.probing = true
+		return true, nil
+	default:
+		return false, &ClientError{
+			Category: ErrCategoryServer,
+			Message:  "circuit breaker open: the inference gateway is failing",
+			RawError: ErrCircuitOpen,
+		}
+	}
+}
+
+// record updates the breaker with the result of a request it let through.
+// The results of requests sent before the breaker opened don't change its state once it is open.
+func (b *CircuitBreaker) record(probe bool, err *ClientError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	switch {
+	case err != nil && err.Category == ErrCategoryServer:
+		b.failures++
+		if probe || (b.state == BreakerClosed && b.failures >= b.config.FailureThreshold) {
+			b.openedAt = b.now()
+			b.setState(BreakerOpen)
+		}
+	case err != nil && err.Category == ErrCategoryUnknown:
+		// e.g. a cancelled request, which tells nothing about the backend; a probe is sent again
+	default:
+		b.failures = 0
+		if probe {
+			b.setState(BreakerClosed)
+		}
+	}
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if state != BreakerClosed {
+		b.failures = 0
+	}
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(state)
+	}
+}
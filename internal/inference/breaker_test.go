@@ -0,0 +1,190 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the circuit breaker around the inference client.
+
+package inference
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedClient answers the requests with its next error, and succeeds once the errors are used up
+type scriptedClient struct {
+	errs  []*ClientError
+	calls int
+}
+
+func (c *scriptedClient) Generate(_ context.Context, req *GenerateRequest) (*GenerateResponse, *ClientError) {
+	c.calls++
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &GenerateResponse{RequestID: req.RequestID}, nil
+}
+
+func serverError() *ClientError {
+	return &ClientError{Category: ErrCategoryServer, Message: "service unavailable"}
+}
+
+// newTestBreaker returns a breaker with a threshold of 3 and a cooldown of a minute, on a clock set by the test
+func newTestBreaker(client Client) (*CircuitBreaker, *time.Time, *[]BreakerState) {
+	var states []BreakerState
+	breaker := NewCircuitBreaker(client, BreakerConfig{
+		FailureThreshold: 3,
+		Cooldown:         time.Minute,
+		OnStateChange:    func(state BreakerState) { states = append(states, state) },
+	})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	return breaker, &now, &states
+}
+
+func generate(b *CircuitBreaker) *ClientError {
+	_, err := b.Generate(context.Background(), &GenerateRequest{RequestID: "test", Endpoint: "/v1/chat/completions"})
+	return err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("trips after consecutive server errors", func(t *testing.T) {
+		client := &scriptedClient{errs: []*ClientError{serverError(), serverError(), serverError()}}
+		breaker, _, states := newTestBreaker(client)
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, BreakerClosed, breaker.State())
+			require.NotNil(t, generate(breaker))
+		}
+		assert.Equal(t, BreakerOpen, breaker.State())
+		assert.Equal(t, []BreakerState{BreakerOpen}, *states)
+
+		// the open breaker fails the requests without sending them
+		err := generate(breaker)
+		require.NotNil(t, err)
+		assert.Equal(t, ErrCategoryServer, err.Category)
+		assert.ErrorIs(t, err.RawError, ErrCircuitOpen)
+		assert.Contains(t, err.Message, "circuit breaker open")
+		assert.Equal(t, 3, client.calls)
+	})
+
+	t.Run("other answers reset the failures", func(t *testing.T) {
+		client := &scriptedClient{errs: []*ClientError{
+			serverError(), serverError(),
+			{Category: ErrCategoryInvalidReq, Message: "bad request"},
+			serverError(), serverError(),
+			nil,
+			serverError(), serverError(),
+		}}
+		breaker, _, states := newTestBreaker(client)
+
+		for i := 0; i < 8; i++ {
+			generate(breaker)
+		}
+		assert.Equal(t, BreakerClosed, breaker.State())
+		assert.Empty(t, *states)
+		assert.Equal(t, 8, client.calls)
+	})
+
+	t.Run("cancelled requests don't count", func(t *testing.T) {
+		cancelled := &ClientError{Category: ErrCategoryUnknown, Message: "request cancelled"}
+		client := &scriptedClient{errs: []*ClientError{serverError(), serverError(), cancelled, serverError()}}
+		breaker, _, _ := newTestBreaker(client)
+
+		for i := 0; i < 4; i++ {
+			generate(breaker)
+		}
+		assert.Equal(t, BreakerOpen, breaker.State())
+	})
+
+	t.Run("half-opens after the cooldown", func(t *testing.T) {
+		client := &scriptedClient{errs: []*ClientError{serverError(), serverError(), serverError()}}
+		breaker, now, _ := newTestBreaker(client)
+		for i := 0; i < 3; i++ {
+			generate(breaker)
+		}
+
+		*now = now.Add(time.Minute - time.Second)
+		require.NotNil(t, generate(breaker))
+		assert.Equal(t, 3, client.calls)
+
+		*now = now.Add(time.Second)
+		probe, probeErr := breaker.allow()
+		require.Nil(t, probeErr)
+		assert.True(t, probe)
+		assert.Equal(t, BreakerHalfOpen, breaker.State())
+
+		// only one probe is sent at a time
+		_, err := breaker.allow()
+		require.NotNil(t, err)
+		assert.ErrorIs(t, err.RawError, ErrCircuitOpen)
+	})
+
+	t.Run("recovers when the probe succeeds", func(t *testing.T) {
+		client := &scriptedClient{errs: []*ClientError{serverError(), serverError(), serverError()}}
+		breaker, now, states := newTestBreaker(client)
+		for i := 0; i < 3; i++ {
+			generate(breaker)
+		}
+
+		*now = now.Add(time.Minute)
+		assert.Nil(t, generate(breaker))
+		assert.Equal(t, BreakerClosed, breaker.State())
+		assert.Equal(t, []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed}, *states)
+
+		assert.Nil(t, generate(breaker))
+		assert.Equal(t, 5, client.calls)
+	})
+
+	t.Run("reopens when the probe fails", func(t *testing.T) {
+		client := &scriptedClient{errs: []*ClientError{serverError(), serverError(), serverError(), serverError()}}
+		breaker, now, states := newTestBreaker(client)
+		for i := 0; i < 3; i++ {
+			generate(breaker)
+		}
+
+		*now = now.Add(time.Minute)
+		require.NotNil(t, generate(breaker))
+		assert.Equal(t, BreakerOpen, breaker.State())
+		assert.Equal(t, []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen}, *states)
+
+		// a new cooldown starts from the failed probe
+		*now = now.Add(time.Minute - time.Second)
+		require.NotNil(t, generate(breaker))
+		assert.Equal(t, 4, client.calls)
+
+		*now = now.Add(time.Second)
+		assert.Nil(t, generate(breaker))
+		assert.Equal(t, BreakerClosed, breaker.State())
+	})
+
+	t.Run("falls back to generate without a streaming client", func(t *testing.T) {
+		client := &scriptedClient{}
+		breaker, _, _ := newTestBreaker(client)
+
+		resp, err := breaker.GenerateStream(context.Background(), &GenerateRequest{RequestID: "test"}, func([]byte) error { return nil })
+		require.Nil(t, err)
+		assert.Equal(t, "test", resp.RequestID)
+		assert.Equal(t, 1, client.calls)
+	})
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inference
+
+import (
+	"net/http"
+)
+
+// ErrorClassifier maps a failed inference request to an ErrorCategory.
+// The HTTP client consults it to decide whether to retry a request and to categorize the returned error.
+type ErrorClassifier interface {
+	// Classify returns the category of a failed request.
+	// For a request that got a response, statusCode and body describe the response and err is nil.
+	// For a request that failed without a response (e.g., a network error), statusCode is 0 and err is set.
+	Classify(statusCode int, body []byte, err error) ErrorCategory
+}
+
+// ErrorClassifierFunc is an adapter to use an ordinary function as an ErrorClassifier.
+type ErrorClassifierFunc func(statusCode int, body []byte, err error) ErrorCategory
+
+func (f ErrorClassifierFunc) Classify(statusCode int, body []byte, err error) ErrorCategory {
+	return f(statusCode, body, err)
+}
+
+// DefaultErrorClassifier categorizes errors by HTTP status code.
+// Requests that failed without a response are categorized as server errors.
+type DefaultErrorClassifier struct{}
+
+func (DefaultErrorClassifier) Classify(statusCode int, body []byte, err error) ErrorCategory {
+	if err != nil {
+		return ErrCategoryServer
+	}
+
+	switch statusCode {
+	case http.StatusBadRequest: // 400
+		return ErrCategoryInvalidReq
+	case http.StatusUnauthorized, http.StatusForbidden: // 401, 403
+		return ErrCategoryAuth
+	case http.StatusTooManyRequests: // 429
+		return ErrCategoryRateLimit
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout: // 500, 502, 503, 504
+		return ErrCategoryServer
+	default:
+		if statusCode >= http.StatusInternalServerError {
+			return ErrCategoryServer
+		}
+		return ErrCategoryUnknown
+	}
+}
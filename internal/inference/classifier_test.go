@@ -0,0 +1,93 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inference
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorClassifier(t *testing.T) {
+	t.Run("default classifier maps HTTP status codes", func(t *testing.T) {
+		tests := []struct {
+			statusCode int
+			err        error
+			expected   ErrorCategory
+		}{
+			{statusCode: http.StatusBadRequest, expected: ErrCategoryInvalidReq},
+			{statusCode: http.StatusUnauthorized, expected: ErrCategoryAuth},
+			{statusCode: http.StatusForbidden, expected: ErrCategoryAuth},
+			{statusCode: http.StatusTooManyRequests, expected: ErrCategoryRateLimit},
+			{statusCode: http.StatusInternalServerError, expected: ErrCategoryServer},
+			{statusCode: 599, expected: ErrCategoryServer},
+			{statusCode: http.StatusNotFound, expected: ErrCategoryUnknown},
+			{statusCode: 0, err: errors.New("connection reset"), expected: ErrCategoryServer},
+		}
+
+		for _, tt := range tests {
+			assert.Equal(t, tt.expected, DefaultErrorClassifier{}.Classify(tt.statusCode, nil, tt.err),
+				"status=%d err=%v", tt.statusCode, tt.err)
+		}
+	})
+
+	t.Run("custom classifier overrides the default", func(t *testing.T) {
+		// the backend reports overload with a 400 and a custom error code
+		attemptCount := 0
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attemptCount++
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"type":"backend_overloaded","message":"try again later"}}`))
+		}))
+		t.Cleanup(testServer.Close)
+
+		classifier := ErrorClassifierFunc(func(statusCode int, body []byte, err error) ErrorCategory {
+			if bytes.Contains(body, []byte("backend_overloaded")) {
+				return ErrCategoryRateLimit
+			}
+			return DefaultErrorClassifier{}.Classify(statusCode, body, err)
+		})
+
+		client, err := NewHTTPClient(HTTPClientConfig{
+			BaseURL:         testServer.URL,
+			MaxRetries:      2,
+			InitialBackoff:  10 * time.Millisecond,
+			ErrorClassifier: classifier,
+		})
+		require.NoError(t, err)
+
+		req := &GenerateRequest{
+			RequestID: "test",
+			Endpoint:  "/v1/chat/completions",
+			Params:    map[string]interface{}{"model": "gpt-4"},
+		}
+
+		resp, genErr := client.Generate(context.Background(), req)
+		assert.Nil(t, resp)
+		require.NotNil(t, genErr)
+		assert.Equal(t, ErrCategoryRateLimit, genErr.Category)
+		assert.True(t, genErr.IsRetryable())
+		assert.Equal(t, 3, attemptCount) // Initial + 2 retries, a 400 is not retried by default
+	})
+}
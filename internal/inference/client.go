@@ -17,26 +17,51 @@ limitations under the License.
 package inference
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"k8s.io/klog/v2"
 )
 
 // HTTPClient implements InferenceClient interface for HTTP-based inference gateways
 // Supports both llm-d (OpenAI-compatible) and GAIE endpoints
 type HTTPClient struct {
-	client *resty.Client
+	client     *resty.Client
+	classifier ErrorClassifier
+	buffers    *sync.Pool // response buffers, nil when ReuseResponseBuffers is disabled
 }
 
+// HTTPProtocol is the HTTP protocol used to send requests to the inference gateway
+type HTTPProtocol string
+
+const (
+	// HTTPProtocolHTTP1 uses HTTP/1.1 with a pool of connections
+	HTTPProtocolHTTP1 HTTPProtocol = "http1"
+	// HTTPProtocolHTTP2 negotiates HTTP/2 with ALPN over TLS, multiplexing requests over fewer connections.
+	// It falls back to HTTP/1.1 when the gateway doesn't support HTTP/2 or TLS isn't used.
+	HTTPProtocolHTTP2 HTTPProtocol = "http2"
+	// HTTPProtocolH2C uses HTTP/2 with prior knowledge over cleartext connections (h2c)
+	HTTPProtocolH2C HTTPProtocol = "h2c"
+)
+
+// maxPooledBufferSize is the capacity above which a response buffer is dropped instead of returned to the pool,
+// so a few very large responses don't keep their memory allocated
+const maxPooledBufferSize = 1 << 20
+
 // HTTPClientConfig holds configuration for the HTTP client
 type HTTPClientConfig struct {
 	BaseURL         string        // Base URL of the inference gateway (e.g., "http://localhost:8000")
@@ -44,6 +69,18 @@ type HTTPClientConfig struct {
 	MaxIdleConns    int           // Maximum idle connections (default: 100)
 	IdleConnTimeout time.Duration // Idle connection timeout (default: 90 seconds)
 	APIKey          string        // Optional API key for authentication
+	Protocol        HTTPProtocol  // HTTP protocol: http1, http2 or h2c (default: http1)
+
+	// Connection pool configuration (optional)
+	// The connections to the gateway are pooled and reused by all the requests of the client
+	MaxIdleConnsPerHost   int           // Maximum idle connections to the gateway (default: MaxIdleConns)
+	DialTimeout           time.Duration // Timeout of opening a connection (default: 30 seconds)
+	KeepAlive             time.Duration // TCP keep-alive period of the connections (default: 30 seconds, negative disables)
+	ResponseHeaderTimeout time.Duration // Timeout of waiting for the response headers (default: 30 seconds)
+
+	// OnConnection is called each time a request attempt gets a connection, telling whether
+	// an idle connection of the pool was reused or a new connection was opened (optional)
+	OnConnection func(reused bool)
 
 	// TLS configuration (optional)
 	TLSInsecureSkipVerify bool   // Skip TLS certificate verification (default: false - INSECURE, only for testing)
@@ -58,6 +95,28 @@ type HTTPClientConfig struct {
 	MaxRetries     int           // Maximum number of retry attempts (default: 0 = disabled)
 	InitialBackoff time.Duration // Initial/minimum retry wait time (default: 1 second)
 	MaxBackoff     time.Duration // Maximum retry wait time (default: 60 seconds)
+
+	// OnRetry is called each time a failed request is retried, with the model of the request
+	// and the category of the error that caused the retry (optional)
+	OnRetry func(model string, category ErrorCategory)
+
+	// RetryBudget caps the rate of the retries of all the requests of the client (optional, default: not capped)
+	// A retryable request failing once the budget is spent fails right away instead of being retried
+	RetryBudget *RetryBudget
+
+	// OnRetryBudgetExhausted is called each time a failed request is not retried because the retry budget
+	// is spent, with the model of the request (optional)
+	OnRetryBudgetExhausted func(model string)
+
+	// ErrorClassifier maps failed requests to error categories, which decide if a request is retried
+	// (optional, default: DefaultErrorClassifier based on the HTTP status code)
+	ErrorClassifier ErrorClassifier
+
+	// ReuseResponseBuffers reads response bodies into buffers from a shared pool instead of allocating them,
+	// which reduces GC pressure on large batches. Responses must then be released with GenerateResponse.Release.
+	// Retry conditions don't see the body of failed attempts, so the ErrorClassifier gets a nil body for them.
+	// (optional, default: false)
+	ReuseResponseBuffers bool
 }
 
 // NewHTTPClient creates a new HTTP-based inference client
@@ -72,6 +131,30 @@ func NewHTTPClient(config HTTPClientConfig) (*HTTPClient, error) {
 	if config.IdleConnTimeout == 0 {
 		config.IdleConnTimeout = 90 * time.Second
 	}
+	if config.MaxIdleConnsPerHost == 0 {
+		config.MaxIdleConnsPerHost = config.MaxIdleConns
+	}
+	if config.DialTimeout == 0 {
+		config.DialTimeout = 30 * time.Second
+	}
+	if config.KeepAlive == 0 {
+		config.KeepAlive = 30 * time.Second
+	}
+	if config.ResponseHeaderTimeout == 0 {
+		config.ResponseHeaderTimeout = 30 * time.Second
+	}
+
+	if config.Protocol == "" {
+		config.Protocol = HTTPProtocolHTTP1
+	}
+	protocols, err := transportProtocols(config.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.ErrorClassifier == nil {
+		config.ErrorClassifier = DefaultErrorClassifier{}
+	}
 
 	// Set defaults for retry configuration
 	if config.MaxRetries > 0 {
@@ -100,9 +183,14 @@ func NewHTTPClient(config HTTPClientConfig) (*HTTPClient, error) {
 
 	// Override only the settings we need to customize for batch processing
 	transport.MaxIdleConns = config.MaxIdleConns
-	transport.MaxIdleConnsPerHost = config.MaxIdleConns // Higher than default (17) for batch workloads
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost // Higher than default (2) for batch workloads
 	transport.IdleConnTimeout = config.IdleConnTimeout
-	transport.ResponseHeaderTimeout = 30 * time.Second // Prevent hanging on slow backends
+	transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout // Prevent hanging on slow backends
+	transport.DialContext = (&net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: config.KeepAlive,
+	}).DialContext
+	transport.Protocols = protocols
 
 	// Configure custom TLS if needed
 	tlsConfig, err := buildTLSConfig(config)
@@ -116,6 +204,17 @@ func NewHTTPClient(config HTTPClientConfig) (*HTTPClient, error) {
 
 	client.SetTransport(transport)
 
+	// Propagate the trace of the requests to the inference gateway
+	client.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+		otel.GetTextMapPropagator().Inject(r.Context(), propagation.HeaderCarrier(r.Header))
+		return nil
+	})
+
+	// Trace the connections of the request attempts
+	if config.OnConnection != nil {
+		client.OnBeforeRequest(connectionTracer(config.OnConnection))
+	}
+
 	// Configure retry only if enabled
 	if config.MaxRetries > 0 {
 		client.SetRetryCount(config.MaxRetries).
@@ -123,29 +222,83 @@ func NewHTTPClient(config HTTPClientConfig) (*HTTPClient, error) {
 			SetRetryMaxWaitTime(config.MaxBackoff)      // Max wait time between retries
 		// Resty automatically applies exponential backoff with jitter
 
-		// Retry condition: retry on server errors, rate limits, and network errors
+		// Retry condition: retry on errors the classifier categorizes as retryable
+		// (by default server errors, rate limits, and network errors), as long as the retry budget allows it
 		client.AddRetryCondition(func(r *resty.Response, err error) bool {
+			var retryable bool
 			if err != nil {
-				return true // Retry on network errors
+				retryable = config.ErrorClassifier.Classify(0, nil, err).IsRetryable()
+			} else if statusCode := r.StatusCode(); statusCode != http.StatusOK {
+				retryable = config.ErrorClassifier.Classify(statusCode, r.Body(), nil).IsRetryable()
 			}
-
-			statusCode := r.StatusCode()
-			// Retry on 429 (rate limit) and 5xx (server errors)
-			return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+			// the last attempt is not retried, it doesn't take from the retry budget
+			if !retryable || (r != nil && r.Request.Attempt > config.MaxRetries) {
+				return retryable
+			}
+			if !config.RetryBudget.Allow() {
+				if config.OnRetryBudgetExhausted != nil && r != nil {
+					config.OnRetryBudgetExhausted(requestModel(r.Request))
+				}
+				return false
+			}
+			return true
 		})
 
 		// Add retry hook for logging
 		client.AddRetryHook(func(resp *resty.Response, err error) {
+			// the unread body of an attempt that is retried is discarded. the last attempt is read by Generate
+			// or GenerateStream
+			unread := config.ReuseResponseBuffers || (resp != nil && resp.Request.Header.Get("Accept") == eventStreamContentType)
+			if unread && resp != nil && resp.RawResponse != nil && resp.Request.Attempt <= config.MaxRetries {
+				resp.RawBody().Close()
+			}
 			if reqID := resp.Request.Header.Get("X-Request-ID"); reqID != "" {
 				klog.V(3).Infof("Retrying request_id=%s (attempt %d/%d)",
 					reqID, resp.Request.Attempt, config.MaxRetries)
 			}
+			// the hook also runs after the last attempt, which is not retried
+			if config.OnRetry != nil && resp != nil && resp.Request.Attempt <= config.MaxRetries {
+				config.OnRetry(requestModel(resp.Request), retryCategory(config.ErrorClassifier, resp, err))
+			}
 		})
 	}
 
-	return &HTTPClient{
-		client: client,
-	}, nil
+	httpClient := &HTTPClient{
+		client:     client,
+		classifier: config.ErrorClassifier,
+	}
+	if config.ReuseResponseBuffers {
+		// response bodies are read by Generate into pooled buffers
+		client.SetDoNotParseResponse(true)
+		httpClient.buffers = &sync.Pool{
+			New: func() any { return new(bytes.Buffer) },
+		}
+	}
+	return httpClient, nil
+}
+
+// readBody returns the body of the response. With pooled buffers, the body is read into a buffer from the pool,
+// and the returned release func puts the buffer back. The body must not be referenced after release is called.
+func (c *HTTPClient) readBody(resp *resty.Response) (body []byte, release func(), err error) {
+	if c.buffers == nil {
+		return resp.Body(), func() {}, nil
+	}
+
+	rawBody := resp.RawBody()
+	defer rawBody.Close()
+
+	buf := c.buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	release = func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			c.buffers.Put(buf)
+		}
+	}
+	if _, err := buf.ReadFrom(rawBody); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return buf.Bytes(), release, nil
 }
 
 // Generate makes an inference request to the HTTP gateway with automatic retry logic
@@ -196,9 +349,15 @@ func (c *HTTPClient) Generate(ctx context.Context, req *GenerateRequest) (*Gener
 		return c.handleRequestError(ctx, err, req)
 	}
 
+	body, release, err := c.readBody(resp)
+	if err != nil {
+		return c.handleRequestError(ctx, err, req)
+	}
+
 	// Check for non-retryable errors after all retries exhausted
 	if resp.StatusCode() != http.StatusOK {
-		return nil, c.handleErrorResponse(resp.StatusCode(), resp.Body())
+		defer release()
+		return nil, c.handleErrorResponse(resp.StatusCode(), body)
 	}
 
 	// Log success with retry info
@@ -209,21 +368,22 @@ func (c *HTTPClient) Generate(ctx context.Context, req *GenerateRequest) (*Gener
 
 	// Parse response body
 	var rawData interface{}
-	if len(resp.Body()) > 0 {
-		if jsonErr := json.Unmarshal(resp.Body(), &rawData); jsonErr != nil {
+	if len(body) > 0 {
+		if jsonErr := json.Unmarshal(body, &rawData); jsonErr != nil {
 			klog.Warningf("Failed to unmarshal response as JSON for request_id=%s: %v",
 				req.RequestID, jsonErr)
 			rawData = nil
 		}
 	}
 
-	klog.V(4).Infof("Received successful response for request_id=%s, status=%d, body_size=%d",
-		req.RequestID, resp.StatusCode(), len(resp.Body()))
+	klog.V(4).Infof("Received successful response for request_id=%s, status=%d, body_size=%d, proto=%s",
+		req.RequestID, resp.StatusCode(), len(body), resp.Proto())
 
 	return &GenerateResponse{
 		RequestID: req.RequestID,
-		Response:  resp.Body(),
+		Response:  body,
 		RawData:   rawData,
+		release:   release,
 	}, nil
 }
 
@@ -248,7 +408,7 @@ func (c *HTTPClient) handleRequestError(ctx context.Context, err error, req *Gen
 
 	klog.V(3).Infof("Request failed with network error for request_id=%s: %v", req.RequestID, err)
 	return nil, &ClientError{
-		Category: ErrCategoryServer,
+		Category: c.classifier.Classify(0, nil, err),
 		Message:  fmt.Sprintf("failed to execute request: %v", err),
 		RawError: err,
 	}
@@ -271,8 +431,8 @@ func (c *HTTPClient) handleErrorResponse(statusCode int, body []byte) *ClientErr
 		message = errorResp.Error.Message
 	}
 
-	// Map HTTP status codes to error categories
-	category := c.mapStatusCodeToCategory(statusCode)
+	// Map the error response to an error category
+	category := c.classifier.Classify(statusCode, body, nil)
 
 	klog.V(3).Infof("Inference request failed with status=%d, category=%s, message=%s", statusCode, category, message)
 
@@ -283,23 +443,61 @@ func (c *HTTPClient) handleErrorResponse(statusCode int, body []byte) *ClientErr
 	}
 }
 
-// mapStatusCodeToCategory maps HTTP status codes to error categories
-func (c *HTTPClient) mapStatusCodeToCategory(statusCode int) ErrorCategory {
-	switch statusCode {
-	case http.StatusBadRequest: // 400
-		return ErrCategoryInvalidReq
-	case http.StatusUnauthorized, http.StatusForbidden: // 401, 403
-		return ErrCategoryAuth
-	case http.StatusTooManyRequests: // 429
-		return ErrCategoryRateLimit
-	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout: // 500, 502, 503, 504
-		return ErrCategoryServer
-	default:
-		if statusCode >= http.StatusInternalServerError {
-			return ErrCategoryServer
+// requestModel returns the model of a request, or an empty string if the request has no model
+func requestModel(r *resty.Request) string {
+	params, ok := r.Body.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	model, _ := params["model"].(string)
+	return model
+}
+
+// retryCategory returns the category of the error that caused an attempt to be retried
+func retryCategory(classifier ErrorClassifier, resp *resty.Response, err error) ErrorCategory {
+	if err != nil || resp.RawResponse == nil {
+		return classifier.Classify(0, nil, err)
+	}
+	return classifier.Classify(resp.StatusCode(), resp.Body(), nil)
+}
+
+// connTraceKey marks the context of a request whose connections are traced
+type connTraceKey struct{}
+
+// connectionTracer returns a request middleware calling onConnection with each connection the attempts of
+// a request get. The trace is added once per request, as the attempts of a retried request share its context.
+func connectionTracer(onConnection func(reused bool)) resty.RequestMiddleware {
+	return func(_ *resty.Client, r *resty.Request) error {
+		ctx := r.Context()
+		if ctx.Value(connTraceKey{}) != nil {
+			return nil
 		}
-		return ErrCategoryUnknown
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				onConnection(info.Reused)
+			},
+		}
+		r.SetContext(context.WithValue(httptrace.WithClientTrace(ctx, trace), connTraceKey{}, true))
+		return nil
+	}
+}
+
+// transportProtocols returns the protocols the transport uses for the HTTP protocol
+func transportProtocols(protocol HTTPProtocol) (*http.Protocols, error) {
+	protocols := &http.Protocols{}
+	switch protocol {
+	case HTTPProtocolHTTP1:
+		protocols.SetHTTP1(true)
+	case HTTPProtocolHTTP2:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	case HTTPProtocolH2C:
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("unsupported HTTP protocol %q (supported: %s, %s, %s)",
+			protocol, HTTPProtocolHTTP1, HTTPProtocolHTTP2, HTTPProtocolH2C)
 	}
+	return protocols, nil
 }
 
 // buildTLSConfig constructs a custom TLS configuration based on provided options
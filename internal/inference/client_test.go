@@ -28,11 +28,16 @@ import (
 	"crypto/x509/pkix"
 	"encoding/json"
 	"encoding/pem"
+	"fmt"
 	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -50,6 +55,12 @@ func TestInferenceClient(t *testing.T) {
 	t.Run("TLSConfiguration", testTLSConfiguration)
 	t.Run("Authentication", testAuthentication)
 	t.Run("NetworkErrors", testNetworkErrors)
+	t.Run("ResponseBuffers", testResponseBuffers)
+	t.Run("HTTPProtocols", testHTTPProtocols)
+	t.Run("OnRetry", testOnRetry)
+	t.Run("ConnectionReuse", testConnectionReuse)
+	t.Run("GenerateStream", testGenerateStream)
+	t.Run("Ping", testPing)
 }
 
 func testNewHTTPInferenceClient(t *testing.T) {
@@ -1102,3 +1113,303 @@ func testNetworkErrors(t *testing.T) {
 	})
 }
 
+func testResponseBuffers(t *testing.T) {
+	attemptCount := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attemptCount++
+		switch r.Header.Get("X-Request-ID") {
+		case "retry":
+			if attemptCount == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"message": "unavailable"}})
+				return
+			}
+		case "bad":
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"message": "bad request"}})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"id":"%s"}`, r.Header.Get("X-Request-ID"))
+	}))
+	t.Cleanup(testServer.Close)
+
+	client, err := NewHTTPClient(HTTPClientConfig{
+		BaseURL:              testServer.URL,
+		MaxRetries:           3,
+		InitialBackoff:       10 * time.Millisecond,
+		ReuseResponseBuffers: true,
+	})
+	require.NoError(t, err)
+
+	generate := func(requestID string) (*GenerateResponse, *ClientError) {
+		return client.Generate(context.Background(), &GenerateRequest{
+			RequestID: requestID,
+			Endpoint:  "/v1/chat/completions",
+			Params:    map[string]interface{}{"model": "test"},
+		})
+	}
+
+	t.Run("responses are read into pooled buffers", func(t *testing.T) {
+		for _, id := range []string{"first", "second"} {
+			resp, genErr := generate(id)
+			require.Nil(t, genErr)
+			assert.JSONEq(t, fmt.Sprintf(`{"id":"%s"}`, id), string(resp.Response))
+			assert.Equal(t, map[string]interface{}{"id": id}, resp.RawData)
+
+			resp.Release()
+			assert.Nil(t, resp.Response)
+			resp.Release() // releasing twice is a no-op
+		}
+	})
+
+	t.Run("error responses are read from pooled buffers", func(t *testing.T) {
+		resp, genErr := generate("bad")
+		assert.Nil(t, resp)
+		require.NotNil(t, genErr)
+		assert.Equal(t, ErrCategoryInvalidReq, genErr.Category)
+		assert.Contains(t, genErr.Message, "bad request")
+	})
+
+	t.Run("retried attempts are discarded", func(t *testing.T) {
+		attemptCount = 0
+		resp, genErr := generate("retry")
+		require.Nil(t, genErr)
+		assert.Equal(t, 2, attemptCount)
+		assert.JSONEq(t, `{"id":"retry"}`, string(resp.Response))
+		resp.Release()
+	})
+}
+
+// BenchmarkGenerate compares allocating a buffer per response with reusing pooled response buffers.
+// Run with: go test -run ^$ -bench BenchmarkGenerate -benchmem ./internal/inference
+func BenchmarkGenerate(b *testing.B) {
+	body := fmt.Sprintf(`{"id":"resp","content":"%s"}`, strings.Repeat("a", 64*1024))
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	}))
+	b.Cleanup(testServer.Close)
+
+	for _, reuse := range []bool{false, true} {
+		b.Run(fmt.Sprintf("ReuseResponseBuffers=%v", reuse), func(b *testing.B) {
+			client, err := NewHTTPClient(HTTPClientConfig{
+				BaseURL:              testServer.URL,
+				ReuseResponseBuffers: reuse,
+			})
+			require.NoError(b, err)
+			req := &GenerateRequest{
+				RequestID: "bench",
+				Endpoint:  "/v1/chat/completions",
+				Params:    map[string]interface{}{"model": "test"},
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resp, genErr := client.Generate(context.Background(), req)
+				if genErr != nil {
+					b.Fatal(genErr)
+				}
+				resp.Release()
+			}
+		})
+	}
+}
+
+func testHTTPProtocols(t *testing.T) {
+	// the handler echoes the protocol of the request
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"proto": r.Proto})
+	})
+
+	// h2 server, negotiating HTTP/2 with ALPN over TLS
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	t.Cleanup(tlsServer.Close)
+
+	// h2c server, accepting HTTP/2 with prior knowledge over cleartext connections
+	h2cServer := httptest.NewUnstartedServer(handler)
+	h2cServer.Config.Protocols = &http.Protocols{}
+	h2cServer.Config.Protocols.SetHTTP1(true)
+	h2cServer.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2cServer.Start()
+	t.Cleanup(h2cServer.Close)
+
+	tests := []struct {
+		name      string
+		baseURL   string
+		protocol  HTTPProtocol
+		wantProto string
+	}{
+		{name: "default uses HTTP/1.1", baseURL: tlsServer.URL, wantProto: "HTTP/1.1"},
+		{name: "http1 uses HTTP/1.1", baseURL: tlsServer.URL, protocol: HTTPProtocolHTTP1, wantProto: "HTTP/1.1"},
+		{name: "http2 negotiates HTTP/2", baseURL: tlsServer.URL, protocol: HTTPProtocolHTTP2, wantProto: "HTTP/2.0"},
+		{name: "http2 falls back to HTTP/1.1 without TLS", baseURL: h2cServer.URL, protocol: HTTPProtocolHTTP2, wantProto: "HTTP/1.1"},
+		{name: "h2c uses HTTP/2 with prior knowledge", baseURL: h2cServer.URL, protocol: HTTPProtocolH2C, wantProto: "HTTP/2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewHTTPClient(HTTPClientConfig{
+				BaseURL:               tt.baseURL,
+				Protocol:              tt.protocol,
+				TLSInsecureSkipVerify: true,
+			})
+			require.NoError(t, err)
+
+			resp, genErr := client.Generate(context.Background(), &GenerateRequest{
+				RequestID: "test",
+				Endpoint:  "/v1/chat/completions",
+				Params:    map[string]interface{}{"model": "test"},
+			})
+			require.Nil(t, genErr)
+			assert.Equal(t, map[string]interface{}{"proto": tt.wantProto}, resp.RawData)
+		})
+	}
+
+	t.Run("unsupported protocol", func(t *testing.T) {
+		_, err := NewHTTPClient(HTTPClientConfig{BaseURL: tlsServer.URL, Protocol: "spdy"})
+		assert.Error(t, err)
+	})
+}
+
+func testOnRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statusCodes  []int // status codes returned by the attempts before the successful one
+		maxRetries   int
+		wantRetries  int
+		wantCategory ErrorCategory
+	}{
+		{name: "server errors", statusCodes: []int{http.StatusServiceUnavailable, http.StatusInternalServerError}, maxRetries: 3, wantRetries: 2, wantCategory: ErrCategoryServer},
+		{name: "rate limit", statusCodes: []int{http.StatusTooManyRequests}, maxRetries: 3, wantRetries: 1, wantCategory: ErrCategoryRateLimit},
+		{name: "retries exhausted", statusCodes: []int{500, 500, 500, 500}, maxRetries: 2, wantRetries: 2, wantCategory: ErrCategoryServer},
+		{name: "no retry", statusCodes: nil, maxRetries: 3, wantRetries: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attemptCount := 0
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attemptCount++
+				if attemptCount <= len(tt.statusCodes) {
+					w.WriteHeader(tt.statusCodes[attemptCount-1])
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]interface{}{"id": "success"})
+			}))
+			t.Cleanup(testServer.Close)
+
+			var models []string
+			var categories []ErrorCategory
+			client, err := NewHTTPClient(HTTPClientConfig{
+				BaseURL:        testServer.URL,
+				MaxRetries:     tt.maxRetries,
+				InitialBackoff: 10 * time.Millisecond,
+				OnRetry: func(model string, category ErrorCategory) {
+					models = append(models, model)
+					categories = append(categories, category)
+				},
+			})
+			require.NoError(t, err)
+
+			client.Generate(context.Background(), &GenerateRequest{
+				RequestID: "test",
+				Endpoint:  "/v1/chat/completions",
+				Params:    map[string]interface{}{"model": "test-model"},
+			})
+
+			assert.Len(t, categories, tt.wantRetries)
+			for i := range categories {
+				assert.Equal(t, "test-model", models[i])
+				assert.Equal(t, tt.wantCategory, categories[i])
+			}
+		})
+	}
+}
+
+func testConnectionReuse(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "success"})
+	}))
+	t.Cleanup(testServer.Close)
+
+	var mu sync.Mutex
+	var reused []bool
+	client, err := NewHTTPClient(HTTPClientConfig{
+		BaseURL: testServer.URL,
+		OnConnection: func(r bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			reused = append(reused, r)
+		},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		resp, genErr := client.Generate(context.Background(), &GenerateRequest{
+			RequestID: fmt.Sprintf("test-%d", i),
+			Endpoint:  "/v1/chat/completions",
+			Params:    map[string]interface{}{"model": "test"},
+		})
+		require.Nil(t, genErr)
+		resp.Release()
+	}
+
+	// sequential requests open one connection, then reuse it
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []bool{false, true, true}, reused)
+}
+
+// BenchmarkGenerateConcurrent measures the connections opened by concurrent requests sharing the pooled client.
+// With enough idle connections per host, the connections are reused and conns/op stays close to zero.
+// Run with: go test -run ^$ -bench BenchmarkGenerateConcurrent ./internal/inference
+func BenchmarkGenerateConcurrent(b *testing.B) {
+	var conns atomic.Int64
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"resp"}`))
+	}))
+	testServer.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	testServer.Start()
+	b.Cleanup(testServer.Close)
+
+	for _, idleConnsPerHost := range []int{2, 100} {
+		b.Run(fmt.Sprintf("MaxIdleConnsPerHost=%d", idleConnsPerHost), func(b *testing.B) {
+			client, err := NewHTTPClient(HTTPClientConfig{
+				BaseURL:             testServer.URL,
+				MaxIdleConnsPerHost: idleConnsPerHost,
+			})
+			require.NoError(b, err)
+			req := &GenerateRequest{
+				RequestID: "bench",
+				Endpoint:  "/v1/chat/completions",
+				Params:    map[string]interface{}{"model": "test"},
+			}
+
+			start := conns.Load()
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					resp, genErr := client.Generate(context.Background(), req)
+					if genErr != nil {
+						b.Fatal(genErr)
+					}
+					resp.Release()
+				}
+			})
+			b.ReportMetric(float64(conns.Load()-start)/float64(b.N), "conns/op")
+		})
+	}
+}
@@ -27,6 +27,11 @@ const (
 	ErrCategoryUnknown    ErrorCategory = "UNKNOWN"      // not retryable
 )
 
+// IsRetryable checks if errors of the category are retryable
+func (c ErrorCategory) IsRetryable() bool {
+	return c == ErrCategoryRateLimit || c == ErrCategoryServer
+}
+
 // ClientError represents an inference client error with category and context
 type ClientError struct {
 	Category ErrorCategory
@@ -40,5 +45,5 @@ func (e *ClientError) Error() string {
 
 // IsRetryable checks if the error is retryable
 func (e *ClientError) IsRetryable() bool {
-	return e.Category == ErrCategoryRateLimit || e.Category == ErrCategoryServer
+	return e.Category.IsRetryable()
 }
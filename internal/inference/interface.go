@@ -23,6 +23,17 @@ type Client interface {
 	Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, *ClientError)
 }
 
+// StreamingClient is a Client that can also stream inference responses
+type StreamingClient interface {
+	Client
+	GenerateStream(ctx context.Context, req *GenerateRequest, onChunk func(chunk []byte) error) (*GenerateResponse, *ClientError)
+}
+
+// Pinger is implemented by clients that can check the inference gateway is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 // GenerateRequest represents an inference generation request
 type GenerateRequest struct {
 	RequestID string                 // unique request id set by user
@@ -71,6 +82,23 @@ type GenerateResponse struct {
 	RequestID string
 	Response  []byte
 	RawData   interface{}
+
+	// release returns the buffer backing Response to the client's buffer pool, if any
+	release func()
+}
+
+// Release returns the buffer backing Response to the client's buffer pool.
+// It must be called once the response data is no longer referenced, e.g. after it was written to the output file.
+// Response is not usable after Release. Calling Release on a response that isn't pooled is a no-op.
+func (r *GenerateResponse) Release() {
+	if r == nil {
+		return
+	}
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+	r.Response = nil
 }
 
 // Response example for openai chat completion with tool calls:
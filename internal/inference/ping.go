@@ -0,0 +1,38 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inference
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Ping checks that the inference gateway is reachable by sending a HEAD request to its base URL.
+// Any HTTP response counts as reachable, whatever its status code, and the request isn't retried.
+func (c *HTTPClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.client.BaseURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create ping request: %w", err)
+	}
+	resp, err := c.client.GetClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("inference gateway is unreachable: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inference
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPing(t *testing.T) {
+	t.Run("should succeed on any HTTP response without retrying", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			assert.Equal(t, http.MethodHead, r.Method)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client, err := NewHTTPClient(HTTPClientConfig{BaseURL: server.URL, MaxRetries: 3})
+		require.NoError(t, err)
+
+		assert.NoError(t, client.Ping(context.Background()))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("should fail when the gateway is unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		client, err := NewHTTPClient(HTTPClientConfig{BaseURL: server.URL})
+		require.NoError(t, err)
+
+		assert.Error(t, client.Ping(context.Background()))
+	})
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains the retry budget of the inference client, which caps the rate of the retries
+// of all the requests, so that the retries don't amplify the load of a struggling inference gateway.
+
+package inference
+
+import (
+	"golang.org/x/time/rate"
+)
+
+// RetryBudget is a token bucket shared by the requests of a client, each retry taking a token.
+// Once the tokens are spent, the failed requests are not retried until the bucket refills.
+type RetryBudget struct {
+	limiter *rate.Limiter
+}
+
+// NewRetryBudget returns a budget of perSecond retries on average, with bursts of up to burst retries.
+func NewRetryBudget(perSecond float64, burst int) *RetryBudget {
+	return &RetryBudget{limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+}
+
+// Allow takes a token for a retry, and reports whether the budget had one. A nil budget allows all the retries.
+func (b *RetryBudget) Allow() bool {
+	return b == nil || b.limiter.Allow()
+}
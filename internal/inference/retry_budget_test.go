@@ -0,0 +1,116 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the retry budget of the inference client.
+
+package inference
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBudget(t *testing.T) {
+	t.Run("nil budget allows all retries", func(t *testing.T) {
+		var budget *RetryBudget
+		for range 100 {
+			assert.True(t, budget.Allow())
+		}
+	})
+
+	t.Run("burst is spent", func(t *testing.T) {
+		budget := NewRetryBudget(0.001, 2)
+		assert.True(t, budget.Allow())
+		assert.True(t, budget.Allow())
+		assert.False(t, budget.Allow())
+	})
+
+	t.Run("excessive retries are throttled", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		var retries, exhausted atomic.Int32
+		client, err := NewHTTPClient(HTTPClientConfig{
+			BaseURL:        server.URL,
+			MaxRetries:     3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+			OnRetry:        func(string, ErrorCategory) { retries.Add(1) },
+			// the budget doesn't refill during the test
+			RetryBudget: NewRetryBudget(0.001, 2),
+			OnRetryBudgetExhausted: func(model string) {
+				assert.Equal(t, "m", model)
+				exhausted.Add(1)
+			},
+		})
+		require.NoError(t, err)
+
+		for i := range 3 {
+			_, genErr := client.Generate(context.Background(), &GenerateRequest{
+				RequestID: fmt.Sprintf("req-%d", i),
+				Endpoint:  "/v1/chat/completions",
+				Params:    map[string]interface{}{"model": "m"},
+			})
+			require.NotNil(t, genErr)
+			assert.Equal(t, ErrCategoryServer, genErr.Category)
+		}
+
+		// the first request spends the budget on 2 retries, the others fail on their first attempt
+		assert.Equal(t, int32(2), retries.Load())
+		assert.Equal(t, int32(3), exhausted.Load())
+		assert.Equal(t, int32(3+1+1), attempts.Load())
+	})
+
+	t.Run("last attempt does not take from the budget", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		budget := NewRetryBudget(0.001, 2)
+		client, err := NewHTTPClient(HTTPClientConfig{
+			BaseURL:        server.URL,
+			MaxRetries:     1,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+			RetryBudget:    budget,
+		})
+		require.NoError(t, err)
+
+		_, genErr := client.Generate(context.Background(), &GenerateRequest{
+			RequestID: "req",
+			Endpoint:  "/v1/chat/completions",
+			Params:    map[string]interface{}{"model": "m"},
+		})
+		require.NotNil(t, genErr)
+		assert.Equal(t, int32(2), attempts.Load())
+		assert.True(t, budget.Allow(), "Expected one token left after a single retry")
+	})
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inference
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// eventStreamContentType is the content type of server-sent event streams
+	eventStreamContentType = "text/event-stream"
+
+	// streamDoneSentinel is the data of the last event of an OpenAI-compatible stream
+	streamDoneSentinel = "[DONE]"
+
+	// maxStreamEventSize is the maximum size of a single server-sent event line
+	maxStreamEventSize = 4 << 20
+)
+
+// GenerateStream makes an inference request with streaming enabled and calls onChunk with the data of each
+// streamed event, e.g. a chat.completion.chunk object. The chunk must not be retained after onChunk returns.
+// When the response was streamed, no response is returned and the caller assembles the chunks.
+// When the gateway answers without streaming, or rejects the streaming request as invalid (e.g. the model
+// doesn't support streaming), the full response of a non-streaming request is returned instead and onChunk
+// isn't called.
+func (c *HTTPClient) GenerateStream(ctx context.Context, req *GenerateRequest, onChunk func(chunk []byte) error) (*GenerateResponse, *ClientError) {
+	if req == nil {
+		return nil, &ClientError{
+			Category: ErrCategoryInvalidReq,
+			Message:  "request cannot be nil",
+		}
+	}
+	if req.Endpoint == "" {
+		return nil, &ClientError{
+			Category: ErrCategoryInvalidReq,
+			Message:  "endpoint cannot be empty",
+		}
+	}
+
+	// the usage is sent with the last chunk
+	params := make(map[string]interface{}, len(req.Params)+2)
+	for k, v := range req.Params {
+		params[k] = v
+	}
+	params["stream"] = true
+	params["stream_options"] = map[string]interface{}{"include_usage": true}
+
+	restyReq := c.client.R().
+		SetContext(ctx).
+		SetDoNotParseResponse(true).
+		SetHeader("Accept", eventStreamContentType).
+		SetBody(params)
+	if req.RequestID != "" {
+		restyReq.SetHeader("X-Request-ID", req.RequestID)
+	}
+
+	klog.V(4).Infof("Sending streaming inference request to %s with request_id=%s", req.Endpoint, req.RequestID)
+
+	resp, err := restyReq.Post(req.Endpoint)
+	if err != nil {
+		return c.handleRequestError(ctx, err, req)
+	}
+	rawBody := resp.RawBody()
+	defer rawBody.Close()
+
+	if resp.StatusCode() != http.StatusOK {
+		body, err := io.ReadAll(rawBody)
+		if err != nil {
+			return c.handleRequestError(ctx, err, req)
+		}
+		clientErr := c.handleErrorResponse(resp.StatusCode(), body)
+		if clientErr.Category != ErrCategoryInvalidReq {
+			return nil, clientErr
+		}
+		klog.V(3).Infof("Streaming request rejected for request_id=%s, falling back to non-streaming: %s",
+			req.RequestID, clientErr.Message)
+		return c.Generate(ctx, req)
+	}
+
+	if !strings.HasPrefix(resp.Header().Get("Content-Type"), eventStreamContentType) {
+		// the gateway answered with the full response
+		body, err := io.ReadAll(rawBody)
+		if err != nil {
+			return c.handleRequestError(ctx, err, req)
+		}
+		var rawData interface{}
+		if jsonErr := json.Unmarshal(body, &rawData); jsonErr != nil {
+			rawData = nil
+		}
+		return &GenerateResponse{RequestID: req.RequestID, Response: body, RawData: rawData}, nil
+	}
+
+	var chunkErr error
+	err = readEventStream(rawBody, func(data []byte) error {
+		chunkErr = onChunk(data)
+		return chunkErr
+	})
+	if chunkErr != nil {
+		return nil, &ClientError{
+			Category: ErrCategoryUnknown,
+			Message:  fmt.Sprintf("invalid stream chunk: %v", chunkErr),
+			RawError: chunkErr,
+		}
+	}
+	if err != nil {
+		return c.handleRequestError(ctx, err, req)
+	}
+
+	klog.V(4).Infof("Received streamed response for request_id=%s, proto=%s", req.RequestID, resp.Proto())
+	return nil, nil
+}
+
+// readEventStream reads server-sent events and calls onData with the data of each event,
+// until the [DONE] sentinel. Other fields, comments and blank lines are ignored.
+// A stream that ends without the sentinel is truncated and returns io.ErrUnexpectedEOF.
+func readEventStream(r io.Reader, onData func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamEventSize)
+	for scanner.Scan() {
+		data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data:"))
+		if !ok {
+			continue
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == streamDoneSentinel {
+			return nil
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if err := onData(data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testGenerateStream(t *testing.T) {
+	chunks := []string{
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+	}
+	streamHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive comment\n\n")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}
+	newRequest := func() *GenerateRequest {
+		return &GenerateRequest{
+			RequestID: "req-stream",
+			Endpoint:  "/v1/chat/completions",
+			Params:    map[string]interface{}{"model": "m"},
+		}
+	}
+
+	t.Run("should call onChunk for each event until DONE", func(t *testing.T) {
+		var params map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+			assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+			streamHandler(w, r)
+		}))
+		defer server.Close()
+
+		client, err := NewHTTPClient(HTTPClientConfig{BaseURL: server.URL})
+		require.NoError(t, err)
+
+		var received []string
+		resp, clientErr := client.GenerateStream(context.Background(), newRequest(), func(chunk []byte) error {
+			received = append(received, string(chunk))
+			return nil
+		})
+		require.Nil(t, clientErr)
+		assert.Nil(t, resp)
+		assert.Equal(t, chunks, received)
+		assert.Equal(t, true, params["stream"])
+		assert.Equal(t, map[string]interface{}{"include_usage": true}, params["stream_options"])
+		assert.Equal(t, "m", params["model"])
+	})
+
+	t.Run("should return the full response when the gateway doesn't stream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"chatcmpl-2","object":"chat.completion"}`))
+		}))
+		defer server.Close()
+
+		client, err := NewHTTPClient(HTTPClientConfig{BaseURL: server.URL})
+		require.NoError(t, err)
+
+		resp, clientErr := client.GenerateStream(context.Background(), newRequest(), func(chunk []byte) error {
+			t.Errorf("unexpected chunk %s", chunk)
+			return nil
+		})
+		require.Nil(t, clientErr)
+		require.NotNil(t, resp)
+		assert.JSONEq(t, `{"id":"chatcmpl-2","object":"chat.completion"}`, string(resp.Response))
+	})
+
+	t.Run("should fall back to non-streaming when streaming is rejected", func(t *testing.T) {
+		var streamed []bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var params map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+			stream, _ := params["stream"].(bool)
+			streamed = append(streamed, stream)
+			if stream {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":{"message":"streaming is not supported for this model"}}`))
+				return
+			}
+			w.Write([]byte(`{"id":"chatcmpl-3","object":"chat.completion"}`))
+		}))
+		defer server.Close()
+
+		client, err := NewHTTPClient(HTTPClientConfig{BaseURL: server.URL})
+		require.NoError(t, err)
+
+		resp, clientErr := client.GenerateStream(context.Background(), newRequest(), func(chunk []byte) error { return nil })
+		require.Nil(t, clientErr)
+		require.NotNil(t, resp)
+		assert.JSONEq(t, `{"id":"chatcmpl-3","object":"chat.completion"}`, string(resp.Response))
+		assert.Equal(t, []bool{true, false}, streamed)
+	})
+
+	t.Run("should retry retryable errors before streaming", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			streamHandler(w, r)
+		}))
+		defer server.Close()
+
+		client, err := NewHTTPClient(HTTPClientConfig{
+			BaseURL:        server.URL,
+			MaxRetries:     2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		count := 0
+		_, clientErr := client.GenerateStream(context.Background(), newRequest(), func(chunk []byte) error {
+			count++
+			return nil
+		})
+		require.Nil(t, clientErr)
+		assert.Equal(t, len(chunks), count)
+		assert.Equal(t, int32(2), attempts.Load())
+	})
+
+	t.Run("should fail on a truncated stream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: %s\n\n", chunks[0])
+		}))
+		defer server.Close()
+
+		client, err := NewHTTPClient(HTTPClientConfig{BaseURL: server.URL})
+		require.NoError(t, err)
+
+		_, clientErr := client.GenerateStream(context.Background(), newRequest(), func(chunk []byte) error { return nil })
+		require.NotNil(t, clientErr)
+	})
+
+	t.Run("should fail when a chunk is rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(streamHandler))
+		defer server.Close()
+
+		client, err := NewHTTPClient(HTTPClientConfig{BaseURL: server.URL})
+		require.NoError(t, err)
+
+		_, clientErr := client.GenerateStream(context.Background(), newRequest(), func(chunk []byte) error {
+			return fmt.Errorf("bad chunk")
+		})
+		require.NotNil(t, clientErr)
+		assert.Equal(t, ErrCategoryUnknown, clientErr.Category)
+	})
+}
@@ -19,13 +19,29 @@ limitations under the License.
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/tracing"
 )
 
+// MemoryDatabaseURL selects the in-memory database and files storage clients, for local development.
+// Their data is lost when the processor exits and is not shared with the API server.
+const MemoryDatabaseURL = "memory://"
+
 type ProcessorConfig struct {
+	// DatabaseURL is the URL of the database holding the jobs, or MemoryDatabaseURL.
+	// A postgres:// or postgresql:// URL selects the postgresql jobs and status clients.
+	DatabaseURL string `yaml:"database_url"`
+
 	// TaskWaitTime is the timeout parameter used when dequeueing from the priority queue
 	// This should be shorter than PollInterval
 	TaskWaitTime time.Duration `yaml:"task_wait_time"`
@@ -33,9 +49,21 @@ type ProcessorConfig struct {
 	// NumWorkers is the fixed number of worker goroutines spawned to process jobs
 	NumWorkers int `yaml:"num_workers"`
 
+	// WorkerReservations reserves a number of workers per tenant, so the tenant always has workers available.
+	// Reserved workers that are not in use are lent to other tenants, and reclaimed when needed.
+	WorkerReservations map[string]int `yaml:"worker_reservations"`
+
 	// MaxJobConcurrency defines how many lines within a single job are processed concurrently
 	MaxJobConcurrency int `yaml:"max_job_concurrency"`
 
+	// PreserveInputOrder writes the output lines of a job in the order of its input file, holding the lines
+	// completed before a previous line. Otherwise the lines are written as they complete, for lower latency.
+	PreserveInputOrder bool `yaml:"preserve_input_order"`
+
+	// MaxInferenceConcurrency caps the inference requests in flight across all the jobs of the processor,
+	// so NumWorkers x MaxJobConcurrency can't exceed the capacity of the inference gateway. Zero means no cap.
+	MaxInferenceConcurrency int `yaml:"max_inference_concurrency"`
+
 	// PollInterval defines how frequently the processor checks the database for new jobs
 	PollInterval time.Duration `yaml:"poll_interval"`
 
@@ -45,16 +73,118 @@ type ProcessorConfig struct {
 	// ProcessTimeBucket defines exponential bucket configs for process time metric
 	ProcessTimeBucket BucketConfig `yaml:"process_time_bucket"`
 
+	// InferenceTimeBucket defines exponential bucket configs for inference call duration metric
+	InferenceTimeBucket BucketConfig `yaml:"inference_time_bucket"`
+
+	// WorkDir is the local directory where partial output files are assembled
+	WorkDir string `yaml:"work_dir"`
+
+	// CheckpointInterval is the number of lines processed between two checkpoints of a job
+	CheckpointInterval int `yaml:"checkpoint_interval"`
+
+	// ValidationShutdownTimeout is how long a job that is being validated when the processor shuts down
+	// may continue its validation. Jobs that don't finish validating in time are re-queued, so their validation
+	// restarts on another replica. Zero stops validation immediately.
+	ValidationShutdownTimeout time.Duration `yaml:"validation_shutdown_timeout"`
+
+	// JobLeaseTTL is how long the lease of a replica on a job it processes lasts without being renewed.
+	// Replicas skip the jobs leased by other replicas, and the jobs of a crashed replica can be claimed
+	// again once their lease expires. Leases are renewed every third of their TTL while the job is processed.
+	JobLeaseTTL time.Duration `yaml:"job_lease_ttl"`
+
+	// JobRecoveryInterval is how often the jobs that are neither final nor claimed are put back to the queue,
+	// such as the jobs of a crashed replica once their lease expired. Zero disables the recovery.
+	JobRecoveryInterval time.Duration `yaml:"job_recovery_interval"`
+
+	// ShutdownGracePeriod bounds the graceful shutdown: the processor exits when it hasn't shut down
+	// within the period after the first shutdown signal. Set it below the pod's terminationGracePeriodSeconds.
+	// Zero waits until the shutdown completes or a second signal arrives.
+	ShutdownGracePeriod time.Duration `yaml:"shutdown_grace_period"`
+
+	// DisableMetricsTenantLabel records metrics without their tenantID label value.
+	// Each tenant adds a series per metric, so this bounds the metrics cardinality when there are many tenants.
+	DisableMetricsTenantLabel bool `yaml:"disable_metrics_tenant_label"`
+
 	Addr        string `yaml:"addr"`
 	SSLCertFile string `yaml:"ssl_cert_file"`
 	SSLKeyFile  string `yaml:"ssl_key_file"`
 
+	// OutputFileTTL is how long the output and error files of a batch are kept after their creation,
+	// when the batch doesn't set an output_expires_after policy
+	OutputFileTTL time.Duration `yaml:"output_file_ttl"`
+
+	// DeadLetterFailedJobs copies the batches that fail permanently to the dead-letter table of the database,
+	// where they are kept for inspection with their failure reason
+	DeadLetterFailedJobs bool `yaml:"dead_letter_failed_jobs"`
+
+	// Tracing configures the export of the spans of the jobs and of their inference requests.
+	// The trace of a job continues the trace of the request that created it.
+	Tracing tracing.Config `yaml:"tracing"`
+
+	// CallbackSigningSecret is the key signing the callbacks posted to the callback_url of batches.
+	// The signature is sent in the X-Batch-Signature header, so receivers can verify the callbacks. Empty disables signing.
+	CallbackSigningSecret string `yaml:"callback_signing_secret"`
+
+	// CallbackTimeout is the timeout of a single callback delivery attempt
+	CallbackTimeout time.Duration `yaml:"callback_timeout"`
+
+	// CallbackMaxRetries is the maximum number of retries of a callback that fails with a server or network error
+	CallbackMaxRetries int `yaml:"callback_max_retries"`
+
+	// CallbackInitialBackoff is the wait before the first callback retry, doubled for each following retry
+	CallbackInitialBackoff time.Duration `yaml:"callback_initial_backoff"`
+
 	// InferenceGatewayURL is the base URL of the inference gateway (llm-d or GAIE)
 	InferenceGatewayURL string `yaml:"inference_gateway_url"`
 
 	// InferenceRequestTimeout is the timeout for individual inference requests
 	InferenceRequestTimeout time.Duration `yaml:"inference_request_timeout"`
 
+	// PerLineTimeout bounds the processing of an input line, including the retries of its inference request.
+	// A line that doesn't complete in time is failed with a line_timeout error.
+	PerLineTimeout time.Duration `yaml:"per_line_timeout"`
+
+	// LateResponseGracePeriod is how long after PerLineTimeout the request of a timed out line is kept in flight,
+	// never past the expiry of the batch. The line is failed at the timeout, and a late response received before
+	// the batch is finalized moves it to the output file, after the other lines. The timeout of the requests to
+	// the inference gateway is extended by the grace period. Zero disables the grace period.
+	LateResponseGracePeriod time.Duration `yaml:"late_response_grace_period"`
+
+	// InferenceHTTPProtocol is the HTTP protocol used with the inference gateway: http1, http2 (ALPN over TLS)
+	// or h2c (HTTP/2 with prior knowledge). HTTP/2 multiplexes concurrent requests over fewer connections.
+	InferenceHTTPProtocol string `yaml:"inference_http_protocol"`
+
+	// InferenceMaxIdleConns and InferenceMaxIdleConnsPerHost bound the idle connections kept in the pool of the
+	// inference client. Keep them at least at the number of concurrent inference requests, so the connections
+	// are reused instead of being reopened.
+	InferenceMaxIdleConns        int `yaml:"inference_max_idle_conns"`
+	InferenceMaxIdleConnsPerHost int `yaml:"inference_max_idle_conns_per_host"`
+
+	// InferenceIdleConnTimeout is how long an idle connection to the inference gateway is kept in the pool
+	InferenceIdleConnTimeout time.Duration `yaml:"inference_idle_conn_timeout"`
+
+	// InferenceDialTimeout is the timeout of opening a connection to the inference gateway
+	InferenceDialTimeout time.Duration `yaml:"inference_dial_timeout"`
+
+	// InferenceKeepAlive is the TCP keep-alive period of the connections to the inference gateway.
+	// A negative value disables the keep-alives.
+	InferenceKeepAlive time.Duration `yaml:"inference_keep_alive"`
+
+	// InferenceResponseHeaderTimeout is how long the inference gateway may take to send the response headers
+	InferenceResponseHeaderTimeout time.Duration `yaml:"inference_response_header_timeout"`
+
+	// ModelAliases maps the model names requested by the input files to the models the requests are sent to,
+	// e.g. to redirect a public model name to an internal deployment. The output keeps the requested names.
+	ModelAliases map[string]string `yaml:"model_aliases"`
+
+	// RequestTransforms adapt the request bodies of the input files to the inference backend before they are sent,
+	// e.g. to rename a field the backend expects under another name. The rules are applied in order.
+	RequestTransforms []RequestTransform `yaml:"request_transforms"`
+
+	// ResponseTransforms normalize the response bodies of the inference backend into the OpenAI shape of their
+	// endpoint before they are written to the output files. The rules are applied in order. None by default.
+	ResponseTransforms []ResponseTransform `yaml:"response_transforms"`
+
 	// InferenceAPIKey is the optional API key for authenticating with the inference gateway
 	InferenceAPIKey string `yaml:"inference_api_key"`
 
@@ -67,6 +197,28 @@ type ProcessorConfig struct {
 	// InferenceMaxBackoff is the maximum backoff duration for retries
 	InferenceMaxBackoff time.Duration `yaml:"inference_max_backoff"`
 
+	// InferenceRetryBudgetRate caps the rate of the retries of the inference requests of all the workers,
+	// in retries per second. Once the budget is spent, failed requests fail right away instead of being retried.
+	// Zero disables the budget.
+	InferenceRetryBudgetRate float64 `yaml:"inference_retry_budget_rate"`
+
+	// InferenceRetryBudgetBurst is the number of retries the budget allows at once, above its rate
+	InferenceRetryBudgetBurst int `yaml:"inference_retry_budget_burst"`
+
+	// InferenceBreakerThreshold is the number of consecutive server errors of the inference gateway opening the
+	// circuit breaker, which then fails the inference requests right away. Zero disables the circuit breaker.
+	InferenceBreakerThreshold int `yaml:"inference_breaker_threshold"`
+
+	// InferenceBreakerCooldown is how long the circuit breaker stays open before probing the inference gateway
+	InferenceBreakerCooldown time.Duration `yaml:"inference_breaker_cooldown"`
+
+	// InferenceReuseResponseBuffers reads inference responses into pooled buffers to reduce GC pressure
+	InferenceReuseResponseBuffers bool `yaml:"inference_reuse_response_buffers"`
+
+	// InferenceStreaming streams chat completions from the inference gateway and assembles the streamed chunks
+	// into the output lines. Requests the gateway rejects for streaming are retried without streaming.
+	InferenceStreaming bool `yaml:"inference_streaming"`
+
 	// InferenceTLSInsecureSkipVerify skips TLS certificate verification (INSECURE, only for testing)
 	InferenceTLSInsecureSkipVerify bool `yaml:"inference_tls_insecure_skip_verify"`
 
@@ -80,12 +232,127 @@ type ProcessorConfig struct {
 	InferenceTLSClientKeyFile string `yaml:"inference_tls_client_key_file"`
 }
 
+// RequestTransform is a rule transforming the request bodies sent to the inference backend.
+type RequestTransform struct {
+	// Endpoint restricts the rule to the requests of an endpoint, e.g. /v1/chat/completions. All when empty.
+	Endpoint string `yaml:"endpoint"`
+
+	// Rename renames the fields of the request body named by its keys to its values.
+	// A field already set under the new name is kept, and the renamed field is dropped.
+	Rename map[string]string `yaml:"rename"`
+
+	// Defaults sets the fields missing from the request body to their values.
+	Defaults map[string]interface{} `yaml:"defaults"`
+}
+
+// Validate checks that the rule names a supported endpoint and valid fields.
+// The model is not transformed, model aliases map it to the models of the backend.
+func (rt RequestTransform) Validate() error {
+	if rt.Endpoint != "" && !openai.Endpoint(rt.Endpoint).IsValid() {
+		return fmt.Errorf("endpoint %q is not a supported endpoint", rt.Endpoint)
+	}
+	if len(rt.Rename) == 0 && len(rt.Defaults) == 0 {
+		return fmt.Errorf("rule must rename or set default fields")
+	}
+	for from, to := range rt.Rename {
+		if from == "" || to == "" || from == to {
+			return fmt.Errorf("rename %q -> %q must map a field to another field", from, to)
+		}
+		if from == "model" || to == "model" {
+			return fmt.Errorf("rename %q -> %q must not rename the model, use model_aliases", from, to)
+		}
+	}
+	for field := range rt.Defaults {
+		if field == "" || field == "model" {
+			return fmt.Errorf("default field %q must be a field other than the model", field)
+		}
+	}
+	return nil
+}
+
+// ResponseTransform is a rule normalizing the response bodies of the inference backend.
+// The fields are named by their dotted paths in the body, where numbers index arrays, e.g. choices.0.message.content.
+type ResponseTransform struct {
+	// Endpoint restricts the rule to the responses of an endpoint, e.g. /v1/chat/completions. All when empty.
+	Endpoint string `yaml:"endpoint"`
+
+	// Move moves the fields of the response body at the paths of its keys to the paths of its values.
+	// A field already set at the new path is kept, and the moved field is dropped.
+	Move map[string]string `yaml:"move"`
+
+	// Defaults sets the fields missing from the response body to their values.
+	Defaults map[string]interface{} `yaml:"defaults"`
+
+	// Drop removes fields of the response body, after the fields are moved and the defaults are set.
+	Drop []string `yaml:"drop"`
+}
+
+// Validate checks that the rule names a supported endpoint and valid paths.
+func (rt ResponseTransform) Validate() error {
+	if rt.Endpoint != "" && !openai.Endpoint(rt.Endpoint).IsValid() {
+		return fmt.Errorf("endpoint %q is not a supported endpoint", rt.Endpoint)
+	}
+	if len(rt.Move) == 0 && len(rt.Defaults) == 0 && len(rt.Drop) == 0 {
+		return fmt.Errorf("rule must move, set default or drop fields")
+	}
+	for from, to := range rt.Move {
+		if !validFieldPath(from) || !validFieldPath(to) || from == to {
+			return fmt.Errorf("move %q -> %q must map a field path to another field path", from, to)
+		}
+	}
+	for path := range rt.Defaults {
+		if !validFieldPath(path) {
+			return fmt.Errorf("default field path %q is invalid", path)
+		}
+	}
+	for _, path := range rt.Drop {
+		if !validFieldPath(path) {
+			return fmt.Errorf("dropped field path %q is invalid", path)
+		}
+	}
+	return nil
+}
+
+// validFieldPath reports whether path is a dotted path of non-empty field names.
+func validFieldPath(path string) bool {
+	return path != "" && !slices.Contains(strings.Split(path, "."), "")
+}
+
 type BucketConfig struct {
 	BucketStart  float64 `yaml:"bucket_start"`
 	BucketFactor float64 `yaml:"bucket_factor"`
 	BucketCount  int     `yaml:"bucket_count"`
 }
 
+// Validate checks that the bucket config describes valid exponential buckets.
+func (bc BucketConfig) Validate() error {
+	if bc.BucketStart <= 0 {
+		return fmt.Errorf("bucket_start must be positive, got %v", bc.BucketStart)
+	}
+	if bc.BucketFactor <= 1 {
+		return fmt.Errorf("bucket_factor must be greater than 1, got %v", bc.BucketFactor)
+	}
+	if bc.BucketCount <= 0 {
+		return fmt.Errorf("bucket_count must be positive, got %d", bc.BucketCount)
+	}
+	return nil
+}
+
+// Buckets returns the upper bounds of the exponential buckets, as prometheus.ExponentialBuckets does,
+// or an error instead of its panic if the bucket config is invalid.
+func (bc BucketConfig) Buckets() ([]float64, error) {
+	if err := bc.Validate(); err != nil {
+		return nil, err
+	}
+	buckets := make([]float64, bc.BucketCount)
+	bound := bc.BucketStart
+	for i := range buckets {
+		buckets[i] = bound
+		bound *= bc.BucketFactor
+	}
+	return buckets, nil
+}
+
 func (pc *ProcessorConfig) SSLEnabled() bool {
 	return pc.SSLCertFile != "" && pc.SSLKeyFile != ""
 }
@@ -105,6 +372,88 @@ func (pc *ProcessorConfig) LoadFromYAML(filePath string) error {
 	return nil
 }
 
+// envPrefix is the prefix of the environment variables overriding configuration values
+const envPrefix = "BATCH_PROCESSOR_"
+
+// ApplyEnvOverrides overrides configuration values with the BATCH_PROCESSOR_* environment variables that are set.
+// It is applied after LoadFromYAML, so environment variables take precedence over the YAML file.
+func (pc *ProcessorConfig) ApplyEnvOverrides() error {
+	overrides := []struct {
+		name  string
+		apply func(value string) error
+	}{
+		{"DATABASE_URL", stringOverride(&pc.DatabaseURL)},
+		{"NUM_WORKERS", intOverride(&pc.NumWorkers)},
+		{"MAX_JOB_CONCURRENCY", intOverride(&pc.MaxJobConcurrency)},
+		{"MAX_INFERENCE_CONCURRENCY", intOverride(&pc.MaxInferenceConcurrency)},
+		{"POLL_INTERVAL", durationOverride(&pc.PollInterval)},
+		{"TASK_WAIT_TIME", durationOverride(&pc.TaskWaitTime)},
+		{"WORK_DIR", stringOverride(&pc.WorkDir)},
+		{"CHECKPOINT_INTERVAL", intOverride(&pc.CheckpointInterval)},
+		{"PRESERVE_INPUT_ORDER", boolOverride(&pc.PreserveInputOrder)},
+		{"JOB_LEASE_TTL", durationOverride(&pc.JobLeaseTTL)},
+		{"JOB_RECOVERY_INTERVAL", durationOverride(&pc.JobRecoveryInterval)},
+		{"ADDR", stringOverride(&pc.Addr)},
+		{"INFERENCE_GATEWAY_URL", stringOverride(&pc.InferenceGatewayURL)},
+		{"INFERENCE_REQUEST_TIMEOUT", durationOverride(&pc.InferenceRequestTimeout)},
+		{"PER_LINE_TIMEOUT", durationOverride(&pc.PerLineTimeout)},
+		{"INFERENCE_API_KEY", stringOverride(&pc.InferenceAPIKey)},
+		{"INFERENCE_MAX_RETRIES", intOverride(&pc.InferenceMaxRetries)},
+		{"CALLBACK_SIGNING_SECRET", stringOverride(&pc.CallbackSigningSecret)},
+	}
+
+	for _, o := range overrides {
+		value, ok := os.LookupEnv(envPrefix + o.name)
+		if !ok {
+			continue
+		}
+		if err := o.apply(value); err != nil {
+			return fmt.Errorf("invalid value for %s%s: %w", envPrefix, o.name, err)
+		}
+	}
+	return nil
+}
+
+func intOverride(dst *int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func durationOverride(dst *time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*dst = d
+		return nil
+	}
+}
+
+func boolOverride(dst *bool) func(string) error {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*dst = b
+		return nil
+	}
+}
+
+func stringOverride(dst *string) func(string) error {
+	return func(value string) error {
+		*dst = value
+		return nil
+	}
+}
+
 // NewConfig returns a new ProcessorConfig with default values.
 // TaskWaitTime has to be shorter than poll interval
 func NewConfig() *ProcessorConfig {
@@ -121,21 +470,165 @@ func NewConfig() *ProcessorConfig {
 			BucketFactor: 2,
 			BucketCount:  10,
 		},
+		InferenceTimeBucket: BucketConfig{
+			BucketStart:  0.1,
+			BucketFactor: 2,
+			BucketCount:  12,
+		},
 
-		MaxJobConcurrency: 10,
-		NumWorkers:        1,
-		Addr:              ":9090",
+		MaxJobConcurrency:  10,
+		NumWorkers:         1,
+		WorkDir:            filepath.Join(os.TempDir(), "batch-processor"),
+		CheckpointInterval: 100,
+		Addr:               ":9090",
+
+		ValidationShutdownTimeout: 5 * time.Second,
+		JobLeaseTTL:               1 * time.Minute,
+		JobRecoveryInterval:       1 * time.Minute,
+
+		OutputFileTTL: 30 * 24 * time.Hour,
+
+		CallbackTimeout:        10 * time.Second,
+		CallbackMaxRetries:     3,
+		CallbackInitialBackoff: 1 * time.Second,
 
 		InferenceGatewayURL:     "http://localhost:8000",
 		InferenceRequestTimeout: 5 * time.Minute,
+		PerLineTimeout:          10 * time.Minute,
+		InferenceHTTPProtocol:   "http1",
+
+		InferenceMaxIdleConns:          100,
+		InferenceMaxIdleConnsPerHost:   100,
+		InferenceIdleConnTimeout:       90 * time.Second,
+		InferenceDialTimeout:           30 * time.Second,
+		InferenceKeepAlive:             30 * time.Second,
+		InferenceResponseHeaderTimeout: 30 * time.Second,
+
 		InferenceAPIKey:         "",
 		InferenceMaxRetries:     3,
 		InferenceInitialBackoff: 1 * time.Second,
 		InferenceMaxBackoff:     60 * time.Second,
+
+		InferenceRetryBudgetBurst: 10,
+
+		InferenceBreakerCooldown: 30 * time.Second,
 	}
 }
 
+// Validate checks the configuration values and the relationships between them.
 func (c *ProcessorConfig) Validate() error {
+	if c.NumWorkers < 1 {
+		return fmt.Errorf("num_workers must be at least 1, got %d", c.NumWorkers)
+	}
+	if c.MaxJobConcurrency < 1 {
+		return fmt.Errorf("max_job_concurrency must be at least 1, got %d", c.MaxJobConcurrency)
+	}
+	if c.MaxInferenceConcurrency < 0 {
+		return fmt.Errorf("max_inference_concurrency must not be negative, got %d", c.MaxInferenceConcurrency)
+	}
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("poll_interval must be positive, got %s", c.PollInterval)
+	}
+	if c.TaskWaitTime >= c.PollInterval {
+		return fmt.Errorf("task_wait_time (%s) must be shorter than poll_interval (%s)", c.TaskWaitTime, c.PollInterval)
+	}
+	if c.CheckpointInterval < 1 {
+		return fmt.Errorf("checkpoint_interval must be at least 1, got %d", c.CheckpointInterval)
+	}
+	if c.ValidationShutdownTimeout < 0 {
+		return fmt.Errorf("validation_shutdown_timeout must not be negative, got %s", c.ValidationShutdownTimeout)
+	}
+	if c.JobLeaseTTL < 3*time.Second {
+		return fmt.Errorf("job_lease_ttl must be at least 3s, got %s", c.JobLeaseTTL)
+	}
+	if c.JobRecoveryInterval < 0 {
+		return fmt.Errorf("job_recovery_interval must not be negative, got %s", c.JobRecoveryInterval)
+	}
+	if c.ShutdownGracePeriod < 0 {
+		return fmt.Errorf("shutdown_grace_period must not be negative, got %s", c.ShutdownGracePeriod)
+	}
+	if c.PerLineTimeout <= 0 {
+		return fmt.Errorf("per_line_timeout must be positive, got %s", c.PerLineTimeout)
+	}
+	if c.PerLineTimeout < c.InferenceRequestTimeout {
+		return fmt.Errorf("per_line_timeout (%s) must not be shorter than inference_request_timeout (%s)", c.PerLineTimeout, c.InferenceRequestTimeout)
+	}
+	if c.LateResponseGracePeriod < 0 {
+		return fmt.Errorf("late_response_grace_period must not be negative, got %s", c.LateResponseGracePeriod)
+	}
+	if c.InferenceMaxIdleConns < 0 || c.InferenceMaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("inference_max_idle_conns and inference_max_idle_conns_per_host must not be negative, got %d and %d",
+			c.InferenceMaxIdleConns, c.InferenceMaxIdleConnsPerHost)
+	}
+	if c.InferenceIdleConnTimeout < 0 || c.InferenceDialTimeout < 0 || c.InferenceResponseHeaderTimeout < 0 {
+		return fmt.Errorf("inference_idle_conn_timeout, inference_dial_timeout and inference_response_header_timeout must not be negative")
+	}
+	if c.InferenceRetryBudgetRate < 0 {
+		return fmt.Errorf("inference_retry_budget_rate must not be negative, got %g", c.InferenceRetryBudgetRate)
+	}
+	if c.InferenceRetryBudgetRate > 0 && c.InferenceRetryBudgetBurst < 1 {
+		return fmt.Errorf("inference_retry_budget_burst must be at least 1 when the retry budget is enabled, got %d", c.InferenceRetryBudgetBurst)
+	}
+	if c.InferenceBreakerThreshold < 0 {
+		return fmt.Errorf("inference_breaker_threshold must not be negative, got %d", c.InferenceBreakerThreshold)
+	}
+	if c.InferenceBreakerThreshold > 0 && c.InferenceBreakerCooldown <= 0 {
+		return fmt.Errorf("inference_breaker_cooldown must be positive when the circuit breaker is enabled, got %s", c.InferenceBreakerCooldown)
+	}
+	if c.OutputFileTTL < time.Second {
+		return fmt.Errorf("output_file_ttl must be at least 1s, got %s", c.OutputFileTTL)
+	}
+	if c.CallbackTimeout <= 0 {
+		return fmt.Errorf("callback_timeout must be positive, got %s", c.CallbackTimeout)
+	}
+	if c.CallbackMaxRetries < 0 {
+		return fmt.Errorf("callback_max_retries must not be negative, got %d", c.CallbackMaxRetries)
+	}
+	if c.CallbackInitialBackoff < 0 {
+		return fmt.Errorf("callback_initial_backoff must not be negative, got %s", c.CallbackInitialBackoff)
+	}
+	if err := c.QueueTimeBucket.Validate(); err != nil {
+		return fmt.Errorf("invalid queue_time_bucket: %w", err)
+	}
+	if err := c.ProcessTimeBucket.Validate(); err != nil {
+		return fmt.Errorf("invalid process_time_bucket: %w", err)
+	}
+	if err := c.InferenceTimeBucket.Validate(); err != nil {
+		return fmt.Errorf("invalid inference_time_bucket: %w", err)
+	}
+
+	reserved := 0
+	for tenantID, n := range c.WorkerReservations {
+		if n <= 0 {
+			return fmt.Errorf("worker reservation for tenant %q must be positive", tenantID)
+		}
+		reserved += n
+	}
+	if reserved > c.NumWorkers {
+		return fmt.Errorf("total worker reservations (%d) exceed the number of workers (%d)", reserved, c.NumWorkers)
+	}
+
+	for model, target := range c.ModelAliases {
+		if model == "" || target == "" {
+			return fmt.Errorf("model alias %q -> %q must map a model name to a model name", model, target)
+		}
+	}
+
+	for i, rule := range c.RequestTransforms {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("invalid request_transforms[%d]: %w", i, err)
+		}
+	}
+	for i, rule := range c.ResponseTransforms {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("invalid response_transforms[%d]: %w", i, err)
+		}
+	}
+
+	if err := c.Tracing.Validate(); err != nil {
+		return fmt.Errorf("invalid tracing: %w", err)
+	}
+
 	if c.SSLEnabled() {
 		if _, err := os.Stat(c.SSLCertFile); err != nil {
 			return err
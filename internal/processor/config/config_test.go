@@ -0,0 +1,252 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the processor configuration.
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProcessorConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *ProcessorConfig)
+		wantErr bool
+	}{
+		{name: "defaults", modify: func(c *ProcessorConfig) {}, wantErr: false},
+		{name: "zero workers", modify: func(c *ProcessorConfig) { c.NumWorkers = 0 }, wantErr: true},
+		{name: "zero job concurrency", modify: func(c *ProcessorConfig) { c.MaxJobConcurrency = 0 }, wantErr: true},
+		{name: "uncapped inference concurrency", modify: func(c *ProcessorConfig) { c.MaxInferenceConcurrency = 0 }, wantErr: false},
+		{name: "negative inference concurrency", modify: func(c *ProcessorConfig) { c.MaxInferenceConcurrency = -1 }, wantErr: true},
+		{name: "zero poll interval", modify: func(c *ProcessorConfig) { c.PollInterval = 0; c.TaskWaitTime = 0 }, wantErr: true},
+		{name: "task wait time equal to poll interval", modify: func(c *ProcessorConfig) { c.TaskWaitTime = c.PollInterval }, wantErr: true},
+		{name: "task wait time longer than poll interval", modify: func(c *ProcessorConfig) { c.TaskWaitTime = c.PollInterval + time.Second }, wantErr: true},
+		{name: "zero checkpoint interval", modify: func(c *ProcessorConfig) { c.CheckpointInterval = 0 }, wantErr: true},
+		{name: "zero per-line timeout", modify: func(c *ProcessorConfig) { c.PerLineTimeout = 0 }, wantErr: true},
+		{name: "per-line timeout shorter than request timeout", modify: func(c *ProcessorConfig) { c.PerLineTimeout = c.InferenceRequestTimeout - time.Second }, wantErr: true},
+		{name: "zero output file ttl", modify: func(c *ProcessorConfig) { c.OutputFileTTL = 0 }, wantErr: true},
+		{name: "zero callback timeout", modify: func(c *ProcessorConfig) { c.CallbackTimeout = 0 }, wantErr: true},
+		{name: "negative callback retries", modify: func(c *ProcessorConfig) { c.CallbackMaxRetries = -1 }, wantErr: true},
+		{name: "negative grace period", modify: func(c *ProcessorConfig) { c.LateResponseGracePeriod = -time.Second }, wantErr: true},
+		{name: "negative idle connections", modify: func(c *ProcessorConfig) { c.InferenceMaxIdleConnsPerHost = -1 }, wantErr: true},
+		{name: "negative dial timeout", modify: func(c *ProcessorConfig) { c.InferenceDialTimeout = -time.Second }, wantErr: true},
+		{name: "negative breaker threshold", modify: func(c *ProcessorConfig) { c.InferenceBreakerThreshold = -1 }, wantErr: true},
+		{name: "breaker without cooldown", modify: func(c *ProcessorConfig) { c.InferenceBreakerThreshold = 5; c.InferenceBreakerCooldown = 0 }, wantErr: true},
+		{name: "breaker enabled", modify: func(c *ProcessorConfig) { c.InferenceBreakerThreshold = 5 }, wantErr: false},
+		{name: "negative retry budget rate", modify: func(c *ProcessorConfig) { c.InferenceRetryBudgetRate = -1 }, wantErr: true},
+		{name: "retry budget without burst", modify: func(c *ProcessorConfig) { c.InferenceRetryBudgetRate = 5; c.InferenceRetryBudgetBurst = 0 }, wantErr: true},
+		{name: "retry budget enabled", modify: func(c *ProcessorConfig) { c.InferenceRetryBudgetRate = 5 }, wantErr: false},
+		{
+			name: "request transform",
+			modify: func(c *ProcessorConfig) {
+				c.RequestTransforms = []RequestTransform{{Endpoint: "/v1/chat/completions", Rename: map[string]string{"max_tokens": "max_completion_tokens"}}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "request transform of unknown endpoint",
+			modify: func(c *ProcessorConfig) {
+				c.RequestTransforms = []RequestTransform{{Endpoint: "/v1/images", Rename: map[string]string{"a": "b"}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "request transform renaming the model",
+			modify: func(c *ProcessorConfig) {
+				c.RequestTransforms = []RequestTransform{{Rename: map[string]string{"model": "model_name"}}}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "empty request transform",
+			modify:  func(c *ProcessorConfig) { c.RequestTransforms = []RequestTransform{{}} },
+			wantErr: true,
+		},
+		{
+			name: "response transform",
+			modify: func(c *ProcessorConfig) {
+				c.ResponseTransforms = []ResponseTransform{{Move: map[string]string{"output.text": "choices.0.message.content"}, Drop: []string{"output"}}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "response transform with an empty path segment",
+			modify: func(c *ProcessorConfig) {
+				c.ResponseTransforms = []ResponseTransform{{Defaults: map[string]interface{}{"choices..index": 0}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty response transform",
+			modify: func(c *ProcessorConfig) {
+				c.ResponseTransforms = []ResponseTransform{{Endpoint: "/v1/chat/completions"}}
+			},
+			wantErr: true,
+		},
+		{name: "disabled keep-alives", modify: func(c *ProcessorConfig) { c.InferenceKeepAlive = -1 }, wantErr: false},
+		{name: "negative validation shutdown timeout", modify: func(c *ProcessorConfig) { c.ValidationShutdownTimeout = -time.Second }, wantErr: true},
+		{name: "job lease ttl too short", modify: func(c *ProcessorConfig) { c.JobLeaseTTL = time.Second }, wantErr: true},
+		{name: "negative job recovery interval", modify: func(c *ProcessorConfig) { c.JobRecoveryInterval = -time.Second }, wantErr: true},
+		{name: "queue bucket start not positive", modify: func(c *ProcessorConfig) { c.QueueTimeBucket.BucketStart = 0 }, wantErr: true},
+		{name: "queue bucket factor not greater than 1", modify: func(c *ProcessorConfig) { c.QueueTimeBucket.BucketFactor = 1 }, wantErr: true},
+		{name: "queue bucket count not positive", modify: func(c *ProcessorConfig) { c.QueueTimeBucket.BucketCount = 0 }, wantErr: true},
+		{name: "process bucket factor not greater than 1", modify: func(c *ProcessorConfig) { c.ProcessTimeBucket.BucketFactor = 0.5 }, wantErr: true},
+		{name: "process bucket count not positive", modify: func(c *ProcessorConfig) { c.ProcessTimeBucket.BucketCount = -1 }, wantErr: true},
+		{name: "inference bucket start not positive", modify: func(c *ProcessorConfig) { c.InferenceTimeBucket.BucketStart = 0 }, wantErr: true},
+		{name: "valid worker reservations", modify: func(c *ProcessorConfig) {
+			c.NumWorkers = 4
+			c.WorkerReservations = map[string]int{"a": 2, "b": 2}
+		}, wantErr: false},
+		{name: "worker reservations exceed workers", modify: func(c *ProcessorConfig) {
+			c.NumWorkers = 2
+			c.WorkerReservations = map[string]int{"a": 2, "b": 1}
+		}, wantErr: true},
+		{name: "worker reservation not positive", modify: func(c *ProcessorConfig) { c.WorkerReservations = map[string]int{"a": 0} }, wantErr: true},
+		{name: "valid model aliases", modify: func(c *ProcessorConfig) { c.ModelAliases = map[string]string{"gpt-4": "internal-llama-70b"} }, wantErr: false},
+		{name: "model alias without target", modify: func(c *ProcessorConfig) { c.ModelAliases = map[string]string{"gpt-4": ""} }, wantErr: true},
+		{name: "missing ssl files", modify: func(c *ProcessorConfig) {
+			c.SSLCertFile = "/nonexistent/cert.pem"
+			c.SSLKeyFile = "/nonexistent/key.pem"
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessorConfigEnvOverrides(t *testing.T) {
+	yamlConfig := `
+num_workers: 4
+poll_interval: 10s
+addr: ":9191"
+`
+
+	loadConfig := func(t *testing.T) *ProcessorConfig {
+		t.Helper()
+
+		configFile := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configFile, []byte(yamlConfig), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+		cfg := NewConfig()
+		if err := cfg.LoadFromYAML(configFile); err != nil {
+			t.Fatalf("LoadFromYAML() error = %v", err)
+		}
+		return cfg
+	}
+
+	t.Run("Override", func(t *testing.T) {
+		t.Setenv("BATCH_PROCESSOR_NUM_WORKERS", "8")
+		t.Setenv("BATCH_PROCESSOR_POLL_INTERVAL", "30s")
+		t.Setenv("BATCH_PROCESSOR_ADDR", ":9292")
+		t.Setenv("BATCH_PROCESSOR_INFERENCE_API_KEY", "secret")
+		t.Setenv("BATCH_PROCESSOR_PRESERVE_INPUT_ORDER", "true")
+
+		cfg := loadConfig(t)
+		if err := cfg.ApplyEnvOverrides(); err != nil {
+			t.Fatalf("ApplyEnvOverrides() error = %v", err)
+		}
+
+		if cfg.NumWorkers != 8 {
+			t.Errorf("NumWorkers = %v, want %v", cfg.NumWorkers, 8)
+		}
+		if cfg.PollInterval != 30*time.Second {
+			t.Errorf("PollInterval = %v, want %v", cfg.PollInterval, 30*time.Second)
+		}
+		if cfg.Addr != ":9292" {
+			t.Errorf("Addr = %v, want %v", cfg.Addr, ":9292")
+		}
+		if cfg.InferenceAPIKey != "secret" {
+			t.Errorf("InferenceAPIKey = %v, want %v", cfg.InferenceAPIKey, "secret")
+		}
+		if !cfg.PreserveInputOrder {
+			t.Errorf("PreserveInputOrder = %v, want %v", cfg.PreserveInputOrder, true)
+		}
+	})
+
+	t.Run("MalformedValue", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			value string
+		}{
+			{name: "BATCH_PROCESSOR_NUM_WORKERS", value: "many"},
+			{name: "BATCH_PROCESSOR_POLL_INTERVAL", value: "10"},
+			{name: "BATCH_PROCESSOR_INFERENCE_REQUEST_TIMEOUT", value: "soon"},
+			{name: "BATCH_PROCESSOR_PRESERVE_INPUT_ORDER", value: "maybe"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				t.Setenv(tt.name, tt.value)
+
+				cfg := loadConfig(t)
+				if err := cfg.ApplyEnvOverrides(); err == nil {
+					t.Errorf("ApplyEnvOverrides() expected error for %s=%q, got nil", tt.name, tt.value)
+				}
+			})
+		}
+	})
+
+	t.Run("Absent", func(t *testing.T) {
+		cfg := loadConfig(t)
+		if err := cfg.ApplyEnvOverrides(); err != nil {
+			t.Fatalf("ApplyEnvOverrides() error = %v", err)
+		}
+
+		if cfg.NumWorkers != 4 {
+			t.Errorf("NumWorkers = %v, want %v", cfg.NumWorkers, 4)
+		}
+		if cfg.PollInterval != 10*time.Second {
+			t.Errorf("PollInterval = %v, want %v", cfg.PollInterval, 10*time.Second)
+		}
+		if cfg.Addr != ":9191" {
+			t.Errorf("Addr = %v, want %v", cfg.Addr, ":9191")
+		}
+	})
+}
+
+func TestBucketConfigBuckets(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		buckets, err := BucketConfig{BucketStart: 0.5, BucketFactor: 2, BucketCount: 4}.Buckets()
+		if err != nil {
+			t.Fatalf("Buckets() error = %v", err)
+		}
+		want := []float64{0.5, 1, 2, 4}
+		if !slices.Equal(buckets, want) {
+			t.Errorf("Buckets() = %v, want %v", buckets, want)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := (BucketConfig{BucketStart: 1, BucketFactor: 1, BucketCount: 4}).Buckets(); err == nil {
+			t.Errorf("Buckets() expected error for a factor of 1, got nil")
+		}
+	})
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the readiness check of the processor.
+// Unlike the liveness check, readiness reflects whether the processor can reach the clients it depends on.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+const (
+	ReadinessPath = "/readyz"
+
+	// DefaultReadinessTimeout is the time limit of checking each dependency
+	DefaultReadinessTimeout = 2 * time.Second
+
+	// readinessProbeID is the ID of the job looked up to check the database is reachable
+	readinessProbeID = "readiness-probe"
+)
+
+// Dependency is a client the processor depends on to be ready.
+type Dependency struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// DBDependency checks that the batch jobs database is reachable.
+func DBDependency(client db.BatchDBClient) Dependency {
+	return Dependency{
+		Name: "database",
+		Check: func(ctx context.Context) error {
+			_, _, err := client.Get(ctx, []string{readinessProbeID}, nil, db.TagsLogicalCondNa, false, 0, 1)
+			return err
+		},
+	}
+}
+
+// QueueDependency checks that the priority queue is reachable.
+func QueueDependency(client db.BatchPriorityQueueClient) Dependency {
+	return Dependency{
+		Name: "priority_queue",
+		Check: func(ctx context.Context) error {
+			_, err := client.Len(ctx)
+			return err
+		},
+	}
+}
+
+// InferenceDependency checks that the inference gateway is reachable.
+func InferenceDependency(client inference.Pinger) Dependency {
+	return Dependency{
+		Name:  "inference",
+		Check: client.Ping,
+	}
+}
+
+// DependencyStatus is the status of an unhealthy dependency.
+type DependencyStatus struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// ReadinessResponse is the body of the readiness check response.
+type ReadinessResponse struct {
+	Status    string             `json:"status"`
+	Unhealthy []DependencyStatus `json:"unhealthy,omitempty"`
+}
+
+// ReadinessHandler checks the dependencies of the processor concurrently.
+// It returns 200 when all of them are reachable and 503 listing the unhealthy ones otherwise.
+type ReadinessHandler struct {
+	timeout      time.Duration
+	dependencies []Dependency
+}
+
+func NewReadinessHandler(timeout time.Duration, dependencies ...Dependency) *ReadinessHandler {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+	return &ReadinessHandler{
+		timeout:      timeout,
+		dependencies: dependencies,
+	}
+}
+
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	// a check that doesn't return in time is reported as unhealthy without waiting for it
+	results := make([]error, len(h.dependencies))
+	done := make([]chan struct{}, len(h.dependencies))
+	for i, dep := range h.dependencies {
+		done[i] = make(chan struct{})
+		go func() {
+			defer close(done[i])
+			results[i] = dep.Check(ctx)
+		}()
+	}
+
+	resp := ReadinessResponse{Status: "ready"}
+	for i, dep := range h.dependencies {
+		var err error
+		select {
+		case <-done[i]:
+			err = results[i]
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err == nil {
+			continue
+		}
+		klog.FromContext(r.Context()).V(logging.WARNING).Info("Dependency is not ready", "dependency", dep.Name, "err", err)
+		resp.Unhealthy = append(resp.Unhealthy, DependencyStatus{Name: dep.Name, Error: err.Error()})
+	}
+
+	status := http.StatusOK
+	if len(resp.Unhealthy) > 0 {
+		resp.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
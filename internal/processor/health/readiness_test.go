@@ -0,0 +1,87 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the readiness check.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockapi "github.com/llm-d-incubation/batch-gateway/internal/database/mock"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	healthy := []Dependency{
+		DBDependency(mockapi.NewMockBatchDBClient()),
+		QueueDependency(mockapi.NewMockBatchPriorityQueueClient()),
+	}
+	failing := Dependency{
+		Name:  "inference",
+		Check: func(ctx context.Context) error { return errors.New("connection refused") },
+	}
+	hanging := Dependency{
+		Name: "inference",
+		Check: func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name          string
+		dependencies  []Dependency
+		wantStatus    int
+		wantUnhealthy []string
+	}{
+		{name: "all dependencies healthy", dependencies: healthy, wantStatus: http.StatusOK},
+		{name: "no dependencies", wantStatus: http.StatusOK},
+		{name: "failing dependency", dependencies: append(healthy, failing), wantStatus: http.StatusServiceUnavailable, wantUnhealthy: []string{"inference"}},
+		{name: "dependency timing out", dependencies: append(healthy, hanging), wantStatus: http.StatusServiceUnavailable, wantUnhealthy: []string{"inference"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewReadinessHandler(50*time.Millisecond, tt.dependencies...)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected Content-Type application/json, got %q", ct)
+			}
+			var resp ReadinessResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Unhealthy) != len(tt.wantUnhealthy) {
+				t.Fatalf("expected unhealthy %v, got %+v", tt.wantUnhealthy, resp.Unhealthy)
+			}
+			for i, name := range tt.wantUnhealthy {
+				if resp.Unhealthy[i].Name != name || resp.Unhealthy[i].Error == "" {
+					t.Errorf("expected unhealthy dependency %q with an error, got %+v", name, resp.Unhealthy[i])
+				}
+			}
+		})
+	}
+}
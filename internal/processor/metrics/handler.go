@@ -24,6 +24,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// NewMetricsHandler returns the handler serving the metrics registered by InitMetrics.
 func NewMetricsHandler() http.Handler {
-	return promhttp.Handler()
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
 }
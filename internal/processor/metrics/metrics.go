@@ -17,22 +17,35 @@ limitations under the License.
 package metrics
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 // labels definition
 const (
 	// result labels
-	ResultSuccess = "success"
-	ResultFailed  = "failed"
+	ResultSuccess   = "success"
+	ResultFailed    = "failed"
+	ResultCancelled = "cancelled"
 
 	// reason lables
 	ReasonUnknown     = "unknown"
 	ReasonUserError   = "user_error"   // method, request validation failed.. etc.,
-	ReasonSystemError = "system_error" // SLO failed, system error.. etc.,
+	ReasonSystemError = "system_error" // system error, e.g. the output files can't be stored. missed SLOs are counted by batchSLO
+	ReasonTimeout     = "timeout"      // a deadline was exceeded, e.g. requests timed out or a call to the storage didn't complete in time
+
+	// SLO labels, whether a batch was finalized before it expired
+	SLOMet    = "met"
+	SLOMissed = "missed"
+
+	// callback delivery result labels
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
 
 	// size bucket labels
 	Bucket100   = "100"   // less than 100 lines
@@ -58,21 +71,64 @@ func GetSizeBucket(totalLines int) string {
 }
 
 var (
+	// registry holds the metrics of the processor, served by NewMetricsHandler
+	registry *prometheus.Registry
+
 	jobsProcessed         *prometheus.CounterVec
 	jobProcessingDuration *prometheus.HistogramVec
 	jobQueueWaitDuration  *prometheus.HistogramVec
+	inferenceCallDuration *prometheus.HistogramVec
 	totalWorkers          prometheus.Gauge
 	activeWorkers         prometheus.Gauge
+	workersInUse          *prometheus.GaugeVec
+	queueDepth            prometheus.Gauge
+	drainingJobs          prometheus.Gauge
 	jobErrorsModelTotal   *prometheus.CounterVec
+	lineTimeouts          *prometheus.CounterVec
+	inferenceRetries      *prometheus.CounterVec
+	retryBudgetExhausted  *prometheus.CounterVec
+	callbackDeliveries    *prometheus.CounterVec
+	jobsDeadLettered      *prometheus.CounterVec
+	batchSLO              *prometheus.CounterVec
+	inferenceConnections  *prometheus.CounterVec
+	inferenceBreakerState prometheus.Gauge
+
+	// tenantLabelDisabled records all tenants under an empty tenantID label value
+	tenantLabelDisabled bool
 )
 
-func InitMetrics(cfg config.ProcessorConfig) error {
-	// number of jobs processed : TODO:: add tenantID?
+// InitMetrics creates the metrics of the processor and registers them in the registry.
+// If registry is nil, a new registry is created. The Go runtime and process metrics are registered too.
+func InitMetrics(cfg config.ProcessorConfig, reg *prometheus.Registry) error {
+	// invalid buckets are reported before any metric is replaced
+	processTimeBuckets, err := cfg.ProcessTimeBucket.Buckets()
+	if err != nil {
+		return fmt.Errorf("invalid process_time_bucket: %w", err)
+	}
+	queueTimeBuckets, err := cfg.QueueTimeBucket.Buckets()
+	if err != nil {
+		return fmt.Errorf("invalid queue_time_bucket: %w", err)
+	}
+	inferenceTimeBuckets, err := cfg.InferenceTimeBucket.Buckets()
+	if err != nil {
+		return fmt.Errorf("invalid inference_time_bucket: %w", err)
+	}
+
+	if reg == nil {
+		reg = prometheus.NewRegistry()
+	}
+	registry = reg
+
+	// the tenantID label has one value per tenant. with many tenants, the number of series grows accordingly,
+	// so the label can be disabled with DisableMetricsTenantLabel
+	tenantLabelDisabled = cfg.DisableMetricsTenantLabel
+
+	// number of jobs processed
 	jobsProcessed = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "jobs_processed_total",
 			Help: "Total number of jobs processed",
-		}, []string{"result", "reason"},
+		}, []string{"result", "reason", "tenantID"},
 	)
 
 	// total number of workers for utilization %
@@ -93,11 +149,62 @@ func InitMetrics(cfg config.ProcessorConfig) error {
 		},
 	)
 
+	// current number of workers in use, by the part of the pool they are leased from
+	workersInUse = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "workers_in_use",
+			Help: "Current number of workers in use by pool (reserved, shared, borrowed)",
+		}, []string{"pool"},
+	)
+
+	// number of jobs queued but not started yet
+	queueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "queue_depth",
+			Help: "Current number of jobs waiting in the priority queue to be started",
+		},
+	)
+
+	// number of jobs still processed while the processor shuts down
+	drainingJobs = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "draining_jobs",
+			Help: "Current number of jobs the processor waits for to finish while shutting down",
+		},
+	)
+
 	// errors by model
 	jobErrorsModelTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "job_errors_by_model_total",
-			Help: "Total number of job processing errors by model",
+			Help: "Total number of request lines failed by a system error, by model",
+		},
+		[]string{"model"},
+	)
+
+	// request lines failed by a timeout, by the code of their error: the per-line timeout or the expiry of the batch
+	lineTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "line_timeouts_total",
+			Help: "Total number of request lines failed by a timeout, by model and error code",
+		},
+		[]string{"model", "code"},
+	)
+
+	// retries of failed inference calls, a flaky upstream retries and recovers while a broken one keeps failing
+	inferenceRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inference_retries_total",
+			Help: "Total number of inference call retries by model and error category",
+		},
+		[]string{"model", "category"},
+	)
+
+	// failed inference calls not retried because the retry budget was spent, during an upstream brownout
+	retryBudgetExhausted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inference_retry_budget_exhausted_total",
+			Help: "Total number of failed inference calls not retried because the retry budget was exhausted, by model",
 		},
 		[]string{"model"},
 	)
@@ -105,44 +212,96 @@ func InitMetrics(cfg config.ProcessorConfig) error {
 	// job processing duratino
 	jobProcessingDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name: "job_processing_duration_seconds",
-			Help: "Duration of job processing in seconds",
-			Buckets: prometheus.ExponentialBuckets(
-				cfg.ProcessTimeBucket.BucketStart,
-				cfg.ProcessTimeBucket.BucketFactor,
-				cfg.ProcessTimeBucket.BucketCount,
-			),
+			Name:    "job_processing_duration_seconds",
+			Help:    "Duration of job processing in seconds",
+			Buckets: processTimeBuckets,
 		}, []string{"tenantID", "size_bucket"},
 	)
 
 	// duration of queue wait time
 	jobQueueWaitDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name: "job_queue_wait_duration",
-			Help: "Time spent in the priority queue before being picked up",
-			Buckets: prometheus.ExponentialBuckets(
-				cfg.QueueTimeBucket.BucketStart,
-				cfg.QueueTimeBucket.BucketFactor,
-				cfg.QueueTimeBucket.BucketCount,
-			),
+			Name:    "job_queue_wait_duration",
+			Help:    "Time spent in the priority queue before being picked up",
+			Buckets: queueTimeBuckets,
 		}, []string{"tenantID"},
 	)
 
+	// callbacks posted when batches reach a final status
+	callbackDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "callback_deliveries_total",
+			Help: "Total number of batch completion callbacks by delivery result (delivered, failed)",
+		}, []string{"result"},
+	)
+
+	// batches failing permanently, copied to the dead-letter table
+	jobsDeadLettered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jobs_dead_lettered_total",
+			Help: "Total number of failed batches copied to the dead-letter table by failure code",
+		}, []string{"code"},
+	)
+
+	// batches finalized before or after they expired, to measure whether the completion windows are met
+	batchSLO = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "batch_slo_met_total",
+			Help: "Total number of completed or failed batches by whether they were finalized before they expired (met, missed)",
+		}, []string{"slo", "tenantID"},
+	)
+
+	// connections got by the inference requests, to check that the pooled connections are reused
+	inferenceConnections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "inference_connections_total",
+			Help: "Total number of connections got by inference request attempts, by whether an idle connection was reused",
+		}, []string{"reused"},
+	)
+
+	// state of the circuit breaker around the inference client
+	inferenceBreakerState = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "inference_circuit_breaker_state",
+			Help: "Current state of the inference circuit breaker (0 closed, 1 open, 2 half-open)",
+		},
+	)
+
+	// duration of individual inference calls, to tell the model latency apart from the processing overhead
+	inferenceCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "inference_call_duration_seconds",
+			Help:    "Duration of individual inference calls to the inference gateway in seconds",
+			Buckets: inferenceTimeBuckets,
+		}, []string{"model", "endpoint"},
+	)
+
 	// metrics to register
 	metricsToRegister := []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		jobProcessingDuration,
 		jobQueueWaitDuration,
+		inferenceCallDuration,
 		totalWorkers,
 		activeWorkers,
+		workersInUse,
+		queueDepth,
+		drainingJobs,
 		jobsProcessed,
 		jobErrorsModelTotal,
+		lineTimeouts,
+		inferenceRetries,
+		retryBudgetExhausted,
+		callbackDeliveries,
+		jobsDeadLettered,
+		batchSLO,
+		inferenceConnections,
+		inferenceBreakerState,
 	}
 
 	for _, metric := range metricsToRegister {
-		if err := prometheus.Register(metric); err != nil {
-			if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
-				continue
-			}
+		if err := registry.Register(metric); err != nil {
 			return err
 		}
 	}
@@ -150,21 +309,34 @@ func InitMetrics(cfg config.ProcessorConfig) error {
 	return nil
 }
 
+// tenantLabel returns the value of the tenantID label for the tenant.
+func tenantLabel(tenantID string) string {
+	if tenantLabelDisabled {
+		return ""
+	}
+	return tenantID
+}
+
 // Recorder funcs
 
 // RecordQueueWait observes the queue time
 func RecordQueueWaitDuration(duration time.Duration, tenantID string) {
-	jobQueueWaitDuration.WithLabelValues(tenantID).Observe(duration.Seconds())
+	jobQueueWaitDuration.WithLabelValues(tenantLabel(tenantID)).Observe(duration.Seconds())
 }
 
 // RecordJobProcessed increments the total processed jobs count.
-func RecordJobProcessed(result string, reason string) {
-	jobsProcessed.WithLabelValues(result, reason).Inc()
+func RecordJobProcessed(result string, reason string, tenantID string) {
+	jobsProcessed.WithLabelValues(result, reason, tenantLabel(tenantID)).Inc()
 }
 
 // RecordJobProcessingDuration observes the time taken to process a job.
 func RecordJobProcessingDuration(duration time.Duration, tenantID string, sizeBucket string) {
-	jobProcessingDuration.WithLabelValues(tenantID, sizeBucket).Observe(duration.Seconds())
+	jobProcessingDuration.WithLabelValues(tenantLabel(tenantID), sizeBucket).Observe(duration.Seconds())
+}
+
+// RecordInferenceCallDuration observes the time taken by an inference call.
+func RecordInferenceCallDuration(duration time.Duration, model string, endpoint string) {
+	inferenceCallDuration.WithLabelValues(model, endpoint).Observe(duration.Seconds())
 }
 
 // IncActiveWorkers increments the gauge for active workers.
@@ -177,7 +349,66 @@ func DecActiveWorkers() {
 	activeWorkers.Dec()
 }
 
-// RecordJobError increments the error count for a specific model.
+// SetWorkersInUse sets the number of workers in use for a part of the pool.
+func SetWorkersInUse(pool string, count int) {
+	workersInUse.WithLabelValues(pool).Set(float64(count))
+}
+
+// RecordQueueDepth sets the number of jobs waiting in the queue.
+func RecordQueueDepth(n int) {
+	queueDepth.Set(float64(n))
+}
+
+// SetDrainingJobs sets the number of jobs the processor waits for while shutting down.
+func SetDrainingJobs(n int) {
+	drainingJobs.Set(float64(n))
+}
+
+// RecordInferenceRetry increments the retry count for a model and error category.
+func RecordInferenceRetry(model string, category string) {
+	inferenceRetries.WithLabelValues(model, category).Inc()
+}
+
+// RecordRetryBudgetExhausted increments the count of failed requests of a model not retried for lack of retry budget.
+func RecordRetryBudgetExhausted(model string) {
+	retryBudgetExhausted.WithLabelValues(model).Inc()
+}
+
+// RecordJobError increments the error count for a specific model, once per request line failed by a system error.
 func RecordJobError(model string) {
 	jobErrorsModelTotal.WithLabelValues(model).Inc()
 }
+
+// RecordLineTimeout increments the count of request lines of a model failed by a timeout with the error code.
+func RecordLineTimeout(model string, code string) {
+	lineTimeouts.WithLabelValues(model, code).Inc()
+}
+
+// RecordCallbackDelivery increments the callback delivery count for a result.
+func RecordCallbackDelivery(result string) {
+	callbackDeliveries.WithLabelValues(result).Inc()
+}
+
+// RecordJobDeadLettered increments the count of batches copied to the dead-letter table for a failure code.
+func RecordJobDeadLettered(code string) {
+	jobsDeadLettered.WithLabelValues(code).Inc()
+}
+
+// RecordBatchSLO increments the count of batches finalized before their expiry when met is true, or after it.
+func RecordBatchSLO(met bool, tenantID string) {
+	slo := SLOMissed
+	if met {
+		slo = SLOMet
+	}
+	batchSLO.WithLabelValues(slo, tenantLabel(tenantID)).Inc()
+}
+
+// RecordInferenceConnection increments the count of connections got by inference requests, reused or new.
+func RecordInferenceConnection(reused bool) {
+	inferenceConnections.WithLabelValues(strconv.FormatBool(reused)).Inc()
+}
+
+// SetInferenceBreakerState sets the state of the inference circuit breaker: 0 closed, 1 open, 2 half-open.
+func SetInferenceBreakerState(state int) {
+	inferenceBreakerState.Set(float64(state))
+}
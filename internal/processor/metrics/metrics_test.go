@@ -0,0 +1,115 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the processor metrics.
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordJobProcessed(t *testing.T) {
+	tests := []struct {
+		name          string
+		disableTenant bool
+		wantTenant    string
+	}{
+		{name: "tenant label", disableTenant: false, wantTenant: "tenant-a"},
+		{name: "tenant label disabled", disableTenant: true, wantTenant: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig()
+			cfg.DisableMetricsTenantLabel = tt.disableTenant
+			if err := InitMetrics(*cfg, nil); err != nil {
+				t.Fatalf("Failed to init metrics: %v", err)
+			}
+
+			RecordJobProcessed(ResultSuccess, ReasonUnknown, "tenant-a")
+			RecordJobProcessed(ResultSuccess, ReasonUnknown, "tenant-a")
+
+			if got := testutil.ToFloat64(jobsProcessed.WithLabelValues(ResultSuccess, ReasonUnknown, tt.wantTenant)); got != 2 {
+				t.Errorf("Expected 2 jobs processed for tenantID %q, got %v", tt.wantTenant, got)
+			}
+			if got := testutil.CollectAndCount(jobsProcessed); got != 1 {
+				t.Errorf("Expected 1 series, got %d", got)
+			}
+		})
+	}
+}
+
+func TestInitMetricsInvalidBuckets(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *config.ProcessorConfig)
+	}{
+		{name: "factor not greater than 1", modify: func(c *config.ProcessorConfig) { c.ProcessTimeBucket.BucketFactor = 1 }},
+		{name: "count not positive", modify: func(c *config.ProcessorConfig) { c.QueueTimeBucket.BucketCount = 0 }},
+		{name: "start not positive", modify: func(c *config.ProcessorConfig) { c.InferenceTimeBucket.BucketStart = 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewConfig()
+			tt.modify(cfg)
+
+			// an invalid bucket config returns an error instead of panicking in prometheus.ExponentialBuckets
+			if err := InitMetrics(*cfg, nil); err == nil {
+				t.Errorf("Expected an error for an invalid bucket config")
+			}
+		})
+	}
+}
+
+func TestRecordQueueDepth(t *testing.T) {
+	if err := InitMetrics(*config.NewConfig(), nil); err != nil {
+		t.Fatalf("Failed to init metrics: %v", err)
+	}
+
+	for _, n := range []int{3, 0} {
+		RecordQueueDepth(n)
+		if got := testutil.ToFloat64(queueDepth); got != float64(n) {
+			t.Errorf("Expected queue depth %d, got %v", n, got)
+		}
+	}
+}
+
+func TestNewMetricsHandler(t *testing.T) {
+	// each processor instance registers its metrics in its own registry
+	for i := 0; i < 2; i++ {
+		if err := InitMetrics(*config.NewConfig(), nil); err != nil {
+			t.Fatalf("Failed to init metrics: %v", err)
+		}
+	}
+	RecordQueueDepth(4)
+
+	rr := httptest.NewRecorder()
+	NewMetricsHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "queue_depth 4") {
+		t.Errorf("Expected queue_depth in metrics, got %s", body)
+	}
+}
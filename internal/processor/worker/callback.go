@@ -0,0 +1,151 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the delivery of the batch callbacks, notifying users when their batch reaches a final status.
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/metrics"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+const (
+	// CallbackSignatureHeader carries the HMAC-SHA256 signature of the callback body, as "sha256=<hex>".
+	CallbackSignatureHeader = "X-Batch-Signature"
+)
+
+// callbackNotifier posts the final Batch object of a job to its callback URL.
+type callbackNotifier struct {
+	client         *http.Client
+	secret         []byte
+	maxRetries     int
+	initialBackoff time.Duration
+}
+
+func newCallbackNotifier(cfg *config.ProcessorConfig) *callbackNotifier {
+	return &callbackNotifier{
+		client:         &http.Client{Timeout: cfg.CallbackTimeout},
+		secret:         []byte(cfg.CallbackSigningSecret),
+		maxRetries:     cfg.CallbackMaxRetries,
+		initialBackoff: cfg.CallbackInitialBackoff,
+	}
+}
+
+// SignCallback returns the value of the signature header of a callback body signed with secret.
+func SignCallback(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts the batch to url. Server errors and network errors are retried with an exponential backoff.
+func (n *callbackNotifier) deliver(ctx context.Context, url string, batch *openai.Batch) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.initialBackoff
+	for attempt := 0; ; attempt++ {
+		err = n.post(ctx, url, body)
+		if err == nil || !isRetryableCallbackError(err) || attempt >= n.maxRetries {
+			return err
+		}
+
+		klog.FromContext(ctx).V(logging.DEBUG).Info("Retrying callback", "url", url, "attempt", attempt+1, "err", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// callbackStatusError is returned when the callback receiver answers with an error status.
+type callbackStatusError struct {
+	statusCode int
+}
+
+func (e *callbackStatusError) Error() string {
+	return fmt.Sprintf("callback receiver answered with status %d", e.statusCode)
+}
+
+func isRetryableCallbackError(err error) bool {
+	if statusErr, ok := err.(*callbackStatusError); ok {
+		return statusErr.statusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+func (n *callbackNotifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		req.Header.Set(CallbackSignatureHeader, SignCallback(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &callbackStatusError{statusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// notifyCallback posts the final status of the job to the callback URL of the batch, if any.
+// Delivery failures are logged and metered, and don't change the status of the job.
+func (p *Processor) notifyCallback(ctx context.Context, job *db.BatchJob, statusInfo *openai.BatchStatusInfo) {
+	logger := klog.FromContext(ctx)
+
+	spec := openai.BatchSpec{}
+	if err := json.Unmarshal(job.Spec, &spec); err != nil || spec.CallbackURL == "" {
+		return
+	}
+
+	batch := &openai.Batch{ID: job.ID, BatchSpec: spec, BatchStatusInfo: *statusInfo}
+	if err := p.callbacks.deliver(ctx, spec.CallbackURL, batch); err != nil {
+		logger.V(logging.WARNING).Info("Failed to deliver batch callback", "jobID", job.ID, "url", spec.CallbackURL, "err", err)
+		metrics.RecordCallbackDelivery(metrics.CallbackFailed)
+		return
+	}
+	logger.V(logging.DEBUG).Info("Delivered batch callback", "jobID", job.ID, "url", spec.CallbackURL)
+	metrics.RecordCallbackDelivery(metrics.CallbackDelivered)
+}
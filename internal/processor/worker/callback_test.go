@@ -0,0 +1,144 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains unit tests for the batch callbacks.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/metrics"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// callbackReceiver records the callbacks it receives, answering with the given statuses in turn.
+type callbackReceiver struct {
+	mu         sync.Mutex
+	statuses   []int
+	bodies     [][]byte
+	signatures []string
+}
+
+func (rc *callbackReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.bodies = append(rc.bodies, body)
+	rc.signatures = append(rc.signatures, r.Header.Get(CallbackSignatureHeader))
+
+	status := http.StatusOK
+	if len(rc.bodies) <= len(rc.statuses) {
+		status = rc.statuses[len(rc.bodies)-1]
+	}
+	w.WriteHeader(status)
+}
+
+// setCallbackURL sets the callback URL of the test job.
+func (env *workerTestEnv) setCallbackURL(t *testing.T, url string) {
+	t.Helper()
+
+	jobs, _, err := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	spec := openai.BatchSpec{}
+	if err := json.Unmarshal(jobs[0].Spec, &spec); err != nil {
+		t.Fatalf("Failed to parse job spec: %v", err)
+	}
+	spec.CallbackURL = url
+	jobs[0].Spec, _ = json.Marshal(spec)
+}
+
+func TestCallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		statuses      []int
+		wantAttempts  int
+		wantDelivered bool
+	}{
+		{name: "delivered", statuses: nil, wantAttempts: 1, wantDelivered: true},
+		{name: "retried on transient failure", statuses: []int{http.StatusServiceUnavailable}, wantAttempts: 2, wantDelivered: true},
+		{name: "not retried on client error", statuses: []int{http.StatusBadRequest}, wantAttempts: 1, wantDelivered: false},
+		{name: "retries exhausted", statuses: []int{500, 502, 503, 504}, wantAttempts: 4, wantDelivered: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupWorkerTestEnv(t, 2)
+			env.cfg.CallbackSigningSecret = "secret"
+			env.cfg.CallbackMaxRetries = 3
+			env.cfg.CallbackInitialBackoff = time.Millisecond
+
+			receiver := &callbackReceiver{statuses: tt.statuses}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+			env.setCallbackURL(t, server.URL)
+
+			result := metrics.CallbackFailed
+			if tt.wantDelivered {
+				result = metrics.CallbackDelivered
+			}
+			series := fmt.Sprintf(`callback_deliveries_total{result="%s"}`, result)
+			before := metricValue(t, series)
+
+			statusInfo := env.runJob(t, context.Background(), &fakeInferenceClient{})
+
+			// failing to deliver the callback doesn't fail the batch
+			if statusInfo.Status != openai.BatchStatusCompleted {
+				t.Fatalf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+			}
+			if len(receiver.bodies) != tt.wantAttempts {
+				t.Fatalf("Expected %d delivery attempts, got %d", tt.wantAttempts, len(receiver.bodies))
+			}
+
+			// the final batch object is posted and signed
+			for i, body := range receiver.bodies {
+				if receiver.signatures[i] != SignCallback([]byte("secret"), body) {
+					t.Errorf("Unexpected signature %q", receiver.signatures[i])
+				}
+				batch := openai.Batch{}
+				if err := json.Unmarshal(body, &batch); err != nil {
+					t.Fatalf("Failed to parse callback body: %v", err)
+				}
+				if batch.ID != env.jobID || batch.Status != openai.BatchStatusCompleted || batch.RequestCounts.Completed != 2 {
+					t.Errorf("Unexpected callback batch %+v", batch)
+				}
+			}
+
+			if got := metricValue(t, series) - before; got != 1 {
+				t.Errorf("Expected metric %s to increase by 1, got %v", series, got)
+			}
+		})
+	}
+
+	t.Run("NoCallbackURL", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 1)
+		statusInfo := env.runJob(t, context.Background(), &fakeInferenceClient{})
+		if statusInfo.Status != openai.BatchStatusCompleted {
+			t.Fatalf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the checkpoint logic that allows interrupted jobs to resume where they left off.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+const (
+	checkpointKeySuffix = ":checkpoint"
+	checkpointSaveTime  = 5 * time.Second
+)
+
+// checkpoint records the progress of a job.
+// Everything before LineOffset in the input file has been committed to the partial output files,
+// whose committed sizes are OutputBytes and ErrorBytes.
+type checkpoint struct {
+	LineOffset     int64  `json:"line_offset"`
+	OutputLocation string `json:"output_location"`
+	OutputBytes    int64  `json:"output_bytes"`
+	ErrorLocation  string `json:"error_location"`
+	ErrorBytes     int64  `json:"error_bytes"`
+	Total          int    `json:"total"`
+	Succeeded      int    `json:"succeeded"`
+	Failed         int    `json:"failed"`
+	TimedOut       int    `json:"timed_out"`
+
+	Usage openai.BatchUsage `json:"usage"`
+}
+
+func checkpointKey(jobID string) string {
+	return jobID + checkpointKeySuffix
+}
+
+// setCounts records the result counts of the lines committed to the partial output files.
+func (cp *checkpoint) setCounts(metadata *batch.JobResultMetadata) {
+	cp.Total = metadata.Total
+	cp.Succeeded = metadata.Succeeded
+	cp.Failed = metadata.Failed
+	cp.TimedOut = metadata.TimedOut
+	cp.Usage = metadata.Usage
+}
+
+// newCheckpoint returns an empty checkpoint for a job that starts from the beginning.
+func (p *Processor) newCheckpoint(jobID string) *checkpoint {
+	return &checkpoint{
+		OutputLocation: filepath.Join(p.cfg.WorkDir, jobID+".output.jsonl"),
+		ErrorLocation:  filepath.Join(p.cfg.WorkDir, jobID+".error.jsonl"),
+	}
+}
+
+// loadCheckpoint returns the last saved checkpoint of a job, or nil if there is none.
+func (p *Processor) loadCheckpoint(ctx context.Context, jobID string) (*checkpoint, error) {
+	data, err := p.clients.status.Get(ctx, checkpointKey(jobID))
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	cp := &checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// saveCheckpoint persists the checkpoint of a job.
+// It is saved even when the job context is cancelled, so that progress made before a shutdown is kept.
+func (p *Processor) saveCheckpoint(ctx context.Context, jobID string, cp *checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), checkpointSaveTime)
+	defer cancel()
+	return p.clients.status.Set(saveCtx, checkpointKey(jobID), statusTTLSeconds, data)
+}
+
+// deleteCheckpoint removes the checkpoint of a job once it is finalized.
+func (p *Processor) deleteCheckpoint(ctx context.Context, jobID string) error {
+	return p.clients.status.Delete(ctx, checkpointKey(jobID))
+}
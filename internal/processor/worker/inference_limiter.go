@@ -0,0 +1,55 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the limiter of the inference requests in flight across all the jobs of the processor.
+// The workers bound the concurrent jobs and MaxJobConcurrency the concurrent lines of a job; the limiter
+// bounds their product, so the processor doesn't send more requests than the inference gateway can handle.
+package worker
+
+import (
+	"context"
+)
+
+// inferenceLimiter is a semaphore shared by the workers. A nil limiter doesn't limit the requests.
+type inferenceLimiter chan struct{}
+
+// newInferenceLimiter returns a limiter of n requests in flight, or nil if n isn't positive.
+func newInferenceLimiter(n int) inferenceLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(inferenceLimiter, n)
+}
+
+// acquire waits for a request slot, or until ctx is done.
+func (l inferenceLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the slot of a request that completed.
+func (l inferenceLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the cancellation of the jobs being processed.
+// A job listens to the events of its batch while it is processed: on a cancel event, its lines in flight are
+// stopped, no more line is sent, and the batch is finalized as cancelled with the lines completed before.
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+// jobCancellation is the cancellation of a job by its cancel events.
+type jobCancellation struct {
+	// ctx is cancelled when the cancellation of the job is requested
+	ctx       context.Context
+	cancel    context.CancelFunc
+	requested atomic.Bool
+	stop      func()
+}
+
+// request requests the cancellation of the job.
+func (c *jobCancellation) request() {
+	c.requested.Store(true)
+	c.cancel()
+}
+
+// watchJobCancel listens to the cancel events of the job until the returned cancellation is stopped.
+// If the events of the job can't be subscribed to, the job can't be cancelled while it is processed.
+func (p *Processor) watchJobCancel(ctx context.Context, jobID string) *jobCancellation {
+	logger := klog.FromContext(ctx)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	c := &jobCancellation{ctx: cancelCtx, cancel: cancel, stop: cancel}
+
+	events, err := p.clients.event.ConsumerGetChannel(ctx, jobID)
+	if err != nil {
+		logger.V(logging.WARNING).Info("Failed to subscribe to the job events, the job can't be cancelled while it is processed", "err", err)
+		return c
+	}
+	done := make(chan struct{})
+	c.stop = func() {
+		close(done)
+		cancel()
+		events.CloseFn()
+	}
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case event, ok := <-events.Events:
+				if !ok {
+					return
+				}
+				// the other events of the job, such as its own status updates, are ignored
+				if event.Type == db.BatchEventCancel {
+					logger.V(logging.INFO).Info("Job cancellation requested, stopping its lines")
+					c.request()
+					return
+				}
+			}
+		}
+	}()
+	return c
+}
+
+// cancelJob finalizes a job whose cancellation was requested, with the lines completed before the cancellation.
+// The lines stopped by the cancellation are not written, so the request counts match the lines of the output
+// and error files, which are stored like those of a completed job.
+func (p *Processor) cancelJob(
+	ctx context.Context, job *db.BatchJob, spec *openai.BatchSpec, statusInfo *openai.BatchStatusInfo,
+	cp *checkpoint, out *jobOutput, metadata *batch.JobResultMetadata,
+) error {
+	if err := p.storeJobOutput(ctx, job, spec, statusInfo, cp, out, metadata); err != nil {
+		return err
+	}
+
+	cancelledAt := time.Now().UTC().Unix()
+	statusInfo.Status = openai.BatchStatusCancelled
+	statusInfo.CancelledAt = &cancelledAt
+	statusInfo.RequestCounts = requestCounts(metadata)
+	statusInfo.Usage = jobUsage(metadata)
+	p.updateJobStatus(ctx, job, statusInfo)
+	p.setStatus(ctx, job.ID, batch.StatusCancelled)
+	p.notifyCallback(ctx, job, statusInfo)
+
+	p.cleanupJobOutput(ctx, job.ID, cp)
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the cancellation of the jobs being processed.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	mockapi "github.com/llm-d-incubation/batch-gateway/internal/database/mock"
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+func TestCancelJob(t *testing.T) {
+	t.Run("CancelledWhileProcessing", func(t *testing.T) {
+		numReqs := 10
+		env := setupWorkerTestEnv(t, numReqs)
+		events := mockapi.NewMockBatchEventChannelClient()
+
+		// the batch is cancelled while its 3rd line is sent, which waits until it is stopped
+		client := &fakeInferenceClient{
+			onCall: func(ctx context.Context, call int) *inference.ClientError {
+				if call != 3 {
+					return nil
+				}
+				if _, err := events.ProducerSendEvents(ctx, []api.BatchEvent{{ID: env.jobID, Type: api.BatchEventCancel, TTL: 60}}); err != nil {
+					t.Errorf("Failed to send cancel event: %v", err)
+				}
+				<-ctx.Done()
+				return &inference.ClientError{Category: inference.ErrCategoryUnknown, Message: ctx.Err().Error()}
+			},
+		}
+		clients := NewProcessorClients(env.db, env.queue, env.status, events, env.fileDB, env.files, client)
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		interrupted := NewProcessor(env.cfg, &clients).processJob(context.Background(), 0, jobs[0])
+
+		if interrupted != notInterrupted {
+			t.Fatalf("Expected the cancelled job to be finalized, got interruption %v", interrupted)
+		}
+		if client.calls >= numReqs {
+			t.Errorf("Expected the remaining lines not to be sent, got %d requests for %d lines", client.calls, numReqs)
+		}
+
+		statusInfo := openai.BatchStatusInfo{}
+		if err := json.Unmarshal(jobs[0].Status, &statusInfo); err != nil {
+			t.Fatalf("Failed to parse job status: %v", err)
+		}
+		if statusInfo.Status != openai.BatchStatusCancelled || statusInfo.CancelledAt == nil {
+			t.Fatalf("Expected status %s with its time, got %s", openai.BatchStatusCancelled, statusInfo.Status)
+		}
+		// the counts are the lines completed before the cancellation; the stopped line is not reported
+		want := openai.BatchRequestCounts{Total: 2, Completed: 2}
+		if statusInfo.RequestCounts != want {
+			t.Errorf("Expected request counts %+v, got %+v", want, statusInfo.RequestCounts)
+		}
+		if lines := env.readResponseLines(t, statusInfo.OutputFileID); len(lines) != 2 || lines[0].CustomID != "req-0" || lines[1].CustomID != "req-1" {
+			t.Errorf("Expected the output of the 2 completed lines, got %+v", lines)
+		}
+		if statusInfo.ErrorFileID != "" {
+			t.Errorf("Expected no error file, got %q", statusInfo.ErrorFileID)
+		}
+		if entries, _ := os.ReadDir(env.cfg.WorkDir); len(entries) != 0 {
+			t.Errorf("Expected the work dir to be cleaned up, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("CancellingBeforeProcessing", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 3)
+
+		// the cancel event was sent while the job was queued, and only its status tells it is cancelling
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		jobs[0].Status, _ = json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusCancelling})
+		if err := env.db.Update(context.Background(), jobs[0]); err != nil {
+			t.Fatalf("Failed to update job: %v", err)
+		}
+
+		client := &fakeInferenceClient{}
+		statusInfo := env.runJob(t, context.Background(), client)
+		if client.calls != 0 {
+			t.Errorf("Expected no request to be sent, got %d", client.calls)
+		}
+		if statusInfo.Status != openai.BatchStatusCancelled || statusInfo.RequestCounts.Total != 0 {
+			t.Errorf("Expected status %s without requests, got %s and %+v", openai.BatchStatusCancelled, statusInfo.Status, statusInfo.RequestCounts)
+		}
+		if statusInfo.OutputFileID != "" || statusInfo.ErrorFileID != "" {
+			t.Errorf("Expected no result files, got output %q and error %q", statusInfo.OutputFileID, statusInfo.ErrorFileID)
+		}
+	})
+}
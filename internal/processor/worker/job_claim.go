@@ -0,0 +1,138 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the job claims that prevent processor replicas from processing the same job concurrently.
+// A worker claims a job with a lease before processing it, and renews the lease while the job is processed.
+// Jobs claimed by other workers are skipped, and the lease of a crashed replica expires so its jobs can be claimed again.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/klog/v2"
+
+	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+const (
+	jobClaimKeySuffix = ":claim"
+
+	// jobClaimReleaseTimeout bounds releasing a claim, which is done even when the processor shuts down
+	jobClaimReleaseTimeout = 5 * time.Second
+)
+
+func jobClaimKey(jobID string) string {
+	return jobID + jobClaimKeySuffix
+}
+
+// newReplicaID returns the ID identifying the claims of this replica.
+func newReplicaID() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return uuid.NewString()
+}
+
+// jobClaim is the claim of a worker on a job. Its token is unique to the claim, so a worker only renews
+// and releases its own claim, even if the same job was dequeued twice by the same replica.
+type jobClaim struct {
+	jobID string
+	token []byte
+	lost  atomic.Bool
+}
+
+// claimTTLSeconds returns the TTL of the claims leases.
+func (p *Processor) claimTTLSeconds() int {
+	return int(p.cfg.JobLeaseTTL.Seconds())
+}
+
+// claimJob claims a job for a worker, and returns the claim with the job as read after the claim.
+// If the job is claimed by another worker, or it was deleted or finalized in the meantime, nil is returned.
+func (p *Processor) claimJob(ctx context.Context, jobID string) (*jobClaim, *db.BatchJob, error) {
+	claim := &jobClaim{jobID: jobID, token: []byte(p.replicaID + "/" + uuid.NewString())}
+	claimed, err := p.clients.status.CompareAndSet(ctx, jobClaimKey(jobID), p.claimTTLSeconds(), nil, claim.token)
+	if err != nil || !claimed {
+		return nil, nil, err
+	}
+
+	// the job read before the claim may have been processed since by the previous owner of the claim
+	jobs, _, err := p.clients.database.Get(ctx, []string{jobID}, nil, db.TagsLogicalCondNa, true, 0, 1)
+	if err != nil {
+		p.releaseJob(ctx, claim)
+		return nil, nil, err
+	}
+	if len(jobs) == 0 || isFinalJob(jobs[0]) {
+		p.releaseJob(ctx, claim)
+		return nil, nil, nil
+	}
+	return claim, jobs[0], nil
+}
+
+// isFinalJob reports whether the job reached a final status.
+func isFinalJob(job *db.BatchJob) bool {
+	statusInfo := openai.BatchStatusInfo{}
+	if len(job.Status) == 0 || json.Unmarshal(job.Status, &statusInfo) != nil {
+		return false
+	}
+	return statusInfo.Status.IsFinal()
+}
+
+// keepJobClaim renews the lease of the claim every third of its TTL until ctx is done.
+// If the claim is lost, because its lease expired and the job was claimed by another worker, lost is called.
+func (p *Processor) keepJobClaim(ctx context.Context, claim *jobClaim, lost func()) {
+	logger := klog.FromContext(ctx)
+	ticker := time.NewTicker(p.cfg.JobLeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := p.clients.status.CompareAndSet(ctx, jobClaimKey(claim.jobID), p.claimTTLSeconds(), claim.token, claim.token)
+		if err != nil {
+			// the lease is renewed by the next tick, unless it expires in the meantime
+			logger.V(logging.WARNING).Info("Failed to renew job claim", "jobID", claim.jobID, "err", err)
+			continue
+		}
+		if !renewed && ctx.Err() == nil {
+			logger.V(logging.WARNING).Info("Job claim lost, stopping job", "jobID", claim.jobID)
+			claim.lost.Store(true)
+			lost()
+			return
+		}
+	}
+}
+
+// releaseJob releases the claim, so the job can be claimed again right away.
+// It is released even if ctx was cancelled by the shutdown, so another replica can resume the job.
+func (p *Processor) releaseJob(ctx context.Context, claim *jobClaim) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobClaimReleaseTimeout)
+	defer cancel()
+
+	if _, err := p.clients.status.CompareAndDelete(ctx, jobClaimKey(claim.jobID), claim.token); err != nil {
+		klog.FromContext(ctx).V(logging.WARNING).Info("Failed to release job claim", "jobID", claim.jobID, "err", err)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains unit tests for the job claims.
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	mockbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch/mock"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// getJobStatus returns the current status of the test job.
+func (env *workerTestEnv) getJobStatus(t *testing.T) openai.BatchStatusInfo {
+	t.Helper()
+
+	jobs, _, err := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	statusInfo := openai.BatchStatusInfo{}
+	if err := json.Unmarshal(jobs[0].Status, &statusInfo); err != nil {
+		t.Fatalf("Failed to parse job status: %v", err)
+	}
+	return statusInfo
+}
+
+// startTestJob starts the test job on a worker of the processor, and waits until the worker is released.
+func (env *workerTestEnv) startTestJob(t *testing.T, p *Processor) {
+	t.Helper()
+
+	jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	workerId, ok := p.workerPool.TryAcquire()
+	if !ok {
+		t.Fatalf("Failed to acquire a worker")
+	}
+	p.startJob(context.Background(), workerId, &api.BatchJobPriority{ID: env.jobID, SLO: time.Now().Add(time.Hour)}, jobs[0])
+	p.Stop(context.Background())
+}
+
+func TestJobClaims(t *testing.T) {
+	t.Run("TwoProcessors", func(t *testing.T) {
+		numReqs := 20
+		env := setupWorkerTestEnv(t, 0)
+		env.cfg.PollInterval = 10 * time.Millisecond
+		env.cfg.TaskWaitTime = time.Millisecond
+
+		var input bytes.Buffer
+		for i := 0; i < numReqs; i++ {
+			fmt.Fprintf(&input,
+				`{"custom_id":"req-%d","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[],"user":"line-%d"}}`+"\n", i, i)
+		}
+		env.storeInputFile(t, "file-lines", &input)
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		jobs[0].Spec, _ = json.Marshal(openai.BatchSpec{Object: "batch", Endpoint: openai.EndpointChatCompletions, InputFileID: "file-lines"})
+
+		// the job is queued twice, so both replicas pick it up
+		for i := 0; i < 2; i++ {
+			if err := env.queue.Enqueue(context.Background(), &api.BatchJobPriority{ID: env.jobID, SLO: time.Now().Add(time.Hour)}); err != nil {
+				t.Fatalf("Failed to enqueue job: %v", err)
+			}
+		}
+
+		clients := []*mockbatch.MockInferenceClient{
+			mockbatch.NewMockInferenceClient().WithLatency(5 * time.Millisecond),
+			mockbatch.NewMockInferenceClient().WithLatency(5 * time.Millisecond),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var wg sync.WaitGroup
+		var processors []*Processor
+		for i, client := range clients {
+			p := env.newProcessor(client)
+			p.replicaID = fmt.Sprintf("replica-%d", i)
+			processors = append(processors, p)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := p.RunPollingLoop(ctx); err != nil {
+					t.Errorf("Polling loop failed: %v", err)
+				}
+			}()
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			n, _ := env.queue.Len(context.Background())
+			if n == 0 && env.getJobStatus(t).Status.IsFinal() {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Job wasn't processed in time, status %s", env.getJobStatus(t).Status)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		wg.Wait()
+		for _, p := range processors {
+			p.Stop(context.Background())
+		}
+
+		// each line was processed once, by either replica
+		processed := map[string]int{}
+		for _, client := range clients {
+			for _, req := range client.Requests() {
+				processed[fmt.Sprint(req.Params["user"])]++
+			}
+		}
+		if len(processed) != numReqs {
+			t.Errorf("Expected %d processed lines, got %d", numReqs, len(processed))
+		}
+		for line, n := range processed {
+			if n != 1 {
+				t.Errorf("Expected %s to be processed once, got %d", line, n)
+			}
+		}
+		if statusInfo := env.getJobStatus(t); statusInfo.Status != openai.BatchStatusCompleted || statusInfo.RequestCounts.Completed != int64(numReqs) {
+			t.Errorf("Expected %d completed requests, got status %s and %+v", numReqs, statusInfo.Status, statusInfo.RequestCounts)
+		}
+	})
+
+	t.Run("ClaimedJobSkipped", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+		if claimed, err := env.status.CompareAndSet(context.Background(), jobClaimKey(env.jobID), 60, nil, []byte("other-replica")); err != nil || !claimed {
+			t.Fatalf("Failed to claim job: %v", err)
+		}
+
+		client := &fakeInferenceClient{}
+		env.startTestJob(t, env.newProcessor(client))
+
+		if client.calls != 0 {
+			t.Errorf("Expected no inference requests, got %d", client.calls)
+		}
+		if n, _ := env.queue.Len(context.Background()); n != 0 {
+			t.Errorf("Expected the job of the other replica not to be re-queued, got %d queued jobs", n)
+		}
+		if data, _ := env.status.Get(context.Background(), jobClaimKey(env.jobID)); string(data) != "other-replica" {
+			t.Errorf("Expected the claim of the other replica to be kept, got %q", data)
+		}
+	})
+
+	t.Run("ExpiredClaimReclaimed", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+
+		// the replica holding the claim crashed, and its lease expired
+		if claimed, err := env.status.CompareAndSet(context.Background(), jobClaimKey(env.jobID), 60, nil, []byte("crashed-replica")); err != nil || !claimed {
+			t.Fatalf("Failed to claim job: %v", err)
+		}
+		env.status.SetClock(func() time.Time { return time.Now().Add(time.Minute) })
+
+		client := &fakeInferenceClient{}
+		env.startTestJob(t, env.newProcessor(client))
+
+		if client.calls != 2 {
+			t.Errorf("Expected 2 inference requests, got %d", client.calls)
+		}
+		if statusInfo := env.getJobStatus(t); statusInfo.Status != openai.BatchStatusCompleted {
+			t.Errorf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+		}
+		if data, _ := env.status.Get(context.Background(), jobClaimKey(env.jobID)); data != nil {
+			t.Errorf("Expected the claim to be released, got %q", data)
+		}
+	})
+
+	t.Run("FinalizedJobSkipped", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+
+		// the job was completed by another replica after it was read
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		stale := *jobs[0]
+		env.runJob(t, context.Background(), &fakeInferenceClient{})
+
+		client := &fakeInferenceClient{}
+		p := env.newProcessor(client)
+		workerId, ok := p.workerPool.TryAcquire()
+		if !ok {
+			t.Fatalf("Failed to acquire a worker")
+		}
+		p.startJob(context.Background(), workerId, &api.BatchJobPriority{ID: env.jobID}, &stale)
+		p.Stop(context.Background())
+
+		if client.calls != 0 {
+			t.Errorf("Expected no inference requests, got %d", client.calls)
+		}
+	})
+}
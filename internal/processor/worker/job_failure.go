@@ -0,0 +1,153 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the permanent failures of jobs and their dead-letter path.
+// The reason of a failure is recorded in the errors of the batch, with a code of its cause, such as the category
+// of the inference error that failed the job, and with DeadLetterFailedJobs
+// the failed batch is copied to the dead-letter table of the database for later inspection.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	files "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/metrics"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+const (
+	// jobFailureInputFile is the code of the jobs whose input file can't be read
+	jobFailureInputFile = "input_file_unavailable"
+
+	// jobFailureInternal is the code of the jobs failed by an error of the processor or of its storage
+	jobFailureInternal = "internal_error"
+
+	// paramInputFileID is the parameter of the batch naming its input file
+	paramInputFileID = "input_file_id"
+)
+
+// inferenceFailureCodes are the codes of the job failures caused by the inference errors of each category.
+var inferenceFailureCodes = map[inference.ErrorCategory]string{
+	inference.ErrCategoryAuth:       "inference_unauthorized",
+	inference.ErrCategoryRateLimit:  "inference_rate_limited",
+	inference.ErrCategoryServer:     "inference_server_error",
+	inference.ErrCategoryInvalidReq: "inference_invalid_request",
+	inference.ErrCategoryUnknown:    "inference_error",
+}
+
+// jobFailure is the reason a job failed permanently, recorded in the errors of the batch.
+type jobFailure struct {
+	code    string
+	message string
+	param   string // the parameter of the batch that caused the failure, if any
+}
+
+func (f *jobFailure) Error() string {
+	return f.code + ": " + f.message
+}
+
+// inputFileFailure is the failure of a job whose input file can't be read because of err.
+func inputFileFailure(fileID string, err error) *jobFailure {
+	message := fmt.Sprintf("the input file %s could not be read", fileID)
+	if errors.Is(err, files.ErrFileNotFound) {
+		message = fmt.Sprintf("the input file %s was not found, it may have been deleted or have expired", fileID)
+	}
+	return &jobFailure{code: jobFailureInputFile, message: message, param: paramInputFileID}
+}
+
+// internalFailure is the failure of a job that could not be processed by the processor.
+func internalFailure() *jobFailure {
+	return &jobFailure{code: jobFailureInternal, message: "the batch could not be processed due to an internal error"}
+}
+
+// inferenceFailureCode returns the code of the job failure caused by an inference error of the category.
+func inferenceFailureCode(category inference.ErrorCategory) string {
+	if code, ok := inferenceFailureCodes[category]; ok {
+		return code
+	}
+	return inferenceFailureCodes[inference.ErrCategoryUnknown]
+}
+
+// lineJobFailure returns the failure of the whole job caused by the error of one of its lines, or nil if the
+// other lines may succeed. The inference gateway rejecting the credentials fails every line the same way,
+// so the job fails right away instead of sending all of its lines.
+func lineJobFailure(lineErr *batch.LineError) *jobFailure {
+	if lineErr == nil || lineErr.Code != string(inference.ErrCategoryAuth) {
+		return nil
+	}
+	return &jobFailure{
+		code:    inferenceFailureCode(inference.ErrorCategory(lineErr.Code)),
+		message: fmt.Sprintf("the inference request was not authorized: %s", lineErr.Message),
+	}
+}
+
+// errorReason returns the metrics reason of a job that failed on err: a timeout when a deadline was exceeded,
+// such as a call to the storage that didn't complete in time, a system error otherwise.
+func errorReason(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return metrics.ReasonTimeout
+	}
+	return metrics.ReasonSystemError
+}
+
+// isTimeoutLine reports whether the error line failed because its request didn't complete in time,
+// before the per-line timeout or the expiry of the batch.
+func isTimeoutLine(result *batch.ResponseLine) bool {
+	if result.Error == nil {
+		return false
+	}
+	return result.Error.Code == batch.LineErrorCodeLineTimeout || result.Error.Code == batch.LineErrorCodeBatchExpired
+}
+
+// recordFailure records the failure in the errors of the batch.
+func recordFailure(statusInfo *openai.BatchStatusInfo, failure *jobFailure) {
+	if statusInfo.Errors == nil {
+		statusInfo.Errors = &openai.BatchErrors{Object: "list"}
+	}
+	statusInfo.Errors.Data = append(statusInfo.Errors.Data, openai.BatchError{Code: failure.code, Message: failure.message, Param: failure.param})
+}
+
+// deadLetterJob copies the failed job, with its final status, to the dead-letter table.
+// The time it was dead-lettered is kept in the status only if the copy was stored.
+func (p *Processor) deadLetterJob(ctx context.Context, job *db.BatchJob, statusInfo *openai.BatchStatusInfo, failure *jobFailure) {
+	logger := klog.FromContext(ctx)
+
+	deadLetteredAt := time.Now().UTC().Unix()
+	statusInfo.DeadLetteredAt = &deadLetteredAt
+	data, err := json.Marshal(statusInfo)
+	if err == nil {
+		deadLetter := *job
+		deadLetter.Status = data
+		err = p.clients.database.DeadLetter(ctx, &deadLetter)
+	}
+	if err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to dead-letter job", "jobID", job.ID)
+		statusInfo.DeadLetteredAt = nil
+		return
+	}
+	metrics.RecordJobDeadLettered(failure.code)
+	logger.V(logging.INFO).Info("Job dead-lettered", "jobID", job.ID, "code", failure.code)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the recovery of the jobs left without a worker.
+// A job is dequeued before it is claimed, so a job whose replica crashed, or that was dequeued while another
+// replica held its claim, isn't in the queue anymore. The processors periodically put back to the queue the jobs
+// that are neither final nor claimed, and the duplicates of the jobs still in the queue are removed.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+// recoveryPageSize is the number of active jobs fetched at a time by a recovery pass
+const recoveryPageSize = 100
+
+// runJobRecovery recovers the jobs left without a worker every JobRecoveryInterval until ctx is done.
+func (p *Processor) runJobRecovery(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	ticker := time.NewTicker(p.cfg.JobRecoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		recovered, err := p.recoverJobs(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.V(logging.ERROR).Error(err, "Failed to recover jobs")
+		}
+		if recovered > 0 {
+			logger.V(logging.INFO).Info("Recovered jobs left without a worker", "count", recovered)
+		}
+	}
+}
+
+// recoverJobs puts back to the queue the active jobs that aren't claimed by a worker, and returns the number of
+// those that weren't in the queue anymore.
+// The active tag of the jobs that became final is removed, so they aren't checked again.
+func (p *Processor) recoverJobs(ctx context.Context) (int, error) {
+	logger := klog.FromContext(ctx)
+
+	var jobs []*db.BatchJob
+	for start := 0; ; {
+		page, cursor, err := p.clients.database.Get(ctx, nil, []string{batch.ActiveJobTag}, db.TagsLogicalCondAnd,
+			true, start, recoveryPageSize)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list active jobs: %w", err)
+		}
+		jobs = append(jobs, page...)
+		if cursor == 0 || len(page) < recoveryPageSize {
+			break
+		}
+		start = cursor
+	}
+
+	recovered := 0
+	for _, job := range jobs {
+		if isFinalJob(job) {
+			if err := p.clients.database.Update(ctx, &db.BatchJob{ID: job.ID, Tags: batch.WithoutTag(job.Tags, batch.ActiveJobTag)}); err != nil {
+				logger.V(logging.WARNING).Info("Failed to remove the active tag of a final job", "jobID", job.ID, "err", err)
+			}
+			continue
+		}
+
+		claim, err := p.clients.status.Get(ctx, jobClaimKey(job.ID))
+		if err != nil {
+			return recovered, fmt.Errorf("failed to get the claim of job %s: %w", job.ID, err)
+		}
+		if claim != nil {
+			continue
+		}
+
+		spec := openai.BatchSpec{}
+		if err := json.Unmarshal(job.Spec, &spec); err != nil {
+			logger.V(logging.WARNING).Info("Failed to parse the spec of an active job", "jobID", job.ID, "err", err)
+			continue
+		}
+		task := &db.BatchJobPriority{ID: job.ID, SLO: job.SLO, Priority: batch.QueuePriority(spec.Priority)}
+		queued, err := p.clients.priorityQueue.Remove(ctx, task)
+		if err != nil {
+			return recovered, fmt.Errorf("failed to remove job %s from the queue: %w", job.ID, err)
+		}
+		if err := p.clients.priorityQueue.Enqueue(ctx, task); err != nil {
+			return recovered, fmt.Errorf("failed to enqueue job %s: %w", job.ID, err)
+		}
+		if queued == 0 {
+			logger.V(logging.INFO).Info("Job left without a worker, re-queued", "jobID", job.ID)
+			recovered++
+		}
+	}
+	return recovered, nil
+}
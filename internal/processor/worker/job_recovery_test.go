@@ -0,0 +1,177 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains unit tests for the recovery of the jobs left without a worker.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/database/memory"
+	mockapi "github.com/llm-d-incubation/batch-gateway/internal/database/mock"
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// newRecoveryProcessor returns a processor of the test env using a memory database holding the test job, tagged
+// active as the API server stores it. Unlike the mock, the memory database returns copies of the jobs, so the
+// recovery doesn't share them with the processing of the job.
+func (env *workerTestEnv) newRecoveryProcessor(t *testing.T, client inference.Client) (*Processor, *memory.BatchDBClient) {
+	t.Helper()
+
+	jobs, _, err := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	job := *jobs[0]
+	job.SLO = time.Now().Add(time.Hour)
+	job.TTL = 3600
+	job.Tags = []string{batch.TenantTag(batch.DefaultTenantID), batch.ActiveJobTag}
+	database := memory.NewBatchDBClient()
+	if _, err := database.Store(context.Background(), &job); err != nil {
+		t.Fatalf("Failed to store job: %v", err)
+	}
+
+	clients := NewProcessorClients(
+		database, env.queue, env.status, mockapi.NewMockBatchEventChannelClient(), env.fileDB, env.files, client,
+	)
+	return NewProcessor(env.cfg, &clients), database
+}
+
+// getRecoveryJob returns the current status and tags of the test job in the database.
+func (env *workerTestEnv) getRecoveryJob(t *testing.T, database *memory.BatchDBClient) (openai.BatchStatus, []string) {
+	t.Helper()
+
+	jobs, _, err := database.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	statusInfo := openai.BatchStatusInfo{}
+	if err := json.Unmarshal(jobs[0].Status, &statusInfo); err != nil {
+		t.Fatalf("Failed to parse job status: %v", err)
+	}
+	return statusInfo.Status, jobs[0].Tags
+}
+
+func TestJobRecovery(t *testing.T) {
+	t.Run("ExpiredClaimRecovered", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+		env.cfg.PollInterval = 10 * time.Millisecond
+		env.cfg.TaskWaitTime = time.Millisecond
+		env.cfg.JobRecoveryInterval = 20 * time.Millisecond
+
+		// the replica processing the job crashed after dequeuing it, and its lease expired
+		if claimed, err := env.status.CompareAndSet(context.Background(), jobClaimKey(env.jobID), 60, nil, []byte("crashed-replica")); err != nil || !claimed {
+			t.Fatalf("Failed to claim job: %v", err)
+		}
+		env.status.SetClock(func() time.Time { return time.Now().Add(time.Minute) })
+
+		client := &fakeInferenceClient{}
+		p, database := env.newRecoveryProcessor(t, client)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.RunPollingLoop(ctx); err != nil {
+				t.Errorf("Polling loop failed: %v", err)
+			}
+		}()
+
+		// the job is completed, then the next recovery pass removes its active tag
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			status, tags := env.getRecoveryJob(t, database)
+			if status == openai.BatchStatusCompleted && !slices.Contains(tags, batch.ActiveJobTag) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Job wasn't recovered in time, status %s and tags %v", status, tags)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		wg.Wait()
+		p.Stop(context.Background())
+
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		if client.calls != 2 {
+			t.Errorf("Expected 2 inference requests, got %d", client.calls)
+		}
+		if _, tags := env.getRecoveryJob(t, database); !slices.Equal(tags, []string{batch.TenantTag(batch.DefaultTenantID)}) {
+			t.Errorf("Expected the tenant tag to be kept, got %v", tags)
+		}
+	})
+
+	t.Run("ClaimedJobNotRecovered", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+		p, _ := env.newRecoveryProcessor(t, &fakeInferenceClient{})
+		if claimed, err := env.status.CompareAndSet(context.Background(), jobClaimKey(env.jobID), 60, nil, []byte("other-replica")); err != nil || !claimed {
+			t.Fatalf("Failed to claim job: %v", err)
+		}
+
+		recovered, err := p.recoverJobs(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to recover jobs: %v", err)
+		}
+		if recovered != 0 {
+			t.Errorf("Expected no recovered jobs, got %d", recovered)
+		}
+		if n, _ := env.queue.Len(context.Background()); n != 0 {
+			t.Errorf("Expected the job of the other replica not to be queued, got %d queued jobs", n)
+		}
+	})
+
+	t.Run("QueuedJobNotDuplicated", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+		p, _ := env.newRecoveryProcessor(t, &fakeInferenceClient{})
+		if err := env.queue.Enqueue(context.Background(), &api.BatchJobPriority{ID: env.jobID}); err != nil {
+			t.Fatalf("Failed to enqueue job: %v", err)
+		}
+
+		recovered, err := p.recoverJobs(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to recover jobs: %v", err)
+		}
+		if recovered != 0 {
+			t.Errorf("Expected no recovered jobs, got %d", recovered)
+		}
+		if n, _ := env.queue.Len(context.Background()); n != 1 {
+			t.Errorf("Expected the job to be queued once, got %d queued jobs", n)
+		}
+	})
+
+	t.Run("UntaggedJobIgnored", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+
+		recovered, err := env.newProcessor(&fakeInferenceClient{}).recoverJobs(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to recover jobs: %v", err)
+		}
+		if n, _ := env.queue.Len(context.Background()); recovered != 0 || n != 0 {
+			t.Errorf("Expected the job without the active tag not to be queued, got %d recovered and %d queued jobs", recovered, n)
+		}
+	})
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the recovery of the late responses of the lines failed by the per-line timeout.
+// With a LateResponseGracePeriod, the inference request of a line that times out is kept in flight for the grace
+// period, never past the expiry of the batch. The line is failed like any timed out line, and its late response,
+// if it arrives before the job is finalized, moves the line from the error file to the output file.
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
+)
+
+// lateLines tracks the requests of the timed out lines of a job kept in flight, and the lines they recovered.
+// The requests are bound to the context of the job rather than to the chunk of their line.
+type lateLines struct {
+	ctx       context.Context
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	recovered []*batch.ResponseLine
+}
+
+// add records a line recovered by a late response. The body is copied, the response is released once handled.
+func (l *lateLines) add(result *batch.ResponseLine) {
+	line := *result
+	response := *result.Response
+	response.Body = slices.Clone(result.Response.Body)
+	line.Response = &response
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.recovered = append(l.recovered, &line)
+}
+
+// wait waits for the requests in flight, and returns the recovered lines.
+func (l *lateLines) wait() []*batch.ResponseLine {
+	l.wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.recovered
+}
+
+// lateDeadline returns the time until which the request of a line started at start with the timeout is kept in
+// flight after the line timed out, or the zero time if there is no grace period for the line.
+// A line whose timeout was shortened by the expiry of the batch has no grace period.
+func (p *Processor) lateDeadline(start time.Time, timeout time.Duration, expiresAt time.Time) time.Time {
+	if p.cfg.LateResponseGracePeriod <= 0 || timeout < p.cfg.PerLineTimeout {
+		return time.Time{}
+	}
+	deadline := start.Add(timeout + p.cfg.LateResponseGracePeriod)
+	if !expiresAt.IsZero() && expiresAt.Before(deadline) {
+		deadline = expiresAt
+	}
+	if !deadline.After(start.Add(timeout)) {
+		return time.Time{}
+	}
+	return deadline
+}
+
+// generateLate sends the request of a line like tracedGenerate, for the time of lineCtx. If the line times out,
+// the request is kept in flight until deadline, holding its inference slot, and a successful late response is
+// turned into a recovered line by finish. It reports whether the request was kept in flight, in which case its
+// inference slot is released once the request is done.
+func (p *Processor) generateLate(
+	lineCtx context.Context, deadline time.Time, late *lateLines, req *inference.GenerateRequest, model string,
+	finish func(resp *inference.GenerateResponse) (*batch.ResponseLine, bool),
+) (*inference.GenerateResponse, *inference.ClientError, bool) {
+	type generated struct {
+		resp *inference.GenerateResponse
+		err  *inference.ClientError
+	}
+
+	callCtx, cancel := context.WithDeadline(late.ctx, deadline)
+	done := make(chan generated, 1)
+	go func() {
+		resp, err := p.tracedGenerate(callCtx, req, model)
+		done <- generated{resp: resp, err: err}
+	}()
+
+	select {
+	case g := <-done:
+		cancel()
+		return g.resp, g.err, false
+	case <-lineCtx.Done():
+	}
+	if !errors.Is(lineCtx.Err(), context.DeadlineExceeded) {
+		// the line was stopped rather than timed out
+		cancel()
+		g := <-done
+		return g.resp, g.err, false
+	}
+
+	late.wg.Add(1)
+	go func() {
+		defer late.wg.Done()
+		defer p.inferenceSlots.release()
+		defer cancel()
+
+		g := <-done
+		if g.err != nil {
+			return
+		}
+		defer g.resp.Release()
+		if result, failed := finish(g.resp); !failed {
+			klog.FromContext(late.ctx).V(logging.DEBUG).Info("Received late response within grace period", "customID", req.RequestID)
+			late.add(result)
+		}
+	}()
+	return nil, &inference.ClientError{
+		Category: inference.ErrCategoryServer,
+		Message:  "request timed out",
+		RawError: lineCtx.Err(),
+	}, true
+}
+
+// recoverLateLines waits for the requests of the timed out lines kept in flight, and moves the lines they recovered
+// from the error file to the output file of the job. The output files are closed, no line is written once the job
+// is finalizing, and a checkpoint of the rewritten files is saved, so a job resumed before it is finalized neither
+// loses the recovered lines nor reports them twice.
+func (p *Processor) recoverLateLines(
+	ctx context.Context, jobID string, late *lateLines, cp *checkpoint, out *jobOutput, metadata *batch.JobResultMetadata,
+) error {
+	recovered := late.wait()
+	if len(recovered) == 0 {
+		return nil
+	}
+
+	customIDs := make(map[string]struct{}, len(recovered))
+	for _, line := range recovered {
+		if err := out.output.Write(line); err != nil {
+			return err
+		}
+		customIDs[line.CustomID] = struct{}{}
+		metadata.Failed--
+		metadata.TimedOut--
+		metadata.Succeeded++
+		metadata.Usage.Add(responseUsage(line.Response.Body))
+	}
+	outputBytes, err := out.output.Sync()
+	if err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	errorBytes, err := removeTimedOutLines(cp.ErrorLocation, customIDs)
+	if err != nil {
+		return err
+	}
+
+	cp.OutputBytes = outputBytes
+	cp.ErrorBytes = errorBytes
+	cp.setCounts(metadata)
+	if err := p.saveCheckpoint(ctx, jobID, cp); err != nil {
+		return err
+	}
+	klog.FromContext(ctx).V(logging.INFO).Info("Recovered timed out lines from late responses", "lines", len(recovered))
+	return nil
+}
+
+// removeTimedOutLines rewrites the error file at path without the lines of customIDs failed by the per-line timeout,
+// and returns the size of the rewritten file.
+func removeTimedOutLines(path string, customIDs map[string]struct{}) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var kept bytes.Buffer
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return 0, readErr
+		}
+		if len(bytes.TrimSpace(line)) > 0 && !isRecoveredLine(line, customIDs) {
+			kept.Write(line)
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, kept.Bytes(), 0o600); err != nil {
+		return 0, err
+	}
+	return int64(kept.Len()), os.Rename(tmp, path)
+}
+
+// isRecoveredLine reports whether the error line is the line_timeout error of one of customIDs.
+func isRecoveredLine(line []byte, customIDs map[string]struct{}) bool {
+	var result batch.ResponseLine
+	if err := json.Unmarshal(line, &result); err != nil || result.Error == nil {
+		return false
+	}
+	_, ok := customIDs[result.CustomID]
+	return ok && result.Error.Code == batch.LineErrorCodeLineTimeout
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the model aliases applied to the inference requests.
+// A request for an aliased model is sent to the model it maps to, and the model of its response
+// is renamed back, so the output lines show the model requested in the input file.
+package worker
+
+import (
+	"encoding/json"
+	"maps"
+)
+
+// withModel returns a copy of the request body with its model replaced.
+func withModel(body map[string]interface{}, model string) map[string]interface{} {
+	params := maps.Clone(body)
+	params["model"] = model
+	return params
+}
+
+// restoreModel returns the response body with its model renamed to the requested model.
+// A body without a model is returned unchanged.
+func restoreModel(body []byte, model string) []byte {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+	if _, ok := fields["model"]; !ok {
+		return body
+	}
+	name, err := json.Marshal(model)
+	if err != nil {
+		return body
+	}
+	fields["model"] = name
+	restored, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return restored
+}
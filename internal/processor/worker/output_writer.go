@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the writer used to assemble the output and error files of a job.
+package worker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// jsonlWriter appends JSON lines to a local file.
+type jsonlWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	buf  *bufio.Writer
+	size int64 // bytes written to the file, including buffered bytes
+}
+
+// openJSONLWriter opens the file at path for appending, after truncating it to offset bytes.
+// Truncating discards lines written after the last checkpoint.
+func openJSONLWriter(path string, offset int64) (*jsonlWriter, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size() < offset {
+		file.Close()
+		return nil, fmt.Errorf("file %s is shorter (%d bytes) than the checkpoint offset (%d bytes)", path, info.Size(), offset)
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(offset, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &jsonlWriter{
+		file: file,
+		buf:  bufio.NewWriter(file),
+		size: offset,
+	}, nil
+}
+
+// Write appends v as a JSON line.
+func (w *jsonlWriter) Write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := w.buf.Write(data)
+	w.size += int64(n)
+	return err
+}
+
+// Sync flushes the buffered lines and commits them to stable storage.
+// It returns the size of the file.
+func (w *jsonlWriter) Sync() (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.buf.Flush(); err != nil {
+		return 0, err
+	}
+	if err := w.file.Sync(); err != nil {
+		return 0, err
+	}
+	return w.size, nil
+}
+
+// Close flushes the buffered lines and closes the file.
+func (w *jsonlWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	flushErr := w.buf.Flush()
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
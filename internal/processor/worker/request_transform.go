@@ -0,0 +1,68 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the transforms applied to the bodies of the inference requests.
+// They adapt the OpenAI format of the input files to the inference backend, e.g. when it expects
+// a field under another name, without changing the input files.
+package worker
+
+import (
+	"maps"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+)
+
+// transformParams returns the request body of an endpoint transformed by the rules of the endpoint, in order.
+// The body is copied before it is changed, and returned as is when no rule changes it.
+func transformParams(rules []config.RequestTransform, endpoint string, body map[string]interface{}) map[string]interface{} {
+	params := body
+	copied := false
+	edit := func() {
+		if !copied {
+			params = maps.Clone(body)
+			copied = true
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.Endpoint != "" && rule.Endpoint != endpoint {
+			continue
+		}
+
+		// the fields are renamed together, so a rule can swap fields
+		renamed := map[string]interface{}{}
+		for from, to := range rule.Rename {
+			if value, ok := params[from]; ok {
+				edit()
+				renamed[to] = value
+				delete(params, from)
+			}
+		}
+		for to, value := range renamed {
+			if _, ok := params[to]; !ok {
+				params[to] = value
+			}
+		}
+
+		for field, value := range rule.Defaults {
+			if _, ok := params[field]; !ok {
+				edit()
+				params[field] = value
+			}
+		}
+	}
+	return params
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the transforms of the inference request bodies.
+package worker
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+func TestTransformParams(t *testing.T) {
+	chat := string(openai.EndpointChatCompletions)
+
+	tests := []struct {
+		name     string
+		rules    []config.RequestTransform
+		endpoint string
+		body     map[string]interface{}
+		want     map[string]interface{}
+	}{
+		{
+			name:  "rename",
+			rules: []config.RequestTransform{{Rename: map[string]string{"max_tokens": "max_completion_tokens"}}},
+			body:  map[string]interface{}{"model": "m", "max_tokens": 10.0},
+			want:  map[string]interface{}{"model": "m", "max_completion_tokens": 10.0},
+		},
+		{
+			name:  "rename keeps the field set under the new name",
+			rules: []config.RequestTransform{{Rename: map[string]string{"max_tokens": "max_completion_tokens"}}},
+			body:  map[string]interface{}{"model": "m", "max_tokens": 10.0, "max_completion_tokens": 20.0},
+			want:  map[string]interface{}{"model": "m", "max_completion_tokens": 20.0},
+		},
+		{
+			name:  "rename swaps fields",
+			rules: []config.RequestTransform{{Rename: map[string]string{"a": "b", "b": "a"}}},
+			body:  map[string]interface{}{"model": "m", "a": 1.0, "b": 2.0},
+			want:  map[string]interface{}{"model": "m", "a": 2.0, "b": 1.0},
+		},
+		{
+			name:  "default injected",
+			rules: []config.RequestTransform{{Defaults: map[string]interface{}{"temperature": 0.7, "stop": []interface{}{"\n"}}}},
+			body:  map[string]interface{}{"model": "m"},
+			want:  map[string]interface{}{"model": "m", "temperature": 0.7, "stop": []interface{}{"\n"}},
+		},
+		{
+			name:  "default does not override the request",
+			rules: []config.RequestTransform{{Defaults: map[string]interface{}{"temperature": 0.7}}},
+			body:  map[string]interface{}{"model": "m", "temperature": 0.0},
+			want:  map[string]interface{}{"model": "m", "temperature": 0.0},
+		},
+		{
+			name:     "rule of the endpoint",
+			rules:    []config.RequestTransform{{Endpoint: chat, Defaults: map[string]interface{}{"temperature": 0.7}}},
+			endpoint: chat,
+			body:     map[string]interface{}{"model": "m"},
+			want:     map[string]interface{}{"model": "m", "temperature": 0.7},
+		},
+		{
+			name:     "rule of another endpoint",
+			rules:    []config.RequestTransform{{Endpoint: chat, Defaults: map[string]interface{}{"temperature": 0.7}}},
+			endpoint: string(openai.EndpointEmbeddings),
+			body:     map[string]interface{}{"model": "m"},
+			want:     map[string]interface{}{"model": "m"},
+		},
+		{
+			name: "rules applied in order",
+			rules: []config.RequestTransform{
+				{Defaults: map[string]interface{}{"max_tokens": 100.0}},
+				{Rename: map[string]string{"max_tokens": "max_completion_tokens"}},
+			},
+			body: map[string]interface{}{"model": "m"},
+			want: map[string]interface{}{"model": "m", "max_completion_tokens": 100.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := map[string]interface{}{}
+			for k, v := range tt.body {
+				original[k] = v
+			}
+
+			got := transformParams(tt.rules, tt.endpoint, tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected params %v, got %v", tt.want, got)
+			}
+			// the body of the request line is not changed
+			if !reflect.DeepEqual(tt.body, original) {
+				t.Errorf("Expected the body to be unchanged, got %v", tt.body)
+			}
+		})
+	}
+}
+
+// paramsRecordingClient records the params of the requests it answers
+type paramsRecordingClient struct {
+	fakeInferenceClient
+	mu     sync.Mutex
+	params []map[string]interface{}
+}
+
+func (c *paramsRecordingClient) Generate(ctx context.Context, req *inference.GenerateRequest) (*inference.GenerateResponse, *inference.ClientError) {
+	c.mu.Lock()
+	c.params = append(c.params, req.Params)
+	c.mu.Unlock()
+	return c.fakeInferenceClient.Generate(ctx, req)
+}
+
+func TestRequestTransforms(t *testing.T) {
+	env := setupWorkerTestEnv(t, 2)
+	env.cfg.ModelAliases = map[string]string{"m": "internal-m"}
+	env.cfg.RequestTransforms = []config.RequestTransform{{
+		Endpoint: string(openai.EndpointChatCompletions),
+		Rename:   map[string]string{"messages": "conversation"},
+		Defaults: map[string]interface{}{"temperature": 0.7},
+	}}
+	client := &paramsRecordingClient{}
+
+	statusInfo := env.runJob(t, context.Background(), client)
+	if statusInfo.Status != openai.BatchStatusCompleted {
+		t.Fatalf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+	}
+
+	if len(client.params) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(client.params))
+	}
+	for _, params := range client.params {
+		want := map[string]interface{}{"model": "internal-m", "conversation": []interface{}{}, "temperature": 0.7}
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("Expected the request params %v, got %v", want, params)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the parsing of the inference response bodies written to the output lines.
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// checkResponseBody checks that a response body has the shape of the responses of the endpoint.
+// Endpoints whose responses are written as they are, without a check, always pass.
+func checkResponseBody(endpoint openai.Endpoint, body []byte) error {
+	switch endpoint {
+	case openai.EndpointModerations:
+		resp := openai.ModerationResponse{}
+		if json.Unmarshal(body, &resp) != nil || len(resp.Results) == 0 {
+			return errors.New("inference response is not a moderation response")
+		}
+	case openai.EndpointResponses:
+		resp := openai.Response{}
+		if json.Unmarshal(body, &resp) != nil || resp.Object != openai.ResponseObject {
+			return errors.New("inference response is not a Responses API response")
+		}
+	}
+	return nil
+}
+
+// responseUsage returns the token usage of a response body.
+// The usage of the Responses API is reported as is, the one of the chat completions, completions
+// and embeddings APIs is mapped from their prompt and completion tokens.
+// A body without usage counts no tokens.
+func responseUsage(body []byte) openai.BatchUsage {
+	var resp struct {
+		Usage *struct {
+			// Responses API
+			InputTokens         int64                                 `json:"input_tokens"`
+			OutputTokens        int64                                 `json:"output_tokens"`
+			InputTokensDetails  *openai.BatchUsageInputTokensDetails  `json:"input_tokens_details"`
+			OutputTokensDetails *openai.BatchUsageOutputTokensDetails `json:"output_tokens_details"`
+
+			// chat completions, completions and embeddings APIs
+			PromptTokens            int64                                 `json:"prompt_tokens"`
+			CompletionTokens        int64                                 `json:"completion_tokens"`
+			PromptTokensDetails     *openai.BatchUsageInputTokensDetails  `json:"prompt_tokens_details"`
+			CompletionTokensDetails *openai.BatchUsageOutputTokensDetails `json:"completion_tokens_details"`
+
+			TotalTokens int64 `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Usage == nil {
+		return openai.BatchUsage{}
+	}
+
+	u := resp.Usage
+	usage := openai.BatchUsage{
+		InputTokens:  u.InputTokens + u.PromptTokens,
+		OutputTokens: u.OutputTokens + u.CompletionTokens,
+		TotalTokens:  u.TotalTokens,
+	}
+	for _, details := range []*openai.BatchUsageInputTokensDetails{u.InputTokensDetails, u.PromptTokensDetails} {
+		if details != nil {
+			usage.InputTokensDetails.CachedTokens += details.CachedTokens
+		}
+	}
+	for _, details := range []*openai.BatchUsageOutputTokensDetails{u.OutputTokensDetails, u.CompletionTokensDetails} {
+		if details != nil {
+			usage.OutputTokensDetails.ReasoningTokens += details.ReasoningTokens
+		}
+	}
+	return usage
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+func TestResponseUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want openai.BatchUsage
+	}{
+		{
+			name: "responses",
+			body: `{"object":"response","usage":{"input_tokens":10,"input_tokens_details":{"cached_tokens":4},` +
+				`"output_tokens":20,"output_tokens_details":{"reasoning_tokens":5},"total_tokens":30}}`,
+			want: openai.BatchUsage{
+				InputTokens: 10, InputTokensDetails: openai.BatchUsageInputTokensDetails{CachedTokens: 4},
+				OutputTokens: 20, OutputTokensDetails: openai.BatchUsageOutputTokensDetails{ReasoningTokens: 5},
+				TotalTokens: 30,
+			},
+		},
+		{
+			name: "chat completions",
+			body: `{"object":"chat.completion","usage":{"prompt_tokens":7,"prompt_tokens_details":{"cached_tokens":2},` +
+				`"completion_tokens":3,"completion_tokens_details":{"reasoning_tokens":1},"total_tokens":10}}`,
+			want: openai.BatchUsage{
+				InputTokens: 7, InputTokensDetails: openai.BatchUsageInputTokensDetails{CachedTokens: 2},
+				OutputTokens: 3, OutputTokensDetails: openai.BatchUsageOutputTokensDetails{ReasoningTokens: 1},
+				TotalTokens: 10,
+			},
+		},
+		{
+			name: "embeddings",
+			body: `{"object":"list","usage":{"prompt_tokens":8,"total_tokens":8}}`,
+			want: openai.BatchUsage{InputTokens: 8, TotalTokens: 8},
+		},
+		{name: "no usage", body: `{"object":"list"}`, want: openai.BatchUsage{}},
+		{name: "not an object", body: `[]`, want: openai.BatchUsage{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := responseUsage([]byte(tt.body)); got != tt.want {
+				t.Errorf("Expected usage %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckResponseBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint openai.Endpoint
+		body     string
+		wantErr  bool
+	}{
+		{name: "response", endpoint: openai.EndpointResponses, body: `{"id":"resp_1","object":"response","output":[]}`},
+		{name: "not a response", endpoint: openai.EndpointResponses, body: `{"id":"chatcmpl-1","object":"chat.completion"}`, wantErr: true},
+		{name: "moderation", endpoint: openai.EndpointModerations, body: `{"id":"modr-1","results":[{"flagged":false}]}`},
+		{name: "not a moderation", endpoint: openai.EndpointModerations, body: `{"id":"modr-1"}`, wantErr: true},
+		{name: "unchecked endpoint", endpoint: openai.EndpointChatCompletions, body: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkResponseBody(tt.endpoint, []byte(tt.body)); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
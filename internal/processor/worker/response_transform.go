@@ -0,0 +1,217 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the normalization of the inference response bodies written to the output lines.
+// Backends answering in another shape than the OpenAI API have their responses rewritten into
+// the OpenAI shape of their endpoint, so the output files are the same whatever the backend.
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// ResponseNormalizer rewrites the response bodies of the inference backend before they are written to the output lines.
+type ResponseNormalizer interface {
+	// Normalize returns the body of a response of the endpoint in the shape of the OpenAI API.
+	Normalize(endpoint openai.Endpoint, body []byte) ([]byte, error)
+}
+
+// newResponseNormalizer returns the normalizer applying the response transforms, which keeps the bodies as they are
+// when there are none.
+func newResponseNormalizer(rules []config.ResponseTransform) ResponseNormalizer {
+	if len(rules) == 0 {
+		return noopNormalizer{}
+	}
+	return ruleNormalizer{rules: rules}
+}
+
+// noopNormalizer keeps the response bodies as they are.
+type noopNormalizer struct{}
+
+func (noopNormalizer) Normalize(_ openai.Endpoint, body []byte) ([]byte, error) {
+	return body, nil
+}
+
+// ruleNormalizer applies the response transforms of the endpoint of a response, in order.
+type ruleNormalizer struct {
+	rules []config.ResponseTransform
+}
+
+func (n ruleNormalizer) Normalize(endpoint openai.Endpoint, body []byte) ([]byte, error) {
+	var rules []config.ResponseTransform
+	for _, rule := range n.rules {
+		if rule.Endpoint == "" || rule.Endpoint == string(endpoint) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return body, nil
+	}
+
+	// numbers are kept as they are, large integers such as timestamps are not rounded
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, errors.New("response body is not a JSON object")
+	}
+
+	var err error
+	for _, rule := range rules {
+		if doc, err = applyResponseTransform(doc, rule); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// applyResponseTransform moves the fields of the rule, sets its defaults and drops its fields, in this order.
+func applyResponseTransform(doc interface{}, rule config.ResponseTransform) (interface{}, error) {
+	// the fields are moved together, so a rule can swap fields
+	moved := map[string]interface{}{}
+	for from, to := range rule.Move {
+		if value, ok := getPath(doc, fieldPath(from)); ok {
+			moved[to] = value
+			deletePath(doc, fieldPath(from))
+		}
+	}
+	var err error
+	for to, value := range moved {
+		if doc, err = setMissingPath(doc, to, value); err != nil {
+			return nil, err
+		}
+	}
+	for path, value := range rule.Defaults {
+		if doc, err = setMissingPath(doc, path, value); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range rule.Drop {
+		deletePath(doc, fieldPath(path))
+	}
+	return doc, nil
+}
+
+// fieldPath splits a dotted path into its field names and array indexes.
+func fieldPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// arrayIndex returns the array index named by a path segment, or false if it is a field name.
+func arrayIndex(segment string) (int, bool) {
+	index, err := strconv.Atoi(segment)
+	return index, err == nil && index >= 0
+}
+
+// getPath returns the value at the path, or false if there is none.
+func getPath(node interface{}, path []string) (interface{}, bool) {
+	for _, segment := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[segment]
+			if !ok {
+				return nil, false
+			}
+			node = child
+		case []interface{}:
+			index, ok := arrayIndex(segment)
+			if !ok || index >= len(n) {
+				return nil, false
+			}
+			node = n[index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// deletePath removes the field at the path. The elements of arrays are not removed.
+func deletePath(doc interface{}, path []string) {
+	parent, ok := getPath(doc, path[:len(path)-1])
+	if !ok {
+		return
+	}
+	if fields, ok := parent.(map[string]interface{}); ok {
+		delete(fields, path[len(path)-1])
+	}
+}
+
+// setMissingPath sets the value at the path if there is none, creating the objects and arrays leading to it,
+// and returns the document.
+func setMissingPath(doc interface{}, path string, value interface{}) (interface{}, error) {
+	if _, ok := getPath(doc, fieldPath(path)); ok {
+		return doc, nil
+	}
+	doc, err := setPath(doc, fieldPath(path), value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// setPath sets the value at the path under node and returns the node, which is created when it is nil.
+// An array is extended by one element at most, so a path can't allocate a large array.
+func setPath(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	segment := path[0]
+	if node == nil {
+		if _, ok := arrayIndex(segment); ok {
+			node = []interface{}{}
+		} else {
+			node = map[string]interface{}{}
+		}
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, err := setPath(n[segment], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[segment] = child
+		return n, nil
+	case []interface{}:
+		index, ok := arrayIndex(segment)
+		if !ok || index > len(n) {
+			return nil, fmt.Errorf("index %q is out of the array of %d elements", segment, len(n))
+		}
+		if index == len(n) {
+			n = append(n, nil)
+		}
+		child, err := setPath(n[index], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = child
+		return n, nil
+	default:
+		return nil, fmt.Errorf("field %q is not an object or an array", segment)
+	}
+}
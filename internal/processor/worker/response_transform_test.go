@@ -0,0 +1,182 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the normalization of the inference response bodies.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// chatCompletionTransform normalizes the responses of a backend answering chat completions with
+// {"id","model","created","output":{"text","stop_reason"},"tokens":{"input","output"}}.
+var chatCompletionTransform = config.ResponseTransform{
+	Endpoint: string(openai.EndpointChatCompletions),
+	Move: map[string]string{
+		"output.text":        "choices.0.message.content",
+		"output.stop_reason": "choices.0.finish_reason",
+		"tokens.input":       "usage.prompt_tokens",
+		"tokens.output":      "usage.completion_tokens",
+	},
+	Defaults: map[string]interface{}{
+		"object":                  "chat.completion",
+		"choices.0.index":         0,
+		"choices.0.message.role":  "assistant",
+		"choices.0.finish_reason": "stop",
+	},
+	Drop: []string{"output", "tokens"},
+}
+
+const backendChatResponse = `{"id":"resp-1","model":"m","created":1767225600123,"output":{"text":"hello","stop_reason":"length"},"tokens":{"input":3,"output":5}}`
+
+func TestResponseNormalizer(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []config.ResponseTransform
+		endpoint openai.Endpoint
+		body     string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "no-op by default",
+			endpoint: openai.EndpointChatCompletions,
+			body:     backendChatResponse,
+			want:     backendChatResponse,
+		},
+		{
+			name:     "backend response into a chat completion",
+			rules:    []config.ResponseTransform{chatCompletionTransform},
+			endpoint: openai.EndpointChatCompletions,
+			body:     backendChatResponse,
+			want: `{"id":"resp-1","model":"m","created":1767225600123,"object":"chat.completion",` +
+				`"choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"length"}],` +
+				`"usage":{"prompt_tokens":3,"completion_tokens":5}}`,
+		},
+		{
+			name:     "conformant response kept",
+			rules:    []config.ResponseTransform{chatCompletionTransform},
+			endpoint: openai.EndpointChatCompletions,
+			body:     `{"id":"resp-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`,
+			want:     `{"id":"resp-1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`,
+		},
+		{
+			name:     "rule of another endpoint",
+			rules:    []config.ResponseTransform{chatCompletionTransform},
+			endpoint: openai.EndpointEmbeddings,
+			body:     backendChatResponse,
+			want:     backendChatResponse,
+		},
+		{
+			name:     "not an object",
+			rules:    []config.ResponseTransform{chatCompletionTransform},
+			endpoint: openai.EndpointChatCompletions,
+			body:     `["hello"]`,
+			wantErr:  true,
+		},
+		{
+			name:     "path through a value",
+			rules:    []config.ResponseTransform{{Defaults: map[string]interface{}{"id.value": "x"}}},
+			endpoint: openai.EndpointChatCompletions,
+			body:     `{"id":"resp-1"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "array index out of range",
+			rules:    []config.ResponseTransform{{Move: map[string]string{"text": "choices.3.text"}}},
+			endpoint: openai.EndpointCompletions,
+			body:     `{"text":"hi","choices":[]}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newResponseNormalizer(tt.rules).Normalize(tt.endpoint, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			var gotDoc, wantDoc interface{}
+			if err := json.Unmarshal(got, &gotDoc); err != nil {
+				t.Fatalf("Failed to parse normalized body %s: %v", got, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &wantDoc); err != nil {
+				t.Fatalf("Failed to parse expected body: %v", err)
+			}
+			if !reflect.DeepEqual(gotDoc, wantDoc) {
+				t.Errorf("Expected body %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// backendResponseClient answers the requests in the shape of a backend that is not OpenAI-conformant
+type backendResponseClient struct {
+	fakeInferenceClient
+}
+
+func (c *backendResponseClient) Generate(ctx context.Context, req *inference.GenerateRequest) (*inference.GenerateResponse, *inference.ClientError) {
+	return &inference.GenerateResponse{
+		RequestID: req.RequestID,
+		Response:  []byte(fmt.Sprintf(`{"id":"resp-%s","model":"m","output":{"text":"hello"},"tokens":{"input":3,"output":5}}`, req.RequestID)),
+	}, nil
+}
+
+func TestResponseTransforms(t *testing.T) {
+	env := setupWorkerTestEnv(t, 2)
+	env.cfg.ResponseTransforms = []config.ResponseTransform{chatCompletionTransform}
+
+	statusInfo := env.runJob(t, context.Background(), &backendResponseClient{})
+	if statusInfo.Status != openai.BatchStatusCompleted || statusInfo.RequestCounts.Completed != 2 {
+		t.Fatalf("Expected 2 completed requests, got status %s and counts %+v", statusInfo.Status, statusInfo.RequestCounts)
+	}
+	// the usage is counted from the normalized responses
+	if statusInfo.Usage == nil || statusInfo.Usage.InputTokens != 6 || statusInfo.Usage.OutputTokens != 10 {
+		t.Errorf("Expected usage of 6 input and 10 output tokens, got %+v", statusInfo.Usage)
+	}
+
+	for _, line := range env.readResponseLines(t, statusInfo.OutputFileID) {
+		var completion struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Message struct {
+					Role    string `json:"role"`
+					Content string `json:"content"`
+				} `json:"message"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal(line.Response.Body, &completion); err != nil {
+			t.Fatalf("Failed to parse output body %s: %v", line.Response.Body, err)
+		}
+		if completion.Object != "chat.completion" || len(completion.Choices) != 1 ||
+			completion.Choices[0].Message.Content != "hello" || completion.Choices[0].Message.Role != "assistant" ||
+			completion.Choices[0].FinishReason != "stop" {
+			t.Errorf("Expected a chat completion, got %s", line.Response.Body)
+		}
+	}
+}
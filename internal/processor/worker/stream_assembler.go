@@ -0,0 +1,230 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file assembles the chunks of streamed chat completions into chat completion responses.
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// chatCompletionChunk is a chat.completion.chunk object of a streamed chat completion.
+type chatCompletionChunk struct {
+	ID                string          `json:"id"`
+	Created           int64           `json:"created"`
+	Model             string          `json:"model"`
+	SystemFingerprint string          `json:"system_fingerprint"`
+	Usage             json.RawMessage `json:"usage"`
+	Error             json.RawMessage `json:"error"`
+	Choices           []struct {
+		Index        int     `json:"index"`
+		FinishReason *string `json:"finish_reason"`
+		Delta        struct {
+			Role      string  `json:"role"`
+			Content   *string `json:"content"`
+			Refusal   *string `json:"refusal"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// assembledChoice accumulates the deltas of a choice.
+type assembledChoice struct {
+	role         string
+	content      *strings.Builder
+	refusal      *strings.Builder
+	toolCalls    map[int]*assembledToolCall
+	finishReason *string
+}
+
+type assembledToolCall struct {
+	id        string
+	callType  string
+	name      string
+	arguments strings.Builder
+}
+
+// chatCompletionAssembler assembles the chunks of a streamed chat completion
+// into the body of the equivalent non-streamed chat.completion response.
+type chatCompletionAssembler struct {
+	id                string
+	created           int64
+	model             string
+	systemFingerprint string
+	usage             json.RawMessage
+	choices           map[int]*assembledChoice
+}
+
+func newChatCompletionAssembler() *chatCompletionAssembler {
+	return &chatCompletionAssembler{choices: make(map[int]*assembledChoice)}
+}
+
+// add adds a chunk to the completion. It returns an error if the chunk isn't valid or reports an error.
+func (a *chatCompletionAssembler) add(data []byte) error {
+	chunk := chatCompletionChunk{}
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return fmt.Errorf("chunk is not valid JSON: %w", err)
+	}
+	if len(chunk.Error) > 0 && string(chunk.Error) != "null" {
+		return fmt.Errorf("stream error: %s", chunk.Error)
+	}
+
+	if chunk.ID != "" {
+		a.id = chunk.ID
+	}
+	if chunk.Created != 0 {
+		a.created = chunk.Created
+	}
+	if chunk.Model != "" {
+		a.model = chunk.Model
+	}
+	if chunk.SystemFingerprint != "" {
+		a.systemFingerprint = chunk.SystemFingerprint
+	}
+	if len(chunk.Usage) > 0 && string(chunk.Usage) != "null" {
+		a.usage = append(json.RawMessage(nil), chunk.Usage...)
+	}
+
+	for _, c := range chunk.Choices {
+		choice, ok := a.choices[c.Index]
+		if !ok {
+			choice = &assembledChoice{toolCalls: make(map[int]*assembledToolCall)}
+			a.choices[c.Index] = choice
+		}
+		if c.Delta.Role != "" {
+			choice.role = c.Delta.Role
+		}
+		if c.Delta.Content != nil {
+			if choice.content == nil {
+				choice.content = &strings.Builder{}
+			}
+			choice.content.WriteString(*c.Delta.Content)
+		}
+		if c.Delta.Refusal != nil {
+			if choice.refusal == nil {
+				choice.refusal = &strings.Builder{}
+			}
+			choice.refusal.WriteString(*c.Delta.Refusal)
+		}
+		for _, tc := range c.Delta.ToolCalls {
+			call, ok := choice.toolCalls[tc.Index]
+			if !ok {
+				call = &assembledToolCall{}
+				choice.toolCalls[tc.Index] = call
+			}
+			if tc.ID != "" {
+				call.id = tc.ID
+			}
+			if tc.Type != "" {
+				call.callType = tc.Type
+			}
+			call.name += tc.Function.Name
+			call.arguments.WriteString(tc.Function.Arguments)
+		}
+		if c.FinishReason != nil {
+			choice.finishReason = c.FinishReason
+		}
+	}
+	return nil
+}
+
+// response returns the body of the assembled chat.completion response.
+func (a *chatCompletionAssembler) response() ([]byte, error) {
+	type toolCall struct {
+		ID       string `json:"id"`
+		Type     string `json:"type"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	}
+	type message struct {
+		Role      string     `json:"role"`
+		Content   *string    `json:"content"`
+		Refusal   *string    `json:"refusal,omitempty"`
+		ToolCalls []toolCall `json:"tool_calls,omitempty"`
+	}
+	type choice struct {
+		Index        int     `json:"index"`
+		Message      message `json:"message"`
+		FinishReason *string `json:"finish_reason"`
+	}
+	completion := struct {
+		ID                string          `json:"id"`
+		Object            string          `json:"object"`
+		Created           int64           `json:"created"`
+		Model             string          `json:"model"`
+		SystemFingerprint string          `json:"system_fingerprint,omitempty"`
+		Choices           []choice        `json:"choices"`
+		Usage             json.RawMessage `json:"usage,omitempty"`
+	}{
+		ID:                a.id,
+		Object:            "chat.completion",
+		Created:           a.created,
+		Model:             a.model,
+		SystemFingerprint: a.systemFingerprint,
+		Choices:           []choice{},
+		Usage:             a.usage,
+	}
+
+	for _, index := range sortedKeys(a.choices) {
+		c := a.choices[index]
+		msg := message{Role: c.role}
+		if msg.Role == "" {
+			msg.Role = "assistant"
+		}
+		if c.content != nil {
+			content := c.content.String()
+			msg.Content = &content
+		}
+		if c.refusal != nil {
+			refusal := c.refusal.String()
+			msg.Refusal = &refusal
+		}
+		for _, callIndex := range sortedKeys(c.toolCalls) {
+			call := c.toolCalls[callIndex]
+			tc := toolCall{ID: call.id, Type: call.callType}
+			if tc.Type == "" {
+				tc.Type = "function"
+			}
+			tc.Function.Name = call.name
+			tc.Function.Arguments = call.arguments.String()
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+		}
+		completion.Choices = append(completion.Choices, choice{Index: index, Message: msg, FinishReason: c.finishReason})
+	}
+	return json.Marshal(completion)
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
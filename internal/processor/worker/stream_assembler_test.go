@@ -0,0 +1,105 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestChatCompletionAssembler(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "content",
+			chunks: []string{
+				`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}`,
+				`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"m","choices":[{"index":0,"delta":{"content":"Hello"},"finish_reason":null}]}`,
+				`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"m","choices":[{"index":0,"delta":{"content":" world"},"finish_reason":"stop"}]}`,
+				`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1700000000,"model":"m","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`,
+			},
+			want: `{"id":"chatcmpl-1","object":"chat.completion","created":1700000000,"model":"m",
+				"choices":[{"index":0,"message":{"role":"assistant","content":"Hello world"},"finish_reason":"stop"}],
+				"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`,
+		},
+		{
+			name: "tool calls and multiple choices",
+			chunks: []string{
+				`{"id":"chatcmpl-2","model":"m","choices":[{"index":1,"delta":{"role":"assistant","content":"Sunny"}}]}`,
+				`{"id":"chatcmpl-2","model":"m","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+				`{"id":"chatcmpl-2","model":"m","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+				`{"id":"chatcmpl-2","model":"m","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Boston\"}"}}]},"finish_reason":"tool_calls"}]}`,
+				`{"id":"chatcmpl-2","model":"m","choices":[{"index":1,"delta":{},"finish_reason":"stop"}]}`,
+			},
+			want: `{"id":"chatcmpl-2","object":"chat.completion","created":0,"model":"m","choices":[
+				{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Boston\"}"}}]},"finish_reason":"tool_calls"},
+				{"index":1,"message":{"role":"assistant","content":"Sunny"},"finish_reason":"stop"}]}`,
+		},
+		{
+			name:    "invalid chunk",
+			chunks:  []string{`{"id":`},
+			wantErr: true,
+		},
+		{
+			name:    "error chunk",
+			chunks:  []string{`{"error":{"message":"overloaded"}}`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assembler := newChatCompletionAssembler()
+			var err error
+			for _, chunk := range tt.chunks {
+				if err = assembler.add([]byte(chunk)); err != nil {
+					break
+				}
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			body, err := assembler.response()
+			if err != nil {
+				t.Fatalf("Failed to assemble response: %v", err)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("Assembled response is not valid JSON: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("Invalid expected JSON: %v", err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("Expected response\n%s\ngot\n%s", wantJSON, gotJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// this file contains the tracing of the jobs and of their inference requests.
+// A job continues the trace of the request that created it, and each inference request is a span of the job.
+package worker
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/metrics"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/util/tracing"
+)
+
+// startJobSpan starts the span of the processing of a job, in the trace stored with the job.
+func startJobSpan(ctx context.Context, job *db.BatchJob) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(tracing.Extract(ctx, job.TraceContext), "process batch",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("batch.id", job.ID),
+			attribute.String("batch.tenant_id", batch.GetTenantIDFromTags(job.Tags)),
+		))
+}
+
+// endJobSpan ends the span of a job with its result.
+func endJobSpan(span trace.Span, result, reason string, total int) {
+	span.SetAttributes(attribute.String("batch.result", result), attribute.Int("batch.requests.total", total))
+	if result == metrics.ResultFailed {
+		span.SetStatus(codes.Error, reason)
+	}
+	span.End()
+}
+
+// tracedGenerate sends an inference request in its own span.
+func (p *Processor) tracedGenerate(ctx context.Context, req *inference.GenerateRequest, model string) (*inference.GenerateResponse, *inference.ClientError) {
+	ctx, span := tracing.Tracer().Start(ctx, "inference "+req.Endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("batch.custom_id", req.RequestID),
+			attribute.String("inference.model", model),
+		))
+	defer span.End()
+
+	resp, genErr := p.generate(ctx, req)
+	if genErr != nil {
+		span.SetAttributes(attribute.String("error.type", string(genErr.Category)))
+		span.SetStatus(codes.Error, genErr.Message)
+	}
+	return resp, genErr
+}
@@ -18,26 +18,62 @@ limitations under the License.
 package worker
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"k8s.io/klog/v2"
 
 	db "github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	files "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
 	"github.com/llm-d-incubation/batch-gateway/internal/inference"
 	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
 	"github.com/llm-d-incubation/batch-gateway/internal/processor/metrics"
 	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
 	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
 )
 
+const (
+	// statusTTLSeconds is the TTL of the temporary job status and checkpoint records (24h)
+	statusTTLSeconds = 24 * 60 * 60
+
+	// requeueTimeout bounds putting an interrupted job back to the queue
+	requeueTimeout = 5 * time.Second
+
+	// defaultDrainLogInterval is the interval of the progress logs while the processor waits for its workers on shutdown
+	defaultDrainLogInterval = 5 * time.Second
+)
+
+// jobInterruption is the phase in which the processing of a job was interrupted.
+type jobInterruption int
+
+const (
+	// notInterrupted means the job was finalized, successfully or not
+	notInterrupted jobInterruption = iota
+	// interruptedValidating means the job was stopped before its lines were processed
+	interruptedValidating
+	// interruptedInProgress means the job was stopped while its lines were processed
+	interruptedInProgress
+)
+
 type ProcessorClients struct {
 	database      db.BatchDBClient
 	priorityQueue db.BatchPriorityQueueClient
 	status        db.BatchStatusClient
 	event         db.BatchEventChannelClient
+	fileDatabase  db.BatchFileDBClient
+	files         files.BatchFilesClient
 	inference     inference.Client
 }
 
@@ -46,6 +82,8 @@ func NewProcessorClients(
 	pq db.BatchPriorityQueueClient,
 	status db.BatchStatusClient,
 	event db.BatchEventChannelClient,
+	fileDB db.BatchFileDBClient,
+	files files.BatchFilesClient,
 	inference inference.Client,
 ) ProcessorClients {
 	return ProcessorClients{
@@ -53,6 +91,8 @@ func NewProcessorClients(
 		priorityQueue: pq,
 		status:        status,
 		event:         event,
+		fileDatabase:  fileDB,
+		files:         files,
 		inference:     inference,
 	}
 }
@@ -61,7 +101,16 @@ type Processor struct {
 	cfg        *config.ProcessorConfig
 	workerPool *WorkerPool
 
-	clients *ProcessorClients
+	clients        *ProcessorClients
+	callbacks      *callbackNotifier
+	locate         batch.FileLocator
+	replicaID      string             // identifies the job claims of the replica
+	inferenceSlots inferenceLimiter   // caps the inference requests in flight across the jobs
+	normalizer     ResponseNormalizer // rewrites the response bodies of the backend into the OpenAI shape
+
+	jobs             sync.WaitGroup // the goroutines of the started jobs, done once their worker is released
+	draining         atomic.Bool    // set when Stop waits for the workers to finish
+	drainLogInterval time.Duration  // the interval of the progress logs of the drain
 }
 
 func NewProcessor(
@@ -69,9 +118,16 @@ func NewProcessor(
 	clients *ProcessorClients,
 ) *Processor {
 	return &Processor{
-		cfg:        cfg,
-		workerPool: NewWorkerPool(cfg.NumWorkers),
-		clients:    clients,
+		cfg:            cfg,
+		workerPool:     NewReservedWorkerPool(cfg.NumWorkers, cfg.WorkerReservations),
+		clients:        clients,
+		callbacks:      newCallbackNotifier(cfg),
+		locate:         batch.TenantFileLocation,
+		replicaID:      newReplicaID(),
+		inferenceSlots: newInferenceLimiter(cfg.MaxInferenceConcurrency),
+		normalizer:     newResponseNormalizer(cfg.ResponseTransforms),
+
+		drainLogInterval: defaultDrainLogInterval,
 	}
 }
 
@@ -88,6 +144,12 @@ func (pc *ProcessorClients) Validate() error {
 	if pc.event == nil {
 		return fmt.Errorf("event channel client is missing")
 	}
+	if pc.fileDatabase == nil {
+		return fmt.Errorf("file database client is missing")
+	}
+	if pc.files == nil {
+		return fmt.Errorf("files client is missing")
+	}
 	if pc.inference == nil {
 		return fmt.Errorf("inference client is missing")
 	}
@@ -106,7 +168,7 @@ func (p *Processor) prepare(ctx context.Context) error {
 	return nil
 }
 
-// TODO: events implementation (cancel, pause, resume)
+// TODO: events implementation (pause, resume)
 // RunPollingLoop runs the main job polling loop for the processor, try assign the job to the worker,
 func (p *Processor) RunPollingLoop(ctx context.Context) error {
 	if err := p.prepare(ctx); err != nil {
@@ -119,20 +181,47 @@ func (p *Processor) RunPollingLoop(ctx context.Context) error {
 		"maxWorkers", p.cfg.NumWorkers,
 	)
 
+	// put back to the queue the jobs left without a worker, such as the jobs of crashed replicas
+	if p.cfg.JobRecoveryInterval > 0 {
+		var recovery sync.WaitGroup
+		defer recovery.Wait()
+		recovery.Add(1)
+		go func() {
+			defer recovery.Done()
+			p.runJobRecovery(ctx)
+		}()
+	}
+
+	// job waiting for a worker reclaimed from another tenant
+	var reclaiming *reclaimingJob
+
 	// worker driven non-busy wait
 	for {
-		var workerId int
-		select {
-		case <-ctx.Done():
-			return nil
-		case id, ok := <-p.workerPool.workerIds: // wait until at least one worker is available
+		// a reclaimed worker is released soon, assign it to the waiting job
+		if reclaiming != nil {
+			workerId, ok := p.workerPool.Acquire(ctx)
 			if !ok {
+				// the job was dequeued, it is put back to the queue for the next processor
+				p.requeueJob(ctx, reclaiming.task)
 				return nil
 			}
-			workerId = id
+			p.startJob(ctx, workerId, reclaiming.task, reclaiming.job)
+			reclaiming = nil
+			continue
+		}
+
+		workerId, ok := p.waitForWorker(ctx) // wait until at least one worker is available
+		if !ok {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// no worker was released while reserved workers are lent to other tenants
+			reclaiming = p.reclaimWorker(ctx)
+			continue
 		}
 
 		// check queue for available tasks
+		p.recordQueueDepth(ctx)
 		task := p.getTaskFromQueue(ctx)
 
 		// when there's no waiting tasks in the queue
@@ -154,26 +243,190 @@ func (p *Processor) RunPollingLoop(ctx context.Context) error {
 			continue
 		}
 
-		// TODO:: get tenant id from job.Spec
-		// tenantID := "unknown"
 		// TODO:: job queue object should have enqueued at field (maybe updated at too)
 		// TODO:: metrics.RecordQueueWait(time.Since(task.EnqueuedAt), tenantID)
 
 		// process job
-		go func(wid int, j *db.BatchJob) {
-			defer func() {
-				if r := recover(); r != nil {
-					recoverErr := fmt.Errorf("%v", r)
-					logger.V(logging.ERROR).Error(recoverErr, "Panic recovered", "workerID", wid)
-				}
-				p.workerPool.Release(wid)
-				metrics.DecActiveWorkers()
-			}()
+		p.startJob(ctx, workerId, task, jobDbData)
+	}
+}
+
+// reclaimingJob is a job waiting for a reserved worker that is reclaimed from another tenant.
+type reclaimingJob struct {
+	task *db.BatchJobPriority
+	job  *db.BatchJob
+}
 
-			metrics.IncActiveWorkers()
-			p.processJob(ctx, wid, j)
-		}(workerId, jobDbData)
+// waitForWorker waits until a worker is available and returns its id.
+// While reserved workers are lent to other tenants, it gives up after the poll interval,
+// so that the tenants they are reserved for can reclaim them.
+func (p *Processor) waitForWorker(ctx context.Context) (int, bool) {
+	if !p.workerPool.HasBorrowed() {
+		return p.workerPool.Acquire(ctx)
 	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, p.cfg.PollInterval)
+	defer cancel()
+	return p.workerPool.Acquire(waitCtx)
+}
+
+// reclaimWorker checks if the job at the head of the queue belongs to a tenant whose reserved workers are lent,
+// and reclaims one of them for the job. If so, the job is returned to wait for the reclaimed worker.
+func (p *Processor) reclaimWorker(ctx context.Context) *reclaimingJob {
+	logger := klog.FromContext(ctx)
+
+	task := p.getTaskFromQueue(ctx)
+	if task == nil {
+		return nil
+	}
+	job, err := p.getJobData(ctx, task)
+	if err != nil {
+		return nil
+	}
+
+	tenantID := batch.GetTenantIDFromTags(job.Tags)
+	if p.workerPool.Reclaim(tenantID) {
+		logger.V(logging.INFO).Info("Reclaiming a reserved worker", "jobID", job.ID, "tenantID", tenantID)
+		return &reclaimingJob{task: task, job: job}
+	}
+
+	// the job waits in the queue for a worker to be released
+	p.requeueJob(ctx, task)
+	return nil
+}
+
+// startJob claims the job, leases the worker to the job's tenant and processes the job in the background.
+// Jobs claimed by other workers, of this or another replica, are skipped. If their worker stops without
+// finalizing or re-queueing them, the job recovery puts them back to the queue once their claim expired.
+// If the worker is borrowed and gets reclaimed, the claim is lost, or the processor shuts down, the job is stopped
+// and put back to the queue with its original priority to resume later from its last checkpoint.
+func (p *Processor) startJob(ctx context.Context, workerId int, task *db.BatchJobPriority, job *db.BatchJob) {
+	logger := klog.FromContext(ctx)
+
+	claim, job, err := p.claimJob(ctx, job.ID)
+	if err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to claim job. re-queueing ID", "jobID", task.ID)
+		p.workerPool.Release(workerId)
+		p.requeueJob(ctx, task)
+		return
+	}
+	if claim == nil {
+		logger.V(logging.DEBUG).Info("Job is claimed by another worker or finalized, skipping", "jobID", task.ID)
+		p.workerPool.Release(workerId)
+		return
+	}
+
+	lease := p.workerPool.Assign(workerId, batch.GetTenantIDFromTags(job.Tags))
+	p.recordWorkerUtilization()
+	logger.V(logging.DEBUG).Info("Worker assigned", "jobID", job.ID, "workerID", workerId,
+		"tenantID", lease.TenantID, "lease", lease.Kind, "owner", lease.Owner)
+
+	p.jobs.Add(1)
+	go func() {
+		defer p.jobs.Done()
+		jobCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		defer func() {
+			if r := recover(); r != nil {
+				recoverErr := fmt.Errorf("%v", r)
+				logger.V(logging.ERROR).Error(recoverErr, "Panic recovered", "workerID", workerId)
+			}
+			p.workerPool.Release(workerId)
+			p.recordWorkerUtilization()
+			p.recordDrainingJobs()
+			metrics.DecActiveWorkers()
+		}()
+
+		// stop the job when the borrowed worker is reclaimed
+		go func() {
+			select {
+			case <-lease.Reclaimed():
+				cancel()
+			case <-jobCtx.Done():
+			}
+		}()
+
+		// stop the job when its claim is lost
+		go p.keepJobClaim(jobCtx, claim, cancel)
+
+		metrics.IncActiveWorkers()
+		interrupted := p.processJob(jobCtx, workerId, job)
+
+		// the claim is released before the job is re-queued, so the replica picking it up can claim it
+		p.releaseJob(ctx, claim)
+		switch {
+		case interrupted == interruptedValidating:
+			// no processor owns the job anymore. it restarts its validation when it is picked up again
+			logger.V(logging.INFO).Info("Validation interrupted, re-queueing job", "jobID", job.ID, "workerID", workerId)
+			p.requeueJob(ctx, task)
+		case interrupted == interruptedInProgress:
+			// the job resumes from its last checkpoint when it is picked up again, by this or another replica
+			reason := "shutdown"
+			if claim.lost.Load() {
+				reason = "job claim lost"
+			} else if lease.IsReclaimed() && ctx.Err() == nil {
+				reason = "worker reclaimed"
+			}
+			logger.V(logging.INFO).Info("Job interrupted, re-queueing job", "jobID", job.ID, "workerID", workerId, "reason", reason)
+			p.requeueJob(ctx, task)
+		}
+	}()
+}
+
+// requeueJob puts an interrupted job back to the queue.
+// The job is re-queued even if ctx was cancelled by the shutdown, so another replica picks it up.
+func (p *Processor) requeueJob(ctx context.Context, task *db.BatchJobPriority) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), requeueTimeout)
+	defer cancel()
+
+	if err := p.clients.priorityQueue.Enqueue(ctx, task); err != nil {
+		klog.FromContext(ctx).V(logging.ERROR).Error(err, "CRITICAL: Failed to re-enqueue job", "jobID", task.ID)
+	}
+}
+
+// validationContext returns the context used to validate a job.
+// It is cancelled ValidationShutdownTimeout after ctx, so a validation in progress during shutdown can complete.
+func (p *Processor) validationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.cfg.ValidationShutdownTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	valctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	var timer *time.Timer
+	var mu sync.Mutex
+	stop := context.AfterFunc(ctx, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		timer = time.AfterFunc(p.cfg.ValidationShutdownTimeout, cancel)
+	})
+	return valctx, func() {
+		stop()
+		mu.Lock()
+		if timer != nil {
+			timer.Stop()
+		}
+		mu.Unlock()
+		cancel()
+	}
+}
+
+// recordQueueDepth records the number of jobs waiting in the queue to be started.
+func (p *Processor) recordQueueDepth(ctx context.Context) {
+	n, err := p.clients.priorityQueue.Len(ctx)
+	if err != nil {
+		klog.FromContext(ctx).V(logging.WARNING).Info("Failed to get queue length", "err", err)
+		return
+	}
+	metrics.RecordQueueDepth(n)
+}
+
+// recordWorkerUtilization records the number of reserved, shared and borrowed workers in use.
+func (p *Processor) recordWorkerUtilization() {
+	reserved, shared, borrowed := p.workerPool.Utilization()
+	metrics.SetWorkersInUse(string(LeaseReserved), reserved)
+	metrics.SetWorkersInUse(string(LeaseShared), shared)
+	metrics.SetWorkersInUse(string(LeaseBorrowed), borrowed)
 }
 
 // getTask is executed when at least one worker is available
@@ -223,17 +476,13 @@ func (p *Processor) getJobData(ctx context.Context, task *db.BatchJobPriority) (
 	return jobs[0], nil
 }
 
-// TODO:: complete job processing logic
-// read input file, streaming, line processing, result writing, etc.
-// TODO:: add event handling (cancel, pause, resume)
-// TODO:: add metrics (job duration, job processed, job result, job failure reason)
-// TODO:: add logging (job started, job finished, job failed)
-// TODO:: add error handling (error handling. inference request failed)
-// TODO:: add response handling (response handling + writing line to the output file ...)
-// TODO:: add output file writing (output file writing)
-// TODO:: add output file reading (output file reading)
-// TODO:: add output file closing (output file closing)
-func (p *Processor) processJob(ctx context.Context, workerId int, job *db.BatchJob) {
+// TODO:: add event handling (pause, resume)
+// processJob reads the input file of a job line by line, sends each request line to the inference gateway,
+// and writes the results to the output and error files of the job.
+// Progress is checkpointed every CheckpointInterval lines, so an interrupted job resumes where it left off.
+// A job cancelled while it is processed stops its lines and is finalized as cancelled, see cancelJob.
+// It returns the phase in which the job was interrupted by the cancellation of ctx, if it wasn't finalized.
+func (p *Processor) processJob(ctx context.Context, workerId int, job *db.BatchJob) (interrupted jobInterruption) {
 	// logger and ctx
 	logger := klog.FromContext(ctx).WithValues("jobID", job.ID, "workerID", workerId)
 	jobctx := klog.NewContext(ctx, logger)
@@ -241,140 +490,941 @@ func (p *Processor) processJob(ctx context.Context, workerId int, job *db.BatchJ
 	// metrics
 	startTime := time.Now()
 	metadata := batch.JobResultMetadata{}
+	jobResult := metrics.ResultSuccess
+	jobFailureReason := metrics.ReasonUnknown
 	defer func() {
-		// TODO:: get tenant id from job.Spec (should be included in the job object)
-		// job result / failure reason for metric
 		// TODO:: how to check if the failure is on user or system
-		tenantID := "unknown"
-		jobFailureReason := metrics.ReasonUnknown
-		jobResult := metrics.ResultSuccess
+		tenantID := batch.GetTenantIDFromTags(job.Tags)
 
 		metrics.RecordJobProcessingDuration(time.Since(startTime), tenantID, metrics.GetSizeBucket(metadata.Total))
-		metrics.RecordJobProcessed(jobResult, jobFailureReason)
+		metrics.RecordJobProcessed(jobResult, jobFailureReason, tenantID)
 	}()
 
-	// status update - inprogress (TTL 24h)
-	p.clients.status.Set(jobctx, job.ID, 24*60*60, []byte(batch.StatusInProgress))
+	jobctx, span := startJobSpan(jobctx, job)
+	defer func() { endJobSpan(span, jobResult, jobFailureReason, metadata.Total) }()
+
+	spec := openai.BatchSpec{}
+	if err := json.Unmarshal(job.Spec, &spec); err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to parse job spec")
+		jobResult, jobFailureReason = metrics.ResultFailed, metrics.ReasonSystemError
+		return
+	}
+	statusInfo := openai.BatchStatusInfo{}
+	if len(job.Status) > 0 {
+		if err := json.Unmarshal(job.Status, &statusInfo); err != nil {
+			logger.V(logging.ERROR).Error(err, "Failed to parse job status")
+			jobResult, jobFailureReason = metrics.ResultFailed, metrics.ReasonSystemError
+			return
+		}
+	}
+
+	// a job cancelled while it was queued or interrupted is cancelled once validated
+	cancellation := p.watchJobCancel(jobctx, job.ID)
+	defer cancellation.stop()
+	if statusInfo.Status == openai.BatchStatusCancelling {
+		cancellation.request()
+	}
+
+	// status update - validating
+	// the validation may outlive ctx for a while, so a shutdown doesn't leave it half done
+	valctx, cancelValidation := p.validationContext(jobctx)
+	defer cancelValidation()
+	p.setStatus(valctx, job.ID, batch.StatusValidating)
 	logger.V(logging.DEBUG).Info("Worker started job", "workerID", workerId, "jobID", job.ID)
 
-	// TODO:: file validating
-	p.clients.status.Set(jobctx, job.ID, 24*60*60, []byte(batch.StatusValidating))
+	input, closeInput, err := p.openInputFile(valctx, spec.InputFileID)
+	if err != nil {
+		if valctx.Err() != nil {
+			logger.V(logging.INFO).Info("Stopping validation due to shutdown")
+			return interruptedValidating
+		}
+		logger.V(logging.ERROR).Error(err, "Failed to retrieve input file", "inputFileID", spec.InputFileID)
+		jobResult, jobFailureReason = metrics.ResultFailed, metrics.ReasonUserError
+		p.failJob(valctx, job, &statusInfo, inputFileFailure(spec.InputFileID, err))
+		return
+	}
+	defer closeInput()
+
+	// the model is detected by the first validation of the job, a resumed job keeps it
+	if statusInfo.Model == "" {
+		model, err := p.detectModel(valctx, &spec)
+		if err != nil {
+			if valctx.Err() != nil {
+				logger.V(logging.INFO).Info("Stopping validation due to shutdown")
+				return interruptedValidating
+			}
+			// the model is informational, the job is processed without it
+			logger.V(logging.WARNING).Info("Failed to detect the model of the input file", "inputFileID", spec.InputFileID, "err", err)
+		}
+		statusInfo.Model = model
+	}
+
+	// resume from the last checkpoint, if any
+	cp, out, err := p.openJobOutput(valctx, job.ID)
+	if err != nil {
+		if valctx.Err() != nil {
+			logger.V(logging.INFO).Info("Stopping validation due to shutdown")
+			return interruptedValidating
+		}
+		logger.V(logging.ERROR).Error(err, "Failed to open job output files")
+		jobResult, jobFailureReason = metrics.ResultFailed, errorReason(err)
+		p.failJob(valctx, job, &statusInfo, internalFailure())
+		return
+	}
+	defer out.Close()
+	metadata = batch.JobResultMetadata{Total: cp.Total, Succeeded: cp.Succeeded, Failed: cp.Failed, TimedOut: cp.TimedOut, Usage: cp.Usage}
+
+	// a job validated during shutdown is not started, it is left to another replica
+	if jobctx.Err() != nil {
+		logger.V(logging.INFO).Info("Not starting validated job due to shutdown")
+		return interruptedValidating
+	}
+
+	if cancellation.requested.Load() {
+		if err := p.cancelJob(jobctx, job, &spec, &statusInfo, cp, out, &metadata); err != nil {
+			logger.V(logging.ERROR).Error(err, "Failed to cancel job")
+			jobResult, jobFailureReason = metrics.ResultFailed, errorReason(err)
+			p.abortJob(jobctx, job, &statusInfo, cp, out, internalFailure())
+			return
+		}
+		jobResult = metrics.ResultCancelled
+		logger.V(logging.INFO).Info("Job cancelled before processing")
+		return notInterrupted
+	}
+
+	// status update - in progress
+	if statusInfo.InProgressAt == nil {
+		inProgressAt := time.Now().UTC().Unix()
+		statusInfo.InProgressAt = &inProgressAt
+	}
+	statusInfo.Status = openai.BatchStatusInProgress
+	p.updateJobStatus(jobctx, job, &statusInfo)
+	p.setStatus(jobctx, job.ID, batch.StatusInProgress)
+
+	// the request counts are updated at each checkpoint, to report the progress of the job
+	reportProgress := func() {
+		statusInfo.RequestCounts = requestCounts(&metadata)
+		statusInfo.Usage = jobUsage(&metadata)
+		p.updateJobStatus(jobctx, job, &statusInfo)
+	}
+	expiresAt := jobExpiresAt(job, &statusInfo)
+	// the requests of the timed out lines kept in flight are stopped with the job, which waits for them
+	lateCtx, stopLate := context.WithCancel(cancellation.ctx)
+	late := &lateLines{ctx: lateCtx}
+	defer late.wait()
+	defer stopLate()
+	// the lines are stopped by the cancellation of the job, but its progress is reported until it is finalized
+	if err := p.processLines(cancellation.ctx, job.ID, &spec, expiresAt, input, cp, out, &metadata, late, reportProgress); err != nil {
+		var failure *jobFailure
+		if errors.As(err, &failure) {
+			logger.V(logging.ERROR).Error(err, "Job failed permanently")
+			jobResult, jobFailureReason = metrics.ResultFailed, metrics.ReasonUserError
+			p.abortJob(jobctx, job, &statusInfo, cp, out, failure)
+			return
+		}
+		if jobctx.Err() != nil {
+			logger.V(logging.INFO).Info("Stopping line processing due to shutdown", "lineOffset", cp.LineOffset)
+			return interruptedInProgress
+		}
+		if cancellation.requested.Load() {
+			if err := p.cancelJob(jobctx, job, &spec, &statusInfo, cp, out, &metadata); err != nil {
+				logger.V(logging.ERROR).Error(err, "Failed to cancel job")
+				jobResult, jobFailureReason = metrics.ResultFailed, errorReason(err)
+				p.abortJob(jobctx, job, &statusInfo, cp, out, internalFailure())
+				return
+			}
+			jobResult = metrics.ResultCancelled
+			logger.V(logging.INFO).Info("Job cancelled", "lineOffset", cp.LineOffset, "metadata", metadata)
+			return notInterrupted
+		}
+		logger.V(logging.ERROR).Error(err, "Failed to process input file")
+		jobResult, jobFailureReason = metrics.ResultFailed, errorReason(err)
+		p.abortJob(jobctx, job, &statusInfo, cp, out, internalFailure())
+		return
+	}
+
+	// final status decision
+	// like an openai batch, a job whose lines were all resolved is completed even when some or all of its requests
+	// failed: the failed requests are reported in the error file and the failed count. The failed status is reserved
+	// for the failures of the batch itself, such as an unreadable input file or rejected credentials (see jobFailure)
+	finalStatus := batch.StatusCompleted
+	if metadata.Failed > 0 {
+		logger.V(logging.WARNING).Info("Job finished with failed requests", "jobID", job.ID, "metadata", metadata)
+	}
+
+	// status update - finalizing
+	p.setStatus(jobctx, job.ID, batch.StatusFinalizing)
+	finalizingAt := time.Now().UTC().Unix()
+	statusInfo.FinalizingAt = &finalizingAt
+
+	if err := p.recoverLateLines(jobctx, job.ID, late, cp, out, &metadata); err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to recover late lines")
+		jobResult, jobFailureReason = metrics.ResultFailed, errorReason(err)
+		p.abortJob(jobctx, job, &statusInfo, cp, out, internalFailure())
+		return
+	}
+	// the job completes with its timed out requests, they are the reason of its failed requests
+	if metadata.TimedOut > 0 {
+		jobFailureReason = metrics.ReasonTimeout
+	}
+	if err := p.storeJobOutput(jobctx, job, &spec, &statusInfo, cp, out, &metadata); err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to store job output")
+		jobResult, jobFailureReason = metrics.ResultFailed, errorReason(err)
+		p.abortJob(jobctx, job, &statusInfo, cp, out, internalFailure())
+		return
+	}
+
+	// db update
+	completedAt := time.Now().UTC().Unix()
+	statusInfo.Status = openai.BatchStatus(finalStatus)
+	statusInfo.CompletedAt = &completedAt
+	statusInfo.RequestCounts = requestCounts(&metadata)
+	statusInfo.Usage = jobUsage(&metadata)
+	p.updateJobStatus(jobctx, job, &statusInfo)
+	p.setStatus(jobctx, job.ID, finalStatus)
+	recordJobSLO(job, &statusInfo, time.Unix(completedAt, 0))
+	p.notifyCallback(jobctx, job, &statusInfo)
+
+	p.cleanupJobOutput(jobctx, job.ID, cp)
+	logger.V(logging.INFO).Info("Job Processed", "jobID", job.ID, "status", finalStatus)
+	return notInterrupted
+}
+
+// openInputFile returns a reader of the content of an input file, decompressed if the file was uploaded compressed,
+// and a func closing the file.
+func (p *Processor) openInputFile(ctx context.Context, fileID string) (io.Reader, func(), error) {
+	records, _, err := p.clients.fileDatabase.Get(ctx, []string{fileID}, nil, db.TagsLogicalCondNa, 0, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("input file %s: %w", fileID, files.ErrFileNotFound)
+	}
+	input, _, err := p.clients.files.Retrieve(ctx, records[0].ContentLocation())
+	if err != nil {
+		return nil, nil, err
+	}
+	closeInput := func() {
+		if closer, ok := input.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	reader, err := batch.NewInputReader(input)
+	if err != nil {
+		closeInput()
+		return nil, nil, err
+	}
+	return reader, closeInput, nil
+}
+
+// detectModel returns the model targeted by all the valid lines of the input file of a batch,
+// or an empty string if the lines target different models.
+func (p *Processor) detectModel(ctx context.Context, spec *openai.BatchSpec) (string, error) {
+	input, closeInput, err := p.openInputFile(ctx, spec.InputFileID)
+	if err != nil {
+		return "", err
+	}
+	defer closeInput()
+
+	var model string
+	found := false
+	reader := bufio.NewReader(input)
+	for {
+		data, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return "", readErr
+		}
+
+		data = bytes.TrimSpace(data)
+		if len(data) > 0 {
+			// invalid lines are not sent to any model
+			if line, lineErr := batch.ParseRequestLine(data, spec.Endpoint, spec.MixedEndpoints, spec.ValidationStrictness); lineErr == nil {
+				lineModel, _ := line.Body["model"].(string)
+				if found && lineModel != model {
+					return "", nil
+				}
+				model, found = lineModel, true
+			}
+		}
+
+		if readErr == io.EOF {
+			return model, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+	}
+}
+
+// processLines processes the input lines after the checkpoint's line offset, in chunks of CheckpointInterval lines.
+// The checkpoint is saved after each chunk once all of its lines are written to the output files,
+// then onCheckpoint is called.
+func (p *Processor) processLines(
+	ctx context.Context, jobID string, spec *openai.BatchSpec, expiresAt time.Time, input io.Reader,
+	cp *checkpoint, out *jobOutput, metadata *batch.JobResultMetadata, late *lateLines, onCheckpoint func(),
+) error {
+	logger := klog.FromContext(ctx)
+	reader := bufio.NewReader(input)
+
+	var lineNum int64
+	chunk := make([][]byte, 0, p.cfg.CheckpointInterval)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			lineNum++
+			if lineNum > cp.LineOffset {
+				chunk = append(chunk, line)
+			}
+		}
+
+		if len(chunk) == p.cfg.CheckpointInterval || (readErr == io.EOF && len(chunk) > 0) {
+			if failure := p.processChunk(ctx, spec, expiresAt, chunk, out, metadata, late); failure != nil {
+				return failure
+			}
+			if err := ctx.Err(); err != nil {
+				// lines of an interrupted chunk are discarded on resume
+				return err
+			}
+
+			if err := p.commitCheckpoint(ctx, jobID, lineNum, cp, out, metadata); err != nil {
+				return err
+			}
+			logger.V(logging.TRACE).Info("Checkpoint saved", "lineOffset", cp.LineOffset)
+			onCheckpoint()
+			chunk = chunk[:0]
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// chunkLine is the result of a line of a chunk, waiting to be written.
+type chunkLine struct {
+	result  *batch.ResponseLine
+	failed  bool
+	skipped bool // the line was stopped, it is not written
+	usage   openai.BatchUsage
+	release func()
+}
+
+// processChunk processes the lines of a chunk concurrently, limited by the job's max concurrency.
+// If a line fails in a way that fails the whole job, the other lines are stopped and the job failure is returned.
+// The result lines are written as they complete, or in the order of the chunk when the input order is preserved.
+func (p *Processor) processChunk(
+	ctx context.Context, spec *openai.BatchSpec, expiresAt time.Time, lines [][]byte,
+	out *jobOutput, metadata *batch.JobResultMetadata, late *lateLines,
+) *jobFailure {
+	logger := klog.FromContext(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// TODO:: download file, streaming
-	// check if the method in the request is allowed
-	// check if the model in the request is allowed (optional)
-	// set total request num in result obj + init other fields
-	// goroutine per one line reading
-	// limit goroutines using config's max job concurrency
 	sem := make(chan struct{}, p.cfg.MaxJobConcurrency)
 	var wg sync.WaitGroup
 	var mu sync.Mutex // for metadata update
+	var failure *jobFailure
 
-	// TODO:: mock file lines
-	lines := []string{"req1", "req2", "req3"}
-
-	// result metadata init
-	metadata = batch.JobResultMetadata{
-		Total:     len(lines),
-		Succeeded: 0,
-		Failed:    0,
+	// with the input order preserved, the lines completed before a previous line wait in pending,
+	// and are written once all the previous lines were written
+	var pending []*chunkLine
+	next := 0
+	if p.cfg.PreserveInputOrder {
+		pending = make([]*chunkLine, len(lines))
 	}
 
-	// TODO:: read lines + process (mockup)
-	lineChan := make(chan string)
-	go func() {
-		for _, l := range lines {
-			lineChan <- l
+	// write writes a completed line, the lock must be held
+	write := func(cl *chunkLine) {
+		defer cl.release()
+		if cl.skipped {
+			return
 		}
-		close(lineChan)
-	}()
+		metadata.Total++
+		writer := out.output
+		if cl.failed {
+			writer = out.errors
+			metadata.Failed++
+			if isTimeoutLine(cl.result) {
+				metadata.TimedOut++
+			}
+		} else {
+			metadata.Succeeded++
+			metadata.Usage.Add(cl.usage)
+		}
+		if err := writer.Write(cl.result); err != nil {
+			logger.V(logging.ERROR).Error(err, "Failed to write result line", "customID", cl.result.CustomID)
+		}
+	}
 
-	for line := range lineChan {
+lineLoop:
+	for i, line := range lines {
 		// check context termination
 		select {
-		case <-jobctx.Done():
-			logger.V(logging.INFO).Info("Stopping line processing due to shutdown")
-			return
+		case <-ctx.Done():
+			break lineLoop
 		case sem <- struct{}{}: // wait here if max concurrency is reached
 		}
 		wg.Add(1)
-		go func(l string) {
+		go func(i int, l []byte) {
 			defer func() {
 				<-sem
 				wg.Done()
 			}()
 
-			// check again for signal in the goroutine
-			select {
-			case <-jobctx.Done():
-				return
-			default:
+			result, failed, release := p.processLine(ctx, spec, expiresAt, l, late)
+			cl := &chunkLine{result: result, failed: failed, release: release}
+			if !failed {
+				cl.usage = responseUsage(result.Response.Body)
 			}
-			// TODO:: line parsing
-			// TODO:: check allowed methods
-			// TODO:: request validation
-
-			// mock request
-			mockRequest := &inference.GenerateRequest{}
-			result, err := p.clients.inference.Generate(jobctx, mockRequest)
 
-			// shared resources (metadata / totaljoblines) lock
+			// shared resources (metadata / output files) lock
 			mu.Lock()
 			defer mu.Unlock()
 
-			if err != nil {
-				p.handleError(jobctx, err)
-				metadata.Failed++
+			// a line failed once the chunk is stopped is not written: it was stopped, and is either processed
+			// again on resume, or discarded with the job
+			cl.skipped = failed && ctx.Err() != nil
+			if failed && !cl.skipped && failure == nil {
+				if failure = lineJobFailure(result.Error); failure != nil {
+					cancel()
+				}
+			}
+
+			if pending == nil {
+				write(cl)
 				return
 			}
+			pending[i] = cl
+			for ; next < len(pending) && pending[next] != nil; next++ {
+				write(pending[next])
+				pending[next] = nil
+			}
+		}(i, line)
+	}
+	wg.Wait()
+
+	// the lines waiting for a line that was not started are not written, the chunk was stopped
+	for _, cl := range pending {
+		if cl != nil {
+			cl.release()
+		}
+	}
+	return failure
+}
+
+// processLine sends the request of an input line to the inference gateway.
+// The request must complete before the batch expires; lines that cannot start before expiry are failed.
+// It returns the line to write to the output file, or to the error file when failed is true,
+// and a func releasing the inference response referenced by the line, to call once the line is written.
+// The request of a line that times out may be kept in flight in late, to recover the line from a late response.
+func (p *Processor) processLine(
+	ctx context.Context, spec *openai.BatchSpec, expiresAt time.Time, line []byte, late *lateLines,
+) (result *batch.ResponseLine, failed bool, release func()) {
+	noRelease := func() {}
 
-			if err := p.handleResponse(jobctx, result); err != nil {
-				metadata.Failed++
-			} else {
-				metadata.Succeeded++
+	// in a mixed-endpoint batch, the request is sent to the endpoint of the line
+	reqLine, lineErr := batch.ParseRequestLine(line, spec.Endpoint, spec.MixedEndpoints, spec.ValidationStrictness)
+	if lineErr != nil {
+		customID := ""
+		if reqLine != nil {
+			customID = reqLine.CustomID
+		}
+		return newErrorLine(customID, lineErr.Code, lineErr.Message), true, noRelease
+	}
+
+	// the wait for a request slot doesn't count against the per-line timeout
+	if err := p.inferenceSlots.acquire(ctx); err != nil {
+		// the job is stopping, the line is processed again on resume
+		return newErrorLine(reqLine.CustomID, string(inference.ErrCategoryUnknown), "request cancelled"), true, noRelease
+	}
+	// the slot of a request kept in flight after its line timed out is released once the request is done
+	kept := false
+	defer func() {
+		if !kept {
+			p.inferenceSlots.release()
+		}
+	}()
+
+	model, _ := reqLine.Body["model"].(string)
+	timeout := p.lineTimeout(time.Now(), expiresAt)
+	if timeout <= 0 {
+		metrics.RecordLineTimeout(model, batch.LineErrorCodeBatchExpired)
+		return newErrorLine(reqLine.CustomID, batch.LineErrorCodeBatchExpired, "batch expired before the request could be sent"), true, noRelease
+	}
+	lineCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	target, aliased := p.cfg.ModelAliases[model]
+	req := &inference.GenerateRequest{
+		RequestID: reqLine.CustomID,
+		Endpoint:  reqLine.URL,
+		Params:    reqLine.Body,
+	}
+	if aliased {
+		req.Params = withModel(reqLine.Body, target)
+	}
+	req.Params = transformParams(p.cfg.RequestTransforms, reqLine.URL, req.Params)
+	// the response is turned into the result line, with the model of the batch
+	finish := func(resp *inference.GenerateResponse) (*batch.ResponseLine, bool) {
+		result, failed := p.handleResponse(ctx, reqLine.CustomID, openai.Endpoint(reqLine.URL), resp)
+		if aliased && !failed {
+			result.Response.Body = restoreModel(result.Response.Body, model)
+		}
+		return result, failed
+	}
+
+	start := time.Now()
+	var resp *inference.GenerateResponse
+	var genErr *inference.ClientError
+	// a late response arriving within the grace period after the timeout recovers the line before finalization
+	if deadline := p.lateDeadline(start, timeout, expiresAt); !deadline.IsZero() {
+		resp, genErr, kept = p.generateLate(lineCtx, deadline, late, req, model, finish)
+	} else {
+		resp, genErr = p.tracedGenerate(lineCtx, req, model)
+	}
+	// the requests failed by the open circuit breaker were not sent, they are not upstream calls nor errors
+	upstream := genErr == nil || !errors.Is(genErr.RawError, inference.ErrCircuitOpen)
+	if upstream {
+		metrics.RecordInferenceCallDuration(time.Since(start), model, reqLine.URL)
+	}
+	if genErr != nil {
+		p.handleError(ctx, genErr)
+		if ctx.Err() == nil && errors.Is(lineCtx.Err(), context.DeadlineExceeded) {
+			if timeout < p.cfg.PerLineTimeout {
+				metrics.RecordLineTimeout(model, batch.LineErrorCodeBatchExpired)
+				return newErrorLine(reqLine.CustomID, batch.LineErrorCodeBatchExpired, "batch expired before the response was received"), true, noRelease
 			}
-		}(line)
+			metrics.RecordJobError(model)
+			metrics.RecordLineTimeout(model, batch.LineErrorCodeLineTimeout)
+			return newErrorLine(reqLine.CustomID, batch.LineErrorCodeLineTimeout,
+				fmt.Sprintf("request did not complete within the per-line timeout of %s", p.cfg.PerLineTimeout)), true, noRelease
+		}
+		// the client already retried the request, so the line is counted once with its final error.
+		// lines interrupted by a shutdown are processed again on resume and not counted
+		if ctx.Err() == nil && upstream && genErr.Category != inference.ErrCategoryInvalidReq {
+			metrics.RecordJobError(model)
+		}
+		return newErrorLine(reqLine.CustomID, string(genErr.Category), genErr.Message), true, noRelease
+	}
 
+	result, failed = finish(resp)
+	return result, failed, resp.Release
+}
+
+// generate sends an inference request. When streaming is enabled and supported by the client,
+// chat completions are streamed and their chunks are assembled into the response.
+func (p *Processor) generate(ctx context.Context, req *inference.GenerateRequest) (*inference.GenerateResponse, *inference.ClientError) {
+	streamer, ok := p.clients.inference.(inference.StreamingClient)
+	if !ok || !p.cfg.InferenceStreaming || req.Endpoint != string(openai.EndpointChatCompletions) {
+		return p.clients.inference.Generate(ctx, req)
 	}
-	wg.Wait()
 
-	// final status decision
-	// TODO:: final status decision (should be included in the job object)
-	// openai batch set the job as completed even there are some failures - should we do the same?
-	// failed status is used when the file is not valid or the batch request is not started properly
-	finalStatus := batch.StatusCompleted
-	if !metadata.Validate() {
-		logger.V(logging.WARNING).Info("Job finished with partial failures", "jobID", job.ID, "metadata", metadata)
-		// TODO:: finalStatus = batch.Failed
+	assembler := newChatCompletionAssembler()
+	resp, genErr := streamer.GenerateStream(ctx, req, assembler.add)
+	if genErr != nil || resp != nil {
+		// failed, or answered without streaming
+		return resp, genErr
+	}
+	body, err := assembler.response()
+	if err != nil {
+		return nil, &inference.ClientError{
+			Category: inference.ErrCategoryUnknown,
+			Message:  fmt.Sprintf("failed to assemble streamed response: %v", err),
+			RawError: err,
+		}
 	}
+	return &inference.GenerateResponse{RequestID: req.RequestID, Response: body}, nil
+}
 
-	// status update
-	p.clients.status.Set(jobctx, job.ID, 24*60*60, []byte(batch.StatusFinalizing))
+// lineTimeout returns the time an inference request started at now may take:
+// the configured per-line timeout, shortened to the remaining time before the batch expires.
+// A zero expiresAt means the batch never expires. A non-positive result means the batch has expired.
+func (p *Processor) lineTimeout(now, expiresAt time.Time) time.Duration {
+	timeout := p.cfg.PerLineTimeout
+	if expiresAt.IsZero() {
+		return timeout
+	}
+	if remaining := expiresAt.Sub(now); remaining < timeout {
+		return remaining
+	}
+	return timeout
+}
 
-	// db update (job.Status should be updated before this line)
-	if err := p.clients.database.Update(jobctx, job); err != nil {
-		logger.V(logging.ERROR).Error(err, "Failed to update final job status in DB", "jobID", job.ID)
+// jobExpiresAt returns the time the batch expires, derived from its completion window.
+func jobExpiresAt(job *db.BatchJob, statusInfo *openai.BatchStatusInfo) time.Time {
+	if statusInfo.ExpiresAt != nil {
+		return time.Unix(*statusInfo.ExpiresAt, 0)
 	}
-	p.clients.status.Set(jobctx, job.ID, 24*60*60, []byte(finalStatus))
-	logger.V(logging.INFO).Info("Job Processed", "jobID", job.ID, "status", finalStatus)
+	return job.SLO
+}
+
+// recordJobSLO records whether the job was finalized before it expired.
+// Cancelled jobs are not recorded, as the client stopped them.
+func recordJobSLO(job *db.BatchJob, statusInfo *openai.BatchStatusInfo, finalizedAt time.Time) {
+	met := !finalizedAt.After(jobExpiresAt(job, statusInfo))
+	metrics.RecordBatchSLO(met, batch.GetTenantIDFromTags(job.Tags))
 }
 
 func (p *Processor) handleError(ctx context.Context, err error) {
-	// TODO:: error handling.
 	logger := klog.FromContext(ctx)
 	logger.V(logging.ERROR).Error(err, "Inference request failed")
 }
 
-func (p *Processor) handleResponse(ctx context.Context, inferenceResponse *inference.GenerateResponse) error {
-	// TODO:: response handling + writing line to the output file ...
+func (p *Processor) handleResponse(
+	ctx context.Context, customID string, endpoint openai.Endpoint, inferenceResponse *inference.GenerateResponse,
+) (*batch.ResponseLine, bool) {
 	logger := klog.FromContext(ctx)
-	logger.V(logging.DEBUG).Info("Handling response")
+	logger.V(logging.DEBUG).Info("Handling response", "customID", customID)
+
+	if !json.Valid(inferenceResponse.Response) {
+		return newErrorLine(customID, string(inference.ErrCategoryUnknown), "inference response is not valid JSON"), true
+	}
+	body, err := p.normalizer.Normalize(endpoint, inferenceResponse.Response)
+	if err != nil {
+		return newErrorLine(customID, string(inference.ErrCategoryUnknown), fmt.Sprintf("inference response could not be normalized: %v", err)), true
+	}
+	if err := checkResponseBody(endpoint, body); err != nil {
+		return newErrorLine(customID, string(inference.ErrCategoryUnknown), err.Error()), true
+	}
+
+	return &batch.ResponseLine{
+		ID:       newRequestLineID(),
+		CustomID: customID,
+		Response: &batch.LineResponse{
+			StatusCode: http.StatusOK,
+			RequestID:  inferenceResponse.RequestID,
+			Body:       body,
+		},
+	}, false
+}
+
+func newErrorLine(customID, code, message string) *batch.ResponseLine {
+	return &batch.ResponseLine{
+		ID:       newRequestLineID(),
+		CustomID: customID,
+		Error: &batch.LineError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+func newRequestLineID() string {
+	return fmt.Sprintf("batch_req_%s", uuid.NewString())
+}
+
+// jobOutput holds the partial output and error files of a job.
+type jobOutput struct {
+	output *jsonlWriter
+	errors *jsonlWriter
+	closed bool
+}
+
+// Close flushes and closes the output files. It is safe to call more than once.
+func (o *jobOutput) Close() error {
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	outErr := o.output.Close()
+	errErr := o.errors.Close()
+	if outErr != nil {
+		return outErr
+	}
+	return errErr
+}
+
+// openJobOutput opens the partial output files of a job, resuming from the last checkpoint when possible.
+func (p *Processor) openJobOutput(ctx context.Context, jobID string) (*checkpoint, *jobOutput, error) {
+	logger := klog.FromContext(ctx)
+
+	if err := os.MkdirAll(p.cfg.WorkDir, 0o700); err != nil {
+		return nil, nil, err
+	}
+
+	cp, err := p.loadCheckpoint(ctx, jobID)
+	if err != nil {
+		logger.V(logging.WARNING).Info("Failed to load checkpoint, starting from the beginning", "err", err)
+	}
+	if cp != nil {
+		out, err := openJobOutputFiles(cp)
+		if err == nil {
+			logger.V(logging.INFO).Info("Resuming job from checkpoint", "lineOffset", cp.LineOffset)
+			return cp, out, nil
+		}
+		logger.V(logging.WARNING).Info("Partial output files do not match the checkpoint, starting from the beginning", "err", err)
+	}
+
+	cp = p.newCheckpoint(jobID)
+	out, err := openJobOutputFiles(cp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cp, out, nil
+}
+
+func openJobOutputFiles(cp *checkpoint) (*jobOutput, error) {
+	output, err := openJSONLWriter(cp.OutputLocation, cp.OutputBytes)
+	if err != nil {
+		return nil, err
+	}
+	errors, err := openJSONLWriter(cp.ErrorLocation, cp.ErrorBytes)
+	if err != nil {
+		output.Close()
+		return nil, err
+	}
+	return &jobOutput{output: output, errors: errors}, nil
+}
+
+// commitCheckpoint syncs the output files and saves a checkpoint at the given line offset.
+func (p *Processor) commitCheckpoint(
+	ctx context.Context, jobID string, lineOffset int64,
+	cp *checkpoint, out *jobOutput, metadata *batch.JobResultMetadata,
+) error {
+	outputBytes, err := out.output.Sync()
+	if err != nil {
+		return err
+	}
+	errorBytes, err := out.errors.Sync()
+	if err != nil {
+		return err
+	}
+
+	cp.LineOffset = lineOffset
+	cp.OutputBytes = outputBytes
+	cp.ErrorBytes = errorBytes
+	cp.setCounts(metadata)
+	return p.saveCheckpoint(ctx, jobID, cp)
+}
+
+// storeJobOutput closes the output files of a finished job, and stores the output file if lines succeeded
+// and the error file if lines failed, recording their file IDs in the status of the job.
+func (p *Processor) storeJobOutput(
+	ctx context.Context, job *db.BatchJob, spec *openai.BatchSpec, statusInfo *openai.BatchStatusInfo,
+	cp *checkpoint, out *jobOutput, metadata *batch.JobResultMetadata,
+) error {
+	var err error
+	if err = out.Close(); err != nil {
+		return fmt.Errorf("failed to close job output files: %w", err)
+	}
+	if metadata.Succeeded > 0 {
+		if statusInfo.OutputFileID, err = p.storeJobFile(ctx, job, spec, cp.OutputLocation, job.ID+"_output.jsonl"); err != nil {
+			return fmt.Errorf("failed to store output file: %w", err)
+		}
+	}
+	if metadata.Failed > 0 {
+		if statusInfo.ErrorFileID, err = p.storeJobFile(ctx, job, spec, cp.ErrorLocation, job.ID+"_error.jsonl"); err != nil {
+			return fmt.Errorf("failed to store error file: %w", err)
+		}
+	}
 	return nil
 }
 
+// storeJobFile uploads a local output file of the job to the files storage, records it in the file database
+// as a file of the job's tenant, and returns its file ID.
+// The file expires after the output_expires_after policy of the batch, anchored at the file creation, or after OutputFileTTL.
+func (p *Processor) storeJobFile(ctx context.Context, job *db.BatchJob, spec *openai.BatchSpec, path, filename string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	tenantID := batch.GetTenantIDFromTags(job.Tags)
+	fileID := fmt.Sprintf("file-%s", uuid.NewString())
+	location := p.locate(tenantID, fileID)
+	fileMd, err := p.clients.files.Store(ctx, location, 0, file)
+	if err != nil {
+		return "", err
+	}
+
+	ttl := int64(p.cfg.OutputFileTTL.Seconds())
+	if spec.OutputExpiresAfter != nil {
+		ttl = spec.OutputExpiresAfter.Seconds
+	}
+	createdAt := time.Now().UTC().Unix()
+	fileObj := openai.FileObject{
+		ID:        fileID,
+		Bytes:     int32(fileMd.Size),
+		CreatedAt: int32(createdAt),
+		ExpiresAt: int32(createdAt + ttl),
+		Filename:  filename,
+		Object:    "file",
+		Purpose:   openai.FileObjectPurposeBatchOutput,
+		Status:    openai.FileObjectStatusProcessed,
+	}
+	fileObjData, err := json.Marshal(fileObj)
+	if err == nil {
+		_, err = p.clients.fileDatabase.Store(ctx, &db.BatchFile{
+			ID:       fileID,
+			TTL:      int(ttl),
+			Tags:     []string{batch.TenantTag(tenantID), batch.PurposeTag(openai.FileObjectPurposeBatchOutput)},
+			Spec:     fileObjData,
+			Location: location,
+		})
+	}
+	if err != nil {
+		if delErr := p.clients.files.Delete(ctx, location); delErr != nil {
+			klog.FromContext(ctx).V(logging.WARNING).Info("Failed to delete unrecorded output file", "fileID", fileID, "location", location, "err", delErr)
+		}
+		return "", fmt.Errorf("failed to record file %s: %w", fileID, err)
+	}
+	return fileID, nil
+}
+
+// cleanupJobOutput removes the checkpoint and the partial output files of a finalized job.
+func (p *Processor) cleanupJobOutput(ctx context.Context, jobID string, cp *checkpoint) {
+	logger := klog.FromContext(ctx)
+
+	if err := p.deleteCheckpoint(ctx, jobID); err != nil {
+		logger.V(logging.WARNING).Info("Failed to delete checkpoint", "err", err)
+	}
+	for _, path := range []string{cp.OutputLocation, cp.ErrorLocation} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.V(logging.WARNING).Info("Failed to remove partial output file", "path", path, "err", err)
+		}
+	}
+}
+
+// abortJob fails a job whose output files were opened. Its partial output files, its checkpoint and the files
+// already stored for it are removed, so a failed job leaves no partial result.
+func (p *Processor) abortJob(
+	ctx context.Context, job *db.BatchJob, statusInfo *openai.BatchStatusInfo, cp *checkpoint, out *jobOutput, failure *jobFailure,
+) {
+	logger := klog.FromContext(ctx)
+
+	if err := out.Close(); err != nil {
+		logger.V(logging.WARNING).Info("Failed to close job output files", "err", err)
+	}
+	for _, fileID := range []string{statusInfo.OutputFileID, statusInfo.ErrorFileID} {
+		if fileID != "" {
+			p.deleteJobFile(ctx, fileID)
+		}
+	}
+	statusInfo.OutputFileID, statusInfo.ErrorFileID = "", ""
+	p.cleanupJobOutput(ctx, job.ID, cp)
+	p.failJob(ctx, job, statusInfo, failure)
+}
+
+// deleteJobFile deletes a file stored for a job and its record.
+func (p *Processor) deleteJobFile(ctx context.Context, fileID string) {
+	logger := klog.FromContext(ctx)
+
+	records, _, err := p.clients.fileDatabase.Get(ctx, []string{fileID}, nil, db.TagsLogicalCondNa, 0, 1)
+	if err != nil {
+		logger.V(logging.WARNING).Info("Failed to get stored file record", "fileID", fileID, "err", err)
+		return
+	}
+	if len(records) > 0 {
+		if err := p.clients.files.Delete(ctx, records[0].ContentLocation()); err != nil {
+			logger.V(logging.WARNING).Info("Failed to delete stored file", "fileID", fileID, "err", err)
+		}
+	}
+	if _, err := p.clients.fileDatabase.Delete(ctx, []string{fileID}); err != nil {
+		logger.V(logging.WARNING).Info("Failed to delete stored file record", "fileID", fileID, "err", err)
+	}
+}
+
+// failJob marks the job as failed, recording the failure in its errors, and dead-letters it when enabled.
+func (p *Processor) failJob(ctx context.Context, job *db.BatchJob, statusInfo *openai.BatchStatusInfo, failure *jobFailure) {
+	failedAt := time.Now().UTC().Unix()
+	statusInfo.Status = openai.BatchStatusFailed
+	statusInfo.FailedAt = &failedAt
+	recordFailure(statusInfo, failure)
+	if p.cfg.DeadLetterFailedJobs {
+		p.deadLetterJob(ctx, job, statusInfo, failure)
+	}
+	p.updateJobStatus(ctx, job, statusInfo)
+	p.setStatus(ctx, job.ID, batch.StatusFailed)
+	recordJobSLO(job, statusInfo, time.Unix(failedAt, 0))
+	p.notifyCallback(ctx, job, statusInfo)
+}
+
+// updateJobStatus stores the status of the job in the database.
+func (p *Processor) updateJobStatus(ctx context.Context, job *db.BatchJob, statusInfo *openai.BatchStatusInfo) {
+	logger := klog.FromContext(ctx)
+
+	data, err := json.Marshal(statusInfo)
+	if err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to marshal job status", "jobID", job.ID)
+		return
+	}
+	job.Status = data
+	if err := p.clients.database.Update(ctx, job); err != nil {
+		logger.V(logging.ERROR).Error(err, "Failed to update job status in DB", "jobID", job.ID)
+		return
+	}
+
+	// notify the listeners of the job's status, such as the event streams of the API server
+	event := []db.BatchEvent{{ID: job.ID, Type: db.BatchEventStatusUpdate, TTL: statusTTLSeconds}}
+	if _, err := p.clients.event.ProducerSendEvents(ctx, event); err != nil {
+		logger.V(logging.WARNING).Info("Failed to send job status update event", "jobID", job.ID, "err", err)
+	}
+}
+
+// requestCounts returns the request counts of a job's result metadata.
+func requestCounts(metadata *batch.JobResultMetadata) openai.BatchRequestCounts {
+	return openai.BatchRequestCounts{
+		Total:     int64(metadata.Total),
+		Completed: int64(metadata.Succeeded),
+		Failed:    int64(metadata.Failed),
+	}
+}
+
+// jobUsage returns the token usage of a job's result metadata.
+func jobUsage(metadata *batch.JobResultMetadata) *openai.BatchUsage {
+	usage := metadata.Usage
+	return &usage
+}
+
+// setStatus updates the temporary status of the job.
+func (p *Processor) setStatus(ctx context.Context, jobID string, status batch.BatchStatus) {
+	logger := klog.FromContext(ctx)
+	if err := p.clients.status.Set(ctx, jobID, statusTTLSeconds, []byte(status)); err != nil {
+		logger.V(logging.WARNING).Info("Failed to set job status", "jobID", jobID, "status", status, "err", err)
+	}
+}
+
 // Stop gracefully stops the processor, waiting for all workers to finish.
+// While it waits, the remaining workers are logged every drain log interval and counted by the draining_jobs gauge.
 func (p *Processor) Stop(ctx context.Context) {
 	logger := klog.FromContext(ctx)
-	p.workerPool.WaitAll()
-	logger.V(logging.INFO).Info("All workers have finished")
+	start := time.Now()
+	p.draining.Store(true)
+	p.recordDrainingJobs()
+
+	done := make(chan struct{})
+	go func() {
+		// the workers are released before the jobs record their metrics
+		p.workerPool.WaitAll()
+		p.jobs.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(p.drainLogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			metrics.SetDrainingJobs(0)
+			logger.V(logging.INFO).Info("All workers have finished", "drainTime", time.Since(start).String())
+			return
+		case <-ticker.C:
+			p.recordDrainingJobs()
+			logger.V(logging.INFO).Info("Waiting for workers to finish",
+				"remainingWorkers", p.workerPool.InUse(), "drainTime", time.Since(start).String())
+		}
+	}
+}
+
+// recordDrainingJobs sets the number of jobs still processed while the processor stops.
+func (p *Processor) recordDrainingJobs() {
+	if p.draining.Load() {
+		metrics.SetDrainingJobs(p.workerPool.InUse())
+	}
 }
@@ -14,27 +14,92 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// file explains;;
+// this file contains the worker pool, which bounds the number of jobs processed concurrently.
+// A subset of the workers can be reserved per tenant. Reserved workers that are not in use are lent
+// to other tenants, and are reclaimed when the tenant they are reserved for needs them.
 
 package worker
 
 import (
+	"context"
 	"sync"
 )
 
+// LeaseKind describes which part of the pool a worker is leased from.
+type LeaseKind string
+
+const (
+	LeaseReserved LeaseKind = "reserved" // a worker reserved for the job's tenant
+	LeaseShared   LeaseKind = "shared"   // a worker not reserved for any tenant
+	LeaseBorrowed LeaseKind = "borrowed" // an unused worker reserved for another tenant
+)
+
+// Lease is the assignment of a worker to a job of a tenant.
+type Lease struct {
+	WorkerID int
+	TenantID string
+	Kind     LeaseKind
+	Owner    string // the tenant the borrowed worker is reserved for
+
+	reclaimOnce sync.Once
+	reclaimed   chan struct{}
+}
+
+// Reclaimed is closed when the owner of a borrowed worker needs it back.
+// The job running on the worker should stop and release the worker.
+func (l *Lease) Reclaimed() <-chan struct{} {
+	return l.reclaimed
+}
+
+// IsReclaimed reports whether the worker was reclaimed by its owner.
+func (l *Lease) IsReclaimed() bool {
+	select {
+	case <-l.reclaimed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *Lease) reclaim() {
+	l.reclaimOnce.Do(func() { close(l.reclaimed) })
+}
+
 // worker id is integer that starts with 1 to the max number of worker
 type WorkerPool struct {
 	workerIds chan int
 	wg        sync.WaitGroup
+
+	mu           sync.Mutex
+	shared       int            // number of workers not reserved for any tenant
+	reservations map[string]int // tenant -> number of reserved workers
+	leases       map[int]*Lease // worker id -> lease of the worker
 }
 
 func NewWorkerPool(maxWorkers int) *WorkerPool {
+	return NewReservedWorkerPool(maxWorkers, nil)
+}
+
+// NewReservedWorkerPool creates a worker pool where reservations[tenant] workers are reserved for the tenant.
+// The total number of reserved workers must not exceed maxWorkers.
+func NewReservedWorkerPool(maxWorkers int, reservations map[string]int) *WorkerPool {
 	ids := make(chan int, maxWorkers)
 	for i := 1; i <= maxWorkers; i++ {
 		ids <- i // fill worker ids first
 	}
+
+	shared := maxWorkers
+	reserved := make(map[string]int, len(reservations))
+	for tenantID, n := range reservations {
+		reserved[tenantID] = n
+		shared -= n
+	}
+
 	return &WorkerPool{
-		workerIds: ids,
+		workerIds:    ids,
+		shared:       shared,
+		reservations: reserved,
+		leases:       make(map[int]*Lease),
 	}
 }
 
@@ -50,11 +115,138 @@ func (wp *WorkerPool) TryAcquire() (int, bool) {
 	}
 }
 
+// Acquire waits until a worker is available and returns its id.
+// It returns false if the context is done first.
+func (wp *WorkerPool) Acquire(ctx context.Context) (int, bool) {
+	select {
+	case <-ctx.Done():
+		return 0, false
+	case id := <-wp.workerIds:
+		wp.wg.Add(1)
+		return id, true
+	}
+}
+
+// Assign leases an acquired worker to a job of the tenant.
+// The tenant's reserved workers are used first, then the shared workers,
+// and finally unused workers reserved for other tenants are borrowed.
+func (wp *WorkerPool) Assign(id int, tenantID string) *Lease {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	lease := &Lease{
+		WorkerID:  id,
+		TenantID:  tenantID,
+		reclaimed: make(chan struct{}),
+	}
+
+	reservedInUse, sharedInUse, borrowedFrom := wp.usageLocked()
+	switch {
+	case reservedInUse[tenantID] < wp.reservations[tenantID]:
+		lease.Kind = LeaseReserved
+	case sharedInUse < wp.shared:
+		lease.Kind = LeaseShared
+	default:
+		// a worker is available, so at least one tenant does not use all of its reserved workers
+		lease.Kind = LeaseBorrowed
+		for owner, n := range wp.reservations {
+			if reservedInUse[owner]+borrowedFrom[owner] < n {
+				lease.Owner = owner
+				break
+			}
+		}
+	}
+
+	wp.leases[id] = lease
+	return lease
+}
+
+// Reclaim asks a job running on a worker borrowed from the tenant to release it,
+// if the tenant does not use all of its reserved workers. It returns true if a worker is being reclaimed.
+func (wp *WorkerPool) Reclaim(tenantID string) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	reservedInUse, _, _ := wp.usageLocked()
+	if reservedInUse[tenantID] >= wp.reservations[tenantID] {
+		return false
+	}
+	for _, lease := range wp.leases {
+		if lease.Kind == LeaseBorrowed && lease.Owner == tenantID {
+			if lease.IsReclaimed() {
+				// already being reclaimed
+				return true
+			}
+			lease.reclaim()
+			return true
+		}
+	}
+	return false
+}
+
+// HasBorrowed reports whether any reserved worker is lent to another tenant.
+func (wp *WorkerPool) HasBorrowed() bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	for _, lease := range wp.leases {
+		if lease.Kind == LeaseBorrowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Utilization returns the number of reserved, shared and borrowed workers in use.
+func (wp *WorkerPool) Utilization() (reserved, shared, borrowed int) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	for _, lease := range wp.leases {
+		switch lease.Kind {
+		case LeaseReserved:
+			reserved++
+		case LeaseShared:
+			shared++
+		case LeaseBorrowed:
+			borrowed++
+		}
+	}
+	return reserved, shared, borrowed
+}
+
+// usageLocked returns the reserved workers in use per tenant, the shared workers in use,
+// and the reserved workers lent to other tenants per owner.
+func (wp *WorkerPool) usageLocked() (reservedInUse map[string]int, sharedInUse int, borrowedFrom map[string]int) {
+	reservedInUse = make(map[string]int)
+	borrowedFrom = make(map[string]int)
+	for _, lease := range wp.leases {
+		switch lease.Kind {
+		case LeaseReserved:
+			reservedInUse[lease.TenantID]++
+		case LeaseShared:
+			sharedInUse++
+		case LeaseBorrowed:
+			borrowedFrom[lease.Owner]++
+		}
+	}
+	return reservedInUse, sharedInUse, borrowedFrom
+}
+
 func (wp *WorkerPool) Release(id int) {
+	wp.mu.Lock()
+	delete(wp.leases, id)
+	wp.mu.Unlock()
+
 	wp.workerIds <- id
 	wp.wg.Done()
 }
 
+// InUse returns the number of workers acquired and not released yet.
+func (wp *WorkerPool) InUse() int {
+	return cap(wp.workerIds) - len(wp.workerIds)
+}
+
 func (wp *WorkerPool) WaitAll() {
 	wp.wg.Wait()
 }
@@ -0,0 +1,126 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the worker pool.
+package worker
+
+import (
+	"context"
+	"testing"
+)
+
+func acquireFor(t *testing.T, wp *WorkerPool, tenantID string) *Lease {
+	t.Helper()
+
+	id, ok := wp.TryAcquire()
+	if !ok {
+		t.Fatalf("Expected a worker to be available for tenant %s", tenantID)
+	}
+	return wp.Assign(id, tenantID)
+}
+
+func TestWorkerPool(t *testing.T) {
+
+	t.Run("ReservedTenantAlwaysGetsWorker", func(t *testing.T) {
+		wp := NewReservedWorkerPool(3, map[string]int{"premium": 1})
+
+		// other tenants use all the shared workers
+		for i := 0; i < 2; i++ {
+			if lease := acquireFor(t, wp, "other"); lease.Kind != LeaseShared {
+				t.Errorf("Expected a shared worker, got %s", lease.Kind)
+			}
+		}
+
+		// the reserved worker is still available
+		if lease := acquireFor(t, wp, "premium"); lease.Kind != LeaseReserved {
+			t.Errorf("Expected a reserved worker, got %s", lease.Kind)
+		}
+		if _, ok := wp.TryAcquire(); ok {
+			t.Errorf("Expected no worker to be available")
+		}
+	})
+
+	t.Run("ReservedWorkerUsesSharedWhenReservationFull", func(t *testing.T) {
+		wp := NewReservedWorkerPool(3, map[string]int{"premium": 1})
+
+		if lease := acquireFor(t, wp, "premium"); lease.Kind != LeaseReserved {
+			t.Errorf("Expected a reserved worker, got %s", lease.Kind)
+		}
+		if lease := acquireFor(t, wp, "premium"); lease.Kind != LeaseShared {
+			t.Errorf("Expected a shared worker, got %s", lease.Kind)
+		}
+	})
+
+	t.Run("UnusedReservedWorkerIsLentAndReclaimed", func(t *testing.T) {
+		wp := NewReservedWorkerPool(2, map[string]int{"premium": 1})
+
+		acquireFor(t, wp, "other")
+		borrowed := acquireFor(t, wp, "other")
+		if borrowed.Kind != LeaseBorrowed || borrowed.Owner != "premium" {
+			t.Fatalf("Expected a worker borrowed from premium, got %s from %q", borrowed.Kind, borrowed.Owner)
+		}
+		if !wp.HasBorrowed() {
+			t.Errorf("Expected the pool to have a borrowed worker")
+		}
+
+		// a tenant without reservation cannot reclaim workers
+		if wp.Reclaim("other") {
+			t.Errorf("Expected no worker to be reclaimed for a tenant without reservation")
+		}
+
+		// the premium tenant reclaims its worker
+		if !wp.Reclaim("premium") {
+			t.Fatalf("Expected a worker to be reclaimed")
+		}
+		select {
+		case <-borrowed.Reclaimed():
+		default:
+			t.Fatalf("Expected the borrowed lease to be reclaimed")
+		}
+
+		// the borrower releases the worker, which is then reserved for premium
+		wp.Release(borrowed.WorkerID)
+		id, ok := wp.Acquire(context.Background())
+		if !ok {
+			t.Fatalf("Expected the reclaimed worker to be available")
+		}
+		if lease := wp.Assign(id, "premium"); lease.Kind != LeaseReserved {
+			t.Errorf("Expected a reserved worker, got %s", lease.Kind)
+		}
+	})
+
+	t.Run("Utilization", func(t *testing.T) {
+		wp := NewReservedWorkerPool(4, map[string]int{"premium": 1, "gold": 1})
+
+		acquireFor(t, wp, "premium")
+		acquireFor(t, wp, "other")
+		acquireFor(t, wp, "other")
+		last := acquireFor(t, wp, "other")
+
+		reserved, shared, borrowed := wp.Utilization()
+		if reserved != 1 || shared != 2 || borrowed != 1 {
+			t.Errorf("Expected utilization 1/2/1, got %d/%d/%d", reserved, shared, borrowed)
+		}
+		if last.Owner != "gold" {
+			t.Errorf("Expected the worker to be borrowed from gold, got %q", last.Owner)
+		}
+
+		wp.Release(last.WorkerID)
+		if _, _, borrowed := wp.Utilization(); borrowed != 0 {
+			t.Errorf("Expected no borrowed workers after release, got %d", borrowed)
+		}
+	})
+}
@@ -0,0 +1,1828 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the worker job processing.
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	mockapi "github.com/llm-d-incubation/batch-gateway/internal/database/mock"
+	files "github.com/llm-d-incubation/batch-gateway/internal/files_store/api"
+	mockfiles "github.com/llm-d-incubation/batch-gateway/internal/files_store/mock"
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/config"
+	"github.com/llm-d-incubation/batch-gateway/internal/processor/metrics"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/batch"
+	mockbatch "github.com/llm-d-incubation/batch-gateway/internal/shared/batch/mock"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// fakeInferenceClient echoes requests back, or answers with response when set,
+// and optionally calls onCall before each request.
+type fakeInferenceClient struct {
+	mu        sync.Mutex
+	calls     int
+	endpoints []string
+	response  []byte
+	onCall    func(ctx context.Context, call int) *inference.ClientError
+}
+
+func (c *fakeInferenceClient) Generate(ctx context.Context, req *inference.GenerateRequest) (*inference.GenerateResponse, *inference.ClientError) {
+	c.mu.Lock()
+	c.calls++
+	call := c.calls
+	c.endpoints = append(c.endpoints, req.Endpoint)
+	c.mu.Unlock()
+
+	if c.onCall != nil {
+		if err := c.onCall(ctx, call); err != nil {
+			return nil, err
+		}
+	}
+	if c.response != nil {
+		return &inference.GenerateResponse{RequestID: req.RequestID, Response: c.response}, nil
+	}
+	return &inference.GenerateResponse{
+		RequestID: req.RequestID,
+		Response:  []byte(fmt.Sprintf(`{"id":"resp-%s"}`, req.RequestID)),
+	}, nil
+}
+
+type workerTestEnv struct {
+	cfg     *config.ProcessorConfig
+	db      *mockapi.MockBatchDBClient
+	status  *mockapi.MockBatchStatusClient
+	fileDB  *mockapi.MockBatchFileDBClient
+	files   *mockfiles.MockBatchFilesClient
+	queue   *mockapi.MockBatchPriorityQueueClient
+	jobID   string
+	numReqs int
+}
+
+// TestMain initializes the metrics once: the jobs of the tests record them concurrently,
+// so the tests check the change of the metrics they record, see metricValue.
+func TestMain(m *testing.M) {
+	if err := metrics.InitMetrics(*config.NewConfig(), nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to init metrics: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// metricValue returns the value of a series of the metrics, such as `jobs_dead_lettered_total{code="x"}`,
+// or 0 if it wasn't recorded.
+func metricValue(t *testing.T, series string) float64 {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	metrics.NewMetricsHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(rr.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, series+" "); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("Failed to parse metric %s: %v", line, err)
+			}
+			return v
+		}
+	}
+	return 0
+}
+
+func setupWorkerTestEnv(t *testing.T, numReqs int) *workerTestEnv {
+	t.Helper()
+
+	cfg := config.NewConfig()
+	cfg.NumWorkers = 1
+	cfg.MaxJobConcurrency = 1
+	cfg.CheckpointInterval = 1
+	cfg.WorkDir = t.TempDir()
+
+	env := &workerTestEnv{
+		cfg:     cfg,
+		db:      mockapi.NewMockBatchDBClient(),
+		status:  mockapi.NewMockBatchStatusClient(),
+		fileDB:  mockapi.NewMockBatchFileDBClient(),
+		files:   mockfiles.NewMockBatchFilesClient(),
+		queue:   mockapi.NewMockBatchPriorityQueueClient(),
+		jobID:   "batch-test",
+		numReqs: numReqs,
+	}
+
+	var input bytes.Buffer
+	for i := 0; i < numReqs; i++ {
+		fmt.Fprintf(&input,
+			`{"custom_id":"req-%d","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[]}}`+"\n", i)
+	}
+	env.storeInputFile(t, "file-input", &input)
+
+	spec, _ := json.Marshal(openai.BatchSpec{
+		Object:      "batch",
+		Endpoint:    openai.EndpointChatCompletions,
+		InputFileID: "file-input",
+	})
+	status, _ := json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusValidating})
+	if _, err := env.db.Store(context.Background(), &api.BatchJob{ID: env.jobID, Spec: spec, Status: status}); err != nil {
+		t.Fatalf("Failed to store job: %v", err)
+	}
+	return env
+}
+
+// storeInputFile stores the content of an input file of the default tenant and records it in the file database.
+func (env *workerTestEnv) storeInputFile(t *testing.T, fileID string, content io.Reader) {
+	t.Helper()
+
+	location := batch.TenantFileLocation(batch.DefaultTenantID, fileID)
+	if _, err := env.files.Store(context.Background(), location, 0, content); err != nil {
+		t.Fatalf("Failed to store input file: %v", err)
+	}
+	if _, err := env.fileDB.Store(context.Background(), &api.BatchFile{ID: fileID, TTL: 3600, Location: location}); err != nil {
+		t.Fatalf("Failed to record input file: %v", err)
+	}
+}
+
+func (env *workerTestEnv) newProcessor(client inference.Client) *Processor {
+	clients := NewProcessorClients(
+		env.db, env.queue, env.status,
+		mockapi.NewMockBatchEventChannelClient(), env.fileDB, env.files, client,
+	)
+	return NewProcessor(env.cfg, &clients)
+}
+
+func (env *workerTestEnv) runJob(t *testing.T, ctx context.Context, client inference.Client) openai.BatchStatusInfo {
+	t.Helper()
+
+	jobs, _, err := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	p := env.newProcessor(client)
+	p.processJob(ctx, 0, jobs[0])
+	p.Stop(context.Background())
+
+	statusInfo := openai.BatchStatusInfo{}
+	if err := json.Unmarshal(jobs[0].Status, &statusInfo); err != nil {
+		t.Fatalf("Failed to parse job status: %v", err)
+	}
+	return statusInfo
+}
+
+// readResponseLines reads the lines of a file stored for the job, resolving its location from its record.
+func (env *workerTestEnv) readResponseLines(t *testing.T, fileID string) []batch.ResponseLine {
+	t.Helper()
+
+	records, _, err := env.fileDB.Get(context.Background(), []string{fileID}, nil, api.TagsLogicalCondNa, 0, 1)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to get record of file %s: %v", fileID, err)
+	}
+	reader, _, err := env.files.Retrieve(context.Background(), records[0].ContentLocation())
+	if err != nil {
+		t.Fatalf("Failed to retrieve file %s: %v", fileID, err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read file %s: %v", fileID, err)
+	}
+
+	var lines []batch.ResponseLine
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := batch.ResponseLine{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Failed to parse output line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestProcessJob(t *testing.T) {
+
+	t.Run("CompletesJob", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 3)
+
+		client := &fakeInferenceClient{
+			onCall: func(ctx context.Context, call int) *inference.ClientError {
+				if call == 2 {
+					return &inference.ClientError{Category: inference.ErrCategoryInvalidReq, Message: "bad request"}
+				}
+				return nil
+			},
+		}
+		statusInfo := env.runJob(t, context.Background(), client)
+
+		if statusInfo.Status != openai.BatchStatusCompleted {
+			t.Fatalf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+		}
+		if statusInfo.RequestCounts.Total != 3 || statusInfo.RequestCounts.Completed != 2 || statusInfo.RequestCounts.Failed != 1 {
+			t.Errorf("Unexpected request counts: %+v", statusInfo.RequestCounts)
+		}
+		if lines := env.readResponseLines(t, statusInfo.OutputFileID); len(lines) != 2 {
+			t.Errorf("Expected 2 output lines, got %d", len(lines))
+		}
+		errLines := env.readResponseLines(t, statusInfo.ErrorFileID)
+		if len(errLines) != 1 || errLines[0].CustomID != "req-1" || errLines[0].Error == nil {
+			t.Errorf("Unexpected error lines: %+v", errLines)
+		}
+	})
+
+	t.Run("ResumesFromCheckpoint", func(t *testing.T) {
+		numReqs := 10
+		env := setupWorkerTestEnv(t, numReqs)
+
+		// first run - the processor is shut down while processing the 6th line
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		firstClient := &fakeInferenceClient{
+			onCall: func(ctx context.Context, call int) *inference.ClientError {
+				if call == numReqs/2+1 {
+					cancel()
+					<-ctx.Done()
+					return &inference.ClientError{Category: inference.ErrCategoryUnknown, Message: ctx.Err().Error()}
+				}
+				return nil
+			},
+		}
+		statusInfo := env.runJob(t, ctx, firstClient)
+		if statusInfo.Status != openai.BatchStatusInProgress {
+			t.Fatalf("Expected status %s after shutdown, got %s", openai.BatchStatusInProgress, statusInfo.Status)
+		}
+
+		// second run - only the remaining lines are sent
+		secondClient := &fakeInferenceClient{}
+		statusInfo = env.runJob(t, context.Background(), secondClient)
+
+		if secondClient.calls != numReqs/2 {
+			t.Errorf("Expected %d requests after resume, got %d", numReqs/2, secondClient.calls)
+		}
+		if statusInfo.Status != openai.BatchStatusCompleted {
+			t.Fatalf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+		}
+		if statusInfo.RequestCounts.Total != int64(numReqs) || statusInfo.RequestCounts.Completed != int64(numReqs) {
+			t.Errorf("Unexpected request counts: %+v", statusInfo.RequestCounts)
+		}
+		if statusInfo.ErrorFileID != "" {
+			t.Errorf("Expected no error file, got %s", statusInfo.ErrorFileID)
+		}
+
+		// every request appears exactly once in the output
+		seen := map[string]int{}
+		for _, line := range env.readResponseLines(t, statusInfo.OutputFileID) {
+			seen[line.CustomID]++
+		}
+		for i := 0; i < numReqs; i++ {
+			if id := fmt.Sprintf("req-%d", i); seen[id] != 1 {
+				t.Errorf("Expected %s once in output, got %d", id, seen[id])
+			}
+		}
+
+		// the checkpoint is removed once the job is finalized
+		if data, _ := env.status.Get(context.Background(), checkpointKey(env.jobID)); data != nil {
+			t.Errorf("Expected checkpoint to be deleted, got %s", data)
+		}
+	})
+
+	t.Run("FailedRequests", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			client        *mockbatch.MockInferenceClient
+			wantCompleted int64
+			wantErrCode   string
+		}{
+			{name: "all succeed", client: mockbatch.NewMockInferenceClient().WithResponse([]byte(`{"id":"canned"}`)), wantCompleted: 4},
+			{name: "first requests fail", client: mockbatch.NewMockInferenceClient().FailFirst(2, inference.ErrCategoryRateLimit), wantCompleted: 2, wantErrCode: string(inference.ErrCategoryRateLimit)},
+			{name: "all fail", client: mockbatch.NewMockInferenceClient().WithError(inference.ErrCategoryServer), wantCompleted: 0, wantErrCode: string(inference.ErrCategoryServer)},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				env := setupWorkerTestEnv(t, 4)
+				statusInfo := env.runJob(t, context.Background(), tt.client)
+
+				if statusInfo.Status != openai.BatchStatusCompleted {
+					t.Fatalf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+				}
+				if tt.client.Calls() != 4 {
+					t.Errorf("Expected 4 inference calls, got %d", tt.client.Calls())
+				}
+				if statusInfo.RequestCounts.Completed != tt.wantCompleted || statusInfo.RequestCounts.Failed != 4-tt.wantCompleted {
+					t.Errorf("Unexpected request counts: %+v", statusInfo.RequestCounts)
+				}
+				if statusInfo.Errors != nil {
+					t.Errorf("Expected no batch errors, got %+v", statusInfo.Errors)
+				}
+
+				// each file is stored only when it has lines
+				if (statusInfo.OutputFileID != "") != (tt.wantCompleted > 0) {
+					t.Errorf("Unexpected output file %q for %d completed requests", statusInfo.OutputFileID, tt.wantCompleted)
+				}
+				if tt.wantCompleted > 0 {
+					if lines := env.readResponseLines(t, statusInfo.OutputFileID); int64(len(lines)) != tt.wantCompleted {
+						t.Errorf("Expected %d output lines, got %d", tt.wantCompleted, len(lines))
+					}
+				}
+				if tt.wantErrCode == "" {
+					if statusInfo.ErrorFileID != "" {
+						t.Errorf("Expected no error file, got %s", statusInfo.ErrorFileID)
+					}
+					return
+				}
+				lines := env.readResponseLines(t, statusInfo.ErrorFileID)
+				if int64(len(lines)) != 4-tt.wantCompleted {
+					t.Errorf("Expected %d error lines, got %d", 4-tt.wantCompleted, len(lines))
+				}
+				for _, line := range lines {
+					if line.Error == nil || line.Error.Code != tt.wantErrCode {
+						t.Errorf("Expected error code %s, got %+v", tt.wantErrCode, line.Error)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("TemplatedLines", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 0)
+
+		input := bytes.NewBufferString(
+			`{"custom_id":"ok","method":"POST","url":"/v1/chat/completions","template":{"model":"{{model}}","messages":[{"role":"user","content":"Say {{word}}"}]},"vars":{"model":"m","word":"hi"}}` + "\n" +
+				`{"custom_id":"missing","method":"POST","url":"/v1/chat/completions","template":{"model":"m","messages":[{"role":"user","content":"Say {{word}}"}]},"vars":{}}` + "\n")
+		env.storeInputFile(t, "file-templated", input)
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		jobs[0].Spec, _ = json.Marshal(openai.BatchSpec{
+			Object:      "batch",
+			Endpoint:    openai.EndpointChatCompletions,
+			InputFileID: "file-templated",
+		})
+
+		client := mockbatch.NewMockInferenceClient()
+		statusInfo := env.runJob(t, context.Background(), client)
+
+		if statusInfo.RequestCounts.Completed != 1 || statusInfo.RequestCounts.Failed != 1 {
+			t.Errorf("Unexpected request counts: %+v", statusInfo.RequestCounts)
+		}
+		if requests := client.Requests(); len(requests) != 1 || fmt.Sprint(requests[0].Params["messages"]) != "[map[content:Say hi role:user]]" {
+			t.Errorf("Expected the expanded template to be sent, got %+v", requests)
+		}
+		errLines := env.readResponseLines(t, statusInfo.ErrorFileID)
+		if len(errLines) != 1 || errLines[0].CustomID != "missing" || errLines[0].Error.Code != batch.LineErrorCodeInvalidRequest {
+			t.Errorf("Unexpected error lines: %+v", errLines)
+		}
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			streaming bool
+			client    *mockbatch.MockInferenceClient
+			wantBody  string
+		}{
+			{
+				name:      "streamed",
+				streaming: true,
+				client: mockbatch.NewMockInferenceClient().WithStreamChunks(
+					[]byte(`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}`),
+					[]byte(`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{"content":"!"},"finish_reason":"stop"}]}`),
+				),
+				wantBody: `{"id":"chatcmpl-1","object":"chat.completion","created":0,"model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"Hi!"},"finish_reason":"stop"}]}`,
+			},
+			{
+				name:      "not streamed by the gateway",
+				streaming: true,
+				client:    mockbatch.NewMockInferenceClient().WithResponse([]byte(`{"id":"full"}`)),
+				wantBody:  `{"id":"full"}`,
+			},
+			{
+				name:      "streaming disabled",
+				streaming: false,
+				client:    mockbatch.NewMockInferenceClient().WithResponse([]byte(`{"id":"full"}`)).WithStreamChunks([]byte(`{"id":"chunk"}`)),
+				wantBody:  `{"id":"full"}`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				env := setupWorkerTestEnv(t, 1)
+				env.cfg.InferenceStreaming = tt.streaming
+				statusInfo := env.runJob(t, context.Background(), tt.client)
+
+				if statusInfo.RequestCounts.Completed != 1 {
+					t.Fatalf("Unexpected request counts: %+v", statusInfo.RequestCounts)
+				}
+				lines := env.readResponseLines(t, statusInfo.OutputFileID)
+				if len(lines) != 1 || string(lines[0].Response.Body) != tt.wantBody {
+					t.Errorf("Expected output body %s, got %+v", tt.wantBody, lines)
+				}
+			})
+		}
+	})
+
+	t.Run("Moderations", func(t *testing.T) {
+		moderationLine := `{"custom_id":"mod","method":"POST","url":"/v1/moderations","body":{"model":"omni-moderation-latest","input":"some text"}}` + "\n"
+		moderation := `{"id":"modr-1","model":"omni-moderation-latest","results":[{"flagged":true,"categories":{"violence":true},"category_scores":{"violence":0.9}}]}`
+
+		tests := []struct {
+			name        string
+			line        string
+			response    string
+			wantErrCode string
+		}{
+			{name: "moderation response", line: moderationLine, response: moderation},
+			{name: "unexpected response", line: moderationLine, response: `{"id":"chatcmpl-1","choices":[]}`, wantErrCode: string(inference.ErrCategoryUnknown)},
+			{
+				name:        "missing input",
+				line:        `{"custom_id":"mod","method":"POST","url":"/v1/moderations","body":{"model":"omni-moderation-latest"}}` + "\n",
+				response:    moderation,
+				wantErrCode: batch.LineErrorCodeInvalidRequest,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				env := setupWorkerTestEnv(t, 0)
+				env.storeInputFile(t, "file-moderations", bytes.NewBufferString(tt.line))
+				jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+				jobs[0].Spec, _ = json.Marshal(openai.BatchSpec{
+					Object:      "batch",
+					Endpoint:    openai.EndpointModerations,
+					InputFileID: "file-moderations",
+				})
+
+				client := mockbatch.NewMockInferenceClient().WithResponse([]byte(tt.response))
+				statusInfo := env.runJob(t, context.Background(), client)
+
+				if tt.wantErrCode != "" {
+					errLines := env.readResponseLines(t, statusInfo.ErrorFileID)
+					if len(errLines) != 1 || errLines[0].Error == nil || errLines[0].Error.Code != tt.wantErrCode {
+						t.Errorf("Expected a %s error line, got %+v", tt.wantErrCode, errLines)
+					}
+					return
+				}
+
+				if reqs := client.Requests(); len(reqs) != 1 || reqs[0].Endpoint != string(openai.EndpointModerations) || reqs[0].Params["input"] != "some text" {
+					t.Fatalf("Expected the moderation request to be sent, got %+v", reqs)
+				}
+				lines := env.readResponseLines(t, statusInfo.OutputFileID)
+				if len(lines) != 1 || lines[0].CustomID != "mod" || lines[0].Response == nil || lines[0].Response.StatusCode != http.StatusOK {
+					t.Fatalf("Unexpected output lines: %+v", lines)
+				}
+				result := openai.ModerationResponse{}
+				if err := json.Unmarshal(lines[0].Response.Body, &result); err != nil {
+					t.Fatalf("Failed to parse moderation response: %v", err)
+				}
+				if len(result.Results) != 1 || !result.Results[0].Flagged || result.Results[0].CategoryScores["violence"] != 0.9 {
+					t.Errorf("Unexpected moderation response: %+v", result)
+				}
+			})
+		}
+	})
+
+	t.Run("Responses", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 0)
+		input := `{"custom_id":"resp-1","method":"POST","url":"/v1/responses","body":{"model":"m","input":"Tell me a joke"}}` + "\n" +
+			`{"custom_id":"resp-2","method":"POST","url":"/v1/responses","body":{"model":"m","input":[{"role":"user","content":"Hi"}]}}` + "\n"
+		env.storeInputFile(t, "file-responses", bytes.NewBufferString(input))
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		jobs[0].Spec, _ = json.Marshal(openai.BatchSpec{
+			Object:      "batch",
+			Endpoint:    openai.EndpointResponses,
+			InputFileID: "file-responses",
+		})
+
+		client := mockbatch.NewMockInferenceClient().WithResponse([]byte(`{"id":"resp_abc","object":"response","status":"completed","model":"m",` +
+			`"output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"..."}]}],` +
+			`"usage":{"input_tokens":12,"input_tokens_details":{"cached_tokens":2},"output_tokens":8,` +
+			`"output_tokens_details":{"reasoning_tokens":3},"total_tokens":20}}`))
+		statusInfo := env.runJob(t, context.Background(), client)
+
+		if statusInfo.RequestCounts.Completed != 2 {
+			t.Fatalf("Expected 2 completed requests, got %+v", statusInfo.RequestCounts)
+		}
+		for _, req := range client.Requests() {
+			if req.Endpoint != string(openai.EndpointResponses) || req.Params["input"] == nil {
+				t.Errorf("Expected a Responses API request, got %+v", req)
+			}
+		}
+		for _, line := range env.readResponseLines(t, statusInfo.OutputFileID) {
+			resp := openai.Response{}
+			if err := json.Unmarshal(line.Response.Body, &resp); err != nil || resp.Object != openai.ResponseObject || len(resp.Output) != 1 {
+				t.Errorf("Expected a Responses API response in line %s, got %s", line.CustomID, line.Response.Body)
+			}
+		}
+		want := openai.BatchUsage{
+			InputTokens: 24, InputTokensDetails: openai.BatchUsageInputTokensDetails{CachedTokens: 4},
+			OutputTokens: 16, OutputTokensDetails: openai.BatchUsageOutputTokensDetails{ReasoningTokens: 6},
+			TotalTokens: 40,
+		}
+		if statusInfo.Usage == nil || *statusInfo.Usage != want {
+			t.Errorf("Expected usage %+v, got %+v", want, statusInfo.Usage)
+		}
+	})
+
+	t.Run("CompressedInput", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 0)
+
+		var input bytes.Buffer
+		zw := gzip.NewWriter(&input)
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(zw, `{"custom_id":"req-%d","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[]}}`+"\n", i)
+		}
+		zw.Close()
+		env.storeInputFile(t, "file-gzip", &input)
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		jobs[0].Spec, _ = json.Marshal(openai.BatchSpec{
+			Object:      "batch",
+			Endpoint:    openai.EndpointChatCompletions,
+			InputFileID: "file-gzip",
+		})
+
+		statusInfo := env.runJob(t, context.Background(), &fakeInferenceClient{})
+
+		if statusInfo.Status != openai.BatchStatusCompleted || statusInfo.RequestCounts.Completed != 3 {
+			t.Fatalf("Expected 3 completed requests, got status %s and counts %+v", statusInfo.Status, statusInfo.RequestCounts)
+		}
+		if statusInfo.Model != "m" {
+			t.Errorf("Expected model %q, got %q", "m", statusInfo.Model)
+		}
+		if lines := env.readResponseLines(t, statusInfo.OutputFileID); len(lines) != 3 {
+			t.Errorf("Expected 3 output lines, got %d", len(lines))
+		}
+	})
+
+	t.Run("SingleRequestInput", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 0)
+
+		// a single JSON object, pretty printed and without custom_id
+		input := `{
+  "method": "POST",
+  "url": "/v1/chat/completions",
+  "body": {
+    "model": "m",
+    "messages": [{"role": "user", "content": "hi"}]
+  }
+}
+`
+		env.storeInputFile(t, "file-single", strings.NewReader(input))
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		jobs[0].Spec, _ = json.Marshal(openai.BatchSpec{
+			Object:      "batch",
+			Endpoint:    openai.EndpointChatCompletions,
+			InputFileID: "file-single",
+		})
+
+		statusInfo := env.runJob(t, context.Background(), &fakeInferenceClient{})
+
+		if statusInfo.Status != openai.BatchStatusCompleted || statusInfo.RequestCounts.Total != 1 || statusInfo.RequestCounts.Completed != 1 {
+			t.Fatalf("Expected 1 completed request, got status %s and counts %+v", statusInfo.Status, statusInfo.RequestCounts)
+		}
+		lines := env.readResponseLines(t, statusInfo.OutputFileID)
+		if len(lines) != 1 || lines[0].CustomID != batch.SingleRequestCustomID {
+			t.Errorf("Expected 1 output line with custom_id %q, got %+v", batch.SingleRequestCustomID, lines)
+		}
+	})
+
+	t.Run("MixedEndpoints", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			mixed         bool
+			wantCompleted int64
+			wantEndpoints []string
+		}{
+			{name: "strict", mixed: false, wantCompleted: 1, wantEndpoints: []string{"/v1/chat/completions"}},
+			{name: "mixed", mixed: true, wantCompleted: 2, wantEndpoints: []string{"/v1/chat/completions", "/v1/embeddings"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				env := setupWorkerTestEnv(t, 0)
+
+				input := bytes.NewBufferString(
+					`{"custom_id":"chat","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[]}}` + "\n" +
+						`{"custom_id":"embed","method":"POST","url":"/v1/embeddings","body":{"model":"e","input":"text"}}` + "\n")
+				env.storeInputFile(t, "file-mixed", input)
+				jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+				jobs[0].Spec, _ = json.Marshal(openai.BatchSpec{
+					Object:         "batch",
+					Endpoint:       openai.EndpointChatCompletions,
+					InputFileID:    "file-mixed",
+					MixedEndpoints: tt.mixed,
+				})
+
+				client := &fakeInferenceClient{}
+				statusInfo := env.runJob(t, context.Background(), client)
+
+				if statusInfo.RequestCounts.Total != 2 || statusInfo.RequestCounts.Completed != tt.wantCompleted {
+					t.Errorf("Unexpected request counts: %+v", statusInfo.RequestCounts)
+				}
+				if fmt.Sprint(client.endpoints) != fmt.Sprint(tt.wantEndpoints) {
+					t.Errorf("Expected requests sent to %v, got %v", tt.wantEndpoints, client.endpoints)
+				}
+				if !tt.mixed {
+					errLines := env.readResponseLines(t, statusInfo.ErrorFileID)
+					if len(errLines) != 1 || errLines[0].CustomID != "embed" || errLines[0].Error.Code != batch.LineErrorCodeInvalidRequest {
+						t.Errorf("Unexpected error lines: %+v", errLines)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestLargeBatchMemory(t *testing.T) {
+	const numReqs = 4000
+	env := setupWorkerTestEnv(t, numReqs)
+	env.cfg.CheckpointInterval = 500
+	env.cfg.MaxJobConcurrency = 8
+
+	// 4000 responses of 8KB assemble a 32MB output file
+	response := []byte(`{"id":"resp","content":"` + strings.Repeat("x", 8*1024) + `"}`)
+	outputSize := uint64(numReqs * len(response))
+
+	var mu sync.Mutex
+	var peak uint64
+	sampleHeap := func() {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		mu.Lock()
+		peak = max(peak, stats.HeapAlloc)
+		mu.Unlock()
+	}
+	runtime.GC()
+	var baseline runtime.MemStats
+	runtime.ReadMemStats(&baseline)
+
+	client := &fakeInferenceClient{
+		response: response,
+		onCall: func(ctx context.Context, call int) *inference.ClientError {
+			if call%100 == 0 {
+				sampleHeap()
+			}
+			return nil
+		},
+	}
+	statusInfo := env.runJob(t, context.Background(), client)
+
+	if statusInfo.Status != openai.BatchStatusCompleted || statusInfo.RequestCounts.Completed != numReqs {
+		t.Fatalf("Expected %d completed requests, got status %s and counts %+v", numReqs, statusInfo.Status, statusInfo.RequestCounts)
+	}
+	// the output lines are written to disk as they complete, not held in memory
+	if growth := peak - min(peak, baseline.HeapAlloc); growth > outputSize/2 {
+		t.Errorf("Expected the heap to grow by less than %d bytes while processing, grew by %d bytes", outputSize/2, growth)
+	}
+}
+
+// failingStoreFilesClient fails storing the job files after the first failAfter ones, with err if set.
+type failingStoreFilesClient struct {
+	*mockfiles.MockBatchFilesClient
+	failAfter int
+	stored    int
+	err       error
+}
+
+func (c *failingStoreFilesClient) Store(ctx context.Context, location string, fileSizeLimit int64, reader io.Reader) (*files.BatchFileMetadata, error) {
+	if c.stored >= c.failAfter {
+		if c.err != nil {
+			return nil, c.err
+		}
+		return nil, fmt.Errorf("storage unavailable")
+	}
+	c.stored++
+	return c.MockBatchFilesClient.Store(ctx, location, fileSizeLimit, reader)
+}
+
+func TestFailedJobCleanup(t *testing.T) {
+	env := setupWorkerTestEnv(t, 2)
+
+	// the output file is stored, then storing the error file fails
+	filesClient := &failingStoreFilesClient{MockBatchFilesClient: env.files, failAfter: 1}
+	clients := NewProcessorClients(
+		env.db, env.queue, env.status, mockapi.NewMockBatchEventChannelClient(), env.fileDB, filesClient,
+		mockbatch.NewMockInferenceClient().FailFirst(1, inference.ErrCategoryInvalidReq),
+	)
+	jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	NewProcessor(env.cfg, &clients).processJob(context.Background(), 0, jobs[0])
+
+	statusInfo := openai.BatchStatusInfo{}
+	if err := json.Unmarshal(jobs[0].Status, &statusInfo); err != nil {
+		t.Fatalf("Failed to parse job status: %v", err)
+	}
+	if statusInfo.Status != openai.BatchStatusFailed {
+		t.Fatalf("Expected status %s, got %s", openai.BatchStatusFailed, statusInfo.Status)
+	}
+	if statusInfo.OutputFileID != "" || statusInfo.ErrorFileID != "" {
+		t.Errorf("Expected no result files on the failed batch, got output %q and error %q", statusInfo.OutputFileID, statusInfo.ErrorFileID)
+	}
+
+	// the stored output file was removed with its record
+	stored, err := env.files.List(context.Background(), batch.DefaultTenantID+"/*")
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Location != batch.TenantFileLocation(batch.DefaultTenantID, "file-input") {
+		t.Errorf("Expected only the input file to be stored, got %+v", stored)
+	}
+	if records, _, _ := env.fileDB.Get(context.Background(), nil, nil, api.TagsLogicalCondNa, 0, 10); len(records) != 1 || records[0].ID != "file-input" {
+		t.Errorf("Expected only the input file record, got %d records", len(records))
+	}
+
+	// the partial output files and the checkpoint were removed
+	if entries, _ := os.ReadDir(env.cfg.WorkDir); len(entries) != 0 {
+		t.Errorf("Expected the work dir to be empty, got %d entries", len(entries))
+	}
+	if data, _ := env.status.Get(context.Background(), checkpointKey(env.jobID)); data != nil {
+		t.Errorf("Expected the checkpoint to be deleted")
+	}
+}
+
+func TestOutputOpenFailure(t *testing.T) {
+	env := setupWorkerTestEnv(t, 1)
+
+	// the work dir can't be created over a file
+	workDir := filepath.Join(t.TempDir(), "work")
+	if err := os.WriteFile(workDir, nil, 0o600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	env.cfg.WorkDir = workDir
+
+	client := &fakeInferenceClient{}
+	statusInfo := env.runJob(t, context.Background(), client)
+
+	// the job is failed instead of being left in validating
+	if statusInfo.Status != openai.BatchStatusFailed {
+		t.Fatalf("Expected status %s, got %s", openai.BatchStatusFailed, statusInfo.Status)
+	}
+	if statusInfo.Errors == nil || len(statusInfo.Errors.Data) != 1 || statusInfo.Errors.Data[0].Code != jobFailureInternal {
+		t.Errorf("Expected a %s error, got %+v", jobFailureInternal, statusInfo.Errors)
+	}
+	if client.calls != 0 {
+		t.Errorf("Expected no inference requests, got %d", client.calls)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	t.Run("AuthError", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 5)
+		env.cfg.DeadLetterFailedJobs = true
+
+		series := `jobs_dead_lettered_total{code="inference_unauthorized"}`
+		before := metricValue(t, series)
+
+		client := mockbatch.NewMockInferenceClient().WithError(inference.ErrCategoryAuth)
+		statusInfo := env.runJob(t, context.Background(), client)
+
+		// the job fails at the first rejected request, without sending the other lines
+		if statusInfo.Status != openai.BatchStatusFailed {
+			t.Fatalf("Expected status %s, got %s", openai.BatchStatusFailed, statusInfo.Status)
+		}
+		if client.Calls() != 1 {
+			t.Errorf("Expected 1 inference request, got %d", client.Calls())
+		}
+		wantCode := inferenceFailureCode(inference.ErrCategoryAuth)
+		if statusInfo.Errors == nil || len(statusInfo.Errors.Data) != 1 || statusInfo.Errors.Data[0].Code != wantCode {
+			t.Fatalf("Expected the %s error to be recorded, got %+v", wantCode, statusInfo.Errors)
+		}
+		if statusInfo.DeadLetteredAt == nil {
+			t.Errorf("Expected the dead-letter time to be set")
+		}
+		if statusInfo.OutputFileID != "" || statusInfo.ErrorFileID != "" {
+			t.Errorf("Expected no result files on the failed batch, got output %q and error %q", statusInfo.OutputFileID, statusInfo.ErrorFileID)
+		}
+
+		// the dead-lettered copy carries the failure
+		deadLetters, err := env.db.GetDeadLetters(context.Background(), []string{env.jobID})
+		if err != nil || len(deadLetters) != 1 {
+			t.Fatalf("Expected the job to be dead-lettered, got %d jobs, err %v", len(deadLetters), err)
+		}
+		deadStatus := openai.BatchStatusInfo{}
+		if err := json.Unmarshal(deadLetters[0].Status, &deadStatus); err != nil {
+			t.Fatalf("Failed to parse dead-lettered job status: %v", err)
+		}
+		if deadStatus.Status != openai.BatchStatusFailed || deadStatus.Errors == nil || deadStatus.Errors.Data[0] != statusInfo.Errors.Data[0] {
+			t.Errorf("Expected the dead-lettered job to carry the failure, got status %s and errors %+v", deadStatus.Status, deadStatus.Errors)
+		}
+
+		if got := metricValue(t, series) - before; got != 1 {
+			t.Errorf("Expected metric %s to increase by 1, got %v", series, got)
+		}
+	})
+
+	t.Run("MissingInputFile", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 1)
+		env.cfg.DeadLetterFailedJobs = true
+		if _, err := env.fileDB.Delete(context.Background(), []string{"file-input"}); err != nil {
+			t.Fatalf("Failed to delete input file record: %v", err)
+		}
+
+		statusInfo := env.runJob(t, context.Background(), &fakeInferenceClient{})
+		if statusInfo.Status != openai.BatchStatusFailed {
+			t.Fatalf("Expected status %s, got %s", openai.BatchStatusFailed, statusInfo.Status)
+		}
+		if statusInfo.Errors == nil || len(statusInfo.Errors.Data) != 1 || statusInfo.Errors.Data[0].Code != jobFailureInputFile {
+			t.Fatalf("Expected the %s error to be recorded, got %+v", jobFailureInputFile, statusInfo.Errors)
+		}
+		// the batch error tells which input file is missing
+		batchErr := statusInfo.Errors.Data[0]
+		if batchErr.Param != paramInputFileID || !strings.Contains(batchErr.Message, "file-input was not found") {
+			t.Errorf("Expected a descriptive error about the missing input file, got %+v", batchErr)
+		}
+		if deadLetters, _ := env.db.GetDeadLetters(context.Background(), []string{env.jobID}); len(deadLetters) != 1 {
+			t.Errorf("Expected the job to be dead-lettered, got %d jobs", len(deadLetters))
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+
+		statusInfo := env.runJob(t, context.Background(), mockbatch.NewMockInferenceClient().WithError(inference.ErrCategoryAuth))
+		if statusInfo.Status != openai.BatchStatusFailed || statusInfo.Errors == nil {
+			t.Fatalf("Expected the failed status with its errors, got status %s and errors %+v", statusInfo.Status, statusInfo.Errors)
+		}
+		if statusInfo.DeadLetteredAt != nil {
+			t.Errorf("Expected no dead-letter time")
+		}
+		if deadLetters, _ := env.db.GetDeadLetters(context.Background(), []string{env.jobID}); len(deadLetters) != 0 {
+			t.Errorf("Expected the job not to be dead-lettered, got %d jobs", len(deadLetters))
+		}
+	})
+
+	t.Run("OtherErrorsCompleteJob", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+		env.cfg.DeadLetterFailedJobs = true
+
+		// errors of single requests are reported in the error file of a completed batch
+		statusInfo := env.runJob(t, context.Background(), mockbatch.NewMockInferenceClient().WithError(inference.ErrCategoryRateLimit))
+		if statusInfo.Status != openai.BatchStatusCompleted || statusInfo.RequestCounts.Failed != 2 {
+			t.Fatalf("Expected a completed batch with 2 failed requests, got status %s and %+v", statusInfo.Status, statusInfo.RequestCounts)
+		}
+		if statusInfo.Errors != nil {
+			t.Errorf("Expected no batch errors, got %+v", statusInfo.Errors)
+		}
+		if deadLetters, _ := env.db.GetDeadLetters(context.Background(), []string{env.jobID}); len(deadLetters) != 0 {
+			t.Errorf("Expected the job not to be dead-lettered, got %d jobs", len(deadLetters))
+		}
+	})
+}
+
+func TestInferenceFailureCode(t *testing.T) {
+	tests := []struct {
+		category inference.ErrorCategory
+		want     string
+	}{
+		{category: inference.ErrCategoryAuth, want: "inference_unauthorized"},
+		{category: inference.ErrCategoryRateLimit, want: "inference_rate_limited"},
+		{category: inference.ErrCategoryServer, want: "inference_server_error"},
+		{category: inference.ErrCategoryInvalidReq, want: "inference_invalid_request"},
+		{category: inference.ErrCategoryUnknown, want: "inference_error"},
+		{category: "UNLISTED", want: "inference_error"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.category), func(t *testing.T) {
+			if got := inferenceFailureCode(tt.category); got != tt.want {
+				t.Errorf("Expected code %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJobErrorsByModel(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       *inference.ClientError
+		wantCount float64
+	}{
+		{name: "system error", err: &inference.ClientError{Category: inference.ErrCategoryServer, Message: "server error"}, wantCount: 1},
+		{name: "user error", err: &inference.ClientError{Category: inference.ErrCategoryInvalidReq, Message: "bad request"}, wantCount: 0},
+		{
+			// the requests failed by the open circuit breaker were not sent
+			name:      "circuit open",
+			err:       &inference.ClientError{Category: inference.ErrCategoryServer, Message: "circuit breaker open", RawError: inference.ErrCircuitOpen},
+			wantCount: 0,
+		},
+	}
+
+	const series = `job_errors_by_model_total{model="m"}`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupWorkerTestEnv(t, 1)
+			before := metricValue(t, series)
+			client := &fakeInferenceClient{onCall: func(ctx context.Context, call int) *inference.ClientError { return tt.err }}
+			statusInfo := env.runJob(t, context.Background(), client)
+			if statusInfo.RequestCounts.Failed != 1 {
+				t.Fatalf("Expected 1 failed request, got %+v", statusInfo.RequestCounts)
+			}
+
+			if got := metricValue(t, series) - before; got != tt.wantCount {
+				t.Errorf("Expected metric %s to increase by %v, got %v", series, tt.wantCount, got)
+			}
+		})
+	}
+}
+
+func TestInferenceConcurrencyCap(t *testing.T) {
+	env := setupWorkerTestEnv(t, 8)
+	env.cfg.NumWorkers = 2
+	env.cfg.MaxJobConcurrency = 4
+	env.cfg.CheckpointInterval = 8
+	env.cfg.MaxInferenceConcurrency = 3
+
+	// a second job with the same input
+	jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	second := *jobs[0]
+	second.ID = "batch-test-2"
+	if _, err := env.db.Store(context.Background(), &second); err != nil {
+		t.Fatalf("Failed to store job: %v", err)
+	}
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	client := &fakeInferenceClient{onCall: func(ctx context.Context, call int) *inference.ClientError {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil
+	}}
+
+	// both jobs run concurrently, each allowed more requests than the cap
+	p := env.newProcessor(client)
+	for _, job := range []*api.BatchJob{jobs[0], &second} {
+		workerId, ok := p.workerPool.TryAcquire()
+		if !ok {
+			t.Fatalf("Failed to acquire a worker")
+		}
+		p.startJob(context.Background(), workerId, &api.BatchJobPriority{ID: job.ID, SLO: time.Now().Add(time.Hour)}, job)
+	}
+	p.Stop(context.Background())
+
+	if client.calls != 16 {
+		t.Errorf("Expected 16 inference requests, got %d", client.calls)
+	}
+	if maxInFlight != 3 {
+		t.Errorf("Expected at most 3 inference requests in flight, got %d", maxInFlight)
+	}
+}
+
+// slowFirstLineClient answers the request of req-0 once the requests of the other lines were answered.
+type slowFirstLineClient struct {
+	fakeInferenceClient
+	others sync.WaitGroup
+}
+
+func (c *slowFirstLineClient) Generate(ctx context.Context, req *inference.GenerateRequest) (*inference.GenerateResponse, *inference.ClientError) {
+	if req.RequestID == "req-0" {
+		c.others.Wait()
+		// leaves time to write the lines of the other requests
+		time.Sleep(50 * time.Millisecond)
+	} else {
+		defer c.others.Done()
+	}
+	return c.fakeInferenceClient.Generate(ctx, req)
+}
+
+func TestPreserveInputOrder(t *testing.T) {
+	const numReqs = 4
+
+	tests := []struct {
+		name          string
+		preserveOrder bool
+		wantInOrder   bool // otherwise the slow first line is written last
+	}{
+		{name: "input order", preserveOrder: true, wantInOrder: true},
+		{name: "completion order", preserveOrder: false, wantInOrder: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupWorkerTestEnv(t, numReqs)
+			env.cfg.MaxJobConcurrency = numReqs
+			env.cfg.CheckpointInterval = numReqs
+			env.cfg.PreserveInputOrder = tt.preserveOrder
+
+			client := &slowFirstLineClient{}
+			client.others.Add(numReqs - 1)
+			statusInfo := env.runJob(t, context.Background(), client)
+			if statusInfo.Status != openai.BatchStatusCompleted {
+				t.Fatalf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+			}
+
+			lines := env.readResponseLines(t, statusInfo.OutputFileID)
+			var order []string
+			for _, line := range lines {
+				order = append(order, line.CustomID)
+			}
+			if len(order) != numReqs {
+				t.Fatalf("Expected %d output lines, got %v", numReqs, order)
+			}
+			if slices.IsSorted(order) != tt.wantInOrder {
+				t.Errorf("Expected output lines in input order %v, got %v", tt.wantInOrder, order)
+			}
+			if !tt.wantInOrder && order[numReqs-1] != "req-0" {
+				t.Errorf("Expected the slow line req-0 to be written last, got %v", order)
+			}
+		})
+	}
+}
+
+func TestBatchSLO(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresIn time.Duration
+		wantSLO   string
+	}{
+		{name: "finalized before expiry", expiresIn: time.Hour, wantSLO: metrics.SLOMet},
+		{name: "finalized late", expiresIn: -time.Minute, wantSLO: metrics.SLOMissed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupWorkerTestEnv(t, 2)
+			expiresAt := time.Now().Add(tt.expiresIn).Unix()
+			status, _ := json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusValidating, ExpiresAt: &expiresAt})
+			if err := env.db.Update(context.Background(), &api.BatchJob{ID: env.jobID, Status: status}); err != nil {
+				t.Fatalf("Failed to update job: %v", err)
+			}
+			series := fmt.Sprintf(`batch_slo_met_total{slo="%s",tenantID="%s"}`, tt.wantSLO, batch.DefaultTenantID)
+			before := metricValue(t, series)
+
+			statusInfo := env.runJob(t, context.Background(), &fakeInferenceClient{})
+			if !statusInfo.Status.IsFinal() {
+				t.Fatalf("Expected a final status, got %s", statusInfo.Status)
+			}
+
+			if got := metricValue(t, series) - before; got != 1 {
+				t.Errorf("Expected metric %s to increase by 1, got %v", series, got)
+			}
+		})
+	}
+}
+
+func TestModelDetection(t *testing.T) {
+	chatLine := func(customID, model string) string {
+		return fmt.Sprintf(`{"custom_id":"%s","method":"POST","url":"/v1/chat/completions","body":{"model":"%s","messages":[]}}`+"\n", customID, model)
+	}
+	tests := []struct {
+		name      string
+		input     string
+		wantModel string
+	}{
+		{name: "single model", input: chatLine("a", "m1") + chatLine("b", "m1"), wantModel: "m1"},
+		{name: "mixed models", input: chatLine("a", "m1") + chatLine("b", "m2"), wantModel: ""},
+		{name: "invalid lines ignored", input: chatLine("a", "m1") + "not json\n" + chatLine("b", "m1"), wantModel: "m1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupWorkerTestEnv(t, 0)
+			env.storeInputFile(t, "file-models", bytes.NewBufferString(tt.input))
+			jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+			jobs[0].Spec, _ = json.Marshal(openai.BatchSpec{
+				Object:      "batch",
+				Endpoint:    openai.EndpointChatCompletions,
+				InputFileID: "file-models",
+			})
+
+			statusInfo := env.runJob(t, context.Background(), &fakeInferenceClient{})
+
+			if statusInfo.Status != openai.BatchStatusCompleted {
+				t.Fatalf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+			}
+			if statusInfo.Model != tt.wantModel {
+				t.Errorf("Expected model %q, got %q", tt.wantModel, statusInfo.Model)
+			}
+		})
+	}
+}
+
+// modelEchoClient answers the requests with the model they were sent to
+type modelEchoClient struct {
+	mu     sync.Mutex
+	models []string
+}
+
+func (c *modelEchoClient) Generate(ctx context.Context, req *inference.GenerateRequest) (*inference.GenerateResponse, *inference.ClientError) {
+	model, _ := req.Params["model"].(string)
+	c.mu.Lock()
+	c.models = append(c.models, model)
+	c.mu.Unlock()
+	return &inference.GenerateResponse{
+		RequestID: req.RequestID,
+		Response:  []byte(fmt.Sprintf(`{"id":"resp-%s","model":%q}`, req.RequestID, model)),
+	}, nil
+}
+
+func TestModelAliases(t *testing.T) {
+	tests := []struct {
+		name      string
+		aliases   map[string]string
+		wantSent  string
+		wantModel string
+	}{
+		{name: "mapped model", aliases: map[string]string{"m": "internal-m"}, wantSent: "internal-m", wantModel: "m"},
+		{name: "unmapped model passes through", aliases: map[string]string{"other": "internal-other"}, wantSent: "m", wantModel: "m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupWorkerTestEnv(t, 2)
+			env.cfg.ModelAliases = tt.aliases
+			client := &modelEchoClient{}
+
+			statusInfo := env.runJob(t, context.Background(), client)
+			if statusInfo.Status != openai.BatchStatusCompleted {
+				t.Fatalf("Expected status %s, got %s", openai.BatchStatusCompleted, statusInfo.Status)
+			}
+
+			for _, model := range client.models {
+				if model != tt.wantSent {
+					t.Errorf("Expected the request to be sent to model %q, got %q", tt.wantSent, model)
+				}
+			}
+			if statusInfo.Model != tt.wantModel {
+				t.Errorf("Expected batch model %q, got %q", tt.wantModel, statusInfo.Model)
+			}
+
+			lines := env.readResponseLines(t, statusInfo.OutputFileID)
+			if len(lines) != 2 {
+				t.Fatalf("Expected 2 output lines, got %d", len(lines))
+			}
+			for _, line := range lines {
+				body := map[string]interface{}{}
+				if err := json.Unmarshal(line.Response.Body, &body); err != nil {
+					t.Fatalf("Failed to parse response body %s: %v", line.Response.Body, err)
+				}
+				if body["model"] != tt.wantModel {
+					t.Errorf("Expected output model %q, got %v", tt.wantModel, body["model"])
+				}
+				if body["id"] != "resp-"+line.CustomID {
+					t.Errorf("Expected the other fields of the response to be kept, got %s", line.Response.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestOutputFileExpiration(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *openai.OutputExpiresAfter
+		wantTTL int64
+	}{
+		{name: "batch policy", policy: &openai.OutputExpiresAfter{Anchor: "created_at", Seconds: 3600}, wantTTL: 3600},
+		{name: "default", policy: nil, wantTTL: int64((30 * 24 * time.Hour).Seconds())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupWorkerTestEnv(t, 1)
+			jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+			spec := openai.BatchSpec{}
+			if err := json.Unmarshal(jobs[0].Spec, &spec); err != nil {
+				t.Fatalf("Failed to parse job spec: %v", err)
+			}
+			spec.OutputExpiresAfter = tt.policy
+			jobs[0].Spec, _ = json.Marshal(spec)
+			jobs[0].Tags = []string{batch.TenantTag("tenant-a")}
+
+			statusInfo := env.runJob(t, context.Background(), &fakeInferenceClient{})
+
+			files, _, err := env.fileDB.Get(context.Background(), []string{statusInfo.OutputFileID}, nil, api.TagsLogicalCondNa, 0, 1)
+			if err != nil || len(files) != 1 {
+				t.Fatalf("Expected the output file to be recorded, got %v, err %v", files, err)
+			}
+			fileObj := openai.FileObject{}
+			if err := json.Unmarshal(files[0].Spec, &fileObj); err != nil {
+				t.Fatalf("Failed to parse file object: %v", err)
+			}
+			if fileObj.Purpose != openai.FileObjectPurposeBatchOutput {
+				t.Errorf("Expected purpose %s, got %s", openai.FileObjectPurposeBatchOutput, fileObj.Purpose)
+			}
+			if got := int64(fileObj.ExpiresAt - fileObj.CreatedAt); got != tt.wantTTL {
+				t.Errorf("Expected the output file to expire %ds after its creation, got %ds", tt.wantTTL, got)
+			}
+			if batch.GetTenantIDFromTags(files[0].Tags) != "tenant-a" {
+				t.Errorf("Expected the output file to belong to the tenant of the batch, got tags %v", files[0].Tags)
+			}
+		})
+	}
+}
+
+func TestOutputFileLocation(t *testing.T) {
+	env := setupWorkerTestEnv(t, 1)
+	jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	jobs[0].Tags = []string{batch.TenantTag("tenant-a")}
+
+	p := env.newProcessor(&fakeInferenceClient{})
+	p.locate = func(tenantID, fileID string) string { return "outputs/" + tenantID + "/" + fileID }
+	p.processJob(context.Background(), 0, jobs[0])
+
+	statusInfo := openai.BatchStatusInfo{}
+	if err := json.Unmarshal(jobs[0].Status, &statusInfo); err != nil {
+		t.Fatalf("Failed to parse job status: %v", err)
+	}
+	files, _, err := env.fileDB.Get(context.Background(), []string{statusInfo.OutputFileID}, nil, api.TagsLogicalCondNa, 0, 1)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected the output file to be recorded, got %v, err %v", files, err)
+	}
+
+	// the output is stored at the location recorded for its file ID
+	want := "outputs/tenant-a/" + statusInfo.OutputFileID
+	if files[0].Location != want {
+		t.Errorf("Expected location %q, got %q", want, files[0].Location)
+	}
+	if _, _, err := env.files.Retrieve(context.Background(), want); err != nil {
+		t.Errorf("Expected the output to be stored at %q: %v", want, err)
+	}
+	if lines := env.readResponseLines(t, statusInfo.OutputFileID); len(lines) != 1 {
+		t.Errorf("Expected 1 output line, got %d", len(lines))
+	}
+}
+
+func TestLineTimeout(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.PerLineTimeout = 5 * time.Minute
+	p := NewProcessor(cfg, &ProcessorClients{})
+
+	now := time.Now()
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      time.Duration
+	}{
+		{name: "no expiry", expiresAt: time.Time{}, want: 5 * time.Minute},
+		{name: "far from expiry", expiresAt: now.Add(time.Hour), want: 5 * time.Minute},
+		{name: "near expiry", expiresAt: now.Add(2 * time.Minute), want: 2 * time.Minute},
+		{name: "nearer expiry", expiresAt: now.Add(10 * time.Second), want: 10 * time.Second},
+		{name: "expired", expiresAt: now.Add(-time.Second), want: -time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.lineTimeout(now, tt.expiresAt); got != tt.want {
+				t.Errorf("lineTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("ExpiredLinesFail", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 3)
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		expiresAt := now.Add(-time.Minute).Unix()
+		status, _ := json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusValidating, ExpiresAt: &expiresAt})
+		jobs[0].Status = status
+
+		client := &fakeInferenceClient{}
+		statusInfo := env.runJob(t, context.Background(), client)
+
+		if client.calls != 0 {
+			t.Errorf("Expected no inference requests for an expired batch, got %d", client.calls)
+		}
+		if statusInfo.RequestCounts.Failed != 3 {
+			t.Errorf("Expected 3 failed requests, got %+v", statusInfo.RequestCounts)
+		}
+		for _, line := range env.readResponseLines(t, statusInfo.ErrorFileID) {
+			if line.Error == nil || line.Error.Code != batch.LineErrorCodeBatchExpired {
+				t.Errorf("Expected %s error, got %+v", batch.LineErrorCodeBatchExpired, line.Error)
+			}
+		}
+	})
+
+	t.Run("TimeoutsRecorded", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		expiresAt := now.Add(-time.Minute).Unix()
+		status, _ := json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusValidating, ExpiresAt: &expiresAt})
+		jobs[0].Status = status
+
+		want := map[string]float64{
+			fmt.Sprintf(`line_timeouts_total{code="%s",model="m"}`, batch.LineErrorCodeBatchExpired): 2,
+			fmt.Sprintf(`jobs_processed_total{reason="%s",result="%s",tenantID="%s"}`,
+				metrics.ReasonTimeout, metrics.ResultSuccess, batch.DefaultTenantID): 1,
+		}
+		before := map[string]float64{}
+		for series := range want {
+			before[series] = metricValue(t, series)
+		}
+
+		// the job completes with its expired lines, and records the timeout as the reason of their failure
+		env.runJob(t, context.Background(), &fakeInferenceClient{})
+
+		for series, count := range want {
+			if got := metricValue(t, series) - before[series]; got != count {
+				t.Errorf("Expected metric %s to increase by %v, got %v", series, count, got)
+			}
+		}
+	})
+
+	t.Run("TimedOutJobRecordsTimeoutReason", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 2)
+		env.cfg.PerLineTimeout = 50 * time.Millisecond
+
+		// the second request doesn't complete within the per-line timeout
+		client := &fakeInferenceClient{
+			onCall: func(ctx context.Context, call int) *inference.ClientError {
+				if call == 1 {
+					return nil
+				}
+				<-ctx.Done()
+				return &inference.ClientError{Category: inference.ErrCategoryServer, Message: "request timeout", RawError: ctx.Err()}
+			},
+		}
+		series := fmt.Sprintf(`jobs_processed_total{reason="%s",result="%s",tenantID="%s"}`,
+			metrics.ReasonTimeout, metrics.ResultSuccess, batch.DefaultTenantID)
+		before := metricValue(t, series)
+
+		statusInfo := env.runJob(t, context.Background(), client)
+
+		if statusInfo.RequestCounts.Completed != 1 || statusInfo.RequestCounts.Failed != 1 {
+			t.Errorf("Expected 1 completed and 1 failed request, got %+v", statusInfo.RequestCounts)
+		}
+		if got := metricValue(t, series) - before; got != 1 {
+			t.Errorf("Expected metric %s to increase by 1, got %v", series, got)
+		}
+	})
+
+	t.Run("DeadlinePropagated", func(t *testing.T) {
+		env := setupWorkerTestEnv(t, 1)
+		jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+		expiresAtUnix := time.Now().Add(time.Minute).Unix()
+		status, _ := json.Marshal(openai.BatchStatusInfo{Status: openai.BatchStatusValidating, ExpiresAt: &expiresAtUnix})
+		jobs[0].Status = status
+
+		var deadline time.Time
+		client := &fakeInferenceClient{
+			onCall: func(ctx context.Context, call int) *inference.ClientError {
+				deadline, _ = ctx.Deadline()
+				return nil
+			},
+		}
+		env.runJob(t, context.Background(), client)
+
+		// the deadline is derived from the batch expiry instead of the 5 minutes line timeout
+		if deadline.IsZero() || deadline.Sub(time.Unix(expiresAtUnix, 0)) > time.Millisecond {
+			t.Errorf("Expected inference deadline no later than batch expiry %v, got %v", time.Unix(expiresAtUnix, 0), deadline)
+		}
+	})
+}
+
+func TestLateResponseGracePeriod(t *testing.T) {
+	// the response arrives after the per-line timeout
+	slowClient := func() *fakeInferenceClient {
+		return &fakeInferenceClient{
+			onCall: func(ctx context.Context, call int) *inference.ClientError {
+				select {
+				case <-time.After(100 * time.Millisecond):
+					return nil
+				case <-ctx.Done():
+					return &inference.ClientError{Category: inference.ErrCategoryServer, Message: ctx.Err().Error()}
+				}
+			},
+		}
+	}
+	timeouts := fmt.Sprintf(`line_timeouts_total{code="%s",model="m"}`, batch.LineErrorCodeLineTimeout)
+	jobsProcessed := func(reason string) string {
+		return fmt.Sprintf(`jobs_processed_total{reason="%s",result="%s",tenantID="%s"}`, reason, metrics.ResultSuccess, batch.DefaultTenantID)
+	}
+
+	tests := []struct {
+		name        string
+		gracePeriod time.Duration
+		wantOutput  bool
+	}{
+		{name: "disabled", gracePeriod: 0, wantOutput: false},
+		{name: "late response within grace period", gracePeriod: time.Second, wantOutput: true},
+		{name: "late response after grace period", gracePeriod: 10 * time.Millisecond, wantOutput: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupWorkerTestEnv(t, 1)
+			env.cfg.PerLineTimeout = 20 * time.Millisecond
+			env.cfg.LateResponseGracePeriod = tt.gracePeriod
+
+			// a recovered line is not a timeout of the job
+			reason := jobsProcessed(metrics.ReasonTimeout)
+			if tt.wantOutput {
+				reason = jobsProcessed(metrics.ReasonUnknown)
+			}
+			before, beforeReason := metricValue(t, timeouts), metricValue(t, reason)
+			statusInfo := env.runJob(t, context.Background(), slowClient())
+
+			// the line is failed by the timeout either way, and recovered by a late response
+			if got := metricValue(t, timeouts) - before; got != 1 {
+				t.Errorf("Expected the line to time out once, got %v", got)
+			}
+			if got := metricValue(t, reason) - beforeReason; got != 1 {
+				t.Errorf("Expected metric %s to increase by 1, got %v", reason, got)
+			}
+			if !tt.wantOutput {
+				if statusInfo.RequestCounts.Failed != 1 || statusInfo.OutputFileID != "" {
+					t.Fatalf("Expected the line to stay failed, got %+v and output file %q", statusInfo.RequestCounts, statusInfo.OutputFileID)
+				}
+				errLines := env.readResponseLines(t, statusInfo.ErrorFileID)
+				if len(errLines) != 1 || errLines[0].Error.Code != batch.LineErrorCodeLineTimeout {
+					t.Errorf("Expected the line_timeout line in the error file, got %+v", errLines)
+				}
+				return
+			}
+
+			if statusInfo.RequestCounts.Completed != 1 || statusInfo.RequestCounts.Failed != 0 {
+				t.Errorf("Expected the line to be recovered, got %+v", statusInfo.RequestCounts)
+			}
+			if statusInfo.ErrorFileID != "" {
+				t.Errorf("Expected no error file, got %s", statusInfo.ErrorFileID)
+			}
+			lines := env.readResponseLines(t, statusInfo.OutputFileID)
+			if len(lines) != 1 || lines[0].CustomID != "req-0" || lines[0].Response == nil {
+				t.Errorf("Expected the recovered line in the output file, got %+v", lines)
+			}
+		})
+	}
+}
+
+func TestRecoverLateLines(t *testing.T) {
+	env := setupWorkerTestEnv(t, 0)
+	p := env.newProcessor(&fakeInferenceClient{})
+	ctx := context.Background()
+
+	// req-0 and req-1 timed out, and the late response of req-0 arrived
+	cp, out, err := p.openJobOutput(ctx, env.jobID)
+	if err != nil {
+		t.Fatalf("Failed to open job output: %v", err)
+	}
+	defer out.Close()
+	metadata := batch.JobResultMetadata{}
+	for _, customID := range []string{"req-0", "req-1"} {
+		if err := out.errors.Write(newErrorLine(customID, batch.LineErrorCodeLineTimeout, "timed out")); err != nil {
+			t.Fatalf("Failed to write error line: %v", err)
+		}
+		metadata.Total++
+		metadata.Failed++
+		metadata.TimedOut++
+	}
+	if err := p.commitCheckpoint(ctx, env.jobID, 2, cp, out, &metadata); err != nil {
+		t.Fatalf("Failed to commit checkpoint: %v", err)
+	}
+	late := &lateLines{ctx: ctx}
+	late.add(&batch.ResponseLine{CustomID: "req-0", Response: &batch.LineResponse{StatusCode: 200, Body: []byte(`{}`)}})
+
+	if err := p.recoverLateLines(ctx, env.jobID, late, cp, out, &metadata); err != nil {
+		t.Fatalf("Failed to recover late lines: %v", err)
+	}
+	if !out.closed {
+		t.Errorf("Expected the output files to be closed before the error file is rewritten")
+	}
+
+	// the saved checkpoint matches the rewritten files, so the job resumes with the recovered line
+	saved, err := p.loadCheckpoint(ctx, env.jobID)
+	if err != nil || saved == nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	for location, size := range map[string]int64{saved.OutputLocation: saved.OutputBytes, saved.ErrorLocation: saved.ErrorBytes} {
+		if info, err := os.Stat(location); err != nil || info.Size() != size {
+			t.Errorf("Expected %s to have the %d bytes of the checkpoint, got %v (err %v)", location, size, info, err)
+		}
+	}
+	if saved.LineOffset != 2 || saved.Succeeded != 1 || saved.Failed != 1 || saved.TimedOut != 1 {
+		t.Errorf("Expected the recovered line in the checkpoint, got %+v", saved)
+	}
+	resumedCp, resumed, err := p.openJobOutput(ctx, env.jobID)
+	if err != nil {
+		t.Fatalf("Failed to resume job output: %v", err)
+	}
+	resumed.Close()
+	if resumedCp.OutputBytes == 0 || resumedCp.ErrorBytes != saved.ErrorBytes {
+		t.Errorf("Expected the job to resume from the checkpoint of the recovery, got %+v", resumedCp)
+	}
+}
+
+func TestLateDeadline(t *testing.T) {
+	start := time.Now()
+	p := &Processor{cfg: &config.ProcessorConfig{PerLineTimeout: time.Second, LateResponseGracePeriod: time.Minute}}
+
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		expiresAt time.Time
+		want      time.Time
+	}{
+		{name: "never expires", timeout: time.Second, want: start.Add(time.Second + time.Minute)},
+		{name: "expires after the grace period", timeout: time.Second, expiresAt: start.Add(time.Hour), want: start.Add(time.Second + time.Minute)},
+		{name: "expires within the grace period", timeout: time.Second, expiresAt: start.Add(10 * time.Second), want: start.Add(10 * time.Second)},
+		{name: "timeout shortened by the expiry", timeout: 500 * time.Millisecond, expiresAt: start.Add(500 * time.Millisecond)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.lateDeadline(start, tt.timeout, tt.expiresAt); !got.Equal(tt.want) {
+				t.Errorf("Expected deadline %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPerLineTimeout(t *testing.T) {
+	env := setupWorkerTestEnv(t, 1)
+	env.cfg.PerLineTimeout = 50 * time.Millisecond
+
+	// the inference call only returns once it is cancelled
+	var elapsed time.Duration
+	client := &fakeInferenceClient{
+		onCall: func(ctx context.Context, call int) *inference.ClientError {
+			start := time.Now()
+			select {
+			case <-ctx.Done():
+				elapsed = time.Since(start)
+				return &inference.ClientError{Category: inference.ErrCategoryServer, Message: "request timeout"}
+			case <-time.After(5 * time.Second):
+				return nil
+			}
+		},
+	}
+	statusInfo := env.runJob(t, context.Background(), client)
+
+	if elapsed < env.cfg.PerLineTimeout || elapsed > time.Second {
+		t.Errorf("Expected the inference call to be cancelled after %v, got %v", env.cfg.PerLineTimeout, elapsed)
+	}
+	if statusInfo.RequestCounts.Failed != 1 {
+		t.Fatalf("Expected 1 failed request, got %+v", statusInfo.RequestCounts)
+	}
+	errLines := env.readResponseLines(t, statusInfo.ErrorFileID)
+	if len(errLines) != 1 || errLines[0].Error == nil || errLines[0].Error.Code != batch.LineErrorCodeLineTimeout {
+		t.Errorf("Expected a %s error line, got %+v", batch.LineErrorCodeLineTimeout, errLines)
+	}
+}
+
+// shutdownFilesClient simulates a SIGTERM received while the input file of a job is retrieved.
+type shutdownFilesClient struct {
+	*mockfiles.MockBatchFilesClient
+	shutdown context.CancelFunc
+	err      error
+}
+
+func (c *shutdownFilesClient) Retrieve(ctx context.Context, location string) (io.Reader, *files.BatchFileMetadata, error) {
+	c.shutdown()
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+	return c.MockBatchFilesClient.Retrieve(ctx, location)
+}
+
+func TestShutdownDuringValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		retrieveErr  error
+		wantRequeued bool
+		wantStatus   openai.BatchStatus
+	}{
+		{name: "validation stopped", timeout: 0, wantRequeued: true, wantStatus: openai.BatchStatusValidating},
+		{name: "validation completed", timeout: time.Second, wantRequeued: true, wantStatus: openai.BatchStatusValidating},
+		{name: "validation failed", timeout: time.Second, retrieveErr: fmt.Errorf("file not found"), wantStatus: openai.BatchStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupWorkerTestEnv(t, 3)
+			env.cfg.ValidationShutdownTimeout = tt.timeout
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			filesClient := &shutdownFilesClient{MockBatchFilesClient: env.files, shutdown: cancel, err: tt.retrieveErr}
+			client := &fakeInferenceClient{}
+			clients := NewProcessorClients(
+				env.db, env.queue, env.status, mockapi.NewMockBatchEventChannelClient(), env.fileDB, filesClient, client,
+			)
+			p := NewProcessor(env.cfg, &clients)
+
+			jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+			task := &api.BatchJobPriority{ID: env.jobID, SLO: time.Now().Add(time.Hour)}
+			workerId, ok := p.workerPool.TryAcquire()
+			if !ok {
+				t.Fatalf("Failed to acquire a worker")
+			}
+			p.startJob(ctx, workerId, task, jobs[0])
+			p.Stop(context.Background())
+
+			if client.calls != 0 {
+				t.Errorf("Expected no inference requests, got %d", client.calls)
+			}
+			statusInfo := openai.BatchStatusInfo{}
+			if err := json.Unmarshal(jobs[0].Status, &statusInfo); err != nil {
+				t.Fatalf("Failed to parse job status: %v", err)
+			}
+			if statusInfo.Status != tt.wantStatus {
+				t.Errorf("Expected status %s, got %s", tt.wantStatus, statusInfo.Status)
+			}
+
+			queued, err := env.queue.Dequeue(context.Background(), 0, 1)
+			if err != nil {
+				t.Fatalf("Failed to dequeue: %v", err)
+			}
+			if requeued := len(queued) == 1 && queued[0].ID == env.jobID; requeued != tt.wantRequeued {
+				t.Errorf("Expected requeued=%v, got queue %+v", tt.wantRequeued, queued)
+			}
+		})
+	}
+}
+
+func TestShutdownDuringProcessing(t *testing.T) {
+	numReqs := 4
+	env := setupWorkerTestEnv(t, numReqs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the processor is shut down while processing the 3rd line
+	client := &fakeInferenceClient{
+		onCall: func(ctx context.Context, call int) *inference.ClientError {
+			if call == 3 {
+				cancel()
+				<-ctx.Done()
+				return &inference.ClientError{Category: inference.ErrCategoryUnknown, Message: ctx.Err().Error()}
+			}
+			return nil
+		},
+	}
+	p := env.newProcessor(client)
+
+	jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	task := &api.BatchJobPriority{ID: env.jobID, SLO: time.Now().Add(time.Hour), Priority: api.PriorityHigh}
+	workerId, ok := p.workerPool.TryAcquire()
+	if !ok {
+		t.Fatalf("Failed to acquire a worker")
+	}
+	p.startJob(ctx, workerId, task, jobs[0])
+	p.Stop(context.Background())
+
+	// the job is back in the queue with its original priority
+	queued, err := env.queue.Dequeue(context.Background(), 0, 1)
+	if err != nil {
+		t.Fatalf("Failed to dequeue: %v", err)
+	}
+	if len(queued) != 1 || queued[0].ID != env.jobID || queued[0].Priority != api.PriorityHigh || !queued[0].SLO.Equal(task.SLO) {
+		t.Fatalf("Expected the job to be re-queued with its priority, got queue %+v", queued)
+	}
+
+	// the progress of the lines completed before the shutdown is kept
+	cp, err := p.loadCheckpoint(context.Background(), env.jobID)
+	if err != nil || cp == nil {
+		t.Fatalf("Expected a checkpoint, got %v, err %v", cp, err)
+	}
+	if cp.LineOffset != 2 {
+		t.Errorf("Expected checkpoint at line 2, got %d", cp.LineOffset)
+	}
+
+	// the re-queued job resumes with the remaining lines
+	resumeClient := &fakeInferenceClient{}
+	statusInfo := env.runJob(t, context.Background(), resumeClient)
+	if resumeClient.calls != numReqs-2 {
+		t.Errorf("Expected %d requests after resume, got %d", numReqs-2, resumeClient.calls)
+	}
+	if statusInfo.Status != openai.BatchStatusCompleted || statusInfo.RequestCounts.Completed != int64(numReqs) {
+		t.Errorf("Expected %d completed requests, got status %s and %+v", numReqs, statusInfo.Status, statusInfo.RequestCounts)
+	}
+}
+
+func TestStopDrainsWorkers(t *testing.T) {
+	env := setupWorkerTestEnv(t, 0)
+	env.cfg.NumWorkers = 2
+	p := env.newProcessor(&fakeInferenceClient{})
+	p.drainLogInterval = time.Millisecond
+
+	drainingJobs := func() string {
+		rr := httptest.NewRecorder()
+		metrics.NewMetricsHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		for _, line := range strings.Split(rr.Body.String(), "\n") {
+			if value, ok := strings.CutPrefix(line, "draining_jobs "); ok {
+				return value
+			}
+		}
+		return ""
+	}
+	waitForDrainingJobs := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for drainingJobs() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected draining_jobs %s, got %s", want, drainingJobs())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	var workerIds []int
+	for range 2 {
+		workerId, ok := p.workerPool.TryAcquire()
+		if !ok {
+			t.Fatalf("Failed to acquire a worker")
+		}
+		workerIds = append(workerIds, workerId)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		p.Stop(context.Background())
+		close(stopped)
+	}()
+
+	// the gauge counts the workers remaining as they finish
+	waitForDrainingJobs("2")
+	p.workerPool.Release(workerIds[0])
+	waitForDrainingJobs("1")
+	select {
+	case <-stopped:
+		t.Fatal("Expected Stop to wait for the remaining worker")
+	default:
+	}
+	p.workerPool.Release(workerIds[1])
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Stop to return once the workers finished")
+	}
+	if got := drainingJobs(); got != "0" {
+		t.Errorf("Expected draining_jobs 0, got %s", got)
+	}
+}
+
+func TestReclaimingJobRequeuedOnShutdown(t *testing.T) {
+	env := setupWorkerTestEnv(t, 0)
+	env.cfg.PollInterval = 10 * time.Millisecond
+	env.cfg.TaskWaitTime = time.Millisecond
+	env.cfg.WorkerReservations = map[string]int{"premium": 1}
+	p := env.newProcessor(&fakeInferenceClient{})
+
+	// the reserved worker is lent to another tenant, whose job doesn't release it
+	workerId, ok := p.workerPool.TryAcquire()
+	if !ok {
+		t.Fatalf("Failed to acquire a worker")
+	}
+	lease := p.workerPool.Assign(workerId, "other")
+
+	jobs, _, _ := env.db.Get(context.Background(), []string{env.jobID}, nil, api.TagsLogicalCondNa, true, 0, 1)
+	jobs[0].Tags = []string{batch.TenantTag("premium")}
+	task := &api.BatchJobPriority{ID: env.jobID, SLO: time.Now().Add(time.Hour)}
+	if err := env.queue.Enqueue(context.Background(), task); err != nil {
+		t.Fatalf("Failed to enqueue job: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.RunPollingLoop(ctx) }()
+
+	// the processor shuts down while the job waits for the reclaimed worker
+	select {
+	case <-lease.Reclaimed():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the lent worker to be reclaimed")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Polling loop failed: %v", err)
+	}
+
+	if n, _ := env.queue.Len(context.Background()); n != 1 {
+		t.Errorf("Expected the job to be put back to the queue, got %d queued jobs", n)
+	}
+}
@@ -47,6 +47,12 @@ type JobResultMetadata struct {
 	Total     int `json:"total"`
 	Succeeded int `json:"succeeded"`
 	Failed    int `json:"failed"`
+
+	// the failed requests that didn't complete in time, before the per-line timeout or the expiry of the batch
+	TimedOut int `json:"timed_out"`
+
+	// the token usage of the succeeded requests
+	Usage openai.BatchUsage `json:"usage"`
 }
 
 func (rm JobResultMetadata) Validate() bool {
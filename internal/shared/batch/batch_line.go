@@ -0,0 +1,238 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file defines the line formats of the batch input, output and error files.
+
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// https://platform.openai.com/docs/api-reference/batch/request-input
+
+// RequestLine represents a line in the batch input file.
+type RequestLine struct {
+	// A developer-provided per-request id that will be used to match outputs to inputs.
+	CustomID string `json:"custom_id"`
+
+	// The HTTP method to be used for the request. Currently only `POST` is supported.
+	Method string `json:"method"`
+
+	// The relative URL to be used for the request, matching the endpoint of the batch.
+	// In a mixed-endpoint batch, it is any supported endpoint.
+	URL string `json:"url"`
+
+	// The request body, which must include the model.
+	Body map[string]interface{} `json:"body"`
+
+	// A request body with {{name}} placeholders, expanded with Vars into the body.
+	// A line has either a body or a template. See template.go for the placeholder syntax.
+	Template map[string]interface{} `json:"template,omitempty"`
+
+	// The variables substituted for the placeholders of the template.
+	Vars map[string]interface{} `json:"vars,omitempty"`
+}
+
+// ExpandTemplate expands the template of a templated line into its body.
+// It fails if the line has both a body and a template, or if a placeholder is not resolved by the vars.
+// Lines without a template are left unchanged.
+func (r *RequestLine) ExpandTemplate() error {
+	if r.Template == nil {
+		if r.Vars != nil {
+			return errors.New("vars requires a template")
+		}
+		return nil
+	}
+	if r.Body != nil {
+		return errors.New("body and template are mutually exclusive")
+	}
+	body, err := expandTemplate(r.Template, r.Vars)
+	if err != nil {
+		return err
+	}
+	r.Body = body
+	r.Template = nil
+	r.Vars = nil
+	return nil
+}
+
+// Validate checks that the request line is well-formed for a batch targeting the given endpoint.
+func (r *RequestLine) Validate(endpoint openai.Endpoint) error {
+	return r.validate(func() error {
+		if r.URL != endpoint.String() {
+			return fmt.Errorf("url %q does not match the batch endpoint %q", r.URL, endpoint)
+		}
+		return nil
+	})
+}
+
+// ValidateMixed checks that the request line is well-formed for a mixed-endpoint batch,
+// where the url of each line selects the endpoint the request is sent to.
+func (r *RequestLine) ValidateMixed() error {
+	return r.validate(func() error {
+		if !openai.Endpoint(r.URL).IsValid() {
+			return fmt.Errorf("url %q is not a supported endpoint", r.URL)
+		}
+		return nil
+	})
+}
+
+func (r *RequestLine) validate(validateURL func() error) error {
+	if r.CustomID == "" {
+		return errors.New("custom_id is required")
+	}
+	if r.Method != http.MethodPost {
+		return fmt.Errorf("method must be %s, got %q", http.MethodPost, r.Method)
+	}
+	if err := validateURL(); err != nil {
+		return err
+	}
+	if r.Body == nil {
+		return errors.New("body is required")
+	}
+	if model, ok := r.Body["model"].(string); !ok || model == "" {
+		return errors.New("body.model is required")
+	}
+	// moderations classify the input and the Responses API answers it, neither has a request without it
+	if endpoint := openai.Endpoint(r.URL); (endpoint == openai.EndpointModerations || endpoint == openai.EndpointResponses) && r.Body["input"] == nil {
+		return errors.New("body.input is required")
+	}
+	return nil
+}
+
+// strictBodyFields are the fields of the request body that the strict validation requires for each endpoint,
+// besides the model. The lenient validation lets the inference backend reject the bodies missing them.
+var strictBodyFields = map[openai.Endpoint]string{
+	openai.EndpointChatCompletions: "messages",
+	openai.EndpointCompletions:     "prompt",
+	openai.EndpointEmbeddings:      "input",
+}
+
+// validateStrict checks the request body of a validated line against the fields its endpoint requires.
+func (r *RequestLine) validateStrict() error {
+	field, ok := strictBodyFields[openai.Endpoint(r.URL)]
+	if !ok {
+		return nil
+	}
+	switch value := r.Body[field].(type) {
+	case nil:
+		return fmt.Errorf("body.%s is required", field)
+	case []interface{}:
+		if len(value) == 0 {
+			return fmt.Errorf("body.%s must not be empty", field)
+		}
+	default:
+		// the messages of a chat are always a list, prompts and inputs may also be a single string
+		if field == "messages" {
+			return errors.New("body.messages must be a list")
+		}
+	}
+	return nil
+}
+
+// ParseRequestLine parses a line of the input file of a batch targeting endpoint, expands its template
+// and validates it. In a mixed-endpoint batch, the url of the line selects its endpoint.
+// The strict validation also rejects the lines with unknown keys and the bodies missing the fields
+// required by their endpoint; the lenient validation, used when strictness is not set, only checks
+// the keys required to route the line.
+// On failure, the returned line error tells why the line is invalid, and the returned line
+// is nil when the line is not valid JSON.
+func ParseRequestLine(data []byte, endpoint openai.Endpoint, mixed bool, strictness openai.ValidationStrictness) (*RequestLine, *LineError) {
+	line := &RequestLine{}
+	if err := json.Unmarshal(data, line); err != nil {
+		return nil, &LineError{Code: LineErrorCodeInvalidJSON, Message: fmt.Sprintf("invalid JSON line: %v", err)}
+	}
+	if strictness == openai.ValidationStrict {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&RequestLine{}); err != nil {
+			return line, &LineError{Code: LineErrorCodeInvalidRequest, Message: err.Error()}
+		}
+	}
+	if err := line.ExpandTemplate(); err != nil {
+		return line, &LineError{Code: LineErrorCodeInvalidRequest, Message: err.Error()}
+	}
+	validate := func() error { return line.Validate(endpoint) }
+	if mixed {
+		validate = line.ValidateMixed
+	}
+	if err := validate(); err != nil {
+		return line, &LineError{Code: LineErrorCodeInvalidRequest, Message: err.Error()}
+	}
+	if strictness == openai.ValidationStrict {
+		if err := line.validateStrict(); err != nil {
+			return line, &LineError{Code: LineErrorCodeInvalidRequest, Message: err.Error()}
+		}
+	}
+	return line, nil
+}
+
+// https://platform.openai.com/docs/api-reference/batch/request-output
+
+// ResponseLine represents a line in the batch output or error file.
+type ResponseLine struct {
+	ID       string        `json:"id"`
+	CustomID string        `json:"custom_id"`
+	Response *LineResponse `json:"response"`
+	Error    *LineError    `json:"error"`
+}
+
+// OutputPage is a page of the response lines of the output file of a batch.
+type OutputPage struct {
+	openai.ListResponse[ResponseLine]
+
+	// The cursor of the next page, to be given as its after parameter. It is the number of the last line of the page.
+	NextAfter int64 `json:"next_after,omitempty"`
+}
+
+// LineResponse holds the inference response of a request line.
+type LineResponse struct {
+	// The HTTP status code of the response.
+	StatusCode int `json:"status_code"`
+
+	// An unique identifier for the request.
+	RequestID string `json:"request_id"`
+
+	// The JSON body of the response.
+	Body json.RawMessage `json:"body"`
+}
+
+// LineError holds the error of a request line that failed.
+type LineError struct {
+	// A machine-readable error code.
+	Code string `json:"code"`
+
+	// A human-readable error message.
+	Message string `json:"message"`
+}
+
+// Line error codes
+const (
+	LineErrorCodeInvalidJSON       = "invalid_json_line"
+	LineErrorCodeInvalidRequest    = "invalid_request"
+	LineErrorCodeBatchExpired      = "batch_expired"
+	LineErrorCodeLineTimeout       = "line_timeout"
+	LineErrorCodeBodyTooLarge      = "body_too_large"
+	LineErrorCodeTooManyInputs     = "too_many_inputs"
+	LineErrorCodeDuplicateCustomID = "duplicate_custom_id"
+)
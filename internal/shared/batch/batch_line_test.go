@@ -0,0 +1,123 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the parsing and validation of the request lines.
+package batch
+
+import (
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+func TestParseRequestLineStrictness(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		endpoint    openai.Endpoint
+		mixed       bool
+		wantLenient bool // whether the lenient validation accepts the line
+		wantStrict  bool // whether the strict validation accepts the line
+	}{
+		{
+			name:        "well-formed line",
+			line:        `{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[{"role":"user","content":"hi"}]}}`,
+			endpoint:    openai.EndpointChatCompletions,
+			wantLenient: true,
+			wantStrict:  true,
+		},
+		{
+			name:        "unknown top-level key",
+			line:        `{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[{"role":"user","content":"hi"}]},"priority":"high"}`,
+			endpoint:    openai.EndpointChatCompletions,
+			wantLenient: true,
+		},
+		{
+			name:        "chat body without messages",
+			line:        `{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"model":"m","prompt":"hi"}}`,
+			endpoint:    openai.EndpointChatCompletions,
+			wantLenient: true,
+		},
+		{
+			name:        "chat messages not a list",
+			line:        `{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":"hi"}}`,
+			endpoint:    openai.EndpointChatCompletions,
+			wantLenient: true,
+		},
+		{
+			name:        "completion without prompt",
+			line:        `{"custom_id":"req-1","method":"POST","url":"/v1/completions","body":{"model":"m"}}`,
+			endpoint:    openai.EndpointCompletions,
+			wantLenient: true,
+		},
+		{
+			name:        "embeddings with empty input",
+			line:        `{"custom_id":"req-1","method":"POST","url":"/v1/embeddings","body":{"model":"m","input":[]}}`,
+			endpoint:    openai.EndpointEmbeddings,
+			wantLenient: true,
+		},
+		{
+			name:        "mixed batch line without the fields of its endpoint",
+			line:        `{"custom_id":"req-1","method":"POST","url":"/v1/embeddings","body":{"model":"m"}}`,
+			endpoint:    openai.EndpointChatCompletions,
+			mixed:       true,
+			wantLenient: true,
+		},
+		{
+			name:        "templated line",
+			line:        `{"custom_id":"req-1","method":"POST","url":"/v1/completions","template":{"model":"m","prompt":"{{q}}"},"vars":{"q":"hi"}}`,
+			endpoint:    openai.EndpointCompletions,
+			wantLenient: true,
+			wantStrict:  true,
+		},
+		{
+			name:     "missing model",
+			line:     `{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"messages":[{"role":"user","content":"hi"}]}}`,
+			endpoint: openai.EndpointChatCompletions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for strictness, want := range map[openai.ValidationStrictness]bool{
+				openai.ValidationLenient: tt.wantLenient,
+				openai.ValidationStrict:  tt.wantStrict,
+			} {
+				line, lineErr := ParseRequestLine([]byte(tt.line), tt.endpoint, tt.mixed, strictness)
+				if want && lineErr != nil {
+					t.Errorf("Expected the %s validation to accept the line, got error: %s", strictness, lineErr.Message)
+				}
+				if !want {
+					if lineErr == nil {
+						t.Errorf("Expected the %s validation to reject the line", strictness)
+					} else if lineErr.Code != LineErrorCodeInvalidRequest {
+						t.Errorf("Expected error code %s with the %s validation, got %s", LineErrorCodeInvalidRequest, strictness, lineErr.Code)
+					}
+				}
+				if line == nil || line.CustomID != "req-1" {
+					t.Errorf("Expected the parsed line to be returned with the %s validation, got %+v", strictness, line)
+				}
+			}
+		})
+	}
+
+	t.Run("unset strictness is lenient", func(t *testing.T) {
+		line := `{"custom_id":"req-1","method":"POST","url":"/v1/completions","body":{"model":"m"},"extra":true}`
+		if _, lineErr := ParseRequestLine([]byte(line), openai.EndpointCompletions, false, ""); lineErr != nil {
+			t.Errorf("Expected the line to be accepted, got error: %s", lineErr.Message)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file tracks the custom_id of the request lines of an input file. The custom_id of a line
+// must be unique within the file, as it is the only way to match the output lines to the requests.
+
+package batch
+
+// DuplicateCustomID is a custom_id used by more than one line, with the numbers of these lines.
+type DuplicateCustomID struct {
+	CustomID string
+	Lines    []int64
+}
+
+// CustomIDs records the lines using each custom_id of an input file, in the order of the file.
+type CustomIDs struct {
+	lines      map[string][]int64
+	duplicated []string // in the order they were first duplicated
+}
+
+// NewCustomIDs returns the tracker of the custom_ids of one input file.
+func NewCustomIDs() *CustomIDs {
+	return &CustomIDs{lines: map[string][]int64{}}
+}
+
+// Add records the line using the custom_id, and returns the number of the first line using it,
+// or 0 if no line used it before.
+func (c *CustomIDs) Add(customID string, lineNum int64) int64 {
+	lines := c.lines[customID]
+	if len(lines) == 1 {
+		c.duplicated = append(c.duplicated, customID)
+	}
+	c.lines[customID] = append(lines, lineNum)
+	if len(lines) == 0 {
+		return 0
+	}
+	return lines[0]
+}
+
+// Duplicates returns the custom_ids used by more than one line, in the order they were first duplicated.
+func (c *CustomIDs) Duplicates() []DuplicateCustomID {
+	duplicates := make([]DuplicateCustomID, len(c.duplicated))
+	for i, customID := range c.duplicated {
+		duplicates[i] = DuplicateCustomID{CustomID: customID, Lines: c.lines[customID]}
+	}
+	return duplicates
+}
@@ -0,0 +1,30 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the marker of the input files being deleted.
+// A file is marked before its references are checked, and a batch is checked for the mark of its input file once
+// it is stored: either the deletion finds the batch, or the batch finds the mark, and is not created.
+package batch
+
+// FileDeletionTTLSeconds is the TTL of the mark of a file being deleted, which outlives the deletion.
+const FileDeletionTTLSeconds = 60
+
+const fileDeletionKeySuffix = ":deleting"
+
+// FileDeletionKey returns the status key marking a file being deleted.
+func FileDeletionKey(fileID string) string {
+	return fileID + fileDeletionKeySuffix
+}
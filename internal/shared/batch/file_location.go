@@ -0,0 +1,32 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the mapping of the public IDs of files to the locations of their content in the files storage.
+// The location of a file is chosen when its content is stored, and recorded with its metadata in the file database,
+// so the files are resolved by their record and the storage layout can change without affecting existing files.
+package batch
+
+import (
+	"net/url"
+)
+
+// FileLocator returns the location in the files storage where the content of a new tenant's file is stored.
+type FileLocator func(tenantID, fileID string) string
+
+// TenantFileLocation is the default FileLocator. It lays the files out by tenant, as <tenant ID>/<file ID>.
+func TenantFileLocation(tenantID, fileID string) string {
+	return url.PathEscape(tenantID) + "/" + fileID
+}
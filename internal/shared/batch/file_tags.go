@@ -0,0 +1,29 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides helpers for tagging batch files.
+package batch
+
+import (
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+const purposeTagPrefix = "purpose="
+
+// PurposeTag returns the DB tag used to select files by purpose.
+func PurposeTag(purpose openai.FileObjectPurpose) string {
+	return purposeTagPrefix + string(purpose)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the reading of batch input files, which may be uploaded gzip compressed.
+// An input file is either JSONL, one request per line, or a single JSON object holding one request,
+// which may span several lines. The two formats are told apart by the content of the file.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// maxSingleRequestBytes is the maximum size of an input file holding a single JSON request.
+// The content of larger files is read as JSONL.
+const maxSingleRequestBytes = 16 * 1024 * 1024
+
+// SingleRequestCustomID is the custom_id given to the request of a single-request input file that has none.
+const SingleRequestCustomID = "request-1"
+
+// gzipMagic is the header of gzip compressed content
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// IsGzip reports whether data starts with the gzip magic bytes.
+func IsGzip(data []byte) bool {
+	return bytes.HasPrefix(data, gzipMagic)
+}
+
+// NewInputReader returns a reader of the JSONL content of a batch input file.
+// Gzip compressed content, detected by its magic bytes, is decompressed transparently.
+// The content of a single-request input file is read as one line, with a synthesized custom_id if it has none.
+func NewInputReader(r io.Reader) (io.Reader, error) {
+	reader := bufio.NewReader(r)
+	header, err := reader.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !IsGzip(header) {
+		return singleRequestReader(reader)
+	}
+	zr, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	return singleRequestReader(bufio.NewReader(zr))
+}
+
+// singleRequestReader returns a reader of the content as JSONL. If the content is a single JSON object,
+// it is returned as one line. Otherwise the content is returned unchanged, to be read as JSONL.
+func singleRequestReader(content *bufio.Reader) (io.Reader, error) {
+	first, err := peekNonSpace(content)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if err != nil || first != '{' {
+		return content, nil
+	}
+
+	// the first value is decoded from the start of the content, which is recorded to be read again if more follows
+	var recorded bytes.Buffer
+	decoder := json.NewDecoder(io.TeeReader(io.LimitReader(content, maxSingleRequestBytes), &recorded))
+	var request json.RawMessage
+	if err := decoder.Decode(&request); err != nil {
+		return io.MultiReader(&recorded, content), nil
+	}
+	end := decoder.InputOffset()
+	rest := bufio.NewReader(io.MultiReader(bytes.NewReader(recorded.Bytes()[end:]), content))
+	_, err = peekNonSpace(rest)
+	if errors.Is(err, io.EOF) {
+		return bytes.NewReader(singleRequestLine(request)), nil
+	}
+	if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	return io.MultiReader(bytes.NewReader(recorded.Bytes()[:end]), rest), nil
+}
+
+// singleRequestLine returns the request of a single-request input file as a JSONL line,
+// with the SingleRequestCustomID if the request has no custom_id.
+func singleRequestLine(request json.RawMessage) []byte {
+	var line bytes.Buffer
+	if err := json.Compact(&line, request); err != nil {
+		return append(request, '\n')
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(request, &fields); err == nil {
+		if _, ok := fields["custom_id"]; !ok {
+			customID := `{"custom_id":"` + SingleRequestCustomID + `"`
+			if len(fields) > 0 {
+				customID += ","
+			}
+			compact := line.Bytes()
+			return append(append([]byte(customID), compact[1:]...), '\n')
+		}
+	}
+	return append(line.Bytes(), '\n')
+}
+
+// peekNonSpace returns the first byte of the content that is not white space, without reading it.
+// It returns io.EOF if there is none, and bufio.ErrBufferFull if the white space fills the buffer.
+func peekNonSpace(content *bufio.Reader) (byte, error) {
+	for n := 1; ; n++ {
+		data, err := content.Peek(n)
+		if len(data) < n {
+			return 0, err
+		}
+		if c := data[n-1]; c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return c, nil
+		}
+	}
+}
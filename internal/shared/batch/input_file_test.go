@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the reading of batch input files.
+package batch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewInputReader(t *testing.T) {
+	const content = `{"custom_id":"req-1"}` + "\n"
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", input: []byte(content), want: content},
+		{name: "gzip", input: gzipData(t, content), want: content},
+		{name: "empty", input: nil, want: ""},
+		{name: "single byte", input: []byte("x"), want: "x"},
+		{name: "corrupt gzip", input: append([]byte{}, gzipMagic...), wantErr: true},
+		{name: "jsonl", input: []byte(content + content), want: content + content},
+		{name: "jsonl without trailing newline", input: []byte(content + `{"custom_id":"req-2"}`), want: content + `{"custom_id":"req-2"}`},
+		{name: "jsonl with invalid first line", input: []byte("{\"custom_id\":\n" + content), want: "{\"custom_id\":\n" + content},
+		{name: "jsonl with blank lines", input: []byte(content + "\n \n" + content), want: content + "\n \n" + content},
+		{name: "not an object", input: []byte("[1, 2]\n"), want: "[1, 2]\n"},
+		{
+			name:  "single request",
+			input: []byte("{\n  \"custom_id\": \"req-1\"\n}"),
+			want:  content,
+		},
+		{
+			name:  "single request without custom_id",
+			input: []byte("\n{\n  \"method\": \"POST\",\n  \"body\": {\"model\": \"m\"}\n}\n\n"),
+			want:  `{"custom_id":"request-1","method":"POST","body":{"model":"m"}}` + "\n",
+		},
+		{name: "empty single request", input: []byte("{ }"), want: `{"custom_id":"request-1"}` + "\n"},
+		{name: "gzip single request", input: gzipData(t, "{\n\"custom_id\": \"req-1\"\n}\n"), want: content},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewInputReader(bytes.NewReader(tt.input))
+			if err == nil {
+				var data []byte
+				data, err = io.ReadAll(reader)
+				if err == nil && string(data) != tt.want {
+					t.Errorf("Expected content %q, got %q", tt.want, data)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file defines the limits of the endpoints that the request lines of an input file are checked against,
+// so lines the inference gateway would reject are reported when the batch is created instead of being sent.
+
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// InputLimits are the limits of the request lines of a batch.
+type InputLimits struct {
+	// MaxBodyBytes is the maximum size of the body of a request line, once its template is expanded.
+	// Zero means no limit.
+	MaxBodyBytes int
+
+	// MaxEmbeddingsInputs is the maximum number of inputs of the embeddings requests across the lines of a batch.
+	// Zero means no limit.
+	MaxEmbeddingsInputs int
+}
+
+// InputLimitChecker checks the lines of an input file against the limits, in the order of the file.
+type InputLimitChecker struct {
+	limits           InputLimits
+	embeddingsInputs int
+}
+
+// NewChecker returns a checker of the lines of one input file.
+func (l InputLimits) NewChecker() *InputLimitChecker {
+	return &InputLimitChecker{limits: l}
+}
+
+// Check checks a valid request line against the limits, and returns the error of the line when it exceeds one.
+// The limit of embeddings inputs is reported once, on the line exceeding it.
+func (c *InputLimitChecker) Check(line *RequestLine) *LineError {
+	if c.limits.MaxBodyBytes > 0 {
+		body, err := json.Marshal(line.Body)
+		if err != nil {
+			return &LineError{Code: LineErrorCodeInvalidRequest, Message: fmt.Sprintf("invalid body: %v", err)}
+		}
+		if len(body) > c.limits.MaxBodyBytes {
+			return &LineError{
+				Code:    LineErrorCodeBodyTooLarge,
+				Message: fmt.Sprintf("the request body is %d bytes, more than the limit of %d bytes", len(body), c.limits.MaxBodyBytes),
+			}
+		}
+	}
+
+	if c.limits.MaxEmbeddingsInputs > 0 && openai.Endpoint(line.URL) == openai.EndpointEmbeddings {
+		exceeded := c.embeddingsInputs > c.limits.MaxEmbeddingsInputs
+		c.embeddingsInputs += embeddingsInputCount(line.Body["input"])
+		if !exceeded && c.embeddingsInputs > c.limits.MaxEmbeddingsInputs {
+			return &LineError{
+				Code:    LineErrorCodeTooManyInputs,
+				Message: fmt.Sprintf("the batch has more than the limit of %d embeddings inputs", c.limits.MaxEmbeddingsInputs),
+			}
+		}
+	}
+	return nil
+}
+
+// embeddingsInputCount returns the number of inputs of an embeddings request: a string or an array of tokens
+// is one input, and an array of strings or of token arrays is one input per element.
+func embeddingsInputCount(input interface{}) int {
+	inputs, ok := input.([]interface{})
+	if !ok {
+		return 1
+	}
+	if len(inputs) > 0 {
+		if _, isToken := inputs[0].(float64); isToken {
+			return 1
+		}
+	}
+	return len(inputs)
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the mapping of the batch priorities to the priorities of the job queue.
+package batch
+
+import (
+	"github.com/llm-d-incubation/batch-gateway/internal/database/api"
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+// QueuePriority maps the priority of a batch to the priority of its job in the queue.
+func QueuePriority(priority openai.BatchPriority) int {
+	switch priority {
+	case openai.BatchPriorityLow:
+		return api.PriorityLow
+	case openai.BatchPriorityHigh:
+		return api.PriorityHigh
+	default:
+		return api.PriorityNormal
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the DB tags of batch jobs.
+package batch
+
+// ActiveJobTag is the DB tag of the jobs that haven't reached a final status. It lets the processors find
+// the jobs left without a worker, and is removed once the job is final.
+const ActiveJobTag = "active"
+
+const inputFileTagPrefix = "input_file="
+
+// InputFileTag returns the DB tag of the jobs reading the input file, which must not be deleted while they are processed.
+func InputFileTag(fileID string) string {
+	return inputFileTagPrefix + fileID
+}
+
+// WithoutTag returns the tags without tag.
+func WithoutTag(tags []string, tag string) []string {
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t != tag {
+			result = append(result, t)
+		}
+	}
+	return result
+}
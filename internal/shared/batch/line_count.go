@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file counts the request lines of batch input files without parsing them,
+// for the checks that only need their number, such as the maximum number of requests of a batch.
+
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// countReaderSize is the buffer size of the line counter. Longer lines are accumulated across reads.
+const countReaderSize = 64 * 1024
+
+// LineCount is the count of the request lines of an input file.
+type LineCount struct {
+	// The number of non-blank lines, at most the limit of the count.
+	Lines int64
+
+	// The number of the counted lines that are not a JSON object.
+	Invalid int64
+
+	// Whether the file has more lines than the limit. The lines after the limit are not read.
+	ExceedsLimit bool
+}
+
+// CountLines counts the non-blank lines of the JSONL content, checking that each line is a JSON object
+// without decoding it, and stops reading when it finds more lines than limit. A limit of 0 counts all the lines.
+// A last line without a trailing newline is counted.
+func CountLines(r io.Reader, limit int64) (LineCount, error) {
+	return ScanLines(r, limit, nil)
+}
+
+// ScanLines counts the lines of the JSONL content like CountLines, and calls onLine, if set, with the 1-based
+// line number and the content of each counted line. The content is only valid until onLine returns.
+func ScanLines(r io.Reader, limit int64, onLine func(lineNum int64, data []byte)) (LineCount, error) {
+	var count LineCount
+	reader := bufio.NewReaderSize(r, countReaderSize)
+	var long []byte // the start of a line longer than the buffer
+	for lineNum := int64(1); ; lineNum++ {
+		data, err := reader.ReadSlice('\n')
+		for errors.Is(err, bufio.ErrBufferFull) {
+			long = append(long, data...)
+			data, err = reader.ReadSlice('\n')
+		}
+		if len(long) > 0 {
+			long = append(long, data...)
+			data = long
+		}
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			if limit > 0 && count.Lines == limit {
+				count.ExceedsLimit = true
+				return count, nil
+			}
+			count.Lines++
+			if data[0] != '{' || !json.Valid(data) {
+				count.Invalid++
+			}
+			if onLine != nil {
+				onLine(lineNum, data)
+			}
+		}
+		long = long[:0]
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests and benchmarks for the counting of the request lines of input files.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/shared/openai"
+)
+
+const countTestLine = `{"custom_id":"req-%d","method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[{"role":"user","content":"hi"}]}}`
+
+// countTestInput returns an input file of n request lines, each followed by a newline.
+func countTestInput(n int) []byte {
+	var buf bytes.Buffer
+	for i := range n {
+		fmt.Fprintf(&buf, countTestLine+"\n", i)
+	}
+	return buf.Bytes()
+}
+
+func TestCountLines(t *testing.T) {
+	line := fmt.Sprintf(countTestLine, 0)
+	long := `{"custom_id":"req-long","body":{"input":"` + strings.Repeat("x", 3*countReaderSize) + `"}}`
+
+	tests := []struct {
+		name    string
+		content string
+		limit   int64
+		want    LineCount
+	}{
+		{name: "empty", content: "", want: LineCount{}},
+		{name: "trailing newline", content: line + "\n" + line + "\n", want: LineCount{Lines: 2}},
+		{name: "no trailing newline", content: line + "\n" + line, want: LineCount{Lines: 2}},
+		{name: "single line without newline", content: line, want: LineCount{Lines: 1}},
+		{name: "blank lines", content: "\n" + line + "\n  \n\r\n" + line + "\n\n", want: LineCount{Lines: 2}},
+		{name: "crlf", content: line + "\r\n" + line + "\r\n", want: LineCount{Lines: 2}},
+		{name: "invalid lines", content: line + "\nnot json\n[1,2]\n" + `{"custom_id":` + "\n", want: LineCount{Lines: 4, Invalid: 3}},
+		{name: "line longer than the buffer", content: line + "\n" + long + "\n" + line, want: LineCount{Lines: 3}},
+		{name: "truncated long line", content: long[:len(long)-2], want: LineCount{Lines: 1, Invalid: 1}},
+		{name: "at the limit", content: line + "\n" + line + "\n", limit: 2, want: LineCount{Lines: 2}},
+		{name: "at the limit without newline", content: line + "\n" + line, limit: 2, want: LineCount{Lines: 2}},
+		{name: "trailing blank lines at the limit", content: line + "\n" + line + "\n\n \n", limit: 2, want: LineCount{Lines: 2}},
+		{name: "over the limit", content: line + "\n" + line + "\n" + line, limit: 2, want: LineCount{Lines: 2, ExceedsLimit: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CountLines(strings.NewReader(tt.content), tt.limit)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected count %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("stops reading at the limit", func(t *testing.T) {
+		input := bytes.NewReader(countTestInput(10000))
+		got, err := CountLines(input, 10)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !got.ExceedsLimit || got.Lines != 10 {
+			t.Errorf("Expected 10 lines over the limit, got %+v", got)
+		}
+		if input.Len() == 0 {
+			t.Error("Expected the file not to be read to its end")
+		}
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		content := countTestInput(100)
+		reader, err := NewInputReader(bytes.NewReader(gzipData(t, string(content[:len(content)-1]))))
+		if err != nil {
+			t.Fatalf("Failed to open the input: %v", err)
+		}
+		got, err := CountLines(reader, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got != (LineCount{Lines: 100}) {
+			t.Errorf("Expected 100 lines, got %+v", got)
+		}
+	})
+}
+
+func TestScanLines(t *testing.T) {
+	line := fmt.Sprintf(countTestLine, 0)
+	long := `{"custom_id":"req-long","body":{"input":"` + strings.Repeat("x", 3*countReaderSize) + `"}}`
+	content := "\n" + line + "\n" + long + "\n\n" + line
+
+	var lineNums []int64
+	var lengths []int
+	got, err := ScanLines(strings.NewReader(content), 0, func(lineNum int64, data []byte) {
+		lineNums = append(lineNums, lineNum)
+		lengths = append(lengths, len(data))
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != (LineCount{Lines: 3}) {
+		t.Errorf("Expected 3 lines, got %+v", got)
+	}
+	if fmt.Sprint(lineNums) != "[2 3 5]" {
+		t.Errorf("Expected the lines 2, 3 and 5, got %v", lineNums)
+	}
+	if fmt.Sprint(lengths) != fmt.Sprint([]int{len(line), len(long), len(line)}) {
+		t.Errorf("Expected the content of the lines, got lengths %v", lengths)
+	}
+}
+
+// BenchmarkCountLines compares the line counter with parsing all the lines of an input file of the maximum size.
+func BenchmarkCountLines(b *testing.B) {
+	content := countTestInput(50000)
+
+	b.Run("CountLines", func(b *testing.B) {
+		b.SetBytes(int64(len(content)))
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := CountLines(bytes.NewReader(content), 50000); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("ParseAll", func(b *testing.B) {
+		b.SetBytes(int64(len(content)))
+		b.ReportAllocs()
+		for b.Loop() {
+			var lines []*RequestLine
+			input := bufio.NewReader(bytes.NewReader(content))
+			for {
+				data, err := input.ReadBytes('\n')
+				if data = bytes.TrimSpace(data); len(data) > 0 {
+					line, _ := ParseRequestLine(data, openai.EndpointChatCompletions, false, openai.ValidationLenient)
+					lines = append(lines, line)
+				}
+				if err != nil {
+					break
+				}
+			}
+			if len(lines) != 50000 {
+				b.Fatalf("Expected 50000 lines, got %d", len(lines))
+			}
+		}
+	})
+}
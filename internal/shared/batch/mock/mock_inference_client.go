@@ -0,0 +1,150 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides a deterministic mock implementation of the inference Client.
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/llm-d-incubation/batch-gateway/internal/inference"
+)
+
+// MockInferenceClient is an inference client with configurable behavior.
+// By default it echoes a response with the ID "resp-<request ID>" for every request.
+type MockInferenceClient struct {
+	mu        sync.Mutex
+	response  []byte
+	chunks    [][]byte
+	errCat    inference.ErrorCategory
+	failFirst int
+	latency   time.Duration
+	requests  []*inference.GenerateRequest
+}
+
+func NewMockInferenceClient() *MockInferenceClient {
+	return &MockInferenceClient{}
+}
+
+// WithResponse makes the client return the canned response body for every successful request.
+func (m *MockInferenceClient) WithResponse(response []byte) *MockInferenceClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.response = response
+	return m
+}
+
+// WithStreamChunks makes GenerateStream stream the chunks for every successful request.
+// Without chunks, GenerateStream answers like a gateway that doesn't stream, with the response of Generate.
+func (m *MockInferenceClient) WithStreamChunks(chunks ...[]byte) *MockInferenceClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chunks = chunks
+	return m
+}
+
+// WithError makes every request fail with an error of the category.
+func (m *MockInferenceClient) WithError(category inference.ErrorCategory) *MockInferenceClient {
+	return m.FailFirst(-1, category)
+}
+
+// FailFirst makes the first n requests fail with an error of the category; later requests succeed.
+// A negative n fails all requests.
+func (m *MockInferenceClient) FailFirst(n int, category inference.ErrorCategory) *MockInferenceClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failFirst = n
+	m.errCat = category
+	return m
+}
+
+// WithLatency delays every request by d, or until its context is done.
+func (m *MockInferenceClient) WithLatency(d time.Duration) *MockInferenceClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latency = d
+	return m
+}
+
+// Calls returns the number of requests made.
+func (m *MockInferenceClient) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.requests)
+}
+
+// Requests returns the requests made, in call order.
+func (m *MockInferenceClient) Requests() []*inference.GenerateRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*inference.GenerateRequest(nil), m.requests...)
+}
+
+func (m *MockInferenceClient) Generate(ctx context.Context, req *inference.GenerateRequest) (*inference.GenerateResponse, *inference.ClientError) {
+	m.mu.Lock()
+	m.requests = append(m.requests, req)
+	call := len(m.requests)
+	fail := m.failFirst < 0 || call <= m.failFirst
+	category, response, latency := m.errCat, m.response, m.latency
+	m.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			// like the HTTP client, a deadline is a retryable server error and a cancellation is not
+			category := inference.ErrCategoryUnknown
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				category = inference.ErrCategoryServer
+			}
+			return nil, &inference.ClientError{Category: category, Message: "request cancelled", RawError: ctx.Err()}
+		}
+	}
+
+	if fail {
+		return nil, &inference.ClientError{
+			Category: category,
+			Message:  fmt.Sprintf("mock %s error for call %d", category, call),
+		}
+	}
+	if response == nil {
+		response = []byte(fmt.Sprintf(`{"id":"resp-%s"}`, req.RequestID))
+	}
+	return &inference.GenerateResponse{RequestID: req.RequestID, Response: response}, nil
+}
+
+func (m *MockInferenceClient) GenerateStream(ctx context.Context, req *inference.GenerateRequest, onChunk func(chunk []byte) error) (*inference.GenerateResponse, *inference.ClientError) {
+	resp, clientErr := m.Generate(ctx, req)
+	m.mu.Lock()
+	chunks := m.chunks
+	m.mu.Unlock()
+	if clientErr != nil || len(chunks) == 0 {
+		return resp, clientErr
+	}
+
+	for _, chunk := range chunks {
+		if err := onChunk(chunk); err != nil {
+			return nil, &inference.ClientError{Category: inference.ErrCategoryUnknown, Message: err.Error(), RawError: err}
+		}
+	}
+	return nil, nil
+}
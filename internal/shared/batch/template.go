@@ -0,0 +1,104 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file implements the expansion of templated request bodies.
+//
+// A template is a request body whose string values may contain {{name}} placeholders, where name
+// is a variable of the line's vars object (letters, digits and underscores, not starting with a digit;
+// spaces inside the braces are ignored). A string value that is exactly one placeholder is replaced by
+// the variable with its JSON type, so numbers, booleans, arrays and objects can be substituted.
+// A placeholder within a longer string is replaced by the variable's text: strings are inserted as-is,
+// other values as JSON. Object keys are not expanded.
+//
+//	{"custom_id":"q-1","method":"POST","url":"/v1/chat/completions",
+//	 "template":{"model":"m","max_tokens":"{{max_tokens}}","messages":[{"role":"user","content":"Translate {{word}} to French"}]},
+//	 "vars":{"word":"cat","max_tokens":16}}
+
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var placeholderRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// expandTemplate returns a copy of the template with its placeholders replaced by the vars.
+// It fails if a placeholder refers to a variable that is not defined.
+func expandTemplate(template map[string]interface{}, vars map[string]interface{}) (map[string]interface{}, error) {
+	missing := map[string]struct{}{}
+	body, _ := expandValue(template, vars, missing).(map[string]interface{})
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, "{{"+name+"}}")
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("template has unresolved placeholders: %s", strings.Join(names, ", "))
+	}
+	return body, nil
+}
+
+func expandValue(value interface{}, vars map[string]interface{}, missing map[string]struct{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		expanded := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			expanded[key] = expandValue(elem, vars, missing)
+		}
+		return expanded
+	case []interface{}:
+		expanded := make([]interface{}, len(v))
+		for i, elem := range v {
+			expanded[i] = expandValue(elem, vars, missing)
+		}
+		return expanded
+	case string:
+		return expandString(v, vars, missing)
+	default:
+		return v
+	}
+}
+
+func expandString(s string, vars map[string]interface{}, missing map[string]struct{}) interface{} {
+	// a string that is a single placeholder takes the type of its variable
+	if match := placeholderRegexp.FindStringSubmatchIndex(s); match != nil && match[0] == 0 && match[1] == len(s) {
+		name := s[match[2]:match[3]]
+		value, ok := vars[name]
+		if !ok {
+			missing[name] = struct{}{}
+			return s
+		}
+		return value
+	}
+
+	return placeholderRegexp.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := placeholderRegexp.FindStringSubmatch(placeholder)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing[name] = struct{}{}
+			return placeholder
+		}
+		if str, ok := value.(string); ok {
+			return str
+		}
+		text, _ := json.Marshal(value)
+		return string(text)
+	})
+}
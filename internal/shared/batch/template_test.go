@@ -0,0 +1,108 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the expansion of templated request lines.
+
+package batch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantBody string
+		wantErr  string
+	}{
+		{
+			name:     "no template",
+			line:     `{"custom_id":"1","body":{"model":"m","prompt":"{{word}}"}}`,
+			wantBody: `{"model":"m","prompt":"{{word}}"}`,
+		},
+		{
+			name:     "string substitution",
+			line:     `{"custom_id":"1","template":{"model":"m","messages":[{"role":"user","content":"Translate {{word}} to {{ lang }}"}]},"vars":{"word":"cat","lang":"French"}}`,
+			wantBody: `{"model":"m","messages":[{"role":"user","content":"Translate cat to French"}]}`,
+		},
+		{
+			name:     "typed substitution",
+			line:     `{"custom_id":"1","template":{"model":"{{model}}","max_tokens":"{{n}}","stop":"{{stop}}","prompt":"n={{n}}"},"vars":{"model":"m","n":16,"stop":["\n"]}}`,
+			wantBody: `{"model":"m","max_tokens":16,"stop":["\n"],"prompt":"n=16"}`,
+		},
+		{
+			name:     "unused vars",
+			line:     `{"custom_id":"1","template":{"model":"m"},"vars":{"unused":1}}`,
+			wantBody: `{"model":"m"}`,
+		},
+		{
+			name:    "missing variable",
+			line:    `{"custom_id":"1","template":{"model":"m","prompt":"{{word}} and {{other}}"},"vars":{"word":"cat"}}`,
+			wantErr: "template has unresolved placeholders: {{other}}",
+		},
+		{
+			name:    "missing vars",
+			line:    `{"custom_id":"1","template":{"model":"{{model}}"}}`,
+			wantErr: "template has unresolved placeholders: {{model}}",
+		},
+		{
+			name:    "body and template",
+			line:    `{"custom_id":"1","body":{"model":"m"},"template":{"model":"m"}}`,
+			wantErr: "body and template are mutually exclusive",
+		},
+		{
+			name:    "vars without template",
+			line:    `{"custom_id":"1","body":{"model":"m"},"vars":{"a":1}}`,
+			wantErr: "vars requires a template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := RequestLine{}
+			if err := json.Unmarshal([]byte(tt.line), &line); err != nil {
+				t.Fatalf("failed to parse line: %v", err)
+			}
+
+			err := line.ExpandTemplate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var want map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.wantBody), &want); err != nil {
+				t.Fatalf("invalid expected body: %v", err)
+			}
+			got, _ := json.Marshal(line.Body)
+			wantJSON, _ := json.Marshal(want)
+			if string(got) != string(wantJSON) {
+				t.Errorf("expected body %s, got %s", wantJSON, got)
+			}
+			if line.Template != nil || line.Vars != nil {
+				t.Errorf("expected template and vars to be cleared, got %v and %v", line.Template, line.Vars)
+			}
+		})
+	}
+}
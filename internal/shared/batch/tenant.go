@@ -0,0 +1,42 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides helpers for associating batch objects with tenants.
+package batch
+
+import (
+	"strings"
+)
+
+// DefaultTenantID is the tenant of objects that are not associated with any tenant.
+const DefaultTenantID = "default"
+
+const tenantTagPrefix = "tenant="
+
+// TenantTag returns the DB tag used to associate an object with a tenant.
+func TenantTag(tenantID string) string {
+	return tenantTagPrefix + tenantID
+}
+
+// GetTenantIDFromTags returns the tenant of an object from its DB tags, or DefaultTenantID if there is none.
+func GetTenantIDFromTags(tags []string) string {
+	for _, tag := range tags {
+		if tenantID, ok := strings.CutPrefix(tag, tenantTagPrefix); ok && tenantID != "" {
+			return tenantID
+		}
+	}
+	return DefaultTenantID
+}
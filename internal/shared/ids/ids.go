@@ -0,0 +1,60 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file provides the generation of the IDs of batches and files.
+// IDs are a prefix, like OpenAI's "batch_" and "file-", followed by the lowercase base32 encoding of
+// 128 random bits, which makes them URL-safe and collisions practically impossible.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultBatchIDPrefix = "batch_"
+	DefaultFileIDPrefix  = "file-"
+
+	// MaxPrefixLength is the maximum length of an ID prefix
+	MaxPrefixLength = 32
+
+	// randomBytes is the number of random bytes of an ID
+	randomBytes = 16
+)
+
+var encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// New returns a new ID with the prefix.
+func New(prefix string) string {
+	b := make([]byte, randomBytes)
+	rand.Read(b) // never returns an error
+	return prefix + encoding.EncodeToString(b)
+}
+
+// ValidatePrefix checks that an ID prefix is URL-safe: it may only contain letters, digits, '-' and '_'.
+func ValidatePrefix(prefix string) error {
+	if len(prefix) > MaxPrefixLength {
+		return fmt.Errorf("prefix %q is longer than %d characters", prefix, MaxPrefixLength)
+	}
+	if i := strings.IndexFunc(prefix, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_')
+	}); i >= 0 {
+		return fmt.Errorf("prefix %q contains the invalid character %q", prefix, prefix[i])
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ids
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	const n = 100000
+
+	for _, prefix := range []string{DefaultBatchIDPrefix, DefaultFileIDPrefix, ""} {
+		t.Run("prefix "+prefix, func(t *testing.T) {
+			seen := make(map[string]struct{}, n)
+			for i := 0; i < n; i++ {
+				id := New(prefix)
+				if !strings.HasPrefix(id, prefix) {
+					t.Fatalf("expected ID %q to have the prefix %q", id, prefix)
+				}
+				if len(id) != len(prefix)+26 {
+					t.Fatalf("expected ID %q to have %d random characters", id, 26)
+				}
+				if url.PathEscape(id) != id {
+					t.Fatalf("expected ID %q to be URL-safe", id)
+				}
+				if _, ok := seen[id]; ok {
+					t.Fatalf("duplicate ID %q after %d generations", id, i)
+				}
+				seen[id] = struct{}{}
+			}
+		})
+	}
+}
+
+func TestValidatePrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		wantErr bool
+	}{
+		{prefix: ""},
+		{prefix: "batch_"},
+		{prefix: "file-"},
+		{prefix: "Tenant-A_batch-"},
+		{prefix: "batch/", wantErr: true},
+		{prefix: "batch ", wantErr: true},
+		{prefix: "batch?", wantErr: true},
+		{prefix: strings.Repeat("a", MaxPrefixLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidatePrefix(tt.prefix); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePrefix(%q) returned %v, want error: %v", tt.prefix, err, tt.wantErr)
+		}
+	}
+}
@@ -19,6 +19,7 @@ package openai
 
 import (
 	"net/http"
+	"strings"
 )
 
 // APIError represents an error that originates from the API
@@ -27,6 +28,44 @@ type APIError struct {
 	Type    string  `json:"type"`
 	Message string  `json:"message"`
 	Param   *string `json:"param"`
+
+	// Extension: every invalid field of a request failing validation. Param is the first of them.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a validation problem of a request field.
+type FieldError struct {
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects the validation problems of all the fields of a request.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Add records a validation problem of the param field.
+func (e *ValidationError) Add(param, message string) {
+	e.Errors = append(e.Errors, FieldError{Param: param, Message: message})
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// APIError returns the error response of the validation problems, with the given status code.
+func (e *ValidationError) APIError(code int) APIError {
+	var param *string
+	if len(e.Errors) > 0 {
+		param = &e.Errors[0].Param
+	}
+	apiErr := NewAPIError(code, "", e.Error(), param)
+	apiErr.Errors = e.Errors
+	return apiErr
 }
 
 func NewAPIError(code int, errorType string, message string, param *string) APIError {
@@ -58,6 +97,8 @@ func ErrorCodeToType(code int) string {
 		errorType = "PermissionDeniedError"
 	case http.StatusNotFound:
 		errorType = "NotFoundError"
+	case http.StatusConflict:
+		errorType = "ConflictError"
 	case http.StatusUnprocessableEntity:
 		errorType = "UnprocessableEntityError"
 	case http.StatusTooManyRequests:
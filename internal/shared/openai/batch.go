@@ -18,8 +18,12 @@ limitations under the License.
 package openai
 
 import (
-	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // https://platform.openai.com/docs/api-reference/batch
@@ -36,10 +40,62 @@ const (
 	EndpointModerations     Endpoint = "/v1/moderations"
 )
 
+// SupportedEndpoints lists the endpoints accepted for batches.
+var SupportedEndpoints = []Endpoint{
+	EndpointResponses,
+	EndpointChatCompletions,
+	EndpointEmbeddings,
+	EndpointCompletions,
+	EndpointModerations,
+}
+
 func (e Endpoint) String() string {
 	return string(e)
 }
 
+// IsValid reports whether the endpoint is one of the supported endpoints.
+func (e Endpoint) IsValid() bool {
+	for _, supported := range SupportedEndpoints {
+		if e == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchPriority is the extension that orders queued batches; higher priority batches are dequeued first.
+type BatchPriority string
+
+const (
+	BatchPriorityLow    BatchPriority = "low"
+	BatchPriorityNormal BatchPriority = "normal"
+	BatchPriorityHigh   BatchPriority = "high"
+)
+
+// IsValid reports whether p is a known priority.
+func (p BatchPriority) IsValid() bool {
+	switch p {
+	case BatchPriorityLow, BatchPriorityNormal, BatchPriorityHigh:
+		return true
+	}
+	return false
+}
+
+// ValidationStrictness is how strictly the request lines of the input file of a batch are validated.
+type ValidationStrictness string
+
+const (
+	// ValidationLenient only checks the keys required to route a line, the backend validates the request body.
+	ValidationLenient ValidationStrictness = "lenient"
+	// ValidationStrict also rejects unknown keys of the line and bodies missing the fields their endpoint requires.
+	ValidationStrict ValidationStrictness = "strict"
+)
+
+// IsValid reports whether s is a known strictness.
+func (s ValidationStrictness) IsValid() bool {
+	return s == ValidationLenient || s == ValidationStrict
+}
+
 type BatchStatus string
 
 const (
@@ -79,6 +135,21 @@ type BatchSpec struct {
 
 	// required. The Unix timestamp (in seconds) for when the batch was created.
 	CreatedAt int64 `json:"created_at"`
+
+	// optional. Extension: whether the url of each request line selects its endpoint, in which case Endpoint is advisory.
+	MixedEndpoints bool `json:"mixed_endpoints,omitempty"`
+
+	// optional. Extension: the priority of the batch in the queue.
+	Priority BatchPriority `json:"priority,omitempty"`
+
+	// optional. Extension: the URL the batch is posted to when it reaches a final status.
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// optional. The expiration policy of the output and error files of the batch.
+	OutputExpiresAfter *OutputExpiresAfter `json:"output_expires_after,omitempty"`
+
+	// optional. Extension: the strictness of the validation of the request lines, lenient when not set.
+	ValidationStrictness ValidationStrictness `json:"validation_strictness,omitempty"`
 }
 
 type BatchStatusInfo struct {
@@ -109,6 +180,10 @@ type BatchStatusInfo struct {
 	// optional. The Unix timestamp (in seconds) for when the batch failed.
 	FailedAt *int64 `json:"failed_at,omitempty"`
 
+	// optional. Extension: the Unix timestamp (in seconds) for when the failed batch was copied to the dead-letter
+	// table for inspection.
+	DeadLetteredAt *int64 `json:"dead_lettered_at,omitempty"`
+
 	// optional. The Unix timestamp (in seconds) for when the batch started finalizing.
 	FinalizingAt *int64 `json:"finalizing_at,omitempty"`
 
@@ -136,22 +211,7 @@ type Batch struct {
 	BatchStatusInfo
 }
 
-type ListBatchResponse struct {
-	// required. The type of object returned, must be `list`.
-	Object string `json:"object"`
-
-	// required. A list of items used to generate this response.
-	Data []Batch `json:"data"`
-
-	// required. The ID of the first item in the list.
-	FirstID string `json:"first_id"`
-
-	// required. The ID of the last item in the list.
-	LastID string `json:"last_id"`
-
-	// required. Whether there are more items available.
-	HasMore bool `json:"has_more"`
-}
+type ListBatchResponse = ListResponse[Batch]
 
 type BatchError struct {
 
@@ -175,6 +235,23 @@ type BatchErrors struct {
 
 	// optional.
 	Data []BatchError `json:"data"`
+
+	// optional. Extension: the total number of errors, when Data was truncated.
+	Total int `json:"total,omitempty"`
+
+	// optional. Extension: whether Data was truncated to bound the response size.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Truncate caps the errors in Data at maxErrors, keeping the total number of errors in Total.
+// A non-positive maxErrors leaves the errors unchanged.
+func (e *BatchErrors) Truncate(maxErrors int) {
+	if maxErrors <= 0 || len(e.Data) <= maxErrors {
+		return
+	}
+	e.Total = max(e.Total, len(e.Data))
+	e.Data = e.Data[:maxErrors]
+	e.Truncated = true
 }
 
 // BatchRequestCounts - The request counts for different statuses within the batch.
@@ -190,6 +267,23 @@ type BatchRequestCounts struct {
 	Failed int64 `json:"failed"`
 }
 
+// BatchValidation - Extension: the result of validating the input file of a batch without creating the batch.
+type BatchValidation struct {
+
+	// required. The object type, which is always `batch.validation`.
+	Object string `json:"object"`
+
+	// required. Whether all the lines of the input file are valid.
+	Valid bool `json:"valid"`
+
+	// required. The request counts the batch would have; Total is the number of requests in the input file,
+	// and Failed the number of invalid requests.
+	RequestCounts BatchRequestCounts `json:"request_counts"`
+
+	// optional. The errors of the invalid lines.
+	Errors *BatchErrors `json:"errors,omitempty"`
+}
+
 type BatchUsage struct {
 	// required. The number of input tokens.
 	InputTokens int64 `json:"input_tokens"`
@@ -207,6 +301,15 @@ type BatchUsage struct {
 	TotalTokens int64 `json:"total_tokens"`
 }
 
+// Add adds the token counts of other to the usage.
+func (u *BatchUsage) Add(other BatchUsage) {
+	u.InputTokens += other.InputTokens
+	u.InputTokensDetails.CachedTokens += other.InputTokensDetails.CachedTokens
+	u.OutputTokens += other.OutputTokens
+	u.OutputTokensDetails.ReasoningTokens += other.OutputTokensDetails.ReasoningTokens
+	u.TotalTokens += other.TotalTokens
+}
+
 type BatchUsageInputTokensDetails struct {
 	// required. The number of tokens that were retrieved from the cache.
 	// [More on prompt caching](https://platform.openai.com/docs/guides/prompt-caching).
@@ -234,8 +337,24 @@ type CreateBatchRequest struct {
 
 	// optional. The expiration policy for the output and/or error file that are generated for a batch.
 	OutputExpiresAfter *OutputExpiresAfter `json:"output_expires_after"`
+
+	// optional. Extension: when true, each request line is sent to the supported endpoint given by its url,
+	// and the endpoint of the batch is advisory. By default all the lines must match the endpoint of the batch.
+	MixedEndpoints bool `json:"mixed_endpoints,omitempty"`
+
+	// optional. Extension: the priority of the batch, one of `low`, `normal` and `high`. Higher priority batches
+	// are dequeued before lower priority ones, and batches of equal priority are dequeued by deadline.
+	// Defaults to `normal`.
+	Priority BatchPriority `json:"priority,omitempty"`
+
+	// optional. Extension: an http or https URL the Batch object is posted to when the batch reaches a final status.
+	// The body is signed in the X-Batch-Signature header when the processor has a callback signing secret.
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
+// OutputExpiresAfterAnchorCreatedAt anchors the expiration of a file at its creation time.
+const OutputExpiresAfterAnchorCreatedAt = "created_at"
+
 type OutputExpiresAfter struct {
 	// required. The number of seconds after the anchor time that the file will expire. Must be
 	// between 3600 (1 hour) and 2592000 (30 days).
@@ -248,43 +367,86 @@ type OutputExpiresAfter struct {
 	Anchor string `json:"anchor"`
 }
 
+// Metadata limits of the OpenAI specification.
+const (
+	MaxMetadataPairs       = 16
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 512
+)
+
+// NormalizeCompletionWindow returns the canonical form of a completion window, e.g. "24h" for " 24H ".
+func NormalizeCompletionWindow(window string) string {
+	return strings.ToLower(strings.TrimSpace(window))
+}
+
+// Validate checks the request, and returns a *ValidationError listing every invalid field.
+// The completion window may be given in any case and with surrounding whitespace, see NormalizeCompletionWindow.
 func (r *CreateBatchRequest) Validate() error {
-	if r.CompletionWindow == "" {
-		return errors.New("completion_window is required")
-	}
+	verr := &ValidationError{}
 
-	if _, err := time.ParseDuration(r.CompletionWindow); err != nil {
-		return errors.New("completion_window must be a valid duration (e.g., 24h)")
+	if completionWindow := NormalizeCompletionWindow(r.CompletionWindow); completionWindow == "" {
+		verr.Add("completion_window", "completion_window is required")
+	} else if d, err := time.ParseDuration(completionWindow); err != nil || d <= 0 {
+		verr.Add("completion_window", "completion_window must be a valid duration (e.g., 24h)")
 	}
 
 	if r.Endpoint == "" {
-		return errors.New("endpoint is required")
+		verr.Add("endpoint", "endpoint is required")
+	} else if !r.Endpoint.IsValid() {
+		verr.Add("endpoint", "invalid endpoint: "+string(r.Endpoint))
 	}
 
-	validEndpoints := map[Endpoint]bool{
-		EndpointResponses:       true,
-		EndpointChatCompletions: true,
-		EndpointEmbeddings:      true,
-		EndpointCompletions:     true,
-		EndpointModerations:     true,
+	if r.InputFileID == "" {
+		verr.Add("input_file_id", "input_file_id is required")
 	}
-	if !validEndpoints[r.Endpoint] {
-		return errors.New("invalid endpoint: " + string(r.Endpoint))
+
+	validateMetadata(verr, r.Metadata)
+
+	if r.Priority != "" && !r.Priority.IsValid() {
+		verr.Add("priority", "priority must be one of: low, normal, high")
 	}
 
-	if r.InputFileID == "" {
-		return errors.New("input_file_id is required")
+	if r.CallbackURL != "" {
+		if u, err := url.Parse(r.CallbackURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			verr.Add("callback_url", "callback_url must be an absolute http or https URL")
+		}
 	}
 
 	if r.OutputExpiresAfter != nil {
 		if r.OutputExpiresAfter.Anchor == "" {
-			return errors.New("output_expires_after.anchor is required")
+			verr.Add("output_expires_after.anchor", "output_expires_after.anchor is required")
+		} else if r.OutputExpiresAfter.Anchor != OutputExpiresAfterAnchorCreatedAt {
+			verr.Add("output_expires_after.anchor", "output_expires_after.anchor must be "+OutputExpiresAfterAnchorCreatedAt)
 		}
 
 		if r.OutputExpiresAfter.Seconds < 3600 || r.OutputExpiresAfter.Seconds > 2592000 {
-			return errors.New("output_expires_after.seconds must be between 3600 (1 hour) and 2592000 (30 days)")
+			verr.Add("output_expires_after.seconds", "output_expires_after.seconds must be between 3600 (1 hour) and 2592000 (30 days)")
 		}
 	}
 
+	if len(verr.Errors) > 0 {
+		return verr
+	}
 	return nil
 }
+
+// validateMetadata adds an error to verr for each metadata limit exceeded, naming the offending keys.
+func validateMetadata(verr *ValidationError, metadata map[string]string) {
+	if len(metadata) > MaxMetadataPairs {
+		verr.Add("metadata", fmt.Sprintf("metadata must have at most %d key-value pairs, got %d", MaxMetadataPairs, len(metadata)))
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if utf8.RuneCountInString(key) > MaxMetadataKeyLength {
+			verr.Add("metadata", fmt.Sprintf("metadata key %q must be at most %d characters", key, MaxMetadataKeyLength))
+		}
+		if utf8.RuneCountInString(metadata[key]) > MaxMetadataValueLength {
+			verr.Add("metadata", fmt.Sprintf("metadata value of key %q must be at most %d characters", key, MaxMetadataValueLength))
+		}
+	}
+}
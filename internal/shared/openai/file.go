@@ -35,6 +35,18 @@ const (
 	FileObjectPurposeUserData         FileObjectPurpose = "user_data"
 )
 
+// FileObjectPurposes lists all known file purposes.
+var FileObjectPurposes = []FileObjectPurpose{
+	FileObjectPurposeAssistants,
+	FileObjectPurposeAssistantsOutput,
+	FileObjectPurposeBatch,
+	FileObjectPurposeBatchOutput,
+	FileObjectPurposeFineTune,
+	FileObjectPurposeFineTuneResults,
+	FileObjectPurposeVision,
+	FileObjectPurposeUserData,
+}
+
 // Deprecated. The current status of the file, which can be either `uploaded`,
 // `processed`, or `error`.
 type FileObjectStatus string
@@ -54,6 +66,9 @@ type FileObject struct {
 	// required. The size of the file, in bytes.
 	Bytes int32 `json:"bytes"`
 
+	// The size of the content of a gzip compressed file once decompressed, in bytes. Bytes is then the compressed size.
+	UncompressedBytes int64 `json:"uncompressed_bytes,omitempty"`
+
 	// required. The Unix timestamp (in seconds) for when the file was created.
 	CreatedAt int32 `json:"created_at"`
 
@@ -75,3 +90,26 @@ type FileObject struct {
 	// Deprecated. For details on why a fine-tuning training file failed validation, see the `error` field on `fine_tuning.job`.
 	StatusDetails string `json:"status_details,omitempty"`
 }
+
+// IsValid reports whether the purpose is one of the known file purposes.
+func (p FileObjectPurpose) IsValid() bool {
+	for _, purpose := range FileObjectPurposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+type ListFilesResponse = ListResponse[FileObject]
+
+type DeleteFileResponse struct {
+	// required. The ID of the deleted file.
+	ID string `json:"id"`
+
+	// required. The object type, which is always `file`.
+	Object string `json:"object"`
+
+	// required. Whether the file was deleted.
+	Deleted bool `json:"deleted"`
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file defines the envelope of paginated list responses matching the OpenAI specification.
+package openai
+
+type ListResponse[T any] struct {
+	// required. The type of object returned, must be `list`.
+	Object string `json:"object"`
+
+	// required. A list of items used to generate this response.
+	Data []T `json:"data"`
+
+	// required. The ID of the first item in the list.
+	FirstID string `json:"first_id"`
+
+	// required. The ID of the last item in the list.
+	LastID string `json:"last_id"`
+
+	// required. Whether there are more items available.
+	HasMore bool `json:"has_more"`
+}
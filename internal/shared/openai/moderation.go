@@ -0,0 +1,47 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file defines the Moderation API data structures matching the OpenAI specification.
+package openai
+
+// https://platform.openai.com/docs/api-reference/moderations
+
+// ModerationResponse represents if a given text input is potentially harmful.
+type ModerationResponse struct {
+	// required. The unique identifier for the moderation request.
+	ID string `json:"id"`
+
+	// required. The model used to generate the moderation results.
+	Model string `json:"model"`
+
+	// required. A list of moderation objects, one per input.
+	Results []ModerationResult `json:"results"`
+}
+
+// ModerationResult is the moderation of one input.
+type ModerationResult struct {
+	// required. Whether any of the categories are flagged.
+	Flagged bool `json:"flagged"`
+
+	// required. A list of the categories, and whether they are flagged or not.
+	Categories map[string]bool `json:"categories"`
+
+	// required. A list of the categories along with their scores as predicted by model.
+	CategoryScores map[string]float64 `json:"category_scores"`
+
+	// A list of the categories along with the input type(s) that the score applies to.
+	CategoryAppliedInputTypes map[string][]string `json:"category_applied_input_types,omitempty"`
+}
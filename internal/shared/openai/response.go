@@ -0,0 +1,46 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file defines the Responses API data structures matching the OpenAI specification.
+package openai
+
+import "encoding/json"
+
+// https://platform.openai.com/docs/api-reference/responses/object
+
+// ResponseObject is the object type of a Response.
+const ResponseObject = "response"
+
+// Response is the response of the Responses API. Only the fields used by the batch processing are decoded.
+type Response struct {
+	// required. Unique identifier for this Response.
+	ID string `json:"id"`
+
+	// required. The object type of this resource, always `response`.
+	Object string `json:"object"`
+
+	// required. The status of the response generation: `completed`, `failed`, `in_progress`, `cancelled`, `queued` or `incomplete`.
+	Status string `json:"status"`
+
+	// required. The model used to generate the response.
+	Model string `json:"model"`
+
+	// required. An array of content items generated by the model.
+	Output []json.RawMessage `json:"output"`
+
+	// optional. The token usage details. The batch usage aggregates them.
+	Usage *BatchUsage `json:"usage,omitempty"`
+}
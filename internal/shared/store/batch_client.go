@@ -23,15 +23,27 @@ import (
 	"time"
 )
 
+// DefaultTimeLimit is the time limit of the contexts returned by GetContext when no time limit is set.
+const DefaultTimeLimit = 30 * time.Second
+
 // -- Admin interfaces --
 
 // BatchClientAdmin specifies administrative interface functions.
 type BatchClientAdmin interface {
 
 	// GetContext returns a derived context for a call.
-	// If no time limit is set, the context will be set with a default time limit.
+	// If no time limit is set (timeLimit <= 0), the context will be set with DefaultTimeLimit.
 	GetContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc)
 
 	// Close closes the client.
 	Close() error
 }
+
+// CallContext returns a context derived from parentCtx for a call, limited to timeLimit,
+// or to DefaultTimeLimit if timeLimit is not positive. It implements GetContext for the clients.
+func CallContext(parentCtx context.Context, timeLimit time.Duration) (context.Context, context.CancelFunc) {
+	if timeLimit <= 0 {
+		timeLimit = DefaultTimeLimit
+	}
+	return context.WithTimeout(parentCtx, timeLimit)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the shared code of the storage clients.
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCallContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeLimit time.Duration
+		want      time.Duration
+	}{
+		{name: "time limit", timeLimit: time.Second, want: time.Second},
+		{name: "zero time limit", timeLimit: 0, want: DefaultTimeLimit},
+		{name: "negative time limit", timeLimit: -time.Second, want: DefaultTimeLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			ctx, cancel := CallContext(context.Background(), tt.timeLimit)
+			defer cancel()
+			end := time.Now()
+
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatalf("Expected the context to have a deadline")
+			}
+			if deadline.Before(start.Add(tt.want)) || deadline.After(end.Add(tt.want)) {
+				t.Errorf("Expected a deadline in %s, got %s", tt.want, deadline.Sub(start))
+			}
+			if ctx.Err() != nil {
+				t.Errorf("Expected the context not to be done, got %v", ctx.Err())
+			}
+		})
+	}
+}
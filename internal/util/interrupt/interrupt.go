@@ -7,14 +7,24 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/llm-d-incubation/batch-gateway/internal/util/logging"
 	"k8s.io/klog/v2"
 )
 
+// exit terminates the process. It is replaced in tests.
+var exit = os.Exit
+
 // ContextWithSignal monitors os signal, and return the context that cancels further works.
 // Immediately exit on the second signal.
 func ContextWithSignal(parent context.Context) (context.Context, context.CancelFunc) {
+	return ContextWithSignalTimeout(parent, 0)
+}
+
+// ContextWithSignalTimeout is like ContextWithSignal, but also exits when the graceful shutdown
+// doesn't complete within grace after the first signal. Zero grace waits for the second signal.
+func ContextWithSignalTimeout(parent context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(parent)
 	logger := klog.FromContext(ctx)
 
@@ -23,13 +33,24 @@ func ContextWithSignal(parent context.Context) (context.Context, context.CancelF
 
 	go func() {
 		sig := <-signalChan
-		logger.V(logging.INFO).Info("Received shutdown signal, starting graceful shutdown...", "signal", sig)
+		logger.V(logging.INFO).Info("Received shutdown signal, starting graceful shutdown...", "signal", sig, "grace", grace)
 		cancel()
 
-		sig = <-signalChan
-		logger.V(logging.INFO).Info("Received second shutdown signal, forcing shutdown...", "signal", sig)
+		var graceExpired <-chan time.Time
+		if grace > 0 {
+			timer := time.NewTimer(grace)
+			defer timer.Stop()
+			graceExpired = timer.C
+		}
+
+		select {
+		case sig = <-signalChan:
+			logger.V(logging.INFO).Info("Received second shutdown signal, forcing shutdown...", "signal", sig)
+		case <-graceExpired:
+			logger.V(logging.INFO).Info("Graceful shutdown timed out, forcing shutdown...", "grace", grace)
+		}
 		klog.Flush()
-		os.Exit(1)
+		exit(1)
 	}()
 
 	return ctx, cancel
@@ -0,0 +1,63 @@
+// internal/util/interrupt/interrupt_test.go
+
+package interrupt
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestContextWithSignalTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		grace        time.Duration
+		secondSignal bool
+	}{
+		{name: "grace expired", grace: 50 * time.Millisecond},
+		{name: "second signal", grace: time.Hour, secondSignal: true},
+		{name: "second signal without grace", grace: 0, secondSignal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exited := make(chan int, 1)
+			exit = func(code int) { exited <- code }
+			t.Cleanup(func() { exit = os.Exit })
+
+			ctx, cancel := ContextWithSignalTimeout(context.Background(), tt.grace)
+			defer cancel()
+
+			if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+				t.Fatalf("failed to send signal: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the context to be cancelled on the first signal")
+			}
+
+			select {
+			case code := <-exited:
+				t.Fatalf("expected no exit before the grace period or second signal, got exit code %d", code)
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			if tt.secondSignal {
+				if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+					t.Fatalf("failed to send signal: %v", err)
+				}
+			}
+			select {
+			case code := <-exited:
+				if code != 1 {
+					t.Errorf("expected exit code 1, got %d", code)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the process to exit")
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file contains unit tests for the certificate reloader.
+
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeTestKeyPair(t, certFile, keyFile, "old-cert", time.Now().Add(-time.Minute))
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader failed: %v", err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: reloader.GetCertificate})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	// handshakeCN returns the common name of the certificate presented by the server
+	handshakeCN := func() string {
+		t.Helper()
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatalf("handshake failed: %v", err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+
+	if cn := handshakeCN(); cn != "old-cert" {
+		t.Fatalf("expected certificate %q, got %q", "old-cert", cn)
+	}
+
+	t.Run("Unchanged", func(t *testing.T) {
+		reloaded, err := reloader.Reload()
+		if err != nil || reloaded {
+			t.Fatalf("expected no reload of unchanged files, got reloaded=%v err=%v", reloaded, err)
+		}
+	})
+
+	t.Run("InvalidFiles", func(t *testing.T) {
+		if err := os.WriteFile(certFile, []byte("not a certificate"), 0o600); err != nil {
+			t.Fatalf("failed to write cert: %v", err)
+		}
+		if _, err := reloader.Reload(); err == nil {
+			t.Fatal("expected reload of an invalid certificate to fail")
+		}
+		if cn := handshakeCN(); cn != "old-cert" {
+			t.Errorf("expected the previous certificate %q to be kept, got %q", "old-cert", cn)
+		}
+	})
+
+	t.Run("Rotated", func(t *testing.T) {
+		writeTestKeyPair(t, certFile, keyFile, "new-cert", time.Now())
+		reloaded, err := reloader.Reload()
+		if err != nil || !reloaded {
+			t.Fatalf("expected rotated files to be reloaded, got reloaded=%v err=%v", reloaded, err)
+		}
+		if cn := handshakeCN(); cn != "new-cert" {
+			t.Errorf("expected certificate %q, got %q", "new-cert", cn)
+		}
+	})
+}
+
+// writeTestKeyPair writes a self-signed certificate for cn and its key, with the given modification time.
+func writeTestKeyPair(t *testing.T, certFile, keyFile, cn string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	files := map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDer},
+	}
+	for path, block := range files {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		// set the modification time explicitly, as file systems may have a coarse timestamp resolution
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to set modification time of %s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file provides the OpenTelemetry tracing utilities.
+// The spans are exported with OTLP over HTTP when an endpoint is configured; otherwise the tracing is a no-op,
+// but the trace context is still propagated so the traces of callers continue through the batches.
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracers of the batch gateway.
+const InstrumentationName = "github.com/llm-d-incubation/batch-gateway"
+
+// shutdownTimeout bounds the export of the remaining spans at shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// Config is the tracing configuration of a service.
+type Config struct {
+	// OTLPEndpoint is the URL of the OTLP/HTTP endpoint the spans are exported to, e.g. http://collector:4318.
+	// Tracing is disabled when it is empty.
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+
+	// SampleRatio is the ratio of the traces started by the service that are sampled. Defaults to all of them.
+	// The traces continued from a caller follow the sampling decision of the caller.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+func (c *Config) Validate() error {
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("sample_ratio must be between 0 and 1, got %g", c.SampleRatio)
+	}
+	return nil
+}
+
+// Setup installs the global trace context propagator and, when an OTLP endpoint is configured,
+// the global tracer provider exporting the spans of the service.
+// The returned function flushes the remaining spans, and must be called when the service stops.
+func Setup(ctx context.Context, serviceName string, cfg Config) (func() error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.OTLPEndpoint == "" {
+		return func() error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP trace exporter: %w", err)
+	}
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return provider.Shutdown(ctx)
+	}, nil
+}
+
+// Tracer returns the tracer of the batch gateway, from the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Inject returns the trace context of ctx as a map, to be stored with a record. It returns nil if ctx has no trace.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns a copy of ctx continuing the trace context stored by Inject.
+func Extract(ctx context.Context, traceContext map[string]string) context.Context {
+	if len(traceContext) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(traceContext))
+}
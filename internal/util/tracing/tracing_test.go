@@ -0,0 +1,80 @@
+/*
+Copyright 2026 The llm-d Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The file contains unit tests for the tracing utilities.
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ratio   float64
+		wantErr bool
+	}{
+		{name: "default", ratio: 0},
+		{name: "ratio", ratio: 0.25},
+		{name: "negative", ratio: -0.1, wantErr: true},
+		{name: "above one", ratio: 1.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{SampleRatio: tt.ratio}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	t.Run("no trace", func(t *testing.T) {
+		if traceContext := Inject(context.Background()); traceContext != nil {
+			t.Errorf("Expected no trace context, got %v", traceContext)
+		}
+		ctx := context.Background()
+		if got := Extract(ctx, nil); got != ctx {
+			t.Error("Expected the context to be returned unchanged")
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+		spanCtx := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+		ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+		traceContext := Inject(ctx)
+		if traceContext["traceparent"] == "" {
+			t.Fatalf("Expected a traceparent, got %v", traceContext)
+		}
+		got := trace.SpanContextFromContext(Extract(context.Background(), traceContext))
+		if got.TraceID() != traceID || got.SpanID() != spanID || !got.IsRemote() {
+			t.Errorf("Expected the remote span context %v, got %v", spanCtx, got)
+		}
+	})
+}